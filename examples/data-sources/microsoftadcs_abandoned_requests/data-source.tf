@@ -0,0 +1,14 @@
+provider "microsoftadcs" {
+  host       = "ca.example.com"
+  workspace  = terraform.workspace
+  event_file = "/var/log/terraform/adcs-events.ndjson"
+}
+
+data "microsoftadcs_abandoned_requests" "stale" {
+  older_than_days = 14
+  workspace       = terraform.workspace
+}
+
+output "stale_requests" {
+  value = data.microsoftadcs_abandoned_requests.stale.requests
+}
@@ -0,0 +1,5 @@
+data "microsoftadcs_ca_chain" "current" {}
+
+output "ca_chain" {
+  value = join("", data.microsoftadcs_ca_chain.current.certificates_pem)
+}
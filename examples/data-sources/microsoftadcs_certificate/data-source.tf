@@ -0,0 +1,7 @@
+data "microsoftadcs_certificate" "example" {
+  id = "525135"
+}
+
+output "example_certificate" {
+  value = data.microsoftadcs_certificate.example
+}
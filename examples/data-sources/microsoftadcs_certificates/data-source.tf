@@ -0,0 +1,10 @@
+data "microsoftadcs_certificates" "web" {
+  request_id_from = 5000
+  request_id_to   = 5999
+  subject_pattern = "CN=.*\\.example\\.com"
+}
+
+resource "local_file" "imports" {
+  filename = "${path.module}/imports.tf"
+  content  = data.microsoftadcs_certificates.web.import_blocks_hcl
+}
@@ -0,0 +1,24 @@
+variable "notification_webhook_url" {
+  type = string
+}
+
+data "microsoftadcs_expiring_certificates" "web" {
+  request_id_from = 5000
+  request_id_to   = 5999
+  within_hours    = 30 * 24
+  template        = "WebServer"
+}
+
+resource "terraform_data" "notify" {
+  for_each = { for cert in data.microsoftadcs_expiring_certificates.web.certificates : cert.request_id => cert }
+
+  input = {
+    subject         = each.value.subject
+    dns_names       = each.value.dns_names
+    hours_remaining = each.value.hours_remaining
+  }
+
+  provisioner "local-exec" {
+    command = "curl -sf -X POST -d '${jsonencode(self.input)}' ${var.notification_webhook_url}"
+  }
+}
@@ -0,0 +1,5 @@
+data "microsoftadcs_ndes" "scep" {}
+
+output "scep_ca_thumbprint" {
+  value = data.microsoftadcs_ndes.scep.ca_thumbprint
+}
@@ -0,0 +1,20 @@
+data "microsoftadcs_ocsp_status" "web" {
+  certificate_pem = microsoftadcs_certificate.web.certificate_pem
+}
+
+resource "terraform_data" "deploy" {
+  input = microsoftadcs_certificate.web.certificate_pem
+
+  lifecycle {
+    precondition {
+      condition     = data.microsoftadcs_ocsp_status.web.status == "good"
+      error_message = "The certificate is ${data.microsoftadcs_ocsp_status.web.status} according to the OCSP responder."
+    }
+  }
+}
+
+# Certificates that are not at hand are checked by serial number, the responder has to be given then.
+data "microsoftadcs_ocsp_status" "vpn" {
+  serial_number = "61000000057ba3e1c6a8bd2e73000000000057"
+  ocsp_url      = "http://pki.example.com/ocsp"
+}
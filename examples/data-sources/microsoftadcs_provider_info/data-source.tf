@@ -0,0 +1,6 @@
+data "microsoftadcs_provider_info" "ca" {}
+
+# Only request the certificate through the Certificate Enrollment Web Service where it exists.
+output "enrollment_endpoint" {
+  value = data.microsoftadcs_provider_info.ca.wstep_available ? "https://${data.microsoftadcs_provider_info.ca.id}/${data.microsoftadcs_provider_info.ca.ca_name}_CES_Kerberos/service.svc" : "http://${data.microsoftadcs_provider_info.ca.id}/certsrv"
+}
@@ -0,0 +1,12 @@
+terraform {
+  required_providers {
+    microsoftadcs = {
+      source = "registry.terraform.io/flipyap/microsoft-adcs"
+    }
+  }
+}
+
+provider "microsoftadcs" {
+  host     = "server.company.local"
+  username = "Username"
+}
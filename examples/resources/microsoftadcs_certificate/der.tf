@@ -0,0 +1,10 @@
+# Appliances such as NetScaler import the certificate and its issuers as DER files.
+resource "local_file" "certificate_der" {
+  filename       = "${path.module}/vpn.cer"
+  content_base64 = microsoftadcs_certificate.vpn.certificate_der
+}
+
+resource "local_file" "issuing_ca_der" {
+  filename       = "${path.module}/issuing-ca.cer"
+  content_base64 = microsoftadcs_certificate.vpn.certificate_chain_der[0]
+}
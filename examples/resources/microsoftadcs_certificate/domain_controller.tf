@@ -0,0 +1,15 @@
+resource "microsoftadcs_certificate" "dc01" {
+  # A duplicate of Kerberos Authentication with the subject name supplied in the request.
+  template           = "KerberosAuthenticationSupplied"
+  verify_issued_sans = "error"
+
+  # Requests dc01.corp.example.com, corp.example.com and CORP as DNS names and checks the certificate carries the
+  # Client Authentication, Server Authentication, Smart Card Logon and KDC Authentication extended key usages.
+  domain_controller {
+    host_name    = "dc01"
+    domain       = "corp.example.com"
+    netbios_name = "CORP"
+  }
+
+  generate_csr {}
+}
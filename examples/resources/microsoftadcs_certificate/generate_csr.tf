@@ -0,0 +1,17 @@
+resource "microsoftadcs_certificate" "api" {
+  template = "WebServer"
+
+  # The provider creates the key and the certificate signing request, no tls_private_key needed.
+  generate_csr {
+    key_algorithm = "ECDSA"
+    ecdsa_curve   = "P384"
+    common_name   = "api.example.com"
+    organization  = "Example"
+    dns_names     = ["api.example.com", "api.internal.example.com"]
+  }
+}
+
+output "api_combined_pem" {
+  value     = microsoftadcs_certificate.api.combined_pem
+  sensitive = true
+}
@@ -0,0 +1,12 @@
+# Certificates are imported by the request ID the CA assigned to them.
+terraform import microsoftadcs_certificate.my_cert 5123
+terraform import microsoftadcs_certificate.my_cert request:5123
+
+# When only the serial number is known, the provider retrieves request IDs one by one from 1, or
+# from the request ID after the @, until it finds the certificate.
+terraform import microsoftadcs_certificate.my_cert serial:61000000057ba3ef8c2d4e0000000000005
+terraform import microsoftadcs_certificate.my_cert serial:61000000057ba3ef8c2d4e0000000000005@5000
+
+# Certificates found by inventory scanners can be imported by their SHA-1 or SHA-256 thumbprint,
+# searched the same way.
+terraform import microsoftadcs_certificate.my_cert thumbprint:3f2a9c0e5b7d41e8a6c2f09d8b1e7a4c5d6e3f21
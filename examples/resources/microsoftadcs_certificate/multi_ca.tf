@@ -0,0 +1,25 @@
+variable "payments_ca_password" {
+  type      = string
+  sensitive = true
+}
+
+# Requested from the CA the provider is configured with.
+resource "microsoftadcs_certificate" "web" {
+  template = "WebServer"
+
+  generate_csr {
+    common_name = "web.example.com"
+  }
+}
+
+# Requested from another issuing CA, with an account enrolled for its templates.
+resource "microsoftadcs_certificate" "payments" {
+  template = "PaymentsServer"
+  host     = "ca-payments.example.com"
+  username = "svc-payments"
+  password = var.payments_ca_password
+
+  generate_csr {
+    common_name = "payments.example.com"
+  }
+}
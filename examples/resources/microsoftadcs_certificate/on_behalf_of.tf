@@ -0,0 +1,17 @@
+variable "enrollment_agent_key" {
+  type      = string
+  sensitive = true
+}
+
+resource "microsoftadcs_certificate" "smartcard" {
+  template     = "SmartcardUser"
+  on_behalf_of = "EXAMPLE\\jdoe"
+
+  # The service identity's enrollment agent certificate signs the request.
+  enrollment_agent_certificate_pem = file("${path.module}/enrollment-agent.crt")
+  enrollment_agent_private_key_pem = var.enrollment_agent_key
+
+  generate_csr {
+    common_name = "jdoe"
+  }
+}
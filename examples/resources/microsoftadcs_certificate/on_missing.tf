@@ -0,0 +1,8 @@
+resource "microsoftadcs_certificate" "web" {
+  template   = "WebServer"
+  on_missing = "recreate"
+
+  generate_csr {
+    common_name = "web.example.com"
+  }
+}
@@ -0,0 +1,5 @@
+# certutil -addstore and Intune import the chain as a p7b file.
+resource "local_file" "chain_p7b" {
+  filename       = "${path.module}/vpn.p7b"
+  content_base64 = microsoftadcs_certificate.vpn.certificate_chain_p7b
+}
@@ -0,0 +1,8 @@
+resource "microsoftadcs_certificate" "approved" {
+  certificate_signing_request = local.csr
+  template                    = "ManagerApproval"
+
+  # Keep the request in state while the CA managers decide, and take over the certificate on the
+  # first apply after they issued it.
+  pending_behavior = "save"
+}
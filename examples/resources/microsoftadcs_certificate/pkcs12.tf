@@ -0,0 +1,23 @@
+variable "pfx_password" {
+  type      = string
+  sensitive = true
+}
+
+resource "microsoftadcs_certificate" "iis" {
+  template = "WebServer"
+
+  generate_csr {
+    common_name = "intranet.example.com"
+    dns_names   = ["intranet.example.com"]
+  }
+
+  # pkcs12_b64 holds the certificate, its chain and the generated key for IIS or a Java keystore.
+  pkcs12 {
+    password = var.pfx_password
+  }
+}
+
+resource "local_sensitive_file" "pfx" {
+  filename       = "${path.module}/intranet.pfx"
+  content_base64 = microsoftadcs_certificate.iis.pkcs12_b64
+}
@@ -0,0 +1,7 @@
+# The agent on the host runs certreq -new renewal.inf renewal.req with RenewalCert set to the
+# thumbprint of the current certificate, which signs the renewal request with its key.
+resource "microsoftadcs_certificate" "host" {
+  template                    = "WebServer"
+  request_format              = "pkcs7"
+  certificate_signing_request = file("${path.module}/renewal.req")
+}
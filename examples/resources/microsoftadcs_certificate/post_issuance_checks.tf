@@ -0,0 +1,12 @@
+resource "microsoftadcs_certificate" "web" {
+  certificate_signing_request = local.csr
+  template                    = "WebServer"
+
+  post_issuance_checks {
+    expected_ekus      = ["server_auth"]
+    max_validity_hours = 9528
+    required_dns_names = ["web.example.com"]
+    issuer_dn          = "CN=Example Issuing CA, DC=example, DC=com"
+    warn_only          = ["max_validity_hours"]
+  }
+}
@@ -0,0 +1,12 @@
+resource "microsoftadcs_certificate" "api" {
+  template = "WebServer"
+  profile  = "tls_server"
+
+  # Overrides the 30 days of early renewal of the profile.
+  early_renewal_hours = 336
+
+  generate_csr {
+    common_name = "api.example.com"
+    dns_names   = ["api.example.com"]
+  }
+}
@@ -0,0 +1,12 @@
+resource "microsoftadcs_certificate" "vpn" {
+  template = "VPNGateway"
+
+  # Renew through the ADCS renewal flow during the last 30 days instead of enrolling anew.
+  renew_existing      = true
+  early_renewal_hours = 720
+
+  generate_csr {
+    common_name = "vpn.example.com"
+    dns_names   = ["vpn.example.com"]
+  }
+}
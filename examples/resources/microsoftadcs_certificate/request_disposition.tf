@@ -0,0 +1,11 @@
+resource "microsoftadcs_certificate" "web" {
+  certificate_signing_request = local.csr
+  template                    = "WebServer"
+
+  lifecycle {
+    postcondition {
+      condition     = self.request_disposition == "issued"
+      error_message = "The certificate is ${self.request_disposition}: ${self.disposition_message}"
+    }
+  }
+}
@@ -0,0 +1,8 @@
+resource "microsoftadcs_certificate" "my_cert" {
+  certificate_signing_request = base64decode(local.csr)
+  template                    = "User"
+}
+
+output "my_cert_certs" {
+  value = microsoftadcs_certificate.my_cert
+}
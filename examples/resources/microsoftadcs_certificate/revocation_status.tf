@@ -0,0 +1,11 @@
+resource "microsoftadcs_certificate" "web" {
+  certificate_signing_request = local.csr
+  template                    = "WebServer"
+}
+
+check "web_certificate_not_revoked" {
+  assert {
+    condition     = microsoftadcs_certificate.web.revocation_status != "revoked"
+    error_message = "The certificate was revoked on ${microsoftadcs_certificate.web.revocation_date}: ${microsoftadcs_certificate.web.revoked_reason}"
+  }
+}
@@ -0,0 +1,14 @@
+resource "microsoftadcs_certificate" "web" {
+  certificate_signing_request = file("${path.module}/web.csr")
+  template                    = "WebServer"
+
+  # Request an extra DNS name, sent to the CA as the san request attribute "san:dns=www.example.com".
+  subject_alternative_names {
+    dns = ["www.example.com"]
+  }
+
+  # Fail at plan time when the CSR or subject_alternative_names ask for names outside example.com.
+  allowed_san_patterns = [
+    "([a-z0-9-]+\\.)*example\\.com",
+  ]
+}
@@ -0,0 +1,15 @@
+resource "microsoftadcs_certificate" "payments" {
+  template = "WebServer"
+
+  # The issuing CA signs with SHA-384 and RSASSA-PSS; fail instead of storing a SHA-256 certificate.
+  signature_hash_algorithm = "SHA384"
+
+  generate_csr {
+    rsa_bits    = 3072
+    common_name = "payments.example.com"
+  }
+}
+
+output "payments_signature_algorithm" {
+  value = microsoftadcs_certificate.payments.signature_algorithm
+}
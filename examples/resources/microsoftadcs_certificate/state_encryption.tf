@@ -0,0 +1,17 @@
+resource "microsoftadcs_certificate" "sealed" {
+  template = "WebServer"
+
+  # generated_private_key_pem is stored encrypted for the holder of the matching private key,
+  # who opens it with:
+  #   terraform output -raw sealed_key | terraform-provider-microsoft-adcs decrypt -key state-key.pem
+  state_encryption_public_key = file("${path.module}/state-key.pub.pem")
+
+  generate_csr {
+    common_name = "sealed.example.com"
+  }
+}
+
+output "sealed_key" {
+  value     = microsoftadcs_certificate.sealed.generated_private_key_pem
+  sensitive = true
+}
@@ -0,0 +1,12 @@
+resource "microsoftadcs_certificate" "api" {
+  template = "WebServer"
+
+  generate_csr {
+    common_name = "api.example.com"
+  }
+
+  # Sent to the CA as "san:dns=api.example.com&dns=api.internal.example.com&ipaddress=10.0.4.20&url=spiffe://example.com/api".
+  dns_names    = ["api.example.com", "api.internal.example.com"]
+  ip_addresses = ["10.0.4.20"]
+  uris         = ["spiffe://example.com/api"]
+}
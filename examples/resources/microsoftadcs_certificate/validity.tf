@@ -0,0 +1,7 @@
+resource "microsoftadcs_certificate" "event" {
+  certificate_signing_request = tls_cert_request.event.cert_request_pem
+  template                    = "WebServer"
+
+  # Needs certutil -setreg policy\EditFlags +EDITF_ATTRIBUTEENDDATE on the CA.
+  requested_not_after = "2030-06-30T23:59:59Z"
+}
@@ -0,0 +1,11 @@
+resource "microsoftadcs_certificate" "device" {
+  template = "DeviceAuthentication"
+
+  generate_csr {
+    common_name = "sensor-17.example.com"
+  }
+
+  # Sent to the CA as the request attributes "ValidityPeriod:Weeks" and "ValidityPeriodUnits:6".
+  validity_period       = "Weeks"
+  validity_period_units = 6
+}
@@ -0,0 +1,12 @@
+resource "microsoftadcs_certificate" "ingress" {
+  template = "WebServer"
+
+  generate_csr {
+    common_name = "ingress.example.com"
+  }
+
+  dns_names = ["ingress.example.com", "ingress.internal.example.com"]
+
+  # Fail the apply when the CA issued the certificate without any of the names above, or with others.
+  verify_issued_sans = "error"
+}
@@ -0,0 +1,22 @@
+variable "web_hosts" {
+  type    = set(string)
+  default = ["web01.example.com", "web02.example.com", "web03.example.com"]
+}
+
+# One resource issues the certificates of the whole web farm.
+resource "microsoftadcs_certificate_batch" "web" {
+  template      = "WebServer"
+  key_algorithm = "ECDSA"
+  ecdsa_curve   = "P384"
+
+  certificates = {
+    for host in var.web_hosts : host => {
+      common_name = host
+      dns_names   = [host]
+    }
+  }
+}
+
+output "web_batch_failures" {
+  value = microsoftadcs_certificate_batch.web.failed
+}
@@ -0,0 +1,2 @@
+# Requests are imported by the request ID the CA assigned to them.
+terraform import microsoftadcs_certificate_request.payments 5123
@@ -0,0 +1,14 @@
+# The first apply only submits the request. A CA manager approves it whenever they get to it.
+resource "microsoftadcs_certificate_request" "payments" {
+  certificate_signing_request = tls_cert_request.payments.cert_request_pem
+  template                    = "ManagerApproval"
+}
+
+# Once a refresh saw the request issued, the next apply takes the certificate over.
+resource "microsoftadcs_certificate" "payments" {
+  count = microsoftadcs_certificate_request.payments.disposition == "issued" ? 1 : 0
+
+  certificate_signing_request = tls_cert_request.payments.cert_request_pem
+  template                    = microsoftadcs_certificate_request.payments.template
+  adopt_request_id            = microsoftadcs_certificate_request.payments.id
+}
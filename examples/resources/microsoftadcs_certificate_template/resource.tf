@@ -0,0 +1,26 @@
+provider "microsoftadcs" {
+  host     = "ca.example.com"
+  ldap_url = "ldaps://dc01.example.com"
+}
+
+resource "microsoftadcs_certificate_template" "web" {
+  name                  = "WebServerTF"
+  display_name          = "Web Server (Terraform)"
+  validity_period       = "Years"
+  validity_period_units = 2
+  renewal_period        = "Weeks"
+  renewal_period_units  = 6
+  key_usage             = ["digital_signature", "key_encipherment"]
+  extended_key_usages   = ["server_auth"]
+  subject_name_flags    = ["enrollee_supplies_subject"]
+  minimum_key_size      = 3072
+}
+
+# Certificates refer to the template by its name once it is published on the CA.
+resource "microsoftadcs_certificate" "web" {
+  template = microsoftadcs_certificate_template.web.name
+
+  generate_csr {
+    common_name = "web.example.com"
+  }
+}
@@ -0,0 +1,16 @@
+provider "microsoftadcs" {
+  host               = "ca.example.com"
+  workspace          = terraform.workspace
+  event_file         = "/var/log/terraform/adcs-events.ndjson"
+  denial_webhook_url = "https://pki-automation.example.com/deny"
+}
+
+data "microsoftadcs_abandoned_requests" "stale" {
+  older_than_days = 14
+  workspace       = terraform.workspace
+}
+
+resource "microsoftadcs_request_cleanup" "stale" {
+  request_ids = data.microsoftadcs_abandoned_requests.stale.request_ids
+  reason      = "Pending for more than 14 days without approval"
+}
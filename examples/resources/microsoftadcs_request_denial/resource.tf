@@ -0,0 +1,16 @@
+provider "microsoftadcs" {
+  host               = "ca.example.com"
+  denial_webhook_url = "https://pki-automation.example.com/deny"
+}
+
+resource "microsoftadcs_certificate_request" "legacy" {
+  certificate_signing_request = file("legacy.csr")
+  template                    = "WebServer"
+}
+
+# Withdraw the request once the service it was for is retired, without waiting for a CA manager.
+resource "microsoftadcs_request_denial" "legacy" {
+  request_id = microsoftadcs_certificate_request.legacy.id
+  ca_name    = microsoftadcs_certificate_request.legacy.ca_name
+  reason     = "Service retired before the request was approved"
+}
@@ -0,0 +1,36 @@
+# A client authentication certificate for a service account, with its UPN and an extra DNS name
+# requested through the subject_alternative_names block.
+
+resource "tls_private_key" "client" {
+  algorithm   = "ECDSA"
+  ecdsa_curve = "P256"
+}
+
+resource "tls_cert_request" "client" {
+  private_key_pem = tls_private_key.client.private_key_pem
+  dns_names       = ["svc-app.example.com"]
+
+  subject {
+    common_name = "svc-app"
+  }
+}
+
+resource "microsoftadcs_certificate" "client" {
+  certificate_signing_request = tls_cert_request.client.cert_request_pem
+  template                    = "User"
+
+  subject_alternative_names {
+    dns = ["svc-app.internal.example.com"]
+    upn = ["svc-app@example.com"]
+  }
+
+  # Fail at plan time when the request asks for names outside example.com.
+  allowed_san_patterns = [
+    "([a-z0-9-]+\\.)*example\\.com",
+    "[a-z0-9-]+@example\\.com",
+  ]
+}
+
+output "thumbprint_sha1" {
+  value = microsoftadcs_certificate.client.thumbprint_sha1
+}
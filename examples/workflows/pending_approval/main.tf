@@ -0,0 +1,28 @@
+# A certificate from a template that requires CA manager approval. The apply waits until a CA
+# manager issues the pending request instead of failing.
+
+resource "tls_private_key" "approved" {
+  algorithm = "RSA"
+  rsa_bits  = 2048
+}
+
+resource "tls_cert_request" "approved" {
+  private_key_pem = tls_private_key.approved.private_key_pem
+  dns_names       = ["payments.example.com"]
+
+  subject {
+    common_name = "payments.example.com"
+  }
+}
+
+resource "microsoftadcs_certificate" "approved" {
+  certificate_signing_request = tls_cert_request.approved.cert_request_pem
+  template                    = "ManagerApproval"
+
+  wait_for_issuance = true
+  issuance_timeout  = "4h"
+}
+
+output "request_id" {
+  value = microsoftadcs_certificate.approved.id
+}
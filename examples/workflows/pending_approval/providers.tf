@@ -0,0 +1,15 @@
+terraform {
+  required_providers {
+    microsoftadcs = {
+      source = "registry.terraform.io/flipyap/microsoft-adcs"
+    }
+    tls = {
+      source = "hashicorp/tls"
+    }
+  }
+}
+
+provider "microsoftadcs" {
+  host     = "server.company.local"
+  username = "Username"
+}
@@ -0,0 +1,44 @@
+# A TLS server certificate for a web server, renewed a month before it expires.
+
+resource "tls_private_key" "web" {
+  algorithm = "RSA"
+  rsa_bits  = 2048
+}
+
+resource "tls_cert_request" "web" {
+  private_key_pem = tls_private_key.web.private_key_pem
+  dns_names       = ["www.example.com"]
+
+  subject {
+    common_name  = "www.example.com"
+    organization = "Example"
+  }
+}
+
+resource "microsoftadcs_certificate" "web" {
+  certificate_signing_request = tls_cert_request.web.cert_request_pem
+  template                    = "WebServer"
+
+  # Bundle the key with the chain in combined_pem for HAProxy or NGINX.
+  private_key_pem = tls_private_key.web.private_key_pem
+
+  early_renewal_hours = 720
+}
+
+output "certificate_pem" {
+  value = microsoftadcs_certificate.web.certificate_pem
+}
+
+output "certificate_chain_pem" {
+  value = microsoftadcs_certificate.web.certificate_chain_pem
+}
+
+# The issuing CA alone, for servers that take the intermediate as a separate file.
+output "issuing_ca_pem" {
+  value = microsoftadcs_certificate.web.issuing_ca_pem
+}
+
+output "combined_pem" {
+  value     = microsoftadcs_certificate.web.combined_pem
+  sensitive = true
+}
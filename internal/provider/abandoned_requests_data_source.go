@@ -0,0 +1,264 @@
+package provider
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/flipyap/microsoft-adcs-client/client"
+	"github.com/hashicorp/terraform-plugin-framework/attr"
+	"github.com/hashicorp/terraform-plugin-framework/datasource"
+	"github.com/hashicorp/terraform-plugin-framework/datasource/schema"
+	"github.com/hashicorp/terraform-plugin-framework/path"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+	"github.com/hashicorp/terraform-plugin-log/tflog"
+)
+
+// Ensure the implementation satisfies the expected interfaces.
+var (
+	_ datasource.DataSource                   = &abandonedRequestsDataSource{}
+	_ datasource.DataSourceWithConfigure      = &abandonedRequestsDataSource{}
+	_ datasource.DataSourceWithValidateConfig = &abandonedRequestsDataSource{}
+)
+
+// NewAbandonedRequestsDataSource is a helper function to simplify the provider implementation.
+func NewAbandonedRequestsDataSource() datasource.DataSource {
+	return &abandonedRequestsDataSource{}
+}
+
+// abandonedRequestsDataSource finds requests Terraform submitted that are still pending long after.
+type abandonedRequestsDataSource struct {
+	client   *client.ADCSClient
+	provider *providerData
+}
+
+// abandonedRequestsModel maps the query and its results.
+type abandonedRequestsModel struct {
+	ID            types.String `tfsdk:"id"`
+	OlderThanDays types.Int64  `tfsdk:"older_than_days"`
+	Workspace     types.String `tfsdk:"workspace"`
+	Requests      types.List   `tfsdk:"requests"`
+	RequestIDs    types.List   `tfsdk:"request_ids"`
+}
+
+// abandonedRequest is a single entry of the requests attribute.
+type abandonedRequest struct {
+	RequestID   string `tfsdk:"request_id"`
+	Host        string `tfsdk:"host"`
+	CAName      string `tfsdk:"ca_name"`
+	Template    string `tfsdk:"template"`
+	Description string `tfsdk:"description"`
+	SubmittedAt string `tfsdk:"submitted_at"`
+}
+
+var abandonedRequestAttrTypes = map[string]attr.Type{
+	"request_id":   types.StringType,
+	"host":         types.StringType,
+	"ca_name":      types.StringType,
+	"template":     types.StringType,
+	"description":  types.StringType,
+	"submitted_at": types.StringType,
+}
+
+// pendingCandidates returns the pending events of requests submitted before cutoff with the
+// Terraform description of workspace, or of any workspace when it is empty, oldest first. Requests
+// with a later event, such as their certificate being adopted once issued, are left out.
+func pendingCandidates(events []lifecycleEvent, cutoff time.Time, workspace string) []lifecycleEvent {
+	type requestKey struct{ host, caName, requestID string }
+	latest := map[requestKey]lifecycleEvent{}
+	for _, event := range events {
+		key := requestKey{event.Host, event.CAName, event.RequestID}
+		if event.Event != eventPending {
+			// Only pending events carry the CA name.
+			for candidate := range latest {
+				if candidate.host == key.host && candidate.requestID == key.requestID {
+					delete(latest, candidate)
+				}
+			}
+			continue
+		}
+		latest[key] = event
+	}
+
+	candidates := []lifecycleEvent{}
+	for _, event := range latest {
+		if event.Timestamp.Before(cutoff) && isTerraformDescription(event.Description, workspace) {
+			candidates = append(candidates, event)
+		}
+	}
+	sort.Slice(candidates, func(i, j int) bool { return candidates[i].Timestamp.Before(candidates[j].Timestamp) })
+	return candidates
+}
+
+// Configure adds the provider configured client to the data source.
+func (d *abandonedRequestsDataSource) Configure(_ context.Context, req datasource.ConfigureRequest, resp *datasource.ConfigureResponse) {
+	if req.ProviderData == nil {
+		return
+	}
+
+	data, ok := req.ProviderData.(*providerData)
+	if !ok {
+		resp.Diagnostics.AddError(
+			"Unexpected Data Source Configure Type",
+			fmt.Sprintf("Expected *providerData, got: %T. Please report this issue to the provider developers.", req.ProviderData),
+		)
+
+		return
+	}
+
+	d.client = data.client
+	d.provider = data
+}
+
+// Metadata returns the data source type name.
+func (d *abandonedRequestsDataSource) Metadata(_ context.Context, req datasource.MetadataRequest, resp *datasource.MetadataResponse) {
+	resp.TypeName = req.ProviderTypeName + "_abandoned_requests"
+}
+
+// Schema defines the schema for the data source.
+func (d *abandonedRequestsDataSource) Schema(_ context.Context, _ datasource.SchemaRequest, resp *datasource.SchemaResponse) {
+	resp.Schema = schema.Schema{
+		Description: `Finds requests microsoftadcs_certificate submitted with the default "Managed by Terraform" description that
+are still pending approval after a number of days, for example because the apply that waited for them failed or the
+resource was removed. The certsrv web enrollment pages can neither list requests nor show their attributes, so the
+requests are taken from the pending events in the event_file of the provider, and each one is checked with the CA before
+it is returned. Hand request_ids to microsoftadcs_request_cleanup to deny them.`,
+		Attributes: map[string]schema.Attribute{
+			"id": schema.StringAttribute{
+				Computed:    true,
+				Description: "The age and workspace the requests were searched with.",
+			},
+			"older_than_days": schema.Int64Attribute{
+				Required:    true,
+				Description: "Only return requests submitted at least this many days ago.",
+			},
+			"workspace": schema.StringAttribute{
+				Optional: true,
+				Description: `Only return requests described with the default description of this workspace, see workspace of the
+provider. Without it, requests of every workspace are returned.`,
+			},
+			"requests": schema.ListNestedAttribute{
+				Computed:    true,
+				Description: "The requests still pending at the CA, oldest first.",
+				NestedObject: schema.NestedAttributeObject{
+					Attributes: map[string]schema.Attribute{
+						"request_id": schema.StringAttribute{
+							Computed:    true,
+							Description: "Request ID assigned by the CA.",
+						},
+						"host": schema.StringAttribute{
+							Computed:    true,
+							Description: "The certsrv server the request was submitted to.",
+						},
+						"ca_name": schema.StringAttribute{
+							Computed:    true,
+							Description: "Name of the CA that took the request, empty when certsrv did not name it.",
+						},
+						"template": schema.StringAttribute{
+							Computed:    true,
+							Description: "Template the certificate was requested from.",
+						},
+						"description": schema.StringAttribute{
+							Computed:    true,
+							Description: "The CertificateDescription request attribute the request was submitted with.",
+						},
+						"submitted_at": schema.StringAttribute{
+							Computed:    true,
+							Description: "When the CA took the request under submission, in RFC 3339 format.",
+						},
+					},
+				},
+			},
+			"request_ids": schema.ListAttribute{
+				Computed:    true,
+				ElementType: types.StringType,
+				Description: "Request IDs of the requests, in the order of requests.",
+			},
+		},
+	}
+}
+
+// ValidateConfig checks the age.
+func (d *abandonedRequestsDataSource) ValidateConfig(ctx context.Context, req datasource.ValidateConfigRequest, resp *datasource.ValidateConfigResponse) {
+	var config abandonedRequestsModel
+	resp.Diagnostics.Append(req.Config.Get(ctx, &config)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	if !config.OlderThanDays.IsUnknown() && config.OlderThanDays.ValueInt64() < 1 {
+		resp.Diagnostics.AddAttributeError(path.Root("older_than_days"), "Invalid Age", "older_than_days must be at least 1.")
+	}
+}
+
+// Read refreshes the Terraform state with the latest data.
+func (d *abandonedRequestsDataSource) Read(ctx context.Context, req datasource.ReadRequest, resp *datasource.ReadResponse) {
+	if d.provider.caOffline(ctx) {
+		resp.Diagnostics.Append(applyOnlyError("check pending requests"))
+		return
+	}
+	var data abandonedRequestsModel
+	resp.Diagnostics.Append(req.Config.Get(ctx, &data)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+	if d.provider.eventFile == "" {
+		resp.Diagnostics.AddError(
+			"Missing Event File",
+			"Abandoned requests are found through the pending events the provider writes to event_file, set event_file on the provider.",
+		)
+		return
+	}
+
+	events, err := readEvents(d.provider.eventFile)
+	if err != nil {
+		resp.Diagnostics.AddError("Unable to Read Event File", err.Error())
+		return
+	}
+
+	cutoff := time.Now().Add(-time.Duration(data.OlderThanDays.ValueInt64()) * 24 * time.Hour)
+	abandoned := []abandonedRequest{}
+	for _, event := range pendingCandidates(events, cutoff, data.Workspace.ValueString()) {
+		pending, err := d.provider.requestPending(ctx, event.Host, event.CAName, event.RequestID)
+		if err != nil {
+			resp.Diagnostics.AddError(
+				classifiedSummary("Error Reading Certificate Request", err),
+				fmt.Sprintf("Could not check whether request ID %s is still pending: %s", event.RequestID, err.Error()),
+			)
+			return
+		}
+		if !pending {
+			tflog.Debug(ctx, "Skipping request that is no longer pending", map[string]interface{}{
+				"request_id": event.RequestID,
+			})
+			continue
+		}
+		abandoned = append(abandoned, abandonedRequest{
+			RequestID:   event.RequestID,
+			Host:        event.Host,
+			CAName:      event.CAName,
+			Template:    event.Template,
+			Description: event.Description,
+			SubmittedAt: event.Timestamp.UTC().Format(time.RFC3339),
+		})
+	}
+
+	requestIDs := make([]string, len(abandoned))
+	for i, request := range abandoned {
+		requestIDs[i] = request.RequestID
+	}
+
+	data.ID = types.StringValue(fmt.Sprintf("%dd-%s", data.OlderThanDays.ValueInt64(), data.Workspace.ValueString()))
+	requestsValue, diags := types.ListValueFrom(ctx, types.ObjectType{AttrTypes: abandonedRequestAttrTypes}, abandoned)
+	resp.Diagnostics.Append(diags...)
+	requestIDsValue, diags := types.ListValueFrom(ctx, types.StringType, requestIDs)
+	resp.Diagnostics.Append(diags...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+	data.Requests = requestsValue
+	data.RequestIDs = requestIDsValue
+
+	resp.Diagnostics.Append(resp.State.Set(ctx, &data)...)
+}
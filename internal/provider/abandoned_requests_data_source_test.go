@@ -0,0 +1,65 @@
+package provider
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestPendingCandidates(t *testing.T) {
+	now := time.Date(2026, 10, 16, 12, 0, 0, 0, time.UTC)
+	prod, staging := defaultCertificateDescription("prod"), defaultCertificateDescription("staging")
+	pending := func(reqID string, age time.Duration, description string) lifecycleEvent {
+		return lifecycleEvent{Event: eventPending, Timestamp: now.Add(-age), Host: "ca.example.com", RequestID: reqID, Description: description}
+	}
+	events := []lifecycleEvent{
+		pending("10", 30*24*time.Hour, prod),
+		pending("11", 20*24*time.Hour, staging),
+		pending("12", 2*24*time.Hour, prod),
+		pending("13", 40*24*time.Hour, "web front end of team-a"),
+		pending("14", 25*24*time.Hour, prod),
+		{Event: eventAdopted, Timestamp: now.Add(-24 * time.Hour), Host: "ca.example.com", RequestID: "14"},
+		pending("15", 50*24*time.Hour, prod),
+		{Event: eventDenied, Timestamp: now.Add(-24 * time.Hour), Host: "ca.example.com", RequestID: "15"},
+	}
+	requestIDs := func(events []lifecycleEvent) []string {
+		ids := []string{}
+		for _, event := range events {
+			ids = append(ids, event.RequestID)
+		}
+		return ids
+	}
+
+	cutoff := now.Add(-7 * 24 * time.Hour)
+	if got, want := requestIDs(pendingCandidates(events, cutoff, "")), []string{"10", "11"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("pendingCandidates() = %v, want %v", got, want)
+	}
+	if got, want := requestIDs(pendingCandidates(events, cutoff, "staging")), []string{"11"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("pendingCandidates() of staging = %v, want %v", got, want)
+	}
+}
+
+func TestReadEvents(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "events.ndjson")
+	content := `{"event":"pending","timestamp":"2026-10-01T08:00:00Z","host":"ca.example.com","request_id":"10","description":"Managed by Terraform, workspace prod"}
+not an event
+{"event":"issued","timestamp":"2026-10-02T08:00:00Z","host":"ca.example.com","request_id":"10"}
+{"event":"pending","timestamp":"2026-10-0`
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	events, err := readEvents(path)
+	if err != nil {
+		t.Fatalf("readEvents() failed: %v", err)
+	}
+	if len(events) != 2 || events[0].Event != eventPending || events[0].Description != defaultCertificateDescription("prod") || events[1].Event != eventIssued {
+		t.Errorf("readEvents() = %+v, want the pending and issued events", events)
+	}
+
+	if _, err := readEvents(filepath.Join(t.TempDir(), "missing.ndjson")); err == nil {
+		t.Error("readEvents() of a missing file succeeded, want an error")
+	}
+}
@@ -0,0 +1,151 @@
+package provider
+
+import (
+	"bytes"
+	"context"
+	"crypto/x509"
+	"encoding/pem"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+
+	"github.com/hashicorp/terraform-plugin-log/tflog"
+)
+
+// maxAIAResponseSize caps the download of an issuer certificate, a .crt or .p7c is a few kilobytes.
+const maxAIAResponseSize = 1 << 20
+
+// maxAIADepth is how many issuers are fetched for one chain before giving up on reaching a root.
+const maxAIADepth = 8
+
+// aiaFetcher completes chains the CA returned without some of its issuers by following the
+// Authority Information Access URLs of the certificates, see fetch_aia_issuers. Downloads are
+// cached per URL for the lifetime of the provider, every certificate of a CA points at the same one.
+type aiaFetcher struct {
+	mu    sync.Mutex
+	cache map[string][]*x509.Certificate
+}
+
+// newAIAFetcher returns a fetcher with an empty cache.
+func newAIAFetcher() *aiaFetcher {
+	return &aiaFetcher{cache: map[string][]*x509.Certificate{}}
+}
+
+// aiaFetcherKey is the context key of the fetcher completing chains.
+type aiaFetcherKey struct{}
+
+// withAIAFetcher makes chains built with ctx complete through fetcher. A nil fetcher keeps chains as
+// the CA returned them.
+func withAIAFetcher(ctx context.Context, fetcher *aiaFetcher) context.Context {
+	return context.WithValue(ctx, aiaFetcherKey{}, fetcher)
+}
+
+// aiaFetcherFrom returns the fetcher of ctx, nil when chains are not completed.
+func aiaFetcherFrom(ctx context.Context) *aiaFetcher {
+	fetcher, _ := ctx.Value(aiaFetcherKey{}).(*aiaFetcher)
+	return fetcher
+}
+
+// complete appends the missing issuers of chain, which starts with the issuer of leaf, until it ends
+// in a self-signed root or in a certificate without http AIA URLs. The issuers found so far are
+// returned together with the error that stopped the walk.
+func (f *aiaFetcher) complete(ctx context.Context, leaf *x509.Certificate, chain []*x509.Certificate) ([]*x509.Certificate, error) {
+	top := leaf
+	if len(chain) > 0 {
+		top = chain[len(chain)-1]
+	}
+	for depth := 0; !isSelfSigned(top); depth++ {
+		if depth == maxAIADepth {
+			return chain, fmt.Errorf("no root found after fetching %d issuers", maxAIADepth)
+		}
+		issuer, err := f.issuer(ctx, top)
+		if err != nil || issuer == nil {
+			return chain, err
+		}
+		chain = append(chain[:len(chain):len(chain)], issuer)
+		top = issuer
+	}
+	return chain, nil
+}
+
+// issuer fetches the certificate that signed cert from the first of its AIA URLs that has it. ADCS
+// usually lists an ldap URL first, only http and https URLs are followed; without one there is
+// nothing to fetch and issuer returns nil.
+func (f *aiaFetcher) issuer(ctx context.Context, cert *x509.Certificate) (*x509.Certificate, error) {
+	var errs []string
+	for _, url := range cert.IssuingCertificateURL {
+		if !strings.HasPrefix(strings.ToLower(url), "http://") && !strings.HasPrefix(strings.ToLower(url), "https://") {
+			continue
+		}
+		certs, err := f.fetch(ctx, url)
+		if err != nil {
+			errs = append(errs, err.Error())
+			continue
+		}
+		for _, candidate := range certs {
+			if bytes.Equal(cert.RawIssuer, candidate.RawSubject) && cert.CheckSignatureFrom(candidate) == nil {
+				return candidate, nil
+			}
+		}
+		errs = append(errs, url+" does not hold the issuer")
+	}
+	if len(errs) == 0 {
+		return nil, nil
+	}
+	return nil, fmt.Errorf("could not fetch the issuer of %q: %s", cert.Subject.String(), strings.Join(errs, "; "))
+}
+
+// fetch downloads the certificates published at url, from the cache when it was downloaded before.
+func (f *aiaFetcher) fetch(ctx context.Context, url string) ([]*x509.Certificate, error) {
+	f.mu.Lock()
+	certs, ok := f.cache[url]
+	f.mu.Unlock()
+	if ok {
+		return certs, nil
+	}
+
+	tflog.Debug(ctx, "Fetching issuer certificate from AIA URL", map[string]interface{}{"url": url})
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, fmt.Errorf("could not create request for %s: %v", url, err)
+	}
+	resp, err := webhookClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("request to %s failed: %v", url, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("%s returned status %d", url, resp.StatusCode)
+	}
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxAIAResponseSize))
+	if err != nil {
+		return nil, fmt.Errorf("could not read %s: %v", url, err)
+	}
+	certs, err = parseAIACertificates(body)
+	if err != nil {
+		return nil, fmt.Errorf("could not parse %s: %v", url, err)
+	}
+
+	f.mu.Lock()
+	f.cache[url] = certs
+	f.mu.Unlock()
+	return certs, nil
+}
+
+// parseAIACertificates parses what an AIA URL serves: a DER certificate (.crt, .cer), a PKCS#7
+// bundle (.p7c) or either of them PEM encoded.
+func parseAIACertificates(body []byte) ([]*x509.Certificate, error) {
+	if block, _ := pem.Decode(body); block != nil {
+		body = block.Bytes
+	}
+	if cert, err := x509.ParseCertificate(body); err == nil {
+		return []*x509.Certificate{cert}, nil
+	}
+	certs, err := parsePKCS7Certificates(body)
+	if err != nil {
+		return nil, fmt.Errorf("neither a certificate nor a PKCS#7 bundle: %v", err)
+	}
+	return certs, nil
+}
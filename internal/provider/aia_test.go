@@ -0,0 +1,154 @@
+package provider
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/flipyap/microsoft-adcs-client/client"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+)
+
+// testAIACertificate returns a certificate pointing at its issuer through the given AIA URLs.
+func testAIACertificate(t *testing.T, cn string, aia []string, parent *x509.Certificate, parentKey *ecdsa.PrivateKey) (*x509.Certificate, *ecdsa.PrivateKey) {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	serial, _ := rand.Int(rand.Reader, big.NewInt(1<<62))
+	template := &x509.Certificate{
+		SerialNumber:          serial,
+		Subject:               pkix.Name{CommonName: cn},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(24 * time.Hour),
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+		KeyUsage:              x509.KeyUsageCertSign,
+		IssuingCertificateURL: aia,
+	}
+	if parent == nil {
+		parent, parentKey = template, key
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, parent, &key.PublicKey, parentKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return cert, key
+}
+
+func TestCompleteChains(t *testing.T) {
+	var fetches atomic.Int64
+	mux := http.NewServeMux()
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	root, rootKey := testAIACertificate(t, "Test Root CA", nil, nil, nil)
+	intermediate, intermediateKey := testAIACertificate(t, "Test Policy CA", []string{server.URL + "/root.crt"}, root, rootKey)
+	issuing, issuingKey := testAIACertificate(t, "Test Issuing CA", []string{"ldap:///CN=Test%20Policy%20CA,CN=AIA", server.URL + "/policy.p7c"}, intermediate, intermediateKey)
+	leaf, _ := testAIACertificate(t, "app.example.com", []string{server.URL + "/missing.crt"}, issuing, issuingKey)
+
+	mux.HandleFunc("/root.crt", func(w http.ResponseWriter, r *http.Request) {
+		fetches.Add(1)
+		_ = pem.Encode(w, &pem.Block{Type: "CERTIFICATE", Bytes: root.Raw})
+	})
+	mux.HandleFunc("/policy.p7c", func(w http.ResponseWriter, r *http.Request) {
+		fetches.Add(1)
+		p7c, _ := marshalTestPKCS7(root, intermediate)
+		_, _ = w.Write(p7c)
+	})
+
+	// The CA returns the issuing CA without the policy CA and root above it.
+	certificates := &client.Certificates{
+		ID:                  "42",
+		CertificateB64:      encodePEM(leaf),
+		CertificateChainB64: string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: testPKCS7(t, leaf, issuing)})),
+	}
+	material, err := parseCertificateMaterial(certificates)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := material.completeChains(context.Background()); err != nil || len(material.chain) != 1 {
+		t.Fatalf("completeChains() without a fetcher = %v, changed the chain to %d certificates", err, len(material.chain))
+	}
+
+	ctx := withAIAFetcher(context.Background(), newAIAFetcher())
+	for i := 0; i < 2; i++ {
+		material, _ := parseCertificateMaterial(certificates)
+		if err := material.completeChains(ctx); err != nil {
+			t.Fatalf("completeChains() = %v", err)
+		}
+		if len(material.chain) != 3 || !material.chain[1].Equal(intermediate) || !material.chain[2].Equal(root) {
+			t.Errorf("completeChains() built a chain of %d certificates, want issuing, policy and root CA", len(material.chain))
+		}
+	}
+	if fetches.Load() != 2 {
+		t.Errorf("fetched %d times, want each AIA URL once", fetches.Load())
+	}
+
+	// A chain that ends at an issuer without an http AIA URL is left as it is.
+	other, otherKey := testAIACertificate(t, "Other CA", []string{"ldap:///CN=Other%20Root,CN=AIA"}, intermediate, intermediateKey)
+	otherLeaf, _ := testAIACertificate(t, "other.example.com", nil, other, otherKey)
+	noHTTP := &client.Certificates{
+		ID:                  "43",
+		CertificateB64:      encodePEM(otherLeaf),
+		CertificateChainB64: string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: testPKCS7(t, otherLeaf, other)})),
+	}
+	material, _ = parseCertificateMaterial(noHTTP)
+	if err := material.completeChains(ctx); err != nil || len(material.chain) != 1 {
+		t.Errorf("completeChains() without http AIA URLs = %v, changed the chain to %d certificates", err, len(material.chain))
+	}
+
+	// Issuers that cannot be fetched make setCertificateOutputs warn and keep what was found.
+	stray, strayKey := testAIACertificate(t, "Stray CA", []string{server.URL + "/gone.crt"}, intermediate, intermediateKey)
+	strayLeaf, _ := testAIACertificate(t, "stray.example.com", nil, stray, strayKey)
+	model := certificateCreateModel{PKCS12B64: types.StringNull()}
+	diags := model.setCertificateOutputs(ctx, &client.Certificates{
+		ID:                  "44",
+		CertificateB64:      encodePEM(strayLeaf),
+		CertificateChainB64: string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: testPKCS7(t, strayLeaf, stray)})),
+	})
+	if diags.HasError() || diags.WarningsCount() != 1 || !strings.Contains(diags.Warnings()[0].Detail(), "status 404") {
+		t.Errorf("setCertificateOutputs() = %v, want a warning about the missing issuer", diags)
+	}
+	if model.IssuingCAPEM.ValueString() != encodePEM(stray) || !model.RootCAPEM.IsNull() {
+		t.Errorf("setCertificateOutputs() did not keep the chain the CA returned")
+	}
+}
+
+func TestParseAIACertificates(t *testing.T) {
+	h := newTestHierarchy(t)
+	for name, body := range map[string][]byte{
+		"der":       h.issuing.Raw,
+		"pem":       pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: h.issuing.Raw}),
+		"p7c":       testPKCS7(t, h.root, h.issuing),
+		"p7c pem":   pem.EncodeToMemory(&pem.Block{Type: "PKCS7", Bytes: testPKCS7(t, h.issuing)}),
+		"malformed": []byte("<html>Not Found</html>"),
+	} {
+		certs, err := parseAIACertificates(body)
+		if name == "malformed" {
+			if err == nil {
+				t.Errorf("%s: parseAIACertificates() = %d certificates, want an error", name, len(certs))
+			}
+			continue
+		}
+		if err != nil || !containsCertificate(certs, h.issuing) {
+			t.Errorf("%s: parseAIACertificates() = %v, want the issuing CA", name, err)
+		}
+	}
+}
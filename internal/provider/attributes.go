@@ -0,0 +1,132 @@
+package provider
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/hashicorp/terraform-plugin-framework/diag"
+	"github.com/hashicorp/terraform-plugin-framework/path"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+)
+
+// reservedAttributes are request attributes the resource sets itself, keyed by lower-case name,
+// with the argument that controls them.
+var reservedAttributes = map[string]string{
+	"certificatetemplate":                  "template",
+	strings.ToLower(requestNonceAttribute): "request_nonce",
+}
+
+// mapAttributes turns attributes_map into "name:value" request attributes, sorted by name so the
+// submission does not depend on map iteration order.
+func mapAttributes(ctx context.Context, attributes types.Map) ([]string, diag.Diagnostics) {
+	var diags diag.Diagnostics
+	if attributes.IsNull() || attributes.IsUnknown() {
+		return nil, diags
+	}
+
+	var values map[string]string
+	diags.Append(attributes.ElementsAs(ctx, &values, false)...)
+	if diags.HasError() {
+		return nil, diags
+	}
+
+	names := make([]string, 0, len(values))
+	for name := range values {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	result := make([]string, 0, len(names))
+	for _, name := range names {
+		result = append(result, name+":"+values[name])
+	}
+	return result, diags
+}
+
+// attributeName returns the lower-case name of a "name:value" request attribute.
+func attributeName(attribute string) string {
+	name, _, _ := strings.Cut(attribute, ":")
+	return strings.ToLower(strings.TrimSpace(name))
+}
+
+// validateAttributesMap checks that every entry of attributes_map becomes exactly one request
+// attribute: names cannot hold the ":" separating them from the value, values cannot span lines,
+// and no attribute may be given twice or override one the resource sets itself.
+func validateAttributesMap(ctx context.Context, config certificateCreateModel) diag.Diagnostics {
+	var diags diag.Diagnostics
+	if config.AttributesMap.IsNull() || config.AttributesMap.IsUnknown() {
+		return diags
+	}
+
+	seen := map[string]string{}
+	if attributes := config.requestAttributes(); !attributes.IsUnknown() {
+		for _, attribute := range splitAttributes(attributes.ValueString()) {
+			seen[attributeName(attribute)] = "request_attributes"
+		}
+	}
+
+	names := make([]string, 0, len(config.AttributesMap.Elements()))
+	for name := range config.AttributesMap.Elements() {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		attributePath := path.Root("attributes_map").AtMapKey(name)
+		if strings.TrimSpace(name) == "" || strings.ContainsAny(name, ":\r\n") {
+			diags.AddAttributeError(attributePath, "Invalid Request Attribute Name",
+				fmt.Sprintf("%q is not a request attribute name, names cannot be empty or contain \":\" or line breaks.", name))
+			continue
+		}
+		if value, ok := config.AttributesMap.Elements()[name].(types.String); ok && strings.ContainsAny(value.ValueString(), "\r\n") {
+			diags.AddAttributeError(attributePath, "Invalid Request Attribute Value",
+				fmt.Sprintf("The value of %s spans several lines, request attribute values have to fit on one line.", name))
+		}
+
+		key := strings.ToLower(strings.TrimSpace(name))
+		if argument, ok := reservedAttributes[key]; ok {
+			diags.AddAttributeError(attributePath, "Reserved Request Attribute",
+				fmt.Sprintf("The %s request attribute is set through %s.", name, argument))
+			continue
+		}
+		if key == "san" && !config.subjectAlternativeNames().IsNull() {
+			diags.AddAttributeError(attributePath, "Conflicting Subject Alternative Names",
+				"Set subject alternative names either in the san entry of attributes_map or in the subject alternative names of the resource.")
+			continue
+		}
+		if key == strings.ToLower(expirationDateAttribute) && !config.RequestedNotAfter.IsNull() {
+			diags.AddAttributeError(attributePath, "Conflicting Requested Validity",
+				"Set the expiration date either in the ExpirationDate entry of attributes_map or in requested_not_after.")
+			continue
+		}
+		if (key == strings.ToLower(validityPeriodAttribute) || key == strings.ToLower(validityPeriodUnitsAttribute)) && !config.ValidityPeriod.IsNull() {
+			diags.AddAttributeError(attributePath, "Conflicting Requested Validity",
+				fmt.Sprintf("Set the validity period either in the %s entry of attributes_map or in validity_period and validity_period_units.", name))
+			continue
+		}
+		if key == strings.ToLower(requesterNameAttribute) && !config.RequesterName.IsNull() {
+			diags.AddAttributeError(attributePath, "Conflicting Requester Name",
+				"Set the requester either in the RequesterName entry of attributes_map or in requester_name.")
+			continue
+		}
+		if key == strings.ToLower(friendlyNameAttribute) && !config.FriendlyName.IsNull() {
+			diags.AddAttributeError(attributePath, "Conflicting Friendly Name",
+				"Set the friendly name either in the FriendlyName entry of attributes_map or in friendly_name.")
+			continue
+		}
+		if key == strings.ToLower(certificateDescriptionAttribute) && !config.CertificateDescription.IsNull() {
+			diags.AddAttributeError(attributePath, "Conflicting Certificate Description",
+				"Set the description either in the CertificateDescription entry of attributes_map or in certificate_description.")
+			continue
+		}
+		if source, ok := seen[key]; ok {
+			diags.AddAttributeError(attributePath, "Conflicting Request Attributes",
+				fmt.Sprintf("The %s request attribute is also set in %s.", name, source))
+			continue
+		}
+		seen[key] = "attributes_map"
+	}
+	return diags
+}
@@ -0,0 +1,134 @@
+package provider
+
+import (
+	"context"
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-framework/attr"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+)
+
+// testAttributesMap builds an attributes_map value.
+func testAttributesMap(entries map[string]string) types.Map {
+	values := map[string]attr.Value{}
+	for name, value := range entries {
+		values[name] = types.StringValue(value)
+	}
+	return types.MapValueMust(types.StringType, values)
+}
+
+func TestSubmittedAttributesWithMap(t *testing.T) {
+	plan := certificateCreateModel{
+		Attributes:        types.StringNull(),
+		RequestAttributes: types.StringValue("Owner:platform"),
+		AttributesMap: testAttributesMap(map[string]string{
+			"ValidityPeriodUnits": "1",
+			"ValidityPeriod":      "Years",
+		}),
+		SubjectAlternativeNames: testSANBlock(t, map[string][]string{"dns": {"www.example.com"}}),
+	}
+
+	got, diags := plan.submittedAttributes(context.Background())
+	if diags.HasError() {
+		t.Fatalf("unexpected diagnostics: %v", diags)
+	}
+	want := "Owner:platform\nValidityPeriod:Years\nValidityPeriodUnits:1\nsan:dns=www.example.com"
+	if got != want {
+		t.Errorf("submittedAttributes() = %q, want %q", got, want)
+	}
+}
+
+func TestValidateAttributesMap(t *testing.T) {
+	cases := map[string]struct {
+		entries    map[string]string
+		attributes string
+		sanBlock   bool
+		notAfter   string
+		period     string
+		requester  string
+		friendly   string
+		described  string
+		errors     int
+	}{
+		"valid": {
+			entries:    map[string]string{"ValidityPeriod": "Years", "san": "dns=www.example.com"},
+			attributes: "Owner:platform",
+		},
+		"malformed": {
+			entries: map[string]string{"Owner:Team": "platform", "Notes": "line one\nline two", " ": "empty"},
+			errors:  3,
+		},
+		"reserved": {
+			entries: map[string]string{"CertificateTemplate": "WebServer", "clientrequestnonce": "1"},
+			errors:  2,
+		},
+		"repeats request_attributes": {
+			entries:    map[string]string{"owner": "platform"},
+			attributes: "Owner:security",
+			errors:     1,
+		},
+		"san with subject_alternative_names": {
+			entries:  map[string]string{"SAN": "dns=www.example.com"},
+			sanBlock: true,
+			errors:   1,
+		},
+		"expiration date with requested_not_after": {
+			entries:  map[string]string{"ExpirationDate": "Tue, 01 Jan 2030 00:00:00 GMT"},
+			notAfter: "2030-01-01T00:00:00Z",
+			errors:   1,
+		},
+		"validity period with validity_period": {
+			entries: map[string]string{"ValidityPeriod": "Years", "ValidityPeriodUnits": "1"},
+			period:  "Years",
+			errors:  2,
+		},
+		"requester name with requester_name": {
+			entries:   map[string]string{"RequesterName": `EXAMPLE\jdoe`},
+			requester: `EXAMPLE\jdoe`,
+			errors:    1,
+		},
+		"friendly name with friendly_name": {
+			entries:  map[string]string{"FriendlyName": "web"},
+			friendly: "web-tls",
+			errors:   1,
+		},
+		"description with certificate_description": {
+			entries:   map[string]string{"CertificateDescription": "web"},
+			described: "web-tls",
+			errors:    1,
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			config := certificateCreateModel{
+				Attributes:              types.StringNull(),
+				RequestAttributes:       types.StringValue(tc.attributes),
+				AttributesMap:           testAttributesMap(tc.entries),
+				SubjectAlternativeNames: types.ObjectNull(testSANBlock(t, nil).AttributeTypes(context.Background())),
+			}
+			if tc.notAfter != "" {
+				config.RequestedNotAfter = types.StringValue(tc.notAfter)
+			}
+			if tc.period != "" {
+				config.ValidityPeriod = types.StringValue(tc.period)
+				config.ValidityPeriodUnits = types.Int64Value(1)
+			}
+			if tc.requester != "" {
+				config.RequesterName = types.StringValue(tc.requester)
+			}
+			if tc.friendly != "" {
+				config.FriendlyName = types.StringValue(tc.friendly)
+			}
+			if tc.described != "" {
+				config.CertificateDescription = types.StringValue(tc.described)
+			}
+			if tc.sanBlock {
+				config.SubjectAlternativeNames = testSANBlock(t, map[string][]string{"dns": {"www.example.com"}})
+			}
+			if diags := validateAttributesMap(context.Background(), config); diags.ErrorsCount() != tc.errors {
+				t.Errorf("got %d errors, want %d: %v", diags.ErrorsCount(), tc.errors, diags)
+			}
+		})
+	}
+}
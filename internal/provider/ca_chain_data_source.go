@@ -0,0 +1,153 @@
+package provider
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+
+	"github.com/flipyap/microsoft-adcs-client/client"
+	"github.com/hashicorp/terraform-plugin-framework/datasource"
+	"github.com/hashicorp/terraform-plugin-framework/datasource/schema"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+)
+
+// Ensure the implementation satisfies the expected interfaces.
+var (
+	_ datasource.DataSource              = &caChainDataSource{}
+	_ datasource.DataSourceWithConfigure = &caChainDataSource{}
+)
+
+// NewCAChainDataSource is a helper function to simplify the provider implementation.
+func NewCAChainDataSource() datasource.DataSource {
+	return &caChainDataSource{}
+}
+
+// caChainDataSource is the data source implementation.
+type caChainDataSource struct {
+	client   *client.ADCSClient
+	parser   *certsrvParser
+	provider *providerData
+}
+
+// caChainModel maps the CA certificate chain.
+type caChainModel struct {
+	ID                  types.String `tfsdk:"id"`
+	Renewal             types.Int64  `tfsdk:"renewal"`
+	CertificateChainB64 types.String `tfsdk:"certificate_chain_b64"`
+	CertificatesPEM     types.List   `tfsdk:"certificates_pem"`
+}
+
+// Configure adds the provider configured client to the data source.
+func (d *caChainDataSource) Configure(_ context.Context, req datasource.ConfigureRequest, resp *datasource.ConfigureResponse) {
+	if req.ProviderData == nil {
+		return
+	}
+
+	data, ok := req.ProviderData.(*providerData)
+	if !ok {
+		resp.Diagnostics.AddError(
+			"Unexpected Data Source Configure Type",
+			fmt.Sprintf("Expected *providerData, got: %T. Please report this issue to the provider developers.", req.ProviderData),
+		)
+
+		return
+	}
+
+	d.client = data.client
+	d.parser = data.parser
+	d.provider = data
+}
+
+// Metadata returns the data source type name.
+func (d *caChainDataSource) Metadata(_ context.Context, req datasource.MetadataRequest, resp *datasource.MetadataResponse) {
+	resp.TypeName = req.ProviderTypeName + "_ca_chain"
+}
+
+// Schema defines the schema for the data source.
+func (d *caChainDataSource) Schema(_ context.Context, _ datasource.SchemaRequest, resp *datasource.SchemaResponse) {
+	resp.Schema = schema.Schema{
+		Description: "Retrieves the certificate chain of the CA behind the certsrv web enrollment pages.",
+		Attributes: map[string]schema.Attribute{
+			"id": schema.StringAttribute{
+				Computed:    true,
+				Description: "Renewal index of the CA certificate that was retrieved.",
+			},
+			"renewal": schema.Int64Attribute{
+				Optional: true,
+				Computed: true,
+				Description: `Renewal index of the CA certificate, 0 being the original CA certificate. Defaults to the
+current CA certificate.`,
+			},
+			"certificate_chain_b64": schema.StringAttribute{
+				Computed:    true,
+				Description: "The CA certificate chain returned from ADCS as base64 encoded.",
+			},
+			"certificates_pem": schema.ListAttribute{
+				ElementType: types.StringType,
+				Computed:    true,
+				Description: "PEM encoded certificates of the chain, starting with the CA certificate and ending with the root.",
+			},
+		},
+	}
+}
+
+// Read refreshes the Terraform state with the latest data.
+func (d *caChainDataSource) Read(ctx context.Context, req datasource.ReadRequest, resp *datasource.ReadResponse) {
+	if d.provider.caOffline(ctx) {
+		resp.Diagnostics.Append(applyOnlyError("read the CA certificate chain"))
+		return
+	}
+	var data caChainModel
+	resp.Diagnostics.Append(req.Config.Get(ctx, &data)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	renewal := int64(-1)
+	if !data.Renewal.IsNull() {
+		renewal = data.Renewal.ValueInt64()
+	}
+
+	chainB64, renewal, err := retrieveCAChain(ctx, d.client, d.parser, renewal)
+	if err != nil && !isTransient(err) {
+		if unsupported := d.provider.unsupportedFeature(ctx, featureRenewalIndex); unsupported != nil {
+			resp.Diagnostics.AddError("Not Supported on This ADCS Version", unsupported.Error()+": "+err.Error())
+			return
+		}
+	}
+	if err != nil {
+		resp.Diagnostics.AddError(classifiedSummary("Unable to Read CA Certificate Chain", err), err.Error())
+		return
+	}
+
+	certs, err := parseCertificateChain(chainB64)
+	if err != nil {
+		resp.Diagnostics.AddError(classifiedSummary("Unable to Parse CA Certificate Chain", &ParseError{Err: err}), err.Error())
+		return
+	}
+
+	// The chain is not ordered, walk it from the CA certificate, the one no other certificate was issued by.
+	var pems []string
+	for _, cert := range certs {
+		if isIssuerOfAny(cert, certs) {
+			continue
+		}
+		pems = append(pems, encodePEM(cert))
+		for _, issuer := range buildChains(cert, certs)[0] {
+			pems = append(pems, encodePEM(issuer))
+		}
+		break
+	}
+
+	data.ID = types.StringValue(strconv.FormatInt(renewal, 10))
+	data.Renewal = types.Int64Value(renewal)
+	data.CertificateChainB64 = types.StringValue(normalizePEM(chainB64))
+	certificatesPEM, diags := types.ListValueFrom(ctx, types.StringType, pems)
+	resp.Diagnostics.Append(diags...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+	data.CertificatesPEM = certificatesPEM
+
+	resp.Diagnostics.Append(resp.State.Set(ctx, &data)...)
+}
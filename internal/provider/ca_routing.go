@@ -0,0 +1,132 @@
+package provider
+
+import (
+	"context"
+	"html"
+	"strings"
+
+	"github.com/flipyap/microsoft-adcs-client/client"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+)
+
+// caRouteKey is the context key of the caRoute certsrv requests are sent along.
+type caRouteKey struct{}
+
+// caRoute pins the certsrv requests for a certificate to the CA that took its request. When several
+// issuing CAs answer under one host name, request IDs are only unique per CA, so downloads and
+// polls for a request have to reach the CA that holds it instead of whichever one the load
+// balancer picks.
+type caRoute struct {
+	// hosts maps lower-cased CA names to the host of their own certsrv server, see ca_hosts.
+	hosts map[string]string
+	// caName is the CA that took the request, empty until a submission was answered.
+	caName string
+	// endpoint is the certsrv server of the resource, see the host attribute of
+	// microsoftadcs_certificate, empty for the configured host.
+	endpoint string
+	// client authenticates the requests with the credentials of the resource, nil for those of the
+	// provider.
+	client *client.ADCSClient
+}
+
+// withCARoute sends the certsrv requests made with ctx along route.
+func withCARoute(ctx context.Context, route *caRoute) context.Context {
+	return context.WithValue(ctx, caRouteKey{}, route)
+}
+
+// caRouteFrom returns the route of ctx, nil when the requests are not routed.
+func caRouteFrom(ctx context.Context) *caRoute {
+	route, _ := ctx.Value(caRouteKey{}).(*caRoute)
+	return route
+}
+
+// host returns the certsrv server of the CA, or the endpoint of the resource when the CA is unknown
+// or has no entry in ca_hosts. It is empty when neither is set.
+func (r *caRoute) host() string {
+	if r == nil {
+		return ""
+	}
+	if host := r.hosts[strings.ToLower(r.caName)]; r.caName != "" && host != "" {
+		return host
+	}
+	return r.endpoint
+}
+
+// record takes the CA name from the banner of a certsrv page, which names the CA that served it.
+// The first page answered wins, later requests already go to that CA.
+func (r *caRoute) record(page string) {
+	if r == nil || r.caName != "" {
+		return
+	}
+	if match := caNameRegex.FindStringSubmatch(page); match != nil {
+		r.caName = strings.TrimSpace(html.UnescapeString(match[1]))
+	}
+}
+
+// caNameValue returns the CA name of the route for the ca_name attribute.
+func (r *caRoute) caNameValue() types.String {
+	if r == nil || r.caName == "" {
+		return types.StringNull()
+	}
+	return types.StringValue(r.caName)
+}
+
+// certsrvHost returns the host certsrv requests made with ctx go to: the server of the CA the
+// request is pinned to, or the configured host.
+func certsrvHost(ctx context.Context, c *client.ADCSClient) string {
+	if host := caRouteFrom(ctx).host(); host != "" {
+		return host
+	}
+	return c.HostURL
+}
+
+// certsrvClient returns the client certsrv requests made with ctx are sent with: the one holding the
+// credentials of the resource, or c.
+func certsrvClient(ctx context.Context, c *client.ADCSClient) *client.ADCSClient {
+	if route := caRouteFrom(ctx); route != nil && route.client != nil {
+		return route.client
+	}
+	return c
+}
+
+// newCARoute returns the route for a request taken by the named CA, or for a new submission when
+// caName is empty.
+func (p *providerData) newCARoute(caName string) *caRoute {
+	return &caRoute{hosts: p.caHosts, caName: caName}
+}
+
+// pollerKey identifies the poller of a CA server and the credentials it is polled with.
+type pollerKey struct {
+	host   string
+	client *client.ADCSClient
+}
+
+// pollerFor returns the poller that waits on pending requests of the CA of route. Every CA server
+// gets a poller of its own, as request IDs of different CAs overlap, and so does every set of
+// credentials resources reach it with.
+func (p *providerData) pollerFor(route *caRoute) *pendingPoller {
+	key := pollerKey{host: route.host()}
+	if route != nil {
+		key.client = route.client
+	}
+	if key == (pollerKey{}) {
+		return p.poller
+	}
+
+	p.pollersMu.Lock()
+	defer p.pollersMu.Unlock()
+	if poller, ok := p.pollers[key]; ok {
+		return poller
+	}
+	if p.pollers == nil {
+		p.pollers = map[pollerKey]*pendingPoller{}
+	}
+	poller := newPendingPoller(p.client, p.parser)
+	check := poller.check
+	pinned := &caRoute{hosts: p.caHosts, caName: route.caName, endpoint: route.endpoint, client: route.client}
+	poller.check = func(ctx context.Context, reqID string) (*client.Certificates, error) {
+		return check(withCARoute(ctx, pinned), reqID)
+	}
+	p.pollers[key] = poller
+	return poller
+}
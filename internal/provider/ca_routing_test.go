@@ -0,0 +1,134 @@
+package provider
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/http/httputil"
+	"net/url"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-framework/diag"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+)
+
+func TestCARouteRecord(t *testing.T) {
+	route := &caRoute{hosts: map[string]string{"corp issuing ca 1": "ca1.example.com"}}
+	if route.host() != "" || !route.caNameValue().IsNull() {
+		t.Fatalf("a route without a CA name should not pin requests: %q", route.host())
+	}
+
+	route.record(`<TD><Font color=#ffffff><LocID ID=locMSCertSrv>Microsoft</LocID> Active Directory Certificate Services &nbsp;--&nbsp; Corp Issuing CA 1&nbsp;</Font></TD>`)
+	if route.caName != "Corp Issuing CA 1" || route.host() != "ca1.example.com" {
+		t.Fatalf("record() = %q, host %q", route.caName, route.host())
+	}
+	route.record(`Active Directory Certificate Services -- Corp Issuing CA 2<`)
+	if route.caName != "Corp Issuing CA 1" {
+		t.Errorf("a later page changed the CA name to %q", route.caName)
+	}
+
+	unlisted := &caRoute{hosts: route.hosts, caName: "Corp Issuing CA 3"}
+	if unlisted.host() != "" {
+		t.Errorf("a CA without an entry in ca_hosts should not be pinned, got %q", unlisted.host())
+	}
+	var unrouted *caRoute
+	if unrouted.host() != "" || caRouteFrom(context.Background()) != nil {
+		t.Error("requests without a route should go to the configured host")
+	}
+}
+
+// newTestCACluster puts two acceptance CAs behind one host name: submissions reach the first CA and
+// carry its banner, everything else lands on the second one, which knows none of the requests.
+func newTestCACluster(t *testing.T) (string, *accCertsrv) {
+	t.Helper()
+	first, second := newAccCertsrv(t), newAccCertsrv(t)
+	proxy := func(srv *accCertsrv) *httputil.ReverseProxy {
+		target, _ := url.Parse(srv.server.URL)
+		return httputil.NewSingleHostReverseProxy(target)
+	}
+	submissions := proxy(first)
+	submissions.ModifyResponse = func(resp *http.Response) error {
+		if resp.StatusCode != http.StatusOK {
+			return nil
+		}
+		body, err := io.ReadAll(resp.Body)
+		if err != nil {
+			return err
+		}
+		body = append([]byte(`<TD><Font color=#ffffff>Microsoft Active Directory Certificate Services &nbsp;--&nbsp; Issuing CA 1&nbsp;</Font></TD>`), body...)
+		resp.Body = io.NopCloser(bytes.NewReader(body))
+		resp.ContentLength = int64(len(body))
+		resp.Header.Set("Content-Length", strconv.Itoa(len(body)))
+		return nil
+	}
+	others := proxy(second)
+
+	balancer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if strings.HasSuffix(r.URL.Path, "/certfnsh.asp") {
+			submissions.ServeHTTP(w, r)
+			return
+		}
+		others.ServeHTTP(w, r)
+	}))
+	t.Cleanup(balancer.Close)
+	return strings.TrimPrefix(balancer.URL, "http://"), first
+}
+
+func TestCARouting(t *testing.T) {
+	host, first := newTestCACluster(t)
+	t.Setenv("ADCS_PASSWORD", "secret")
+
+	for _, pinned := range []bool{false, true} {
+		t.Run(fmt.Sprintf("pinned=%t", pinned), func(t *testing.T) {
+			data, err := debugEnrollConfigure(context.Background(), "test", map[string]interface{}{
+				"host":     host,
+				"username": "svc-terraform",
+				"use_ntlm": true,
+			}, io.Discard)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if pinned {
+				data.caHosts = map[string]string{"issuing ca 1": first.host()}
+			}
+			r := &certificateResource{client: data.client, provider: data}
+
+			csr, err := debugEnrollRequest(context.Background(), "", "app.example.com")
+			if err != nil {
+				t.Fatal(err)
+			}
+			plan := &certificateCreateModel{CSR: types.StringValue(csr), Template: types.StringValue("WebServer")}
+			var diags diag.Diagnostics
+			certificates := r.requestCertificate(context.Background(), plan, csr, "", &diags)
+			if plan.CAName.ValueString() != "Issuing CA 1" {
+				t.Errorf("ca_name = %q, want the CA from the banner", plan.CAName.ValueString())
+			}
+			if !pinned {
+				if !diags.HasError() {
+					t.Fatal("the download should land on the CA that does not know the request")
+				}
+				return
+			}
+			if diags.HasError() {
+				t.Fatalf("unexpected diagnostics: %v", diags)
+			}
+			if want := first.request(certificates.ID).cert; want == nil {
+				t.Fatalf("request ID %s is not in the database of the first CA", certificates.ID)
+			}
+
+			// Polls for the CA share a poller of their own.
+			route := data.newCARoute(plan.CAName.ValueString())
+			if poller := data.pollerFor(route); poller == data.poller || poller != data.pollerFor(route) {
+				t.Error("requests of a pinned CA should share a poller of their own")
+			}
+			if _, err := data.pollerFor(route).check(context.Background(), certificates.ID); err != nil {
+				t.Errorf("the poller of the CA could not download request ID %s: %v", certificates.ID, err)
+			}
+		})
+	}
+}
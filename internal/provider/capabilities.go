@@ -0,0 +1,108 @@
+package provider
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/flipyap/microsoft-adcs-client/client"
+)
+
+var (
+	// caNameRegex reads the CA name from the banner of the certsrv pages, "Microsoft Active Directory
+	// Certificate Services -- COMPANY-CA".
+	caNameRegex = regexp.MustCompile(`(?i)Active Directory Certificate Services(?:\s|&nbsp;|</?[a-z][^>]*>)*--(?:\s|&nbsp;)*([^<&\r\n]+?)(?:\s|&nbsp;)*<`)
+	// iisVersionRegex reads the IIS version from the Server header, such as "Microsoft-IIS/10.0".
+	iisVersionRegex = regexp.MustCompile(`Microsoft-IIS/(\d+\.\d+)`)
+	// statusErrorRegex reads the HTTP status the client reports for responses other than 200.
+	statusErrorRegex = regexp.MustCompile(`status error: (\d+)`)
+)
+
+// cesAuthenticationTypes are the suffixes of the virtual directories the Certificate Enrollment
+// Web Service installs, one per authentication type.
+var cesAuthenticationTypes = []string{"Kerberos", "UsernamePassword", "Certificate"}
+
+// caCapabilities is what the provider can tell about the CA from the outside.
+type caCapabilities struct {
+	// CAName is read from the certsrv banner.
+	CAName string
+	// Server is the Server header of the web enrollment pages and IISVersion the version in it.
+	Server     string
+	IISVersion string
+	// Version is the release of the CA host the IIS version maps to.
+	Version adcsVersion
+	// WSTEP reports whether the Certificate Enrollment Web Service of the CA is installed.
+	WSTEP bool
+}
+
+// detectCACapabilities reads the certsrv welcome page and probes for the Certificate Enrollment
+// Web Service, which lives next to certsrv in virtual directories named after the CA.
+func detectCACapabilities(ctx context.Context, c *client.ADCSClient) (*caCapabilities, error) {
+	header, body, err := getCertsrvPage(ctx, c, "/certsrv/")
+	if err != nil {
+		return nil, fmt.Errorf("could not read the web enrollment pages: %v", err)
+	}
+
+	capabilities := &caCapabilities{Server: header.Get("Server")}
+	if match := iisVersionRegex.FindStringSubmatch(capabilities.Server); match != nil {
+		capabilities.IISVersion = match[1]
+	}
+	capabilities.Version = versionFromServerHeader(capabilities.Server)
+	if match := caNameRegex.FindStringSubmatch(body); match != nil {
+		capabilities.CAName = strings.TrimSpace(match[1])
+	}
+
+	if capabilities.CAName != "" && capabilities.Version.supports(featureCES) {
+		for _, authentication := range cesAuthenticationTypes {
+			_, _, err := getCertsrvPage(ctx, c, "/"+url.PathEscape(capabilities.CAName+"_CES_"+authentication)+"/service.svc")
+			status := responseStatus(err)
+			if err != nil && status == 0 {
+				return nil, fmt.Errorf("could not probe the Certificate Enrollment Web Service: %v", err)
+			}
+			// The service answers requests without TLS or the right credentials with an error, but
+			// not with a 404.
+			if status != http.StatusNotFound {
+				capabilities.WSTEP = true
+				break
+			}
+		}
+	}
+	return capabilities, nil
+}
+
+// responseStatus returns the HTTP status of a failed request, 0 when it did not get a response.
+func responseStatus(err error) int {
+	if err == nil {
+		return 0
+	}
+	match := statusErrorRegex.FindStringSubmatch(err.Error())
+	if match == nil {
+		return 0
+	}
+	status, _ := strconv.Atoi(match[1])
+	return status
+}
+
+// getCertsrvPage requests a page of the ADCS host and returns its headers and body.
+func getCertsrvPage(ctx context.Context, c *client.ADCSClient, path string) (http.Header, string, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, "http://"+c.HostURL+path, nil)
+	if err != nil {
+		return nil, "", fmt.Errorf("could not create request: %v", err)
+	}
+	resp, err := c.DoRequest(req)
+	if err != nil {
+		return nil, "", err
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, "", fmt.Errorf("error reading response body: %v", err)
+	}
+	return resp.Header, string(body), nil
+}
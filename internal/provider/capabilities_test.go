@@ -0,0 +1,70 @@
+package provider
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/flipyap/microsoft-adcs-client/client"
+)
+
+func TestDetectCACapabilities(t *testing.T) {
+	cases := map[string]struct {
+		server string
+		banner string
+		ces    bool
+		want   caCapabilities
+	}{
+		"stock certsrv with CES": {
+			server: "Microsoft-IIS/10.0",
+			banner: `<Font color=#ffffff><LocID ID=locMSCertSrv>Microsoft</LocID> Active Directory Certificate Services &nbsp;--&nbsp; Company Issuing CA&nbsp;</Font>`,
+			ces:    true,
+			want:   caCapabilities{CAName: "Company Issuing CA", Server: "Microsoft-IIS/10.0", IISVersion: "10.0", Version: adcsVersion2016, WSTEP: true},
+		},
+		"2008 R2 without CES": {
+			server: "Microsoft-IIS/7.5",
+			banner: `<Font>Microsoft Active Directory Certificate Services  --  COMPANY-CA</Font>`,
+			want:   caCapabilities{CAName: "COMPANY-CA", Server: "Microsoft-IIS/7.5", IISVersion: "7.5", Version: adcsVersion2008R2},
+		},
+		"2003 predates CES": {
+			server: "Microsoft-IIS/6.0",
+			banner: `<Font>Microsoft Certificate Services  --  LEGACY-CA</Font>`,
+			ces:    true,
+			want:   caCapabilities{Server: "Microsoft-IIS/6.0", IISVersion: "6.0", Version: adcsVersion2003},
+		},
+		"customized portal behind a proxy": {
+			server: "nginx",
+			banner: `<h1>Certificate portal</h1>`,
+			want:   caCapabilities{Server: "nginx"},
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("Server", tc.server)
+				switch {
+				case r.URL.Path == "/certsrv/":
+					fmt.Fprint(w, tc.banner)
+				case tc.ces && strings.HasSuffix(r.URL.Path, "_CES_Kerberos/service.svc"):
+					http.Error(w, "403.4 SSL required", http.StatusForbidden)
+				default:
+					http.NotFound(w, r)
+				}
+			}))
+			defer server.Close()
+
+			c := &client.ADCSClient{HostURL: strings.TrimPrefix(server.URL, "http://"), NtlmClient: server.Client(), UseNtlm: true}
+			got, err := detectCACapabilities(context.Background(), c)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if *got != tc.want {
+				t.Errorf("capabilities = %+v, want %+v", *got, tc.want)
+			}
+		})
+	}
+}
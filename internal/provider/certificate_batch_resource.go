@@ -0,0 +1,746 @@
+package provider
+
+import (
+	"context"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/flipyap/microsoft-adcs-client/client"
+	"github.com/hashicorp/terraform-plugin-framework/attr"
+	"github.com/hashicorp/terraform-plugin-framework/diag"
+	"github.com/hashicorp/terraform-plugin-framework/path"
+	"github.com/hashicorp/terraform-plugin-framework/resource"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/int64planmodifier"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/planmodifier"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/stringplanmodifier"
+	"github.com/hashicorp/terraform-plugin-framework/schema/validator"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+	"github.com/hashicorp/terraform-plugin-log/tflog"
+)
+
+// dispositionFailed is the disposition of a batch entry the CA never answered for, because it could
+// not be reached or the key could not be generated. The next apply submits the entry again.
+const dispositionFailed = "failed"
+
+// Ensure the implementation satisfies the expected interfaces.
+var (
+	_ resource.Resource                   = &certificateBatchResource{}
+	_ resource.ResourceWithConfigure      = &certificateBatchResource{}
+	_ resource.ResourceWithModifyPlan     = &certificateBatchResource{}
+	_ resource.ResourceWithValidateConfig = &certificateBatchResource{}
+)
+
+// NewCertificateBatchResource is a helper function to simplify the provider implementation.
+func NewCertificateBatchResource() resource.Resource {
+	return &certificateBatchResource{}
+}
+
+// certificateBatchResource issues a map of certificates from one template. Entries are submitted
+// in parallel over the pooled connections, the CA chain is downloaded once for all of them and an
+// entry the CA denied or never answered for is reported on its own instead of failing the batch.
+type certificateBatchResource struct {
+	client   *client.ADCSClient
+	provider *providerData
+}
+
+type certificateBatchModel struct {
+	ID                types.String `tfsdk:"id"`
+	Template          types.String `tfsdk:"template"`
+	RequestAttributes types.String `tfsdk:"request_attributes"`
+	KeyAlgorithm      types.String `tfsdk:"key_algorithm"`
+	RSABits           types.Int64  `tfsdk:"rsa_bits"`
+	ECDSACurve        types.String `tfsdk:"ecdsa_curve"`
+	Parallelism       types.Int64  `tfsdk:"parallelism"`
+	Certificates      types.Map    `tfsdk:"certificates"`
+	ChainPEM          types.String `tfsdk:"chain_pem"`
+	Failed            types.List   `tfsdk:"failed"`
+}
+
+// certificateBatchEntryModel maps an entry of certificates.
+type certificateBatchEntryModel struct {
+	CSR                    types.String `tfsdk:"certificate_signing_request"`
+	CommonName             types.String `tfsdk:"common_name"`
+	DNSNames               types.List   `tfsdk:"dns_names"`
+	RequestID              types.String `tfsdk:"request_id"`
+	Disposition            types.String `tfsdk:"disposition"`
+	DispositionMessage     types.String `tfsdk:"disposition_message"`
+	CertificatePEM         types.String `tfsdk:"certificate_pem"`
+	GeneratedPrivateKeyPEM types.String `tfsdk:"generated_private_key_pem"`
+	NotAfter               types.String `tfsdk:"not_after"`
+	CAName                 types.String `tfsdk:"ca_name"`
+}
+
+// certificateBatchEntryAttrTypes are the attribute types of an entry of certificates.
+var certificateBatchEntryAttrTypes = map[string]attr.Type{
+	"certificate_signing_request": types.StringType,
+	"common_name":                 types.StringType,
+	"dns_names":                   types.ListType{ElemType: types.StringType},
+	"request_id":                  types.StringType,
+	"disposition":                 types.StringType,
+	"disposition_message":         types.StringType,
+	"certificate_pem":             types.StringType,
+	"generated_private_key_pem":   types.StringType,
+	"not_after":                   types.StringType,
+	"ca_name":                     types.StringType,
+}
+
+// Metadata returns the resource type name.
+func (r *certificateBatchResource) Metadata(_ context.Context, req resource.MetadataRequest, resp *resource.MetadataResponse) {
+	resp.TypeName = req.ProviderTypeName + "_certificate_batch"
+}
+
+// Schema defines the schema for the resource.
+func (r *certificateBatchResource) Schema(_ context.Context, _ resource.SchemaRequest, resp *resource.SchemaResponse) {
+	resp.Schema = schema.Schema{
+		Description: `Issues a map of certificates from one template, for fleets too large for one microsoftadcs_certificate per
+host. Entries are submitted in parallel, the CA chain is downloaded once for the whole batch and entries the CA denies or
+never answers for are reported per entry without failing the others.`,
+		Attributes: map[string]schema.Attribute{
+			"id": schema.StringAttribute{
+				Description: "Random identifier of the batch.",
+				Computed:    true,
+				PlanModifiers: []planmodifier.String{
+					stringplanmodifier.UseStateForUnknown(),
+				},
+			},
+			"template": schema.StringAttribute{
+				Required:    true,
+				Description: "Name of the certificate template to request every certificate of the batch from.",
+				PlanModifiers: []planmodifier.String{
+					stringplanmodifier.RequiresReplace(),
+				},
+			},
+			"request_attributes": schema.StringAttribute{
+				Optional:    true,
+				Description: "Extra request attributes sent with every submission, one name:value pair per line.",
+				PlanModifiers: []planmodifier.String{
+					stringplanmodifier.RequiresReplace(),
+				},
+			},
+			"key_algorithm": schema.StringAttribute{
+				Optional:    true,
+				Description: `Algorithm of the keys generated for entries without a certificate_signing_request: "RSA" or "ECDSA". Defaults to "RSA".`,
+				PlanModifiers: []planmodifier.String{
+					stringplanmodifier.RequiresReplace(),
+				},
+			},
+			"rsa_bits": schema.Int64Attribute{
+				Optional:    true,
+				Description: fmt.Sprintf("Size of generated RSA keys: 2048, 3072 or 4096. Defaults to %d.", defaultRSABits),
+				PlanModifiers: []planmodifier.Int64{
+					int64planmodifier.RequiresReplace(),
+				},
+			},
+			"ecdsa_curve": schema.StringAttribute{
+				Optional:    true,
+				Description: fmt.Sprintf(`Curve of generated ECDSA keys: "P256", "P384" or "P521". Defaults to %q.`, defaultECDSACurve),
+				PlanModifiers: []planmodifier.String{
+					stringplanmodifier.RequiresReplace(),
+				},
+			},
+			"parallelism": schema.Int64Attribute{
+				Optional:    true,
+				Description: fmt.Sprintf("How many requests are submitted at the same time, from 1 to %d. Defaults to %d.", ntlmConnections, ntlmConnections),
+			},
+			"certificates": schema.MapNestedAttribute{
+				Required: true,
+				Description: `The certificates of the batch by a name of your choice, such as the host name. Each entry either holds a
+certificate_signing_request or a common_name and dns_names to generate a key and request for. Adding an entry issues only
+that certificate, removing one drops it from state while it stays valid and in the CA database.`,
+				NestedObject: schema.NestedAttributeObject{
+					Attributes: map[string]schema.Attribute{
+						"certificate_signing_request": schema.StringAttribute{
+							Optional:    true,
+							Description: "The certificate signing request to submit. Conflicts with common_name and dns_names.",
+							Validators:  []validator.String{csrSyntaxValidator{}},
+						},
+						"common_name": schema.StringAttribute{
+							Optional:    true,
+							Description: "Common name of the request the provider generates for the entry.",
+						},
+						"dns_names": schema.ListAttribute{
+							Optional:    true,
+							ElementType: types.StringType,
+							Description: "DNS subject alternative names of the request the provider generates for the entry.",
+						},
+						"request_id": schema.StringAttribute{
+							Computed:    true,
+							Description: "Request ID assigned by the CA, null when the submission failed.",
+						},
+						"disposition": schema.StringAttribute{
+							Computed: true,
+							Description: `Outcome of the entry: "issued", "pending" when a CA manager has to approve it, "denied" when the CA
+refused it or "failed" when the CA could not be reached. Pending entries are checked on every refresh, failed entries are
+submitted again on the next apply.`,
+						},
+						"disposition_message": schema.StringAttribute{
+							Computed: true,
+							Description: `Disposition message of the CA, such as "Issued" or "Denied by Policy Module  0x80094800", or the error of an
+entry that failed or whose certificate could not be downloaded yet.`,
+						},
+						"certificate_pem": schema.StringAttribute{
+							Computed:    true,
+							Description: "The issued certificate, PEM encoded. Null until the entry is issued.",
+						},
+						"generated_private_key_pem": schema.StringAttribute{
+							Computed:    true,
+							Sensitive:   true,
+							Description: "PKCS#8 PEM encoded private key generated for entries without a certificate_signing_request.",
+						},
+						"not_after": schema.StringAttribute{
+							Computed:    true,
+							Description: "End of the validity period of the issued certificate, in RFC 3339 format.",
+						},
+						"ca_name": schema.StringAttribute{
+							Computed:    true,
+							Description: "Name of the CA that took the request, see ca_hosts on the provider.",
+						},
+					},
+				},
+			},
+			"chain_pem": schema.StringAttribute{
+				Computed:    true,
+				Description: "The chain of the issuing CA shared by the certificates of the batch, PEM encoded, issuing CA first.",
+			},
+			"failed": schema.ListAttribute{
+				Computed:    true,
+				ElementType: types.StringType,
+				Description: "Names of the entries that were denied or failed, sorted.",
+			},
+		},
+	}
+}
+
+// Configure adds the provider configured client to the resource.
+func (r *certificateBatchResource) Configure(_ context.Context, req resource.ConfigureRequest, resp *resource.ConfigureResponse) {
+	if req.ProviderData == nil {
+		return
+	}
+
+	data, ok := req.ProviderData.(*providerData)
+	if !ok {
+		resp.Diagnostics.AddError(
+			"Unexpected Resource Configure Type",
+			fmt.Sprintf("Expected *providerData, got: %T. Please report this issue to the provider developers.", req.ProviderData),
+		)
+
+		return
+	}
+
+	r.client = data.client
+	r.provider = data
+}
+
+// Create submits every entry of the batch.
+func (r *certificateBatchResource) Create(ctx context.Context, req resource.CreateRequest, resp *resource.CreateResponse) {
+	if r.provider.readOnly {
+		resp.Diagnostics.Append(readOnlyError("issue a certificate batch"))
+		return
+	}
+
+	var plan certificateBatchModel
+	resp.Diagnostics.Append(req.Plan.Get(ctx, &plan)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	id, err := newRequestNonce()
+	if err != nil {
+		resp.Diagnostics.AddError("Error Generating Batch ID", err.Error())
+		return
+	}
+	plan.ID = types.StringValue(id)
+	resp.Diagnostics.Append(r.issue(ctx, &plan)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+	resp.Diagnostics.Append(resp.State.Set(ctx, plan)...)
+}
+
+// Read checks the entries that are still pending, issued and denied entries do not change anymore.
+func (r *certificateBatchResource) Read(ctx context.Context, req resource.ReadRequest, resp *resource.ReadResponse) {
+	// Pending entries are only checked where refreshes can reach the CA.
+	if r.provider.caOffline(ctx) {
+		return
+	}
+	var state certificateBatchModel
+	resp.Diagnostics.Append(req.State.Get(ctx, &state)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+	entries, diags := state.entries(ctx)
+	resp.Diagnostics.Append(diags...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	var names []string
+	for name, entry := range entries {
+		if entry.Disposition.ValueString() == dispositionPending {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	refreshed := make([]certificateBatchEntryModel, len(names))
+	errs := make([]error, len(names))
+	runParallel(int(state.parallelism()), len(names), func(i int) {
+		entry := entries[names[i]]
+		routed := withCARoute(ctx, r.provider.newCARoute(entry.CAName.ValueString()))
+		certificates, err := retrieveCertificate(routed, r.client, r.provider.parser, entry.RequestID.ValueString())
+		if err != nil && (isTransient(err) || isContextError(err)) {
+			errs[i] = err
+			return
+		}
+		entry.setDisposition(certificates, err)
+		refreshed[i] = entry
+	})
+
+	var issued []string
+	for i, name := range names {
+		if errs[i] != nil {
+			resp.Diagnostics.AddAttributeWarning(
+				path.Root("certificates").AtMapKey(name),
+				"Error Reading Certificate Request",
+				fmt.Sprintf("Could not read the disposition of request ID %s, it stays pending: %s", entries[name].RequestID.ValueString(), errs[i].Error()),
+			)
+			continue
+		}
+		entries[name] = refreshed[i]
+		if refreshed[i].Disposition.ValueString() == dispositionIssued {
+			issued = append(issued, name)
+		}
+	}
+	if len(issued) > 0 && state.ChainPEM.IsNull() {
+		state.ChainPEM = r.chainPEM(ctx, &resp.Diagnostics)
+	}
+	r.emitIssued(ctx, state, entries, issued, &resp.Diagnostics)
+	resp.Diagnostics.Append(state.setEntries(ctx, entries)...)
+	resp.Diagnostics.Append(resp.State.Set(ctx, &state)...)
+}
+
+// Update submits the entries that were added or changed and those that failed before.
+func (r *certificateBatchResource) Update(ctx context.Context, req resource.UpdateRequest, resp *resource.UpdateResponse) {
+	var plan certificateBatchModel
+	resp.Diagnostics.Append(req.Plan.Get(ctx, &plan)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+	if !plan.ChainPEM.IsUnknown() {
+		resp.Diagnostics.Append(resp.State.Set(ctx, plan)...)
+		return
+	}
+	if r.provider.readOnly {
+		resp.Diagnostics.Append(readOnlyError("issue a certificate batch"))
+		return
+	}
+
+	resp.Diagnostics.Append(r.issue(ctx, &plan)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+	resp.Diagnostics.Append(resp.State.Set(ctx, plan)...)
+}
+
+// Delete removes the batch from state, the certificates stay valid and in the CA database.
+func (r *certificateBatchResource) Delete(_ context.Context, _ resource.DeleteRequest, resp *resource.DeleteResponse) {
+	if r.provider.readOnly {
+		resp.Diagnostics.Append(readOnlyError("remove a certificate batch from state"))
+	}
+}
+
+// ModifyPlan keeps the outcome of entries that did not change, so only new, changed and failed
+// entries are submitted. New batches have their template checked against the CA.
+func (r *certificateBatchResource) ModifyPlan(ctx context.Context, req resource.ModifyPlanRequest, resp *resource.ModifyPlanResponse) {
+	// Nothing to keep when the batch is created or destroyed.
+	if req.Plan.Raw.IsNull() {
+		return
+	}
+	if req.State.Raw.IsNull() {
+		var template types.String
+		resp.Diagnostics.Append(req.Plan.GetAttribute(ctx, path.Root("template"), &template)...)
+		if r.provider != nil && !r.provider.caOffline(ctx) && !template.IsUnknown() && !resp.Diagnostics.HasError() {
+			r.provider.checkTemplateOffered(ctx, template.ValueString(), &resp.Diagnostics)
+		}
+		return
+	}
+
+	var plan, state certificateBatchModel
+	resp.Diagnostics.Append(req.Plan.Get(ctx, &plan)...)
+	resp.Diagnostics.Append(req.State.Get(ctx, &state)...)
+	if resp.Diagnostics.HasError() || plan.Certificates.IsUnknown() {
+		return
+	}
+	planned, diags := plan.entries(ctx)
+	resp.Diagnostics.Append(diags...)
+	prior, diags := state.entries(ctx)
+	resp.Diagnostics.Append(diags...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	submit := false
+	for name, entry := range planned {
+		previous, ok := prior[name]
+		if ok && previous.Disposition.ValueString() != dispositionFailed && entry.sameRequest(previous) {
+			entry.copyOutputs(previous)
+		} else {
+			entry.unknownOutputs()
+			submit = true
+		}
+		planned[name] = entry
+	}
+	resp.Diagnostics.Append(plan.setEntries(ctx, planned)...)
+	if submit {
+		plan.ChainPEM = types.StringUnknown()
+		plan.Failed = types.ListUnknown(types.StringType)
+	} else {
+		plan.ChainPEM = state.ChainPEM
+	}
+	resp.Diagnostics.Append(resp.Plan.Set(ctx, plan)...)
+}
+
+// ValidateConfig checks that every entry either holds a request or describes one to generate, and
+// that the generated keys are ones ADCS issues certificates for.
+func (r *certificateBatchResource) ValidateConfig(ctx context.Context, req resource.ValidateConfigRequest, resp *resource.ValidateConfigResponse) {
+	var config certificateBatchModel
+	resp.Diagnostics.Append(req.Config.Get(ctx, &config)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	if !config.Parallelism.IsNull() && !config.Parallelism.IsUnknown() {
+		if n := config.Parallelism.ValueInt64(); n < 1 || n > ntlmConnections {
+			resp.Diagnostics.AddAttributeError(
+				path.Root("parallelism"),
+				"Invalid Parallelism",
+				fmt.Sprintf("parallelism must be between 1 and %d, the connections the provider keeps to the CA, got %d.", ntlmConnections, n),
+			)
+		}
+	}
+	key := generateCSRModel{KeyAlgorithm: config.KeyAlgorithm, RSABits: config.RSABits, ECDSACurve: config.ECDSACurve}
+	resp.Diagnostics.Append(key.validateKey(path.Root)...)
+
+	if config.Certificates.IsUnknown() {
+		return
+	}
+	entries, diags := config.entries(ctx)
+	resp.Diagnostics.Append(diags...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+	for name, entry := range entries {
+		resp.Diagnostics.Append(entry.validate(path.Root("certificates").AtMapKey(name))...)
+	}
+}
+
+// issue submits the entries without an outcome, at most parallelism at a time, downloads the chain
+// of the CA once and warns about every entry that was not issued.
+func (r *certificateBatchResource) issue(ctx context.Context, plan *certificateBatchModel) diag.Diagnostics {
+	entries, diags := plan.entries(ctx)
+	if diags.HasError() {
+		return diags
+	}
+
+	var names []string
+	for name, entry := range entries {
+		if entry.Disposition.IsUnknown() || entry.Disposition.IsNull() {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	submitted := make([]certificateBatchEntryModel, len(names))
+	runParallel(int(plan.parallelism()), len(names), func(i int) {
+		submitted[i] = r.submit(ctx, *plan, entries[names[i]])
+	})
+
+	var issued []string
+	for i, name := range names {
+		entry := submitted[i]
+		entries[name] = entry
+		entryPath := path.Root("certificates").AtMapKey(name)
+		switch entry.Disposition.ValueString() {
+		case dispositionIssued:
+			issued = append(issued, name)
+		case dispositionPending:
+			diags.AddAttributeWarning(entryPath, "Certificate Pending Approval",
+				fmt.Sprintf("Request ID %s of %q is pending approval by a CA manager, refreshes pick up the certificate once it is issued.",
+					entry.RequestID.ValueString(), name))
+		case dispositionDenied:
+			diags.AddAttributeWarning(entryPath, "Certificate Request Denied",
+				fmt.Sprintf("The CA denied the request of %q: %s", name, entry.DispositionMessage.ValueString()))
+		default:
+			diags.AddAttributeWarning(entryPath, "Unable to Submit Certificate Request",
+				fmt.Sprintf("The request of %q failed and is submitted again on the next apply: %s", name, entry.DispositionMessage.ValueString()))
+		}
+	}
+	tflog.Info(ctx, "Submitted certificate batch", map[string]interface{}{
+		"submitted": len(names),
+		"issued":    len(issued),
+	})
+
+	plan.ChainPEM = types.StringNull()
+	for _, entry := range entries {
+		if entry.Disposition.ValueString() == dispositionIssued {
+			plan.ChainPEM = r.chainPEM(ctx, &diags)
+			break
+		}
+	}
+	r.emitIssued(ctx, *plan, entries, issued, &diags)
+	diags.Append(plan.setEntries(ctx, entries)...)
+	return diags
+}
+
+// submit generates the request of an entry when needed, submits it and downloads the certificate
+// once it is issued. The chain is left out, the batch downloads it once for every entry.
+func (r *certificateBatchResource) submit(ctx context.Context, plan certificateBatchModel, entry certificateBatchEntryModel) certificateBatchEntryModel {
+	entry.RequestID = types.StringNull()
+	entry.CAName = types.StringNull()
+	entry.GeneratedPrivateKeyPEM = types.StringNull()
+	csr := entry.CSR.ValueString()
+	if entry.CSR.IsNull() {
+		block := plan.generateCSRModel(entry)
+		csrPEM, keyPEM, diags := block.generate(ctx)
+		if diags.HasError() {
+			entry.setDisposition(nil, diagnosticsError(diags))
+			return entry
+		}
+		csr = csrPEM
+		entry.GeneratedPrivateKeyPEM = types.StringValue(keyPEM)
+	}
+
+	nonce, err := newRequestNonce()
+	if err != nil {
+		entry.setDisposition(nil, err)
+		return entry
+	}
+	route := r.provider.newCARoute("")
+	routed := withCARoute(ctx, route)
+	reqID, err := submitRequest(routed, r.client, r.provider.parser, certsrvSubmission{
+		CSR:        csr,
+		Template:   plan.Template.ValueString(),
+		Attributes: append(splitAttributes(plan.RequestAttributes.ValueString()), requestNonceAttribute+":"+nonce),
+		Chunked:    r.provider.chunkedSubmissions,
+	})
+	entry.CAName = route.caNameValue()
+	if err != nil {
+		if pendingID, pending := pendingRequestID(err); pending {
+			entry.RequestID = types.StringValue(pendingID)
+		}
+		entry.setDisposition(nil, err)
+		return entry
+	}
+	entry.RequestID = types.StringValue(reqID)
+	certificates, err := retrieveCertificate(routed, r.client, r.provider.parser, reqID)
+	entry.setDisposition(certificates, err)
+	return entry
+}
+
+// chainPEM downloads the chain of the current CA certificate, issuing CA first. A failure only
+// leaves chain_pem empty, the certificates were issued all the same.
+func (r *certificateBatchResource) chainPEM(ctx context.Context, diags *diag.Diagnostics) types.String {
+	b64, _, err := retrieveCAChain(ctx, r.client, r.provider.parser, -1)
+	var certs []*x509.Certificate
+	if err == nil {
+		certs, err = parseCertificateChain(b64)
+	}
+	if err != nil {
+		diags.AddAttributeWarning(path.Root("chain_pem"), "Unable to Read CA Chain",
+			"The certificates were issued but the chain of the CA could not be read: "+err.Error())
+		return types.StringNull()
+	}
+	for _, cert := range certs {
+		if !isIssuerOfAny(cert, certs) {
+			return types.StringValue(encodePEM(append([]*x509.Certificate{cert}, buildChains(cert, certs)[0]...)...))
+		}
+	}
+	return types.StringValue(encodePEM(certs...))
+}
+
+// emitIssued publishes the issued event of the named entries.
+func (r *certificateBatchResource) emitIssued(ctx context.Context, m certificateBatchModel, entries map[string]certificateBatchEntryModel, names []string, diags *diag.Diagnostics) {
+	for _, name := range names {
+		entry := entries[name]
+		reqID := entry.RequestID.ValueString()
+		event := newLifecycleEvent(eventIssued, r.client.HostURL, reqID, m.Template.ValueString(), entry.CertificatePEM.ValueString())
+		if err := r.provider.emitEvent(ctx, event); err != nil {
+			diags.AddAttributeWarning(
+				path.Root("certificates").AtMapKey(name),
+				"Unable to Publish Certificate Event",
+				fmt.Sprintf("Request ID %s was %s but the %q event could not be published: %s", reqID, eventIssued, eventIssued, err.Error()),
+			)
+		}
+	}
+}
+
+// runParallel calls fn for every index below count, at most n calls at a time.
+func runParallel(n int, count int, fn func(i int)) {
+	indexes := make(chan int)
+	var wg sync.WaitGroup
+	for w := 0; w < n && w < count; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range indexes {
+				fn(i)
+			}
+		}()
+	}
+	for i := 0; i < count; i++ {
+		indexes <- i
+	}
+	close(indexes)
+	wg.Wait()
+}
+
+// isContextError reports whether err comes from a cancelled or timed out context.
+func isContextError(err error) bool {
+	return errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded)
+}
+
+// parallelism returns how many requests are submitted at the same time.
+func (m *certificateBatchModel) parallelism() int64 {
+	if m.Parallelism.IsNull() || m.Parallelism.IsUnknown() || m.Parallelism.ValueInt64() < 1 {
+		return ntlmConnections
+	}
+	return m.Parallelism.ValueInt64()
+}
+
+// generateCSRModel returns the generate_csr settings of an entry without a request of its own.
+func (m *certificateBatchModel) generateCSRModel(entry certificateBatchEntryModel) generateCSRModel {
+	return generateCSRModel{
+		KeyAlgorithm:   m.KeyAlgorithm,
+		RSABits:        m.RSABits,
+		ECDSACurve:     m.ECDSACurve,
+		CommonName:     entry.CommonName,
+		DNSNames:       entry.DNSNames,
+		IPAddresses:    types.ListNull(types.StringType),
+		EmailAddresses: types.ListNull(types.StringType),
+	}
+}
+
+// entries returns the entries of certificates by name.
+func (m *certificateBatchModel) entries(ctx context.Context) (map[string]certificateBatchEntryModel, diag.Diagnostics) {
+	entries := map[string]certificateBatchEntryModel{}
+	diags := m.Certificates.ElementsAs(ctx, &entries, false)
+	return entries, diags
+}
+
+// setEntries sets certificates and the failed entries from entries.
+func (m *certificateBatchModel) setEntries(ctx context.Context, entries map[string]certificateBatchEntryModel) diag.Diagnostics {
+	certificates, diags := types.MapValueFrom(ctx, types.ObjectType{AttrTypes: certificateBatchEntryAttrTypes}, entries)
+	m.Certificates = certificates
+
+	failed := []string{}
+	for name, entry := range entries {
+		switch {
+		case entry.Disposition.IsUnknown():
+			m.Failed = types.ListUnknown(types.StringType)
+			return diags
+		case entry.Disposition.ValueString() == dispositionDenied || entry.Disposition.ValueString() == dispositionFailed:
+			failed = append(failed, name)
+		}
+	}
+	sort.Strings(failed)
+	list, listDiags := types.ListValueFrom(ctx, types.StringType, failed)
+	diags.Append(listDiags...)
+	m.Failed = list
+	return diags
+}
+
+// sameRequest reports whether an entry asks for the same certificate as a previous one.
+func (e certificateBatchEntryModel) sameRequest(previous certificateBatchEntryModel) bool {
+	return e.CSR.Equal(previous.CSR) && e.CommonName.Equal(previous.CommonName) && e.DNSNames.Equal(previous.DNSNames)
+}
+
+// copyOutputs takes the outcome of a previous entry over.
+func (e *certificateBatchEntryModel) copyOutputs(previous certificateBatchEntryModel) {
+	e.RequestID = previous.RequestID
+	e.Disposition = previous.Disposition
+	e.DispositionMessage = previous.DispositionMessage
+	e.CertificatePEM = previous.CertificatePEM
+	e.GeneratedPrivateKeyPEM = previous.GeneratedPrivateKeyPEM
+	e.NotAfter = previous.NotAfter
+	e.CAName = previous.CAName
+}
+
+// unknownOutputs plans a new submission of the entry.
+func (e *certificateBatchEntryModel) unknownOutputs() {
+	e.RequestID = types.StringUnknown()
+	e.Disposition = types.StringUnknown()
+	e.DispositionMessage = types.StringUnknown()
+	e.CertificatePEM = types.StringUnknown()
+	e.GeneratedPrivateKeyPEM = types.StringUnknown()
+	e.NotAfter = types.StringUnknown()
+	e.CAName = types.StringUnknown()
+}
+
+// setDisposition records the outcome of a submission or download of the entry. A disposition
+// message of the CA denies the entry unless it says the CA service is unavailable. A submission
+// that never reached the CA fails the entry, a download that did not keeps it pending so refreshes
+// download the certificate once the CA can be reached.
+func (e *certificateBatchEntryModel) setDisposition(certificates *client.Certificates, err error) {
+	e.DispositionMessage = types.StringValue(dispositionMessage(err))
+	e.CertificatePEM = types.StringNull()
+	e.NotAfter = types.StringNull()
+	switch {
+	case err == nil:
+		e.Disposition = types.StringValue(dispositionIssued)
+		e.CertificatePEM = types.StringValue(normalizePEM(certificates.CertificateB64))
+		if cert, err := parseCertificate(certificates.CertificateB64); err == nil {
+			e.CertificatePEM = types.StringValue(encodePEM(cert))
+			e.NotAfter = types.StringValue(cert.NotAfter.UTC().Format(time.RFC3339))
+		}
+	case isStillPending(err):
+		e.Disposition = types.StringValue(dispositionPending)
+	case e.DispositionMessage.ValueString() == "" || isTransientDisposition(err):
+		e.Disposition = types.StringValue(dispositionFailed)
+		if !e.RequestID.IsNull() {
+			e.Disposition = types.StringValue(dispositionPending)
+		}
+		e.DispositionMessage = types.StringValue(err.Error())
+	default:
+		e.Disposition = types.StringValue(dispositionDenied)
+	}
+}
+
+// validate checks that the entry either holds a request or describes one to generate.
+func (e certificateBatchEntryModel) validate(entryPath path.Path) diag.Diagnostics {
+	var diags diag.Diagnostics
+	generated := !e.CommonName.IsNull() || !e.DNSNames.IsNull()
+	switch {
+	case e.CSR.IsNull() && !generated:
+		diags.AddAttributeError(entryPath, "Missing Certificate Signing Request",
+			"Set certificate_signing_request, or common_name or dns_names to let the provider create the key and request.")
+	case !e.CSR.IsNull() && generated:
+		diags.AddAttributeError(entryPath.AtName("certificate_signing_request"), "Conflicting Certificate Signing Request",
+			"certificate_signing_request cannot be combined with common_name and dns_names, which generate the request.")
+	}
+
+	if !e.CSR.IsNull() && !e.CSR.IsUnknown() {
+		if request, err := parseCertificateRequest(e.CSR.ValueString()); err == nil {
+			if err := checkKeyAlgorithm(request.PublicKey); err != nil {
+				diags.AddAttributeError(entryPath.AtName("certificate_signing_request"), "Unsupported Key Algorithm", err.Error())
+			}
+		}
+	}
+	if !e.CommonName.IsNull() && !e.CommonName.IsUnknown() {
+		if _, err := normalizeCommonName(e.CommonName.ValueString()); err != nil {
+			diags.AddAttributeError(entryPath.AtName("common_name"), "Invalid Common Name", err.Error()+".")
+		}
+	}
+	if !e.DNSNames.IsUnknown() {
+		for i, element := range e.DNSNames.Elements() {
+			if name, ok := element.(types.String); ok && !name.IsUnknown() {
+				if _, err := normalizeDNSName(name.ValueString()); err != nil {
+					diags.AddAttributeError(entryPath.AtName("dns_names").AtListIndex(i), "Invalid DNS Name", err.Error()+".")
+				}
+			}
+		}
+	}
+	return diags
+}
@@ -0,0 +1,255 @@
+package provider
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"reflect"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-framework/attr"
+	"github.com/hashicorp/terraform-plugin-framework/path"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+	"github.com/hashicorp/terraform-plugin-testing/helper/resource"
+	"github.com/hashicorp/terraform-plugin-testing/terraform"
+)
+
+// testBatchEntry returns an entry for the request or, when csr is empty, for a request generated
+// for commonName, with its outputs planned unknown.
+func testBatchEntry(csr string, commonName string) certificateBatchEntryModel {
+	entry := certificateBatchEntryModel{
+		CSR:        types.StringNull(),
+		CommonName: types.StringNull(),
+		DNSNames:   types.ListNull(types.StringType),
+	}
+	if csr != "" {
+		entry.CSR = types.StringValue(csr)
+	} else {
+		entry.CommonName = types.StringValue(commonName)
+		entry.DNSNames = types.ListValueMust(types.StringType, nil)
+	}
+	entry.unknownOutputs()
+	return entry
+}
+
+func TestCertificateBatchIssue(t *testing.T) {
+	srv := newAccCertsrv(t)
+	// Submissions for down.example.com meet a CA that cannot be reached.
+	var down atomic.Bool
+	down.Store(true)
+	srv.server.Config.Handler = func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.URL.Path == "/certsrv/certfnsh.asp" && strings.HasPrefix(r.Header.Get("Authorization"), "NTLM ") && r.ParseForm() == nil {
+				if request, err := parseCertificateRequest(r.PostForm.Get("CertRequest")); err == nil && request.Subject.CommonName == "down.example.com" && down.Load() {
+					http.Error(w, "Service Unavailable", http.StatusServiceUnavailable)
+					return
+				}
+			}
+			next.ServeHTTP(w, r)
+		})
+	}(srv.server.Config.Handler)
+
+	t.Setenv("ADCS_PASSWORD", "secret")
+	ctx := withRetryPolicy(context.Background(), noRetry)
+	data, err := debugEnrollConfigure(ctx, "test", map[string]interface{}{
+		"host":     srv.host(),
+		"username": "svc-terraform",
+		"use_ntlm": true,
+	}, io.Discard)
+	if err != nil {
+		t.Fatal(err)
+	}
+	r := &certificateBatchResource{client: data.client, provider: data}
+	csr, err := debugEnrollRequest(ctx, "", "api.example.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	plan := certificateBatchModel{Template: types.StringValue("WebServer"), Parallelism: types.Int64Value(2)}
+	if diags := plan.setEntries(ctx, map[string]certificateBatchEntryModel{
+		"api":    testBatchEntry(csr, ""),
+		"web":    testBatchEntry("", "web.example.com"),
+		"broken": testBatchEntry("not a request", ""),
+		"down":   testBatchEntry("", "down.example.com"),
+	}); diags.HasError() {
+		t.Fatal(diags)
+	}
+	diags := r.issue(ctx, &plan)
+	if diags.HasError() {
+		t.Fatalf("issue() = %v", diags)
+	}
+	if diags.WarningsCount() != 2 {
+		t.Errorf("issue() warned %d times, want once for each entry that was not issued: %v", diags.WarningsCount(), diags)
+	}
+	entries, _ := plan.entries(ctx)
+	for name, want := range map[string]string{"api": dispositionIssued, "web": dispositionIssued, "broken": dispositionDenied, "down": dispositionFailed} {
+		if got := entries[name].Disposition.ValueString(); got != want {
+			t.Errorf("%s: disposition = %s (%s), want %s", name, got, entries[name].DispositionMessage, want)
+		}
+	}
+	if !entries["api"].GeneratedPrivateKeyPEM.IsNull() || entries["web"].GeneratedPrivateKeyPEM.IsNull() {
+		t.Errorf("generated_private_key_pem is only set for generated requests")
+	}
+	if cert, err := parseCertificate(entries["web"].CertificatePEM.ValueString()); err != nil || cert.Subject.CommonName != "web.example.com" {
+		t.Errorf("web: certificate_pem = %v, want a certificate for web.example.com", err)
+	}
+	if !entries["down"].RequestID.IsNull() || entries["api"].NotAfter.IsNull() {
+		t.Errorf("request_id and not_after are only set once the CA answered")
+	}
+	var failed []string
+	plan.Failed.ElementsAs(ctx, &failed, false)
+	if !reflect.DeepEqual(failed, []string{"broken", "down"}) {
+		t.Errorf("failed = %v, want [broken down]", failed)
+	}
+	if want := encodePEM(srv.hierarchy.issuing, srv.hierarchy.root); plan.ChainPEM.ValueString() != want {
+		t.Errorf("chain_pem = %q, want the issuing CA followed by the root", plan.ChainPEM.ValueString())
+	}
+
+	// The next apply only submits the failed entry again.
+	down.Store(false)
+	submitted := len(srv.requests)
+	entry := entries["down"]
+	entry.unknownOutputs()
+	entries["down"] = entry
+	_ = plan.setEntries(ctx, entries)
+	if diags := r.issue(ctx, &plan); diags.HasError() || diags.WarningsCount() != 0 {
+		t.Fatalf("issue() = %v", diags)
+	}
+	if len(srv.requests) != submitted+1 {
+		t.Errorf("issue() submitted %d requests, want only the failed one", len(srv.requests)-submitted)
+	}
+	entries, _ = plan.entries(ctx)
+	if got := entries["down"].Disposition.ValueString(); got != dispositionIssued {
+		t.Errorf("down: disposition = %s, want issued", got)
+	}
+	plan.Failed.ElementsAs(ctx, &failed, false)
+	if !reflect.DeepEqual(failed, []string{"broken"}) {
+		t.Errorf("failed = %v, want [broken]", failed)
+	}
+}
+
+func TestCertificateBatchEntrySetDisposition(t *testing.T) {
+	entry := testBatchEntry("", "web.example.com")
+	entry.RequestID = types.StringNull()
+
+	entry.setDisposition(nil, errors.New("error making request: dial tcp 10.0.0.2:80: connect: connection refused"))
+	if entry.Disposition.ValueString() != dispositionFailed {
+		t.Errorf("unreachable CA: disposition = %s, want failed", entry.Disposition)
+	}
+	entry.setDisposition(nil, errors.New("could not generate request nonce: no entropy"))
+	if entry.Disposition.ValueString() != dispositionFailed {
+		t.Errorf("local error: disposition = %s, want failed", entry.Disposition)
+	}
+	entry.setDisposition(nil, &dispositionError{prefix: "failed to get request ID: ", message: "The RPC server is unavailable. 0x800706ba"})
+	if entry.Disposition.ValueString() != dispositionFailed {
+		t.Errorf("CA service unavailable: disposition = %s, want failed", entry.Disposition)
+	}
+	entry.setDisposition(nil, &dispositionError{prefix: "failed to get request ID: ", message: "Denied by Policy Module  0x80094800"})
+	if entry.Disposition.ValueString() != dispositionDenied || entry.DispositionMessage.ValueString() != "Denied by Policy Module  0x80094800" {
+		t.Errorf("denial: disposition = %s, message = %s, want denied", entry.Disposition, entry.DispositionMessage)
+	}
+
+	// A download that fails after the CA took the request leaves it to the next refresh.
+	entry.RequestID = types.StringValue("7")
+	entry.setDisposition(nil, errors.New("failed to download certificate: status error: 503"))
+	if entry.Disposition.ValueString() != dispositionPending || entry.DispositionMessage.IsNull() {
+		t.Errorf("failed download: disposition = %s, message = %s, want pending", entry.Disposition, entry.DispositionMessage)
+	}
+	entry.setDisposition(nil, fmt.Errorf("failed to download certificate: %w", &dispositionError{message: "Denied by Policy Module  0x80094800"}))
+	if entry.Disposition.ValueString() != dispositionDenied {
+		t.Errorf("denied download: disposition = %s, want denied", entry.Disposition)
+	}
+}
+
+func TestCertificateBatchEntryValidate(t *testing.T) {
+	entryPath := path.Root("certificates").AtMapKey("web")
+	for name, tc := range map[string]struct {
+		entry certificateBatchEntryModel
+		want  string
+	}{
+		"request":   {entry: testBatchEntry(csr, "")},
+		"generated": {entry: testBatchEntry("", "web.example.com")},
+		"missing": {
+			entry: certificateBatchEntryModel{CSR: types.StringNull(), CommonName: types.StringNull(), DNSNames: types.ListNull(types.StringType)},
+			want:  "Missing Certificate Signing Request",
+		},
+		"conflict": {
+			entry: func() certificateBatchEntryModel {
+				entry := testBatchEntry(csr, "")
+				entry.CommonName = types.StringValue("web.example.com")
+				return entry
+			}(),
+			want: "Conflicting Certificate Signing Request",
+		},
+		"invalid dns name": {
+			entry: func() certificateBatchEntryModel {
+				entry := testBatchEntry("", "web.example.com")
+				entry.DNSNames = types.ListValueMust(types.StringType, []attr.Value{types.StringValue("web..example.com")})
+				return entry
+			}(),
+			want: "Invalid DNS Name",
+		},
+	} {
+		diags := tc.entry.validate(entryPath)
+		switch {
+		case tc.want == "" && diags.HasError():
+			t.Errorf("%s: validate() = %v", name, diags)
+		case tc.want != "" && (!diags.HasError() || diags.Errors()[0].Summary() != tc.want):
+			t.Errorf("%s: validate() = %v, want %q", name, diags, tc.want)
+		}
+	}
+}
+
+func TestAccCertificateBatchResource(t *testing.T) {
+	forEachAuthMode(t, func(t *testing.T, srv *accCertsrv) {
+		config := func(names ...string) string {
+			var entries strings.Builder
+			for _, name := range names {
+				fmt.Fprintf(&entries, "    %s = { common_name = %q }\n", name, name+".example.com")
+			}
+			return srv.providerConfig() + fmt.Sprintf(`
+resource "microsoftadcs_certificate_batch" "test" {
+  template = "WebServer"
+
+  certificates = {
+%s  }
+}
+`, entries.String())
+		}
+
+		resource.Test(t, resource.TestCase{
+			ProtoV6ProviderFactories: testAccProtoV6ProviderFactories,
+			Steps: []resource.TestStep{
+				{
+					Config: config("web1", "web2"),
+					Check: resource.ComposeAggregateTestCheckFunc(
+						resource.TestCheckResourceAttr("microsoftadcs_certificate_batch.test", "certificates.web1.disposition", dispositionIssued),
+						resource.TestCheckResourceAttr("microsoftadcs_certificate_batch.test", "certificates.web2.disposition", dispositionIssued),
+						resource.TestCheckResourceAttrSet("microsoftadcs_certificate_batch.test", "certificates.web1.generated_private_key_pem"),
+						resource.TestCheckResourceAttrSet("microsoftadcs_certificate_batch.test", "chain_pem"),
+						resource.TestCheckResourceAttr("microsoftadcs_certificate_batch.test", "failed.#", "0"),
+					),
+				},
+				// Adding an entry only issues that certificate.
+				{
+					Config: config("web1", "web2", "web3"),
+					Check: resource.ComposeAggregateTestCheckFunc(
+						resource.TestCheckResourceAttr("microsoftadcs_certificate_batch.test", "certificates.web3.disposition", dispositionIssued),
+						func(*terraform.State) error {
+							srv.mu.Lock()
+							defer srv.mu.Unlock()
+							if len(srv.requests) != 3 {
+								return fmt.Errorf("the CA received %d requests, want 3", len(srv.requests))
+							}
+							return nil
+						},
+					),
+				},
+			},
+		})
+	})
+}
@@ -23,7 +23,9 @@ func NewCertificateDataSource() datasource.DataSource {
 
 // certificateDataSource is the data source implementation.
 type certificateDataSource struct {
-	client *client.ADCSClient
+	client   *client.ADCSClient
+	parser   *certsrvParser
+	provider *providerData
 }
 
 // coffeesModel maps coffees schema data.
@@ -39,17 +41,19 @@ func (d *certificateDataSource) Configure(_ context.Context, req datasource.Conf
 		return
 	}
 
-	client, ok := req.ProviderData.(*client.ADCSClient)
+	data, ok := req.ProviderData.(*providerData)
 	if !ok {
 		resp.Diagnostics.AddError(
 			"Unexpected Data Source Configure Type",
-			fmt.Sprintf("Expected *client.ADCSClient, got: %T. Please report this issue to the provider developers.", req.ProviderData),
+			fmt.Sprintf("Expected *providerData, got: %T. Please report this issue to the provider developers.", req.ProviderData),
 		)
 
 		return
 	}
 
-	d.client = client
+	d.client = data.client
+	d.provider = data
+	d.parser = data.parser
 }
 
 // Metadata returns the data source type name.
@@ -60,6 +64,7 @@ func (d *certificateDataSource) Metadata(_ context.Context, req datasource.Metad
 // Schema defines the schema for the data source.
 func (d *certificateDataSource) Schema(_ context.Context, _ datasource.SchemaRequest, resp *datasource.SchemaResponse) {
 	resp.Schema = schema.Schema{
+		Description: "Retrieves a certificate issued outside of Terraform by its request ID.",
 		Attributes: map[string]schema.Attribute{
 			"id": schema.StringAttribute{
 				Description: "Numeric identifier of the certificate that was generated.",
@@ -79,17 +84,24 @@ func (d *certificateDataSource) Schema(_ context.Context, _ datasource.SchemaReq
 
 // Read refreshes the Terraform state with the latest data.
 func (d *certificateDataSource) Read(ctx context.Context, req datasource.ReadRequest, resp *datasource.ReadResponse) {
+	if d.provider.caOffline(ctx) {
+		resp.Diagnostics.Append(applyOnlyError("read a certificate"))
+		return
+	}
+	ctx, trace := withOperationTrace(ctx)
+	defer trace.annotate(&resp.Diagnostics)
+
 	// Retrieve values from plan
 	var data certificateModel
 	// Read Terraform configuration data into the model
 	resp.Diagnostics.Append(req.Config.Get(ctx, &data)...)
 	reqID := data.ID.ValueString()
 
-	certificates, err := d.client.RetrieveCertificates(reqID)
+	certificates, err := retrieveCertificates(ctx, d.client, d.parser, reqID)
 	if err != nil {
 		// diagError = "Unable to Read certificates for " + reqID
 		resp.Diagnostics.AddError(
-			fmt.Sprintf("Unable to Read certificates for %d", &reqID),
+			classifiedSummary(fmt.Sprintf("Unable to Read certificates for %d", &reqID), err),
 			err.Error(),
 		)
 		return
@@ -98,8 +110,8 @@ func (d *certificateDataSource) Read(ctx context.Context, req datasource.ReadReq
 	// Map response body to model
 	state := certificateModel{
 		ID:                  types.StringValue(certificates.ID),
-		CertificateB64:      types.StringValue(certificates.CertificateB64),
-		CertificateChainB64: types.StringValue(certificates.CertificateChainB64),
+		CertificateB64:      types.StringValue(normalizePEM(certificates.CertificateB64)),
+		CertificateChainB64: types.StringValue(normalizePEM(certificates.CertificateChainB64)),
 	}
 
 	// Set state
@@ -0,0 +1,111 @@
+package provider
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/hashicorp/terraform-plugin-framework/diag"
+	"github.com/hashicorp/terraform-plugin-framework/path"
+)
+
+// certificateDescriptionAttribute is the request attribute describing a request in the CA database,
+// shown with the other request attributes in the Certification Authority console and certutil -view.
+const certificateDescriptionAttribute = "CertificateDescription"
+
+// terraformDescriptionMarker starts the default description, it marks requests Terraform submitted.
+const terraformDescriptionMarker = "Managed by Terraform"
+
+// defaultWorkspace is the workspace of the default description when neither workspace nor
+// TF_WORKSPACE is set, the name of the workspace Terraform starts with.
+const defaultWorkspace = "default"
+
+// providerWorkspace returns the workspace requests are tagged with: workspace of the provider, or
+// TF_WORKSPACE, which also selects the workspace of the Terraform CLI.
+func providerWorkspace(config MicrosoftADCSProviderModel) string {
+	if !config.Workspace.IsNull() && config.Workspace.ValueString() != "" {
+		return config.Workspace.ValueString()
+	}
+	if workspace := os.Getenv("TF_WORKSPACE"); workspace != "" {
+		return workspace
+	}
+	return defaultWorkspace
+}
+
+// defaultCertificateDescription is the description of requests without certificate_description, so
+// certificates Terraform manages can be told apart in the CA console.
+func defaultCertificateDescription(workspace string) string {
+	return fmt.Sprintf("%s, workspace %s", terraformDescriptionMarker, workspace)
+}
+
+// isTerraformDescription reports whether description is a default description, of the given
+// workspace unless it is empty.
+func isTerraformDescription(description, workspace string) bool {
+	if workspace != "" {
+		return description == defaultCertificateDescription(workspace)
+	}
+	return strings.HasPrefix(description, terraformDescriptionMarker+", workspace ")
+}
+
+// requestDescription returns the value of the CertificateDescription request attribute in
+// attributes, empty when there is none.
+func requestDescription(attributes string) string {
+	for _, attribute := range splitAttributes(attributes) {
+		if attributeName(attribute) == strings.ToLower(certificateDescriptionAttribute) {
+			_, value, _ := strings.Cut(attribute, ":")
+			return value
+		}
+	}
+	return ""
+}
+
+// describedAttributes returns the submitted request attributes with the CertificateDescription
+// request attribute for certificate_description, or the default description when it is not set.
+// An empty certificate_description leaves the description out, and so does a CertificateDescription
+// of the request attributes, which wins over the default.
+func (m *certificateCreateModel) describedAttributes(attributes, workspace string) string {
+	description := defaultCertificateDescription(workspace)
+	switch {
+	case m.CertificateDescription.IsUnknown():
+		return attributes
+	case !m.CertificateDescription.IsNull():
+		description = m.CertificateDescription.ValueString()
+	default:
+		for _, attribute := range splitAttributes(attributes) {
+			if attributeName(attribute) == strings.ToLower(certificateDescriptionAttribute) {
+				return attributes
+			}
+		}
+	}
+	if description == "" {
+		return attributes
+	}
+	if attributes == "" {
+		return certificateDescriptionAttribute + ":" + description
+	}
+	return attributes + "\n" + certificateDescriptionAttribute + ":" + description
+}
+
+// validateCertificateDescription checks that certificate_description fits in a single request
+// attribute and that no request attribute sets the description as well.
+func validateCertificateDescription(config certificateCreateModel) diag.Diagnostics {
+	var diags diag.Diagnostics
+	if config.CertificateDescription.IsNull() || config.CertificateDescription.IsUnknown() {
+		return diags
+	}
+
+	if description := config.CertificateDescription.ValueString(); strings.ContainsAny(description, "\r\n") {
+		diags.AddAttributeError(path.Root("certificate_description"), "Invalid Certificate Description",
+			fmt.Sprintf("certificate_description %q spans several lines, request attribute values have to fit on one line.", description))
+	}
+
+	if attributes := config.requestAttributes(); !attributes.IsUnknown() {
+		for _, attribute := range splitAttributes(attributes.ValueString()) {
+			if attributeName(attribute) == strings.ToLower(certificateDescriptionAttribute) {
+				diags.AddAttributeError(path.Root("certificate_description"), "Conflicting Certificate Description",
+					"Set the description either in the CertificateDescription entry of request_attributes or in certificate_description.")
+			}
+		}
+	}
+	return diags
+}
@@ -0,0 +1,74 @@
+package provider
+
+import (
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-framework/types"
+)
+
+func TestDescribedAttributes(t *testing.T) {
+	cases := map[string]struct {
+		description types.String
+		attributes  string
+		want        string
+	}{
+		"default":            {attributes: "Owner:platform", want: "Owner:platform\nCertificateDescription:Managed by Terraform, workspace prod"},
+		"default alone":      {want: "CertificateDescription:Managed by Terraform, workspace prod"},
+		"set":                {description: types.StringValue("web-tls for team-a"), want: "CertificateDescription:web-tls for team-a"},
+		"empty":              {description: types.StringValue(""), attributes: "Owner:platform", want: "Owner:platform"},
+		"unknown":            {description: types.StringUnknown(), attributes: "Owner:platform", want: "Owner:platform"},
+		"request attributes": {attributes: "certificatedescription:by hand", want: "certificatedescription:by hand"},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			model := certificateCreateModel{CertificateDescription: tc.description}
+			if got := model.describedAttributes(tc.attributes, "prod"); got != tc.want {
+				t.Errorf("describedAttributes() = %q, want %q", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestProviderWorkspace(t *testing.T) {
+	t.Setenv("TF_WORKSPACE", "")
+	if got := providerWorkspace(MicrosoftADCSProviderModel{}); got != defaultWorkspace {
+		t.Errorf("providerWorkspace() = %q, want %q", got, defaultWorkspace)
+	}
+	t.Setenv("TF_WORKSPACE", "staging")
+	if got := providerWorkspace(MicrosoftADCSProviderModel{}); got != "staging" {
+		t.Errorf("providerWorkspace() = %q, want TF_WORKSPACE", got)
+	}
+	if got := providerWorkspace(MicrosoftADCSProviderModel{Workspace: types.StringValue("prod")}); got != "prod" {
+		t.Errorf("providerWorkspace() = %q, want the workspace of the provider", got)
+	}
+}
+
+func TestValidateCertificateDescription(t *testing.T) {
+	cases := map[string]struct {
+		description string
+		attributes  string
+		errors      int
+	}{
+		"none":       {},
+		"valid":      {description: "web-tls for team-a"},
+		"multi-line": {description: "web\ntls", errors: 1},
+		"in request_attributes": {
+			description: "web-tls",
+			attributes:  "Owner:platform\nCertificateDescription:web",
+			errors:      1,
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			config := certificateCreateModel{RequestAttributes: types.StringValue(tc.attributes)}
+			if tc.description != "" {
+				config.CertificateDescription = types.StringValue(tc.description)
+			}
+			if diags := validateCertificateDescription(config); diags.ErrorsCount() != tc.errors {
+				t.Errorf("got %d errors, want %d: %v", diags.ErrorsCount(), tc.errors, diags)
+			}
+		})
+	}
+}
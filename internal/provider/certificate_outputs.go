@@ -0,0 +1,335 @@
+package provider
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/rsa"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/flipyap/microsoft-adcs-client/client"
+	"github.com/hashicorp/terraform-plugin-framework/attr"
+	"github.com/hashicorp/terraform-plugin-framework/diag"
+	"github.com/hashicorp/terraform-plugin-framework/path"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+)
+
+// certificateMaterial is the parsed form of what ADCS returned for a request.
+type certificateMaterial struct {
+	leaf *x509.Certificate
+	// chain holds the issuers of leaf, starting with the issuing CA. The leaf is not included.
+	chain []*x509.Certificate
+	// chains holds every chain the CA returned, chain is one of them.
+	chains [][]*x509.Certificate
+	// p7b is the PKCS#7 chain exactly as ADCS returned it, in DER.
+	p7b []byte
+	// keyPEM is the private key of leaf, only set when the provider holds it.
+	keyPEM string
+	// rootFirst and excludeRoot lay out the certificate_chain outputs, see arrangeChain.
+	rootFirst   bool
+	excludeRoot bool
+}
+
+// parseCertificateMaterial parses the leaf certificate and orders the PKCS#7 chain above it.
+func parseCertificateMaterial(certificates *client.Certificates) (*certificateMaterial, error) {
+	leaf, err := parseCertificate(certificates.CertificateB64)
+	if err != nil {
+		return nil, fmt.Errorf("could not parse certificate: %v", err)
+	}
+
+	// Without a stored chain the outputs only hold the leaf.
+	if certificates.CertificateChainB64 == "" {
+		return &certificateMaterial{leaf: leaf}, nil
+	}
+
+	p7b, err := decodePEMOrBase64(certificates.CertificateChainB64)
+	if err != nil {
+		return nil, fmt.Errorf("could not parse certificate chain: %v", err)
+	}
+	bundle, err := parsePKCS7Certificates(p7b)
+	if err != nil {
+		return nil, fmt.Errorf("could not parse certificate chain: %v", err)
+	}
+
+	chains := buildChains(leaf, bundle)
+	return &certificateMaterial{
+		leaf:   leaf,
+		chain:  chains[0],
+		chains: chains,
+		p7b:    p7b,
+	}, nil
+}
+
+// completeChains appends the issuers the CA left out of its chains, fetched from their AIA URLs.
+// It does nothing without a fetcher in ctx or without a stored chain.
+func (m *certificateMaterial) completeChains(ctx context.Context) error {
+	fetcher := aiaFetcherFrom(ctx)
+	if fetcher == nil || len(m.p7b) == 0 {
+		return nil
+	}
+	var err error
+	for i, chain := range m.chains {
+		var completed []*x509.Certificate
+		completed, err = fetcher.complete(ctx, m.leaf, chain)
+		m.chains[i] = completed
+		if err != nil {
+			break
+		}
+	}
+	// Completed chains can change length, keep them in the order buildChains gives.
+	sortChains(m.chains)
+	m.chain = m.chains[0]
+	return err
+}
+
+// selectChain switches to the chain ending in a certificate with the given common name. It reports
+// false and keeps the current chain when there is no such chain.
+func (m *certificateMaterial) selectChain(rootCN string) bool {
+	for _, chain := range m.chains {
+		if len(chain) > 0 && chain[len(chain)-1].Subject.CommonName == rootCN {
+			m.chain = chain
+			return true
+		}
+	}
+	return false
+}
+
+// certificateChainsType is the type of certificate_chains.
+var certificateChainsType = types.ListType{ElemType: types.ListType{ElemType: types.StringType}}
+
+// certificateChains returns every chain as a list of PEM encoded certificates, in the order of
+// arrangeChain.
+func (m *certificateMaterial) certificateChains() types.List {
+	chains := make([]attr.Value, 0, len(m.chains))
+	for _, chain := range m.chains {
+		certs := make([]attr.Value, 0, len(chain))
+		for _, cert := range m.arrangeChain(chain) {
+			certs = append(certs, types.StringValue(encodePEM(cert)))
+		}
+		chains = append(chains, types.ListValueMust(types.StringType, certs))
+	}
+	return types.ListValueMust(certificateChainsType.ElemType, chains)
+}
+
+// certificateChain returns the selected chain as a list of PEM encoded certificates, in the order
+// of arrangeChain, empty when the chain is not stored.
+func (m *certificateMaterial) certificateChain() types.List {
+	certs := make([]attr.Value, 0, len(m.chain))
+	for _, cert := range m.arrangeChain(m.chain) {
+		certs = append(certs, types.StringValue(encodePEM(cert)))
+	}
+	return types.ListValueMust(types.StringType, certs)
+}
+
+// intermediates returns the chain without a self-signed root.
+func (m *certificateMaterial) intermediates() []*x509.Certificate {
+	if root := m.root(); root != nil {
+		return m.chain[:len(m.chain)-1]
+	}
+	return m.chain
+}
+
+// root returns the self-signed root at the top of the chain, if the CA included it.
+func (m *certificateMaterial) root() *x509.Certificate {
+	if len(m.chain) == 0 || !isSelfSigned(m.chain[len(m.chain)-1]) {
+		return nil
+	}
+	return m.chain[len(m.chain)-1]
+}
+
+// kubernetesTLSSecret returns the material keyed the way a kubernetes.io/tls secret expects it,
+// with base64 values ready for the binary_data of a kubernetes_secret. tls.crt holds the leaf
+// followed by the intermediates so TLS servers can present the full chain, tls.key is only
+// included when the private key is known.
+func (m *certificateMaterial) kubernetesTLSSecret() types.Map {
+	secret := map[string]attr.Value{
+		"tls.crt": types.StringValue(base64.StdEncoding.EncodeToString([]byte(encodePEM(append([]*x509.Certificate{m.leaf}, m.intermediates()...)...)))),
+	}
+	if m.keyPEM != "" {
+		secret["tls.key"] = types.StringValue(base64.StdEncoding.EncodeToString([]byte(m.keyPEM)))
+	}
+	if root := m.root(); root != nil {
+		secret["ca.crt"] = types.StringValue(base64.StdEncoding.EncodeToString([]byte(encodePEM(root))))
+	} else if len(m.chain) > 0 {
+		secret["ca.crt"] = types.StringValue(base64.StdEncoding.EncodeToString([]byte(encodePEM(m.chain[len(m.chain)-1]))))
+	}
+	return types.MapValueMust(types.StringType, secret)
+}
+
+// azureKeyVaultCertificateAttrTypes describes the azure_key_vault_certificate object.
+var azureKeyVaultCertificateAttrTypes = map[string]attr.Type{
+	"contents":     types.StringType,
+	"content_type": types.StringType,
+	"key_type":     types.StringType,
+	"key_size":     types.Int64Type,
+	"curve":        types.StringType,
+	"exportable":   types.BoolType,
+	"reuse_key":    types.BoolType,
+	"issuer_name":  types.StringType,
+}
+
+// azureKeyVaultCertificate returns the material shaped like the certificate and certificate_policy
+// blocks of azurerm_key_vault_certificate when importing an existing certificate. The key properties
+// are taken from the public key of the issued certificate.
+func (m *certificateMaterial) azureKeyVaultCertificate() types.Object {
+	keyType, keySize, curve := types.StringNull(), types.Int64Null(), types.StringNull()
+	switch pub := m.leaf.PublicKey.(type) {
+	case *rsa.PublicKey:
+		keyType = types.StringValue("RSA")
+		keySize = types.Int64Value(int64(pub.N.BitLen()))
+	case *ecdsa.PublicKey:
+		keyType = types.StringValue("EC")
+		keySize = types.Int64Value(int64(pub.Curve.Params().BitSize))
+		curve = types.StringValue(pub.Curve.Params().Name)
+	}
+
+	return types.ObjectValueMust(azureKeyVaultCertificateAttrTypes, map[string]attr.Value{
+		"contents":     types.StringValue(m.keyPEM + encodePEM(append([]*x509.Certificate{m.leaf}, m.chain...)...)),
+		"content_type": types.StringValue("application/x-pem-file"),
+		"key_type":     keyType,
+		"key_size":     keySize,
+		"curve":        curve,
+		"exportable":   types.BoolValue(true),
+		"reuse_key":    types.BoolValue(false),
+		"issuer_name":  types.StringValue("Unknown"),
+	})
+}
+
+// combinedPEM returns the leaf, the intermediates and the private key in one PEM bundle, the
+// layout HAProxy and NGINX expect for a single certificate file. Without a key there is nothing
+// to combine and the value is null.
+func (m *certificateMaterial) combinedPEM() types.String {
+	if m.keyPEM == "" {
+		return types.StringNull()
+	}
+	return types.StringValue(encodePEM(append([]*x509.Certificate{m.leaf}, m.intermediates()...)...) + m.keyPEM)
+}
+
+// chainDER returns the selected chain as a list of base64 encoded DER certificates, in the order
+// of arrangeChain, empty when the chain is not stored.
+func (m *certificateMaterial) chainDER() types.List {
+	certs := make([]attr.Value, 0, len(m.chain))
+	for _, cert := range m.arrangeChain(m.chain) {
+		certs = append(certs, types.StringValue(base64.StdEncoding.EncodeToString(cert.Raw)))
+	}
+	return types.ListValueMust(types.StringType, certs)
+}
+
+// chainP7B returns the PKCS#7 chain of ADCS as base64 encoded DER, null when the chain is not stored.
+func (m *certificateMaterial) chainP7B() types.String {
+	if len(m.p7b) == 0 {
+		return types.StringNull()
+	}
+	return types.StringValue(base64.StdEncoding.EncodeToString(m.p7b))
+}
+
+// issuingCAPEM returns the CA that signed the leaf as PEM, null when the chain is not stored.
+func (m *certificateMaterial) issuingCAPEM() types.String {
+	if len(m.chain) == 0 {
+		return types.StringNull()
+	}
+	return types.StringValue(encodePEM(m.chain[0]))
+}
+
+// rootCAPEM returns the self-signed root of the chain as PEM, null when the CA did not include it.
+func (m *certificateMaterial) rootCAPEM() types.String {
+	root := m.root()
+	if root == nil {
+		return types.StringNull()
+	}
+	return types.StringValue(encodePEM(root))
+}
+
+// chainPEM returns the selected chain as concatenated PEM, in the order of arrangeChain, null when
+// the chain is not stored or only held the excluded root.
+func (m *certificateMaterial) chainPEM() types.String {
+	chain := m.arrangeChain(m.chain)
+	if len(chain) == 0 {
+		return types.StringNull()
+	}
+	return types.StringValue(encodePEM(chain...))
+}
+
+// setCertificateOutputs fills the attributes derived from the certificate material ADCS returned.
+// pkcs12_b64 is only rebuilt when planned unknown, its encryption is salted and never comes out the same.
+func (m *certificateCreateModel) setCertificateOutputs(ctx context.Context, certificates *client.Certificates) diag.Diagnostics {
+	var diags diag.Diagnostics
+
+	material, err := parseCertificateMaterial(certificates)
+	if err != nil {
+		diags.AddError(
+			classifiedSummary("Error Parsing Certificate", &ParseError{Err: err}),
+			fmt.Sprintf("Could not parse the certificate material returned for request ID %s: %s", certificates.ID, err.Error()),
+		)
+		return diags
+	}
+
+	if err := material.completeChains(ctx); err != nil {
+		diags.AddWarning(
+			"Incomplete Certificate Chain",
+			fmt.Sprintf("The chain returned for request ID %s does not reach a root and its missing issuers could not be fetched from "+
+				"their AIA URLs: %s. The chain outputs only hold the issuers found.", certificates.ID, err.Error()),
+		)
+	}
+
+	if rootCN := m.PreferredRootCN.ValueString(); rootCN != "" && len(material.chains) > 0 && !material.selectChain(rootCN) {
+		diags.AddAttributeWarning(
+			path.Root("preferred_root_cn"),
+			"Preferred Root Not Found",
+			fmt.Sprintf("None of the %d chains returned for request ID %s ends in a certificate with common name %q, the first chain is used instead.",
+				len(material.chains), certificates.ID, rootCN),
+		)
+	}
+
+	material.rootFirst = m.chainOrder() == chainOrderRootFirst
+	material.excludeRoot = !m.includeRootInChain()
+
+	if keyPEM := m.privateKeyPEM(); !keyPEM.IsNull() && keyPEM.ValueString() != "" {
+		key, err := parsePrivateKey(keyPEM.ValueString())
+		if err != nil {
+			diags.AddAttributeError(
+				path.Root("private_key_pem"),
+				"Invalid Private Key",
+				"Could not parse private_key_pem: "+err.Error(),
+			)
+			return diags
+		}
+		if !keyMatches(key, material.leaf.PublicKey) {
+			diags.AddAttributeError(
+				path.Root("private_key_pem"),
+				"Private Key Does Not Match Certificate",
+				fmt.Sprintf("private_key_pem is not the key of the certificate issued for request ID %s.", certificates.ID),
+			)
+			return diags
+		}
+		material.keyPEM = strings.TrimSpace(keyPEM.ValueString()) + "\n"
+	}
+
+	m.ThumbprintSHA1 = types.StringValue(fmt.Sprintf("%X", sha1.Sum(material.leaf.Raw)))
+	m.ThumbprintSHA256 = types.StringValue(fmt.Sprintf("%X", sha256.Sum256(material.leaf.Raw)))
+	m.SignatureAlgorithm = types.StringValue(material.leaf.SignatureAlgorithm.String())
+	m.CertificatePEM = types.StringValue(encodePEM(material.leaf))
+	m.NotAfter = types.StringValue(material.leaf.NotAfter.UTC().Format(time.RFC3339))
+	m.DispositionMessage = types.StringValue(dispositionMessageIssued)
+	m.CertificateDER = types.StringValue(base64.StdEncoding.EncodeToString(material.leaf.Raw))
+	m.CertificateChainDER = material.chainDER()
+	m.CertificateChainP7B = material.chainP7B()
+	m.CertificateChainPEM = material.chainPEM()
+	m.CertificateChain = material.certificateChain()
+	m.IssuingCAPEM = material.issuingCAPEM()
+	m.RootCAPEM = material.rootCAPEM()
+	m.CertificateChains = material.certificateChains()
+	m.KubernetesTLSSecret = material.kubernetesTLSSecret()
+	m.AzureKeyVaultCertificate = material.azureKeyVaultCertificate()
+	m.CombinedPEM = material.combinedPEM()
+	if m.PKCS12B64.IsUnknown() {
+		diags.Append(m.setPKCS12(ctx, material)...)
+	}
+
+	return diags
+}
@@ -0,0 +1,326 @@
+package provider
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/flipyap/microsoft-adcs-client/client"
+	"github.com/hashicorp/terraform-plugin-framework/diag"
+	"github.com/hashicorp/terraform-plugin-framework/path"
+	"github.com/hashicorp/terraform-plugin-framework/resource"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/planmodifier"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/stringplanmodifier"
+	"github.com/hashicorp/terraform-plugin-framework/schema/validator"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+	"github.com/hashicorp/terraform-plugin-log/tflog"
+)
+
+// Dispositions of a submitted request as the certificate_request resource reports them.
+const (
+	dispositionIssued  = "issued"
+	dispositionPending = "pending"
+	dispositionDenied  = "denied"
+)
+
+// Ensure the implementation satisfies the expected interfaces.
+var (
+	_ resource.Resource                   = &certificateRequestResource{}
+	_ resource.ResourceWithConfigure      = &certificateRequestResource{}
+	_ resource.ResourceWithImportState    = &certificateRequestResource{}
+	_ resource.ResourceWithValidateConfig = &certificateRequestResource{}
+)
+
+// NewCertificateRequestResource is a helper function to simplify the provider implementation.
+func NewCertificateRequestResource() resource.Resource {
+	return &certificateRequestResource{}
+}
+
+// certificateRequestResource submits a request without waiting for it to be issued. Refreshes pick
+// up the certificate once a CA manager approved the request, which may be days later.
+type certificateRequestResource struct {
+	client   *client.ADCSClient
+	provider *providerData
+}
+
+type certificateRequestModel struct {
+	ID                 types.String `tfsdk:"id"`
+	CSR                types.String `tfsdk:"certificate_signing_request"`
+	Template           types.String `tfsdk:"template"`
+	RequestAttributes  types.String `tfsdk:"request_attributes"`
+	Disposition        types.String `tfsdk:"disposition"`
+	DispositionMessage types.String `tfsdk:"disposition_message"`
+	CertificatePEM     types.String `tfsdk:"certificate_pem"`
+	CAName             types.String `tfsdk:"ca_name"`
+}
+
+// Metadata returns the resource type name.
+func (r *certificateRequestResource) Metadata(_ context.Context, req resource.MetadataRequest, resp *resource.MetadataResponse) {
+	resp.TypeName = req.ProviderTypeName + "_certificate_request"
+}
+
+// Schema defines the schema for the resource.
+func (r *certificateRequestResource) Schema(_ context.Context, _ resource.SchemaRequest, resp *resource.SchemaResponse) {
+	resp.Schema = schema.Schema{
+		Description: `Submits a certificate signing request (CSR) to ADCS and records its request ID and disposition without waiting
+for a CA manager to approve it. Refreshes update the disposition and pick up the certificate once it is issued, so approval can
+happen days after the apply. Hand the request ID to adopt_request_id of microsoftadcs_certificate to manage the issued certificate.`,
+		Attributes: map[string]schema.Attribute{
+			"id": schema.StringAttribute{
+				Description: "Request ID assigned by the CA.",
+				Computed:    true,
+				PlanModifiers: []planmodifier.String{
+					stringplanmodifier.UseStateForUnknown(),
+				},
+			},
+			"certificate_signing_request": schema.StringAttribute{
+				Required:    true,
+				Description: "The certificate signing request to submit, as PEM or base64 encoded DER. It is checked at plan time.",
+				PlanModifiers: []planmodifier.String{
+					stringplanmodifier.RequiresReplace(),
+				},
+				Validators: []validator.String{csrSyntaxValidator{}},
+			},
+			"template": schema.StringAttribute{
+				Required:    true,
+				Description: "Name of the certificate template to request the certificate from.",
+				PlanModifiers: []planmodifier.String{
+					stringplanmodifier.RequiresReplace(),
+				},
+			},
+			"request_attributes": schema.StringAttribute{
+				Optional: true,
+				Description: `Extra request attributes sent with the submission, one name:value pair per line, for example
+"san:dns=www.example.com" to request additional subject alternative names.`,
+				PlanModifiers: []planmodifier.String{
+					stringplanmodifier.RequiresReplace(),
+				},
+			},
+			"disposition": schema.StringAttribute{
+				Computed:    true,
+				Description: `State of the request at the last refresh: "pending", "issued" or "denied".`,
+			},
+			"disposition_message": schema.StringAttribute{
+				Computed: true,
+				Description: `Disposition message of the CA at the last refresh, such as "Issued", "Taken Under Submission" or
+"Denied by Policy Module  0x80094800". Failures that never reached the CA hold the error instead.`,
+			},
+			"certificate_pem": schema.StringAttribute{
+				Computed:    true,
+				Description: "The issued certificate, PEM encoded. Null until the request is issued.",
+			},
+			"ca_name": schema.StringAttribute{
+				Computed: true,
+				Description: `Name of the CA that took the request, from the banner of the certsrv page that answered the submission.
+With ca_hosts on the provider, refreshes go to the certsrv server of this CA.`,
+				PlanModifiers: []planmodifier.String{
+					stringplanmodifier.UseStateForUnknown(),
+				},
+			},
+		},
+	}
+}
+
+// Configure adds the provider configured client to the resource.
+func (r *certificateRequestResource) Configure(_ context.Context, req resource.ConfigureRequest, resp *resource.ConfigureResponse) {
+	if req.ProviderData == nil {
+		return
+	}
+
+	data, ok := req.ProviderData.(*providerData)
+	if !ok {
+		resp.Diagnostics.AddError(
+			"Unexpected Resource Configure Type",
+			fmt.Sprintf("Expected *providerData, got: %T. Please report this issue to the provider developers.", req.ProviderData),
+		)
+
+		return
+	}
+
+	r.client = data.client
+	r.provider = data
+}
+
+// Create submits the request and records whatever disposition the CA answered with.
+func (r *certificateRequestResource) Create(ctx context.Context, req resource.CreateRequest, resp *resource.CreateResponse) {
+	ctx, trace := withOperationTrace(ctx)
+	defer trace.annotate(&resp.Diagnostics)
+
+	if r.provider.readOnly {
+		resp.Diagnostics.Append(readOnlyError("submit a certificate request"))
+		return
+	}
+
+	var plan certificateRequestModel
+	resp.Diagnostics.Append(req.Plan.Get(ctx, &plan)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	nonce, err := newRequestNonce()
+	if err != nil {
+		resp.Diagnostics.AddError("Error Generating Request Nonce", err.Error())
+		return
+	}
+	route := r.provider.newCARoute("")
+	certificates, err := submitCertificateRequest(withCARoute(ctx, route), r.client, r.provider.parser, certsrvSubmission{
+		CSR:        plan.CSR.ValueString(),
+		Template:   plan.Template.ValueString(),
+		Attributes: append(splitAttributes(plan.RequestAttributes.ValueString()), requestNonceAttribute+":"+nonce),
+		Chunked:    r.provider.chunkedSubmissions,
+	})
+	plan.CAName = route.caNameValue()
+	if err != nil {
+		reqID, pending := pendingRequestID(err)
+		if !pending {
+			resp.Diagnostics.AddError(
+				classifiedSummary("Unable to Submit Certificate Request", err),
+				"The CA did not accept the request: "+err.Error()+
+					fmt.Sprintf("\n\nIf the CA received the request before the failure, it carries the request attribute %s:%s. "+
+						"Look it up in the CA database before retrying to avoid submitting it twice.", requestNonceAttribute, nonce),
+			)
+			return
+		}
+		plan.ID = types.StringValue(reqID)
+		plan.setDisposition(nil, err)
+		if r.provider.approvalWebhookURL != "" {
+			r.notifyPendingApproval(ctx, plan, &resp.Diagnostics)
+		}
+	} else {
+		plan.ID = types.StringValue(certificates.ID)
+		plan.setDisposition(certificates, nil)
+		r.emitIssued(ctx, plan, certificates.CertificateB64, &resp.Diagnostics)
+	}
+
+	tflog.Info(ctx, "Submitted certificate request", map[string]interface{}{
+		"request_id":  plan.ID.ValueString(),
+		"disposition": plan.Disposition.ValueString(),
+	})
+	resp.Diagnostics.Append(resp.State.Set(ctx, plan)...)
+}
+
+// Read refreshes the disposition of requests that are still pending.
+func (r *certificateRequestResource) Read(ctx context.Context, req resource.ReadRequest, resp *resource.ReadResponse) {
+	ctx, trace := withOperationTrace(ctx)
+	defer trace.annotate(&resp.Diagnostics)
+
+	var state certificateRequestModel
+	resp.Diagnostics.Append(req.State.Get(ctx, &state)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+	// Issued and denied requests do not change anymore, and pending ones are only checked where
+	// refreshes can reach the CA.
+	if (!state.Disposition.IsNull() && state.Disposition.ValueString() != dispositionPending) || r.provider.caOffline(ctx) {
+		return
+	}
+
+	reqID := state.ID.ValueString()
+	routed := withCARoute(ctx, r.provider.newCARoute(state.CAName.ValueString()))
+	certificates, err := retrieveCertificate(routed, r.client, r.provider.parser, reqID)
+	if err != nil && isTransient(err) {
+		resp.Diagnostics.AddError(
+			classifiedSummary("Error Reading Certificate Request", err),
+			fmt.Sprintf("Could not read the disposition of request ID %s: %s", reqID, err.Error()),
+		)
+		return
+	}
+	if err != nil && !isStillPending(err) {
+		if unsupported := r.provider.unsupportedFeature(ctx, featurePendingDisposition); unsupported != nil {
+			resp.Diagnostics.AddError("Not Supported on This ADCS Version", unsupported.Error()+": "+err.Error())
+			return
+		}
+	}
+	state.setDisposition(certificates, err)
+	if err == nil {
+		r.emitIssued(ctx, state, certificates.CertificateB64, &resp.Diagnostics)
+	}
+
+	resp.Diagnostics.Append(resp.State.Set(ctx, &state)...)
+}
+
+// notifyPendingApproval tells the approval webhook about a request waiting for a CA manager.
+func (r *certificateRequestResource) notifyPendingApproval(ctx context.Context, plan certificateRequestModel, diags *diag.Diagnostics) {
+	payload := approvalRequest{
+		Event:     "pending",
+		RequestID: plan.ID.ValueString(),
+		Template:  plan.Template.ValueString(),
+		Host:      r.client.HostURL,
+	}
+	if request, err := parseCertificateRequest(plan.CSR.ValueString()); err == nil {
+		payload.Subject = request.Subject.String()
+	}
+	if err := r.provider.notifyApprovalWebhook(ctx, payload); err != nil {
+		diags.AddWarning(
+			"Unable to Notify Approval Webhook",
+			fmt.Sprintf("Request ID %s is pending approval but the approval webhook could not be called: %s", payload.RequestID, err.Error()),
+		)
+	}
+}
+
+// emitIssued publishes the issued event once the request is first seen issued.
+func (r *certificateRequestResource) emitIssued(ctx context.Context, m certificateRequestModel, certB64 string, diags *diag.Diagnostics) {
+	reqID := m.ID.ValueString()
+	if err := r.provider.emitEvent(ctx, newLifecycleEvent(eventIssued, r.client.HostURL, reqID, m.Template.ValueString(), certB64)); err != nil {
+		diags.AddWarning(
+			"Unable to Publish Certificate Event",
+			fmt.Sprintf("Request ID %s was %s but the %q event could not be published: %s", reqID, eventIssued, eventIssued, err.Error()),
+		)
+	}
+}
+
+// Update only happens when nothing that requires a replacement changed, so there is nothing to do.
+func (r *certificateRequestResource) Update(ctx context.Context, req resource.UpdateRequest, resp *resource.UpdateResponse) {
+	var plan certificateRequestModel
+	resp.Diagnostics.Append(req.Plan.Get(ctx, &plan)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+	resp.Diagnostics.Append(resp.State.Set(ctx, plan)...)
+}
+
+// Delete removes the request from state, it stays in the CA database.
+func (r *certificateRequestResource) Delete(_ context.Context, _ resource.DeleteRequest, resp *resource.DeleteResponse) {
+	if r.provider.readOnly {
+		resp.Diagnostics.Append(readOnlyError("remove a certificate request from state"))
+	}
+}
+
+// ImportState imports a request by its request ID.
+func (r *certificateRequestResource) ImportState(ctx context.Context, req resource.ImportStateRequest, resp *resource.ImportStateResponse) {
+	resource.ImportStatePassthroughID(ctx, path.Root("id"), req, resp)
+}
+
+// ValidateConfig rejects requests for keys ADCS does not issue certificates for before they are submitted.
+func (r *certificateRequestResource) ValidateConfig(ctx context.Context, req resource.ValidateConfigRequest, resp *resource.ValidateConfigResponse) {
+	var csr types.String
+	resp.Diagnostics.Append(req.Config.GetAttribute(ctx, path.Root("certificate_signing_request"), &csr)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+	resp.Diagnostics.Append(validateRequestKey(csr)...)
+}
+
+// setDisposition records the outcome of a submission or download of the request.
+func (m *certificateRequestModel) setDisposition(certificates *client.Certificates, err error) {
+	switch {
+	case err == nil:
+		m.Disposition = types.StringValue(dispositionIssued)
+		m.DispositionMessage = types.StringValue(dispositionMessage(err))
+		m.CertificatePEM = types.StringValue(normalizePEM(certificates.CertificateB64))
+		if cert, err := parseCertificate(certificates.CertificateB64); err == nil {
+			m.CertificatePEM = types.StringValue(encodePEM(cert))
+		}
+	case isStillPending(err):
+		m.Disposition = types.StringValue(dispositionPending)
+		m.DispositionMessage = types.StringValue(dispositionMessage(err))
+		m.CertificatePEM = types.StringNull()
+	default:
+		m.Disposition = types.StringValue(dispositionDenied)
+		m.DispositionMessage = types.StringValue(err.Error())
+		if message := dispositionMessage(err); message != "" {
+			m.DispositionMessage = types.StringValue(message)
+		}
+		m.CertificatePEM = types.StringNull()
+	}
+}
@@ -0,0 +1,66 @@
+package provider
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-testing/helper/resource"
+)
+
+func TestAccCertificateRequestResource(t *testing.T) {
+	forEachAuthMode(t, func(t *testing.T, srv *accCertsrv) {
+		config := srv.providerConfig() + fmt.Sprintf(`
+resource "microsoftadcs_certificate_request" "test" {
+  certificate_signing_request = base64decode(%q)
+  template                    = %q
+}
+`, csr, accApprovalTemplate)
+
+		resource.Test(t, resource.TestCase{
+			ProtoV6ProviderFactories: testAccProtoV6ProviderFactories,
+			Steps: []resource.TestStep{
+				{
+					Config: config,
+					Check: resource.ComposeAggregateTestCheckFunc(
+						resource.TestCheckResourceAttrSet("microsoftadcs_certificate_request.test", "id"),
+						resource.TestCheckResourceAttr("microsoftadcs_certificate_request.test", "disposition", dispositionPending),
+						resource.TestCheckNoResourceAttr("microsoftadcs_certificate_request.test", "certificate_pem"),
+					),
+				},
+				// The acceptance certsrv approves the request when it is first polled for, which the
+				// refresh of the next step does.
+				{
+					Config: config,
+					Check: resource.ComposeAggregateTestCheckFunc(
+						resource.TestCheckResourceAttr("microsoftadcs_certificate_request.test", "disposition", dispositionIssued),
+						resource.TestCheckResourceAttrSet("microsoftadcs_certificate_request.test", "certificate_pem"),
+					),
+				},
+			},
+		})
+	})
+}
+
+func TestCertificateRequestModelSetDisposition(t *testing.T) {
+	var m certificateRequestModel
+
+	m.setDisposition(nil, errors.New("certificate pending for request id 7"))
+	if m.Disposition.ValueString() != dispositionPending || !m.CertificatePEM.IsNull() || m.DispositionMessage.ValueString() != dispositionMessagePending {
+		t.Errorf("pending: disposition = %s, message = %s, certificate_pem = %s", m.Disposition, m.DispositionMessage, m.CertificatePEM)
+	}
+
+	m.setDisposition(newTestHierarchy(t).testCertificates(t), nil)
+	if m.Disposition.ValueString() != dispositionIssued || m.DispositionMessage.ValueString() != dispositionMessageIssued {
+		t.Errorf("issued: disposition = %s, message = %s", m.Disposition, m.DispositionMessage)
+	}
+	if _, err := parseCertificate(m.CertificatePEM.ValueString()); err != nil {
+		t.Errorf("issued: certificate_pem is not a certificate: %v", err)
+	}
+
+	_, err := strictParser.requestID(`The disposition message is "Denied by Policy Module"`)
+	m.setDisposition(nil, err)
+	if m.Disposition.ValueString() != dispositionDenied || m.DispositionMessage.ValueString() != "Denied by Policy Module" || !m.CertificatePEM.IsNull() {
+		t.Errorf("denied: disposition = %s, message = %s, certificate_pem = %s", m.Disposition, m.DispositionMessage, m.CertificatePEM)
+	}
+}
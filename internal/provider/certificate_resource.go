@@ -6,22 +6,30 @@ import (
 	"strings"
 	"time"
 
-	"github.com/fatih/structs"
 	"github.com/flipyap/microsoft-adcs-client/client"
+	"github.com/hashicorp/terraform-plugin-framework/diag"
 	"github.com/hashicorp/terraform-plugin-framework/path"
 	"github.com/hashicorp/terraform-plugin-framework/resource"
 	"github.com/hashicorp/terraform-plugin-framework/resource/schema"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/boolplanmodifier"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/int64planmodifier"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/listplanmodifier"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/mapplanmodifier"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/objectplanmodifier"
 	"github.com/hashicorp/terraform-plugin-framework/resource/schema/planmodifier"
 	"github.com/hashicorp/terraform-plugin-framework/resource/schema/stringplanmodifier"
+	"github.com/hashicorp/terraform-plugin-framework/schema/validator"
 	"github.com/hashicorp/terraform-plugin-framework/types"
 	"github.com/hashicorp/terraform-plugin-log/tflog"
 )
 
 // Ensure the implementation satisfies the expected interfaces.
 var (
-	_ resource.Resource                = &certificateResource{}
-	_ resource.ResourceWithConfigure   = &certificateResource{}
-	_ resource.ResourceWithImportState = &certificateResource{}
+	_ resource.Resource                   = &certificateResource{}
+	_ resource.ResourceWithConfigure      = &certificateResource{}
+	_ resource.ResourceWithImportState    = &certificateResource{}
+	_ resource.ResourceWithValidateConfig = &certificateResource{}
+	_ resource.ResourceWithModifyPlan     = &certificateResource{}
 )
 
 // NewCertificateResource is a helper function to simplify the provider implementation.
@@ -31,17 +39,151 @@ func NewCertificateResource() resource.Resource {
 
 // certificateResource is the resource implementation.
 type certificateResource struct {
-	client *client.ADCSClient
+	client   *client.ADCSClient
+	provider *providerData
 }
 
 type certificateCreateModel struct {
 	ID                  types.String `tfsdk:"id"`
 	Attributes          types.String `tfsdk:"attributes"`
+	RequestAttributes   types.String `tfsdk:"request_attributes"`
+	AttributesMap       types.Map    `tfsdk:"attributes_map"`
 	CSR                 types.String `tfsdk:"certificate_signing_request"`
+	RequestFormat       types.String `tfsdk:"request_format"`
 	Template            types.String `tfsdk:"template"`
 	CertificateB64      types.String `tfsdk:"certificate_b64"`
 	CertificateChainB64 types.String `tfsdk:"certificate_chain_b64"`
 	LastUpdated         types.String `tfsdk:"last_updated"`
+	AllowedSANPatterns  types.List   `tfsdk:"allowed_san_patterns"`
+	MaxValidityHours    types.Int64  `tfsdk:"max_accepted_validity_hours"`
+	ReissueEveryApply   types.Bool   `tfsdk:"reissue_every_apply"`
+	Triggers            types.Map    `tfsdk:"triggers"`
+	KubernetesTLSSecret types.Map    `tfsdk:"kubernetes_tls_secret"`
+
+	AzureKeyVaultCertificate types.Object `tfsdk:"azure_key_vault_certificate"`
+	PrivateKeyPEM            types.String `tfsdk:"private_key_pem"`
+	CombinedPEM              types.String `tfsdk:"combined_pem"`
+	CertificateChain         types.List   `tfsdk:"certificate_chain"`
+	CertificateDER           types.String `tfsdk:"certificate_der"`
+	CertificateChainDER      types.List   `tfsdk:"certificate_chain_der"`
+	CertificateChainP7B      types.String `tfsdk:"certificate_chain_p7b"`
+	IssuingCAPEM             types.String `tfsdk:"issuing_ca_pem"`
+	RootCAPEM                types.String `tfsdk:"root_ca_pem"`
+	CertificateChains        types.List   `tfsdk:"certificate_chains"`
+	PreferredRootCN          types.String `tfsdk:"preferred_root_cn"`
+	ChainOrder               types.String `tfsdk:"chain_order"`
+	IncludeRootInChain       types.Bool   `tfsdk:"include_root_in_chain"`
+	RequestNonce             types.String `tfsdk:"request_nonce"`
+	RenewalSchedule          types.String `tfsdk:"renewal_schedule"`
+	EarlyRenewalHours        types.Int64  `tfsdk:"early_renewal_hours"`
+	RotationPeriod           types.String `tfsdk:"rotation_period"`
+	ReadyForRenewal          types.Bool   `tfsdk:"ready_for_renewal"`
+	RenewExisting            types.Bool   `tfsdk:"renew_existing"`
+	StoreChain               types.Bool   `tfsdk:"store_chain"`
+	AdoptRequestID           types.String `tfsdk:"adopt_request_id"`
+	WaitForIssuance          types.Bool   `tfsdk:"wait_for_issuance"`
+	IssuanceTimeout          types.String `tfsdk:"issuance_timeout"`
+	PendingBehavior          types.String `tfsdk:"pending_behavior"`
+	OnMissing                types.String `tfsdk:"on_missing"`
+	CertificatePEM           types.String `tfsdk:"certificate_pem"`
+	CertificateChainPEM      types.String `tfsdk:"certificate_chain_pem"`
+	ThumbprintSHA1           types.String `tfsdk:"thumbprint_sha1"`
+	ThumbprintSHA256         types.String `tfsdk:"thumbprint_sha256"`
+	NotAfter                 types.String `tfsdk:"not_after"`
+	CAName                   types.String `tfsdk:"ca_name"`
+	DispositionMessage       types.String `tfsdk:"disposition_message"`
+	RequestDisposition       types.String `tfsdk:"request_disposition"`
+	RevocationStatus         types.String `tfsdk:"revocation_status"`
+	RevokedReason            types.String `tfsdk:"revoked_reason"`
+	RevocationDate           types.String `tfsdk:"revocation_date"`
+	Timeouts                 types.Object `tfsdk:"timeouts"`
+	Retry                    types.Object `tfsdk:"retry"`
+	PostIssuanceChecks       types.Object `tfsdk:"post_issuance_checks"`
+	RevokeOnDestroy          types.Bool   `tfsdk:"revoke_on_destroy"`
+	RevocationReason         types.String `tfsdk:"revocation_reason"`
+	SubjectAlternativeNames  types.Object `tfsdk:"subject_alternative_names"`
+	DNSNames                 types.List   `tfsdk:"dns_names"`
+	IPAddresses              types.List   `tfsdk:"ip_addresses"`
+	Emails                   types.List   `tfsdk:"emails"`
+	UPNs                     types.List   `tfsdk:"upns"`
+	URIs                     types.List   `tfsdk:"uris"`
+	GenerateCSR              types.Object `tfsdk:"generate_csr"`
+	GeneratedPrivateKeyPEM   types.String `tfsdk:"generated_private_key_pem"`
+	PKCS12                   types.Object `tfsdk:"pkcs12"`
+	PKCS12B64                types.String `tfsdk:"pkcs12_b64"`
+	RequestedNotBefore       types.String `tfsdk:"requested_not_before"`
+	RequestedNotAfter        types.String `tfsdk:"requested_not_after"`
+	ValidityPeriod           types.String `tfsdk:"validity_period"`
+	ValidityPeriodUnits      types.Int64  `tfsdk:"validity_period_units"`
+	RequesterName            types.String `tfsdk:"requester_name"`
+	FriendlyName             types.String `tfsdk:"friendly_name"`
+	CertificateDescription   types.String `tfsdk:"certificate_description"`
+	StateEncryptionPublicKey types.String `tfsdk:"state_encryption_public_key"`
+	SignatureHashAlgorithm   types.String `tfsdk:"signature_hash_algorithm"`
+	SignatureAlgorithm       types.String `tfsdk:"signature_algorithm"`
+
+	OnBehalfOf                    types.String `tfsdk:"on_behalf_of"`
+	EnrollmentAgentCertificatePEM types.String `tfsdk:"enrollment_agent_certificate_pem"`
+	EnrollmentAgentPrivateKeyPEM  types.String `tfsdk:"enrollment_agent_private_key_pem"`
+	Profile                       types.String `tfsdk:"profile"`
+	VerifyIssuedSANs              types.String `tfsdk:"verify_issued_sans"`
+	Host                          types.String `tfsdk:"host"`
+	Username                      types.String `tfsdk:"username"`
+	Password                      types.String `tfsdk:"password"`
+	DomainController              types.Object `tfsdk:"domain_controller"`
+}
+
+// requestAttributes returns the request attributes from request_attributes or the deprecated attributes.
+func (m *certificateCreateModel) requestAttributes() types.String {
+	return coalesceString(m.RequestAttributes, m.Attributes)
+}
+
+// submittedAttributes returns the request attributes together with those of attributes_map, the
+// san attribute built from the subject alternative names unless they go into the generated request, the ExpirationDate attribute of
+// requested_not_after, the ValidityPeriod and ValidityPeriodUnits attributes of validity_period, the RequesterName attribute of
+// requester_name and the FriendlyName attribute of friendly_name, one per line.
+func (m *certificateCreateModel) submittedAttributes(ctx context.Context) (string, diag.Diagnostics) {
+	attributes := splitAttributes(m.requestAttributes().ValueString())
+	mapped, diags := mapAttributes(ctx, m.AttributesMap)
+	attributes = append(attributes, mapped...)
+	san, sanDiags := sanRequestAttribute(ctx, m.subjectAlternativeNames())
+	diags.Append(sanDiags...)
+	if san != "" && !m.sanInRequest(ctx, san) {
+		attributes = append(attributes, san)
+	}
+	if expirationDate := m.expirationDateRequestAttribute(); expirationDate != "" {
+		attributes = append(attributes, expirationDate)
+	}
+	attributes = append(attributes, m.validityPeriodRequestAttributes()...)
+	if requesterName := m.requesterNameRequestAttribute(); requesterName != "" {
+		attributes = append(attributes, requesterName)
+	}
+	if friendlyName := m.friendlyNameRequestAttribute(); friendlyName != "" {
+		attributes = append(attributes, friendlyName)
+	}
+	return strings.Join(attributes, "\n"), diags
+}
+
+// privateKeyPEM returns private_key_pem, or the key generated for the generate_csr block unless it
+// is encrypted with state_encryption_public_key.
+func (m *certificateCreateModel) privateKeyPEM() types.String {
+	if isSealed(m.GeneratedPrivateKeyPEM.ValueString()) {
+		return m.PrivateKeyPEM
+	}
+	return coalesceString(m.PrivateKeyPEM, m.GeneratedPrivateKeyPEM)
+}
+
+// issuanceTimeout returns how long to wait for a pending request, ValidateConfig checks the format.
+func (m *certificateCreateModel) issuanceTimeout() time.Duration {
+	if timeout, err := time.ParseDuration(m.IssuanceTimeout.ValueString()); err == nil {
+		return timeout
+	}
+	return defaultIssuanceTimeout
+}
+
+// storeChain reports whether the certificate chain is kept in state, which is the default.
+func (m *certificateCreateModel) storeChain() bool {
+	return m.StoreChain.IsNull() || m.StoreChain.ValueBool()
 }
 
 // Metadata returns the resource type name.
@@ -52,6 +194,7 @@ func (r *certificateResource) Metadata(_ context.Context, req resource.MetadataR
 // Schema defines the schema for the resource.
 func (r *certificateResource) Schema(_ context.Context, _ resource.SchemaRequest, resp *resource.SchemaResponse) {
 	resp.Schema = schema.Schema{
+		Description: "Requests a certificate from ADCS for a certificate signing request (CSR) and keeps it in state.",
 		Attributes: map[string]schema.Attribute{
 			"id": schema.StringAttribute{
 				Description: "Numeric identifier of the generated certificate.",
@@ -61,38 +204,811 @@ func (r *certificateResource) Schema(_ context.Context, _ resource.SchemaRequest
 				},
 			},
 			"certificate_signing_request": schema.StringAttribute{
-				Required:    true,
-				Description: "The certificate signing request used to create a certificate ",
+				Optional: true,
+				Computed: true,
+				Description: `The certificate signing request used to create a certificate, as PEM or base64 encoded DER. Required 
+unless a generate_csr block is given, in which case it holds the request the provider generated. A request that does not 
+parse or whose signature does not verify fails the plan.`,
 				PlanModifiers: []planmodifier.String{
+					stringplanmodifier.UseStateForUnknown(),
 					stringplanmodifier.RequiresReplace(),
 				},
-				// TODO: make a validator that can validate base64: https://developer.hashicorp.com/terraform/plugin/framework/handling-data/types/custom
+				Validators: []validator.String{csrSyntaxValidator{}},
+			},
+			"request_format": schema.StringAttribute{
+				Optional: true,
+				Description: `Format of certificate_signing_request: "pkcs10", the default, for a certificate signing request, or 
+"pkcs7" for a renewal request signed with the key of the certificate it renews, as certreq -new with RenewalCert or an agent on the target 
+host builds it. The renewal request is submitted as is, so the private key never has to leave the host.`,
 			},
 			"template": schema.StringAttribute{
 				Required: true,
 				Description: `There are usually several predefined templates that make it easier to request certificates 
-depending on what they are needed for.`,
+depending on what they are needed for. Check with your ADCS administrator for the templates available to you.`,
+				PlanModifiers: []planmodifier.String{
+					stringplanmodifier.RequiresReplace(),
+				},
+			},
+			"host": schema.StringAttribute{
+				Optional: true,
+				Description: `Host of the certsrv server to request the certificate from instead of the host of the provider, so
+one provider block can issue from several issuing CAs. Request IDs are only unique per CA, so changing it requests a
+new certificate. The template check of the plan is skipped, it reads the templates the CA of the provider offers.`,
 				PlanModifiers: []planmodifier.String{
 					stringplanmodifier.RequiresReplace(),
 				},
 			},
+			"username": schema.StringAttribute{
+				Optional: true,
+				Description: `Active Directory username to authenticate to the CA with instead of the username of the provider.
+The authentication scheme, use_ntlm, and the Kerberos configuration of the provider apply. Requires password.`,
+			},
+			"password": schema.StringAttribute{
+				Optional:    true,
+				Sensitive:   true,
+				Description: "Password of username.",
+			},
+			"profile": schema.StringAttribute{
+				Optional: true,
+				Description: `Preset for a common kind of certificate: "tls_server", "mtls_client", "code_signing" or 
+"domain_controller". It picks the key generate_csr creates, the extended key usages post_issuance_checks expects, 
+early_renewal_hours and, for tls_server and domain_controller, requires a DNS name or IP address. Settings configured on 
+the resource override those of the profile. The domain_controller block implies "domain_controller".`,
+				PlanModifiers: []planmodifier.String{
+					stringplanmodifier.RequiresReplaceIf(
+						func(ctx context.Context, req planmodifier.StringRequest, resp *stringplanmodifier.RequiresReplaceIfFuncResponse) {
+							var generateCSR types.Object
+							resp.Diagnostics.Append(req.Plan.GetAttribute(ctx, path.Root("generate_csr"), &generateCSR)...)
+							resp.RequiresReplace = !generateCSR.IsNull()
+						},
+						"Replaces the resource when the value changes and generate_csr creates the key.",
+						"Replaces the resource when the value changes and `generate_csr` creates the key.",
+					),
+				},
+			},
 			"attributes": schema.StringAttribute{
+				Optional:           true,
+				Description:        "Extra attributes to add to the certificate",
+				DeprecationMessage: deprecationMessage("attributes"),
+				PlanModifiers: []planmodifier.String{
+					aliasRequiresReplace("request_attributes"),
+				},
+			},
+			"request_attributes": schema.StringAttribute{
+				Optional: true,
+				Description: `Extra request attributes sent with the submission, one name:value pair per line, for example 
+"san:dns=www.example.com" to request additional subject alternative names.`,
+				PlanModifiers: []planmodifier.String{
+					aliasRequiresReplace("attributes"),
+				},
+			},
+			"attributes_map": schema.MapAttribute{
+				ElementType: types.StringType,
+				Optional:    true,
+				Description: `Extra request attributes keyed by name, such as { ValidityPeriod = "Years", ValidityPeriodUnits = "1" }. 
+They are sent after request_attributes, one per line and sorted by name; the provider encodes the submission. Names cannot 
+repeat an attribute of request_attributes, and CertificateTemplate and ClientRequestNonce are set through template and request_nonce.`,
+				PlanModifiers: []planmodifier.Map{
+					mapplanmodifier.RequiresReplace(),
+				},
+			},
+			"dns_names": schema.ListAttribute{
+				ElementType: types.StringType,
+				Optional:    true,
+				Description: "DNS names, validated and converted to punycode at plan time. Requested through the san request attribute like subject_alternative_names, which conflicts with it.",
+				PlanModifiers: []planmodifier.List{
+					listplanmodifier.RequiresReplace(),
+				},
+			},
+			"ip_addresses": schema.ListAttribute{
+				ElementType: types.StringType,
+				Optional:    true,
+				Description: "IPv4 or IPv6 addresses. Requested through the san request attribute like subject_alternative_names, which conflicts with it.",
+				PlanModifiers: []planmodifier.List{
+					listplanmodifier.RequiresReplace(),
+				},
+			},
+			"emails": schema.ListAttribute{
+				ElementType: types.StringType,
+				Optional:    true,
+				Description: "Email addresses (RFC 822 names). Requested through the san request attribute like subject_alternative_names, which conflicts with it.",
+				PlanModifiers: []planmodifier.List{
+					listplanmodifier.RequiresReplace(),
+				},
+			},
+			"upns": schema.ListAttribute{
+				ElementType: types.StringType,
+				Optional:    true,
+				Description: "User principal names such as user@example.com. Requested through the san request attribute like subject_alternative_names, which conflicts with it.",
+				PlanModifiers: []planmodifier.List{
+					listplanmodifier.RequiresReplace(),
+				},
+			},
+			"uris": schema.ListAttribute{
+				ElementType: types.StringType,
 				Optional:    true,
-				Description: "Extra attributes to add to the certificate",
+				Description: "Absolute URIs such as spiffe://example.com/web. Requested through the san request attribute like subject_alternative_names, which conflicts with it.",
+				PlanModifiers: []planmodifier.List{
+					listplanmodifier.RequiresReplace(),
+				},
+			},
+			"allowed_san_patterns": schema.ListAttribute{
+				ElementType: types.StringType,
+				Optional:    true,
+				Description: `Regular expressions every requested DNS and UPN subject alternative name has to fully match. 
+Names are taken from the certificate signing request, a "san:" entry in request_attributes, subject_alternative_names, dns_names and upns. Requests asking for any 
+other name fail at plan time, or at apply time when the names are only known then, such as a CSR of a tls_cert_request 
+created in the same apply.`,
+			},
+			"verify_issued_sans": schema.StringAttribute{
+				Optional: true,
+				Description: `Compares the subject alternative names of the issued certificate with those requested in the certificate
+signing request, generate_csr, subject_alternative_names and the typed name lists, and fails with "error" or warns with
+"warn" when names were dropped or added. Policy modules do so silently when the template builds the subject from Active
+Directory or the CA ignores the san request attribute. Not checked when unset.`,
+			},
+			"max_accepted_validity_hours": schema.Int64Attribute{
+				Optional: true,
+				Description: `Longest certificate lifetime, in hours, accepted from the CA. If the issued certificate is valid for 
+longer, for example because of a misconfigured template, creation fails instead of storing the certificate.`,
+			},
+			"signature_hash_algorithm": schema.StringAttribute{
+				Optional: true,
+				Description: `Hash the CA has to sign the certificate with: SHA256, SHA384 or SHA512. ADCS signs with the hash 
+configured on the CA, RSASSA-PSS (PKCS#1 v2.1) included when the CA uses alternate signature algorithms, so this is not 
+sent with the request. Creation fails instead of storing a certificate signed with another hash.`,
+			},
+			"requested_not_before": schema.StringAttribute{
+				Optional: true,
+				Description: `RFC 3339 timestamp the certificate should be valid from. ADCS starts the validity when it issues the 
+certificate, so this is not sent to the CA; a warning is shown when the issued certificate starts later.`,
 				PlanModifiers: []planmodifier.String{
 					stringplanmodifier.RequiresReplace(),
 				},
 			},
-			"certificate_b64": schema.StringAttribute{
+			"requested_not_after": schema.StringAttribute{
+				Optional: true,
+				Description: `RFC 3339 timestamp the certificate should expire at, sent as the ExpirationDate request attribute. 
+The CA honors it only with the EDITF_ATTRIBUTEENDDATE policy flag set; a warning is shown when the issued certificate expires at another time.`,
+				PlanModifiers: []planmodifier.String{
+					stringplanmodifier.RequiresReplace(),
+				},
+			},
+			"validity_period": schema.StringAttribute{
+				Optional: true,
+				Description: `Unit of the validity period to request, counted from issuance: "Seconds", "Minutes", "Hours", "Days",
+"Weeks", "Months" or "Years". Sent with validity_period_units as the ValidityPeriod and ValidityPeriodUnits request
+attributes, which standalone CAs and CAs with the EDITF_ATTRIBUTEENDDATE policy flag honor; a warning is shown when the
+issued certificate is valid for another period. Cannot be combined with requested_not_after.`,
+				PlanModifiers: []planmodifier.String{
+					stringplanmodifier.RequiresReplace(),
+				},
+			},
+			"validity_period_units": schema.Int64Attribute{
+				Optional:    true,
+				Description: "Number of validity_period units to request, such as 2 with \"Years\".",
+				PlanModifiers: []planmodifier.Int64{
+					int64planmodifier.RequiresReplace(),
+				},
+			},
+			"on_behalf_of": schema.StringAttribute{
+				Optional: true,
+				Description: `Account to enroll on behalf of, in the DOMAIN\user form, such as for smart card or user certificates 
+issued by a service identity. The request is wrapped in a PKCS#7 signed with the enrollment agent certificate that names 
+the account, and the CA issues the certificate for it when the template requires an enrollment agent signature and the 
+agent is allowed to enroll for the account. Requires enrollment_agent_certificate_pem and 
+enrollment_agent_private_key_pem.`,
+				PlanModifiers: []planmodifier.String{
+					stringplanmodifier.RequiresReplace(),
+				},
+			},
+			"enrollment_agent_certificate_pem": schema.StringAttribute{
+				Optional:    true,
+				Description: "PEM encoded enrollment agent certificate, with the Certificate Request Agent extended key usage, that signs on_behalf_of requests.",
+			},
+			"enrollment_agent_private_key_pem": schema.StringAttribute{
+				Optional:  true,
+				Sensitive: true,
+				Description: `PEM encoded private key of enrollment_agent_certificate_pem. It is kept in the Terraform state like 
+private_key_pem.`,
+			},
+			"requester_name": schema.StringAttribute{
+				Optional: true,
+				Description: `Account the request is recorded under in the CA database, in the DOMAIN\user form, sent as the 
+RequesterName request attribute. For service desk style issuance, so the CA database names the owner of the certificate 
+rather than the automation account. The CA only honors it when the submitting account may request on behalf of others, 
+for example as an enrollment agent; otherwise the request is denied.`,
+				PlanModifiers: []planmodifier.String{
+					stringplanmodifier.RequiresReplace(),
+				},
+			},
+			"friendly_name": schema.StringAttribute{
+				Optional: true,
+				Description: `Name of the request in the CA database, sent as the FriendlyName request attribute, such as the address 
+of the resource. The Certification Authority console and certutil -view exports show it with the request attributes, so 
+issued certificates can be traced back to the Terraform resource that requested them.`,
+				PlanModifiers: []planmodifier.String{
+					stringplanmodifier.RequiresReplace(),
+				},
+			},
+			"certificate_description": schema.StringAttribute{
+				Optional: true,
+				Description: `Description of the request in the CA database, sent as the CertificateDescription request attribute. 
+Without it requests are described as "Managed by Terraform, workspace <name>", with the workspace of the provider, so 
+certificates Terraform manages stand out in the Certification Authority console. Set it to "" to send no description.`,
+				PlanModifiers: []planmodifier.String{
+					stringplanmodifier.RequiresReplace(),
+				},
+			},
+			"state_encryption_public_key": schema.StringAttribute{
+				Optional: true,
+				Description: `PEM encoded RSA public key, or a certificate holding one, that generated_private_key_pem and pkcs12_b64 are 
+encrypted with before they are written to state. combined_pem, kubernetes_tls_secret and azure_key_vault_certificate then 
+leave the generated key out. Decrypt the values with the decrypt mode of the provider binary and the matching private 
+key. Changing the key replaces the certificate, as values sealed for the old key cannot be opened by the provider.`,
+				PlanModifiers: []planmodifier.String{
+					stringplanmodifier.RequiresReplace(),
+				},
+			},
+			"private_key_pem": schema.StringAttribute{
+				Optional:  true,
+				Sensitive: true,
+				Description: `PEM encoded private key belonging to the certificate signing request. It is never sent to ADCS, 
+it is only used to build the outputs that bundle the key with the certificate.`,
+				PlanModifiers: []planmodifier.String{
+					stringplanmodifier.RequiresReplace(),
+				},
+			},
+			"generated_private_key_pem": schema.StringAttribute{
+				Computed:  true,
+				Sensitive: true,
+				Description: `PEM encoded PKCS#8 private key the provider created for the generate_csr block. It is kept in state, 
+so protect the state accordingly, or encrypt it with state_encryption_public_key. Null when the certificate signing request was provided.`,
+				PlanModifiers: []planmodifier.String{
+					stringplanmodifier.UseStateForUnknown(),
+				},
+			},
+			"adopt_request_id": schema.StringAttribute{
+				Optional: true,
+				Description: `Request ID of an existing certificate to take over instead of submitting certificate_signing_request. 
+The certificate has to be issued for the public key of the certificate signing request. Meant for bringing manually issued 
+certificates under management without terraform import. Removing it afterwards keeps the adopted certificate.`,
+				PlanModifiers: []planmodifier.String{
+					stringplanmodifier.RequiresReplaceIf(
+						func(_ context.Context, req planmodifier.StringRequest, resp *stringplanmodifier.RequiresReplaceIfFuncResponse) {
+							resp.RequiresReplace = !req.PlanValue.IsNull()
+						},
+						"Adopting a different request replaces the certificate, removing adopt_request_id does not.",
+						"Adopting a different request replaces the certificate, removing `adopt_request_id` does not.",
+					),
+				},
+			},
+			"wait_for_issuance": schema.BoolAttribute{
+				Optional: true,
+				Description: `Wait for a CA manager to approve requests the CA takes under submission instead of failing the apply, 
+the same as pending_behavior = "wait". The pending requests of all resources are checked together, 30 seconds after 
+submission and then less often up to every 5 minutes, backing off while the CA is unreachable, until they are issued, 
+denied or issuance_timeout passes.`,
+			},
+			"issuance_timeout": schema.StringAttribute{
+				Optional:    true,
+				Description: `How long pending_behavior = "wait" waits for approval, as a duration such as "30m" or "4h". Defaults to "1h".`,
+			},
+			"pending_behavior": schema.StringAttribute{
+				Optional: true,
+				Description: `What to do when the CA takes the request under submission: "error" fails the apply, "wait" waits for 
+a CA manager like wait_for_issuance, and "save" keeps the request ID in state with request_disposition "pending" and 
+null certificate outputs, taking over the certificate on the first apply after it was issued. Defaults to "wait" with 
+wait_for_issuance and to "error" otherwise.`,
+			},
+			"on_missing": schema.StringAttribute{
+				Optional: true,
+				Description: `What a refresh does when the CA database no longer holds the request, such as after old rows were 
+deleted during CA database maintenance: "ignore", the default, keeps the certificate in state with request_disposition 
+"error" and a warning, "error" fails the refresh, and "recreate" removes the certificate from state so the next apply 
+requests a new one.`,
+			},
+			"revoke_on_destroy": schema.BoolAttribute{
+				Optional: true,
+				Description: `Revoke the certificate on the CA when the resource is destroyed or replaced, through the 
+revocation_webhook_url of the provider. A failed revocation fails the destroy and keeps the certificate in state. A 
+request saved pending has no certificate to revoke, it is denied through the denial_webhook_url of the provider when one 
+is configured.`,
+			},
+			"revocation_reason": schema.StringAttribute{
+				Optional: true,
+				Description: `Reason revoke_on_destroy revokes the certificate with: unspecified, key_compromise, ca_compromise, 
+affiliation_changed, superseded, cessation_of_operation or certificate_hold. Defaults to "unspecified".`,
+			},
+			"request_nonce": schema.StringAttribute{
+				Optional: true,
+				Computed: true,
+				Description: `Idempotency token sent with the submission as the ClientRequestNonce request attribute, so a request 
+that reached the CA before a network failure can be found in the CA database and correlated with this resource. 
+Generated when not set; set it, for example from a random_uuid resource, to keep the same token across failed applies.`,
+				PlanModifiers: []planmodifier.String{
+					stringplanmodifier.UseStateForUnknown(),
+					stringplanmodifier.RequiresReplace(),
+				},
+			},
+			"reissue_every_apply": schema.BoolAttribute{
+				Optional: true,
+				Description: `Request a fresh certificate on every apply. Meant for short-lived, per-deployment credentials: 
+the resource is always planned for replacement and the previous certificate is simply discarded.`,
+			},
+			"triggers": schema.MapAttribute{
+				ElementType: types.StringType,
+				Optional:    true,
+				Description: `Arbitrary values that replace the certificate when they change, to tie rotation to external 
+signals such as an image ID or a key rotation timestamp. Adding triggers to a certificate that has none, or removing 
+them, does not replace it.`,
+				PlanModifiers: []planmodifier.Map{
+					mapplanmodifier.RequiresReplaceIf(
+						func(_ context.Context, req planmodifier.MapRequest, resp *mapplanmodifier.RequiresReplaceIfFuncResponse) {
+							resp.RequiresReplace = !req.StateValue.IsNull() && !req.PlanValue.IsNull()
+						},
+						"Changing the triggers replaces the certificate, adding or removing them does not.",
+						"Changing the triggers replaces the certificate, adding or removing them does not.",
+					),
+				},
+			},
+			"renewal_schedule": schema.StringAttribute{
+				Optional: true,
+				Description: `Cron expression (minute hour day-of-month month day-of-week, in UTC) of the maintenance windows 
+renewals may happen in, for example "0 2 * * 0#1" for 02:00 on the first Sunday of the month. Evaluated on refresh: once 
+two thirds of the lifetime have passed, the certificate is replaced in the first window. The @monthly, @weekly and @daily 
+descriptors are supported as well.`,
+			},
+			"early_renewal_hours": schema.Int64Attribute{
+				Optional: true,
+				Description: `Replace the certificate once it expires within this many hours. Checked on refresh and at plan time, 
+independently of renewal_schedule, so a certificate is renewed in time even when no scheduled window is left.`,
+			},
+			"rotation_period": schema.StringAttribute{
+				Optional: true,
+				Description: `Replace the certificate once it is older than this duration, for example "720h" for rotation policies 
+shorter than the certificate lifetime. The age counts from the not_before of the certificate. Checked on refresh and at 
+plan time alongside renewal_schedule and early_renewal_hours, the earliest of them wins.`,
+			},
+			"ready_for_renewal": schema.BoolAttribute{
 				Computed:    true,
-				Description: "The certificate returned from ADCS as base64 encoded.",
+				Description: "Set on refresh when the certificate is due for renewal, the next apply then replaces or, with renew_existing, renews it.",
+				PlanModifiers: []planmodifier.Bool{
+					boolplanmodifier.UseStateForUnknown(),
+				},
+			},
+			"renew_existing": schema.BoolAttribute{
+				Optional: true,
+				Description: `Renew a certificate that is due for renewal in place instead of replacing it. The certificate signing 
+request is submitted again as an ADCS renewal request, signed with the key of the current certificate, so the CA keeps 
+the template and subject of that certificate and templates that only allow renewal accept it. The renewed certificate 
+keeps the key, so the private key has to be in private_key_pem or generated by generate_csr.`,
+			},
+			"store_chain": schema.BoolAttribute{
+				Optional: true,
+				Description: `Keep the certificate chain in state, defaults to true. When false, certificate_chain_b64, 
+certificate_chain and certificate_chains stay empty, the bundled outputs only contain the leaf certificate and refreshes skip downloading the 
+chain. Use the microsoftadcs_ca_chain data source to get the chain instead.`,
+			},
+			"certificate_b64": schema.StringAttribute{
+				Computed:           true,
+				Description:        "The certificate returned from ADCS as base64 encoded PEM, normalized to LF line endings and 64 column lines.",
+				DeprecationMessage: deprecationMessage("certificate_b64"),
+				PlanModifiers: []planmodifier.String{
+					stringplanmodifier.UseStateForUnknown(),
+				},
 			},
 			"certificate_chain_b64": schema.StringAttribute{
 				Computed:    true,
-				Description: "The certificate chain returned from ADCS as base64 encoded.",
+				Description: "The certificate chain returned from ADCS as base64 encoded PEM, normalized to LF line endings and 64 column lines.",
+				PlanModifiers: []planmodifier.String{
+					stringplanmodifier.UseStateForUnknown(),
+				},
+			},
+			"certificate_pem": schema.StringAttribute{
+				Computed:    true,
+				Description: "The certificate returned from ADCS, PEM encoded.",
+				PlanModifiers: []planmodifier.String{
+					stringplanmodifier.UseStateForUnknown(),
+				},
 			},
-			"last_updated": schema.StringAttribute{
+			"certificate_chain_pem": schema.StringAttribute{
+				Computed: true,
+				Description: `The issuers of the certificate, PEM encoded and concatenated in the order of chain_order, starting 
+with the issuing CA by default. Follows preferred_root_cn when the CA returns several chains and include_root_in_chain, 
+null when store_chain is false.`,
+				PlanModifiers: []planmodifier.String{
+					stringplanmodifier.UseStateForUnknown(),
+				},
+			},
+			"thumbprint_sha1": schema.StringAttribute{
+				Computed:    true,
+				Description: "SHA-1 thumbprint of the certificate as upper-case hex, the form Windows, IIS bindings and Intune use.",
+				PlanModifiers: []planmodifier.String{
+					stringplanmodifier.UseStateForUnknown(),
+				},
+			},
+			"thumbprint_sha256": schema.StringAttribute{
+				Computed:    true,
+				Description: "SHA-256 thumbprint of the certificate as upper-case hex.",
+				PlanModifiers: []planmodifier.String{
+					stringplanmodifier.UseStateForUnknown(),
+				},
+			},
+			"signature_algorithm": schema.StringAttribute{
+				Computed:    true,
+				Description: "Algorithm the CA signed the certificate with, such as SHA256-RSA, SHA384-RSAPSS or ECDSA-SHA384.",
+				PlanModifiers: []planmodifier.String{
+					stringplanmodifier.UseStateForUnknown(),
+				},
+			},
+			"not_after": schema.StringAttribute{
+				Computed: true,
+				Description: `When the certificate expires, in RFC 3339 format. A certificate that has expired is replaced by the next 
+plan, also without early_renewal_hours or renewal_schedule and with renew_existing, as the CA does not accept renewals 
+signed with an expired certificate.`,
+				PlanModifiers: []planmodifier.String{
+					stringplanmodifier.UseStateForUnknown(),
+				},
+			},
+			"ca_name": schema.StringAttribute{
+				Computed: true,
+				Description: `Name of the CA that took the request, from the banner of the certsrv page that answered the submission. 
+With ca_hosts on the provider, downloads and polls for the request go to the certsrv server of this CA.`,
+				PlanModifiers: []planmodifier.String{
+					stringplanmodifier.UseStateForUnknown(),
+				},
+			},
+			"disposition_message": schema.StringAttribute{
+				Computed: true,
+				Description: `Disposition message the CA recorded for the request, "Issued" for a certificate in state. Requests that 
+were denied or failed do not end up in state, their disposition message is part of the error.`,
+				PlanModifiers: []planmodifier.String{
+					stringplanmodifier.UseStateForUnknown(),
+				},
+			},
+			"request_disposition": schema.StringAttribute{
+				Computed: true,
+				Description: `Status of the request, for conditions and checks: "issued" once created, then refreshed to "revoked" 
+when the OCSP responder of the certificate reports it revoked, or to "pending", "denied" or "error" when the CA no 
+longer hands out the certificate of the request.`,
+				PlanModifiers: []planmodifier.String{
+					stringplanmodifier.UseStateForUnknown(),
+				},
+			},
+			"revocation_status": schema.StringAttribute{
+				Computed: true,
+				Description: `Revocation status of the certificate as its OCSP responder reports it on refresh: "good", "revoked" 
+or "unknown". Null until the first refresh, and when the certificate names no OCSP responder, the responder cannot be 
+reached or fetch_aia_issuers is false. A revoked certificate is not replaced, check this attribute in conditions to act 
+on it.`,
+				PlanModifiers: []planmodifier.String{
+					stringplanmodifier.UseStateForUnknown(),
+				},
+			},
+			"revoked_reason": schema.StringAttribute{
+				Computed: true,
+				Description: `Reason the CA revoked the certificate with, as the OCSP responder reports it, for example 
+key_compromise or superseded. Null unless revocation_status is "revoked". Not to be confused with revocation_reason, 
+the reason revoke_on_destroy revokes with.`,
+				PlanModifiers: []planmodifier.String{
+					stringplanmodifier.UseStateForUnknown(),
+				},
+			},
+			"revocation_date": schema.StringAttribute{
+				Computed:    true,
+				Description: `When the CA revoked the certificate, in RFC 3339 format. Null unless revocation_status is "revoked".`,
+				PlanModifiers: []planmodifier.String{
+					stringplanmodifier.UseStateForUnknown(),
+				},
+			},
+			"preferred_root_cn": schema.StringAttribute{
+				Optional: true,
+				Description: `Common name of the root the bundled outputs should chain up to when the CA returns several chains, 
+for example with cross-signed intermediates. Without it, or when no chain ends in that root, the first chain of 
+certificate_chains, the shortest, is used.`,
+			},
+			"chain_order": schema.StringAttribute{
+				Optional: true,
+				Description: `Order of the certificates in certificate_chain_pem, certificate_chain, certificate_chain_der and 
+certificate_chains: "leaf-first", the default, starts with the issuing CA and ends with the root like NGINX and Java 
+keystores expect, "root-first" starts with the root like some Windows and appliance imports expect. The bundled outputs 
+such as combined_pem and kubernetes_tls_secret keep their own layout.`,
+			},
+			"include_root_in_chain": schema.BoolAttribute{
+				Optional: true,
+				Description: `Keep the self-signed root in certificate_chain_pem, certificate_chain, certificate_chain_der and 
+certificate_chains, defaults to true. Set to false for servers that should not send the root, root_ca_pem still holds it.`,
+			},
+			"certificate_chain": schema.ListAttribute{
+				ElementType: types.StringType,
+				Computed:    true,
+				Description: `The issuers of the certificate as a list of PEM encoded certificates, without the leaf, so 
+intermediates and the root can be indexed. Follows preferred_root_cn, chain_order and include_root_in_chain like 
+certificate_chain_pem.`,
+				PlanModifiers: []planmodifier.List{
+					listplanmodifier.UseStateForUnknown(),
+				},
+			},
+			"certificate_der": schema.StringAttribute{
+				Computed: true,
+				Description: `The issued certificate as base64 encoded DER, a single line without PEM armor or the formatting of 
+certsrv, for appliances such as NetScaler that import DER.`,
+				PlanModifiers: []planmodifier.String{
+					stringplanmodifier.UseStateForUnknown(),
+				},
+			},
+			"certificate_chain_der": schema.ListAttribute{
+				ElementType: types.StringType,
+				Computed:    true,
+				Description: `The issuers of the certificate as a list of base64 encoded DER certificates, in the order of 
+certificate_chain. Empty when store_chain is false.`,
+				PlanModifiers: []planmodifier.List{
+					listplanmodifier.UseStateForUnknown(),
+				},
+			},
+			"certificate_chain_p7b": schema.StringAttribute{
+				Computed: true,
+				Description: `The PKCS#7 (p7b) chain exactly as ADCS returned it, as base64 encoded DER, for Windows tooling such as 
+certutil and Intune that import p7b files. Holds the leaf and its issuers. Null when store_chain is false.`,
+				PlanModifiers: []planmodifier.String{
+					stringplanmodifier.UseStateForUnknown(),
+				},
+			},
+			"issuing_ca_pem": schema.StringAttribute{
+				Computed:    true,
+				Description: "The CA that signed the certificate, PEM encoded. Null when store_chain is false.",
+				PlanModifiers: []planmodifier.String{
+					stringplanmodifier.UseStateForUnknown(),
+				},
+			},
+			"root_ca_pem": schema.StringAttribute{
 				Computed: true,
+				Description: `The self-signed root at the top of the chain, PEM encoded. Follows preferred_root_cn, null when the 
+CA did not return the root or store_chain is false.`,
+				PlanModifiers: []planmodifier.String{
+					stringplanmodifier.UseStateForUnknown(),
+				},
+			},
+			"certificate_chains": schema.ListAttribute{
+				ElementType: types.ListType{ElemType: types.StringType},
+				Computed:    true,
+				Description: `Every chain found in the PKCS#7 returned by ADCS, each a list of PEM encoded certificates starting 
+with the issuing CA unless chain_order says otherwise. There is more than one chain when intermediates are cross-signed. 
+Chains are ordered shortest first, 
+whatever the order of the PKCS#7, so the list stays the same between refreshes.`,
+				PlanModifiers: []planmodifier.List{
+					listplanmodifier.UseStateForUnknown(),
+				},
+			},
+			"kubernetes_tls_secret": schema.MapAttribute{
+				ElementType: types.StringType,
+				Computed:    true,
+				Sensitive:   true,
+				Description: `The issued material keyed like a kubernetes.io/tls secret ("tls.crt" with the leaf and intermediates, 
+"tls.key" when the private key is known, "ca.crt" with the root) with base64 encoded values, ready to be used as binary_data of a kubernetes_secret.`,
+				PlanModifiers: []planmodifier.Map{
+					mapplanmodifier.UseStateForUnknown(),
+				},
+			},
+			"azure_key_vault_certificate": schema.SingleNestedAttribute{
+				Computed:  true,
+				Sensitive: true,
+				Description: `The issued material and key properties shaped like the certificate and certificate_policy blocks of 
+azurerm_key_vault_certificate, so the certificate can be imported into Key Vault without reassembling it.`,
+				Attributes: map[string]schema.Attribute{
+					"contents": schema.StringAttribute{
+						Computed:    true,
+						Description: "PEM bundle of the private key, when known, followed by the leaf certificate and its chain, for certificate.contents.",
+					},
+					"content_type": schema.StringAttribute{
+						Computed:    true,
+						Description: "Content type of contents, for certificate_policy.secret_properties.content_type.",
+					},
+					"key_type": schema.StringAttribute{
+						Computed:    true,
+						Description: "RSA or EC, for certificate_policy.key_properties.key_type.",
+					},
+					"key_size": schema.Int64Attribute{
+						Computed:    true,
+						Description: "Key size in bits, for certificate_policy.key_properties.key_size.",
+					},
+					"curve": schema.StringAttribute{
+						Computed:    true,
+						Description: "Curve name of EC keys, for certificate_policy.key_properties.curve.",
+					},
+					"exportable": schema.BoolAttribute{
+						Computed:    true,
+						Description: "Value for certificate_policy.key_properties.exportable.",
+					},
+					"reuse_key": schema.BoolAttribute{
+						Computed:    true,
+						Description: "Value for certificate_policy.key_properties.reuse_key.",
+					},
+					"issuer_name": schema.StringAttribute{
+						Computed:    true,
+						Description: "Value for certificate_policy.issuer_parameters.name, Unknown for certificates issued outside Key Vault.",
+					},
+				},
+				PlanModifiers: []planmodifier.Object{
+					objectplanmodifier.UseStateForUnknown(),
+				},
+			},
+			"combined_pem": schema.StringAttribute{
+				Computed:  true,
+				Sensitive: true,
+				Description: `The leaf certificate, intermediates and private key in a single PEM bundle as HAProxy and NGINX 
+expect it. Only set when private_key_pem is provided or the key comes from generate_csr.`,
+				PlanModifiers: []planmodifier.String{
+					stringplanmodifier.UseStateForUnknown(),
+				},
+			},
+			"pkcs12_b64": schema.StringAttribute{
+				Computed:  true,
+				Sensitive: true,
+				Description: `Base64 encoded PKCS#12 (PFX) file with the leaf, its chain and the private key, protected with the 
+password of the pkcs12 block. Only set with a pkcs12 block; without a known private key it holds the certificates only.`,
+				PlanModifiers: []planmodifier.String{
+					stringplanmodifier.UseStateForUnknown(),
+				},
+			},
+			"last_updated": schema.StringAttribute{
+				Computed:    true,
+				Description: "Time of the last create or update, in RFC 850 format.",
+			},
+		},
+		Blocks: map[string]schema.Block{
+			"timeouts":             timeoutsBlock(),
+			"retry":                retryBlock(),
+			"post_issuance_checks": postIssuanceChecksBlock(),
+			"subject_alternative_names": schema.SingleNestedBlock{
+				Description: `Subject alternative names to request through a san request attribute, which the resource builds and
+escapes. Only honored by CAs with the EDITF_ATTRIBUTESUBJECTALTNAME2 flag set. Conflicts with a "san:" entry in request_attributes.`,
+				Attributes: map[string]schema.Attribute{
+					"dns": schema.ListAttribute{
+						ElementType: types.StringType,
+						Optional:    true,
+						Description: "DNS names.",
+					},
+					"ip": schema.ListAttribute{
+						ElementType: types.StringType,
+						Optional:    true,
+						Description: "IPv4 or IPv6 addresses.",
+					},
+					"email": schema.ListAttribute{
+						ElementType: types.StringType,
+						Optional:    true,
+						Description: "Email addresses (RFC 822 names).",
+					},
+					"upn": schema.ListAttribute{
+						ElementType: types.StringType,
+						Optional:    true,
+						Description: "User principal names such as user@example.com.",
+					},
+					"uri": schema.ListAttribute{
+						ElementType: types.StringType,
+						Optional:    true,
+						Description: "URIs such as spiffe://example.com/web.",
+					},
+				},
+				PlanModifiers: []planmodifier.Object{
+					objectplanmodifier.RequiresReplace(),
+				},
+			},
+			"domain_controller": schema.SingleNestedBlock{
+				Description: `Requests a domain controller certificate, such as one of the Kerberos Authentication template. 
+The DNS names of the domain controller and the domain are added to the subject alternative names, the name of the 
+domain controller becomes the common name of generate_csr and the domain_controller profile applies.`,
+				Attributes: map[string]schema.Attribute{
+					"host_name": schema.StringAttribute{
+						Optional: true,
+						Description: `DNS name of the domain controller, such as dc01.corp.example.com. A single label is 
+qualified with domain. Required with the block.`,
+					},
+					"domain": schema.StringAttribute{
+						Optional:    true,
+						Description: "DNS name of the Active Directory domain, such as corp.example.com. Required with the block.",
+					},
+					"netbios_name": schema.StringAttribute{
+						Optional:    true,
+						Description: "NetBIOS name of the domain, such as CORP, which the Kerberos Authentication template also adds as a DNS name.",
+					},
+				},
+				PlanModifiers: []planmodifier.Object{
+					objectplanmodifier.RequiresReplace(),
+				},
+			},
+			"pkcs12": schema.SingleNestedBlock{
+				Description: `Builds pkcs12_b64, a PFX file for Windows and Java consumers. Changing the block rebuilds the file 
+without requesting a new certificate.`,
+				Attributes: map[string]schema.Attribute{
+					"password": schema.StringAttribute{
+						Optional:    true,
+						Sensitive:   true,
+						Description: "Password protecting the PFX file. Required with the block.",
+					},
+					"private_key_pem": schema.StringAttribute{
+						Optional:  true,
+						Sensitive: true,
+						Description: `PEM encoded private key to include, for when the key is neither in private_key_pem nor 
+generated by generate_csr.`,
+					},
+				},
+			},
+			"generate_csr": schema.SingleNestedBlock{
+				Description: `Lets the provider create the private key and the certificate signing request instead of taking 
+certificate_signing_request. The key is returned in generated_private_key_pem. Conflicts with certificate_signing_request, 
+private_key_pem and adopt_request_id.`,
+				Attributes: map[string]schema.Attribute{
+					"key_algorithm": schema.StringAttribute{
+						Optional:    true,
+						Description: `Algorithm of the key, "RSA" (the default) or "ECDSA". ADCS does not support Ed25519.`,
+					},
+					"rsa_bits": schema.Int64Attribute{
+						Optional:    true,
+						Description: "Size of RSA keys: 2048 (the default), 3072 or 4096.",
+					},
+					"ecdsa_curve": schema.StringAttribute{
+						Optional:    true,
+						Description: `Curve of ECDSA keys: "P256" (the default), "P384" or "P521".`,
+					},
+					"common_name": schema.StringAttribute{
+						Optional:    true,
+						Description: "Common name of the subject.",
+					},
+					"organization": schema.StringAttribute{
+						Optional:    true,
+						Description: "Organization of the subject.",
+					},
+					"organizational_unit": schema.StringAttribute{
+						Optional:    true,
+						Description: "Organizational unit of the subject.",
+					},
+					"locality": schema.StringAttribute{
+						Optional:    true,
+						Description: "Locality of the subject.",
+					},
+					"province": schema.StringAttribute{
+						Optional:    true,
+						Description: "State or province of the subject.",
+					},
+					"country": schema.StringAttribute{
+						Optional:    true,
+						Description: "Two letter country code of the subject.",
+					},
+					"dns_names": schema.ListAttribute{
+						ElementType: types.StringType,
+						Optional:    true,
+						Description: "DNS subject alternative names.",
+					},
+					"ip_addresses": schema.ListAttribute{
+						ElementType: types.StringType,
+						Optional:    true,
+						Description: "IPv4 or IPv6 subject alternative names.",
+					},
+					"email_addresses": schema.ListAttribute{
+						ElementType: types.StringType,
+						Optional:    true,
+						Description: "Email subject alternative names.",
+					},
+					"template_oid": schema.StringAttribute{
+						Optional: true,
+						Description: `OID of the certificate template, as certutil -v -template lists it as msPKI-Cert-Template-OID, to 
+embed in the request as the Certificate Template Information extension (1.3.6.1.4.1.311.21.7) like certreq does. The 
+template request attribute is sent as well.`,
+					},
+					"template_major_version": schema.Int64Attribute{
+						Optional:    true,
+						Description: "Major version of the template for the Certificate Template Information extension, its revision attribute.",
+					},
+					"template_minor_version": schema.Int64Attribute{
+						Optional:    true,
+						Description: "Minor version of the template for the Certificate Template Information extension, its msPKI-Template-Minor-Revision attribute. Requires template_major_version.",
+					},
+				},
+				PlanModifiers: []planmodifier.Object{
+					objectplanmodifier.RequiresReplace(),
+				},
 			},
 		},
 	}
@@ -104,51 +1020,110 @@ func (r *certificateResource) Configure(_ context.Context, req resource.Configur
 		return
 	}
 
-	client, ok := req.ProviderData.(*client.ADCSClient)
+	data, ok := req.ProviderData.(*providerData)
 
 	if !ok {
 		resp.Diagnostics.AddError(
 			"Unexpected Data Source Configure Type",
-			fmt.Sprintf("Expected *client.ADCSClient, got: %T. Please report this issue to the provider developers.", req.ProviderData),
+			fmt.Sprintf("Expected *providerData, got: %T. Please report this issue to the provider developers.", req.ProviderData),
 		)
 
 		return
 	}
 
-	r.client = client
+	r.client = data.client
+	r.provider = data
 }
 
 // Create creates the resource and sets the initial Terraform state.
 func (r *certificateResource) Create(ctx context.Context, req resource.CreateRequest, resp *resource.CreateResponse) {
+	ctx, trace := withOperationTrace(ctx)
+	defer trace.annotate(&resp.Diagnostics)
+
+	if r.provider.readOnly {
+		resp.Diagnostics.Append(readOnlyError("create a certificate"))
+		return
+	}
+
 	// Retrieve values from plan
 	var plan certificateCreateModel
-	attr := ""
+	var attr string
 	diags := req.Plan.Get(ctx, &plan)
 
 	resp.Diagnostics.Append(diags...)
+	ctx, cancel, createTimeout := withTimeout(ctx, plan.Timeouts, "create")
+	defer cancel()
+	ctx = withRetryPolicy(ctx, retryPolicyOf(ctx, plan.Retry))
+	ctx = withAIAFetcher(ctx, r.provider.aia)
 	// Add attributes if provided
-	if !plan.Attributes.IsNull() && !plan.Attributes.IsUnknown() {
+	attr, diags = plan.submittedAttributes(ctx)
+	resp.Diagnostics.Append(diags...)
+	attr = plan.describedAttributes(attr, r.provider.workspace)
+	if attr != "" {
 		tflog.Debug(ctx, "Adding attributes to certificate creation", map[string]interface{}{
-			"attributes": plan.Attributes,
+			"attributes": attr,
 		})
-		attr = plan.Attributes.ValueString()
 	}
-	// Create new certificate
-	tflog.Info(ctx, "Requesting certificate from ADCS server.")
-	tflog.Debug(ctx, "Certificate request Data", structs.Map(plan))
-	certificates, err := r.client.RequestCertificate(plan.CSR.ValueString(), plan.Template.ValueString(), attr)
-	if err != nil {
-		resp.Diagnostics.AddError(
-			"Error creating certificate from singing request",
-			"Could not create certificate, unexpected error: "+err.Error(),
+
+	plan.GeneratedPrivateKeyPEM = types.StringNull()
+	if !plan.GenerateCSR.IsNull() {
+		resp.Diagnostics.Append(plan.generateCSR(ctx)...)
+		if resp.Diagnostics.HasError() {
+			return
+		}
+	}
+	// The last chance to keep names outside allowed_san_patterns from reaching the CA.
+	resp.Diagnostics.Append(validateAllowedSANPatterns(ctx, plan)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	var certificates *client.Certificates
+	event := eventIssued
+	if !plan.AdoptRequestID.IsNull() {
+		certificates = r.adoptCertificate(ctx, plan, &resp.Diagnostics)
+		event = eventAdopted
+		plan.CAName = types.StringNull()
+		if plan.RequestNonce.IsUnknown() {
+			plan.RequestNonce = types.StringNull()
+		}
+	} else {
+		request := plan.CSR.ValueString()
+		if !plan.OnBehalfOf.IsNull() {
+			var err error
+			if request, err = newOnBehalfOfRequest(request, plan.OnBehalfOf.ValueString(), plan.EnrollmentAgentCertificatePEM.ValueString(), plan.EnrollmentAgentPrivateKeyPEM.ValueString()); err != nil {
+				resp.Diagnostics.AddAttributeError(
+					path.Root("on_behalf_of"),
+					"Error Creating Certificate",
+					fmt.Sprintf("Could not build the request on behalf of %s: %s", plan.OnBehalfOf.ValueString(), err.Error()),
+				)
+				return
+			}
+		}
+		certificates = r.requestCertificate(ctx, &plan, request, attr, &resp.Diagnostics)
+	}
+	if resp.Diagnostics.HasError() {
+		resp.Diagnostics.Append(timeoutExceeded(ctx, "create", createTimeout)...)
+		return
+	}
+	if certificates == nil {
+		// pending_behavior = "save" keeps the request in state until a later apply finds it issued.
+		resp.Diagnostics.Append(plan.sealState(ctx, &client.Certificates{ID: plan.ID.ValueString()})...)
+		resp.Diagnostics.AddWarning(
+			"Certificate Request Pending Approval",
+			fmt.Sprintf("The CA took request ID %s under submission, it is kept in state until a CA manager decides on it. "+
+				"The first apply after it was issued takes over the certificate.", plan.ID.ValueString()),
 		)
+		if !resp.Diagnostics.HasError() {
+			resp.Diagnostics.Append(resp.State.Set(ctx, plan)...)
+		}
 		return
 	}
 
-	plan.ID = types.StringValue(certificates.ID)
-	plan.CertificateB64 = types.StringValue(certificates.CertificateB64)
-	plan.CertificateChainB64 = types.StringValue(certificates.CertificateChainB64)
-	plan.LastUpdated = types.StringValue(time.Now().Format(time.RFC850))
+	resp.Diagnostics.Append(plan.setIssuedCertificate(ctx, certificates)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
 
 	// Set state to fully populated data
 	diags = resp.State.Set(ctx, plan)
@@ -156,10 +1131,310 @@ func (r *certificateResource) Create(ctx context.Context, req resource.CreateReq
 	if resp.Diagnostics.HasError() {
 		return
 	}
+	r.recordIssuance(ctx, resp.Private, plan, certificates, event, &resp.Diagnostics)
+}
+
+// setIssuedCertificate checks a certificate ADCS just issued or handed over against the guardrails
+// of the resource and sets the attributes derived from it.
+func (m *certificateCreateModel) setIssuedCertificate(ctx context.Context, certificates *client.Certificates) diag.Diagnostics {
+	var diags diag.Diagnostics
+
+	if !m.MaxValidityHours.IsNull() {
+		diags.Append(checkMaxValidity(certificates, m.MaxValidityHours.ValueInt64())...)
+		if diags.HasError() {
+			return diags
+		}
+	}
+
+	diags.Append(m.checkPostIssuance(ctx, certificates.ID, certificates.CertificateB64)...)
+	if diags.HasError() {
+		return diags
+	}
+	diags.Append(m.checkRequestedValidity(certificates)...)
+	diags.Append(m.checkSignatureHash(certificates)...)
+	diags.Append(m.checkIssuedSANs(ctx, certificates)...)
+	if diags.HasError() {
+		return diags
+	}
+
+	m.ID = types.StringValue(certificates.ID)
+	m.CertificateB64 = types.StringValue(normalizePEM(certificates.CertificateB64))
+	m.CertificateChainB64 = types.StringValue(normalizePEM(certificates.CertificateChainB64))
+	if !m.storeChain() {
+		certificates.CertificateChainB64 = ""
+		m.CertificateChainB64 = types.StringNull()
+	}
+	diags.Append(m.setCertificateOutputs(ctx, certificates)...)
+	diags.Append(m.sealState(ctx, certificates)...)
+	if diags.HasError() {
+		return diags
+	}
+	m.RequestDisposition = types.StringValue(dispositionIssued)
+	m.setRevocationStatus(nil)
+	m.ReadyForRenewal = types.BoolValue(false)
+	m.LastUpdated = types.StringValue(time.Now().Format(time.RFC850))
+	return diags
+}
+
+// recordIssuance writes the custody record of a certificate that was just stored in state and
+// publishes its lifecycle event.
+func (r *certificateResource) recordIssuance(ctx context.Context, private privateStateSetter, plan certificateCreateModel, certificates *client.Certificates, event string, diags *diag.Diagnostics) {
+	diags.Append(writeCustodyRecord(ctx, private, certificates.CertificateB64, plan.CSR.ValueString())...)
+
+	if err := r.provider.emitEvent(ctx, newLifecycleEvent(event, r.host(plan), certificates.ID, plan.Template.ValueString(), certificates.CertificateB64)); err != nil {
+		diags.AddWarning(
+			"Unable to Publish Certificate Event",
+			fmt.Sprintf("Request ID %s was %s but the %q event could not be published: %s", certificates.ID, event, event, err.Error()),
+		)
+	}
+}
+
+// recordPending publishes the pending event of a request the CA took under submission, with the
+// description it was submitted with so microsoftadcs_abandoned_requests can find it later.
+func (r *certificateResource) recordPending(ctx context.Context, plan certificateCreateModel, reqID string, attr string, diags *diag.Diagnostics) {
+	event := newLifecycleEvent(eventPending, r.host(plan), reqID, plan.Template.ValueString(), "")
+	event.CAName = plan.CAName.ValueString()
+	event.Description = requestDescription(attr)
+	if err := r.provider.emitEvent(ctx, event); err != nil {
+		diags.AddWarning(
+			"Unable to Publish Certificate Event",
+			fmt.Sprintf("Request ID %s is pending approval but the %q event could not be published: %s", reqID, eventPending, err.Error()),
+		)
+	}
+}
+
+// requestCertificate submits the request to ADCS, the certificate signing request of the plan or the
+// renewal request wrapping it.
+func (r *certificateResource) requestCertificate(ctx context.Context, plan *certificateCreateModel, request string, attr string, diags *diag.Diagnostics) *client.Certificates {
+	if plan.RequestNonce.IsNull() || plan.RequestNonce.IsUnknown() {
+		nonce, err := newRequestNonce()
+		if err != nil {
+			diags.AddError("Error Generating Request Nonce", err.Error())
+			return nil
+		}
+		plan.RequestNonce = types.StringValue(nonce)
+	}
+	// Create new certificate
+	tflog.Info(ctx, "Requesting certificate from ADCS server.")
+	// The plan holds private keys and passwords, only fields that are safe to log are named.
+	tflog.Debug(ctx, "Certificate request data", map[string]interface{}{
+		"template":      plan.Template.ValueString(),
+		"ca_name":       plan.CAName.ValueString(),
+		"request_nonce": plan.RequestNonce.ValueString(),
+	})
+	route, routeDiags := r.newCARoute(*plan, "")
+	diags.Append(routeDiags...)
+	if diags.HasError() {
+		return nil
+	}
+	ctx = withCARoute(ctx, route)
+	certificates, err := submitCertificateRequest(ctx, r.client, r.provider.parser, certsrvSubmission{
+		CSR:        request,
+		Template:   plan.Template.ValueString(),
+		Attributes: append(splitAttributes(attr), requestNonceAttribute+":"+plan.RequestNonce.ValueString()),
+		Chunked:    r.provider.chunkedSubmissions,
+	})
+	plan.CAName = route.caNameValue()
+	if err != nil {
+		reqID, pending := pendingRequestID(err)
+		if pending {
+			r.recordPending(ctx, *plan, reqID, attr, diags)
+		}
+		if pending && r.provider.approvalWebhookURL != "" {
+			r.notifyPendingApproval(ctx, *plan, reqID, diags)
+		}
+		if pending && plan.pendingBehavior() == pendingBehaviorSave {
+			tflog.Info(ctx, "Certificate request is pending approval, saving the request ID", map[string]interface{}{
+				"request_id": reqID,
+			})
+			plan.setPending(reqID)
+			return nil
+		}
+		if pending && plan.pendingBehavior() == pendingBehaviorWait {
+			tflog.Info(ctx, "Certificate request is pending approval, waiting for issuance", map[string]interface{}{
+				"request_id": reqID,
+				"timeout":    plan.issuanceTimeout().String(),
+			})
+			if certificates, err = r.provider.pollerFor(route).wait(ctx, reqID, plan.issuanceTimeout()); err == nil {
+				return certificates
+			}
+			// The poller checks the request with a context of its own.
+			operationTraceFrom(ctx).record(route, reqID, err)
+			detail := err.Error()
+			if !isStillPending(err) && !isTransient(err) {
+				if unsupported := r.provider.unsupportedFeature(ctx, featurePendingDisposition); unsupported != nil {
+					diags.AddError("Not Supported on This ADCS Version", unsupported.Error()+": "+detail)
+					return nil
+				}
+			}
+			if isStillPending(err) {
+				detail += fmt.Sprintf("\n\nOnce a CA manager issued it, set adopt_request_id = %q to take over the certificate instead of submitting a new request.", reqID)
+			}
+			diags.AddError(classifiedSummary("Certificate Was Not Issued", err), detail)
+			return nil
+		}
+		if pending {
+			diags.AddError(
+				classifiedSummary("Certificate Request Pending Approval", err),
+				fmt.Sprintf("The CA took request ID %s under submission, it has to be approved by a CA manager. "+
+					"Set pending_behavior = \"wait\" to wait for the approval or \"save\" to keep the request in state until it is issued, "+
+					"or set adopt_request_id = %q once it is issued.", reqID, reqID),
+			)
+			return nil
+		}
+		diags.AddError(
+			classifiedSummary("Error creating certificate from singing request", err),
+			"Could not create certificate, unexpected error: "+err.Error()+
+				fmt.Sprintf("\n\nIf the CA received the request before the failure, it carries the request attribute %s:%s. "+
+					"Look it up in the CA database before retrying to avoid issuing a second certificate.", requestNonceAttribute, plan.RequestNonce.ValueString()),
+		)
+		return nil
+	}
+	return certificates
+}
+
+// adoptCertificate takes over a certificate that was requested outside of Terraform. Its public key
+// has to match the certificate signing request, otherwise the resource would describe a certificate
+// it was not issued for.
+func (r *certificateResource) adoptCertificate(ctx context.Context, plan certificateCreateModel, diags *diag.Diagnostics) *client.Certificates {
+	reqID := plan.AdoptRequestID.ValueString()
+	tflog.Info(ctx, "Adopting existing certificate from ADCS server.", map[string]interface{}{
+		"request_id": reqID,
+	})
+
+	route, routeDiags := r.newCARoute(plan, "")
+	diags.Append(routeDiags...)
+	if diags.HasError() {
+		return nil
+	}
+	certificates, err := retrieveCertificates(withCARoute(ctx, route), r.client, r.provider.parser, reqID)
+	if err != nil {
+		diags.AddAttributeError(
+			path.Root("adopt_request_id"),
+			"Error Adopting Certificate",
+			fmt.Sprintf("Could not retrieve the certificate of request ID %s: %s", reqID, err.Error()),
+		)
+		return nil
+	}
+
+	cert, err := parseCertificate(certificates.CertificateB64)
+	if err != nil {
+		diags.AddAttributeError(
+			path.Root("adopt_request_id"),
+			"Error Adopting Certificate",
+			fmt.Sprintf("Could not parse the certificate of request ID %s: %s", reqID, err.Error()),
+		)
+		return nil
+	}
+	request, err := parseCertificateRequest(plan.CSR.ValueString())
+	if err != nil {
+		diags.AddAttributeError(
+			path.Root("certificate_signing_request"),
+			"Error Adopting Certificate",
+			"Could not parse the certificate signing request to compare it with the adopted certificate: "+err.Error(),
+		)
+		return nil
+	}
+	if !publicKeysEqual(cert.PublicKey, request.PublicKey) {
+		diags.AddAttributeError(
+			path.Root("adopt_request_id"),
+			"Adopted Certificate Does Not Match Certificate Signing Request",
+			fmt.Sprintf("The certificate of request ID %s (%s) was not issued for the public key of certificate_signing_request.", reqID, cert.Subject),
+		)
+		return nil
+	}
+	return certificates
+}
+
+// notifyPendingApproval lets the approval webhook know that a request is waiting for a CA manager.
+// A failing webhook should not hide the actual problem so it only results in a warning.
+func (r *certificateResource) notifyPendingApproval(ctx context.Context, plan certificateCreateModel, reqID string, diags *diag.Diagnostics) {
+	payload := approvalRequest{
+		Event:     "pending",
+		RequestID: reqID,
+		Template:  plan.Template.ValueString(),
+		Host:      certsrvHost(ctx, r.client),
+	}
+	if request, err := parseCertificateRequest(plan.CSR.ValueString()); err == nil {
+		payload.Subject = request.Subject.String()
+	}
+
+	tflog.Info(ctx, "Certificate request is pending approval, notifying approval webhook", map[string]interface{}{
+		"request_id": reqID,
+	})
+	if err := r.provider.notifyApprovalWebhook(ctx, payload); err != nil {
+		diags.AddWarning(
+			"Unable to Notify Approval Webhook",
+			fmt.Sprintf("Request ID %s is pending approval but the approval webhook could not be called: %s", reqID, err.Error()),
+		)
+	}
+}
+
+// warnApprovalRequired warns at plan time when the template holds requests for CA manager approval
+// but the apply is not going to wait for it. Templates are only checked when ldap_url is configured.
+func (r *certificateResource) warnApprovalRequired(ctx context.Context, plan certificateCreateModel, diags *diag.Diagnostics) {
+	if r.provider == nil || r.provider.templates == nil || plan.Template.IsUnknown() || !plan.AdoptRequestID.IsNull() || plan.pendingBehavior() != pendingBehaviorError {
+		return
+	}
+
+	template, err := r.provider.templates.lookup(ctx, plan.Template.ValueString())
+	if err != nil {
+		tflog.Warn(ctx, "Could not check whether the certificate template requires approval", map[string]interface{}{
+			"template": plan.Template.ValueString(),
+			"error":    err.Error(),
+		})
+		return
+	}
+	if template.RequiresApproval {
+		diags.AddAttributeWarning(
+			path.Root("template"),
+			"Certificate Template Requires Approval",
+			fmt.Sprintf("Template %q holds every request for CA manager approval, so the apply will fail with the request left pending. "+
+				"Set pending_behavior = \"wait\" to wait for the approval, together with issuance_timeout for approvals that take longer than an hour, "+
+				"or pending_behavior = \"save\" to keep the request in state until it is issued, "+
+				"and consider approval_webhook_url on the provider to notify the approvers.", plan.Template.ValueString()),
+		)
+	}
+}
+
+// warnWildcardsDropped warns at plan time when wildcard names are requested from a template that
+// builds the subject from Active Directory, which never holds wildcards, so the certificate would be
+// issued without them. Templates are only checked when ldap_url is configured.
+func (r *certificateResource) warnWildcardsDropped(ctx context.Context, plan certificateCreateModel, diags *diag.Diagnostics) {
+	if r.provider == nil || r.provider.templates == nil || plan.Template.IsUnknown() || !plan.AdoptRequestID.IsNull() ||
+		plan.CSR.IsUnknown() || plan.requestAttributes().IsUnknown() || plan.AttributesMap.IsUnknown() ||
+		plan.subjectAlternativeNames().IsUnknown() || plan.GenerateCSR.IsUnknown() {
+		return
+	}
+
+	wildcards := requestedWildcards(ctx, plan)
+	if len(wildcards) == 0 {
+		return
+	}
+
+	template, err := r.provider.templates.lookup(ctx, plan.Template.ValueString())
+	if err != nil {
+		tflog.Warn(ctx, "Could not check whether the certificate template accepts requested names", map[string]interface{}{
+			"template": plan.Template.ValueString(),
+			"error":    err.Error(),
+		})
+		return
+	}
+	if !template.SuppliesSubject {
+		diags.AddAttributeWarning(
+			path.Root("template"),
+			"Certificate Template Ignores Wildcard Names",
+			fmt.Sprintf("Template %q builds the subject from Active Directory instead of the request, so the certificate will not hold %s. "+
+				"Use a template with \"Supply in the request\" selected on its Subject Name tab.", plan.Template.ValueString(), strings.Join(wildcards, ", ")),
+		)
+	}
 }
 
 // Read refreshes the Terraform state with the latest data.
 func (r *certificateResource) Read(ctx context.Context, req resource.ReadRequest, resp *resource.ReadResponse) {
+	ctx, trace := withOperationTrace(ctx)
+	defer trace.annotate(&resp.Diagnostics)
+
 	// Get current state
 	var state certificateCreateModel
 	diags := req.State.Get(ctx, &state)
@@ -168,22 +1443,123 @@ func (r *certificateResource) Read(ctx context.Context, req resource.ReadRequest
 		return
 	}
 	reqID := state.ID.ValueString()
+	ctx, cancel, readTimeout := withTimeout(ctx, state.Timeouts, "read")
+	defer cancel()
+
+	custody, diags := readCustodyRecord(ctx, req.Private)
+	resp.Diagnostics.Append(diags...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+	if custody != nil {
+		resp.Diagnostics.Append(checkCustody(custody, reqID, state.CertificateB64.ValueString(), state.CSR.ValueString(), "certificate in state")...)
+		if resp.Diagnostics.HasError() {
+			return
+		}
+	}
+
+	// The state stays as it was applied when the refresh cannot reach the CA. ModifyPlan still
+	// evaluates the renewal settings against it, and plans what the CA would have reported as
+	// unknown for the apply to ask again.
+	if r.provider.caOffline(ctx) {
+		tflog.Debug(ctx, "require_network is apply_only, keeping the certificate in state without contacting the CA", map[string]interface{}{
+			"request_id": reqID,
+		})
+		resp.Diagnostics.Append(setRefreshedOffline(ctx, resp.Private, true)...)
+		return
+	}
+	offline, diags := refreshedOffline(ctx, req.Private)
+	resp.Diagnostics.Append(diags...)
+	if offline {
+		resp.Diagnostics.Append(setRefreshedOffline(ctx, resp.Private, false)...)
+	}
+
+	route, diags := r.newCARoute(state, state.CAName.ValueString())
+	resp.Diagnostics.Append(diags...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+	ctx = withCARoute(ctx, route)
+	ctx = withRetryPolicy(ctx, retryPolicyOf(ctx, state.Retry))
+	ctx = withAIAFetcher(ctx, r.provider.aia)
+
+	if state.savedPending() {
+		r.readPending(ctx, &state, &resp.Diagnostics)
+		if !resp.Diagnostics.HasError() {
+			resp.Diagnostics.Append(resp.State.Set(ctx, &state)...)
+		}
+		return
+	}
 
 	// Get refreshed order value from HashiCups
-	certificates, err := r.client.RetrieveCertificates(reqID)
+	var certificates *client.Certificates
+	var err error
+	if state.storeChain() {
+		certificates, err = retrieveCertificates(ctx, r.client, r.provider.parser, reqID)
+	} else {
+		certificates, err = retrieveCertificate(ctx, r.client, r.provider.parser, reqID)
+	}
 
 	if err != nil {
+		// The CA answering for the request is a status change to report, not a failed refresh.
+		if isRequestMissing(err) && handleMissingRequest(ctx, state, err, resp) {
+			return
+		}
+		if disposition, ok := requestDisposition(err); ok {
+			state.RequestDisposition = types.StringValue(disposition)
+			state.DispositionMessage = types.StringValue(dispositionMessage(err))
+			// certsrv does not tell when or why, those stay as the OCSP responder last reported them.
+			if disposition == dispositionRevoked {
+				state.RevocationStatus = types.StringValue(dispositionRevoked)
+			}
+			resp.Diagnostics.AddWarning(
+				"Certificate No Longer Issued",
+				fmt.Sprintf("ADCS did not return the certificate of request ID %s, request_disposition is now %q: %s", reqID, disposition, err.Error()),
+			)
+			resp.Diagnostics.Append(resp.State.Set(ctx, &state)...)
+			return
+		}
 		resp.Diagnostics.AddError(
-			"Error Reading Certificate",
+			classifiedSummary("Error Reading Certificate", err),
 			fmt.Sprintf("Could not read Certificate ID %s", state.ID.ValueString())+":"+err.Error(),
 		)
+		resp.Diagnostics.Append(timeoutExceeded(ctx, "read", readTimeout)...)
+		return
+	}
+
+	// The request ID itself may have been swapped, so what ADCS returns has to match as well. Resources
+	// created before custody records were kept get one now.
+	if custody != nil {
+		resp.Diagnostics.Append(checkCustody(custody, reqID, certificates.CertificateB64, state.CSR.ValueString(), "certificate returned by ADCS")...)
+		if resp.Diagnostics.HasError() {
+			return
+		}
+	} else if state.CSR.ValueString() != "" {
+		resp.Diagnostics.Append(writeCustodyRecord(ctx, resp.Private, certificates.CertificateB64, state.CSR.ValueString())...)
+	}
+
+	resp.Diagnostics.Append(state.checkPostIssuance(ctx, reqID, certificates.CertificateB64)...)
+	if resp.Diagnostics.HasError() {
 		return
 	}
 
 	// Overwrite items with refreshed state
 	state.ID = types.StringValue(certificates.ID)
-	state.CertificateB64 = types.StringValue(strings.Replace(certificates.CertificateB64, `\r`, "", -1))
-	state.CertificateChainB64 = types.StringValue(strings.Replace(certificates.CertificateChainB64, `\r`, "", -1))
+	state.CertificateB64 = types.StringValue(normalizePEM(certificates.CertificateB64))
+	state.CertificateChainB64 = types.StringValue(normalizePEM(certificates.CertificateChainB64))
+	if !state.storeChain() {
+		state.CertificateChainB64 = types.StringNull()
+	}
+	resp.Diagnostics.Append(state.setCertificateOutputs(ctx, certificates)...)
+	state.RequestDisposition = types.StringValue(dispositionIssued)
+	state.setRevocationStatus(nil)
+	if r.provider.aia != nil {
+		state.setRevocationStatus(revocationStatus(ctx, certificates.CertificateB64, state.IssuingCAPEM))
+	}
+	resp.Diagnostics.Append(state.setReadyForRenewal(ctx, time.Now())...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
 
 	// Set refreshed state
 	diags = resp.State.Set(ctx, &state)
@@ -195,16 +1571,436 @@ func (r *certificateResource) Read(ctx context.Context, req resource.ReadRequest
 
 // Update updates the resource and sets the updated Terraform state on success.
 func (r *certificateResource) Update(ctx context.Context, req resource.UpdateRequest, resp *resource.UpdateResponse) {
-	//There is no updates that can be performed on the adcs side
+	ctx, trace := withOperationTrace(ctx)
+	defer trace.annotate(&resp.Diagnostics)
+
+	// The only update performed on the adcs side is the renewal of renew_existing, otherwise only the
+	// settings that live purely in Terraform can change in place.
+	var plan certificateCreateModel
+	diags := req.Plan.Get(ctx, &plan)
+	resp.Diagnostics.Append(diags...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+	ctx = withRetryPolicy(ctx, retryPolicyOf(ctx, plan.Retry))
+	ctx = withAIAFetcher(ctx, r.provider.aia)
+	var state certificateCreateModel
+	resp.Diagnostics.Append(req.State.Get(ctx, &state)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+	// Every update asks the CA, whatever the refresh of the plan could not.
+	offline, diags := refreshedOffline(ctx, req.Private)
+	resp.Diagnostics.Append(diags...)
+	if offline {
+		resp.Diagnostics.Append(setRefreshedOffline(ctx, resp.Private, false)...)
+	}
+	if resp.Diagnostics.HasError() {
+		return
+	}
+	if state.savedPending() {
+		r.resolvePending(ctx, resp, plan)
+		return
+	}
+	if plan.CertificateB64.IsUnknown() {
+		r.renewCertificate(ctx, req, resp, plan)
+		return
+	}
+
+	// The bundled outputs depend on preferred_root_cn, chain_order, include_root_in_chain and
+	// store_chain, so they are rebuilt from the material in state. The chain is only downloaded again when it was not stored before.
+	certificates := &client.Certificates{
+		ID:                  plan.ID.ValueString(),
+		CertificateB64:      plan.CertificateB64.ValueString(),
+		CertificateChainB64: plan.CertificateChainB64.ValueString(),
+	}
+	switch {
+	case !plan.storeChain():
+		certificates.CertificateChainB64 = ""
+		plan.CertificateChainB64 = types.StringNull()
+	case plan.CertificateChainB64.IsNull() || plan.CertificateChainB64.IsUnknown():
+		route, diags := r.newCARoute(plan, plan.CAName.ValueString())
+		resp.Diagnostics.Append(diags...)
+		if resp.Diagnostics.HasError() {
+			return
+		}
+		retrieved, err := retrieveCertificates(withCARoute(ctx, route), r.client, r.provider.parser, plan.ID.ValueString())
+		if err != nil {
+			resp.Diagnostics.AddError(
+				classifiedSummary("Error Reading Certificate", err),
+				fmt.Sprintf("Could not read the certificate chain of request ID %s: %s", plan.ID.ValueString(), err.Error()),
+			)
+			return
+		}
+		certificates.CertificateChainB64 = retrieved.CertificateChainB64
+		plan.CertificateChainB64 = types.StringValue(normalizePEM(retrieved.CertificateChainB64))
+	}
+	resp.Diagnostics.Append(plan.checkPostIssuance(ctx, plan.ID.ValueString(), plan.CertificateB64.ValueString())...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+	// A generated key is kept from state by the plan, only certificates without one plan it unknown.
+	if plan.GeneratedPrivateKeyPEM.IsUnknown() {
+		plan.GeneratedPrivateKeyPEM = types.StringNull()
+	}
+	resp.Diagnostics.Append(plan.setCertificateOutputs(ctx, certificates)...)
+	resp.Diagnostics.Append(plan.sealState(ctx, certificates)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+	// The refresh of the plan could not reach the CA, see require_network.
+	if plan.RequestDisposition.IsUnknown() {
+		r.refreshDisposition(ctx, &plan, state, &resp.Diagnostics)
+		if resp.Diagnostics.HasError() {
+			return
+		}
+	}
+	plan.LastUpdated = types.StringValue(time.Now().Format(time.RFC850))
+
+	diags = resp.State.Set(ctx, plan)
+	resp.Diagnostics.Append(diags...)
 }
 
-// Delete deletes the resource and removes the Terraform state on success.
+// Delete deletes the resource and removes the Terraform state on success. The certificate stays
+// valid on the CA unless revoke_on_destroy is set.
 func (r *certificateResource) Delete(ctx context.Context, req resource.DeleteRequest, resp *resource.DeleteResponse) {
-	// If we delete there is nothing to be done on the ADCS side.. State management is handled by terraform so we don't
-	// need to do anything here
+	ctx, trace := withOperationTrace(ctx)
+	defer trace.annotate(&resp.Diagnostics)
+
+	if r.provider.readOnly {
+		resp.Diagnostics.Append(readOnlyError("remove a certificate from state"))
+		return
+	}
+
+	var state certificateCreateModel
+	diags := req.State.Get(ctx, &state)
+	resp.Diagnostics.Append(diags...)
+	if resp.Diagnostics.HasError() || !state.RevokeOnDestroy.ValueBool() {
+		return
+	}
+	if state.savedPending() {
+		r.denyOnDestroy(ctx, state, &resp.Diagnostics)
+		return
+	}
+
+	reqID := state.ID.ValueString()
+	payload, err := newRevocationRequest(r.host(state), reqID, state.Template.ValueString(), state.CertificateB64.ValueString(), state.RevocationReason.ValueString())
+	if err == nil {
+		tflog.Info(ctx, "Revoking certificate through the revocation webhook", map[string]interface{}{
+			"request_id":    reqID,
+			"serial_number": payload.SerialNumber,
+			"reason":        payload.Reason,
+		})
+		err = r.provider.revokeCertificate(ctx, payload)
+	}
+	if err != nil {
+		resp.Diagnostics.AddError(
+			classifiedSummary("Unable to Revoke Certificate", err),
+			fmt.Sprintf("Could not revoke the certificate of request ID %s, it is kept in state: %s", reqID, err.Error()),
+		)
+		return
+	}
+
+	if err := r.provider.emitEvent(ctx, newLifecycleEvent(eventRevoked, r.host(state), reqID, state.Template.ValueString(), state.CertificateB64.ValueString())); err != nil {
+		resp.Diagnostics.AddWarning(
+			"Unable to Publish Certificate Event",
+			fmt.Sprintf("Request ID %s was %s but the %q event could not be published: %s", reqID, eventRevoked, eventRevoked, err.Error()),
+		)
+	}
+}
+
+// ModifyPlan forces a replacement on every plan when reissue_every_apply is set or the certificate
+// is ready for renewal, and marks the bundled outputs as changing when a different chain is preferred
+// or the chain is no longer, or again, stored.
+func (r *certificateResource) ModifyPlan(ctx context.Context, req resource.ModifyPlanRequest, resp *resource.ModifyPlanResponse) {
+	// Nothing to do on destroy.
+	if req.Plan.Raw.IsNull() {
+		return
+	}
+
+	var plan certificateCreateModel
+	diags := req.Plan.Get(ctx, &plan)
+	resp.Diagnostics.Append(diags...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	// ValidateConfig skips requests whose names are not known yet, such as a CSR of another resource
+	// created in the same apply. They are checked again here, once the plan of the apply knows them.
+	resp.Diagnostics.Append(validateAllowedSANPatterns(ctx, plan)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	if plan.RevokeOnDestroy.ValueBool() && r.provider != nil && r.provider.revocationWebhookURL == "" {
+		resp.Diagnostics.AddAttributeError(
+			path.Root("revoke_on_destroy"),
+			"Revocation Webhook Not Configured",
+			"revoke_on_destroy revokes certificates through the revocation webhook, set revocation_webhook_url on the provider.",
+		)
+		return
+	}
+
+	if req.State.Raw.IsNull() {
+		// The template checks read the CA and the directory.
+		if r.provider.caOffline(ctx) {
+			return
+		}
+		// The offered templates are those of the CA and account of the provider.
+		if r.provider != nil && !plan.Template.IsUnknown() && plan.AdoptRequestID.IsNull() && !plan.hasEndpoint() {
+			r.provider.checkTemplateOffered(ctx, plan.Template.ValueString(), &resp.Diagnostics)
+		}
+		r.checkTemplateKeyPolicy(ctx, plan, &resp.Diagnostics)
+		r.warnApprovalRequired(ctx, plan, &resp.Diagnostics)
+		r.warnWildcardsDropped(ctx, plan, &resp.Diagnostics)
+		return
+	}
+
+	if plan.ReissueEveryApply.ValueBool() {
+		tflog.Debug(ctx, "reissue_every_apply is set, planning certificate replacement")
+		resp.RequiresReplace = append(resp.RequiresReplace, path.Root("reissue_every_apply"))
+	}
+
+	var state certificateCreateModel
+	diags = req.State.Get(ctx, &state)
+	resp.Diagnostics.Append(diags...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	// Replacements submit a new request as well.
+	if (!plan.Template.Equal(state.Template) || !plan.CSR.Equal(state.CSR) || !plan.GenerateCSR.Equal(state.GenerateCSR)) &&
+		!r.provider.caOffline(ctx) {
+		r.checkTemplateKeyPolicy(ctx, plan, &resp.Diagnostics)
+	}
+
+	// The plan of the apply runs where the CA can be reached, the private state tells it that the
+	// refresh of the plan could not.
+	offline, diags := refreshedOffline(ctx, req.Private)
+	resp.Diagnostics.Append(diags...)
+	offline = offline || r.provider.caOffline(ctx)
+
+	// A saved request has no certificate to renew yet, it is taken over once a refresh found it
+	// decided. Refreshes that cannot reach the CA leave the check to every apply.
+	if state.savedPending() {
+		if state.RequestDisposition.ValueString() != dispositionPending || offline {
+			resp.Diagnostics.Append(planPendingResolution(ctx, &resp.Plan)...)
+		}
+		return
+	}
+
+	// The disposition and revocation status are only known once the apply asks the CA.
+	if offline {
+		for _, name := range []string{"request_disposition", "disposition_message", "revocation_status", "revoked_reason", "revocation_date"} {
+			resp.Diagnostics.Append(resp.Plan.SetAttribute(ctx, path.Root(name), types.StringUnknown())...)
+		}
+	}
+
+	// Evaluate the planned renewal settings against the certificate in state as well, so changing
+	// them or planning without a refresh still renews a certificate that is due.
+	readyForRenewal := state.ReadyForRenewal.ValueBool()
+	if !readyForRenewal && !plan.EarlyRenewalHours.IsUnknown() && !plan.RenewalSchedule.IsUnknown() && !plan.RotationPeriod.IsUnknown() {
+		planned := state
+		planned.EarlyRenewalHours = plan.EarlyRenewalHours
+		planned.RotationPeriod = plan.RotationPeriod
+		planned.Profile = plan.Profile
+		planned.DomainController = plan.DomainController
+		planned.RenewalSchedule = plan.RenewalSchedule
+		resp.Diagnostics.Append(planned.setReadyForRenewal(ctx, time.Now())...)
+		readyForRenewal = planned.ReadyForRenewal.ValueBool()
+	}
+
+	// An expired certificate cannot sign its own renewal, so it is replaced even with renew_existing.
+	if state.expired(time.Now()) {
+		tflog.Info(ctx, "Certificate has expired, planning certificate replacement", map[string]interface{}{
+			"request_id": state.ID.ValueString(),
+			"not_after":  state.NotAfter.ValueString(),
+		})
+		resp.Diagnostics.Append(resp.Plan.SetAttribute(ctx, path.Root("ready_for_renewal"), types.BoolValue(false))...)
+		resp.Diagnostics.Append(resp.Plan.SetAttribute(ctx, path.Root("not_after"), types.StringUnknown())...)
+		resp.RequiresReplace = append(resp.RequiresReplace, path.Root("not_after"))
+	} else if readyForRenewal {
+		resp.Diagnostics.Append(resp.Plan.SetAttribute(ctx, path.Root("ready_for_renewal"), types.BoolValue(false))...)
+		if plan.RenewExisting.ValueBool() {
+			tflog.Debug(ctx, "Certificate is ready for renewal, planning in-place renewal")
+			resp.Diagnostics.Append(planRenewal(ctx, req.Config, &resp.Plan)...)
+		} else {
+			tflog.Debug(ctx, "Certificate is ready for renewal, planning certificate replacement")
+			resp.RequiresReplace = append(resp.RequiresReplace, path.Root("ready_for_renewal"))
+		}
+	}
+
+	if plan.storeChain() != state.storeChain() {
+		resp.Diagnostics.Append(resp.Plan.SetAttribute(ctx, path.Root("certificate_chain_b64"), types.StringUnknown())...)
+		resp.Diagnostics.Append(resp.Plan.SetAttribute(ctx, path.Root("certificate_chain_p7b"), types.StringUnknown())...)
+		resp.Diagnostics.Append(resp.Plan.SetAttribute(ctx, path.Root("certificate_chains"), types.ListUnknown(certificateChainsType.ElemType))...)
+	}
+
+	if !plan.PKCS12.Equal(state.PKCS12) {
+		resp.Diagnostics.Append(resp.Plan.SetAttribute(ctx, path.Root("pkcs12_b64"), types.StringUnknown())...)
+	}
+
+	if !plan.PreferredRootCN.Equal(state.PreferredRootCN) || plan.storeChain() != state.storeChain() {
+		resp.Diagnostics.Append(resp.Plan.SetAttribute(ctx, path.Root("kubernetes_tls_secret"), types.MapUnknown(types.StringType))...)
+		resp.Diagnostics.Append(resp.Plan.SetAttribute(ctx, path.Root("azure_key_vault_certificate"), types.ObjectUnknown(azureKeyVaultCertificateAttrTypes))...)
+		resp.Diagnostics.Append(resp.Plan.SetAttribute(ctx, path.Root("combined_pem"), types.StringUnknown())...)
+		resp.Diagnostics.Append(resp.Plan.SetAttribute(ctx, path.Root("certificate_chain_pem"), types.StringUnknown())...)
+		resp.Diagnostics.Append(resp.Plan.SetAttribute(ctx, path.Root("certificate_chain"), types.ListUnknown(types.StringType))...)
+		resp.Diagnostics.Append(resp.Plan.SetAttribute(ctx, path.Root("certificate_chain_der"), types.ListUnknown(types.StringType))...)
+		resp.Diagnostics.Append(resp.Plan.SetAttribute(ctx, path.Root("issuing_ca_pem"), types.StringUnknown())...)
+		resp.Diagnostics.Append(resp.Plan.SetAttribute(ctx, path.Root("root_ca_pem"), types.StringUnknown())...)
+		resp.Diagnostics.Append(resp.Plan.SetAttribute(ctx, path.Root("pkcs12_b64"), types.StringUnknown())...)
+	}
+
+	if plan.chainOrder() != state.chainOrder() || plan.includeRootInChain() != state.includeRootInChain() {
+		resp.Diagnostics.Append(resp.Plan.SetAttribute(ctx, path.Root("certificate_chain_pem"), types.StringUnknown())...)
+		resp.Diagnostics.Append(resp.Plan.SetAttribute(ctx, path.Root("certificate_chain"), types.ListUnknown(types.StringType))...)
+		resp.Diagnostics.Append(resp.Plan.SetAttribute(ctx, path.Root("certificate_chain_der"), types.ListUnknown(types.StringType))...)
+		resp.Diagnostics.Append(resp.Plan.SetAttribute(ctx, path.Root("certificate_chains"), types.ListUnknown(certificateChainsType.ElemType))...)
+	}
+
+	// The PKCS#12 bundle can only be rebuilt with a key the provider can read, a generated key that
+	// is encrypted in state takes a new certificate.
+	rebuildPKCS12 := !plan.PKCS12.Equal(state.PKCS12) || !plan.PreferredRootCN.Equal(state.PreferredRootCN) || plan.storeChain() != state.storeChain()
+	if rebuildPKCS12 && !plan.PKCS12.IsNull() && plan.PrivateKeyPEM.IsNull() && isSealed(state.GeneratedPrivateKeyPEM.ValueString()) {
+		tflog.Debug(ctx, "The PKCS#12 bundle changes but the generated key is encrypted in state, planning certificate replacement")
+		resp.RequiresReplace = append(resp.RequiresReplace, path.Root("pkcs12"))
+	}
+}
+
+// ValidateConfig checks the configuration against the guardrails set on the resource.
+func (r *certificateResource) ValidateConfig(ctx context.Context, req resource.ValidateConfigRequest, resp *resource.ValidateConfigResponse) {
+	var config certificateCreateModel
+	diags := req.Config.Get(ctx, &config)
+	resp.Diagnostics.Append(diags...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	if !config.MaxValidityHours.IsNull() && !config.MaxValidityHours.IsUnknown() && config.MaxValidityHours.ValueInt64() < 1 {
+		resp.Diagnostics.AddAttributeError(
+			path.Root("max_accepted_validity_hours"),
+			"Invalid Maximum Validity",
+			fmt.Sprintf("max_accepted_validity_hours must be at least 1, got %d.", config.MaxValidityHours.ValueInt64()),
+		)
+	}
+
+	resp.Diagnostics.Append(validateTimeouts(ctx, config.Timeouts)...)
+	resp.Diagnostics.Append(validateRetry(ctx, config.Retry)...)
+	resp.Diagnostics.Append(validatePostIssuanceChecks(ctx, config.PostIssuanceChecks)...)
+	resp.Diagnostics.Append(validatePendingBehavior(config)...)
+	resp.Diagnostics.Append(validateOnMissing(config)...)
+	resp.Diagnostics.Append(validateChainOrder(config)...)
+	resp.Diagnostics.Append(validateSignatureHashAlgorithm(config)...)
+	resp.Diagnostics.Append(validateVerifyIssuedSANs(config)...)
+	resp.Diagnostics.Append(validateEndpoint(config)...)
+	resp.Diagnostics.Append(validateOnBehalfOf(config)...)
+	resp.Diagnostics.Append(validateProfile(ctx, config)...)
+	resp.Diagnostics.Append(validateDomainController(ctx, config)...)
+
+	if !config.IssuanceTimeout.IsNull() && !config.IssuanceTimeout.IsUnknown() {
+		if timeout, err := time.ParseDuration(config.IssuanceTimeout.ValueString()); err != nil || timeout <= 0 {
+			resp.Diagnostics.AddAttributeError(
+				path.Root("issuance_timeout"),
+				"Invalid Issuance Timeout",
+				fmt.Sprintf("issuance_timeout %q is not a positive duration such as \"30m\" or \"4h\".", config.IssuanceTimeout.ValueString()),
+			)
+		}
+	}
+
+	if !config.RenewalSchedule.IsNull() && !config.RenewalSchedule.IsUnknown() {
+		if _, err := parseCronSchedule(config.RenewalSchedule.ValueString()); err != nil {
+			resp.Diagnostics.AddAttributeError(
+				path.Root("renewal_schedule"),
+				"Invalid Renewal Schedule",
+				fmt.Sprintf("renewal_schedule %q is not a valid cron expression: %s", config.RenewalSchedule.ValueString(), err.Error()),
+			)
+		}
+	}
+
+	if !config.EarlyRenewalHours.IsNull() && !config.EarlyRenewalHours.IsUnknown() && config.EarlyRenewalHours.ValueInt64() < 0 {
+		resp.Diagnostics.AddAttributeError(
+			path.Root("early_renewal_hours"),
+			"Invalid Early Renewal Hours",
+			fmt.Sprintf("early_renewal_hours must not be negative, got %d.", config.EarlyRenewalHours.ValueInt64()),
+		)
+	}
+
+	if !config.RotationPeriod.IsNull() && !config.RotationPeriod.IsUnknown() {
+		if period, err := time.ParseDuration(config.RotationPeriod.ValueString()); err != nil || period <= 0 {
+			resp.Diagnostics.AddAttributeError(
+				path.Root("rotation_period"),
+				"Invalid Rotation Period",
+				fmt.Sprintf("rotation_period %q is not a positive duration such as \"720h\" or \"2160h\".", config.RotationPeriod.ValueString()),
+			)
+		}
+	}
+
+	if !config.RevocationReason.IsNull() && !config.RevocationReason.IsUnknown() {
+		if _, ok := revocationReasons[config.RevocationReason.ValueString()]; !ok {
+			resp.Diagnostics.AddAttributeError(
+				path.Root("revocation_reason"),
+				"Invalid Revocation Reason",
+				fmt.Sprintf("revocation_reason %q is not one of: %s.", config.RevocationReason.ValueString(), revocationReasonNames()),
+			)
+		}
+	}
+
+	if !config.Attributes.IsNull() && !config.RequestAttributes.IsNull() {
+		resp.Diagnostics.AddAttributeError(
+			path.Root("request_attributes"),
+			"Conflicting Request Attributes",
+			"request_attributes replaces the deprecated attributes, set only one of them.",
+		)
+	}
+
+	resp.Diagnostics.Append(validateAttributesMap(ctx, config)...)
+	resp.Diagnostics.Append(validateSubjectAlternativeNames(ctx, config)...)
+	resp.Diagnostics.Append(validateSANAttributeLength(ctx, config)...)
+	resp.Diagnostics.Append(validateAllowedSANPatterns(ctx, config)...)
+	resp.Diagnostics.Append(validateGenerateCSR(ctx, config)...)
+	resp.Diagnostics.Append(validateRequestKey(config.CSR)...)
+	resp.Diagnostics.Append(validateRequestFormat(config)...)
+	resp.Diagnostics.Append(validateStateEncryption(config)...)
+	resp.Diagnostics.Append(validatePKCS12(ctx, config)...)
+	resp.Diagnostics.Append(validateRequestedValidity(config)...)
+	resp.Diagnostics.Append(validateValidityPeriod(config)...)
+	resp.Diagnostics.Append(validateRequesterName(config)...)
+	resp.Diagnostics.Append(validateFriendlyName(config)...)
+	resp.Diagnostics.Append(validateCertificateDescription(config)...)
+	resp.Diagnostics.Append(validatePrivateKey(config)...)
+
+	if config.RenewExisting.ValueBool() && config.PrivateKeyPEM.IsNull() && config.GenerateCSR.IsNull() {
+		resp.Diagnostics.AddAttributeError(
+			path.Root("renew_existing"),
+			"Missing Renewal Key",
+			"renew_existing signs the renewal request with the key of the current certificate, set private_key_pem or use a generate_csr block.",
+		)
+	}
 }
 
 func (r *certificateResource) ImportState(ctx context.Context, req resource.ImportStateRequest, resp *resource.ImportStateResponse) {
-	// Retrieve import ID and save to id attribute
-	resource.ImportStatePassthroughID(ctx, path.Root("id"), req, resp)
+	id, err := parseImportID(req.ID)
+	if err != nil {
+		resp.Diagnostics.AddError("Invalid Import ID", fmt.Sprintf("Could not parse import ID %q: %s.", req.ID, err))
+		return
+	}
+	if id.search() {
+		if id.requestID, err = r.findRequest(ctx, id); err != nil {
+			if errorClass(classifyError(err)) != "" {
+				resp.Diagnostics.AddError(
+					classifiedSummary("Unable to Search Certificates", err),
+					fmt.Sprintf("Could not search the CA database for the certificate to import: %s.", err),
+				)
+				return
+			}
+			resp.Diagnostics.AddError("Certificate Not Found", fmt.Sprintf("Could not find the certificate to import: %s.", err))
+			return
+		}
+		tflog.Info(ctx, "Found the request of the certificate to import", map[string]interface{}{
+			"search":     id.String(),
+			"request_id": id.requestID,
+		})
+	}
+	resp.Diagnostics.Append(resp.State.SetAttribute(ctx, path.Root("id"), id.requestID)...)
 }
@@ -2,9 +2,11 @@ package provider
 
 import (
 	"encoding/base64"
+	"fmt"
 	"testing"
 
 	"github.com/hashicorp/terraform-plugin-testing/helper/resource"
+	"github.com/hashicorp/terraform-plugin-testing/terraform"
 )
 
 // TODO: make this easy to say "my domain is subdomain.example.com" and we just create the csr using crypto/x509
@@ -35,6 +37,7 @@ resource "microsoftadcs_certificate" "test" {
 					resource.TestCheckResourceAttrSet("microsoftadcs_certificate.test", "id"),
 					resource.TestCheckResourceAttrSet("microsoftadcs_certificate.test", "certificate_b64"),
 					resource.TestCheckResourceAttrSet("microsoftadcs_certificate.test", "certificate_chain_b64"),
+					resource.TestCheckResourceAttr("microsoftadcs_certificate.test", "disposition_message", dispositionMessageIssued),
 				),
 			},
 			// ImportState testing
@@ -49,3 +52,96 @@ resource "microsoftadcs_certificate" "test" {
 		},
 	})
 }
+
+// TestAccCertificateResourceStableState reads a certificate with list attributes back from the CA
+// and expects the state and the plan to stay the same.
+func TestAccCertificateResourceStableState(t *testing.T) {
+	forEachAuthMode(t, func(t *testing.T, srv *accCertsrv) {
+		config := srv.providerConfig() + `
+resource "microsoftadcs_certificate" "test" {
+  template     = "WebServer"
+  dns_names    = ["app.example.com", "www.example.com"]
+  ip_addresses = ["10.0.0.1"]
+
+  generate_csr {
+    common_name = "app.example.com"
+  }
+
+  post_issuance_checks {
+    expected_ekus = ["server_auth"]
+  }
+}
+`
+		var created map[string]string
+		capture := func(s *terraform.State) error {
+			created = s.RootModule().Resources["microsoftadcs_certificate.test"].Primary.Attributes
+			return nil
+		}
+		compare := func(s *terraform.State) error {
+			read := s.RootModule().Resources["microsoftadcs_certificate.test"].Primary.Attributes
+			for name, value := range created {
+				if read[name] != value {
+					return fmt.Errorf("%s changed on refresh from %q to %q", name, value, read[name])
+				}
+			}
+			if len(read) != len(created) {
+				return fmt.Errorf("refresh changed the number of attributes from %d to %d", len(created), len(read))
+			}
+			return nil
+		}
+
+		resource.Test(t, resource.TestCase{
+			ProtoV6ProviderFactories: testAccProtoV6ProviderFactories,
+			Steps: []resource.TestStep{
+				{Config: config, Check: capture},
+				{RefreshState: true, Check: compare},
+				{RefreshState: true, Check: compare},
+				// Fails on any difference between the refreshed state and the configuration.
+				{Config: config, PlanOnly: true},
+			},
+		})
+	})
+}
+
+// TestAccCertificateResourceTriggers replaces the certificate when its triggers change, but not
+// when they are added or removed.
+func TestAccCertificateResourceTriggers(t *testing.T) {
+	srv := newAccCertsrv(t)
+	config := func(triggers string) string {
+		return srv.providerConfig() + `
+resource "microsoftadcs_certificate" "test" {
+  template = "WebServer"
+  ` + triggers + `
+
+  generate_csr {
+    common_name = "app.example.com"
+  }
+}
+`
+	}
+	var id string
+	requestID := func(replaced bool) resource.TestCheckFunc {
+		return func(s *terraform.State) error {
+			current := s.RootModule().Resources["microsoftadcs_certificate.test"].Primary.ID
+			switch {
+			case replaced && current == id:
+				return fmt.Errorf("request ID is still %s, want a new request", id)
+			case !replaced && id != "" && current != id:
+				return fmt.Errorf("request ID changed from %s to %s, want the certificate kept", id, current)
+			}
+			id = current
+			return nil
+		}
+	}
+
+	resource.Test(t, resource.TestCase{
+		ProtoV6ProviderFactories: testAccProtoV6ProviderFactories,
+		Steps: []resource.TestStep{
+			{Config: config(""), Check: requestID(false)},
+			{Config: config(`triggers = { image = "ami-1" }`), Check: requestID(false)},
+			{Config: config(`triggers = { image = "ami-1" }`), PlanOnly: true},
+			{Config: config(`triggers = { image = "ami-2" }`), Check: requestID(true)},
+			{Config: config(""), Check: requestID(false)},
+		},
+	})
+}
@@ -0,0 +1,541 @@
+package provider
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"math/big"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-ldap/ldap/v3"
+	"github.com/hashicorp/terraform-plugin-log/tflog"
+)
+
+// Flags of the flags attribute of templates, the general flags.
+const (
+	// ctFlagAddTemplateName adds the Certificate Template Name extension, as templates created in
+	// the Certificate Templates console do.
+	ctFlagAddTemplateName = 0x200
+	// ctFlagIsModified marks templates that are not one of the default templates of Windows.
+	ctFlagIsModified = 0x20000
+)
+
+// Flags of the msPKI-Private-Key-Flag template attribute.
+const (
+	// ctFlagExportableKey allows the private key to be exported.
+	ctFlagExportableKey = 0x10
+)
+
+// templateSchemaVersion is the msPKI-Template-Schema-Version of the templates the provider creates,
+// version 2 templates can be changed and are supported by every enterprise CA.
+const templateSchemaVersion = 2
+
+// templatePeriodUnits are the units of validity_period and renewal_period with their length. The
+// Certificate Templates console counts a month as 30 days and a year as 365 days as well.
+var templatePeriodUnits = map[string]time.Duration{
+	"Hours":  time.Hour,
+	"Days":   24 * time.Hour,
+	"Weeks":  7 * 24 * time.Hour,
+	"Months": 30 * 24 * time.Hour,
+	"Years":  365 * 24 * time.Hour,
+}
+
+// templatePeriodUnitNames lists templatePeriodUnits from the longest, the order periods read from
+// Active Directory are expressed in.
+var templatePeriodUnitNames = []string{"Years", "Months", "Weeks", "Days", "Hours"}
+
+// maxTemplatePeriodUnits bounds the units of a period, keeping it within the 64 bit interval
+// Active Directory stores.
+const maxTemplatePeriodUnits = 1000
+
+// templateKeyUsages are the key usages of pKIKeyUsage by name, with their byte and bit in the
+// encoded key usage bit string.
+var templateKeyUsages = map[string]struct {
+	index int
+	bit   byte
+}{
+	"digital_signature": {0, 0x80},
+	"non_repudiation":   {0, 0x40},
+	"key_encipherment":  {0, 0x20},
+	"data_encipherment": {0, 0x10},
+	"key_agreement":     {0, 0x08},
+	"key_cert_sign":     {0, 0x04},
+	"crl_sign":          {0, 0x02},
+	"encipher_only":     {0, 0x01},
+	"decipher_only":     {1, 0x80},
+}
+
+// templateNameFlags are the flags of msPKI-Certificate-Name-Flag by name, which decide whether the
+// subject comes from the request or is built from the Active Directory object of the requester.
+var templateNameFlags = map[string]uint32{
+	"enrollee_supplies_subject":          ctFlagEnrolleeSuppliesSubject,
+	"enrollee_supplies_subject_alt_name": ctFlagEnrolleeSuppliesSubjectAltName,
+	"subject_alt_require_domain_dns":     0x400000,
+	"subject_alt_require_directory_guid": 0x1000000,
+	"subject_alt_require_upn":            0x2000000,
+	"subject_alt_require_email":          0x4000000,
+	"subject_alt_require_dns":            0x8000000,
+	"subject_require_dns_as_cn":          0x10000000,
+	"subject_require_email":              0x20000000,
+	"subject_require_common_name":        0x40000000,
+	"subject_require_directory_path":     0x80000000,
+}
+
+// templatePeriod is a validity or renewal period, such as 2 "Years".
+type templatePeriod struct {
+	unit  string
+	units int64
+}
+
+// encode returns the period as Active Directory stores it in pKIExpirationPeriod and
+// pKIOverlapPeriod: a negative count of 100 nanosecond intervals, little endian.
+func (p templatePeriod) encode() string {
+	intervals := -p.units * int64(templatePeriodUnits[p.unit]/100)
+	raw := make([]byte, 8)
+	binary.LittleEndian.PutUint64(raw, uint64(intervals))
+	return string(raw)
+}
+
+// decodeTemplatePeriod reads a period of Active Directory in the unit preferred, usually the one
+// in state, or else the longest unit it is a whole number of. Periods shorter than an hour, which
+// the provider does not write, are rounded down to whole hours.
+func decodeTemplatePeriod(raw []byte, preferred string) (templatePeriod, error) {
+	if len(raw) != 8 {
+		return templatePeriod{}, fmt.Errorf("period is %d bytes long instead of 8", len(raw))
+	}
+	period := time.Duration(-int64(binary.LittleEndian.Uint64(raw))) * 100
+	if length, ok := templatePeriodUnits[preferred]; ok && period%length == 0 && period >= length {
+		return templatePeriod{unit: preferred, units: int64(period / length)}, nil
+	}
+	for _, unit := range templatePeriodUnitNames {
+		if length := templatePeriodUnits[unit]; period%length == 0 && period >= length {
+			return templatePeriod{unit: unit, units: int64(period / length)}, nil
+		}
+	}
+	return templatePeriod{unit: "Hours", units: int64(period / time.Hour)}, nil
+}
+
+// templateDefinition holds the settings of a certificate template the provider manages.
+type templateDefinition struct {
+	Name        string
+	DisplayName string
+	OID         string
+	Validity    templatePeriod
+	Renewal     templatePeriod
+	// KeyUsages and NameFlags hold the names of templateKeyUsages and templateNameFlags, EKUs the
+	// OIDs of the extended key usages.
+	KeyUsages        []string
+	EKUs             []string
+	NameFlags        []string
+	MinimumKeySize   int64
+	RequiresApproval bool
+	ExportableKey    bool
+	// MinorRevision is the msPKI-Template-Minor-Revision, which clients compare to notice changes.
+	MinorRevision int64
+}
+
+// attributes returns the attributes of the template object, other than its object class and
+// common name, that the provider writes. Changes to an existing template, current, keep the flags
+// the provider does not manage and the settings only written on creation.
+func (t templateDefinition) attributes(current *ldap.Entry) map[string][]string {
+	keyUsage := make([]byte, 2)
+	for _, name := range t.KeyUsages {
+		usage := templateKeyUsages[name]
+		keyUsage[usage.index] |= usage.bit
+	}
+	// Keys that encipher session keys are exchange keys, those that only sign signature keys.
+	keySpec := "2"
+	if keyUsage[0]&(templateKeyUsages["key_encipherment"].bit|templateKeyUsages["key_agreement"].bit) != 0 {
+		keySpec = "1"
+	}
+
+	var nameFlag, nameFlagMask uint32
+	for name, flag := range templateNameFlags {
+		nameFlagMask |= flag
+		if containsString(t.NameFlags, name) {
+			nameFlag |= flag
+		}
+	}
+	var enrollmentFlag, privateKeyFlag uint32
+	if t.RequiresApproval {
+		enrollmentFlag |= ctFlagPendAllRequests
+	}
+	if t.ExportableKey {
+		privateKeyFlag |= ctFlagExportableKey
+	}
+	if current != nil {
+		nameFlag |= currentFlags(current, "msPKI-Certificate-Name-Flag") &^ nameFlagMask
+		enrollmentFlag |= currentFlags(current, "msPKI-Enrollment-Flag") &^ ctFlagPendAllRequests
+		privateKeyFlag |= currentFlags(current, "msPKI-Private-Key-Flag") &^ ctFlagExportableKey
+	}
+
+	attributes := map[string][]string{
+		"displayName":                   {t.DisplayName},
+		"pKIDefaultKeySpec":             {keySpec},
+		"pKIKeyUsage":                   {string(keyUsage)},
+		"pKIExpirationPeriod":           {t.Validity.encode()},
+		"pKIOverlapPeriod":              {t.Renewal.encode()},
+		"msPKI-Template-Minor-Revision": {strconv.FormatInt(t.MinorRevision, 10)},
+		"msPKI-Minimal-Key-Size":        {strconv.FormatInt(t.MinimumKeySize, 10)},
+		// The flags are stored as signed 32 bit integers.
+		"msPKI-Enrollment-Flag":       {strconv.FormatInt(int64(int32(enrollmentFlag)), 10)},
+		"msPKI-Private-Key-Flag":      {strconv.FormatInt(int64(int32(privateKeyFlag)), 10)},
+		"msPKI-Certificate-Name-Flag": {strconv.FormatInt(int64(int32(nameFlag)), 10)},
+		// Version 1 clients read the extended key usages from pKIExtendedKeyUsage, the CA the
+		// application policies of msPKI-Certificate-Application-Policy. Replacing them with nothing
+		// clears them.
+		"pKIExtendedKeyUsage":                  t.EKUs,
+		"msPKI-Certificate-Application-Policy": t.EKUs,
+	}
+	if current == nil {
+		attributes["flags"] = []string{strconv.Itoa(ctFlagAddTemplateName | ctFlagIsModified)}
+		attributes["revision"] = []string{"100"}
+		attributes["pKIMaxIssuingDepth"] = []string{"0"}
+		attributes["pKICriticalExtensions"] = []string{"2.5.29.15"}
+		attributes["msPKI-Template-Schema-Version"] = []string{strconv.Itoa(templateSchemaVersion)}
+		attributes["msPKI-Cert-Template-OID"] = []string{t.OID}
+		attributes["msPKI-RA-Signature"] = []string{"0"}
+	}
+	return attributes
+}
+
+// currentFlags returns the flags of a template attribute, stored as a signed 32 bit integer.
+func currentFlags(entry *ldap.Entry, attribute string) uint32 {
+	flags, _ := strconv.ParseInt(entry.GetAttributeValue(attribute), 10, 32)
+	return uint32(flags)
+}
+
+// templateAttributeNames are the attributes read back from template objects.
+var templateAttributeNames = []string{
+	"cn", "displayName", "pKIKeyUsage", "pKIExpirationPeriod", "pKIOverlapPeriod", "pKIExtendedKeyUsage",
+	"msPKI-Template-Minor-Revision", "msPKI-Cert-Template-OID", "msPKI-Minimal-Key-Size", "msPKI-Enrollment-Flag",
+	"msPKI-Private-Key-Flag", "msPKI-Certificate-Name-Flag", "msPKI-Certificate-Application-Policy",
+	"msPKI-Template-Schema-Version",
+}
+
+// parseTemplateEntry reads the settings of a template object. Periods are read in the units of
+// previous when they are whole numbers of them. Flags and key usages without a name are left out.
+func parseTemplateEntry(entry *ldap.Entry, previous templateDefinition) (templateDefinition, error) {
+	validity, err := decodeTemplatePeriod(entry.GetRawAttributeValue("pKIExpirationPeriod"), previous.Validity.unit)
+	if err != nil {
+		return templateDefinition{}, fmt.Errorf("could not read the validity period of template %q: %v", entry.GetAttributeValue("cn"), err)
+	}
+	renewal, err := decodeTemplatePeriod(entry.GetRawAttributeValue("pKIOverlapPeriod"), previous.Renewal.unit)
+	if err != nil {
+		return templateDefinition{}, fmt.Errorf("could not read the renewal period of template %q: %v", entry.GetAttributeValue("cn"), err)
+	}
+
+	t := templateDefinition{
+		Name:        entry.GetAttributeValue("cn"),
+		DisplayName: entry.GetAttributeValue("displayName"),
+		OID:         entry.GetAttributeValue("msPKI-Cert-Template-OID"),
+		Validity:    validity,
+		Renewal:     renewal,
+		KeyUsages:   []string{},
+		NameFlags:   []string{},
+	}
+	keyUsage := entry.GetRawAttributeValue("pKIKeyUsage")
+	for name, usage := range templateKeyUsages {
+		if usage.index < len(keyUsage) && keyUsage[usage.index]&usage.bit != 0 {
+			t.KeyUsages = append(t.KeyUsages, name)
+		}
+	}
+	sort.Strings(t.KeyUsages)
+
+	// Version 2 templates keep the extended key usages in the application policies.
+	t.EKUs = entry.GetAttributeValues("msPKI-Certificate-Application-Policy")
+	if len(t.EKUs) == 0 {
+		t.EKUs = entry.GetAttributeValues("pKIExtendedKeyUsage")
+	}
+	if t.EKUs == nil {
+		t.EKUs = []string{}
+	}
+
+	nameFlag := currentFlags(entry, "msPKI-Certificate-Name-Flag")
+	for name, flag := range templateNameFlags {
+		if nameFlag&flag != 0 {
+			t.NameFlags = append(t.NameFlags, name)
+		}
+	}
+	sort.Strings(t.NameFlags)
+
+	t.MinimumKeySize, _ = strconv.ParseInt(entry.GetAttributeValue("msPKI-Minimal-Key-Size"), 10, 64)
+	t.MinorRevision, _ = strconv.ParseInt(entry.GetAttributeValue("msPKI-Template-Minor-Revision"), 10, 64)
+	t.RequiresApproval = currentFlags(entry, "msPKI-Enrollment-Flag")&ctFlagPendAllRequests != 0
+	t.ExportableKey = currentFlags(entry, "msPKI-Private-Key-Flag")&ctFlagExportableKey != 0
+	return t, nil
+}
+
+// templatesDN is the container of the certificate templates in the configuration partition baseDN.
+func templatesDN(baseDN string) string {
+	return "CN=Certificate Templates,CN=Public Key Services,CN=Services," + baseDN
+}
+
+// oidsDN is the container of the enterprise OIDs, which names the OIDs of the templates.
+func oidsDN(baseDN string) string {
+	return "CN=OID,CN=Public Key Services,CN=Services," + baseDN
+}
+
+// connect binds to the directory and returns the connection with the configuration partition.
+func (d *templateDirectory) connect() (*ldap.Conn, string, error) {
+	conn, err := ldap.DialURL(d.url)
+	if err != nil {
+		return nil, "", fmt.Errorf("could not connect to %s: %v", d.url, err)
+	}
+
+	if err := conn.Bind(d.username, d.password); err != nil {
+		conn.Close()
+		return nil, "", fmt.Errorf("could not bind to %s as %s: %v", d.url, d.username, err)
+	}
+
+	baseDN := d.baseDN
+	if baseDN == "" {
+		if baseDN, err = configurationNamingContext(conn); err != nil {
+			conn.Close()
+			return nil, "", err
+		}
+	}
+	return conn, baseDN, nil
+}
+
+// forget drops the cached settings of a template the provider changed.
+func (d *templateDirectory) forget(name string) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	delete(d.cache, name)
+}
+
+// searchTemplate returns the template object with the given common name, nil when there is none.
+func searchTemplate(conn *ldap.Conn, baseDN, name string) (*ldap.Entry, error) {
+	result, err := conn.Search(ldap.NewSearchRequest(
+		templatesDN(baseDN),
+		ldap.ScopeSingleLevel, ldap.NeverDerefAliases, 1, 0, false,
+		fmt.Sprintf("(&(objectClass=pKICertificateTemplate)(cn=%s))", ldap.EscapeFilter(name)),
+		templateAttributeNames,
+		nil,
+	))
+	if err != nil {
+		return nil, fmt.Errorf("could not search for template %q: %v", name, err)
+	}
+	if len(result.Entries) == 0 {
+		return nil, nil
+	}
+	return result.Entries[0], nil
+}
+
+// readTemplate returns the settings of the template with the given common name, nil when it does
+// not exist. Periods are read in the units of previous.
+func (d *templateDirectory) readTemplate(ctx context.Context, name string, previous templateDefinition) (*templateDefinition, error) {
+	conn, baseDN, err := d.connect()
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+
+	tflog.Debug(ctx, "Reading certificate template from Active Directory", map[string]interface{}{
+		"template": name,
+		"base_dn":  baseDN,
+	})
+	entry, err := searchTemplate(conn, baseDN, name)
+	if err != nil || entry == nil {
+		return nil, err
+	}
+	t, err := parseTemplateEntry(entry, previous)
+	if err != nil {
+		return nil, err
+	}
+	return &t, nil
+}
+
+// createTemplate adds the template object, with an OID under the OID of the forest when t has none.
+// The OID is registered in the OID container like the Certificate Templates console does, so tools
+// can name the template of certificates. It returns the template as created.
+func (d *templateDirectory) createTemplate(ctx context.Context, t templateDefinition) (templateDefinition, error) {
+	conn, baseDN, err := d.connect()
+	if err != nil {
+		return t, err
+	}
+	defer conn.Close()
+
+	if existing, err := searchTemplate(conn, baseDN, t.Name); err != nil {
+		return t, err
+	} else if existing != nil {
+		return t, fmt.Errorf("template %q already exists, import it to manage it", t.Name)
+	}
+
+	if t.OID == "" {
+		if t.OID, err = newTemplateOID(conn, baseDN); err != nil {
+			return t, err
+		}
+	}
+	if err := registerTemplateOID(conn, baseDN, t); err != nil {
+		return t, err
+	}
+
+	t.MinorRevision = 0
+	request := ldap.NewAddRequest("CN="+t.Name+","+templatesDN(baseDN), nil)
+	request.Attribute("objectClass", []string{"top", "pKICertificateTemplate"})
+	request.Attribute("cn", []string{t.Name})
+	attributes := t.attributes(nil)
+	for _, name := range sortedKeys(attributes) {
+		if len(attributes[name]) > 0 {
+			request.Attribute(name, attributes[name])
+		}
+	}
+	tflog.Info(ctx, "Creating certificate template in Active Directory", map[string]interface{}{
+		"template": t.Name,
+		"oid":      t.OID,
+	})
+	if err := conn.Add(request); err != nil {
+		return t, fmt.Errorf("could not create template %q: %v", t.Name, err)
+	}
+	d.forget(t.Name)
+	return t, nil
+}
+
+// updateTemplate replaces the settings of the template object and raises its minor revision, so
+// clients pick up the changes. It returns the template as updated.
+func (d *templateDirectory) updateTemplate(ctx context.Context, t templateDefinition) (templateDefinition, error) {
+	conn, baseDN, err := d.connect()
+	if err != nil {
+		return t, err
+	}
+	defer conn.Close()
+
+	entry, err := searchTemplate(conn, baseDN, t.Name)
+	if err != nil {
+		return t, err
+	}
+	if entry == nil {
+		return t, fmt.Errorf("template %q was not found in Active Directory", t.Name)
+	}
+	// Version 1 templates are the defaults of Windows, the CA does not pick up changes to them.
+	if version, _ := strconv.Atoi(entry.GetAttributeValue("msPKI-Template-Schema-Version")); version < templateSchemaVersion {
+		return t, fmt.Errorf("template %q is a version %d template, which cannot be changed; duplicate it in the Certificate Templates console instead", t.Name, version)
+	}
+	revision, _ := strconv.ParseInt(entry.GetAttributeValue("msPKI-Template-Minor-Revision"), 10, 64)
+	t.MinorRevision = revision + 1
+
+	request := ldap.NewModifyRequest(entry.DN, nil)
+	attributes := t.attributes(entry)
+	for _, name := range sortedKeys(attributes) {
+		request.Replace(name, attributes[name])
+	}
+	tflog.Info(ctx, "Updating certificate template in Active Directory", map[string]interface{}{
+		"template":       t.Name,
+		"minor_revision": t.MinorRevision,
+	})
+	if err := conn.Modify(request); err != nil {
+		return t, fmt.Errorf("could not update template %q: %v", t.Name, err)
+	}
+	d.forget(t.Name)
+	return t, nil
+}
+
+// deleteTemplate removes the template object and the registration of its OID. A template that is
+// already gone is not an error.
+func (d *templateDirectory) deleteTemplate(ctx context.Context, name, oid string) error {
+	conn, baseDN, err := d.connect()
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	entry, err := searchTemplate(conn, baseDN, name)
+	if err != nil {
+		return err
+	}
+	if entry != nil {
+		tflog.Info(ctx, "Deleting certificate template from Active Directory", map[string]interface{}{
+			"template": name,
+		})
+		if err := conn.Del(ldap.NewDelRequest(entry.DN, nil)); err != nil {
+			return fmt.Errorf("could not delete template %q: %v", name, err)
+		}
+	}
+	d.forget(name)
+
+	if oid == "" {
+		return nil
+	}
+	registration, err := searchTemplateOID(conn, baseDN, oid)
+	if err != nil || registration == nil {
+		return err
+	}
+	if err := conn.Del(ldap.NewDelRequest(registration.DN, nil)); err != nil {
+		return fmt.Errorf("could not delete the registration of template OID %s: %v", oid, err)
+	}
+	return nil
+}
+
+// newTemplateOID returns a new template OID under the OID the forest was assigned, which the OID
+// container keeps in msPKI-Cert-Template-OID, from two random numbers like the Certificate
+// Templates console picks them.
+func newTemplateOID(conn *ldap.Conn, baseDN string) (string, error) {
+	result, err := conn.Search(ldap.NewSearchRequest(
+		oidsDN(baseDN), ldap.ScopeBaseObject, ldap.NeverDerefAliases, 1, 0, false,
+		"(objectClass=*)", []string{"msPKI-Cert-Template-OID"}, nil,
+	))
+	if err != nil {
+		return "", fmt.Errorf("could not read the OID of the forest: %v", err)
+	}
+	if len(result.Entries) == 0 || result.Entries[0].GetAttributeValue("msPKI-Cert-Template-OID") == "" {
+		return "", fmt.Errorf("the OID container does not hold the OID of the forest, set oid")
+	}
+
+	oid := result.Entries[0].GetAttributeValue("msPKI-Cert-Template-OID")
+	for i := 0; i < 2; i++ {
+		n, err := rand.Int(rand.Reader, big.NewInt(1<<24-1))
+		if err != nil {
+			return "", err
+		}
+		oid += "." + strconv.FormatInt(n.Int64()+1, 10)
+	}
+	return oid, nil
+}
+
+// searchTemplateOID returns the registration of a template OID in the OID container, nil when it
+// is not registered.
+func searchTemplateOID(conn *ldap.Conn, baseDN, oid string) (*ldap.Entry, error) {
+	result, err := conn.Search(ldap.NewSearchRequest(
+		oidsDN(baseDN), ldap.ScopeSingleLevel, ldap.NeverDerefAliases, 1, 0, false,
+		fmt.Sprintf("(&(objectClass=msPKI-Enterprise-Oid)(msPKI-Cert-Template-OID=%s))", ldap.EscapeFilter(oid)),
+		[]string{"cn"}, nil,
+	))
+	if err != nil {
+		return nil, fmt.Errorf("could not search for the registration of template OID %s: %v", oid, err)
+	}
+	if len(result.Entries) == 0 {
+		return nil, nil
+	}
+	return result.Entries[0], nil
+}
+
+// registerTemplateOID adds the msPKI-Enterprise-Oid object naming the OID of t, unless the OID is
+// registered already. Its common name is the last arc of the OID and a random suffix.
+func registerTemplateOID(conn *ldap.Conn, baseDN string, t templateDefinition) error {
+	if registration, err := searchTemplateOID(conn, baseDN, t.OID); err != nil || registration != nil {
+		return err
+	}
+
+	suffix := make([]byte, 16)
+	if _, err := rand.Read(suffix); err != nil {
+		return err
+	}
+	cn := t.OID[strings.LastIndex(t.OID, ".")+1:] + "." + strings.ToUpper(hex.EncodeToString(suffix))
+	request := ldap.NewAddRequest("CN="+cn+","+oidsDN(baseDN), nil)
+	request.Attribute("objectClass", []string{"top", "msPKI-Enterprise-Oid"})
+	request.Attribute("cn", []string{cn})
+	request.Attribute("displayName", []string{t.DisplayName})
+	request.Attribute("flags", []string{"1"})
+	request.Attribute("msPKI-Cert-Template-OID", []string{t.OID})
+	if err := conn.Add(request); err != nil {
+		return fmt.Errorf("could not register template OID %s: %v", t.OID, err)
+	}
+	return nil
+}
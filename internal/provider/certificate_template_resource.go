@@ -0,0 +1,560 @@
+package provider
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/hashicorp/terraform-plugin-framework/diag"
+	"github.com/hashicorp/terraform-plugin-framework/path"
+	"github.com/hashicorp/terraform-plugin-framework/resource"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/boolplanmodifier"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/int64planmodifier"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/planmodifier"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/setplanmodifier"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/stringplanmodifier"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+	"github.com/hashicorp/terraform-plugin-log/tflog"
+)
+
+// Ensure the implementation satisfies the expected interfaces.
+var (
+	_ resource.Resource                   = &certificateTemplateResource{}
+	_ resource.ResourceWithConfigure      = &certificateTemplateResource{}
+	_ resource.ResourceWithImportState    = &certificateTemplateResource{}
+	_ resource.ResourceWithValidateConfig = &certificateTemplateResource{}
+)
+
+// NewCertificateTemplateResource is a helper function to simplify the provider implementation.
+func NewCertificateTemplateResource() resource.Resource {
+	return &certificateTemplateResource{}
+}
+
+// certificateTemplateResource manages a certificate template in the configuration partition of
+// Active Directory, through the ldap_url of the provider.
+type certificateTemplateResource struct {
+	provider *providerData
+}
+
+type certificateTemplateModel struct {
+	ID                  types.String `tfsdk:"id"`
+	Name                types.String `tfsdk:"name"`
+	DisplayName         types.String `tfsdk:"display_name"`
+	OID                 types.String `tfsdk:"oid"`
+	ValidityPeriod      types.String `tfsdk:"validity_period"`
+	ValidityPeriodUnits types.Int64  `tfsdk:"validity_period_units"`
+	RenewalPeriod       types.String `tfsdk:"renewal_period"`
+	RenewalPeriodUnits  types.Int64  `tfsdk:"renewal_period_units"`
+	KeyUsage            types.Set    `tfsdk:"key_usage"`
+	ExtendedKeyUsages   types.Set    `tfsdk:"extended_key_usages"`
+	SubjectNameFlags    types.Set    `tfsdk:"subject_name_flags"`
+	MinimumKeySize      types.Int64  `tfsdk:"minimum_key_size"`
+	RequiresApproval    types.Bool   `tfsdk:"requires_approval"`
+	ExportableKey       types.Bool   `tfsdk:"exportable_key"`
+}
+
+// Defaults of the settings of new templates, those of a duplicated Web Server template.
+var (
+	defaultTemplateValidity       = templatePeriod{unit: "Years", units: 1}
+	defaultTemplateRenewal        = templatePeriod{unit: "Weeks", units: 6}
+	defaultTemplateKeyUsages      = []string{"digital_signature", "key_encipherment"}
+	defaultTemplateNameFlags      = []string{"enrollee_supplies_subject"}
+	defaultTemplateMinimumKeySize = int64(2048)
+)
+
+// templateNameRegex matches the common names templates can have, which are part of the DN of the
+// template object and so cannot contain the characters DNs escape.
+var templateNameRegex = regexp.MustCompile(`^[^,+"\\<>;=#/][^,+"\\<>;=/]{0,63}$`)
+
+// templateOIDRegex matches dotted object identifiers.
+var templateOIDRegex = regexp.MustCompile(`^[0-2](\.[0-9]+)+$`)
+
+// templatePeriodUnitsDescription lists the units of validity_period and renewal_period.
+const templatePeriodUnitsDescription = `"Hours", "Days", "Weeks", "Months" or "Years"`
+
+// Metadata returns the resource type name.
+func (r *certificateTemplateResource) Metadata(_ context.Context, req resource.MetadataRequest, resp *resource.MetadataResponse) {
+	resp.TypeName = req.ProviderTypeName + "_certificate_template"
+}
+
+// Schema defines the schema for the resource.
+func (r *certificateTemplateResource) Schema(_ context.Context, _ resource.SchemaRequest, resp *resource.SchemaResponse) {
+	resp.Schema = schema.Schema{
+		Description: `Manages a version 2 certificate template, a pKICertificateTemplate object in the configuration partition of
+Active Directory, through the ldap_url of the provider. The provider account needs write access to the Certificate
+Templates and OID containers, which Enterprise Admins have. Enroll permissions and publishing the template on a CA are
+not managed. Settings left out of the configuration take the defaults of a duplicated Web Server template when the
+template is created, and afterwards keep what Active Directory holds.`,
+		Attributes: map[string]schema.Attribute{
+			"id": schema.StringAttribute{
+				Computed:    true,
+				Description: "The name of the template.",
+				PlanModifiers: []planmodifier.String{
+					stringplanmodifier.UseStateForUnknown(),
+				},
+			},
+			"name": schema.StringAttribute{
+				Required:    true,
+				Description: "Common name of the template, the name requests refer to, such as template of microsoftadcs_certificate.",
+				PlanModifiers: []planmodifier.String{
+					stringplanmodifier.RequiresReplace(),
+				},
+			},
+			"display_name": schema.StringAttribute{
+				Optional:    true,
+				Computed:    true,
+				Description: "Name the Certificate Templates console shows. Defaults to name.",
+				PlanModifiers: []planmodifier.String{
+					stringplanmodifier.UseStateForUnknown(),
+				},
+			},
+			"oid": schema.StringAttribute{
+				Optional: true,
+				Computed: true,
+				Description: `Object identifier of the template, which certificates issued from it name. Generated under the OID of
+the forest when not set, like the Certificate Templates console does.`,
+				PlanModifiers: []planmodifier.String{
+					stringplanmodifier.UseStateForUnknown(),
+					stringplanmodifier.RequiresReplace(),
+				},
+			},
+			"validity_period": schema.StringAttribute{
+				Optional:    true,
+				Computed:    true,
+				Description: "Unit of the validity period of issued certificates: " + templatePeriodUnitsDescription + `. Defaults to "Years".`,
+				PlanModifiers: []planmodifier.String{
+					stringplanmodifier.UseStateForUnknown(),
+				},
+			},
+			"validity_period_units": schema.Int64Attribute{
+				Optional:    true,
+				Computed:    true,
+				Description: "Number of validity_period units certificates are valid for. Defaults to 1.",
+				PlanModifiers: []planmodifier.Int64{
+					int64planmodifier.UseStateForUnknown(),
+				},
+			},
+			"renewal_period": schema.StringAttribute{
+				Optional: true,
+				Computed: true,
+				Description: "Unit of the renewal period, how long before expiry autoenrollment renews certificates: " +
+					templatePeriodUnitsDescription + `. Defaults to "Weeks".`,
+				PlanModifiers: []planmodifier.String{
+					stringplanmodifier.UseStateForUnknown(),
+				},
+			},
+			"renewal_period_units": schema.Int64Attribute{
+				Optional:    true,
+				Computed:    true,
+				Description: "Number of renewal_period units. Defaults to 6.",
+				PlanModifiers: []planmodifier.Int64{
+					int64planmodifier.UseStateForUnknown(),
+				},
+			},
+			"key_usage": schema.SetAttribute{
+				Optional:    true,
+				Computed:    true,
+				ElementType: types.StringType,
+				Description: "Key usages of issued certificates: " + strings.Join(sortedKeys(templateKeyUsages), ", ") + `. Defaults to digital_signature and
+key_encipherment.`,
+				PlanModifiers: []planmodifier.Set{
+					setplanmodifier.UseStateForUnknown(),
+				},
+			},
+			"extended_key_usages": schema.SetAttribute{
+				Required:    true,
+				ElementType: types.StringType,
+				Description: "Extended key usages of issued certificates, by name (" + strings.Join(sortedKeys(extendedKeyUsages), ", ") + `) or OID. They
+are written as application policies as well.`,
+			},
+			"subject_name_flags": schema.SetAttribute{
+				Optional:    true,
+				Computed:    true,
+				ElementType: types.StringType,
+				Description: "Where the CA takes the subject and subject alternative names from: " + strings.Join(sortedKeys(templateNameFlags), ", ") + `.
+The enrollee_supplies flags take them from the request, the require flags build them from the Active Directory object of
+the requester. Defaults to enrollee_supplies_subject.`,
+				PlanModifiers: []planmodifier.Set{
+					setplanmodifier.UseStateForUnknown(),
+				},
+			},
+			"minimum_key_size": schema.Int64Attribute{
+				Optional:    true,
+				Computed:    true,
+				Description: "Smallest key in bits the CA accepts requests for. Defaults to 2048.",
+				PlanModifiers: []planmodifier.Int64{
+					int64planmodifier.UseStateForUnknown(),
+				},
+			},
+			"requires_approval": schema.BoolAttribute{
+				Optional:    true,
+				Computed:    true,
+				Description: "Hold every request for CA manager approval. Defaults to false.",
+				PlanModifiers: []planmodifier.Bool{
+					boolplanmodifier.UseStateForUnknown(),
+				},
+			},
+			"exportable_key": schema.BoolAttribute{
+				Optional:    true,
+				Computed:    true,
+				Description: "Allow the private key to be exported from the key store it is generated in. Defaults to false.",
+				PlanModifiers: []planmodifier.Bool{
+					boolplanmodifier.UseStateForUnknown(),
+				},
+			},
+		},
+	}
+}
+
+// Configure adds the provider settings to the resource.
+func (r *certificateTemplateResource) Configure(_ context.Context, req resource.ConfigureRequest, resp *resource.ConfigureResponse) {
+	if req.ProviderData == nil {
+		return
+	}
+
+	data, ok := req.ProviderData.(*providerData)
+	if !ok {
+		resp.Diagnostics.AddError(
+			"Unexpected Resource Configure Type",
+			fmt.Sprintf("Expected *providerData, got: %T. Please report this issue to the provider developers.", req.ProviderData),
+		)
+
+		return
+	}
+
+	r.provider = data
+}
+
+// directory returns the template directory of the provider, which needs ldap_url.
+func (r *certificateTemplateResource) directory(diags *diag.Diagnostics) *templateDirectory {
+	if r.provider.templates == nil {
+		diags.AddError(
+			"Missing LDAP URL",
+			"Certificate templates are managed in Active Directory, set ldap_url on the provider.",
+		)
+	}
+	return r.provider.templates
+}
+
+// definition returns the template the model describes, with the defaults for settings that are
+// not known yet.
+func (m *certificateTemplateModel) definition(ctx context.Context) (templateDefinition, diag.Diagnostics) {
+	var diags diag.Diagnostics
+	t := templateDefinition{
+		Name:             m.Name.ValueString(),
+		DisplayName:      m.DisplayName.ValueString(),
+		OID:              m.OID.ValueString(),
+		Validity:         defaultTemplateValidity,
+		Renewal:          defaultTemplateRenewal,
+		KeyUsages:        defaultTemplateKeyUsages,
+		NameFlags:        defaultTemplateNameFlags,
+		MinimumKeySize:   defaultTemplateMinimumKeySize,
+		RequiresApproval: m.RequiresApproval.ValueBool(),
+		ExportableKey:    m.ExportableKey.ValueBool(),
+	}
+	if m.DisplayName.IsUnknown() || t.DisplayName == "" {
+		t.DisplayName = t.Name
+	}
+	if m.OID.IsUnknown() {
+		t.OID = ""
+	}
+	if !m.ValidityPeriod.IsUnknown() && !m.ValidityPeriod.IsNull() {
+		t.Validity = templatePeriod{unit: m.ValidityPeriod.ValueString(), units: m.ValidityPeriodUnits.ValueInt64()}
+	}
+	if !m.RenewalPeriod.IsUnknown() && !m.RenewalPeriod.IsNull() {
+		t.Renewal = templatePeriod{unit: m.RenewalPeriod.ValueString(), units: m.RenewalPeriodUnits.ValueInt64()}
+	}
+	if !m.KeyUsage.IsUnknown() && !m.KeyUsage.IsNull() {
+		diags.Append(m.KeyUsage.ElementsAs(ctx, &t.KeyUsages, false)...)
+	}
+	if !m.SubjectNameFlags.IsUnknown() && !m.SubjectNameFlags.IsNull() {
+		diags.Append(m.SubjectNameFlags.ElementsAs(ctx, &t.NameFlags, false)...)
+	}
+	if !m.MinimumKeySize.IsUnknown() && !m.MinimumKeySize.IsNull() {
+		t.MinimumKeySize = m.MinimumKeySize.ValueInt64()
+	}
+
+	var ekus []string
+	diags.Append(m.ExtendedKeyUsages.ElementsAs(ctx, &ekus, false)...)
+	t.EKUs = make([]string, 0, len(ekus))
+	for _, eku := range ekus {
+		if usage, ok := extendedKeyUsages[eku]; ok {
+			eku = usage.oid
+		}
+		t.EKUs = append(t.EKUs, eku)
+	}
+	sort.Strings(t.EKUs)
+	return t, diags
+}
+
+// setDefinition stores the template as Active Directory holds it. Extended key usages are named
+// when they have a name, unless extended_key_usages spells out their OID.
+func (m *certificateTemplateModel) setDefinition(ctx context.Context, t templateDefinition) diag.Diagnostics {
+	var diags diag.Diagnostics
+	var previous []string
+	if !m.ExtendedKeyUsages.IsUnknown() && !m.ExtendedKeyUsages.IsNull() {
+		diags.Append(m.ExtendedKeyUsages.ElementsAs(ctx, &previous, false)...)
+	}
+	ekus := make([]string, 0, len(t.EKUs))
+	for _, oid := range t.EKUs {
+		eku := oid
+		if !containsString(previous, oid) {
+			for name, usage := range extendedKeyUsages {
+				if usage.oid == oid {
+					eku = name
+				}
+			}
+		}
+		ekus = append(ekus, eku)
+	}
+
+	m.ID = types.StringValue(t.Name)
+	m.Name = types.StringValue(t.Name)
+	m.DisplayName = types.StringValue(t.DisplayName)
+	m.OID = types.StringValue(t.OID)
+	m.ValidityPeriod = types.StringValue(t.Validity.unit)
+	m.ValidityPeriodUnits = types.Int64Value(t.Validity.units)
+	m.RenewalPeriod = types.StringValue(t.Renewal.unit)
+	m.RenewalPeriodUnits = types.Int64Value(t.Renewal.units)
+	m.MinimumKeySize = types.Int64Value(t.MinimumKeySize)
+	m.RequiresApproval = types.BoolValue(t.RequiresApproval)
+	m.ExportableKey = types.BoolValue(t.ExportableKey)
+	for _, set := range []struct {
+		value  *types.Set
+		values []string
+	}{
+		{&m.KeyUsage, t.KeyUsages},
+		{&m.ExtendedKeyUsages, ekus},
+		{&m.SubjectNameFlags, t.NameFlags},
+	} {
+		value, setDiags := types.SetValueFrom(ctx, types.StringType, set.values)
+		diags.Append(setDiags...)
+		*set.value = value
+	}
+	return diags
+}
+
+// ValidateConfig checks the names, periods and flags of the template.
+func (r *certificateTemplateResource) ValidateConfig(ctx context.Context, req resource.ValidateConfigRequest, resp *resource.ValidateConfigResponse) {
+	var config certificateTemplateModel
+	resp.Diagnostics.Append(req.Config.Get(ctx, &config)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+	resp.Diagnostics.Append(validateCertificateTemplate(ctx, config)...)
+}
+
+// validateCertificateTemplate checks a template configuration, leaving out the values that are not
+// known yet.
+func validateCertificateTemplate(ctx context.Context, config certificateTemplateModel) diag.Diagnostics {
+	var diags diag.Diagnostics
+	if name := config.Name; !name.IsUnknown() && !templateNameRegex.MatchString(name.ValueString()) {
+		diags.AddAttributeError(path.Root("name"), "Invalid Template Name",
+			fmt.Sprintf("name %q has to be 1 to 64 characters without any of , + \" \\ < > ; = / or a leading #.", name.ValueString()))
+	}
+	if oid := config.OID; !oid.IsNull() && !oid.IsUnknown() && !templateOIDRegex.MatchString(oid.ValueString()) {
+		diags.AddAttributeError(path.Root("oid"), "Invalid Template OID", fmt.Sprintf("oid %q is not a dotted object identifier.", oid.ValueString()))
+	}
+
+	validity := validateTemplatePeriod(config.ValidityPeriod, config.ValidityPeriodUnits, "validity_period", &diags)
+	renewal := validateTemplatePeriod(config.RenewalPeriod, config.RenewalPeriodUnits, "renewal_period", &diags)
+	if validity == nil && config.ValidityPeriod.IsNull() {
+		validity = &defaultTemplateValidity
+	}
+	if validity != nil && renewal != nil &&
+		renewal.units*int64(templatePeriodUnits[renewal.unit]) >= validity.units*int64(templatePeriodUnits[validity.unit]) {
+		diags.AddAttributeError(path.Root("renewal_period"), "Invalid Renewal Period",
+			"The renewal period has to be shorter than the validity period, certificates would be renewed as soon as they are issued.")
+	}
+
+	for _, set := range []struct {
+		attribute string
+		value     types.Set
+		names     []string
+	}{
+		{"key_usage", config.KeyUsage, sortedKeys(templateKeyUsages)},
+		{"subject_name_flags", config.SubjectNameFlags, sortedKeys(templateNameFlags)},
+	} {
+		if set.value.IsNull() || set.value.IsUnknown() {
+			continue
+		}
+		var values []string
+		diags.Append(set.value.ElementsAs(ctx, &values, true)...)
+		for _, value := range values {
+			if !containsString(set.names, value) {
+				diags.AddAttributeError(path.Root(set.attribute), "Invalid Template Setting",
+					fmt.Sprintf("%s %q is not one of: %s.", set.attribute, value, strings.Join(set.names, ", ")))
+			}
+		}
+	}
+
+	if !config.ExtendedKeyUsages.IsUnknown() {
+		var ekus []string
+		diags.Append(config.ExtendedKeyUsages.ElementsAs(ctx, &ekus, true)...)
+		for _, eku := range ekus {
+			if _, ok := extendedKeyUsages[eku]; !ok && !templateOIDRegex.MatchString(eku) {
+				diags.AddAttributeError(path.Root("extended_key_usages"), "Invalid Extended Key Usage",
+					fmt.Sprintf("extended_key_usages %q is neither an OID nor one of: %s.", eku, strings.Join(sortedKeys(extendedKeyUsages), ", ")))
+			}
+		}
+	}
+
+	if size := config.MinimumKeySize; !size.IsNull() && !size.IsUnknown() && size.ValueInt64() < 1 {
+		diags.AddAttributeError(path.Root("minimum_key_size"), "Invalid Minimum Key Size",
+			fmt.Sprintf("minimum_key_size must be at least 1, got %d.", size.ValueInt64()))
+	}
+	return diags
+}
+
+// validateTemplatePeriod checks that a period and its units are set together with a known unit and
+// a count between 1 and maxTemplatePeriodUnits. It returns the period when it is set and known.
+func validateTemplatePeriod(unit types.String, units types.Int64, attribute string, diags *diag.Diagnostics) *templatePeriod {
+	if unit.IsNull() && units.IsNull() {
+		return nil
+	}
+	if unit.IsNull() != units.IsNull() {
+		diags.AddAttributeError(path.Root(attribute), "Incomplete Template Period",
+			fmt.Sprintf("%s and %s_units have to be set together, such as \"Years\" and 2.", attribute, attribute))
+		return nil
+	}
+	if unit.IsUnknown() || units.IsUnknown() {
+		return nil
+	}
+	if _, ok := templatePeriodUnits[unit.ValueString()]; !ok {
+		diags.AddAttributeError(path.Root(attribute), "Invalid Template Period",
+			fmt.Sprintf("%s %q is not one of: %s.", attribute, unit.ValueString(), templatePeriodUnitsDescription))
+		return nil
+	}
+	if units.ValueInt64() < 1 || units.ValueInt64() > maxTemplatePeriodUnits {
+		diags.AddAttributeError(path.Root(attribute+"_units"), "Invalid Template Period",
+			fmt.Sprintf("%s_units must be between 1 and %d, got %d.", attribute, maxTemplatePeriodUnits, units.ValueInt64()))
+		return nil
+	}
+	return &templatePeriod{unit: unit.ValueString(), units: units.ValueInt64()}
+}
+
+// Create creates the template in Active Directory.
+func (r *certificateTemplateResource) Create(ctx context.Context, req resource.CreateRequest, resp *resource.CreateResponse) {
+	var plan certificateTemplateModel
+	resp.Diagnostics.Append(req.Plan.Get(ctx, &plan)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+	if r.provider.readOnly {
+		resp.Diagnostics.Append(readOnlyError("create a certificate template"))
+		return
+	}
+	directory := r.directory(&resp.Diagnostics)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	t, diags := plan.definition(ctx)
+	resp.Diagnostics.Append(diags...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+	t, err := directory.createTemplate(ctx, t)
+	if err != nil {
+		resp.Diagnostics.AddError(classifiedSummary("Error Creating Certificate Template", err), err.Error())
+		return
+	}
+	resp.Diagnostics.Append(plan.setDefinition(ctx, t)...)
+	resp.Diagnostics.Append(resp.State.Set(ctx, plan)...)
+}
+
+// Read refreshes the template from Active Directory.
+func (r *certificateTemplateResource) Read(ctx context.Context, req resource.ReadRequest, resp *resource.ReadResponse) {
+	var state certificateTemplateModel
+	resp.Diagnostics.Append(req.State.Get(ctx, &state)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+	if r.provider.caOffline(ctx) {
+		return
+	}
+	directory := r.directory(&resp.Diagnostics)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	// Imports only know the name.
+	name := state.ID.ValueString()
+	previous := templateDefinition{
+		Validity: templatePeriod{unit: state.ValidityPeriod.ValueString()},
+		Renewal:  templatePeriod{unit: state.RenewalPeriod.ValueString()},
+	}
+	t, err := directory.readTemplate(ctx, name, previous)
+	if err != nil {
+		resp.Diagnostics.AddError(classifiedSummary("Error Reading Certificate Template", err), err.Error())
+		return
+	}
+	if t == nil {
+		tflog.Warn(ctx, "Certificate template was removed from Active Directory, removing it from state", map[string]interface{}{
+			"template": name,
+		})
+		resp.State.RemoveResource(ctx)
+		return
+	}
+	resp.Diagnostics.Append(state.setDefinition(ctx, *t)...)
+	resp.Diagnostics.Append(resp.State.Set(ctx, state)...)
+}
+
+// Update changes the template in Active Directory.
+func (r *certificateTemplateResource) Update(ctx context.Context, req resource.UpdateRequest, resp *resource.UpdateResponse) {
+	var plan certificateTemplateModel
+	resp.Diagnostics.Append(req.Plan.Get(ctx, &plan)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+	if r.provider.readOnly {
+		resp.Diagnostics.Append(readOnlyError("change a certificate template"))
+		return
+	}
+	directory := r.directory(&resp.Diagnostics)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	t, diags := plan.definition(ctx)
+	resp.Diagnostics.Append(diags...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+	t, err := directory.updateTemplate(ctx, t)
+	if err != nil {
+		resp.Diagnostics.AddError(classifiedSummary("Error Updating Certificate Template", err), err.Error())
+		return
+	}
+	resp.Diagnostics.Append(plan.setDefinition(ctx, t)...)
+	resp.Diagnostics.Append(resp.State.Set(ctx, plan)...)
+}
+
+// Delete removes the template and the registration of its OID from Active Directory.
+func (r *certificateTemplateResource) Delete(ctx context.Context, req resource.DeleteRequest, resp *resource.DeleteResponse) {
+	var state certificateTemplateModel
+	resp.Diagnostics.Append(req.State.Get(ctx, &state)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+	if r.provider.readOnly {
+		resp.Diagnostics.Append(readOnlyError("delete a certificate template"))
+		return
+	}
+	directory := r.directory(&resp.Diagnostics)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	if err := directory.deleteTemplate(ctx, state.ID.ValueString(), state.OID.ValueString()); err != nil {
+		resp.Diagnostics.AddError(classifiedSummary("Error Deleting Certificate Template", err), err.Error())
+	}
+}
+
+// ImportState imports a template by its name.
+func (r *certificateTemplateResource) ImportState(ctx context.Context, req resource.ImportStateRequest, resp *resource.ImportStateResponse) {
+	resource.ImportStatePassthroughID(ctx, path.Root("id"), req, resp)
+}
@@ -0,0 +1,171 @@
+package provider
+
+import (
+	"context"
+	"encoding/binary"
+	"reflect"
+	"testing"
+
+	"github.com/go-ldap/ldap/v3"
+	"github.com/hashicorp/terraform-plugin-framework/attr"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+)
+
+func TestTemplatePeriod(t *testing.T) {
+	// One year as the Certificate Templates console stores it.
+	intervals := int64(-315360000000000)
+	year := make([]byte, 8)
+	binary.LittleEndian.PutUint64(year, uint64(intervals))
+	if got := (templatePeriod{unit: "Years", units: 1}).encode(); got != string(year) {
+		t.Errorf("encode() = %x, want %x", got, year)
+	}
+
+	for name, tc := range map[string]struct {
+		period    templatePeriod
+		preferred string
+		want      templatePeriod
+	}{
+		"longest unit":      {period: templatePeriod{"Days", 42}, want: templatePeriod{"Weeks", 6}},
+		"preferred unit":    {period: templatePeriod{"Years", 1}, preferred: "Days", want: templatePeriod{"Days", 365}},
+		"preferred too big": {period: templatePeriod{"Days", 10}, preferred: "Weeks", want: templatePeriod{"Days", 10}},
+		"hours":             {period: templatePeriod{"Hours", 36}, want: templatePeriod{"Hours", 36}},
+	} {
+		got, err := decodeTemplatePeriod([]byte(tc.period.encode()), tc.preferred)
+		if err != nil || got != tc.want {
+			t.Errorf("%s: decodeTemplatePeriod() = %v, %v, want %v", name, got, err, tc.want)
+		}
+	}
+	if _, err := decodeTemplatePeriod([]byte{1, 2}, ""); err == nil {
+		t.Error("decodeTemplatePeriod() of 2 bytes succeeded, want an error")
+	}
+}
+
+func TestTemplateDefinitionAttributes(t *testing.T) {
+	want := templateDefinition{
+		Name:             "WebServerTF",
+		DisplayName:      "Web Server (Terraform)",
+		OID:              "1.3.6.1.4.1.311.21.8.1.2.3.4",
+		Validity:         templatePeriod{"Years", 2},
+		Renewal:          templatePeriod{"Weeks", 6},
+		KeyUsages:        []string{"digital_signature", "key_encipherment"},
+		EKUs:             []string{"1.3.6.1.5.5.7.3.1", "1.3.6.1.5.5.7.3.2"},
+		NameFlags:        []string{"enrollee_supplies_subject", "subject_require_directory_path"},
+		MinimumKeySize:   3072,
+		RequiresApproval: true,
+		ExportableKey:    true,
+		MinorRevision:    4,
+	}
+	attributes := want.attributes(nil)
+	if got := attributes["pKIKeyUsage"][0]; got != "\xa0\x00" {
+		t.Errorf("pKIKeyUsage = %x, want a000", got)
+	}
+	if got := attributes["pKIDefaultKeySpec"][0]; got != "1" {
+		t.Errorf("pKIDefaultKeySpec = %s, want 1 for key_encipherment", got)
+	}
+	if got := attributes["msPKI-Certificate-Name-Flag"][0]; got != "-2147483647" {
+		t.Errorf("msPKI-Certificate-Name-Flag = %s, want the flags as a signed 32 bit integer", got)
+	}
+	attributes["cn"] = []string{want.Name}
+
+	got, err := parseTemplateEntry(ldap.NewEntry("CN=WebServerTF", attributes), templateDefinition{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("parseTemplateEntry() = %+v, want %+v", got, want)
+	}
+
+	// Signature keys and flags the provider does not manage.
+	current := ldap.NewEntry("CN=WebServerTF", map[string][]string{
+		"msPKI-Enrollment-Flag":       {"34"},
+		"msPKI-Private-Key-Flag":      {"16842768"},
+		"msPKI-Certificate-Name-Flag": {"8"},
+	})
+	signing := templateDefinition{KeyUsages: []string{"digital_signature"}}
+	attributes = signing.attributes(current)
+	for name, value := range map[string]string{
+		"pKIDefaultKeySpec":           "2",
+		"msPKI-Enrollment-Flag":       "32",
+		"msPKI-Private-Key-Flag":      "16842752",
+		"msPKI-Certificate-Name-Flag": "8",
+	} {
+		if got := attributes[name][0]; got != value {
+			t.Errorf("%s = %s, want %s", name, got, value)
+		}
+	}
+	if _, ok := attributes["msPKI-Cert-Template-OID"]; ok {
+		t.Error("attributes() of an existing template changes its OID")
+	}
+}
+
+func TestCertificateTemplateModel(t *testing.T) {
+	ctx := context.Background()
+	model := certificateTemplateModel{
+		Name:              types.StringValue("WebServerTF"),
+		DisplayName:       types.StringUnknown(),
+		OID:               types.StringUnknown(),
+		ValidityPeriod:    types.StringUnknown(),
+		RenewalPeriod:     types.StringUnknown(),
+		KeyUsage:          types.SetUnknown(types.StringType),
+		SubjectNameFlags:  types.SetUnknown(types.StringType),
+		MinimumKeySize:    types.Int64Unknown(),
+		ExtendedKeyUsages: types.SetValueMust(types.StringType, []attr.Value{types.StringValue("server_auth"), types.StringValue("1.3.6.1.5.5.7.3.2")}),
+	}
+	definition, diags := model.definition(ctx)
+	if diags.HasError() {
+		t.Fatal(diags)
+	}
+	if definition.DisplayName != "WebServerTF" || definition.Validity != defaultTemplateValidity || definition.MinimumKeySize != defaultTemplateMinimumKeySize {
+		t.Errorf("definition() = %+v, want the defaults", definition)
+	}
+	if want := []string{"1.3.6.1.5.5.7.3.1", "1.3.6.1.5.5.7.3.2"}; !reflect.DeepEqual(definition.EKUs, want) {
+		t.Errorf("definition() EKUs = %v, want %v", definition.EKUs, want)
+	}
+
+	// Extended key usages keep how the configuration spells them.
+	definition.OID = "1.3.6.1.4.1.311.21.8.1.2.3.4"
+	if diags := model.setDefinition(ctx, definition); diags.HasError() {
+		t.Fatal(diags)
+	}
+	if want := types.SetValueMust(types.StringType, []attr.Value{types.StringValue("server_auth"), types.StringValue("1.3.6.1.5.5.7.3.2")}); !model.ExtendedKeyUsages.Equal(want) {
+		t.Errorf("extended_key_usages = %v, want %v", model.ExtendedKeyUsages, want)
+	}
+	if model.ID.ValueString() != "WebServerTF" || model.ValidityPeriod.ValueString() != "Years" || model.RenewalPeriodUnits.ValueInt64() != 6 {
+		t.Errorf("setDefinition() = %+v", model)
+	}
+}
+
+func TestValidateCertificateTemplate(t *testing.T) {
+	ctx := context.Background()
+	valid := certificateTemplateModel{
+		Name:              types.StringValue("WebServerTF"),
+		ExtendedKeyUsages: types.SetValueMust(types.StringType, []attr.Value{types.StringValue("server_auth")}),
+	}
+	if diags := validateCertificateTemplate(ctx, valid); diags.HasError() {
+		t.Errorf("validateCertificateTemplate() = %v", diags)
+	}
+
+	for name, modify := range map[string]func(*certificateTemplateModel){
+		"name":       func(m *certificateTemplateModel) { m.Name = types.StringValue("Web,Server") },
+		"oid":        func(m *certificateTemplateModel) { m.OID = types.StringValue("server") },
+		"incomplete": func(m *certificateTemplateModel) { m.ValidityPeriod = types.StringValue("Years") },
+		"unit": func(m *certificateTemplateModel) {
+			m.ValidityPeriod, m.ValidityPeriodUnits = types.StringValue("Minutes"), types.Int64Value(5)
+		},
+		"renewal too long": func(m *certificateTemplateModel) {
+			m.RenewalPeriod, m.RenewalPeriodUnits = types.StringValue("Months"), types.Int64Value(13)
+		},
+		"key usage": func(m *certificateTemplateModel) {
+			m.KeyUsage = types.SetValueMust(types.StringType, []attr.Value{types.StringValue("signing")})
+		},
+		"eku": func(m *certificateTemplateModel) {
+			m.ExtendedKeyUsages = types.SetValueMust(types.StringType, []attr.Value{types.StringValue("web")})
+		},
+	} {
+		config := valid
+		modify(&config)
+		if diags := validateCertificateTemplate(ctx, config); !diags.HasError() {
+			t.Errorf("%s: validateCertificateTemplate() succeeded, want an error", name)
+		}
+	}
+}
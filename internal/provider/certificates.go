@@ -0,0 +1,200 @@
+package provider
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/flipyap/microsoft-adcs-client/client"
+	"github.com/hashicorp/terraform-plugin-framework/diag"
+)
+
+// parseCertificate decodes a certificate as returned by certsrv. With Enc=b64 certsrv hands back
+// a PEM block with CRLF line endings, but bare base64 encoded DER is accepted as well.
+func parseCertificate(b64 string) (*x509.Certificate, error) {
+	if block, _ := pem.Decode([]byte(b64)); block != nil {
+		if block.Type != "CERTIFICATE" {
+			return nil, fmt.Errorf("expected a CERTIFICATE PEM block, got %q", block.Type)
+		}
+		return x509.ParseCertificate(block.Bytes)
+	}
+
+	der, err := base64.StdEncoding.DecodeString(strings.Join(strings.Fields(b64), ""))
+	if err != nil {
+		return nil, fmt.Errorf("value is neither PEM nor base64 encoded DER: %v", err)
+	}
+	return x509.ParseCertificate(der)
+}
+
+// checkMaxValidity fails when the CA issued a certificate with a longer lifetime than maxHours.
+// The certificate already exists on the CA at this point, so the request ID is included to make
+// it easy to find and revoke.
+func checkMaxValidity(certificates *client.Certificates, maxHours int64) diag.Diagnostics {
+	var diags diag.Diagnostics
+
+	cert, err := parseCertificate(certificates.CertificateB64)
+	if err != nil {
+		diags.AddError(
+			classifiedSummary("Error parsing issued certificate", &ParseError{Err: err}),
+			fmt.Sprintf("Could not parse the certificate issued for request ID %s: %s", certificates.ID, err.Error()),
+		)
+		return diags
+	}
+
+	validity := cert.NotAfter.Sub(cert.NotBefore)
+	if validity > time.Duration(maxHours)*time.Hour {
+		diags.AddError(
+			"Issued Certificate Exceeds Maximum Validity",
+			fmt.Sprintf("ADCS issued request ID %s with a lifetime of %.0f hours, which is more than the %d hours allowed by max_accepted_validity_hours. "+
+				"Check the validity period configured on the template. The certificate has been issued by the CA and may need to be revoked.",
+				certificates.ID, validity.Hours(), maxHours),
+		)
+	}
+
+	return diags
+}
+
+// parseCertificateChain decodes the PKCS#7 chain returned by certsrv. Even though the content is
+// PKCS#7, certsrv labels the PEM block as CERTIFICATE so the block type is not checked.
+func parseCertificateChain(b64 string) ([]*x509.Certificate, error) {
+	der, err := decodePEMOrBase64(b64)
+	if err != nil {
+		return nil, err
+	}
+	return parsePKCS7Certificates(der)
+}
+
+// decodePEMOrBase64 returns the DER bytes of the first PEM block, or of the whole value when it is
+// bare base64.
+func decodePEMOrBase64(value string) ([]byte, error) {
+	if block, _ := pem.Decode([]byte(value)); block != nil {
+		return block.Bytes, nil
+	}
+
+	der, err := base64.StdEncoding.DecodeString(strings.Join(strings.Fields(value), ""))
+	if err != nil {
+		return nil, fmt.Errorf("value is neither PEM nor base64 encoded DER: %v", err)
+	}
+	return der, nil
+}
+
+// pemEscapes turns the escape sequences certsrv pages and hand-edited state leave in PEM values
+// into line breaks. Literal "\r\n" and "\n" become newlines, a literal "\r" is dropped.
+var pemEscapes = strings.NewReplacer(`\r\n`, "\n", `\n`, "\n", `\r`, "", "\r\n", "\n", "\r", "\n")
+
+// normalizePEM returns certificate material in one canonical form, so a refresh stores the same
+// value whatever line endings certsrv used: LF line endings, each PEM block re-encoded with its
+// base64 wrapped at 64 columns, and text around the blocks dropped. Bare base64 is joined into a
+// single line. A value that does not decode is only cleaned of its escapes and CRLF line endings.
+func normalizePEM(value string) string {
+	value = pemEscapes.Replace(value)
+
+	var b strings.Builder
+	rest := []byte(value)
+	for {
+		block, next := pem.Decode(rest)
+		if block == nil {
+			break
+		}
+		_ = pem.Encode(&b, block)
+		rest = next
+	}
+	if b.Len() > 0 {
+		return b.String()
+	}
+
+	joined := strings.Join(strings.Fields(value), "")
+	if der, err := base64.StdEncoding.DecodeString(joined); err == nil && len(der) > 0 {
+		return base64.StdEncoding.EncodeToString(der)
+	}
+	return value
+}
+
+// buildChains returns every path from the leaf up through its issuers found in certs, without the
+// leaf itself. A CA with cross-signed intermediates returns several valid paths in one PKCS#7, each
+// ending in a different root. A path ends at a self-signed certificate or when no further issuer is
+// found. The paths are in the order of sortChains, whatever the order of certs.
+func buildChains(leaf *x509.Certificate, certs []*x509.Certificate) [][]*x509.Certificate {
+	var chains [][]*x509.Certificate
+
+	var walk func(current *x509.Certificate, path []*x509.Certificate)
+	walk = func(current *x509.Certificate, path []*x509.Certificate) {
+		found := false
+		if !isSelfSigned(current) {
+			for _, candidate := range certs {
+				if candidate.Equal(current) || candidate.Equal(leaf) || containsCertificate(path, candidate) {
+					continue
+				}
+				if bytes.Equal(current.RawIssuer, candidate.RawSubject) && current.CheckSignatureFrom(candidate) == nil {
+					found = true
+					walk(candidate, append(path[:len(path):len(path)], candidate))
+				}
+			}
+		}
+		if !found {
+			chains = append(chains, path)
+		}
+	}
+	walk(leaf, nil)
+
+	sortChains(chains)
+	return chains
+}
+
+// sortChains orders chains shortest first, and chains of the same length by the SHA-256 fingerprints
+// of their certificates. certsrv does not promise an order for the certificates of a PKCS#7, so
+// without it the first chain, and with it the chain outputs, could change between reads.
+func sortChains(chains [][]*x509.Certificate) {
+	sort.SliceStable(chains, func(i, j int) bool {
+		if len(chains[i]) != len(chains[j]) {
+			return len(chains[i]) < len(chains[j])
+		}
+		for k := range chains[i] {
+			a, b := sha256.Sum256(chains[i][k].Raw), sha256.Sum256(chains[j][k].Raw)
+			if c := bytes.Compare(a[:], b[:]); c != 0 {
+				return c < 0
+			}
+		}
+		return false
+	})
+}
+
+// containsCertificate reports whether cert is part of certs.
+func containsCertificate(certs []*x509.Certificate, cert *x509.Certificate) bool {
+	for _, c := range certs {
+		if c.Equal(cert) {
+			return true
+		}
+	}
+	return false
+}
+
+// isSelfSigned reports whether the certificate is a root.
+func isSelfSigned(cert *x509.Certificate) bool {
+	return bytes.Equal(cert.RawIssuer, cert.RawSubject) && cert.CheckSignatureFrom(cert) == nil
+}
+
+// encodePEM returns the certificates as concatenated PEM blocks.
+func encodePEM(certs ...*x509.Certificate) string {
+	var b strings.Builder
+	for _, cert := range certs {
+		_ = pem.Encode(&b, &pem.Block{Type: "CERTIFICATE", Bytes: cert.Raw})
+	}
+	return b.String()
+}
+
+// isIssuerOfAny reports whether cert issued any of the other certificates.
+func isIssuerOfAny(cert *x509.Certificate, certs []*x509.Certificate) bool {
+	for _, other := range certs {
+		if !other.Equal(cert) && bytes.Equal(other.RawIssuer, cert.RawSubject) && other.CheckSignatureFrom(cert) == nil {
+			return true
+		}
+	}
+	return false
+}
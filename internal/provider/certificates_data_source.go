@@ -0,0 +1,332 @@
+package provider
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/flipyap/microsoft-adcs-client/client"
+	"github.com/hashicorp/terraform-plugin-framework/attr"
+	"github.com/hashicorp/terraform-plugin-framework/datasource"
+	"github.com/hashicorp/terraform-plugin-framework/datasource/schema"
+	"github.com/hashicorp/terraform-plugin-framework/diag"
+	"github.com/hashicorp/terraform-plugin-framework/path"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+	"github.com/hashicorp/terraform-plugin-log/tflog"
+)
+
+// maxDiscoveryRange limits how many request IDs a single read probes, every ID costs a round trip.
+const maxDiscoveryRange = 1000
+
+// defaultImportAddress is the resource the generated import blocks point to.
+const defaultImportAddress = "microsoftadcs_certificate.discovered"
+
+// Ensure the implementation satisfies the expected interfaces.
+var (
+	_ datasource.DataSource                   = &certificatesDataSource{}
+	_ datasource.DataSourceWithConfigure      = &certificatesDataSource{}
+	_ datasource.DataSourceWithValidateConfig = &certificatesDataSource{}
+)
+
+// NewCertificatesDataSource is a helper function to simplify the provider implementation.
+func NewCertificatesDataSource() datasource.DataSource {
+	return &certificatesDataSource{}
+}
+
+// certificatesDataSource discovers issued certificates in a range of request IDs.
+type certificatesDataSource struct {
+	client   *client.ADCSClient
+	parser   *certsrvParser
+	provider *providerData
+}
+
+// certificatesModel maps the discovery settings and results.
+type certificatesModel struct {
+	ID              types.String `tfsdk:"id"`
+	RequestIDFrom   types.Int64  `tfsdk:"request_id_from"`
+	RequestIDTo     types.Int64  `tfsdk:"request_id_to"`
+	SubjectPattern  types.String `tfsdk:"subject_pattern"`
+	IncludeExpired  types.Bool   `tfsdk:"include_expired"`
+	ImportAddress   types.String `tfsdk:"import_address"`
+	Certificates    types.List   `tfsdk:"certificates"`
+	ImportBlocks    types.List   `tfsdk:"import_blocks"`
+	ImportBlocksHCL types.String `tfsdk:"import_blocks_hcl"`
+}
+
+// discoveredCertificate is a single entry of the certificates attribute.
+type discoveredCertificate struct {
+	RequestID    string `tfsdk:"request_id"`
+	Subject      string `tfsdk:"subject"`
+	SerialNumber string `tfsdk:"serial_number"`
+	NotAfter     string `tfsdk:"not_after"`
+}
+
+// importBlock is a single entry of the import_blocks attribute, shaped like a Terraform import block.
+type importBlock struct {
+	To string `tfsdk:"to"`
+	ID string `tfsdk:"id"`
+}
+
+var discoveredCertificateAttrTypes = map[string]attr.Type{
+	"request_id":    types.StringType,
+	"subject":       types.StringType,
+	"serial_number": types.StringType,
+	"not_after":     types.StringType,
+}
+
+var importBlockAttrTypes = map[string]attr.Type{
+	"to": types.StringType,
+	"id": types.StringType,
+}
+
+// Configure adds the provider configured client to the data source.
+func (d *certificatesDataSource) Configure(_ context.Context, req datasource.ConfigureRequest, resp *datasource.ConfigureResponse) {
+	if req.ProviderData == nil {
+		return
+	}
+
+	data, ok := req.ProviderData.(*providerData)
+	if !ok {
+		resp.Diagnostics.AddError(
+			"Unexpected Data Source Configure Type",
+			fmt.Sprintf("Expected *providerData, got: %T. Please report this issue to the provider developers.", req.ProviderData),
+		)
+
+		return
+	}
+
+	d.client = data.client
+	d.provider = data
+	d.parser = data.parser
+}
+
+// Metadata returns the data source type name.
+func (d *certificatesDataSource) Metadata(_ context.Context, req datasource.MetadataRequest, resp *datasource.MetadataResponse) {
+	resp.TypeName = req.ProviderTypeName + "_certificates"
+}
+
+// Schema defines the schema for the data source.
+func (d *certificatesDataSource) Schema(_ context.Context, _ datasource.SchemaRequest, resp *datasource.SchemaResponse) {
+	resp.Schema = schema.Schema{
+		Description: `Discovers issued certificates by probing a range of request IDs and renders import blocks for them.
+The certsrv web enrollment pages offer no way to search the CA database, so every request ID in the range is
+retrieved; requests that are pending, denied or unknown are skipped. The read fails when the CA cannot be reached or
+refuses the credentials, so an outage does not render empty import blocks.`,
+		Attributes: map[string]schema.Attribute{
+			"id": schema.StringAttribute{
+				Computed:    true,
+				Description: "The probed request ID range.",
+			},
+			"request_id_from": schema.Int64Attribute{
+				Required:    true,
+				Description: "First request ID to probe.",
+			},
+			"request_id_to": schema.Int64Attribute{
+				Required:    true,
+				Description: fmt.Sprintf("Last request ID to probe. At most %d request IDs are probed per read.", maxDiscoveryRange),
+			},
+			"subject_pattern": schema.StringAttribute{
+				Optional:    true,
+				Description: "Regular expression the subject distinguished name, for example CN=app.example.com, has to match.",
+			},
+			"include_expired": schema.BoolAttribute{
+				Optional:    true,
+				Description: "Include certificates that have already expired, defaults to false.",
+			},
+			"import_address": schema.StringAttribute{
+				Optional: true,
+				Description: `Resource address the import blocks point to, keyed by request ID. Defaults to ` + defaultImportAddress + `,
+which expects a for_each over the discovered request IDs.`,
+			},
+			"certificates": schema.ListNestedAttribute{
+				Computed:    true,
+				Description: "The discovered certificates.",
+				NestedObject: schema.NestedAttributeObject{
+					Attributes: map[string]schema.Attribute{
+						"request_id": schema.StringAttribute{
+							Computed:    true,
+							Description: "Request ID of the certificate.",
+						},
+						"subject": schema.StringAttribute{
+							Computed:    true,
+							Description: "Subject distinguished name of the certificate.",
+						},
+						"serial_number": schema.StringAttribute{
+							Computed:    true,
+							Description: "Hex encoded serial number of the certificate.",
+						},
+						"not_after": schema.StringAttribute{
+							Computed:    true,
+							Description: "Expiry of the certificate in RFC 3339 format.",
+						},
+					},
+				},
+			},
+			"import_blocks": schema.ListNestedAttribute{
+				Computed:    true,
+				Description: "One { to, id } object per discovered certificate, the arguments of a Terraform import block.",
+				NestedObject: schema.NestedAttributeObject{
+					Attributes: map[string]schema.Attribute{
+						"to": schema.StringAttribute{
+							Computed:    true,
+							Description: "Resource address to import into.",
+						},
+						"id": schema.StringAttribute{
+							Computed:    true,
+							Description: "Import ID, the request ID of the certificate.",
+						},
+					},
+				},
+			},
+			"import_blocks_hcl": schema.StringAttribute{
+				Computed:    true,
+				Description: "The import blocks rendered as HCL, ready to be written to a .tf file.",
+			},
+		},
+	}
+}
+
+// ValidateConfig checks the request ID range and the subject pattern.
+func (d *certificatesDataSource) ValidateConfig(ctx context.Context, req datasource.ValidateConfigRequest, resp *datasource.ValidateConfigResponse) {
+	var config certificatesModel
+	resp.Diagnostics.Append(req.Config.Get(ctx, &config)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	resp.Diagnostics.Append(validateRequestIDRange(config.RequestIDFrom, config.RequestIDTo)...)
+
+	if !config.SubjectPattern.IsNull() && !config.SubjectPattern.IsUnknown() {
+		if _, err := regexp.Compile(config.SubjectPattern.ValueString()); err != nil {
+			resp.Diagnostics.AddAttributeError(path.Root("subject_pattern"), "Invalid Subject Pattern", err.Error())
+		}
+	}
+}
+
+// validateRequestIDRange checks the request_id_from and request_id_to of the data sources that probe
+// a range of request IDs.
+func validateRequestIDRange(fromValue, toValue types.Int64) diag.Diagnostics {
+	var diags diag.Diagnostics
+	if fromValue.IsUnknown() || toValue.IsUnknown() {
+		return diags
+	}
+	from, to := fromValue.ValueInt64(), toValue.ValueInt64()
+	switch {
+	case from < 1:
+		diags.AddAttributeError(path.Root("request_id_from"), "Invalid Request ID Range", "request_id_from must be at least 1.")
+	case to < from:
+		diags.AddAttributeError(path.Root("request_id_to"), "Invalid Request ID Range", "request_id_to must not be lower than request_id_from.")
+	case to-from+1 > maxDiscoveryRange:
+		diags.AddAttributeError(
+			path.Root("request_id_to"),
+			"Request ID Range Too Large",
+			fmt.Sprintf("At most %d request IDs can be probed at once, got %d. Split the range over several data sources.", maxDiscoveryRange, to-from+1),
+		)
+	}
+	return diags
+}
+
+// probeRequestIDs retrieves the certificates issued for the request IDs from to to and calls visit
+// with each of them. Request IDs the CA answers for without a certificate are skipped, any other
+// failure ends the probe so an outage does not read as a range without certificates.
+func probeRequestIDs(ctx context.Context, c *client.ADCSClient, parser *certsrvParser, from, to int64, visit func(reqID string, certificates *client.Certificates)) error {
+	for id := from; id <= to; id++ {
+		reqID := strconv.FormatInt(id, 10)
+		certificates, err := retrieveCertificate(ctx, c, parser, reqID)
+		if err != nil {
+			if !hasNoCertificate(err) {
+				return fmt.Errorf("could not retrieve request ID %s: %w", reqID, err)
+			}
+			tflog.Debug(ctx, "Skipping request ID without an issued certificate", map[string]interface{}{
+				"request_id": reqID,
+				"error":      err.Error(),
+			})
+			continue
+		}
+		visit(reqID, certificates)
+	}
+	return nil
+}
+
+// Read refreshes the Terraform state with the latest data.
+func (d *certificatesDataSource) Read(ctx context.Context, req datasource.ReadRequest, resp *datasource.ReadResponse) {
+	if d.provider.caOffline(ctx) {
+		resp.Diagnostics.Append(applyOnlyError("search the CA database"))
+		return
+	}
+	var data certificatesModel
+	resp.Diagnostics.Append(req.Config.Get(ctx, &data)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	var subjectPattern *regexp.Regexp
+	if !data.SubjectPattern.IsNull() {
+		subjectPattern = regexp.MustCompile(data.SubjectPattern.ValueString())
+	}
+	address := defaultImportAddress
+	if !data.ImportAddress.IsNull() {
+		address = data.ImportAddress.ValueString()
+	}
+
+	from, to := data.RequestIDFrom.ValueInt64(), data.RequestIDTo.ValueInt64()
+	now := time.Now()
+	var discovered []discoveredCertificate
+	var blocks []importBlock
+	err := probeRequestIDs(ctx, d.client, d.parser, from, to, func(reqID string, certificates *client.Certificates) {
+		cert, err := parseCertificate(certificates.CertificateB64)
+		if err != nil {
+			resp.Diagnostics.AddWarning(
+				"Unable to Parse Discovered Certificate",
+				fmt.Sprintf("The certificate of request ID %s was skipped: %s", reqID, err.Error()),
+			)
+			return
+		}
+		if subjectPattern != nil && !subjectPattern.MatchString(cert.Subject.String()) {
+			return
+		}
+		if !data.IncludeExpired.ValueBool() && now.After(cert.NotAfter) {
+			return
+		}
+
+		discovered = append(discovered, discoveredCertificate{
+			RequestID:    reqID,
+			Subject:      cert.Subject.String(),
+			SerialNumber: fmt.Sprintf("%x", cert.SerialNumber),
+			NotAfter:     cert.NotAfter.UTC().Format(time.RFC3339),
+		})
+		blocks = append(blocks, importBlock{To: fmt.Sprintf("%s[%q]", address, reqID), ID: reqID})
+	})
+	if err != nil {
+		resp.Diagnostics.AddError(
+			classifiedSummary("Unable to Search Certificates", err),
+			fmt.Sprintf("Could not probe request IDs %d to %d for certificates: %s", from, to, err.Error()),
+		)
+		return
+	}
+
+	var hcl strings.Builder
+	for i, block := range blocks {
+		if i > 0 {
+			hcl.WriteString("\n")
+		}
+		fmt.Fprintf(&hcl, "import {\n  to = %s\n  id = %q\n}\n", block.To, block.ID)
+	}
+
+	data.ID = types.StringValue(fmt.Sprintf("%d-%d", from, to))
+	data.ImportBlocksHCL = types.StringValue(hcl.String())
+	certificatesValue, diags := types.ListValueFrom(ctx, types.ObjectType{AttrTypes: discoveredCertificateAttrTypes}, discovered)
+	resp.Diagnostics.Append(diags...)
+	importBlocksValue, diags := types.ListValueFrom(ctx, types.ObjectType{AttrTypes: importBlockAttrTypes}, blocks)
+	resp.Diagnostics.Append(diags...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+	data.Certificates = certificatesValue
+	data.ImportBlocks = importBlocksValue
+
+	resp.Diagnostics.Append(resp.State.Set(ctx, &data)...)
+}
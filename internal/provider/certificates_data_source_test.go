@@ -0,0 +1,54 @@
+package provider
+
+import (
+	"context"
+	"io"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/flipyap/microsoft-adcs-client/client"
+	"github.com/hashicorp/terraform-plugin-framework/diag"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+)
+
+func TestProbeRequestIDs(t *testing.T) {
+	srv := newAccCertsrv(t)
+	t.Setenv("ADCS_PASSWORD", "secret")
+	data, err := debugEnrollConfigure(context.Background(), "test", map[string]interface{}{
+		"host":     srv.host(),
+		"username": "svc-terraform",
+		"use_ntlm": true,
+	}, io.Discard)
+	if err != nil {
+		t.Fatal(err)
+	}
+	r := &certificateResource{client: data.client, provider: data}
+	csr, err := debugEnrollRequest(context.Background(), "", "app.example.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var diags diag.Diagnostics
+	issued := r.requestCertificate(context.Background(), &certificateCreateModel{CSR: types.StringValue(csr), Template: types.StringValue("WebServer")}, csr, "", &diags)
+	if diags.HasError() {
+		t.Fatalf("unexpected diagnostics: %v", diags)
+	}
+
+	// Request IDs the CA does not know are skipped.
+	var visited []string
+	err = probeRequestIDs(context.Background(), data.client, data.parser, 99, 102, func(reqID string, _ *client.Certificates) {
+		visited = append(visited, reqID)
+	})
+	if err != nil || !reflect.DeepEqual(visited, []string{issued.ID}) {
+		t.Errorf("probeRequestIDs() visited %v, %v, want [%s]", visited, err, issued.ID)
+	}
+
+	// A CA that cannot be reached is not a range without certificates.
+	srv.server.Close()
+	err = probeRequestIDs(withRetryPolicy(context.Background(), noRetry), data.client, data.parser, 99, 102, func(reqID string, _ *client.Certificates) {
+		t.Errorf("request ID %s was visited", reqID)
+	})
+	if errorClass(classifyError(err)) != errorClassTransient || !strings.Contains(err.Error(), "could not retrieve request ID 99") {
+		t.Errorf("probeRequestIDs() = %v, want a transient error", err)
+	}
+}
@@ -0,0 +1,411 @@
+package provider
+
+import (
+	"bytes"
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha1"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"encoding/base64"
+	"encoding/pem"
+	"fmt"
+	"math/big"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/flipyap/microsoft-adcs-client/client"
+	"github.com/hashicorp/terraform-plugin-framework/attr"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+)
+
+// testHierarchy is a root -> issuing CA -> leaf hierarchy for unit tests.
+type testHierarchy struct {
+	root, issuing, leaf          *x509.Certificate
+	rootKey, issuingKey, leafKey *ecdsa.PrivateKey
+}
+
+func newTestHierarchy(t *testing.T) *testHierarchy {
+	t.Helper()
+	h := &testHierarchy{}
+	h.root, h.rootKey = testCertificate(t, "Test Root CA", true, nil, nil)
+	h.issuing, h.issuingKey = testCertificate(t, "Test Issuing CA", true, h.root, h.rootKey)
+	h.leaf, h.leafKey = testCertificate(t, "app.example.com", false, h.issuing, h.issuingKey)
+	return h
+}
+
+func testCertificate(t *testing.T, cn string, isCA bool, parent *x509.Certificate, parentKey *ecdsa.PrivateKey) (*x509.Certificate, *ecdsa.PrivateKey) {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("could not generate key: %v", err)
+	}
+	serial, _ := rand.Int(rand.Reader, big.NewInt(1<<62))
+	template := &x509.Certificate{
+		SerialNumber:          serial,
+		Subject:               pkix.Name{CommonName: cn},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(90 * 24 * time.Hour),
+		IsCA:                  isCA,
+		BasicConstraintsValid: true,
+	}
+	if !isCA {
+		template.DNSNames = []string{cn}
+	}
+	if isCA {
+		template.KeyUsage = x509.KeyUsageCertSign | x509.KeyUsageCRLSign
+	}
+	if parent == nil {
+		parent, parentKey = template, key
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, template, parent, &key.PublicKey, parentKey)
+	if err != nil {
+		t.Fatalf("could not create certificate: %v", err)
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatalf("could not parse certificate: %v", err)
+	}
+	return cert, key
+}
+
+// testPKCS7 wraps the certificates in a degenerate PKCS#7 SignedData structure like certnew.p7b.
+func testPKCS7(t *testing.T, certs ...*x509.Certificate) []byte {
+	t.Helper()
+
+	der, err := marshalTestPKCS7(certs...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return der
+}
+
+// marshalTestPKCS7 is testPKCS7 for HTTP handlers, which cannot fail the test themselves.
+func marshalTestPKCS7(certs ...*x509.Certificate) ([]byte, error) {
+	var raw []byte
+	for _, cert := range certs {
+		raw = append(raw, cert.Raw...)
+	}
+	signedData, err := asn1.Marshal(struct {
+		Version          int
+		DigestAlgorithms asn1.RawValue
+		ContentInfo      struct{ ContentType asn1.ObjectIdentifier }
+		Certificates     asn1.RawValue
+		SignerInfos      asn1.RawValue
+	}{
+		Version:          1,
+		DigestAlgorithms: asn1.RawValue{Class: asn1.ClassUniversal, Tag: asn1.TagSet, IsCompound: true},
+		ContentInfo:      struct{ ContentType asn1.ObjectIdentifier }{asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 7, 1}},
+		Certificates:     asn1.RawValue{Class: asn1.ClassContextSpecific, Tag: 0, IsCompound: true, Bytes: raw},
+		SignerInfos:      asn1.RawValue{Class: asn1.ClassUniversal, Tag: asn1.TagSet, IsCompound: true},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("could not marshal signed data: %v", err)
+	}
+	der, err := asn1.Marshal(struct {
+		ContentType asn1.ObjectIdentifier
+		Content     asn1.RawValue
+	}{oidSignedData, asn1.RawValue{Class: asn1.ClassContextSpecific, Tag: 0, IsCompound: true, Bytes: signedData}})
+	if err != nil {
+		return nil, fmt.Errorf("could not marshal content info: %v", err)
+	}
+	return der, nil
+}
+
+// testCertificates returns the hierarchy the way the client hands it over from certsrv.
+func (h *testHierarchy) testCertificates(t *testing.T) *client.Certificates {
+	t.Helper()
+	return &client.Certificates{
+		ID:             "42",
+		CertificateB64: string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: h.leaf.Raw})),
+		// certsrv labels the PKCS#7 chain as a CERTIFICATE block.
+		CertificateChainB64: string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: testPKCS7(t, h.root, h.leaf, h.issuing)})),
+	}
+}
+
+func TestParseCertificateMaterial(t *testing.T) {
+	h := newTestHierarchy(t)
+
+	material, err := parseCertificateMaterial(h.testCertificates(t))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if !material.leaf.Equal(h.leaf) {
+		t.Errorf("leaf = %s, want %s", material.leaf.Subject, h.leaf.Subject)
+	}
+	if len(material.chain) != 2 || !material.chain[0].Equal(h.issuing) || !material.chain[1].Equal(h.root) {
+		t.Fatalf("chain is not ordered issuing CA first: %v", material.chain)
+	}
+	if root := material.root(); root == nil || !root.Equal(h.root) {
+		t.Errorf("root = %v, want %s", root, h.root.Subject)
+	}
+	if intermediates := material.intermediates(); len(intermediates) != 1 || !intermediates[0].Equal(h.issuing) {
+		t.Errorf("intermediates = %v, want [%s]", intermediates, h.issuing.Subject)
+	}
+}
+
+// crossSignedRoot cross-signs the root of h under an older root, the way a CA migrating to a new
+// root publishes it, and returns the cross-signed root and the old root.
+func (h *testHierarchy) crossSignedRoot(t *testing.T) (*x509.Certificate, *x509.Certificate) {
+	t.Helper()
+	oldRoot, oldRootKey := testCertificate(t, "Old Root CA", true, nil, nil)
+	crossTemplate := *h.root
+	crossDER, err := x509.CreateCertificate(rand.Reader, &crossTemplate, oldRoot, &h.rootKey.PublicKey, oldRootKey)
+	if err != nil {
+		t.Fatalf("could not cross-sign root: %v", err)
+	}
+	cross, err := x509.ParseCertificate(crossDER)
+	if err != nil {
+		t.Fatalf("could not parse cross-signed root: %v", err)
+	}
+	return cross, oldRoot
+}
+
+func TestBuildChainsCrossSigned(t *testing.T) {
+	h := newTestHierarchy(t)
+	cross, oldRoot := h.crossSignedRoot(t)
+
+	chains := buildChains(h.leaf, []*x509.Certificate{h.issuing, h.root, cross, oldRoot})
+	if len(chains) != 2 {
+		t.Fatalf("got %d chains, want 2", len(chains))
+	}
+	if len(chains[0]) != 2 || !chains[0][1].Equal(h.root) {
+		t.Errorf("first chain should end in the self-signed root: %v", chains[0])
+	}
+	if len(chains[1]) != 3 || !chains[1][1].Equal(cross) || !chains[1][2].Equal(oldRoot) {
+		t.Errorf("second chain should go through the cross-signed root: %v", chains[1])
+	}
+
+	material := &certificateMaterial{leaf: h.leaf, chain: chains[0], chains: chains}
+	if !material.selectChain("Old Root CA") || !material.root().Equal(oldRoot) {
+		t.Errorf("selectChain did not switch to the chain ending in Old Root CA")
+	}
+	if material.selectChain("Unknown Root") {
+		t.Errorf("selectChain reported a match for an unknown root")
+	}
+}
+
+func TestChainOutputsIgnoreBundleOrder(t *testing.T) {
+	h := newTestHierarchy(t)
+	cross, oldRoot := h.crossSignedRoot(t)
+
+	outputs := func(certs ...*x509.Certificate) certificateCreateModel {
+		t.Helper()
+		model := certificateCreateModel{}
+		diags := model.setCertificateOutputs(context.Background(), &client.Certificates{
+			ID:                  "42",
+			CertificateB64:      encodePEM(h.leaf),
+			CertificateChainB64: string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: testPKCS7(t, certs...)})),
+		})
+		if diags.HasError() {
+			t.Fatalf("unexpected diagnostics: %v", diags)
+		}
+		return model
+	}
+
+	want := outputs(h.leaf, h.issuing, h.root, cross, oldRoot)
+	for _, order := range [][]*x509.Certificate{
+		{oldRoot, cross, h.root, h.issuing, h.leaf},
+		{h.root, h.leaf, oldRoot, h.issuing, cross},
+		{cross, h.issuing, h.leaf, oldRoot, h.root},
+	} {
+		got := outputs(order...)
+		for name, pair := range map[string][2]attr.Value{
+			"certificate_chain":     {got.CertificateChain, want.CertificateChain},
+			"certificate_chains":    {got.CertificateChains, want.CertificateChains},
+			"certificate_chain_der": {got.CertificateChainDER, want.CertificateChainDER},
+			"certificate_chain_pem": {got.CertificateChainPEM, want.CertificateChainPEM},
+			"root_ca_pem":           {got.RootCAPEM, want.RootCAPEM},
+			"kubernetes_tls_secret": {got.KubernetesTLSSecret, want.KubernetesTLSSecret},
+		} {
+			if !pair[0].Equal(pair[1]) {
+				t.Errorf("%s changes with the order of the PKCS#7: %s, want %s", name, pair[0], pair[1])
+			}
+		}
+	}
+	if root := want.RootCAPEM.ValueString(); root != encodePEM(h.root) {
+		t.Errorf("root_ca_pem = %q, want the root of the shortest chain", root)
+	}
+}
+
+func TestKubernetesTLSSecret(t *testing.T) {
+	h := newTestHierarchy(t)
+
+	material, err := parseCertificateMaterial(h.testCertificates(t))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	secret := material.kubernetesTLSSecret().Elements()
+	decoded := func(key string) string {
+		value, _ := secret[key].(types.String)
+		b, _ := base64.StdEncoding.DecodeString(value.ValueString())
+		return string(b)
+	}
+
+	caCrt := decoded("ca.crt")
+	if caCrt != encodePEM(h.root) {
+		t.Errorf("ca.crt does not hold the root certificate")
+	}
+	tlsCrt := decoded("tls.crt")
+	if tlsCrt != encodePEM(h.leaf, h.issuing) {
+		t.Errorf("tls.crt does not hold the leaf followed by the issuing CA")
+	}
+}
+
+func TestCombinedPEM(t *testing.T) {
+	h := newTestHierarchy(t)
+
+	der, err := x509.MarshalPKCS8PrivateKey(h.leafKey)
+	if err != nil {
+		t.Fatalf("could not marshal key: %v", err)
+	}
+	keyPEM := string(pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der}))
+
+	model := certificateCreateModel{PrivateKeyPEM: types.StringValue(keyPEM)}
+	if diags := model.setCertificateOutputs(context.Background(), h.testCertificates(t)); diags.HasError() {
+		t.Fatalf("unexpected diagnostics: %v", diags)
+	}
+	if want := encodePEM(h.leaf, h.issuing) + keyPEM; model.CombinedPEM.ValueString() != want {
+		t.Errorf("combined_pem = %q, want %q", model.CombinedPEM.ValueString(), want)
+	}
+
+	other, _ := x509.MarshalPKCS8PrivateKey(h.rootKey)
+	model = certificateCreateModel{PrivateKeyPEM: types.StringValue(string(pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: other})))}
+	if diags := model.setCertificateOutputs(context.Background(), h.testCertificates(t)); !diags.HasError() {
+		t.Errorf("expected an error for a private key that does not match the certificate")
+	}
+}
+
+func TestCertificatePEMOutputs(t *testing.T) {
+	h := newTestHierarchy(t)
+
+	var model certificateCreateModel
+	if diags := model.setCertificateOutputs(context.Background(), h.testCertificates(t)); diags.HasError() {
+		t.Fatalf("unexpected diagnostics: %v", diags)
+	}
+	if want := encodePEM(h.leaf); model.CertificatePEM.ValueString() != want {
+		t.Errorf("certificate_pem = %q, want %q", model.CertificatePEM.ValueString(), want)
+	}
+	if want := encodePEM(h.issuing, h.root); model.CertificateChainPEM.ValueString() != want {
+		t.Errorf("certificate_chain_pem = %q, want %q", model.CertificateChainPEM.ValueString(), want)
+	}
+	if want := []attr.Value{types.StringValue(encodePEM(h.issuing)), types.StringValue(encodePEM(h.root))}; !model.CertificateChain.Equal(types.ListValueMust(types.StringType, want)) {
+		t.Errorf("certificate_chain = %v, want the issuing CA followed by the root", model.CertificateChain)
+	}
+	if want := base64.StdEncoding.EncodeToString(h.leaf.Raw); model.CertificateDER.ValueString() != want {
+		t.Errorf("certificate_der = %q, want %q", model.CertificateDER.ValueString(), want)
+	}
+	wantDER := []attr.Value{types.StringValue(base64.StdEncoding.EncodeToString(h.issuing.Raw)), types.StringValue(base64.StdEncoding.EncodeToString(h.root.Raw))}
+	if !model.CertificateChainDER.Equal(types.ListValueMust(types.StringType, wantDER)) {
+		t.Errorf("certificate_chain_der = %v, want the issuing CA followed by the root", model.CertificateChainDER)
+	}
+	certificates := h.testCertificates(t)
+	if p7b, err := base64.StdEncoding.DecodeString(model.CertificateChainP7B.ValueString()); err != nil {
+		t.Errorf("certificate_chain_p7b is not base64: %v", err)
+	} else if block, _ := pem.Decode([]byte(certificates.CertificateChainB64)); !bytes.Equal(p7b, block.Bytes) {
+		t.Errorf("certificate_chain_p7b is not the PKCS#7 returned by ADCS")
+	}
+	if want := encodePEM(h.issuing); model.IssuingCAPEM.ValueString() != want {
+		t.Errorf("issuing_ca_pem = %q, want %q", model.IssuingCAPEM.ValueString(), want)
+	}
+	if want := encodePEM(h.root); model.RootCAPEM.ValueString() != want {
+		t.Errorf("root_ca_pem = %q, want %q", model.RootCAPEM.ValueString(), want)
+	}
+
+	leafOnly := h.testCertificates(t)
+	leafOnly.CertificateChainB64 = ""
+	if diags := model.setCertificateOutputs(context.Background(), leafOnly); diags.HasError() {
+		t.Fatalf("unexpected diagnostics: %v", diags)
+	}
+	if !model.CertificateChainPEM.IsNull() {
+		t.Errorf("certificate_chain_pem = %q, want null without a stored chain", model.CertificateChainPEM.ValueString())
+	}
+	if len(model.CertificateChain.Elements()) != 0 {
+		t.Errorf("certificate_chain has %d elements, want none without a stored chain", len(model.CertificateChain.Elements()))
+	}
+	if len(model.CertificateChainDER.Elements()) != 0 {
+		t.Errorf("certificate_chain_der has %d elements, want none without a stored chain", len(model.CertificateChainDER.Elements()))
+	}
+	if !model.IssuingCAPEM.IsNull() || !model.RootCAPEM.IsNull() {
+		t.Errorf("issuing_ca_pem and root_ca_pem should be null without a stored chain")
+	}
+	if !model.CertificateChainP7B.IsNull() {
+		t.Errorf("certificate_chain_p7b = %q, want null without a stored chain", model.CertificateChainP7B.ValueString())
+	}
+
+	// Without the root in the PKCS#7 only the issuing CA is known.
+	noRoot := h.testCertificates(t)
+	noRoot.CertificateChainB64 = string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: testPKCS7(t, h.leaf, h.issuing)}))
+	if diags := model.setCertificateOutputs(context.Background(), noRoot); diags.HasError() {
+		t.Fatalf("unexpected diagnostics: %v", diags)
+	}
+	if model.IssuingCAPEM.ValueString() != encodePEM(h.issuing) || !model.RootCAPEM.IsNull() {
+		t.Errorf("issuing_ca_pem = %q, root_ca_pem = %q, want the issuing CA and null", model.IssuingCAPEM.ValueString(), model.RootCAPEM.ValueString())
+	}
+}
+
+func TestThumbprints(t *testing.T) {
+	h := newTestHierarchy(t)
+
+	var model certificateCreateModel
+	if diags := model.setCertificateOutputs(context.Background(), h.testCertificates(t)); diags.HasError() {
+		t.Fatalf("unexpected diagnostics: %v", diags)
+	}
+	if want := fmt.Sprintf("%X", sha1.Sum(h.leaf.Raw)); model.ThumbprintSHA1.ValueString() != want {
+		t.Errorf("thumbprint_sha1 = %s, want %s", model.ThumbprintSHA1.ValueString(), want)
+	}
+	if got := model.ThumbprintSHA256.ValueString(); len(got) != 64 || strings.ToUpper(got) != got {
+		t.Errorf("thumbprint_sha256 = %s, want 64 upper-case hex digits", got)
+	}
+}
+
+func TestNormalizePEM(t *testing.T) {
+	h := newTestHierarchy(t)
+	want := encodePEM(h.leaf)
+	b64 := base64.StdEncoding.EncodeToString(h.leaf.Raw)
+
+	// certsrv wraps at 76 columns with CRLF line endings.
+	var wrapped strings.Builder
+	for rest := b64; rest != ""; {
+		n := len(rest)
+		if n > 76 {
+			n = 76
+		}
+		wrapped.WriteString(rest[:n] + "\r\n")
+		rest = rest[n:]
+	}
+	certsrv := "-----BEGIN CERTIFICATE-----\r\n" + wrapped.String() + "-----END CERTIFICATE-----\r\n"
+
+	for name, value := range map[string]string{
+		"canonical":   want,
+		"certsrv":     certsrv,
+		"escaped":     strings.ReplaceAll(certsrv, "\r\n", `\r\n`),
+		"bare cr":     strings.ReplaceAll(certsrv, "\r\n", "\r"),
+		"surrounding": "Certificate:\n" + certsrv + "\n\n",
+	} {
+		got := normalizePEM(value)
+		if got != want {
+			t.Errorf("%s: normalizePEM() = %q, want %q", name, got, want)
+		}
+		if again := normalizePEM(got); again != got {
+			t.Errorf("%s: normalizePEM() is not stable: %q, then %q", name, got, again)
+		}
+	}
+
+	if got := normalizePEM(wrapped.String()); got != b64 {
+		t.Errorf("normalizePEM(bare base64) = %q, want %q", got, b64)
+	}
+	for _, value := range []string{"", "not a certificate"} {
+		if got := normalizePEM(value); got != value {
+			t.Errorf("normalizePEM(%q) = %q, want it unchanged", value, got)
+		}
+	}
+}
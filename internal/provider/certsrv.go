@@ -0,0 +1,257 @@
+package provider
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/flipyap/microsoft-adcs-client/client"
+	"github.com/hashicorp/terraform-plugin-log/tflog"
+)
+
+// caRenewalsRegex reads the number of CA certificate renewals from certcarc.asp.
+var caRenewalsRegex = regexp.MustCompile(`nRenewals\s*=\s*(\d+)`)
+
+// certsrvSubmission is a certificate request as it is posted to certfnsh.asp.
+type certsrvSubmission struct {
+	CSR      string
+	Template string
+	// Attributes holds extra "name:value" request attributes, one per entry.
+	Attributes []string
+	// Chunked sends the form with chunked transfer encoding instead of a Content-Length.
+	Chunked bool
+}
+
+// Transfer encodings of submissions, see submission_transfer_encoding.
+const (
+	transferEncodingContentLength = "content-length"
+	transferEncodingChunked       = "chunked"
+)
+
+// requestTooLargeError explains a submission IIS refused for its size. certfnsh.asp is a classic ASP
+// page, so the ASP limit is usually the one hit, long before the request filtering limit.
+func requestTooLargeError(size int) error {
+	return fmt.Errorf("certsrv rejected the %d byte submission as too large (HTTP 413). Raise the limits of the "+
+		"certsrv application in IIS: the ASP limits maxRequestEntityAllowed (200000 bytes by default), the request "+
+		"filtering limit maxAllowedContentLength and, for sites requiring TLS client certificates, uploadReadAheadSize. "+
+		"Sites fronted by ASP.NET also need a higher httpRuntime maxRequestLength", size)
+}
+
+// certAttrib builds the CertAttrib form field, one CRLF terminated attribute per line.
+func (s certsrvSubmission) certAttrib() string {
+	var b strings.Builder
+	b.WriteString("CertificateTemplate:" + s.Template + "\r\n")
+	for _, attribute := range s.Attributes {
+		if attribute = strings.TrimSpace(attribute); attribute != "" {
+			b.WriteString(attribute + "\r\n")
+		}
+	}
+	return b.String()
+}
+
+// splitAttributes turns the attributes argument of the resource into separate request attributes.
+func splitAttributes(attributes string) []string {
+	var result []string
+	for _, line := range strings.Split(strings.ReplaceAll(attributes, "\r\n", "\n"), "\n") {
+		if line = strings.TrimSpace(line); line != "" {
+			result = append(result, line)
+		}
+	}
+	return result
+}
+
+// submitCertificateRequest posts the request to certsrv and retrieves the issued certificate. The
+// client's RequestCertificate only sends the template, so submission is done here to get every
+// request attribute to the CA.
+func submitCertificateRequest(ctx context.Context, c *client.ADCSClient, parser *certsrvParser, submission certsrvSubmission) (*client.Certificates, error) {
+	reqID, err := submitRequest(ctx, c, parser, submission)
+	if err != nil {
+		return nil, err
+	}
+
+	certificates, err := retrieveCertificates(ctx, c, parser, reqID)
+	if err != nil {
+		return nil, fmt.Errorf("certificate downloads failed: %w", err)
+	}
+	return certificates, nil
+}
+
+// submitRequest posts the request to certsrv, retrying failures that left the CA without it, and
+// returns the request ID without downloading the certificate.
+func submitRequest(ctx context.Context, c *client.ADCSClient, parser *certsrvParser, submission certsrvSubmission) (string, error) {
+	var reqID string
+	err := retryTransient(ctx, "submit certificate request", isRetryableSubmission, func() error {
+		var err error
+		reqID, err = postSubmission(ctx, c, parser, submission)
+		return err
+	})
+	return reqID, err
+}
+
+// postSubmission posts the request to certfnsh.asp once and returns the request ID the CA gave it.
+func postSubmission(ctx context.Context, c *client.ADCSClient, parser *certsrvParser, submission certsrvSubmission) (string, error) {
+	form := url.Values{}
+	form.Set("Mode", "newreq")
+	form.Set("CertRequest", submission.CSR)
+	form.Set("CertAttrib", submission.certAttrib())
+	form.Set("FriendlyType", "Saved-Request Certificate")
+	form.Set("TargetStoreFlags", "0")
+	form.Set("SaveCert", "yes")
+
+	encoded := form.Encode()
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, "http://"+certsrvHost(ctx, c)+"/certsrv/certfnsh.asp", strings.NewReader(encoded))
+	if err != nil {
+		return "", fmt.Errorf("could not create request: %v", err)
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	if submission.Chunked {
+		// Proxies in front of IIS that buffer bodies with a Content-Length up to a limit stream
+		// chunked ones instead.
+		req.ContentLength = -1
+		req.TransferEncoding = []string{transferEncodingChunked}
+	}
+
+	tflog.Debug(ctx, "Submitting certificate request to certsrv", map[string]interface{}{
+		"attributes": submission.Attributes,
+		"size":       len(encoded),
+		"chunked":    submission.Chunked,
+	})
+	resp, err := certsrvClient(ctx, c).DoRequest(req)
+	if responseStatus(err) == http.StatusRequestEntityTooLarge {
+		return "", requestTooLargeError(len(encoded))
+	}
+	if err != nil {
+		return "", fmt.Errorf("certificate request failed: %v", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return "", fmt.Errorf("error reading response body from requesting certificates: %v", err)
+	}
+
+	// Downloads have to reach the CA that took the request, also when it stays pending.
+	route := caRouteFrom(ctx)
+	route.record(string(body))
+	reqID, err := parser.requestID(string(body))
+	operationTraceFrom(ctx).recordSubmission(route, string(body), reqID, err)
+	return reqID, err
+}
+
+// requestNonceAttribute is the request attribute carrying the request_nonce of a submission.
+const requestNonceAttribute = "ClientRequestNonce"
+
+// newRequestNonce returns a random token identifying a single submission.
+func newRequestNonce() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("could not generate request nonce: %v", err)
+	}
+	return hex.EncodeToString(b), nil
+}
+
+// retrieveCertificates downloads the issued certificate of a request together with its chain.
+func retrieveCertificates(ctx context.Context, c *client.ADCSClient, parser *certsrvParser, reqID string) (*client.Certificates, error) {
+	query := url.Values{}
+	query.Add("ReqID", reqID)
+	query.Add("Enc", "b64")
+
+	chain, err := downloadCertsrv(ctx, c, parser, "certnew.p7b", query, "application/x-pkcs7-certificates")
+	if err != nil {
+		return nil, fmt.Errorf("failed to download full certificate chain: %w", err)
+	}
+	certificates, err := retrieveCertificate(ctx, c, parser, reqID)
+	if err != nil {
+		return nil, err
+	}
+	certificates.CertificateChainB64 = chain
+	return certificates, nil
+}
+
+// retrieveCertificate downloads only the issued certificate of a request, leaving out the chain.
+func retrieveCertificate(ctx context.Context, c *client.ADCSClient, parser *certsrvParser, reqID string) (*client.Certificates, error) {
+	query := url.Values{}
+	query.Add("ReqID", reqID)
+	query.Add("Enc", "b64")
+
+	body, err := downloadCertsrv(ctx, c, parser, "certnew.cer", query, "application/pkix-cert")
+	if err != nil {
+		return nil, fmt.Errorf("failed to download certificate: %w", err)
+	}
+	return &client.Certificates{ID: reqID, CertificateB64: body}, nil
+}
+
+// retrieveCAChain downloads the PKCS#7 chain of the CA certificate with the given renewal index.
+// A negative index selects the current CA certificate.
+func retrieveCAChain(ctx context.Context, c *client.ADCSClient, parser *certsrvParser, renewal int64) (string, int64, error) {
+	if renewal < 0 {
+		page, err := downloadCertsrv(ctx, c, parser, "certcarc.asp", url.Values{}, "")
+		if err != nil {
+			return "", 0, fmt.Errorf("failed to read CA certificate page: %v", err)
+		}
+		renewal = 0
+		if match := caRenewalsRegex.FindStringSubmatch(page); match != nil {
+			renewal, _ = strconv.ParseInt(match[1], 10, 64)
+		}
+	}
+
+	query := url.Values{}
+	query.Add("ReqID", "CACert")
+	query.Add("Renewal", strconv.FormatInt(renewal, 10))
+	query.Add("Enc", "b64")
+
+	body, err := downloadCertsrv(ctx, c, parser, "certnew.p7b", query, "application/x-pkcs7-certificates")
+	if err != nil {
+		return "", 0, fmt.Errorf("failed to download CA certificate chain: %w", err)
+	}
+	return body, renewal, nil
+}
+
+// downloadCertsrv fetches a certsrv page, retrying transient errors. When contentType is set the
+// parser decides whether the response is the expected download or an error page. Downloads for a
+// request are recorded in the operation trace.
+func downloadCertsrv(ctx context.Context, c *client.ADCSClient, parser *certsrvParser, page string, query url.Values, contentType string) (string, error) {
+	var body string
+	err := retryTransient(ctx, "download "+page, isRetryable, func() error {
+		var err error
+		body, err = downloadCertsrvOnce(ctx, c, parser, page, query, contentType)
+		return err
+	})
+	if reqID := query.Get("ReqID"); reqID != "" && reqID != "CACert" {
+		operationTraceFrom(ctx).record(caRouteFrom(ctx), reqID, err)
+	}
+	return body, err
+}
+
+// downloadCertsrvOnce fetches a certsrv page once.
+func downloadCertsrvOnce(ctx context.Context, c *client.ADCSClient, parser *certsrvParser, page string, query url.Values, contentType string) (string, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, "http://"+certsrvHost(ctx, c)+"/certsrv/"+page, nil)
+	if err != nil {
+		return "", fmt.Errorf("could not create request: %v", err)
+	}
+	req.URL.RawQuery = query.Encode()
+
+	resp, err := certsrvClient(ctx, c).DoRequest(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return "", fmt.Errorf("error reading response body: %v", err)
+	}
+	if contentType != "" {
+		if err := parser.checkDownload(resp.Header.Get("Content-Type"), contentType, string(body)); err != nil {
+			return "", err
+		}
+	}
+	return string(body), nil
+}
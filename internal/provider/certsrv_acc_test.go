@@ -0,0 +1,363 @@
+package provider
+
+import (
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"encoding/base64"
+	"encoding/pem"
+	"fmt"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/jcmturner/gokrb5/v8/spnego"
+	"github.com/vadimi/go-ntlm/ntlm"
+)
+
+const (
+	// accApprovalTemplate is the template the acceptance certsrv holds for CA manager approval.
+	accApprovalTemplate = "ManagerApproval"
+	// accDeniedTemplate is the template the policy module of the acceptance certsrv denies.
+	accDeniedTemplate = "Denied"
+	// accCAName is the name of the CA behind the acceptance certsrv.
+	accCAName = "TEST-ISSUING-CA"
+)
+
+// accTemplates are the templates the acceptance certsrv offers on its advanced request page.
+var accTemplates = []string{"WebServer", "User", "ClientAuth", accApprovalTemplate, accDeniedTemplate}
+
+// accAuthMode is the authentication the acceptance certsrv requires, and the provider is
+// configured with.
+type accAuthMode string
+
+const (
+	// accAuthNTLM requires an NTLM handshake, the default of the acceptance certsrv.
+	accAuthNTLM accAuthMode = "ntlm"
+	// accAuthKerberos requires a SPNEGO Kerberos ticket, which the provider gets from the acceptance
+	// KDC with the password of its account.
+	accAuthKerberos accAuthMode = "kerberos"
+)
+
+// accAuthModes are the authentication modes acceptance tests of the resources run under. The
+// provider has no keytab authentication, Kerberos always logs in with the password.
+var accAuthModes = []accAuthMode{accAuthNTLM, accAuthKerberos}
+
+// forEachAuthMode runs test as a subtest for every authentication mode, against an acceptance
+// certsrv requiring it.
+func forEachAuthMode(t *testing.T, test func(t *testing.T, srv *accCertsrv)) {
+	for _, mode := range accAuthModes {
+		t.Run(string(mode), func(t *testing.T) {
+			test(t, newAccCertsrvWithAuth(t, mode))
+		})
+	}
+}
+
+// accCertsrv is a certsrv stand-in for acceptance tests. Unlike testCertsrv it requires NTLM or
+// Kerberos authentication like IIS does, signs the submitted requests with the test issuing CA and keeps
+// requests for accApprovalTemplate pending until they are first polled for. Requests for
+// accDeniedTemplate are denied.
+type accCertsrv struct {
+	hierarchy *testHierarchy
+	server    *httptest.Server
+	auth      accAuthMode
+	// kdc issues the tickets of accAuthKerberos, nil with NTLM.
+	kdc *accKDC
+
+	mu       sync.Mutex
+	nextID   int
+	requests map[string]*accRequest
+}
+
+// accRequest is a request in the CA database of the acceptance certsrv.
+type accRequest struct {
+	request    *x509.CertificateRequest
+	template   string
+	attributes []string
+	// renews is the certificate a renewal request renews, nil for new enrollments.
+	renews *x509.Certificate
+	// onBehalfOf is the account an enrollment agent requested the certificate for.
+	onBehalfOf string
+	// cert is nil while the request is pending.
+	cert *x509.Certificate
+}
+
+func newAccCertsrv(t *testing.T) *accCertsrv {
+	t.Helper()
+	return newAccCertsrvWithAuth(t, accAuthNTLM)
+}
+
+// newAccCertsrvWithAuth starts an acceptance certsrv requiring the authentication mode, together
+// with the KDC Kerberos needs.
+func newAccCertsrvWithAuth(t *testing.T, mode accAuthMode) *accCertsrv {
+	t.Helper()
+	srv := &accCertsrv{
+		hierarchy: newTestHierarchy(t),
+		auth:      mode,
+		nextID:    100,
+		requests:  map[string]*accRequest{},
+	}
+	authenticate := requireNTLM
+	if mode == accAuthKerberos {
+		srv.kdc = newAccKDC(t, "127.0.0.1", accServiceHost("127.0.0.1"))
+		authenticate = func(next http.Handler) http.Handler {
+			return spnego.SPNEGOKRB5Authenticate(next, srv.kdc.services)
+		}
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/certsrv/certfnsh.asp", srv.submit)
+	mux.HandleFunc("/certsrv/certnew.cer", srv.download)
+	mux.HandleFunc("/certsrv/certnew.p7b", srv.download)
+	mux.HandleFunc("/certsrv/certcarc.asp", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, "<script>var nRenewals=0;</script>")
+	})
+	mux.HandleFunc("/certsrv/certrqxt.asp", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `<Select Name="lbCertTemplate" ID="lbCertTemplateID">`)
+		for _, template := range accTemplates {
+			fmt.Fprintf(w, `<Option Value="E;%s;1;1;Microsoft RSA SChannel Cryptographic Provider;2048;0;0">%s</Option>`, template, template)
+		}
+		fmt.Fprint(w, `</Select>`)
+	})
+	mux.HandleFunc("/certsrv/", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintf(w, `<TD><Font color=#ffffff><LocID ID=locMSCertSrv>Microsoft</LocID> Active Directory Certificate Services &nbsp;--&nbsp; %s&nbsp;</Font></TD>`, accCAName)
+	})
+	// The Certificate Enrollment Web Service refuses requests without TLS.
+	mux.HandleFunc("/"+accCAName+"_CES_Kerberos/service.svc", func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "403.4 SSL required", http.StatusForbidden)
+	})
+
+	srv.server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Server", "Microsoft-IIS/10.0")
+		authenticate(mux).ServeHTTP(w, r)
+	}))
+	t.Cleanup(srv.server.Close)
+	return srv
+}
+
+// host is the value of the provider host argument that reaches the server.
+func (s *accCertsrv) host() string {
+	return strings.TrimPrefix(s.server.URL, "http://")
+}
+
+// providerConfig configures the provider for the server.
+func (s *accCertsrv) providerConfig() string {
+	if s.auth == accAuthKerberos {
+		return fmt.Sprintf(`
+provider "microsoftadcs" {
+  host     = %q
+  username = %q
+  password = %q
+  krb5conf = %q
+}
+`, s.host(), accKerberosUser, accKerberosPassword, s.kdc.krb5conf())
+	}
+	return fmt.Sprintf(`
+provider "microsoftadcs" {
+  host     = %q
+  username = "svc-terraform"
+  password = "secret"
+  use_ntlm = true
+}
+`, s.host())
+}
+
+// providerSettings are the settings of the provider block of providerConfig, for tests that
+// configure the provider with debugEnrollConfigure. The password comes from ADCS_PASSWORD.
+func (s *accCertsrv) providerSettings() map[string]interface{} {
+	if s.auth == accAuthKerberos {
+		return map[string]interface{}{"host": s.host(), "username": accKerberosUser, "krb5conf": s.kdc.krb5conf()}
+	}
+	return map[string]interface{}{"host": s.host(), "username": "svc-terraform", "use_ntlm": true}
+}
+
+// requireNTLM answers every request without an NTLM authenticate message with a challenge. The
+// credentials are not checked, only the handshake the client has to go through.
+func requireNTLM(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if header := r.Header.Get("Authorization"); strings.HasPrefix(header, "NTLM ") {
+			message, err := base64.StdEncoding.DecodeString(strings.TrimPrefix(header, "NTLM "))
+			if err == nil && len(message) > 8 && message[8] == 3 {
+				next.ServeHTTP(w, r)
+				return
+			}
+		}
+
+		session, err := ntlm.CreateServerSession(ntlm.Version2, ntlm.ConnectionlessMode)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		challenge, err := session.GenerateChallengeMessage()
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		w.Header().Add("WWW-Authenticate", "NTLM "+base64.StdEncoding.EncodeToString(challenge.Bytes()))
+		w.WriteHeader(http.StatusUnauthorized)
+	})
+}
+
+func (s *accCertsrv) submit(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	req := &accRequest{}
+	var err error
+	if der, decodeErr := decodePEMOrBase64(r.PostForm.Get("CertRequest")); decodeErr == nil && isPKCS7(der) {
+		var signed *signedRequest
+		if signed, err = parseSignedRequest(der); err == nil {
+			if requester, ok := onBehalfOfRequester(signed); ok {
+				req.request, req.onBehalfOf = signed.request, requester
+			} else {
+				req.request, req.renews, err = parseRenewalRequest(der)
+			}
+		}
+	} else {
+		req.request, err = parseCertificateRequest(r.PostForm.Get("CertRequest"))
+	}
+	if err != nil {
+		fmt.Fprintf(w, `The disposition message is "Error Parsing Request %s"`, err)
+		return
+	}
+
+	for _, attribute := range strings.Split(r.PostForm.Get("CertAttrib"), "\r\n") {
+		if strings.HasPrefix(attribute, "CertificateTemplate:") {
+			req.template = strings.TrimPrefix(attribute, "CertificateTemplate:")
+		} else if attribute != "" {
+			req.attributes = append(req.attributes, attribute)
+		}
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.nextID++
+	reqID := strconv.Itoa(s.nextID)
+	s.requests[reqID] = req
+
+	if req.template == accDeniedTemplate {
+		fmt.Fprintf(w, `<P>Your certificate request was denied.</P><P>Your Request Id is %s. The disposition message is "Denied by Policy Module  0x80094800".</P>`, reqID)
+		return
+	}
+	if req.template == accApprovalTemplate {
+		fmt.Fprintf(w, "<P>Certificate Pending</P><P>Your certificate request has been received.</P><P>Your Request Id is %s.</P>", reqID)
+		return
+	}
+	if req.cert, err = s.issue(req); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	fmt.Fprintf(w, `<a href="certnew.cer?ReqID=%s&amp;Enc=b64">Download certificate</a>`, reqID)
+}
+
+func (s *accCertsrv) download(w http.ResponseWriter, r *http.Request) {
+	reqID := r.URL.Query().Get("ReqID")
+	chain := strings.HasSuffix(r.URL.Path, ".p7b")
+	if reqID == "CACert" {
+		s.writeCertificates(w, chain, s.hierarchy.root, s.hierarchy.issuing)
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	req, ok := s.requests[reqID]
+	if !ok {
+		fmt.Fprintf(w, `The disposition message is "Request %s was not found"`, reqID)
+		return
+	}
+	if req.cert == nil {
+		// A CA manager approves the request by the time it is first polled for.
+		var err error
+		if req.cert, err = s.issue(req); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+	}
+	s.writeCertificates(w, chain, s.hierarchy.root, s.hierarchy.issuing, req.cert)
+}
+
+// writeCertificates answers a download with the last certificate, or all of them as PKCS#7.
+func (s *accCertsrv) writeCertificates(w http.ResponseWriter, chain bool, certs ...*x509.Certificate) {
+	if chain {
+		w.Header().Set("Content-Type", "application/x-pkcs7-certificates")
+		der, err := marshalTestPKCS7(certs...)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		fmt.Fprint(w, string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})))
+		return
+	}
+	w.Header().Set("Content-Type", "application/pkix-cert")
+	fmt.Fprint(w, string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: certs[len(certs)-1].Raw})))
+}
+
+// issue signs the request with the issuing CA, adding the names of a san request attribute and the
+// name of the template. Renewals keep the subject of the renewed certificate like ADCS does.
+func (s *accCertsrv) issue(req *accRequest) (*x509.Certificate, error) {
+	names, err := attributeSubjectAlternativeNames(strings.Join(req.attributes, "\n"))
+	if err != nil {
+		return nil, err
+	}
+	serial, err := rand.Int(rand.Reader, big.NewInt(1<<62))
+	if err != nil {
+		return nil, err
+	}
+	template := &x509.Certificate{
+		SerialNumber:   serial,
+		Subject:        req.request.Subject,
+		NotBefore:      time.Now().Add(-time.Minute),
+		NotAfter:       time.Now().Add(365 * 24 * time.Hour),
+		KeyUsage:       x509.KeyUsageDigitalSignature | x509.KeyUsageKeyEncipherment,
+		DNSNames:       append(append([]string(nil), req.request.DNSNames...), names.DNSNames...),
+		EmailAddresses: req.request.EmailAddresses,
+		IPAddresses:    req.request.IPAddresses,
+		URIs:           req.request.URIs,
+	}
+	if req.renews != nil {
+		template.Subject = req.renews.Subject
+	}
+	if req.template != "" {
+		// Certificates name their template like those of version 1 templates do.
+		name, err := asn1.Marshal(asn1BMPString(req.template))
+		if err != nil {
+			return nil, err
+		}
+		template.ExtraExtensions = []pkix.Extension{{Id: oidCertificateTemplateName, Value: name}}
+	}
+	switch req.template {
+	case "User", "ClientAuth":
+		template.ExtKeyUsage = []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth}
+	default:
+		template.ExtKeyUsage = []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth}
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, template, s.hierarchy.issuing, req.request.PublicKey, s.hierarchy.issuingKey)
+	if err != nil {
+		return nil, err
+	}
+	return x509.ParseCertificate(der)
+}
+
+// purge deletes every request from the CA database, like certutil -deleterow does with old rows.
+func (s *accCertsrv) purge() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for reqID := range s.requests {
+		delete(s.requests, reqID)
+	}
+}
+
+// request returns what was submitted with the request ID.
+func (s *accCertsrv) request(reqID string) *accRequest {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.requests[reqID]
+}
@@ -0,0 +1,154 @@
+package provider
+
+import (
+	"context"
+	"encoding/pem"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/flipyap/microsoft-adcs-client/client"
+)
+
+// testCertsrv is a minimal stand-in for the certsrv web enrollment pages.
+type testCertsrv struct {
+	hierarchy *testHierarchy
+	// page is returned by certfnsh.asp, it defaults to an issued certificate with request ID 42.
+	page string
+	// certAttribs records the CertAttrib field of every submission.
+	certAttribs []string
+	// transferEncodings records the transfer encoding of every submission.
+	transferEncodings []string
+	// submitStatus, when set, is returned by certfnsh.asp instead of page.
+	submitStatus int
+	// caRenewals records the Renewal query parameter of every CA chain download.
+	caRenewals []string
+}
+
+func newTestCertsrv(t *testing.T) (*testCertsrv, *client.ADCSClient) {
+	t.Helper()
+	srv := &testCertsrv{
+		hierarchy: newTestHierarchy(t),
+		page:      `<a href="certnew.cer?ReqID=42&amp;Enc=b64">Download certificate</a>`,
+	}
+	certificates := srv.hierarchy.testCertificates(t)
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/certsrv/certfnsh.asp", func(w http.ResponseWriter, r *http.Request) {
+		srv.transferEncodings = append(srv.transferEncodings, strings.Join(r.TransferEncoding, ","))
+		if srv.submitStatus != 0 {
+			http.Error(w, http.StatusText(srv.submitStatus), srv.submitStatus)
+			return
+		}
+		if err := r.ParseForm(); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		srv.certAttribs = append(srv.certAttribs, r.PostForm.Get("CertAttrib"))
+		fmt.Fprint(w, srv.page)
+	})
+	caChain := string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: testPKCS7(t, srv.hierarchy.root, srv.hierarchy.issuing)}))
+	mux.HandleFunc("/certsrv/certnew.p7b", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/x-pkcs7-certificates")
+		if r.URL.Query().Get("ReqID") == "CACert" {
+			srv.caRenewals = append(srv.caRenewals, r.URL.Query().Get("Renewal"))
+			fmt.Fprint(w, caChain)
+			return
+		}
+		fmt.Fprint(w, certificates.CertificateChainB64)
+	})
+	mux.HandleFunc("/certsrv/certcarc.asp", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, "<script>var nRenewals=2;</script>")
+	})
+	mux.HandleFunc("/certsrv/certnew.cer", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/pkix-cert")
+		fmt.Fprint(w, certificates.CertificateB64)
+	})
+
+	server := httptest.NewServer(mux)
+	t.Cleanup(server.Close)
+
+	return srv, &client.ADCSClient{
+		HostURL:    strings.TrimPrefix(server.URL, "http://"),
+		NtlmClient: server.Client(),
+		UseNtlm:    true,
+	}
+}
+
+func TestSubmitCertificateRequest(t *testing.T) {
+	srv, c := newTestCertsrv(t)
+
+	certificates, err := submitCertificateRequest(context.Background(), c, strictParser, certsrvSubmission{
+		CSR:        csr,
+		Template:   "WebServer",
+		Attributes: splitAttributes("san:dns=app.example.com\n\nClientRequestNonce:abc"),
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if certificates.ID != "42" {
+		t.Errorf("ID = %q, want 42", certificates.ID)
+	}
+	if block, _ := pem.Decode([]byte(certificates.CertificateB64)); block == nil || string(block.Bytes) != string(srv.hierarchy.leaf.Raw) {
+		t.Errorf("certificate is not the issued leaf")
+	}
+
+	want := "CertificateTemplate:WebServer\r\nsan:dns=app.example.com\r\nClientRequestNonce:abc\r\n"
+	if len(srv.certAttribs) != 1 || srv.certAttribs[0] != want {
+		t.Errorf("CertAttrib = %q, want %q", srv.certAttribs, want)
+	}
+}
+
+func TestSubmitCertificateRequestChunked(t *testing.T) {
+	srv, c := newTestCertsrv(t)
+
+	for _, chunked := range []bool{false, true} {
+		if _, err := submitCertificateRequest(context.Background(), c, strictParser, certsrvSubmission{CSR: csr, Template: "WebServer", Chunked: chunked}); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+	if len(srv.transferEncodings) != 2 || srv.transferEncodings[0] != "" || srv.transferEncodings[1] != "chunked" {
+		t.Errorf("transfer encodings = %q, want none and then chunked", srv.transferEncodings)
+	}
+	if len(srv.certAttribs) != 2 || srv.certAttribs[1] != srv.certAttribs[0] {
+		t.Errorf("chunked submission arrived as %q, want %q", srv.certAttribs, srv.certAttribs[:1])
+	}
+}
+
+func TestSubmitCertificateRequestTooLarge(t *testing.T) {
+	srv, c := newTestCertsrv(t)
+	srv.submitStatus = http.StatusRequestEntityTooLarge
+
+	_, err := submitCertificateRequest(context.Background(), c, strictParser, certsrvSubmission{CSR: csr, Template: "WebServer"})
+	if err == nil || !strings.Contains(err.Error(), "HTTP 413") || !strings.Contains(err.Error(), "maxRequestEntityAllowed") {
+		t.Errorf("error = %v, want advice on the IIS request limits", err)
+	}
+	if isTransient(err) || isStillPending(err) {
+		t.Errorf("a submission that is too large must fail right away: %v", err)
+	}
+}
+
+func TestRetrieveCAChain(t *testing.T) {
+	srv, c := newTestCertsrv(t)
+
+	chain, renewal, err := retrieveCAChain(context.Background(), c, strictParser, -1)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if renewal != 2 {
+		t.Errorf("renewal = %d, want the current renewal 2", renewal)
+	}
+	certs, err := parseCertificateChain(chain)
+	if err != nil || len(certs) != 2 {
+		t.Fatalf("chain = %d certificates (%v), want 2", len(certs), err)
+	}
+
+	if _, renewal, _ = retrieveCAChain(context.Background(), c, strictParser, 0); renewal != 0 {
+		t.Errorf("renewal = %d, want 0", renewal)
+	}
+	if strings.Join(srv.caRenewals, ",") != "2,0" {
+		t.Errorf("requested renewals = %v, want [2 0]", srv.caRenewals)
+	}
+}
@@ -0,0 +1,64 @@
+package provider
+
+import (
+	"crypto/x509"
+	"fmt"
+	"strings"
+
+	"github.com/hashicorp/terraform-plugin-framework/diag"
+	"github.com/hashicorp/terraform-plugin-framework/path"
+)
+
+// Values of chain_order, the order of the certificates in the certificate_chain outputs.
+const (
+	chainOrderLeafFirst = "leaf-first"
+	chainOrderRootFirst = "root-first"
+)
+
+// chainOrders lists the values of chain_order.
+var chainOrders = []string{chainOrderLeafFirst, chainOrderRootFirst}
+
+// chainOrder returns chain_order, leaf-first by default.
+func (m *certificateCreateModel) chainOrder() string {
+	if m.ChainOrder.IsNull() || m.ChainOrder.IsUnknown() {
+		return chainOrderLeafFirst
+	}
+	return m.ChainOrder.ValueString()
+}
+
+// includeRootInChain reports whether the certificate_chain outputs hold the root, which is the
+// default.
+func (m *certificateCreateModel) includeRootInChain() bool {
+	return m.IncludeRootInChain.IsNull() || m.IncludeRootInChain.ValueBool()
+}
+
+// arrangeChain returns chain, which starts with the issuing CA, laid out the way chain_order and
+// include_root_in_chain ask for. chain itself is left as it is.
+func (m *certificateMaterial) arrangeChain(chain []*x509.Certificate) []*x509.Certificate {
+	arranged := append([]*x509.Certificate(nil), chain...)
+	if m.excludeRoot && len(arranged) > 0 && isSelfSigned(arranged[len(arranged)-1]) {
+		arranged = arranged[:len(arranged)-1]
+	}
+	if m.rootFirst {
+		for i, j := 0, len(arranged)-1; i < j; i, j = i+1, j-1 {
+			arranged[i], arranged[j] = arranged[j], arranged[i]
+		}
+	}
+	return arranged
+}
+
+// validateChainOrder checks chain_order.
+func validateChainOrder(config certificateCreateModel) diag.Diagnostics {
+	var diags diag.Diagnostics
+	if config.ChainOrder.IsNull() || config.ChainOrder.IsUnknown() {
+		return diags
+	}
+	if order := config.ChainOrder.ValueString(); !containsString(chainOrders, order) {
+		diags.AddAttributeError(
+			path.Root("chain_order"),
+			"Invalid Chain Order",
+			fmt.Sprintf("chain_order %q is not one of: %s.", order, strings.Join(chainOrders, ", ")),
+		)
+	}
+	return diags
+}
@@ -0,0 +1,62 @@
+package provider
+
+import (
+	"context"
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-framework/attr"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+)
+
+func TestChainOrderOutputs(t *testing.T) {
+	h := newTestHierarchy(t)
+
+	for name, tc := range map[string]struct {
+		model certificateCreateModel
+		want  []string
+	}{
+		"default":         {want: []string{encodePEM(h.issuing), encodePEM(h.root)}},
+		"leaf-first":      {model: certificateCreateModel{ChainOrder: types.StringValue(chainOrderLeafFirst)}, want: []string{encodePEM(h.issuing), encodePEM(h.root)}},
+		"root-first":      {model: certificateCreateModel{ChainOrder: types.StringValue(chainOrderRootFirst)}, want: []string{encodePEM(h.root), encodePEM(h.issuing)}},
+		"without root":    {model: certificateCreateModel{IncludeRootInChain: types.BoolValue(false)}, want: []string{encodePEM(h.issuing)}},
+		"root-first only": {model: certificateCreateModel{ChainOrder: types.StringValue(chainOrderRootFirst), IncludeRootInChain: types.BoolValue(false)}, want: []string{encodePEM(h.issuing)}},
+	} {
+		model := tc.model
+		if diags := model.setCertificateOutputs(context.Background(), h.testCertificates(t)); diags.HasError() {
+			t.Fatalf("%s: unexpected diagnostics: %v", name, diags)
+		}
+		var wantPEM string
+		wantList := make([]attr.Value, 0, len(tc.want))
+		for _, cert := range tc.want {
+			wantPEM += cert
+			wantList = append(wantList, types.StringValue(cert))
+		}
+		if got := model.CertificateChainPEM.ValueString(); got != wantPEM {
+			t.Errorf("%s: certificate_chain_pem = %q, want %q", name, got, wantPEM)
+		}
+		if want := types.ListValueMust(types.StringType, wantList); !model.CertificateChain.Equal(want) {
+			t.Errorf("%s: certificate_chain = %v, want %v", name, model.CertificateChain, want)
+		}
+		if got := len(model.CertificateChainDER.Elements()); got != len(tc.want) {
+			t.Errorf("%s: certificate_chain_der has %d elements, want %d", name, got, len(tc.want))
+		}
+		if chains := model.CertificateChains.Elements(); len(chains) != 1 || !chains[0].Equal(types.ListValueMust(types.StringType, wantList)) {
+			t.Errorf("%s: certificate_chains = %v, want one chain laid out like certificate_chain", name, model.CertificateChains)
+		}
+		// The root stays available on its own and in the bundles.
+		if model.RootCAPEM.ValueString() != encodePEM(h.root) {
+			t.Errorf("%s: root_ca_pem = %q, want the root", name, model.RootCAPEM.ValueString())
+		}
+	}
+}
+
+func TestValidateChainOrder(t *testing.T) {
+	for _, order := range chainOrders {
+		if diags := validateChainOrder(certificateCreateModel{ChainOrder: types.StringValue(order)}); diags.HasError() {
+			t.Errorf("validateChainOrder(%q) = %v", order, diags)
+		}
+	}
+	if diags := validateChainOrder(certificateCreateModel{ChainOrder: types.StringValue("issuer-first")}); !diags.HasError() {
+		t.Error("validateChainOrder(\"issuer-first\") succeeded, want an error")
+	}
+}
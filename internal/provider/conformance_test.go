@@ -0,0 +1,130 @@
+package provider
+
+import (
+	"context"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/terraform-plugin-framework/diag"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+)
+
+// conformanceBackend is an enrollment backend the conformance suite runs against. Every backend
+// has to give resources the same dispositions, pending handling and chain outputs, so a new
+// backend registers in conformanceBackends rather than growing tests of its own.
+type conformanceBackend struct {
+	name string
+	// start runs a CA stand-in for the backend and returns the provider configured against it. The
+	// stand-in issues requests for any template, keeps requests for accApprovalTemplate pending until
+	// they are first polled for and denies requests for accDeniedTemplate.
+	start func(t *testing.T) (*providerData, *testHierarchy)
+}
+
+// conformanceBackends are the enrollment backends of the provider, the web enrollment pages once
+// for every authentication mode.
+var conformanceBackends = []conformanceBackend{
+	{name: "web", start: webConformanceBackend(accAuthNTLM)},
+	{name: "web kerberos", start: webConformanceBackend(accAuthKerberos)},
+}
+
+// webConformanceBackend starts the acceptance certsrv requiring the authentication mode.
+func webConformanceBackend(mode accAuthMode) func(t *testing.T) (*providerData, *testHierarchy) {
+	return func(t *testing.T) (*providerData, *testHierarchy) {
+		srv := newAccCertsrvWithAuth(t, mode)
+		t.Setenv("ADCS_PASSWORD", "secret")
+		data, err := debugEnrollConfigure(context.Background(), "test", srv.providerSettings(), io.Discard)
+		if err != nil {
+			t.Fatalf("could not configure the provider: %v", err)
+		}
+		return data, srv.hierarchy
+	}
+}
+
+// conformanceRequest submits a request for the template through the certificate resource.
+func conformanceRequest(t *testing.T, data *providerData, template string, wait bool) (*certificateCreateModel, *certificateMaterial, diag.Diagnostics) {
+	t.Helper()
+	ctx := context.Background()
+	csr, err := debugEnrollRequest(ctx, "", "conformance.example.com")
+	if err != nil {
+		t.Fatalf("could not generate the certificate signing request: %v", err)
+	}
+
+	r := &certificateResource{client: data.client, provider: data}
+	plan := &certificateCreateModel{
+		CSR:             types.StringValue(csr),
+		Template:        types.StringValue(template),
+		WaitForIssuance: types.BoolValue(wait),
+		IssuanceTimeout: types.StringValue("1m"),
+	}
+	var diags diag.Diagnostics
+	certificates := r.requestCertificate(ctx, plan, csr, "", &diags)
+	if certificates == nil {
+		return plan, nil, diags
+	}
+	material, err := parseCertificateMaterial(certificates)
+	if err != nil {
+		t.Fatalf("request ID %s: %v", certificates.ID, err)
+	}
+	return plan, material, diags
+}
+
+func TestEnrollmentBackendConformance(t *testing.T) {
+	for _, backend := range conformanceBackends {
+		backend := backend
+		t.Run(backend.name, func(t *testing.T) {
+			data, hierarchy := backend.start(t)
+			data.poller.interval = time.Millisecond
+
+			checkIssued := func(t *testing.T, material *certificateMaterial, diags diag.Diagnostics) {
+				t.Helper()
+				if diags.HasError() || material == nil {
+					t.Fatalf("certificate was not issued: %v", diags)
+				}
+				if material.leaf.Subject.CommonName != "conformance.example.com" {
+					t.Errorf("issued common name = %q", material.leaf.Subject.CommonName)
+				}
+				if len(material.chain) != 2 || !material.chain[0].Equal(hierarchy.issuing) || !material.chain[1].Equal(hierarchy.root) {
+					t.Errorf("chain = %d certificates, want the issuing CA followed by the root", len(material.chain))
+				}
+				if material.issuingCAPEM().ValueString() != encodePEM(hierarchy.issuing) || material.rootCAPEM().ValueString() != encodePEM(hierarchy.root) {
+					t.Errorf("issuing_ca_pem and root_ca_pem do not hold the CA certificates")
+				}
+			}
+
+			t.Run("issued", func(t *testing.T) {
+				plan, material, diags := conformanceRequest(t, data, "WebServer", false)
+				checkIssued(t, material, diags)
+				if plan.RequestNonce.ValueString() == "" {
+					t.Errorf("request_nonce was not set")
+				}
+			})
+
+			t.Run("pending", func(t *testing.T) {
+				_, material, diags := conformanceRequest(t, data, accApprovalTemplate, false)
+				if material != nil || len(diags.Errors()) != 1 || diags.Errors()[0].Summary() != "Pending: Certificate Request Pending Approval" {
+					t.Fatalf("diagnostics = %v, want the request pending approval", diags)
+				}
+				if !strings.Contains(diags.Errors()[0].Detail(), "adopt_request_id") {
+					t.Errorf("detail does not explain how to take over the request: %s", diags.Errors()[0].Detail())
+				}
+			})
+
+			t.Run("approved", func(t *testing.T) {
+				_, material, diags := conformanceRequest(t, data, accApprovalTemplate, true)
+				checkIssued(t, material, diags)
+			})
+
+			t.Run("denied", func(t *testing.T) {
+				_, material, diags := conformanceRequest(t, data, accDeniedTemplate, true)
+				if material != nil || !diags.HasError() {
+					t.Fatalf("diagnostics = %v, want the denial", diags)
+				}
+				if detail := diags.Errors()[0].Detail(); !strings.Contains(detail, "Denied by Policy Module") || !strings.Contains(detail, requestNonceAttribute) {
+					t.Errorf("detail = %q, want the disposition message and the request nonce", detail)
+				}
+			})
+		})
+	}
+}
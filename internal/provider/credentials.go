@@ -0,0 +1,157 @@
+package provider
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"sort"
+	"strings"
+	"time"
+)
+
+// defaultCredentialCommandTimeout bounds how long the exec sources wait for their command.
+const defaultCredentialCommandTimeout = 30 * time.Second
+
+// credentialSource supplies the credentials the provider authenticates with, so secrets can be
+// fetched from a vault at configure time instead of passing through Terraform variables.
+type credentialSource interface {
+	// credentials returns the username and password. An empty username keeps the configured one.
+	credentials(ctx context.Context) (username string, password string, err error)
+}
+
+// credentialSourceFactory builds a credential source from credential_source_options.
+type credentialSourceFactory func(options map[string]string) (credentialSource, error)
+
+// credentialSources holds the sources credential_source can select. Sources compiled into custom
+// builds add themselves with registerCredentialSource from an init function.
+var credentialSources = map[string]credentialSourceFactory{
+	"exec": newExecCredentialSource,
+	"file": newFileCredentialSource,
+}
+
+// registerCredentialSource makes a credential source selectable by name.
+func registerCredentialSource(name string, factory credentialSourceFactory) {
+	credentialSources[name] = factory
+}
+
+// newCredentialSource looks up the named source and configures it.
+func newCredentialSource(name string, options map[string]string) (credentialSource, error) {
+	factory, ok := credentialSources[name]
+	if !ok {
+		names := make([]string, 0, len(credentialSources))
+		for name := range credentialSources {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		return nil, fmt.Errorf("unknown credential source %q, expected one of: %s", name, strings.Join(names, ", "))
+	}
+	return factory(options)
+}
+
+// credentialDocument is the JSON the exec and file sources read.
+type credentialDocument struct {
+	Username string `json:"username"`
+	Password string `json:"password"`
+}
+
+// parseCredentialDocument decodes the credentials and requires a password.
+func parseCredentialDocument(b []byte) (string, string, error) {
+	var doc credentialDocument
+	if err := json.Unmarshal(b, &doc); err != nil {
+		return "", "", fmt.Errorf("could not decode credentials: %v", err)
+	}
+	if doc.Password == "" {
+		return "", "", fmt.Errorf("the credentials do not contain a password")
+	}
+	return doc.Username, doc.Password, nil
+}
+
+// execCredentialSource runs a helper, such as a CyberArk CCP or AWS Secrets Manager client, that
+// prints the credentials as JSON on stdout.
+type execCredentialSource struct {
+	execCommand
+}
+
+func newExecCredentialSource(options map[string]string) (credentialSource, error) {
+	command, err := newExecCommand("credential", options)
+	if err != nil {
+		return nil, err
+	}
+	return &execCredentialSource{execCommand: command}, nil
+}
+
+func (s *execCredentialSource) credentials(ctx context.Context) (string, string, error) {
+	stdout, err := s.run(ctx)
+	if err != nil {
+		return "", "", err
+	}
+	return parseCredentialDocument(stdout)
+}
+
+// execCommand is the helper command of an exec source, configured by the command, args and timeout
+// options.
+type execCommand struct {
+	// kind names the source in errors, such as "credential".
+	kind    string
+	command string
+	args    []string
+	timeout time.Duration
+}
+
+func newExecCommand(kind string, options map[string]string) (execCommand, error) {
+	command := execCommand{
+		kind:    kind,
+		command: options["command"],
+		args:    strings.Fields(options["args"]),
+		timeout: defaultCredentialCommandTimeout,
+	}
+	if command.command == "" {
+		return execCommand{}, fmt.Errorf("the exec %s source requires the command option", kind)
+	}
+	if timeout, ok := options["timeout"]; ok {
+		d, err := time.ParseDuration(timeout)
+		if err != nil || d <= 0 {
+			return execCommand{}, fmt.Errorf("timeout %q is not a positive duration such as \"30s\"", timeout)
+		}
+		command.timeout = d
+	}
+	return command, nil
+}
+
+// run runs the command and returns its output.
+func (c execCommand) run(ctx context.Context) ([]byte, error) {
+	ctx, cancel := context.WithTimeout(ctx, c.timeout)
+	defer cancel()
+
+	var stdout, stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, c.command, c.args...)
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return nil, fmt.Errorf("%s command %s failed: %v: %s", c.kind, c.command, err, strings.TrimSpace(stderr.String()))
+	}
+	return stdout.Bytes(), nil
+}
+
+// fileCredentialSource reads credentials a sidecar, such as a Vault agent, keeps up to date on disk.
+type fileCredentialSource struct {
+	path string
+}
+
+func newFileCredentialSource(options map[string]string) (credentialSource, error) {
+	if options["path"] == "" {
+		return nil, fmt.Errorf("the file credential source requires the path option")
+	}
+	return &fileCredentialSource{path: options["path"]}, nil
+}
+
+func (s *fileCredentialSource) credentials(_ context.Context) (string, string, error) {
+	b, err := os.ReadFile(s.path)
+	if err != nil {
+		return "", "", fmt.Errorf("could not read credentials: %v", err)
+	}
+	return parseCredentialDocument(b)
+}
@@ -0,0 +1,73 @@
+package provider
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// staticCredentialSource hands out fixed credentials, the way a compiled in source would.
+type staticCredentialSource struct {
+	username string
+	password string
+}
+
+func (s *staticCredentialSource) credentials(_ context.Context) (string, string, error) {
+	return s.username, s.password, nil
+}
+
+func TestRegisterCredentialSource(t *testing.T) {
+	registerCredentialSource("static", func(options map[string]string) (credentialSource, error) {
+		return &staticCredentialSource{username: options["username"], password: options["password"]}, nil
+	})
+	defer delete(credentialSources, "static")
+
+	source, err := newCredentialSource("static", map[string]string{"username": "svc", "password": "secret"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	username, password, err := source.credentials(context.Background())
+	if err != nil || username != "svc" || password != "secret" {
+		t.Errorf("credentials() = %q, %q, %v", username, password, err)
+	}
+
+	if _, err := newCredentialSource("vault", nil); err == nil || !strings.Contains(err.Error(), "exec, file") {
+		t.Errorf("expected an error listing the known sources, got %v", err)
+	}
+}
+
+func TestFileAndExecCredentialSources(t *testing.T) {
+	dir := t.TempDir()
+	credentialsFile := filepath.Join(dir, "credentials.json")
+	if err := os.WriteFile(credentialsFile, []byte(`{"username": "svc", "password": "secret"}`), 0o600); err != nil {
+		t.Fatalf("could not write credentials: %v", err)
+	}
+
+	fileSource, err := newCredentialSource("file", map[string]string{"path": credentialsFile})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if username, password, err := fileSource.credentials(context.Background()); err != nil || username != "svc" || password != "secret" {
+		t.Errorf("file credentials() = %q, %q, %v", username, password, err)
+	}
+
+	execSource, err := newCredentialSource("exec", map[string]string{"command": "cat", "args": credentialsFile})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if username, password, err := execSource.credentials(context.Background()); err != nil || username != "svc" || password != "secret" {
+		t.Errorf("exec credentials() = %q, %q, %v", username, password, err)
+	}
+
+	if _, err := newCredentialSource("exec", nil); err == nil {
+		t.Errorf("expected an error without a command")
+	}
+	if err := os.WriteFile(credentialsFile, []byte(`{"username": "svc"}`), 0o600); err != nil {
+		t.Fatalf("could not write credentials: %v", err)
+	}
+	if _, _, err := fileSource.credentials(context.Background()); err == nil {
+		t.Errorf("expected an error for credentials without a password")
+	}
+}
@@ -0,0 +1,102 @@
+package provider
+
+import (
+	"context"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"fmt"
+	"strings"
+
+	"github.com/hashicorp/terraform-plugin-framework/schema/validator"
+)
+
+// parseCertificateRequest decodes a PKCS#10 certificate signing request given either as a
+// PEM block or as bare base64 encoded DER, which is what certsrv accepts as well. A PKCS#7 renewal
+// request, see request_format, yields the certificate signing request inside.
+func parseCertificateRequest(csr string) (*x509.CertificateRequest, error) {
+	if block, _ := pem.Decode([]byte(csr)); block != nil {
+		switch block.Type {
+		case "CERTIFICATE REQUEST", "NEW CERTIFICATE REQUEST":
+			return x509.ParseCertificateRequest(block.Bytes)
+		case "PKCS7":
+			request, _, err := parseRenewalRequest(block.Bytes)
+			return request, err
+		}
+		return nil, fmt.Errorf("expected a CERTIFICATE REQUEST PEM block, got %q", block.Type)
+	}
+
+	der, err := base64.StdEncoding.DecodeString(strings.Join(strings.Fields(csr), ""))
+	if err != nil {
+		return nil, fmt.Errorf("value is neither PEM nor base64 encoded DER: %v", err)
+	}
+	if isPKCS7(der) {
+		request, _, err := parseRenewalRequest(der)
+		return request, err
+	}
+	return x509.ParseCertificateRequest(der)
+}
+
+// csrSyntaxValidator checks that a certificate_signing_request attribute holds a well-formed
+// PKCS#10 request, as PEM or as base64 encoded DER, so a request mangled on its way into the
+// configuration fails the plan rather than the submission. PKCS#7 renewal requests are left to
+// validateRequestFormat.
+type csrSyntaxValidator struct{}
+
+var _ validator.String = csrSyntaxValidator{}
+
+func (v csrSyntaxValidator) Description(_ context.Context) string {
+	return "value must be a PEM or base64 encoded DER PKCS#10 certificate signing request"
+}
+
+func (v csrSyntaxValidator) MarkdownDescription(ctx context.Context) string {
+	return v.Description(ctx)
+}
+
+func (v csrSyntaxValidator) ValidateString(_ context.Context, req validator.StringRequest, resp *validator.StringResponse) {
+	if req.ConfigValue.IsNull() || req.ConfigValue.IsUnknown() {
+		return
+	}
+	if err := checkRequestSyntax(req.ConfigValue.ValueString()); err != nil {
+		resp.Diagnostics.AddAttributeError(
+			req.Path,
+			"Invalid Certificate Signing Request",
+			fmt.Sprintf("%s is not a PEM or base64 encoded DER PKCS#10 certificate signing request: %s.", req.Path, err.Error()),
+		)
+	}
+}
+
+// checkRequestSyntax returns why csr is not a certificate signing request certsrv would accept.
+func checkRequestSyntax(csr string) error {
+	var der []byte
+	if block, _ := pem.Decode([]byte(csr)); block != nil {
+		switch block.Type {
+		case "CERTIFICATE REQUEST", "NEW CERTIFICATE REQUEST":
+			der = block.Bytes
+		case "PKCS7":
+			return nil
+		default:
+			return fmt.Errorf("the PEM block is a %s rather than a CERTIFICATE REQUEST", block.Type)
+		}
+	} else {
+		if strings.Contains(csr, "-----BEGIN") {
+			return fmt.Errorf("the PEM block is malformed, check that its BEGIN and END lines match and that its line breaks were kept")
+		}
+		var err error
+		if der, err = base64.StdEncoding.DecodeString(strings.Join(strings.Fields(csr), "")); err != nil {
+			return fmt.Errorf("the value is neither PEM nor base64: %v", err)
+		}
+	}
+	if isPKCS7(der) {
+		return nil
+	}
+
+	request, err := x509.ParseCertificateRequest(der)
+	if err != nil {
+		return fmt.Errorf("could not parse the request: %v", err)
+	}
+	if err := request.CheckSignature(); err != nil {
+		return fmt.Errorf("its signature does not verify: %v", err)
+	}
+	return nil
+}
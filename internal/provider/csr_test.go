@@ -0,0 +1,50 @@
+package provider
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/pem"
+	"strings"
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-framework/path"
+	"github.com/hashicorp/terraform-plugin-framework/schema/validator"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+)
+
+func TestCSRSyntaxValidator(t *testing.T) {
+	ctx := context.Background()
+	csr, err := debugEnrollRequest(ctx, "", "app.example.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+	block, _ := pem.Decode([]byte(csr))
+	tampered := append([]byte(nil), block.Bytes...)
+	tampered[len(tampered)-1] ^= 0xff
+	h := newTestHierarchy(t)
+
+	for name, tc := range map[string]struct {
+		value types.String
+		want  string
+	}{
+		"pem":          {value: types.StringValue(csr)},
+		"base64 der":   {value: types.StringValue(base64.StdEncoding.EncodeToString(block.Bytes))},
+		"null":         {value: types.StringNull()},
+		"unknown":      {value: types.StringUnknown()},
+		"certificate":  {value: types.StringValue(encodePEM(h.leaf)), want: "PEM block is a CERTIFICATE"},
+		"one line pem": {value: types.StringValue(strings.ReplaceAll(csr, "\n", " ")), want: "line breaks"},
+		"not base64":   {value: types.StringValue("not a request"), want: "neither PEM nor base64"},
+		"truncated":    {value: types.StringValue(base64.StdEncoding.EncodeToString(block.Bytes[:40])), want: "could not parse"},
+		"tampered":     {value: types.StringValue(base64.StdEncoding.EncodeToString(tampered)), want: "signature does not verify"},
+	} {
+		req := validator.StringRequest{Path: path.Root("certificate_signing_request"), ConfigValue: tc.value}
+		resp := &validator.StringResponse{}
+		csrSyntaxValidator{}.ValidateString(ctx, req, resp)
+		switch {
+		case tc.want == "" && resp.Diagnostics.HasError():
+			t.Errorf("%s: ValidateString() = %v", name, resp.Diagnostics)
+		case tc.want != "" && (!resp.Diagnostics.HasError() || !strings.Contains(resp.Diagnostics.Errors()[0].Detail(), tc.want)):
+			t.Errorf("%s: ValidateString() = %v, want an error mentioning %q", name, resp.Diagnostics, tc.want)
+		}
+	}
+}
@@ -0,0 +1,143 @@
+package provider
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/hashicorp/terraform-plugin-framework/diag"
+)
+
+// custodyPrivateStateKey is the private state key holding the chain-of-custody record.
+const custodyPrivateStateKey = "custody"
+
+// privateState is the part of the framework private state API the custody record needs. It is
+// satisfied by the private state of both requests and responses.
+type privateState interface {
+	GetKey(ctx context.Context, key string) ([]byte, diag.Diagnostics)
+}
+
+// privateStateSetter is the writable counterpart of privateState, satisfied by response private state.
+type privateStateSetter interface {
+	SetKey(ctx context.Context, key string, value []byte) diag.Diagnostics
+}
+
+// custodyRecord binds a certificate to the signing request it was issued for. Private state is not
+// shown in plans, so a hand edit of the certificate, the CSR or the request ID in the state file
+// no longer matches the hash. This is tamper evidence, not protection: someone who can rewrite the
+// private state as well can forge a new record.
+type custodyRecord struct {
+	Salt string `json:"salt"`
+	Hash string `json:"hash"`
+}
+
+// newCustodyRecord hashes the certificate serial and the CSR with a fresh random salt.
+func newCustodyRecord(certB64 string, csr string) (*custodyRecord, error) {
+	salt := make([]byte, 16)
+	if _, err := rand.Read(salt); err != nil {
+		return nil, fmt.Errorf("could not generate salt: %v", err)
+	}
+	hash, err := custodyHash(salt, certB64, csr)
+	if err != nil {
+		return nil, err
+	}
+	return &custodyRecord{Salt: hex.EncodeToString(salt), Hash: hash}, nil
+}
+
+// verify reports whether the certificate and CSR are the ones the record was made for.
+func (c *custodyRecord) verify(certB64 string, csr string) (bool, error) {
+	salt, err := hex.DecodeString(c.Salt)
+	if err != nil {
+		return false, fmt.Errorf("invalid salt in custody record: %v", err)
+	}
+	hash, err := custodyHash(salt, certB64, csr)
+	if err != nil {
+		return false, err
+	}
+	return subtle.ConstantTimeCompare([]byte(hash), []byte(c.Hash)) == 1, nil
+}
+
+// custodyHash is SHA-256 over the salt, the certificate serial and the DER encoded CSR. The CSR is
+// hashed in its DER form so line endings or PEM headers do not matter.
+func custodyHash(salt []byte, certB64 string, csr string) (string, error) {
+	cert, err := parseCertificate(certB64)
+	if err != nil {
+		return "", fmt.Errorf("could not parse certificate: %v", err)
+	}
+	csrBytes := []byte(strings.TrimSpace(csr))
+	if request, err := parseCertificateRequest(csr); err == nil {
+		csrBytes = request.Raw
+	}
+
+	h := sha256.New()
+	h.Write(salt)
+	h.Write(cert.SerialNumber.Bytes())
+	h.Write(csrBytes)
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// readCustodyRecord returns the stored record, or nil for resources created before records were kept.
+func readCustodyRecord(ctx context.Context, private privateState) (*custodyRecord, diag.Diagnostics) {
+	raw, diags := private.GetKey(ctx, custodyPrivateStateKey)
+	if diags.HasError() || len(raw) == 0 {
+		return nil, diags
+	}
+
+	var record custodyRecord
+	if err := json.Unmarshal(raw, &record); err != nil {
+		diags.AddError(
+			"Invalid Certificate Custody Record",
+			"The chain-of-custody record in private state could not be decoded: "+err.Error(),
+		)
+		return nil, diags
+	}
+	return &record, diags
+}
+
+// writeCustodyRecord stores a new record for the certificate and CSR.
+func writeCustodyRecord(ctx context.Context, private privateStateSetter, certB64 string, csr string) diag.Diagnostics {
+	var diags diag.Diagnostics
+
+	record, err := newCustodyRecord(certB64, csr)
+	if err != nil {
+		diags.AddWarning(
+			"Unable to Record Certificate Custody",
+			"The chain-of-custody hash could not be computed, tampering with this certificate in state will not be detected: "+err.Error(),
+		)
+		return diags
+	}
+	raw, err := json.Marshal(record)
+	if err != nil {
+		diags.AddError("Unable to Record Certificate Custody", err.Error())
+		return diags
+	}
+	return private.SetKey(ctx, custodyPrivateStateKey, raw)
+}
+
+// checkCustody fails when the certificate and CSR do not match the stored record.
+func checkCustody(record *custodyRecord, reqID string, certB64 string, csr string, source string) diag.Diagnostics {
+	var diags diag.Diagnostics
+
+	ok, err := record.verify(certB64, csr)
+	if err != nil {
+		diags.AddError(
+			"Certificate Chain of Custody Check Failed",
+			fmt.Sprintf("Could not verify the %s for request ID %s against its custody record: %s", source, reqID, err.Error()),
+		)
+		return diags
+	}
+	if !ok {
+		diags.AddError(
+			"Certificate Chain of Custody Mismatch",
+			fmt.Sprintf("The %s for request ID %s does not match the certificate and signing request recorded when it was issued. "+
+				"The state may have been edited by hand to swap certificates. Inspect the state history before trusting this certificate; "+
+				"if the change is legitimate, taint the resource to issue a new certificate.", source, reqID),
+		)
+	}
+	return diags
+}
@@ -0,0 +1,44 @@
+package provider
+
+import (
+	"encoding/pem"
+	"testing"
+)
+
+func TestCustodyRecord(t *testing.T) {
+	h := newTestHierarchy(t)
+	leaf := string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: h.leaf.Raw}))
+	other := string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: h.issuing.Raw}))
+
+	record, err := newCustodyRecord(leaf, csr)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	tests := []struct {
+		name    string
+		certB64 string
+		csr     string
+		want    bool
+	}{
+		{"unchanged", leaf, csr, true},
+		{"swapped certificate", other, csr, false},
+		{"swapped csr", leaf, "", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := record.verify(tt.certB64, tt.csr)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("verify() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+
+	again, _ := newCustodyRecord(leaf, csr)
+	if again.Hash == record.Hash {
+		t.Errorf("records for the same certificate should not share a hash")
+	}
+}
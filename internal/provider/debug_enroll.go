@@ -0,0 +1,266 @@
+package provider
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/flipyap/microsoft-adcs-client/client"
+	"github.com/hashicorp/go-hclog"
+	"github.com/hashicorp/terraform-plugin-framework/diag"
+	"github.com/hashicorp/terraform-plugin-framework/provider"
+	"github.com/hashicorp/terraform-plugin-framework/tfsdk"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+	"github.com/hashicorp/terraform-plugin-go/tftypes"
+	"github.com/hashicorp/terraform-plugin-log/tflog"
+	"github.com/hashicorp/terraform-plugin-log/tfsdklog"
+)
+
+// debugEnrollUsage introduces the flags of debug-enroll.
+const debugEnrollUsage = `Usage: terraform-provider-microsoft-adcs debug-enroll -template NAME [flags]
+
+Requests a single certificate the way the microsoftadcs_certificate resource does, outside of Terraform, and
+logs every step and HTTP exchange to stderr. The provider is configured from the ADCS_* environment variables
+like the provider block; the password is only read from ADCS_PASSWORD. The issued certificate is written to stdout.
+
+Flags:
+`
+
+// attributeFlags collects repeated -attribute flags.
+type attributeFlags []string
+
+func (a *attributeFlags) String() string { return strings.Join(*a, ", ") }
+
+func (a *attributeFlags) Set(value string) error {
+	if !strings.Contains(value, ":") {
+		return fmt.Errorf("%q is not a name:value request attribute", value)
+	}
+	*a = append(*a, value)
+	return nil
+}
+
+// DebugEnroll runs the debug-enroll mode of the provider binary: one enrollment through the code
+// the provider uses, with verbose logging, so CA and authentication problems can be reproduced and
+// reported without Terraform in between.
+func DebugEnroll(ctx context.Context, version string, args []string, stdout io.Writer, stderr io.Writer) error {
+	flags := flag.NewFlagSet("debug-enroll", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	flags.Usage = func() {
+		fmt.Fprint(stderr, debugEnrollUsage)
+		flags.PrintDefaults()
+	}
+	host := flags.String("host", "", "certsrv host, defaults to ADCS_HOST")
+	username := flags.String("username", "", "user to authenticate as, defaults to ADCS_USERNAME")
+	ntlm := flags.Bool("ntlm", false, "authenticate with NTLM instead of Kerberos")
+	krb5conf := flags.String("krb5conf", "", "path or content of the Kerberos configuration, defaults to ADCS_KRB5CONF")
+	template := flags.String("template", "", "certificate template to request, required")
+	csrPath := flags.String("csr", "", "file holding the PEM certificate signing request to submit, generated for -cn when not set")
+	commonName := flags.String("cn", "debug-enroll.invalid", "common name of the generated certificate signing request")
+	level := flags.String("log-level", "debug", "log level: trace, debug, info, warn or error")
+	var attributes attributeFlags
+	flags.Var(&attributes, "attribute", "extra name:value request attribute, may be repeated")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if *template == "" {
+		flags.Usage()
+		return errors.New("-template is required")
+	}
+
+	logLevel := hclog.LevelFromString(*level)
+	if logLevel == hclog.NoLevel {
+		return fmt.Errorf("-log-level %q is not one of trace, debug, info, warn or error", *level)
+	}
+	ctx = tfsdklog.NewRootProviderLogger(ctx, tfsdklog.WithLevel(logLevel), tfsdklog.WithoutLocation())
+
+	csr, err := debugEnrollRequest(ctx, *csrPath, *commonName)
+	if err != nil {
+		return err
+	}
+
+	settings := map[string]interface{}{"use_ntlm": *ntlm}
+	for name, value := range map[string]string{"host": *host, "username": *username, "krb5conf": *krb5conf} {
+		if value != "" {
+			settings[name] = value
+		}
+	}
+	data, err := debugEnrollConfigure(ctx, version, settings, stderr)
+	if err != nil {
+		return err
+	}
+	traceRequests(data.client)
+
+	nonce, err := newRequestNonce()
+	if err != nil {
+		return err
+	}
+	tflog.Info(ctx, "Submitting certificate request", map[string]interface{}{
+		"host":       data.client.HostURL,
+		"template":   *template,
+		"attributes": strings.Join(attributes, ", "),
+		"nonce":      nonce,
+	})
+	certificates, err := submitCertificateRequest(ctx, data.client, data.parser, certsrvSubmission{
+		CSR:        csr,
+		Template:   *template,
+		Attributes: append(attributes, requestNonceAttribute+":"+nonce),
+		Chunked:    data.chunkedSubmissions,
+	})
+	if err != nil {
+		if reqID, pending := pendingRequestID(err); pending {
+			return fmt.Errorf("request ID %s is pending CA manager approval", reqID)
+		}
+		return fmt.Errorf("enrollment failed: %v", err)
+	}
+
+	cert, err := parseCertificate(certificates.CertificateB64)
+	if err != nil {
+		return fmt.Errorf("request ID %s was issued but the certificate could not be parsed: %v", certificates.ID, err)
+	}
+	tflog.Info(ctx, "Certificate issued", map[string]interface{}{
+		"request_id": certificates.ID,
+		"subject":    cert.Subject.String(),
+		"issuer":     cert.Issuer.String(),
+		"serial":     fmt.Sprintf("%X", cert.SerialNumber),
+		"not_before": cert.NotBefore.UTC().Format(time.RFC3339),
+		"not_after":  cert.NotAfter.UTC().Format(time.RFC3339),
+	})
+	_, err = io.WriteString(stdout, encodePEM(cert))
+	return err
+}
+
+// debugEnrollRequest reads the certificate signing request from path, or generates one for the
+// common name with a throwaway ECDSA key.
+func debugEnrollRequest(ctx context.Context, path string, commonName string) (string, error) {
+	if path != "" {
+		b, err := os.ReadFile(path)
+		if err != nil {
+			return "", fmt.Errorf("could not read the certificate signing request: %v", err)
+		}
+		if _, err := parseCertificateRequest(string(b)); err != nil {
+			return "", fmt.Errorf("%s does not hold a certificate signing request: %v", path, err)
+		}
+		return string(b), nil
+	}
+
+	block := generateCSRModel{
+		KeyAlgorithm:   types.StringValue(keyAlgorithmECDSA),
+		CommonName:     types.StringValue(commonName),
+		DNSNames:       types.ListNull(types.StringType),
+		IPAddresses:    types.ListNull(types.StringType),
+		EmailAddresses: types.ListNull(types.StringType),
+	}
+	csr, _, diags := block.generate(ctx)
+	if diags.HasError() {
+		return "", diagnosticsError(diags)
+	}
+	tflog.Debug(ctx, "Generated certificate signing request", map[string]interface{}{"common_name": commonName})
+	return csr, nil
+}
+
+// debugEnrollConfigure configures the provider the way Terraform would, with the given settings of
+// the provider block and everything else from the environment.
+func debugEnrollConfigure(ctx context.Context, version string, settings map[string]interface{}, stderr io.Writer) (*providerData, error) {
+	p := &MicrosoftADCSProvider{version: version}
+	var schemaResp provider.SchemaResponse
+	p.Schema(ctx, provider.SchemaRequest{}, &schemaResp)
+
+	objectType, ok := schemaResp.Schema.Type().TerraformType(ctx).(tftypes.Object)
+	if !ok {
+		return nil, errors.New("the provider schema is not an object")
+	}
+	values := make(map[string]tftypes.Value, len(objectType.AttributeTypes))
+	for name, attributeType := range objectType.AttributeTypes {
+		values[name] = tftypes.NewValue(attributeType, settings[name])
+	}
+
+	var resp provider.ConfigureResponse
+	p.Configure(ctx, provider.ConfigureRequest{
+		Config: tfsdk.Config{Schema: schemaResp.Schema, Raw: tftypes.NewValue(objectType, values)},
+	}, &resp)
+	for _, d := range resp.Diagnostics {
+		fmt.Fprintf(stderr, "%s: %s\n\n%s\n\n", d.Severity(), d.Summary(), d.Detail())
+	}
+	if resp.Diagnostics.HasError() {
+		return nil, errors.New("the provider could not be configured")
+	}
+	data, ok := resp.ResourceData.(*providerData)
+	if !ok {
+		return nil, errors.New("the provider did not return its configuration")
+	}
+	return data, nil
+}
+
+// traceRequests logs every HTTP exchange with the CA, authentication challenges included. Only the
+// authentication schemes are logged, never the tokens.
+func traceRequests(c *client.ADCSClient) {
+	var httpClient *http.Client
+	if c.UseNtlm {
+		httpClient = c.NtlmClient
+	} else if c.SpnegoClient != nil {
+		httpClient = c.SpnegoClient.Client
+	}
+	if httpClient == nil {
+		return
+	}
+	if pool, ok := httpClient.Transport.(*ntlmConnectionPool); ok {
+		pool.trace = true
+		return
+	}
+	next := httpClient.Transport
+	if next == nil {
+		next = http.DefaultTransport
+	}
+	httpClient.Transport = tracingTransport{next: next}
+}
+
+// tracingTransport logs the requests passing through it.
+type tracingTransport struct {
+	next http.RoundTripper
+}
+
+func (t tracingTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	ctx := req.Context()
+	start := time.Now()
+	fields := map[string]interface{}{
+		"method":         req.Method,
+		"url":            req.URL.String(),
+		"authentication": authenticationScheme(req.Header.Get("Authorization")),
+	}
+	resp, err := t.next.RoundTrip(req)
+	fields["duration"] = time.Since(start).String()
+	if err != nil {
+		fields["error"] = err.Error()
+		tflog.Debug(ctx, "HTTP request failed", fields)
+		return nil, err
+	}
+	fields["status"] = resp.StatusCode
+	var challenges []string
+	for _, challenge := range resp.Header.Values("WWW-Authenticate") {
+		challenges = append(challenges, authenticationScheme(challenge))
+	}
+	fields["challenges"] = strings.Join(challenges, ", ")
+	tflog.Debug(ctx, "HTTP request", fields)
+	return resp, nil
+}
+
+// authenticationScheme returns the scheme of an Authorization or WWW-Authenticate header without its token.
+func authenticationScheme(header string) string {
+	scheme, _, _ := strings.Cut(header, " ")
+	return scheme
+}
+
+// diagnosticsError joins the error diagnostics into one error.
+func diagnosticsError(diags diag.Diagnostics) error {
+	var messages []string
+	for _, d := range diags.Errors() {
+		messages = append(messages, d.Summary()+": "+d.Detail())
+	}
+	return errors.New(strings.Join(messages, "; "))
+}
@@ -0,0 +1,80 @@
+package provider
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+)
+
+func TestDebugEnroll(t *testing.T) {
+	srv := newAccCertsrv(t)
+	t.Setenv("ADCS_PASSWORD", "secret")
+
+	var stdout, stderr bytes.Buffer
+	err := DebugEnroll(context.Background(), "test", []string{
+		"-host", srv.host(), "-username", "svc-terraform", "-ntlm", "-template", "WebServer",
+		"-cn", "debug.example.com", "-attribute", "Owner:platform", "-log-level", "error",
+	}, &stdout, &stderr)
+	if err != nil {
+		t.Fatalf("DebugEnroll() = %v, stderr: %s", err, stderr.String())
+	}
+
+	cert, err := parseCertificate(stdout.String())
+	if err != nil {
+		t.Fatalf("stdout does not hold the issued certificate: %v", err)
+	}
+	if cert.Subject.CommonName != "debug.example.com" {
+		t.Errorf("issued common name = %q", cert.Subject.CommonName)
+	}
+	if len(srv.requests) != 1 {
+		t.Fatalf("got %d submissions, want 1", len(srv.requests))
+	}
+	for _, req := range srv.requests {
+		if !strings.Contains(strings.Join(req.attributes, "\n"), "Owner:platform") {
+			t.Errorf("the -attribute flag was not submitted: %q", req.attributes)
+		}
+	}
+}
+
+func TestDebugEnrollKerberos(t *testing.T) {
+	srv := newAccCertsrvWithAuth(t, accAuthKerberos)
+	args := []string{
+		"-host", srv.host(), "-username", accKerberosUser, "-krb5conf", srv.kdc.krb5conf(), "-template", "WebServer",
+		"-cn", "debug.example.com", "-log-level", "error",
+	}
+
+	t.Setenv("ADCS_PASSWORD", accKerberosPassword)
+	var stdout, stderr bytes.Buffer
+	if err := DebugEnroll(context.Background(), "test", args, &stdout, &stderr); err != nil {
+		t.Fatalf("DebugEnroll() = %v, stderr: %s", err, stderr.String())
+	}
+	if _, err := parseCertificate(stdout.String()); err != nil {
+		t.Fatalf("stdout does not hold the issued certificate: %v", err)
+	}
+
+	// The KDC answers, but the client cannot decrypt the reply with the wrong password.
+	t.Setenv("ADCS_PASSWORD", "wrong")
+	stdout.Reset()
+	stderr.Reset()
+	if err := DebugEnroll(context.Background(), "test", args, &stdout, &stderr); err == nil {
+		t.Fatal("DebugEnroll() succeeded with the wrong password")
+	}
+	if !strings.Contains(stderr.String(), errorClassAuth+": ") {
+		t.Errorf("the login failure is not reported as %s: %s", errorClassAuth, stderr.String())
+	}
+}
+
+func TestDebugEnrollFlags(t *testing.T) {
+	for name, args := range map[string][]string{
+		"no template":       {},
+		"invalid attribute": {"-template", "WebServer", "-attribute", "Owner"},
+		"invalid log level": {"-template", "WebServer", "-log-level", "loud"},
+		"missing csr file":  {"-template", "WebServer", "-csr", "/nonexistent/request.csr"},
+	} {
+		var stdout, stderr bytes.Buffer
+		if err := DebugEnroll(context.Background(), "test", args, &stdout, &stderr); err == nil {
+			t.Errorf("%s: expected an error", name)
+		}
+	}
+}
@@ -0,0 +1,54 @@
+package provider
+
+import (
+	"context"
+	"fmt"
+)
+
+// denialRequest is the JSON body posted to the denial webhook. The web enrollment pages cannot deny
+// requests, so the webhook has to do it through the CA administration interface, for example by
+// running certutil -deny <request_id> on the CA.
+type denialRequest struct {
+	Event     string `json:"event"`
+	RequestID string `json:"request_id"`
+	Host      string `json:"host"`
+	CAName    string `json:"ca_name,omitempty"`
+	Reason    string `json:"reason,omitempty"`
+}
+
+// denyRequest asks the configured denial webhook to deny the pending request on the CA.
+func (p *providerData) denyRequest(ctx context.Context, payload denialRequest) error {
+	if p.denialWebhookURL == "" {
+		return fmt.Errorf("denying requests requires denial_webhook_url to be configured on the provider")
+	}
+	payload.Event = "deny"
+	return postWebhook(ctx, p.denialWebhookURL, p.denialWebhookHeaders, payload)
+}
+
+// requestPending asks the certsrv server at host, the configured one when empty, whether the request
+// is still pending. It fails when the CA did not answer for the request, a request is only denied
+// once the CA confirmed it is still waiting.
+func (p *providerData) requestPending(ctx context.Context, host, caName, reqID string) (bool, error) {
+	disposition, err := p.currentDisposition(ctx, host, caName, reqID)
+	if err != nil {
+		return false, err
+	}
+	return disposition == dispositionPending, nil
+}
+
+// currentDisposition returns the disposition the certsrv server at host, the configured one when
+// empty, reports for the request: issued when it hands out the certificate, otherwise that of
+// requestDisposition.
+func (p *providerData) currentDisposition(ctx context.Context, host, caName, reqID string) (string, error) {
+	route := p.newCARoute(caName)
+	route.endpoint = host
+	_, err := retrieveCertificate(withCARoute(ctx, route), p.client, p.parser, reqID)
+	if err == nil {
+		return dispositionIssued, nil
+	}
+	disposition, ok := requestDisposition(err)
+	if !ok {
+		return "", err
+	}
+	return disposition, nil
+}
@@ -0,0 +1,63 @@
+package provider
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/hashicorp/terraform-plugin-framework/path"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/planmodifier"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/stringplanmodifier"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+)
+
+// deprecation describes an attribute that is replaced by another one. Both keep working until the
+// sunset version, which removes the deprecated attribute. Terraform only warns about deprecated
+// attributes set in configuration, so computed ones are announced in the documentation and the
+// changelog.
+type deprecation struct {
+	replacement string
+	sunset      string
+}
+
+// deprecations lists every deprecated attribute of the provider, keyed by attribute name.
+var deprecations = map[string]deprecation{
+	"attributes":      {replacement: "request_attributes", sunset: "1.0.0"},
+	"certificate_b64": {replacement: "certificate_pem", sunset: "1.0.0"},
+}
+
+// deprecationMessage returns the DeprecationMessage of a deprecated attribute.
+func deprecationMessage(attribute string) string {
+	d, ok := deprecations[attribute]
+	if !ok {
+		panic(fmt.Sprintf("attribute %q is not deprecated", attribute))
+	}
+	return fmt.Sprintf("Use %s instead, %s will be removed in version %s of the provider.", d.replacement, attribute, d.sunset)
+}
+
+// coalesceString returns the first value that is set.
+func coalesceString(values ...types.String) types.String {
+	for _, value := range values {
+		if !value.IsNull() {
+			return value
+		}
+	}
+	return types.StringNull()
+}
+
+// aliasRequiresReplace replaces the resource when the effective value of an attribute and its
+// alias changes, but not when a configuration merely moves the value from one to the other.
+func aliasRequiresReplace(alias string) planmodifier.String {
+	return stringplanmodifier.RequiresReplaceIf(
+		func(ctx context.Context, req planmodifier.StringRequest, resp *stringplanmodifier.RequiresReplaceIfFuncResponse) {
+			var planAlias, stateAlias types.String
+			resp.Diagnostics.Append(req.Plan.GetAttribute(ctx, path.Root(alias), &planAlias)...)
+			resp.Diagnostics.Append(req.State.GetAttribute(ctx, path.Root(alias), &stateAlias)...)
+			if resp.Diagnostics.HasError() {
+				return
+			}
+			resp.RequiresReplace = !coalesceString(req.PlanValue, planAlias).Equal(coalesceString(req.StateValue, stateAlias))
+		},
+		"Replaces the resource when the value changes, moving it to "+alias+" keeps the resource.",
+		"Replaces the resource when the value changes, moving it to `"+alias+"` keeps the resource.",
+	)
+}
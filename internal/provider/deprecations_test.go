@@ -0,0 +1,15 @@
+package provider
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestDeprecationMessage(t *testing.T) {
+	for attribute, d := range deprecations {
+		message := deprecationMessage(attribute)
+		if !strings.Contains(message, d.replacement) || !strings.Contains(message, d.sunset) {
+			t.Errorf("deprecation message of %s does not name the replacement and sunset version: %s", attribute, message)
+		}
+	}
+}
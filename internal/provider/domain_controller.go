@@ -0,0 +1,179 @@
+package provider
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/hashicorp/terraform-plugin-framework/attr"
+	"github.com/hashicorp/terraform-plugin-framework/diag"
+	"github.com/hashicorp/terraform-plugin-framework/path"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+	"github.com/hashicorp/terraform-plugin-framework/types/basetypes"
+)
+
+// domainControllerProfile is the profile the domain_controller block implies.
+const domainControllerProfile = "domain_controller"
+
+// maxNetBIOSNameLength is the longest NetBIOS domain name Windows accepts.
+const maxNetBIOSNameLength = 15
+
+// domainControllerModel maps the domain_controller block.
+type domainControllerModel struct {
+	HostName    types.String `tfsdk:"host_name"`
+	Domain      types.String `tfsdk:"domain"`
+	NetBIOSName types.String `tfsdk:"netbios_name"`
+}
+
+// hostFQDN returns the fully qualified name of the domain controller, host_name qualified with the
+// domain when it is a single label.
+func (m domainControllerModel) hostFQDN() string {
+	host := m.HostName.ValueString()
+	if !strings.Contains(host, ".") {
+		host += "." + m.Domain.ValueString()
+	}
+	return host
+}
+
+// dnsNames returns the DNS names the Kerberos Authentication template puts in the subject
+// alternative names of a domain controller: its own name, the DNS name of the domain and the
+// NetBIOS name of the domain. Clients doing strict KDC validation, Windows Hello for Business key
+// trust among them, look for the domain names. Names that are not valid are left out,
+// validateDomainController reports them.
+func (m domainControllerModel) dnsNames() []string {
+	candidates := []string{m.hostFQDN(), m.Domain.ValueString()}
+	if !m.NetBIOSName.IsNull() {
+		candidates = append(candidates, m.NetBIOSName.ValueString())
+	}
+	var names []string
+	for _, name := range candidates {
+		if normalized, err := normalizeDNSName(name); err == nil {
+			names = append(names, normalized)
+		}
+	}
+	return names
+}
+
+// domainController returns the domain_controller block, false when it is not set or not known yet.
+func (m *certificateCreateModel) domainController(ctx context.Context) (domainControllerModel, bool) {
+	var block domainControllerModel
+	if m.DomainController.IsNull() || m.DomainController.IsUnknown() {
+		return block, false
+	}
+	if diags := m.DomainController.As(ctx, &block, basetypes.ObjectAsOptions{}); diags.HasError() {
+		return block, false
+	}
+	if block.HostName.IsUnknown() || block.Domain.IsUnknown() || block.NetBIOSName.IsUnknown() {
+		return block, false
+	}
+	return block, true
+}
+
+// withDomainControllerNames adds the DNS names of the domain_controller block to the dns entry of
+// the subject alternative names, leaving out those already listed. The names are unknown while the
+// block is.
+func (m *certificateCreateModel) withDomainControllerNames(names types.Object) types.Object {
+	if m.DomainController.IsNull() || names.IsUnknown() {
+		return names
+	}
+	block, ok := m.domainController(context.Background())
+	if !ok {
+		return types.ObjectUnknown(subjectAlternativeNamesAttrTypes)
+	}
+
+	values := map[string]attr.Value{}
+	for entry := range subjectAlternativeNamesAttrTypes {
+		values[entry] = types.ListNull(types.StringType)
+	}
+	if !names.IsNull() {
+		for entry, value := range names.Attributes() {
+			values[entry] = value
+		}
+	}
+	dns, ok := values["dns"].(types.List)
+	if !ok || dns.IsUnknown() {
+		return types.ObjectUnknown(subjectAlternativeNamesAttrTypes)
+	}
+
+	listed := map[string]bool{}
+	elements := append([]attr.Value{}, dns.Elements()...)
+	for _, element := range elements {
+		if value, ok := element.(types.String); ok {
+			if normalized, err := normalizeDNSName(value.ValueString()); err == nil {
+				listed[strings.ToLower(normalized)] = true
+			}
+		}
+	}
+	for _, name := range block.dnsNames() {
+		if !listed[strings.ToLower(name)] {
+			listed[strings.ToLower(name)] = true
+			elements = append(elements, types.StringValue(name))
+		}
+	}
+	values["dns"] = types.ListValueMust(types.StringType, elements)
+	return types.ObjectValueMust(subjectAlternativeNamesAttrTypes, values)
+}
+
+// applyDomainController makes the name of the domain controller the common name of a generated
+// request that does not set one.
+func (m *certificateCreateModel) applyDomainController(ctx context.Context, block *generateCSRModel) {
+	if dc, ok := m.domainController(ctx); ok && block.CommonName.IsNull() {
+		block.CommonName = types.StringValue(dc.hostFQDN())
+	}
+}
+
+// validateDomainController checks the names of the domain_controller block and that it is not
+// combined with a profile other than domain_controller.
+func validateDomainController(ctx context.Context, config certificateCreateModel) diag.Diagnostics {
+	var diags diag.Diagnostics
+	if config.DomainController.IsNull() || config.DomainController.IsUnknown() {
+		return diags
+	}
+	if !config.Profile.IsNull() && !config.Profile.IsUnknown() && config.Profile.ValueString() != domainControllerProfile {
+		diags.AddAttributeError(
+			path.Root("profile"),
+			"Conflicting Profile",
+			fmt.Sprintf("The domain_controller block uses the %q profile, remove profile or set it to %q.", domainControllerProfile, domainControllerProfile),
+		)
+	}
+
+	var block domainControllerModel
+	diags.Append(config.DomainController.As(ctx, &block, basetypes.ObjectAsOptions{})...)
+	if diags.HasError() {
+		return diags
+	}
+	blockPath := path.Root("domain_controller")
+	for _, name := range []struct {
+		attribute string
+		value     types.String
+	}{
+		{attribute: "host_name", value: block.HostName},
+		{attribute: "domain", value: block.Domain},
+	} {
+		if name.value.IsUnknown() {
+			continue
+		}
+		if name.value.IsNull() {
+			diags.AddAttributeError(blockPath.AtName(name.attribute), "Missing Domain Controller Name",
+				fmt.Sprintf("%s is required in the domain_controller block.", name.attribute))
+			continue
+		}
+		if strings.HasPrefix(name.value.ValueString(), "*.") {
+			diags.AddAttributeError(blockPath.AtName(name.attribute), "Invalid Domain Controller Name",
+				fmt.Sprintf("%q is a wildcard, domain controller certificates name the domain controller and the domain.", name.value.ValueString()))
+			continue
+		}
+		if _, err := normalizeDNSName(name.value.ValueString()); err != nil {
+			diags.AddAttributeError(blockPath.AtName(name.attribute), "Invalid Domain Controller Name", err.Error()+".")
+		}
+	}
+
+	if netbios := block.NetBIOSName; !netbios.IsNull() && !netbios.IsUnknown() {
+		name := netbios.ValueString()
+		if _, err := normalizeDNSName(name); err != nil || name == "" || len(name) > maxNetBIOSNameLength || strings.Contains(name, ".") {
+			diags.AddAttributeError(blockPath.AtName("netbios_name"), "Invalid Domain Controller Name",
+				fmt.Sprintf("%q is not a NetBIOS domain name, which has 1 to %d letters, digits and hyphens.", name, maxNetBIOSNameLength))
+		}
+	}
+	return diags
+}
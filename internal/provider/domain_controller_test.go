@@ -0,0 +1,98 @@
+package provider
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-framework/attr"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+)
+
+// domainControllerBlock builds a domain_controller block.
+func domainControllerBlock(hostName, domain, netbiosName types.String) types.Object {
+	return types.ObjectValueMust(map[string]attr.Type{
+		"host_name":    types.StringType,
+		"domain":       types.StringType,
+		"netbios_name": types.StringType,
+	}, map[string]attr.Value{
+		"host_name":    hostName,
+		"domain":       domain,
+		"netbios_name": netbiosName,
+	})
+}
+
+func TestValidateDomainController(t *testing.T) {
+	ctx := context.Background()
+	dc := func(hostName, domain, netbiosName string) types.Object {
+		optional := func(value string) types.String {
+			if value == "" {
+				return types.StringNull()
+			}
+			return types.StringValue(value)
+		}
+		return domainControllerBlock(optional(hostName), optional(domain), optional(netbiosName))
+	}
+
+	for name, tc := range map[string]struct {
+		config certificateCreateModel
+		want   string
+	}{
+		"unset":            {},
+		"fqdn":             {config: certificateCreateModel{DomainController: dc("dc01.corp.example.com", "corp.example.com", "CORP")}},
+		"single label":     {config: certificateCreateModel{DomainController: dc("dc01", "corp.example.com", "")}},
+		"same profile":     {config: certificateCreateModel{DomainController: dc("dc01", "corp.example.com", ""), Profile: types.StringValue("domain_controller")}},
+		"other profile":    {config: certificateCreateModel{DomainController: dc("dc01", "corp.example.com", ""), Profile: types.StringValue("tls_server")}, want: "Conflicting Profile"},
+		"without host":     {config: certificateCreateModel{DomainController: dc("", "corp.example.com", "")}, want: "Missing Domain Controller Name"},
+		"without domain":   {config: certificateCreateModel{DomainController: dc("dc01", "", "")}, want: "Missing Domain Controller Name"},
+		"wildcard":         {config: certificateCreateModel{DomainController: dc("*.corp.example.com", "corp.example.com", "")}, want: "Invalid Domain Controller Name"},
+		"invalid domain":   {config: certificateCreateModel{DomainController: dc("dc01", "corp example.com", "")}, want: "Invalid Domain Controller Name"},
+		"long netbios":     {config: certificateCreateModel{DomainController: dc("dc01", "corp.example.com", "CORPORATEDOMAIN1")}, want: "Invalid Domain Controller Name"},
+		"dotted netbios":   {config: certificateCreateModel{DomainController: dc("dc01", "corp.example.com", "corp.example")}, want: "Invalid Domain Controller Name"},
+		"unknown host":     {config: certificateCreateModel{DomainController: domainControllerBlock(types.StringUnknown(), types.StringValue("corp.example.com"), types.StringNull())}},
+		"unknown dc block": {config: certificateCreateModel{DomainController: types.ObjectUnknown(map[string]attr.Type{"host_name": types.StringType, "domain": types.StringType, "netbios_name": types.StringType})}},
+	} {
+		diags := validateDomainController(ctx, tc.config)
+		switch {
+		case tc.want == "" && diags.HasError():
+			t.Errorf("%s: validateDomainController() = %v", name, diags)
+		case tc.want != "" && (!diags.HasError() || !strings.Contains(diags.Errors()[0].Summary(), tc.want)):
+			t.Errorf("%s: validateDomainController() = %v, want %q", name, diags, tc.want)
+		}
+	}
+}
+
+func TestDomainControllerSubjectAlternativeNames(t *testing.T) {
+	ctx := context.Background()
+	m := &certificateCreateModel{
+		DomainController: domainControllerBlock(types.StringValue("dc01"), types.StringValue("corp.example.com"), types.StringValue("CORP")),
+	}
+	m.DNSNames, _ = types.ListValueFrom(ctx, types.StringType, []string{"DC01.corp.example.com", "ldap.corp.example.com"})
+
+	attributes, diags := m.submittedAttributes(ctx)
+	if diags.HasError() {
+		t.Fatalf("unexpected diagnostics: %v", diags)
+	}
+	want := "san:dns=dc01.corp.example.com&dns=ldap.corp.example.com&dns=corp.example.com&dns=corp"
+	if !strings.Contains(attributes, want) {
+		t.Errorf("submittedAttributes() = %q, want %q", attributes, want)
+	}
+
+	if profile, ok := m.profile(); !ok || profile.requireSAN != certificateProfiles[domainControllerProfile].requireSAN {
+		t.Errorf("profile() = %v, %t, want the domain_controller profile", profile, ok)
+	}
+	if diags := validateProfile(ctx, *m); diags.HasError() {
+		t.Errorf("validateProfile() = %v", diags)
+	}
+
+	block := generateCSRModel{CommonName: types.StringNull()}
+	m.applyDomainController(ctx, &block)
+	if block.CommonName.ValueString() != "dc01.corp.example.com" {
+		t.Errorf("applyDomainController() common name = %q, want dc01.corp.example.com", block.CommonName.ValueString())
+	}
+
+	m.DomainController = domainControllerBlock(types.StringUnknown(), types.StringValue("corp.example.com"), types.StringNull())
+	if !m.subjectAlternativeNames().IsUnknown() {
+		t.Error("subjectAlternativeNames() should be unknown while the domain_controller block is")
+	}
+}
@@ -0,0 +1,108 @@
+package provider
+
+import (
+	"fmt"
+
+	"github.com/flipyap/microsoft-adcs-client/client"
+	"github.com/hashicorp/terraform-plugin-framework/diag"
+	"github.com/hashicorp/terraform-plugin-framework/path"
+)
+
+// clientCredentials identifies the client of a set of credentials, see clientFor.
+type clientCredentials struct {
+	username string
+	password string
+}
+
+// clientFor returns the client authenticating with the given credentials, the provider's own when
+// they are the ones it was configured with. Clients are created once per set of credentials and use
+// the authentication scheme, Kerberos configuration, host aliases and pre-authentication of the
+// provider.
+func (p *providerData) clientFor(username, password string) (*client.ADCSClient, error) {
+	if username == p.clientConfig.Username && password == p.clientConfig.Password {
+		return p.client, nil
+	}
+
+	p.clientsMu.Lock()
+	defer p.clientsMu.Unlock()
+	key := clientCredentials{username: username, password: password}
+	if c, ok := p.clients[key]; ok {
+		return c, nil
+	}
+	config := p.clientConfig
+	config.Username = username
+	config.Password = password
+	c, err := client.NewClient(&config)
+	if err != nil {
+		return nil, err
+	}
+	configureTransport(c, username, password, p.hostAliases)
+	p.preAuth.wrap(c)
+	if p.clients == nil {
+		p.clients = map[clientCredentials]*client.ADCSClient{}
+	}
+	p.clients[key] = c
+	return c, nil
+}
+
+// hasEndpoint reports whether the certificate overrides the CA server or the credentials of the
+// provider.
+func (m *certificateCreateModel) hasEndpoint() bool {
+	return !m.Host.IsNull() || !m.Username.IsNull()
+}
+
+// host returns the certsrv server the certificate is requested from, its host or the one of the
+// provider.
+func (r *certificateResource) host(m certificateCreateModel) string {
+	if host := m.Host.ValueString(); host != "" {
+		return host
+	}
+	return r.client.HostURL
+}
+
+// newCARoute returns the route of the certsrv requests for the certificate, pinned to caName once
+// it is known. The requests go to the host of the resource and authenticate with its username and
+// password when it sets them.
+func (r *certificateResource) newCARoute(m certificateCreateModel, caName string) (*caRoute, diag.Diagnostics) {
+	var diags diag.Diagnostics
+	route := r.provider.newCARoute(caName)
+	route.endpoint = m.Host.ValueString()
+	if m.Username.IsNull() {
+		return route, diags
+	}
+
+	c, err := r.provider.clientFor(m.Username.ValueString(), m.Password.ValueString())
+	if err != nil {
+		diags.AddAttributeError(
+			path.Root("username"),
+			"Unable to Create Active Directory Certificate Services API Client",
+			fmt.Sprintf("Could not create a client authenticating as %s: %s", m.Username.ValueString(), err.Error()),
+		)
+		return nil, diags
+	}
+	route.client = c
+	return route, diags
+}
+
+// validateEndpoint checks that host is not empty and that username and password are set together.
+func validateEndpoint(config certificateCreateModel) diag.Diagnostics {
+	var diags diag.Diagnostics
+	if !config.Host.IsNull() && !config.Host.IsUnknown() && config.Host.ValueString() == "" {
+		diags.AddAttributeError(
+			path.Root("host"),
+			"Invalid Host",
+			"host is empty, set it to the host of the certsrv server or remove it to use the host of the provider.",
+		)
+	}
+	if config.Username.IsUnknown() || config.Password.IsUnknown() {
+		return diags
+	}
+	if config.Username.IsNull() != config.Password.IsNull() {
+		diags.AddAttributeError(
+			path.Root("username"),
+			"Incomplete Credentials",
+			"username and password have to be set together, or both left unset to use the credentials of the provider.",
+		)
+	}
+	return diags
+}
@@ -0,0 +1,195 @@
+package provider
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-framework/attr"
+	"github.com/hashicorp/terraform-plugin-framework/diag"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+	"github.com/hashicorp/terraform-plugin-log/tflogtest"
+	"github.com/hashicorp/terraform-plugin-testing/helper/resource"
+	"github.com/hashicorp/terraform-plugin-testing/terraform"
+)
+
+func TestValidateEndpoint(t *testing.T) {
+	for name, tc := range map[string]struct {
+		config certificateCreateModel
+		want   string
+	}{
+		"unset":            {},
+		"host":             {config: certificateCreateModel{Host: types.StringValue("ca2.example.com")}},
+		"empty host":       {config: certificateCreateModel{Host: types.StringValue("")}, want: "Invalid Host"},
+		"credentials":      {config: certificateCreateModel{Username: types.StringValue("svc-team"), Password: types.StringValue("secret")}},
+		"without password": {config: certificateCreateModel{Username: types.StringValue("svc-team"), Password: types.StringNull()}, want: "Incomplete Credentials"},
+		"without username": {config: certificateCreateModel{Username: types.StringNull(), Password: types.StringValue("secret")}, want: "Incomplete Credentials"},
+		"unknown password": {config: certificateCreateModel{Username: types.StringValue("svc-team"), Password: types.StringUnknown()}},
+	} {
+		diags := validateEndpoint(tc.config)
+		switch {
+		case tc.want == "" && diags.HasError():
+			t.Errorf("%s: validateEndpoint() = %v", name, diags)
+		case tc.want != "" && (!diags.HasError() || !strings.Contains(diags.Errors()[0].Summary(), tc.want)):
+			t.Errorf("%s: validateEndpoint() = %v, want %q", name, diags, tc.want)
+		}
+	}
+}
+
+func TestRequestCertificateLogsNoSecrets(t *testing.T) {
+	srv := newAccCertsrv(t)
+	t.Setenv("ADCS_PASSWORD", "secret")
+	data, err := debugEnrollConfigure(context.Background(), "test", map[string]interface{}{
+		"host":     srv.host(),
+		"username": "svc-terraform",
+		"use_ntlm": true,
+	}, io.Discard)
+	if err != nil {
+		t.Fatal(err)
+	}
+	r := &certificateResource{client: data.client, provider: data}
+	csr, err := debugEnrollRequest(context.Background(), "", "app.example.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	secrets := []string{"password-of-svc-team", "private-key-of-the-certificate", "private-key-of-the-agent", "password-of-the-pfx"}
+	plan := &certificateCreateModel{
+		CSR:                          types.StringValue(csr),
+		Template:                     types.StringValue("WebServer"),
+		Username:                     types.StringValue("svc-terraform"),
+		Password:                     types.StringValue(secrets[0]),
+		PrivateKeyPEM:                types.StringValue(secrets[1]),
+		EnrollmentAgentPrivateKeyPEM: types.StringValue(secrets[2]),
+		PKCS12: types.ObjectValueMust(pkcs12AttrTypes, map[string]attr.Value{
+			"password":        types.StringValue(secrets[3]),
+			"private_key_pem": types.StringNull(),
+		}),
+	}
+	var logs bytes.Buffer
+	var diags diag.Diagnostics
+	r.requestCertificate(tflogtest.RootLogger(withRetryPolicy(context.Background(), noRetry), &logs), plan, csr, "", &diags)
+	if diags.HasError() {
+		t.Fatalf("unexpected diagnostics: %v", diags)
+	}
+	for _, secret := range secrets {
+		if strings.Contains(logs.String(), secret) {
+			t.Errorf("the log holds %q: %s", secret, logs.String())
+		}
+	}
+	// Text logs print values the JSON encoding of the test leaves empty, so no field may name a secret.
+	entries, err := tflogtest.MultilineJSONDecode(&logs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, entry := range entries {
+		for field := range entry {
+			if name := strings.ToLower(field); strings.Contains(name, "password") || strings.Contains(name, "private") || strings.Contains(name, "pkcs12") {
+				t.Errorf("the log entry %q holds the field %s", entry["@message"], field)
+			}
+		}
+	}
+}
+
+func TestCertificateEndpoint(t *testing.T) {
+	first, second := newAccCertsrv(t), newAccCertsrv(t)
+	t.Setenv("ADCS_PASSWORD", "secret")
+	ctx := context.Background()
+	data, err := debugEnrollConfigure(ctx, "test", map[string]interface{}{
+		"host":     first.host(),
+		"username": "svc-terraform",
+		"use_ntlm": true,
+	}, io.Discard)
+	if err != nil {
+		t.Fatal(err)
+	}
+	r := &certificateResource{client: data.client, provider: data}
+
+	if c, err := data.clientFor("svc-terraform", "secret"); err != nil || c != data.client {
+		t.Errorf("clientFor() with the credentials of the provider = %p, %v, want the client of the provider", c, err)
+	}
+	other, err := data.clientFor("svc-team", "other")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if again, _ := data.clientFor("svc-team", "other"); other == data.client || again != other {
+		t.Error("clientFor() should create one client per set of credentials")
+	}
+
+	csr, err := debugEnrollRequest(ctx, "", "app.example.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+	plan := &certificateCreateModel{
+		CSR:      types.StringValue(csr),
+		Template: types.StringValue("WebServer"),
+		Host:     types.StringValue(second.host()),
+		Username: types.StringValue("svc-team"),
+		Password: types.StringValue("other"),
+	}
+	var diags diag.Diagnostics
+	certificates := r.requestCertificate(withRetryPolicy(ctx, noRetry), plan, csr, "", &diags)
+	if diags.HasError() {
+		t.Fatalf("unexpected diagnostics: %v", diags)
+	}
+	if second.request(certificates.ID) == nil || first.request(certificates.ID) != nil {
+		t.Fatalf("request ID %s should only be in the database of the CA of host", certificates.ID)
+	}
+	if r.host(*plan) != second.host() {
+		t.Errorf("host() = %q, want %q", r.host(*plan), second.host())
+	}
+
+	route, diags := r.newCARoute(*plan, "")
+	if diags.HasError() {
+		t.Fatalf("unexpected diagnostics: %v", diags)
+	}
+	if route.client != other {
+		t.Error("the route should authenticate with the credentials of the resource")
+	}
+	if poller := data.pollerFor(route); poller == data.poller || poller != data.pollerFor(route) {
+		t.Error("requests of the resource should share a poller of their own")
+	}
+	if _, err := data.pollerFor(route).check(ctx, certificates.ID); err != nil {
+		t.Errorf("the poller could not download request ID %s from host: %v", certificates.ID, err)
+	}
+}
+
+func TestAccCertificateResourceHost(t *testing.T) {
+	srv, other := newAccCertsrv(t), newAccCertsrv(t)
+	config := srv.providerConfig() + `
+resource "microsoftadcs_certificate" "test" {
+  template = "WebServer"
+  host     = "` + other.host() + `"
+
+  generate_csr {
+    common_name = "app.example.com"
+  }
+}
+`
+
+	resource.Test(t, resource.TestCase{
+		ProtoV6ProviderFactories: testAccProtoV6ProviderFactories,
+		Steps: []resource.TestStep{
+			{
+				Config: config,
+				Check: resource.ComposeAggregateTestCheckFunc(
+					resource.TestCheckResourceAttr("microsoftadcs_certificate.test", "host", other.host()),
+					func(s *terraform.State) error {
+						id := s.RootModule().Resources["microsoftadcs_certificate.test"].Primary.ID
+						if other.request(id) == nil || srv.request(id) != nil {
+							return fmt.Errorf("request ID %s should only be in the database of the CA of host", id)
+						}
+						return nil
+					},
+				),
+			},
+			{
+				Config:   config,
+				PlanOnly: true,
+			},
+		},
+	})
+}
@@ -0,0 +1,77 @@
+package provider
+
+import (
+	"context"
+	"fmt"
+	"html"
+	"net/url"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/hashicorp/terraform-plugin-framework/diag"
+	"github.com/hashicorp/terraform-plugin-framework/path"
+	"github.com/hashicorp/terraform-plugin-log/tflog"
+)
+
+// templateOptionRegex reads the template names from the template list of the advanced request page,
+// whose options carry values such as "E;WebServer;2;1;...". The second field is the name requests
+// refer to, the option text its display name.
+var templateOptionRegex = regexp.MustCompile(`(?i)<option\s+value="[^";]*;([^";]+);`)
+
+// enrollableTemplates returns the templates the CA offers to the configured account, read once per
+// provider configuration from certrqxt.asp, which only lists the templates the account may enroll
+// for. The list is empty when the page lists none, such as on CAs without web enrollment forms.
+func (p *providerData) enrollableTemplates(ctx context.Context) ([]string, error) {
+	p.templatesOnce.Do(func() {
+		page, err := downloadCertsrv(ctx, p.client, p.parser, "certrqxt.asp", url.Values{}, "")
+		if err != nil {
+			p.enrollableErr = fmt.Errorf("could not read the templates offered by the CA: %v", err)
+			return
+		}
+		seen := map[string]bool{}
+		for _, match := range templateOptionRegex.FindAllStringSubmatch(page, -1) {
+			name := strings.TrimSpace(html.UnescapeString(match[1]))
+			if name != "" && !seen[strings.ToLower(name)] {
+				seen[strings.ToLower(name)] = true
+				p.enrollable = append(p.enrollable, name)
+			}
+		}
+		sort.Strings(p.enrollable)
+	})
+	return p.enrollable, p.enrollableErr
+}
+
+// checkTemplateOffered fails the plan of a new certificate when the CA does not offer its template,
+// listing the templates it does offer instead of leaving the apply to fail on the submission. The
+// check is skipped when the offered templates cannot be read.
+func (p *providerData) checkTemplateOffered(ctx context.Context, template string, diags *diag.Diagnostics) {
+	offered, err := p.enrollableTemplates(ctx)
+	if err != nil || len(offered) == 0 {
+		tflog.Debug(ctx, "Could not check whether the CA offers the certificate template", map[string]interface{}{
+			"template": template,
+			"error":    fmt.Sprint(err),
+		})
+		return
+	}
+	if containsStringFold(offered, template) {
+		return
+	}
+	diags.AddAttributeError(
+		path.Root("template"),
+		"Certificate Template Not Offered",
+		fmt.Sprintf("The CA does not offer template %q to the provider account. It offers: %s.\n\n"+
+			"Requests refer to the template name rather than its display name. A template missing from the list is either not "+
+			"published on the CA or the account lacks the Enroll permission on it.", template, strings.Join(offered, ", ")),
+	)
+}
+
+// containsStringFold reports whether values holds s, ignoring case like the CA does for template names.
+func containsStringFold(values []string, s string) bool {
+	for _, value := range values {
+		if strings.EqualFold(value, s) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,74 @@
+package provider
+
+import (
+	"context"
+	"io"
+	"reflect"
+	"regexp"
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-framework/diag"
+	"github.com/hashicorp/terraform-plugin-testing/helper/resource"
+)
+
+func TestCheckTemplateOffered(t *testing.T) {
+	srv := newAccCertsrv(t)
+	t.Setenv("ADCS_PASSWORD", "secret")
+	ctx := withRetryPolicy(context.Background(), noRetry)
+	data, err := debugEnrollConfigure(ctx, "test", map[string]interface{}{
+		"host":     srv.host(),
+		"username": "svc-terraform",
+		"use_ntlm": true,
+	}, io.Discard)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	offered, err := data.enrollableTemplates(ctx)
+	if want := []string{"ClientAuth", "Denied", "ManagerApproval", "User", "WebServer"}; err != nil || !reflect.DeepEqual(offered, want) {
+		t.Fatalf("enrollableTemplates() = %v, %v, want %v", offered, err, want)
+	}
+
+	var diags diag.Diagnostics
+	data.checkTemplateOffered(ctx, "webserver", &diags)
+	if diags.HasError() {
+		t.Errorf("checkTemplateOffered() = %v, want template names matched regardless of case", diags)
+	}
+	data.checkTemplateOffered(ctx, "Web Server", &diags)
+	if !diags.HasError() || diags.Errors()[0].Summary() != "Certificate Template Not Offered" {
+		t.Errorf("checkTemplateOffered() = %v, want the display name rejected", diags)
+	}
+}
+
+func TestCheckTemplateOfferedUnreadable(t *testing.T) {
+	// The certsrv stand-in has no advanced request page, so the check is skipped.
+	_, c := newTestCertsrv(t)
+	data := &providerData{client: c, parser: strictParser}
+	var diags diag.Diagnostics
+	data.checkTemplateOffered(withRetryPolicy(context.Background(), noRetry), "NoSuchTemplate", &diags)
+	if diags.HasError() {
+		t.Errorf("checkTemplateOffered() = %v, want the check skipped", diags)
+	}
+}
+
+func TestAccCertificateResourceTemplateNotOffered(t *testing.T) {
+	srv := newAccCertsrv(t)
+	resource.Test(t, resource.TestCase{
+		ProtoV6ProviderFactories: testAccProtoV6ProviderFactories,
+		Steps: []resource.TestStep{
+			{
+				Config: srv.providerConfig() + `
+resource "microsoftadcs_certificate" "test" {
+  template = "WebServr"
+
+  generate_csr {
+    common_name = "web.example.com"
+  }
+}
+`,
+				PlanOnly:    true,
+				ExpectError: regexp.MustCompile(`(?s)Certificate Template Not Offered.*It offers: ClientAuth, Denied,`),
+			},
+		},
+	})
+}
@@ -0,0 +1,122 @@
+package provider
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+)
+
+// The classes of failures. Errors diagnostics report about an exchange with the CA start their
+// summary with the name of the class, such as "Transient: Error Reading Certificate", so tooling
+// wrapping the provider and tests can assert on the class of a failure instead of its wording.
+// Within the module, errors.As finds the class of an error returned by classifyError.
+const (
+	errorClassAuth         = "AuthError"
+	errorClassPolicyDenied = "PolicyDenied"
+	errorClassPending      = "Pending"
+	errorClassTransient    = "Transient"
+	errorClassParse        = "ParseError"
+)
+
+// AuthError is a failure to authenticate to certsrv, such as credentials IIS keeps answering with
+// 401 or a Kerberos login the KDC refused.
+type AuthError struct {
+	Err error
+}
+
+func (e *AuthError) Error() string { return e.Err.Error() }
+func (e *AuthError) Unwrap() error { return e.Err }
+
+// PolicyDenied is a request the CA refused, denied by its policy module or by a CA manager.
+type PolicyDenied struct {
+	Err error
+}
+
+func (e *PolicyDenied) Error() string { return e.Err.Error() }
+func (e *PolicyDenied) Unwrap() error { return e.Err }
+
+// Pending is a request the CA took under submission that a CA manager has not decided on yet.
+type Pending struct {
+	Err error
+}
+
+func (e *Pending) Error() string { return e.Err.Error() }
+func (e *Pending) Unwrap() error { return e.Err }
+
+// Transient is a failure that says nothing about the request: the CA could not be reached or did
+// not answer, and a later attempt may succeed.
+type Transient struct {
+	Err error
+}
+
+func (e *Transient) Error() string { return e.Err.Error() }
+func (e *Transient) Unwrap() error { return e.Err }
+
+// ParseError is an answer of the CA the provider could not make sense of, such as a certsrv page a
+// portal changed or certificate material that does not decode.
+type ParseError struct {
+	Err error
+}
+
+func (e *ParseError) Error() string { return e.Err.Error() }
+func (e *ParseError) Unwrap() error { return e.Err }
+
+// errorClass returns the name of the class err was classified as, empty when it has none.
+func errorClass(err error) string {
+	var (
+		auth      *AuthError
+		denied    *PolicyDenied
+		pending   *Pending
+		transient *Transient
+		parse     *ParseError
+	)
+	switch {
+	case err == nil:
+		return ""
+	case errors.As(err, &auth):
+		return errorClassAuth
+	case errors.As(err, &denied):
+		return errorClassPolicyDenied
+	case errors.As(err, &pending):
+		return errorClassPending
+	case errors.As(err, &transient):
+		return errorClassTransient
+	case errors.As(err, &parse):
+		return errorClassParse
+	}
+	return ""
+}
+
+// classifyError wraps err in the type of its class. Errors that already have a class, and those
+// that fit none, are returned as they are.
+func classifyError(err error) error {
+	if err == nil || errorClass(err) != "" {
+		return err
+	}
+	disposition, decided := requestDisposition(err)
+	switch {
+	case isStillPending(err):
+		return &Pending{Err: err}
+	case isAuthFailure(err):
+		return &AuthError{Err: err}
+	case decided && disposition == dispositionDenied:
+		return &PolicyDenied{Err: err}
+	case isTransient(err) || isRetryable(err) || isContextError(err):
+		return &Transient{Err: err}
+	}
+	return err
+}
+
+// isAuthFailure reports whether certsrv or the KDC refused the credentials. IIS answers 401 to the
+// first leg of every handshake, so only a 401 that retries did not get past counts.
+func isAuthFailure(err error) bool {
+	return responseStatus(err) == http.StatusUnauthorized || strings.Contains(err.Error(), "could not login client")
+}
+
+// classifiedSummary prefixes the summary of a diagnostic about err with its class.
+func classifiedSummary(summary string, err error) string {
+	if class := errorClass(classifyError(err)); class != "" {
+		return class + ": " + summary
+	}
+	return summary
+}
@@ -0,0 +1,52 @@
+package provider
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"syscall"
+	"testing"
+)
+
+func TestClassifyError(t *testing.T) {
+	for name, tc := range map[string]struct {
+		err  error
+		want string
+	}{
+		"nil":               {},
+		"pending":           {err: errors.New("failed to get request ID: Taken Under Submission"), want: errorClassPending},
+		"unauthorized":      {err: errors.New("failed to get request ID: status error: 401"), want: errorClassAuth},
+		"kerberos login":    {err: errors.New("could not login client: KDC_ERR_PREAUTH_FAILED"), want: errorClassAuth},
+		"denied":            {err: fmt.Errorf("failed to download certificate: %w", &dispositionError{message: "Denied by Policy Module  0x80094800"}), want: errorClassPolicyDenied},
+		"rpc unavailable":   {err: &dispositionError{prefix: "failed to get request ID: ", message: "The RPC server is unavailable. 0x800706ba"}, want: errorClassTransient},
+		"connection":        {err: fmt.Errorf("error making request: %w", syscall.ECONNREFUSED), want: errorClassTransient},
+		"service down":      {err: errors.New("status error: 503"), want: errorClassTransient},
+		"deadline":          {err: fmt.Errorf("waiting for the CA: %w", context.DeadlineExceeded), want: errorClassTransient},
+		"unknown request":   {err: &ParseError{Err: errors.New("failed to get request ID: an unknown error occurred")}, want: errorClassParse},
+		"already wrapped":   {err: fmt.Errorf("renewal: %w", &AuthError{Err: errors.New("status error: 403")}), want: errorClassAuth},
+		"unclassified":      {err: errors.New("the template is not a certificate template")},
+		"forbidden request": {err: errors.New("status error: 400")},
+	} {
+		if got := errorClass(classifyError(tc.err)); got != tc.want {
+			t.Errorf("%s: errorClass(classifyError()) = %q, want %q", name, got, tc.want)
+		}
+	}
+
+	err := classifyError(errors.New("failed to get request ID: status error: 401"))
+	var auth *AuthError
+	if !errors.As(err, &auth) || err.Error() != "failed to get request ID: status error: 401" {
+		t.Errorf("classifyError() = %#v, want an AuthError keeping the message", err)
+	}
+}
+
+func TestClassifiedSummary(t *testing.T) {
+	if got := classifiedSummary("Error Reading Certificate", errors.New("status error: 502")); got != "Transient: Error Reading Certificate" {
+		t.Errorf("classifiedSummary() = %q", got)
+	}
+	if got := classifiedSummary("Error Parsing Certificate", &ParseError{Err: errors.New("asn1: syntax error")}); got != "ParseError: Error Parsing Certificate" {
+		t.Errorf("classifiedSummary() = %q", got)
+	}
+	if got := classifiedSummary("Error Reading Certificate", errors.New("the template is not a certificate template")); got != "Error Reading Certificate" {
+		t.Errorf("classifiedSummary() of an unclassified error = %q", got)
+	}
+}
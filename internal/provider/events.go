@@ -0,0 +1,136 @@
+package provider
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"time"
+)
+
+const (
+	eventIssued  = "issued"
+	eventAdopted = "adopted"
+	eventRenewed = "renewed"
+	eventRevoked = "revoked"
+	eventPending = "pending"
+	eventDenied  = "denied"
+)
+
+// lifecycleEvent describes something that happened to a certificate during a Terraform run. Events
+// are sent as JSON to event_url and/or appended as one JSON document per line to event_file.
+type lifecycleEvent struct {
+	Event        string    `json:"event"`
+	Timestamp    time.Time `json:"timestamp"`
+	Host         string    `json:"host"`
+	RequestID    string    `json:"request_id"`
+	Template     string    `json:"template,omitempty"`
+	Subject      string    `json:"subject,omitempty"`
+	SerialNumber string    `json:"serial_number,omitempty"`
+	NotAfter     string    `json:"not_after,omitempty"`
+	// CAName and Description are only set on pending events, for microsoftadcs_abandoned_requests.
+	CAName      string `json:"ca_name,omitempty"`
+	Description string `json:"description,omitempty"`
+}
+
+// newLifecycleEvent fills in the certificate details an event carries. The certificate is optional,
+// events are still useful without the parsed details.
+func newLifecycleEvent(event string, host string, reqID string, template string, certB64 string) lifecycleEvent {
+	e := lifecycleEvent{
+		Event:     event,
+		Timestamp: time.Now().UTC(),
+		Host:      host,
+		RequestID: reqID,
+		Template:  template,
+	}
+	if cert, err := parseCertificate(certB64); err == nil {
+		e.Subject = cert.Subject.String()
+		e.SerialNumber = fmt.Sprintf("%x", cert.SerialNumber)
+		e.NotAfter = cert.NotAfter.UTC().Format(time.RFC3339)
+	}
+	return e
+}
+
+// emitEvent publishes the event to every configured destination.
+func (p *providerData) emitEvent(ctx context.Context, event lifecycleEvent) error {
+	if p.eventURL == "" && p.eventFile == "" {
+		return nil
+	}
+
+	body, err := json.Marshal(event)
+	if err != nil {
+		return fmt.Errorf("could not encode event: %v", err)
+	}
+
+	if p.eventFile != "" {
+		if err := p.appendEvent(body); err != nil {
+			return err
+		}
+	}
+
+	if p.eventURL != "" {
+		req, err := http.NewRequestWithContext(ctx, http.MethodPost, p.eventURL, bytes.NewReader(body))
+		if err != nil {
+			return fmt.Errorf("could not create event request: %v", err)
+		}
+		req.Header.Set("Content-Type", "application/json")
+
+		resp, err := webhookClient.Do(req)
+		if err != nil {
+			return fmt.Errorf("event request failed: %v", err)
+		}
+		defer resp.Body.Close()
+
+		if resp.StatusCode < 200 || resp.StatusCode > 299 {
+			return fmt.Errorf("event endpoint returned status %d", resp.StatusCode)
+		}
+	}
+
+	return nil
+}
+
+// appendEvent writes the event as a single NDJSON line. Terraform runs resources in parallel so
+// writes are serialized to keep lines from interleaving.
+func (p *providerData) appendEvent(body []byte) error {
+	p.eventFileMu.Lock()
+	defer p.eventFileMu.Unlock()
+
+	f, err := os.OpenFile(p.eventFile, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o600)
+	if err != nil {
+		return fmt.Errorf("could not open event file: %v", err)
+	}
+	defer f.Close()
+
+	if _, err := f.Write(append(body, '\n')); err != nil {
+		return fmt.Errorf("could not write event file: %v", err)
+	}
+	return nil
+}
+
+// readEvents returns the events of an event file in the order they were written. Lines that are not
+// events, such as one cut short by a crash, are skipped.
+func readEvents(path string) ([]lifecycleEvent, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("could not open event file: %v", err)
+	}
+	defer f.Close()
+
+	var events []lifecycleEvent
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1<<20)
+	for scanner.Scan() {
+		var event lifecycleEvent
+		if err := json.Unmarshal(scanner.Bytes(), &event); err != nil || event.Event == "" {
+			continue
+		}
+		events = append(events, event)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("could not read event file: %v", err)
+	}
+	return events, nil
+}
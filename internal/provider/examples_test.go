@@ -0,0 +1,177 @@
+package provider
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/flipyap/microsoft-adcs-client/client"
+	"github.com/hashicorp/terraform-plugin-testing/helper/resource"
+	httpntlm "github.com/vadimi/go-http-ntlm/v2"
+)
+
+// testAccExternalProviders are the providers the example workflows create keys and requests with.
+var testAccExternalProviders = map[string]resource.ExternalProvider{
+	"tls": {Source: "hashicorp/tls"},
+}
+
+// testAccExample returns the main.tf of an example workflow configured for the acceptance certsrv.
+// providers.tf is left out, the test framework supplies the provider under test.
+func testAccExample(t *testing.T, srv *accCertsrv, name string) string {
+	t.Helper()
+	b, err := os.ReadFile(filepath.Join("..", "..", "examples", "workflows", name, "main.tf"))
+	if err != nil {
+		t.Fatalf("could not read example %s: %v", name, err)
+	}
+	return srv.providerConfig() + string(b)
+}
+
+// testCheckIssuedDNSNames checks that the certificate_pem of the resource carries the DNS names.
+func testCheckIssuedDNSNames(name string, dnsNames ...string) resource.TestCheckFunc {
+	return resource.TestCheckResourceAttrWith(name, "certificate_pem", func(value string) error {
+		cert, err := parseCertificate(value)
+		if err != nil {
+			return err
+		}
+		for _, dnsName := range dnsNames {
+			if err := cert.VerifyHostname(dnsName); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// testCheckSubmission checks what the acceptance certsrv received for the request of the resource.
+func testCheckSubmission(srv *accCertsrv, name string, check func(req *accRequest) error) resource.TestCheckFunc {
+	return resource.TestCheckResourceAttrWith(name, "id", func(reqID string) error {
+		req := srv.request(reqID)
+		if req == nil {
+			return fmt.Errorf("request ID %s was not submitted", reqID)
+		}
+		return check(req)
+	})
+}
+
+func TestAccExampleWebServer(t *testing.T) {
+	srv := newAccCertsrv(t)
+
+	resource.Test(t, resource.TestCase{
+		ProtoV6ProviderFactories: testAccProtoV6ProviderFactories,
+		ExternalProviders:        testAccExternalProviders,
+		Steps: []resource.TestStep{
+			{
+				Config: testAccExample(t, srv, "web_server"),
+				Check: resource.ComposeAggregateTestCheckFunc(
+					resource.TestCheckResourceAttr("microsoftadcs_certificate.web", "template", "WebServer"),
+					resource.TestCheckResourceAttr("microsoftadcs_certificate.web", "ready_for_renewal", "false"),
+					resource.TestCheckResourceAttrSet("microsoftadcs_certificate.web", "certificate_chain_pem"),
+					resource.TestCheckResourceAttrSet("microsoftadcs_certificate.web", "certificate_chain.0"),
+					resource.TestCheckResourceAttrPair("microsoftadcs_certificate.web", "issuing_ca_pem", "microsoftadcs_certificate.web", "certificate_chain.0"),
+					resource.TestCheckResourceAttrSet("microsoftadcs_certificate.web", "combined_pem"),
+					testCheckIssuedDNSNames("microsoftadcs_certificate.web", "www.example.com"),
+				),
+			},
+		},
+	})
+}
+
+func TestAccExampleClientCertificate(t *testing.T) {
+	srv := newAccCertsrv(t)
+
+	resource.Test(t, resource.TestCase{
+		ProtoV6ProviderFactories: testAccProtoV6ProviderFactories,
+		ExternalProviders:        testAccExternalProviders,
+		Steps: []resource.TestStep{
+			{
+				Config: testAccExample(t, srv, "client_certificate"),
+				Check: resource.ComposeAggregateTestCheckFunc(
+					resource.TestCheckResourceAttr("microsoftadcs_certificate.client", "template", "User"),
+					resource.TestCheckResourceAttrSet("microsoftadcs_certificate.client", "thumbprint_sha1"),
+					testCheckIssuedDNSNames("microsoftadcs_certificate.client", "svc-app.example.com", "svc-app.internal.example.com"),
+					testCheckSubmission(srv, "microsoftadcs_certificate.client", func(req *accRequest) error {
+						if !strings.Contains(strings.Join(req.attributes, "\n"), "san:dns=svc-app.internal.example.com&upn=svc-app@example.com") {
+							return fmt.Errorf("the san request attribute was not submitted: %q", req.attributes)
+						}
+						return nil
+					}),
+				),
+			},
+		},
+	})
+}
+
+func TestAccExamplePendingApproval(t *testing.T) {
+	srv := newAccCertsrv(t)
+
+	resource.Test(t, resource.TestCase{
+		ProtoV6ProviderFactories: testAccProtoV6ProviderFactories,
+		ExternalProviders:        testAccExternalProviders,
+		Steps: []resource.TestStep{
+			{
+				Config: testAccExample(t, srv, "pending_approval"),
+				Check: resource.ComposeAggregateTestCheckFunc(
+					resource.TestCheckResourceAttrSet("microsoftadcs_certificate.approved", "certificate_pem"),
+					testCheckIssuedDNSNames("microsoftadcs_certificate.approved", "payments.example.com"),
+					testCheckSubmission(srv, "microsoftadcs_certificate.approved", func(req *accRequest) error {
+						if req.template != accApprovalTemplate {
+							return fmt.Errorf("template = %q, want %q", req.template, accApprovalTemplate)
+						}
+						return nil
+					}),
+				),
+			},
+		},
+	})
+}
+
+// TestAcceptanceCertsrv keeps the server the acceptance tests run against working, also where
+// TF_ACC is not set.
+func TestAcceptanceCertsrv(t *testing.T) {
+	srv := newAccCertsrv(t)
+	c := &client.ADCSClient{
+		HostURL:    srv.host(),
+		NtlmClient: &http.Client{Transport: &httpntlm.NtlmTransport{User: "svc-terraform", Password: "secret"}},
+		UseNtlm:    true,
+	}
+	request, err := base64.StdEncoding.DecodeString(csr)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	certificates, err := submitCertificateRequest(context.Background(), c, strictParser, certsrvSubmission{
+		CSR:        string(request),
+		Template:   "WebServer",
+		Attributes: []string{"san:dns=www.example.com"},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	leaf, err := parseCertificate(certificates.CertificateB64)
+	if err != nil {
+		t.Fatalf("could not parse the issued certificate: %v", err)
+	}
+	if err := leaf.VerifyHostname("www.example.com"); err != nil {
+		t.Errorf("the san request attribute was not honored: %v", err)
+	}
+	if err := leaf.CheckSignatureFrom(srv.hierarchy.issuing); err != nil {
+		t.Errorf("the certificate was not issued by the issuing CA: %v", err)
+	}
+
+	_, err = submitCertificateRequest(context.Background(), c, strictParser, certsrvSubmission{CSR: string(request), Template: accApprovalTemplate})
+	reqID, pending := pendingRequestID(err)
+	if !pending {
+		t.Fatalf("expected the request to be pending, got %v", err)
+	}
+	poller := newPendingPoller(c, strictParser)
+	poller.interval = 10 * time.Millisecond
+	if _, err := poller.wait(context.Background(), reqID, time.Second); err != nil {
+		t.Errorf("the pending request was not approved: %v", err)
+	}
+}
@@ -0,0 +1,381 @@
+package provider
+
+import (
+	"context"
+	"crypto/x509"
+	"encoding/asn1"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/flipyap/microsoft-adcs-client/client"
+	"github.com/hashicorp/terraform-plugin-framework/attr"
+	"github.com/hashicorp/terraform-plugin-framework/datasource"
+	"github.com/hashicorp/terraform-plugin-framework/datasource/schema"
+	"github.com/hashicorp/terraform-plugin-framework/path"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+)
+
+// oidCertificateTemplateName is the Certificate Template Name extension, szOID_ENROLL_CERTTYPE_EXTENSION,
+// which certificates of version 1 templates carry instead of the Certificate Template Information
+// extension.
+var oidCertificateTemplateName = asn1.ObjectIdentifier{1, 3, 6, 1, 4, 1, 311, 20, 2}
+
+// Ensure the implementation satisfies the expected interfaces.
+var (
+	_ datasource.DataSource                   = &expiringCertificatesDataSource{}
+	_ datasource.DataSourceWithConfigure      = &expiringCertificatesDataSource{}
+	_ datasource.DataSourceWithValidateConfig = &expiringCertificatesDataSource{}
+)
+
+// NewExpiringCertificatesDataSource is a helper function to simplify the provider implementation.
+func NewExpiringCertificatesDataSource() datasource.DataSource {
+	return &expiringCertificatesDataSource{}
+}
+
+// expiringCertificatesDataSource finds the certificates in a range of request IDs that expire within
+// a window.
+type expiringCertificatesDataSource struct {
+	client   *client.ADCSClient
+	parser   *certsrvParser
+	provider *providerData
+}
+
+// expiringCertificatesModel maps the query and its results.
+type expiringCertificatesModel struct {
+	ID             types.String `tfsdk:"id"`
+	RequestIDFrom  types.Int64  `tfsdk:"request_id_from"`
+	RequestIDTo    types.Int64  `tfsdk:"request_id_to"`
+	WithinHours    types.Int64  `tfsdk:"within_hours"`
+	Template       types.String `tfsdk:"template"`
+	RequesterUPN   types.String `tfsdk:"requester_upn"`
+	IncludeExpired types.Bool   `tfsdk:"include_expired"`
+	Certificates   types.List   `tfsdk:"certificates"`
+	RequestIDs     types.List   `tfsdk:"request_ids"`
+}
+
+// expiringCertificate is a single entry of the certificates attribute.
+type expiringCertificate struct {
+	RequestID          string   `tfsdk:"request_id"`
+	Subject            string   `tfsdk:"subject"`
+	SerialNumber       string   `tfsdk:"serial_number"`
+	NotAfter           string   `tfsdk:"not_after"`
+	HoursRemaining     int64    `tfsdk:"hours_remaining"`
+	Template           string   `tfsdk:"template"`
+	DNSNames           []string `tfsdk:"dns_names"`
+	UserPrincipalNames []string `tfsdk:"user_principal_names"`
+}
+
+var expiringCertificateAttrTypes = map[string]attr.Type{
+	"request_id":           types.StringType,
+	"subject":              types.StringType,
+	"serial_number":        types.StringType,
+	"not_after":            types.StringType,
+	"hours_remaining":      types.Int64Type,
+	"template":             types.StringType,
+	"dns_names":            types.ListType{ElemType: types.StringType},
+	"user_principal_names": types.ListType{ElemType: types.StringType},
+}
+
+// expiryFilter decides which certificates the data source returns.
+type expiryFilter struct {
+	// deadline is the end of the window, certificates expiring later are skipped.
+	deadline time.Time
+	// now is the start of the window, certificates that expired before are skipped unless
+	// includeExpired is set.
+	now            time.Time
+	includeExpired bool
+	// templates are the names and OIDs the template of a certificate has to match, any template
+	// when empty.
+	templates []string
+	// requesterUPN is the user principal name a certificate has to carry, any when empty.
+	requesterUPN string
+}
+
+// certificateTemplate returns the template a certificate was issued from: the OID of the Certificate
+// Template Information extension of version 2 and later templates, or the name of the Certificate
+// Template Name extension of version 1 templates. It is empty when the certificate names neither,
+// such as certificates of standalone CAs.
+func certificateTemplate(cert *x509.Certificate) string {
+	name := ""
+	for _, extension := range cert.Extensions {
+		switch {
+		case extension.Id.Equal(oidCertificateTemplate):
+			var id certificateTemplateID
+			if _, err := asn1.Unmarshal(extension.Value, &id); err == nil {
+				return id.TemplateID.String()
+			}
+		case extension.Id.Equal(oidCertificateTemplateName):
+			var value asn1.RawValue
+			if _, err := asn1.Unmarshal(extension.Value, &value); err == nil {
+				name, _ = decodeBMPString(value)
+			}
+		}
+	}
+	return name
+}
+
+// matches reports whether the filter selects the certificate, and the user principal names it carries.
+func (f expiryFilter) matches(cert *x509.Certificate) (bool, []string) {
+	if cert.NotAfter.After(f.deadline) || (!f.includeExpired && f.now.After(cert.NotAfter)) {
+		return false, nil
+	}
+	if len(f.templates) > 0 {
+		template := certificateTemplate(cert)
+		found := false
+		for _, want := range f.templates {
+			found = found || strings.EqualFold(template, want)
+		}
+		if !found {
+			return false, nil
+		}
+	}
+	upns, _ := userPrincipalNames(cert.Extensions)
+	if f.requesterUPN == "" {
+		return true, upns
+	}
+	for _, upn := range upns {
+		if strings.EqualFold(upn, f.requesterUPN) {
+			return true, upns
+		}
+	}
+	return false, nil
+}
+
+// Configure adds the provider configured client to the data source.
+func (d *expiringCertificatesDataSource) Configure(_ context.Context, req datasource.ConfigureRequest, resp *datasource.ConfigureResponse) {
+	if req.ProviderData == nil {
+		return
+	}
+
+	data, ok := req.ProviderData.(*providerData)
+	if !ok {
+		resp.Diagnostics.AddError(
+			"Unexpected Data Source Configure Type",
+			fmt.Sprintf("Expected *providerData, got: %T. Please report this issue to the provider developers.", req.ProviderData),
+		)
+
+		return
+	}
+
+	d.client = data.client
+	d.parser = data.parser
+	d.provider = data
+}
+
+// Metadata returns the data source type name.
+func (d *expiringCertificatesDataSource) Metadata(_ context.Context, req datasource.MetadataRequest, resp *datasource.MetadataResponse) {
+	resp.TypeName = req.ProviderTypeName + "_expiring_certificates"
+}
+
+// Schema defines the schema for the data source.
+func (d *expiringCertificatesDataSource) Schema(_ context.Context, _ datasource.SchemaRequest, resp *datasource.SchemaResponse) {
+	resp.Schema = schema.Schema{
+		Description: `Finds the certificates in a range of request IDs that expire within a window, to drive notifications and
+renewals from Terraform. The certsrv web enrollment pages offer no way to query the CA database, so every request ID in
+the range is retrieved like the microsoftadcs_certificates data source does; requests that are pending, denied or
+unknown are skipped. The read fails when the CA cannot be reached or refuses the credentials, so an outage does not read
+as nothing expiring.`,
+		Attributes: map[string]schema.Attribute{
+			"id": schema.StringAttribute{
+				Computed:    true,
+				Description: "The probed request ID range.",
+			},
+			"request_id_from": schema.Int64Attribute{
+				Required:    true,
+				Description: "First request ID to probe.",
+			},
+			"request_id_to": schema.Int64Attribute{
+				Required:    true,
+				Description: fmt.Sprintf("Last request ID to probe. At most %d request IDs are probed per read.", maxDiscoveryRange),
+			},
+			"within_hours": schema.Int64Attribute{
+				Required:    true,
+				Description: "Window in hours from now, certificates expiring later are skipped.",
+			},
+			"template": schema.StringAttribute{
+				Optional: true,
+				Description: `Only return certificates issued from this template, given as its name or its OID. Certificates of
+version 2 and later templates only name the OID of their template, so a template name only matches them when ldap_url
+is configured to look the OID up.`,
+			},
+			"requester_upn": schema.StringAttribute{
+				Optional: true,
+				Description: `Only return certificates carrying this user principal name in their subject alternative names, such
+as jdoe@example.com. The CA does not record the requester in the certificate and certsrv does not expose it, but
+certificates built from the Active Directory object of the requester, such as those of the User template, carry
+its user principal name.`,
+			},
+			"include_expired": schema.BoolAttribute{
+				Optional:    true,
+				Description: "Include certificates that have already expired, defaults to false.",
+			},
+			"certificates": schema.ListNestedAttribute{
+				Computed:    true,
+				Description: "The certificates expiring within the window, soonest first.",
+				NestedObject: schema.NestedAttributeObject{
+					Attributes: map[string]schema.Attribute{
+						"request_id": schema.StringAttribute{
+							Computed:    true,
+							Description: "Request ID of the certificate.",
+						},
+						"subject": schema.StringAttribute{
+							Computed:    true,
+							Description: "Subject distinguished name of the certificate.",
+						},
+						"serial_number": schema.StringAttribute{
+							Computed:    true,
+							Description: "Hex encoded serial number of the certificate.",
+						},
+						"not_after": schema.StringAttribute{
+							Computed:    true,
+							Description: "Expiry of the certificate in RFC 3339 format.",
+						},
+						"hours_remaining": schema.Int64Attribute{
+							Computed:    true,
+							Description: "Whole hours until the certificate expires, negative once it has expired.",
+						},
+						"template": schema.StringAttribute{
+							Computed: true,
+							Description: `Template of the certificate, the OID for version 2 and later templates and the name for version 1
+templates. Empty when the certificate names no template.`,
+						},
+						"dns_names": schema.ListAttribute{
+							Computed:    true,
+							ElementType: types.StringType,
+							Description: "DNS names of the subject alternative names.",
+						},
+						"user_principal_names": schema.ListAttribute{
+							Computed:    true,
+							ElementType: types.StringType,
+							Description: "User principal names of the subject alternative names.",
+						},
+					},
+				},
+			},
+			"request_ids": schema.ListAttribute{
+				Computed:    true,
+				ElementType: types.StringType,
+				Description: "Request IDs of the certificates, in the order of certificates.",
+			},
+		},
+	}
+}
+
+// ValidateConfig checks the request ID range and the window.
+func (d *expiringCertificatesDataSource) ValidateConfig(ctx context.Context, req datasource.ValidateConfigRequest, resp *datasource.ValidateConfigResponse) {
+	var config expiringCertificatesModel
+	resp.Diagnostics.Append(req.Config.Get(ctx, &config)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	resp.Diagnostics.Append(validateRequestIDRange(config.RequestIDFrom, config.RequestIDTo)...)
+	if !config.WithinHours.IsUnknown() && config.WithinHours.ValueInt64() < 1 {
+		resp.Diagnostics.AddAttributeError(path.Root("within_hours"), "Invalid Window", "within_hours must be at least 1.")
+	}
+}
+
+// templateFilter returns the names and OIDs a certificate of template may carry. Template names are
+// looked up in Active Directory for their OID when ldap_url is configured.
+func (d *expiringCertificatesDataSource) templateFilter(ctx context.Context, template string) ([]string, error) {
+	if _, err := parseObjectIdentifier(template); err == nil {
+		return []string{template}, nil
+	}
+	if d.provider == nil || d.provider.templates == nil {
+		return []string{template}, nil
+	}
+	info, err := d.provider.templates.lookup(ctx, template)
+	if err != nil {
+		return nil, err
+	}
+	if info.OID == "" {
+		return []string{template}, nil
+	}
+	return []string{template, info.OID}, nil
+}
+
+// Read refreshes the Terraform state with the latest data.
+func (d *expiringCertificatesDataSource) Read(ctx context.Context, req datasource.ReadRequest, resp *datasource.ReadResponse) {
+	if d.provider.caOffline(ctx) {
+		resp.Diagnostics.Append(applyOnlyError("search the CA database"))
+		return
+	}
+	var data expiringCertificatesModel
+	resp.Diagnostics.Append(req.Config.Get(ctx, &data)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	now := time.Now()
+	filter := expiryFilter{
+		deadline:       now.Add(time.Duration(data.WithinHours.ValueInt64()) * time.Hour),
+		now:            now,
+		includeExpired: data.IncludeExpired.ValueBool(),
+		requesterUPN:   data.RequesterUPN.ValueString(),
+	}
+	if !data.Template.IsNull() {
+		templates, err := d.templateFilter(ctx, data.Template.ValueString())
+		if err != nil {
+			resp.Diagnostics.AddAttributeError(path.Root("template"), classifiedSummary("Unable to Look Up Template", err), err.Error())
+			return
+		}
+		filter.templates = templates
+	}
+
+	from, to := data.RequestIDFrom.ValueInt64(), data.RequestIDTo.ValueInt64()
+	expiring := []expiringCertificate{}
+	err := probeRequestIDs(ctx, d.client, d.parser, from, to, func(reqID string, certificates *client.Certificates) {
+		cert, err := parseCertificate(certificates.CertificateB64)
+		if err != nil {
+			resp.Diagnostics.AddWarning(
+				"Unable to Parse Certificate",
+				fmt.Sprintf("The certificate of request ID %s was skipped: %s", reqID, err.Error()),
+			)
+			return
+		}
+		ok, upns := filter.matches(cert)
+		if !ok {
+			return
+		}
+
+		expiring = append(expiring, expiringCertificate{
+			RequestID:          reqID,
+			Subject:            cert.Subject.String(),
+			SerialNumber:       fmt.Sprintf("%x", cert.SerialNumber),
+			NotAfter:           cert.NotAfter.UTC().Format(time.RFC3339),
+			HoursRemaining:     int64(cert.NotAfter.Sub(now) / time.Hour),
+			Template:           certificateTemplate(cert),
+			DNSNames:           append([]string{}, cert.DNSNames...),
+			UserPrincipalNames: append([]string{}, upns...),
+		})
+	})
+	if err != nil {
+		resp.Diagnostics.AddError(
+			classifiedSummary("Unable to Search Certificates", err),
+			fmt.Sprintf("Could not probe request IDs %d to %d for expiring certificates: %s", from, to, err.Error()),
+		)
+		return
+	}
+	// RFC 3339 timestamps in UTC sort chronologically.
+	sort.SliceStable(expiring, func(i, j int) bool { return expiring[i].NotAfter < expiring[j].NotAfter })
+
+	requestIDs := make([]string, len(expiring))
+	for i, cert := range expiring {
+		requestIDs[i] = cert.RequestID
+	}
+
+	data.ID = types.StringValue(fmt.Sprintf("%d-%d", from, to))
+	certificatesValue, diags := types.ListValueFrom(ctx, types.ObjectType{AttrTypes: expiringCertificateAttrTypes}, expiring)
+	resp.Diagnostics.Append(diags...)
+	requestIDsValue, diags := types.ListValueFrom(ctx, types.StringType, requestIDs)
+	resp.Diagnostics.Append(diags...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+	data.Certificates = certificatesValue
+	data.RequestIDs = requestIDsValue
+
+	resp.Diagnostics.Append(resp.State.Set(ctx, &data)...)
+}
@@ -0,0 +1,147 @@
+package provider
+
+import (
+	"context"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/terraform-plugin-testing/helper/resource"
+)
+
+// templateExtensions returns the extension naming a version 1 template, and the one naming a later
+// template by OID.
+func templateExtensions(t *testing.T) (pkix.Extension, pkix.Extension) {
+	t.Helper()
+	name, err := asn1.Marshal(asn1BMPString("WebServer"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	id, err := asn1.Marshal(certificateTemplateIDVersion{TemplateID: asn1.ObjectIdentifier{1, 3, 6, 1, 4, 1, 311, 21, 8, 1, 2}, MajorVersion: 100, MinorVersion: 4})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return pkix.Extension{Id: oidCertificateTemplateName, Value: name}, pkix.Extension{Id: oidCertificateTemplate, Value: id}
+}
+
+func TestCertificateTemplate(t *testing.T) {
+	v1, v2 := templateExtensions(t)
+	for name, tc := range map[string]struct {
+		extensions []pkix.Extension
+		want       string
+	}{
+		"none":      {},
+		"version 1": {extensions: []pkix.Extension{v1}, want: "WebServer"},
+		"version 2": {extensions: []pkix.Extension{v2}, want: "1.3.6.1.4.1.311.21.8.1.2"},
+		"both":      {extensions: []pkix.Extension{v1, v2}, want: "1.3.6.1.4.1.311.21.8.1.2"},
+	} {
+		if got := certificateTemplate(&x509.Certificate{Extensions: tc.extensions}); got != tc.want {
+			t.Errorf("%s: certificateTemplate() = %q, want %q", name, got, tc.want)
+		}
+	}
+}
+
+func TestExpiryFilter(t *testing.T) {
+	now := time.Now()
+	v1, v2 := templateExtensions(t)
+	soon := &x509.Certificate{NotAfter: now.Add(24 * time.Hour), Extensions: []pkix.Extension{v1}}
+	later := &x509.Certificate{NotAfter: now.Add(90 * 24 * time.Hour), Extensions: []pkix.Extension{v2}}
+	expired := &x509.Certificate{NotAfter: now.Add(-time.Hour)}
+
+	for name, tc := range map[string]struct {
+		filter expiryFilter
+		cert   *x509.Certificate
+		want   bool
+	}{
+		"within window":     {filter: expiryFilter{now: now, deadline: now.Add(48 * time.Hour)}, cert: soon, want: true},
+		"after window":      {filter: expiryFilter{now: now, deadline: now.Add(48 * time.Hour)}, cert: later},
+		"expired":           {filter: expiryFilter{now: now, deadline: now.Add(48 * time.Hour)}, cert: expired},
+		"include expired":   {filter: expiryFilter{now: now, deadline: now.Add(48 * time.Hour), includeExpired: true}, cert: expired, want: true},
+		"template name":     {filter: expiryFilter{now: now, deadline: now.Add(48 * time.Hour), templates: []string{"webserver"}}, cert: soon, want: true},
+		"template oid":      {filter: expiryFilter{now: now, deadline: now.Add(2400 * time.Hour), templates: []string{"WebServerV2", "1.3.6.1.4.1.311.21.8.1.2"}}, cert: later, want: true},
+		"other template":    {filter: expiryFilter{now: now, deadline: now.Add(48 * time.Hour), templates: []string{"User"}}, cert: soon},
+		"without template":  {filter: expiryFilter{now: now, deadline: now.Add(48 * time.Hour), includeExpired: true, templates: []string{"User"}}, cert: expired},
+		"without requester": {filter: expiryFilter{now: now, deadline: now.Add(48 * time.Hour), requesterUPN: "jdoe@example.com"}, cert: soon},
+	} {
+		if got, _ := tc.filter.matches(tc.cert); got != tc.want {
+			t.Errorf("%s: matches() = %t, want %t", name, got, tc.want)
+		}
+	}
+}
+
+func TestExpiringCertificatesTemplateFilter(t *testing.T) {
+	ctx := context.Background()
+	d := &expiringCertificatesDataSource{}
+	if got, err := d.templateFilter(ctx, "WebServer"); err != nil || !reflect.DeepEqual(got, []string{"WebServer"}) {
+		t.Errorf("templateFilter() without ldap_url = %v, %v", got, err)
+	}
+
+	d.provider = &providerData{templates: &templateDirectory{cache: map[string]*templateInfo{
+		"WebServerV2": {Name: "WebServerV2", OID: "1.3.6.1.4.1.311.21.8.1.2"},
+	}}}
+	if got, err := d.templateFilter(ctx, "WebServerV2"); err != nil || !reflect.DeepEqual(got, []string{"WebServerV2", "1.3.6.1.4.1.311.21.8.1.2"}) {
+		t.Errorf("templateFilter() = %v, %v, want the name and OID", got, err)
+	}
+	// OIDs are not looked up.
+	if got, err := d.templateFilter(ctx, "1.3.6.1.4.1.311.21.8.1.3"); err != nil || !reflect.DeepEqual(got, []string{"1.3.6.1.4.1.311.21.8.1.3"}) {
+		t.Errorf("templateFilter() for an OID = %v, %v", got, err)
+	}
+}
+
+func TestAccExpiringCertificatesDataSource(t *testing.T) {
+	srv := newAccCertsrv(t)
+	config := srv.providerConfig() + `
+resource "microsoftadcs_certificate" "web" {
+  template = "WebServer"
+
+  generate_csr {
+    common_name = "web.example.com"
+    dns_names   = ["web.example.com"]
+  }
+}
+
+resource "microsoftadcs_certificate" "user" {
+  template = "User"
+
+  generate_csr {
+    common_name = "jdoe"
+  }
+}
+
+data "microsoftadcs_expiring_certificates" "web" {
+  request_id_from = 101
+  request_id_to   = 110
+  within_hours    = 400 * 24
+  template        = "WebServer"
+
+  depends_on = [microsoftadcs_certificate.web, microsoftadcs_certificate.user]
+}
+
+data "microsoftadcs_expiring_certificates" "soon" {
+  request_id_from = 101
+  request_id_to   = 110
+  within_hours    = 24
+
+  depends_on = [microsoftadcs_certificate.web, microsoftadcs_certificate.user]
+}
+`
+
+	resource.Test(t, resource.TestCase{
+		ProtoV6ProviderFactories: testAccProtoV6ProviderFactories,
+		Steps: []resource.TestStep{
+			{
+				Config: config,
+				Check: resource.ComposeAggregateTestCheckFunc(
+					resource.TestCheckResourceAttr("data.microsoftadcs_expiring_certificates.web", "certificates.#", "1"),
+					resource.TestCheckResourceAttrPair("data.microsoftadcs_expiring_certificates.web", "request_ids.0", "microsoftadcs_certificate.web", "id"),
+					resource.TestCheckResourceAttr("data.microsoftadcs_expiring_certificates.web", "certificates.0.template", "WebServer"),
+					resource.TestCheckResourceAttr("data.microsoftadcs_expiring_certificates.web", "certificates.0.dns_names.0", "web.example.com"),
+					resource.TestCheckResourceAttr("data.microsoftadcs_expiring_certificates.soon", "certificates.#", "0"),
+				),
+			},
+		},
+	})
+}
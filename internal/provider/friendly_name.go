@@ -0,0 +1,47 @@
+package provider
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/hashicorp/terraform-plugin-framework/diag"
+	"github.com/hashicorp/terraform-plugin-framework/path"
+)
+
+// friendlyNameAttribute is the request attribute naming a request after the resource that
+// submitted it. The CA keeps it with the other request attributes, where the Certification
+// Authority console and certutil -view exports show it.
+const friendlyNameAttribute = "FriendlyName"
+
+// friendlyNameRequestAttribute returns the FriendlyName request attribute for friendly_name, empty
+// when it is not set.
+func (m *certificateCreateModel) friendlyNameRequestAttribute() string {
+	if m.FriendlyName.IsNull() || m.FriendlyName.IsUnknown() {
+		return ""
+	}
+	return friendlyNameAttribute + ":" + m.FriendlyName.ValueString()
+}
+
+// validateFriendlyName checks that friendly_name fits in a single request attribute and that no
+// request attribute sets the friendly name as well.
+func validateFriendlyName(config certificateCreateModel) diag.Diagnostics {
+	var diags diag.Diagnostics
+	if config.FriendlyName.IsNull() || config.FriendlyName.IsUnknown() {
+		return diags
+	}
+
+	if name := config.FriendlyName.ValueString(); strings.TrimSpace(name) == "" || strings.ContainsAny(name, "\r\n") {
+		diags.AddAttributeError(path.Root("friendly_name"), "Invalid Friendly Name",
+			fmt.Sprintf("friendly_name %q has to be a single non-empty line, request attribute values cannot span lines.", name))
+	}
+
+	if attributes := config.requestAttributes(); !attributes.IsUnknown() {
+		for _, attribute := range splitAttributes(attributes.ValueString()) {
+			if attributeName(attribute) == strings.ToLower(friendlyNameAttribute) {
+				diags.AddAttributeError(path.Root("friendly_name"), "Conflicting Friendly Name",
+					"Set the friendly name either in the FriendlyName entry of request_attributes or in friendly_name.")
+			}
+		}
+	}
+	return diags
+}
@@ -0,0 +1,57 @@
+package provider
+
+import (
+	"context"
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-framework/types"
+)
+
+func TestFriendlyNameRequestAttribute(t *testing.T) {
+	model := certificateCreateModel{
+		RequestAttributes: types.StringValue("Owner:platform"),
+		RequesterName:     types.StringValue(`EXAMPLE\jdoe`),
+		FriendlyName:      types.StringValue("module.web.microsoftadcs_certificate.tls"),
+	}
+	attributes, diags := model.submittedAttributes(context.Background())
+	if diags.HasError() {
+		t.Fatalf("unexpected diagnostics: %v", diags)
+	}
+	if want := "Owner:platform\nRequesterName:EXAMPLE\\jdoe\nFriendlyName:module.web.microsoftadcs_certificate.tls"; attributes != want {
+		t.Errorf("submittedAttributes() = %q, want %q", attributes, want)
+	}
+
+	if got := (&certificateCreateModel{}).friendlyNameRequestAttribute(); got != "" {
+		t.Errorf("friendlyNameRequestAttribute() = %q without friendly_name", got)
+	}
+}
+
+func TestValidateFriendlyName(t *testing.T) {
+	cases := map[string]struct {
+		name       string
+		attributes string
+		errors     int
+	}{
+		"none":       {},
+		"valid":      {name: "web-tls (prod)"},
+		"blank":      {name: "  ", errors: 1},
+		"multi-line": {name: "web\ntls", errors: 1},
+		"in request_attributes": {
+			name:       "web-tls",
+			attributes: "Owner:platform\nfriendlyname:web",
+			errors:     1,
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			config := certificateCreateModel{RequestAttributes: types.StringValue(tc.attributes)}
+			if tc.name != "" {
+				config.FriendlyName = types.StringValue(tc.name)
+			}
+			if diags := validateFriendlyName(config); diags.ErrorsCount() != tc.errors {
+				t.Errorf("got %d errors, want %d: %v", diags.ErrorsCount(), tc.errors, diags)
+			}
+		})
+	}
+}
@@ -0,0 +1,390 @@
+package provider
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"fmt"
+	"net"
+	"sort"
+	"strings"
+
+	"github.com/hashicorp/terraform-plugin-framework/diag"
+	"github.com/hashicorp/terraform-plugin-framework/path"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+	"github.com/hashicorp/terraform-plugin-framework/types/basetypes"
+	"github.com/hashicorp/terraform-plugin-log/tflog"
+)
+
+const (
+	keyAlgorithmRSA     = "RSA"
+	keyAlgorithmECDSA   = "ECDSA"
+	keyAlgorithmEd25519 = "ED25519"
+
+	defaultRSABits    = 2048
+	defaultECDSACurve = "P256"
+)
+
+// rsaKeySizes are the RSA key sizes generate_csr creates keys with.
+var rsaKeySizes = map[int64]bool{2048: true, 3072: true, 4096: true}
+
+// ecdsaCurves are the curves generate_csr creates ECDSA keys on, by the names the tls provider uses.
+var ecdsaCurves = map[string]elliptic.Curve{
+	"P256": elliptic.P256(),
+	"P384": elliptic.P384(),
+	"P521": elliptic.P521(),
+}
+
+// generateCSRModel maps the generate_csr block.
+type generateCSRModel struct {
+	KeyAlgorithm       types.String `tfsdk:"key_algorithm"`
+	RSABits            types.Int64  `tfsdk:"rsa_bits"`
+	ECDSACurve         types.String `tfsdk:"ecdsa_curve"`
+	CommonName         types.String `tfsdk:"common_name"`
+	Organization       types.String `tfsdk:"organization"`
+	OrganizationalUnit types.String `tfsdk:"organizational_unit"`
+	Locality           types.String `tfsdk:"locality"`
+	Province           types.String `tfsdk:"province"`
+	Country            types.String `tfsdk:"country"`
+	DNSNames           types.List   `tfsdk:"dns_names"`
+	IPAddresses        types.List   `tfsdk:"ip_addresses"`
+	EmailAddresses     types.List   `tfsdk:"email_addresses"`
+
+	TemplateOID          types.String `tfsdk:"template_oid"`
+	TemplateMajorVersion types.Int64  `tfsdk:"template_major_version"`
+	TemplateMinorVersion types.Int64  `tfsdk:"template_minor_version"`
+}
+
+// keyAlgorithm returns the key algorithm, RSA unless set.
+func (m *generateCSRModel) keyAlgorithm() string {
+	if m.KeyAlgorithm.IsNull() {
+		return keyAlgorithmRSA
+	}
+	return strings.ToUpper(m.KeyAlgorithm.ValueString())
+}
+
+// rsaBits returns the RSA key size, 2048 unless set.
+func (m *generateCSRModel) rsaBits() int64 {
+	if m.RSABits.IsNull() {
+		return defaultRSABits
+	}
+	return m.RSABits.ValueInt64()
+}
+
+// ecdsaCurve returns the name of the ECDSA curve, P256 unless set.
+func (m *generateCSRModel) ecdsaCurve() string {
+	if m.ECDSACurve.IsNull() {
+		return defaultECDSACurve
+	}
+	return strings.ToUpper(m.ECDSACurve.ValueString())
+}
+
+// generateKey creates the private key the block asks for.
+func (m *generateCSRModel) generateKey() (crypto.Signer, error) {
+	switch m.keyAlgorithm() {
+	case keyAlgorithmRSA:
+		return rsa.GenerateKey(rand.Reader, int(m.rsaBits()))
+	case keyAlgorithmECDSA:
+		curve, ok := ecdsaCurves[m.ecdsaCurve()]
+		if !ok {
+			return nil, fmt.Errorf("unsupported ECDSA curve %q", m.ecdsaCurve())
+		}
+		return ecdsa.GenerateKey(curve, rand.Reader)
+	}
+	return nil, fmt.Errorf("unsupported key algorithm %q", m.keyAlgorithm())
+}
+
+// signatureAlgorithm picks the algorithm the certificate signing request is signed with: SHA-256 for
+// RSA keys and, for ECDSA, the hash matching the curve size as RFC 5480 recommends. Without it Go signs
+// requests for every curve with SHA-256.
+func signatureAlgorithm(key crypto.Signer) x509.SignatureAlgorithm {
+	pub, ok := key.Public().(*ecdsa.PublicKey)
+	if !ok {
+		return x509.SHA256WithRSA
+	}
+	switch pub.Curve.Params().BitSize {
+	case 384:
+		return x509.ECDSAWithSHA384
+	case 521:
+		return x509.ECDSAWithSHA512
+	}
+	return x509.ECDSAWithSHA256
+}
+
+// template builds the certificate request the block describes.
+func (m *generateCSRModel) template(ctx context.Context) (*x509.CertificateRequest, diag.Diagnostics) {
+	var diags diag.Diagnostics
+	commonName, err := normalizeCommonName(m.CommonName.ValueString())
+	if err != nil {
+		diags.AddAttributeError(path.Root("generate_csr").AtName("common_name"), "Invalid Common Name", err.Error()+".")
+	}
+	template := &x509.CertificateRequest{
+		Subject: pkix.Name{CommonName: commonName},
+	}
+	for _, component := range []struct {
+		value types.String
+		field *[]string
+	}{
+		{value: m.Organization, field: &template.Subject.Organization},
+		{value: m.OrganizationalUnit, field: &template.Subject.OrganizationalUnit},
+		{value: m.Locality, field: &template.Subject.Locality},
+		{value: m.Province, field: &template.Subject.Province},
+		{value: m.Country, field: &template.Subject.Country},
+	} {
+		if component.value.ValueString() != "" {
+			*component.field = []string{component.value.ValueString()}
+		}
+	}
+
+	var dnsNames []string
+	diags.Append(m.DNSNames.ElementsAs(ctx, &dnsNames, false)...)
+	for _, name := range dnsNames {
+		normalized, err := normalizeDNSName(name)
+		if err != nil {
+			diags.AddAttributeError(path.Root("generate_csr").AtName("dns_names"), "Invalid DNS Name", err.Error()+".")
+			continue
+		}
+		template.DNSNames = append(template.DNSNames, normalized)
+	}
+	diags.Append(m.EmailAddresses.ElementsAs(ctx, &template.EmailAddresses, false)...)
+	var addresses []string
+	diags.Append(m.IPAddresses.ElementsAs(ctx, &addresses, false)...)
+	for _, address := range addresses {
+		ip := net.ParseIP(address)
+		if ip == nil {
+			diags.AddAttributeError(path.Root("generate_csr").AtName("ip_addresses"), "Invalid IP Address",
+				fmt.Sprintf("%q is not an IPv4 or IPv6 address.", address))
+			continue
+		}
+		template.IPAddresses = append(template.IPAddresses, ip)
+	}
+
+	extension, err := m.templateExtension()
+	if err != nil {
+		diags.AddAttributeError(path.Root("generate_csr").AtName("template_oid"), "Invalid Template OID", err.Error()+".")
+	} else if extension != nil {
+		template.ExtraExtensions = append(template.ExtraExtensions, *extension)
+	}
+	return template, diags
+}
+
+// generate creates a private key and a certificate signing request for it, both PEM encoded. The
+// key is PKCS#8 encoded whatever its algorithm.
+func (m *generateCSRModel) generate(ctx context.Context) (string, string, diag.Diagnostics) {
+	template, diags := m.template(ctx)
+	if diags.HasError() {
+		return "", "", diags
+	}
+
+	key, err := m.generateKey()
+	if err != nil {
+		diags.AddAttributeError(path.Root("generate_csr"), "Unable to Generate Private Key", err.Error())
+		return "", "", diags
+	}
+	keyDER, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		diags.AddAttributeError(path.Root("generate_csr"), "Unable to Generate Private Key", err.Error())
+		return "", "", diags
+	}
+	template.SignatureAlgorithm = signatureAlgorithm(key)
+	csrDER, err := x509.CreateCertificateRequest(rand.Reader, template, key)
+	if err != nil {
+		diags.AddAttributeError(path.Root("generate_csr"), "Unable to Generate Certificate Signing Request", err.Error())
+		return "", "", diags
+	}
+
+	csrPEM := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE REQUEST", Bytes: csrDER})
+	keyPEM := pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: keyDER})
+	return string(csrPEM), string(keyPEM), diags
+}
+
+// generateCSR creates the key and certificate signing request of the generate_csr block and sets
+// certificate_signing_request and generated_private_key_pem from them. The names of the
+// subject_alternative_names block are added to the request when they are too many for the san
+// request attribute.
+func (m *certificateCreateModel) generateCSR(ctx context.Context) diag.Diagnostics {
+	var block generateCSRModel
+	diags := m.GenerateCSR.As(ctx, &block, basetypes.ObjectAsOptions{})
+	if diags.HasError() {
+		return diags
+	}
+	if profile, ok := m.profile(); ok {
+		block.applyProfile(profile)
+	}
+	m.applyDomainController(ctx, &block)
+	if san, _ := sanRequestAttribute(ctx, m.subjectAlternativeNames()); m.sanInRequest(ctx, san) {
+		diags.Append(block.addSubjectAlternativeNames(ctx, m.subjectAlternativeNames())...)
+		if diags.HasError() {
+			return diags
+		}
+	}
+	csrPEM, keyPEM, generateDiags := block.generate(ctx)
+	diags.Append(generateDiags...)
+	if diags.HasError() {
+		return diags
+	}
+	tflog.Debug(ctx, "Generated private key and certificate signing request", map[string]interface{}{
+		"key_algorithm": block.keyAlgorithm(),
+	})
+	m.CSR = types.StringValue(csrPEM)
+	m.GeneratedPrivateKeyPEM = types.StringValue(keyPEM)
+	return diags
+}
+
+// addSubjectAlternativeNames appends the DNS names, IP addresses and email addresses of a
+// subject_alternative_names block to those of the generate_csr block.
+func (m *generateCSRModel) addSubjectAlternativeNames(ctx context.Context, block types.Object) diag.Diagnostics {
+	var names subjectAlternativeNamesModel
+	diags := block.As(ctx, &names, basetypes.ObjectAsOptions{})
+	if diags.HasError() {
+		return diags
+	}
+	merge := func(target *types.List, source types.List) {
+		var existing, added []string
+		diags.Append(target.ElementsAs(ctx, &existing, false)...)
+		diags.Append(source.ElementsAs(ctx, &added, false)...)
+		merged, listDiags := types.ListValueFrom(ctx, types.StringType, append(existing, added...))
+		diags.Append(listDiags...)
+		*target = merged
+	}
+	merge(&m.DNSNames, names.DNS)
+	merge(&m.IPAddresses, names.IP)
+	merge(&m.EmailAddresses, names.Email)
+	return diags
+}
+
+// validateGenerateCSR checks that the certificate is requested either for certificate_signing_request
+// or for a generate_csr block, and that the block describes a key the provider can create.
+func validateGenerateCSR(ctx context.Context, config certificateCreateModel) diag.Diagnostics {
+	var diags diag.Diagnostics
+
+	if config.GenerateCSR.IsNull() {
+		if config.CSR.IsNull() {
+			diags.AddAttributeError(
+				path.Root("certificate_signing_request"),
+				"Missing Certificate Signing Request",
+				"Set certificate_signing_request, or add a generate_csr block to let the provider create the key and request.",
+			)
+		}
+		return diags
+	}
+
+	for _, conflict := range []struct {
+		attribute string
+		set       bool
+	}{
+		{attribute: "certificate_signing_request", set: !config.CSR.IsNull()},
+		{attribute: "private_key_pem", set: !config.PrivateKeyPEM.IsNull()},
+		{attribute: "adopt_request_id", set: !config.AdoptRequestID.IsNull()},
+	} {
+		if conflict.set {
+			diags.AddAttributeError(
+				path.Root(conflict.attribute),
+				"Conflicting Certificate Signing Request",
+				fmt.Sprintf("%s cannot be combined with generate_csr, which creates the key and the request.", conflict.attribute),
+			)
+		}
+	}
+	if config.GenerateCSR.IsUnknown() {
+		return diags
+	}
+
+	var block generateCSRModel
+	diags.Append(config.GenerateCSR.As(ctx, &block, basetypes.ObjectAsOptions{})...)
+	if diags.HasError() {
+		return diags
+	}
+
+	blockPath := path.Root("generate_csr")
+	diags.Append(block.validateKey(blockPath.AtName)...)
+	diags.Append(block.validateTemplateExtension(blockPath.AtName)...)
+
+	if block.CommonName.IsNull() && block.DNSNames.IsNull() && block.IPAddresses.IsNull() && block.EmailAddresses.IsNull() &&
+		config.DomainController.IsNull() {
+		diags.AddAttributeError(blockPath, "Missing Subject",
+			"generate_csr needs a common_name or at least one subject alternative name.")
+	}
+	if !block.CommonName.IsUnknown() {
+		if _, err := normalizeCommonName(block.CommonName.ValueString()); err != nil {
+			diags.AddAttributeError(blockPath.AtName("common_name"), "Invalid Common Name", err.Error()+".")
+		}
+	}
+	if !block.DNSNames.IsUnknown() {
+		for i, element := range block.DNSNames.Elements() {
+			if name, ok := element.(types.String); ok && !name.IsUnknown() {
+				if _, err := normalizeDNSName(name.ValueString()); err != nil {
+					diags.AddAttributeError(blockPath.AtName("dns_names").AtListIndex(i), "Invalid DNS Name", err.Error()+".")
+				}
+			}
+		}
+	}
+	if !block.IPAddresses.IsUnknown() {
+		for i, element := range block.IPAddresses.Elements() {
+			if address, ok := element.(types.String); ok && !address.IsUnknown() && net.ParseIP(address.ValueString()) == nil {
+				diags.AddAttributeError(blockPath.AtName("ip_addresses").AtListIndex(i), "Invalid IP Address",
+					fmt.Sprintf("%q is not an IPv4 or IPv6 address.", address.ValueString()))
+			}
+		}
+	}
+	return diags
+}
+
+// validateKey checks that the key algorithm, size and curve describe a key the provider can create
+// and ADCS issues certificates for. at returns the path of an attribute by name.
+func (m *generateCSRModel) validateKey(at func(string) path.Path) diag.Diagnostics {
+	var diags diag.Diagnostics
+	switch {
+	case m.KeyAlgorithm.IsUnknown() || m.RSABits.IsUnknown() || m.ECDSACurve.IsUnknown():
+	case m.keyAlgorithm() == keyAlgorithmRSA && !rsaKeySizes[m.rsaBits()]:
+		diags.AddAttributeError(at("rsa_bits"), "Invalid RSA Key Size",
+			fmt.Sprintf("rsa_bits must be one of %s, got %d.", joinSortedKeys(rsaKeySizes), m.rsaBits()))
+	case m.keyAlgorithm() == keyAlgorithmECDSA && ecdsaCurves[m.ecdsaCurve()] == nil:
+		diags.AddAttributeError(at("ecdsa_curve"), "Invalid ECDSA Curve",
+			fmt.Sprintf("ecdsa_curve must be one of P256, P384 or P521, got %q.", m.ECDSACurve.ValueString()))
+	case m.keyAlgorithm() == keyAlgorithmEd25519:
+		diags.AddAttributeError(at("key_algorithm"), "Unsupported Key Algorithm",
+			"ADCS does not issue certificates for Ed25519 keys, use RSA or ECDSA.")
+	case m.keyAlgorithm() != keyAlgorithmRSA && m.keyAlgorithm() != keyAlgorithmECDSA:
+		diags.AddAttributeError(at("key_algorithm"), "Invalid Key Algorithm",
+			fmt.Sprintf("key_algorithm must be RSA or ECDSA, got %q.", m.KeyAlgorithm.ValueString()))
+	}
+	return diags
+}
+
+// generatedDNSNames returns the DNS names of the generate_csr block as they are requested, for the
+// checks of allowed_san_patterns.
+func generatedDNSNames(ctx context.Context, block types.Object) ([]string, diag.Diagnostics) {
+	var diags diag.Diagnostics
+	if block.IsNull() || block.IsUnknown() {
+		return nil, diags
+	}
+	var m generateCSRModel
+	diags.Append(block.As(ctx, &m, basetypes.ObjectAsOptions{})...)
+	if diags.HasError() {
+		return nil, diags
+	}
+	var names []string
+	diags.Append(m.DNSNames.ElementsAs(ctx, &names, false)...)
+	for i, name := range names {
+		if normalized, err := normalizeDNSName(name); err == nil {
+			names[i] = normalized
+		}
+	}
+	return names, diags
+}
+
+// joinSortedKeys lists the RSA key sizes for diagnostics.
+func joinSortedKeys(sizes map[int64]bool) string {
+	var keys []string
+	for size := range sizes {
+		keys = append(keys, fmt.Sprint(size))
+	}
+	sort.Strings(keys)
+	return strings.Join(keys, ", ")
+}
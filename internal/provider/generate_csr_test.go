@@ -0,0 +1,385 @@
+package provider
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/base64"
+	"encoding/pem"
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-framework/attr"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+	"github.com/hashicorp/terraform-plugin-testing/helper/resource"
+	"github.com/hashicorp/terraform-plugin-testing/terraform"
+)
+
+// testGenerateCSRBlock builds a generate_csr block value, leaving unset attributes null.
+func testGenerateCSRBlock(t *testing.T, block generateCSRModel) types.Object {
+	t.Helper()
+	for _, list := range []*types.List{&block.DNSNames, &block.IPAddresses, &block.EmailAddresses} {
+		if list.ElementType(context.Background()) == nil {
+			*list = types.ListNull(types.StringType)
+		}
+	}
+	value, diags := types.ObjectValueFrom(context.Background(), generateCSRAttrTypes(), block)
+	if diags.HasError() {
+		t.Fatalf("unexpected diagnostics: %v", diags)
+	}
+	return value
+}
+
+// generateCSRAttrTypes are the attribute types of the generate_csr block.
+func generateCSRAttrTypes() map[string]attr.Type {
+	return map[string]attr.Type{
+		"key_algorithm":       types.StringType,
+		"rsa_bits":            types.Int64Type,
+		"ecdsa_curve":         types.StringType,
+		"common_name":         types.StringType,
+		"organization":        types.StringType,
+		"organizational_unit": types.StringType,
+		"locality":            types.StringType,
+		"province":            types.StringType,
+		"country":             types.StringType,
+		"dns_names":           types.ListType{ElemType: types.StringType},
+		"ip_addresses":        types.ListType{ElemType: types.StringType},
+		"email_addresses":     types.ListType{ElemType: types.StringType},
+
+		"template_oid":           types.StringType,
+		"template_major_version": types.Int64Type,
+		"template_minor_version": types.Int64Type,
+	}
+}
+
+func testStringList(values ...string) types.List {
+	elements := make([]attr.Value, 0, len(values))
+	for _, value := range values {
+		elements = append(elements, types.StringValue(value))
+	}
+	return types.ListValueMust(types.StringType, elements)
+}
+
+func TestGenerateCSR(t *testing.T) {
+	cases := map[string]struct {
+		block     generateCSRModel
+		signature x509.SignatureAlgorithm
+		check     func(t *testing.T, key interface{})
+	}{
+		"default RSA": {
+			block:     generateCSRModel{CommonName: types.StringValue("web.example.com")},
+			signature: x509.SHA256WithRSA,
+			check: func(t *testing.T, key interface{}) {
+				if rsaKey, ok := key.(*rsa.PublicKey); !ok || rsaKey.N.BitLen() != 2048 {
+					t.Errorf("key = %T, want a 2048 bit RSA key", key)
+				}
+			},
+		},
+		"ECDSA P384": {
+			block: generateCSRModel{
+				KeyAlgorithm: types.StringValue("ECDSA"),
+				ECDSACurve:   types.StringValue("P384"),
+				CommonName:   types.StringValue("web.example.com"),
+			},
+			signature: x509.ECDSAWithSHA384,
+			check: func(t *testing.T, key interface{}) {
+				if ecKey, ok := key.(*ecdsa.PublicKey); !ok || ecKey.Curve.Params().Name != "P-384" {
+					t.Errorf("key = %T, want a P-384 ECDSA key", key)
+				}
+			},
+		},
+		"ECDSA P521": {
+			block: generateCSRModel{
+				KeyAlgorithm: types.StringValue("ecdsa"),
+				ECDSACurve:   types.StringValue("p521"),
+				CommonName:   types.StringValue("web.example.com"),
+			},
+			signature: x509.ECDSAWithSHA512,
+			check: func(t *testing.T, key interface{}) {
+				if ecKey, ok := key.(*ecdsa.PublicKey); !ok || ecKey.Curve.Params().Name != "P-521" {
+					t.Errorf("key = %T, want a P-521 ECDSA key", key)
+				}
+			},
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			tc.block.Organization = types.StringValue("Example")
+			tc.block.Country = types.StringValue("NL")
+			tc.block.DNSNames = testStringList("web.example.com", "www.example.com")
+			tc.block.IPAddresses = testStringList("10.0.0.1")
+			plan := certificateCreateModel{GenerateCSR: testGenerateCSRBlock(t, tc.block)}
+
+			diags := plan.generateCSR(context.Background())
+			if diags.HasError() {
+				t.Fatalf("unexpected diagnostics: %v", diags)
+			}
+
+			request, err := parseCertificateRequest(plan.CSR.ValueString())
+			if err != nil {
+				t.Fatalf("certificate_signing_request is not a request: %v", err)
+			}
+			if err := request.CheckSignature(); err != nil {
+				t.Errorf("request signature: %v", err)
+			}
+			if request.SignatureAlgorithm != tc.signature {
+				t.Errorf("signature algorithm = %s, want %s", request.SignatureAlgorithm, tc.signature)
+			}
+			if request.Subject.CommonName != "web.example.com" || len(request.Subject.Organization) != 1 || request.Subject.Country[0] != "NL" {
+				t.Errorf("subject = %s", request.Subject)
+			}
+			if len(request.DNSNames) != 2 || len(request.IPAddresses) != 1 || request.IPAddresses[0].String() != "10.0.0.1" {
+				t.Errorf("names = %v %v", request.DNSNames, request.IPAddresses)
+			}
+			tc.check(t, request.PublicKey)
+
+			key, err := parsePrivateKey(plan.GeneratedPrivateKeyPEM.ValueString())
+			if err != nil {
+				t.Fatalf("generated_private_key_pem is not a key: %v", err)
+			}
+			if !keyMatches(key, request.PublicKey) {
+				t.Error("generated_private_key_pem does not belong to the request")
+			}
+		})
+	}
+}
+
+func TestValidateGenerateCSR(t *testing.T) {
+	cases := map[string]struct {
+		csr     bool
+		key     bool
+		adopt   bool
+		block   *generateCSRModel
+		summary []string
+	}{
+		"csr": {csr: true},
+		"neither": {
+			summary: []string{"Missing Certificate Signing Request"},
+		},
+		"generated": {
+			block: &generateCSRModel{CommonName: types.StringValue("web.example.com")},
+		},
+		"conflicts": {
+			csr:     true,
+			key:     true,
+			adopt:   true,
+			block:   &generateCSRModel{CommonName: types.StringValue("web.example.com")},
+			summary: []string{"Conflicting Certificate Signing Request", "Conflicting Certificate Signing Request", "Conflicting Certificate Signing Request"},
+		},
+		"rsa bits": {
+			block:   &generateCSRModel{RSABits: types.Int64Value(1024), CommonName: types.StringValue("web.example.com")},
+			summary: []string{"Invalid RSA Key Size"},
+		},
+		"curve": {
+			block:   &generateCSRModel{KeyAlgorithm: types.StringValue("ECDSA"), ECDSACurve: types.StringValue("P224"), CommonName: types.StringValue("web.example.com")},
+			summary: []string{"Invalid ECDSA Curve"},
+		},
+		"algorithm": {
+			block:   &generateCSRModel{KeyAlgorithm: types.StringValue("DSA"), CommonName: types.StringValue("web.example.com")},
+			summary: []string{"Invalid Key Algorithm"},
+		},
+		"ed25519": {
+			block:   &generateCSRModel{KeyAlgorithm: types.StringValue("Ed25519"), CommonName: types.StringValue("web.example.com")},
+			summary: []string{"Unsupported Key Algorithm"},
+		},
+		"no subject": {
+			block:   &generateCSRModel{},
+			summary: []string{"Missing Subject"},
+		},
+		"ip address": {
+			block:   &generateCSRModel{IPAddresses: testStringList("10.0.0.1", "web.example.com")},
+			summary: []string{"Invalid IP Address"},
+		},
+		"internationalized and wildcard names": {
+			block: &generateCSRModel{CommonName: types.StringValue("*.bücher.example"), DNSNames: testStringList("*.bücher.example", "bücher.example")},
+		},
+		"misplaced wildcards": {
+			block:   &generateCSRModel{CommonName: types.StringValue("web*.example.com"), DNSNames: testStringList("*.com", "a.*.example.com")},
+			summary: []string{"Invalid Common Name", "Invalid DNS Name", "Invalid DNS Name"},
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			config := certificateCreateModel{
+				CSR:            types.StringNull(),
+				PrivateKeyPEM:  types.StringNull(),
+				AdoptRequestID: types.StringNull(),
+				GenerateCSR:    types.ObjectNull(generateCSRAttrTypes()),
+			}
+			if tc.csr {
+				config.CSR = types.StringValue("request")
+			}
+			if tc.key {
+				config.PrivateKeyPEM = types.StringValue("key")
+			}
+			if tc.adopt {
+				config.AdoptRequestID = types.StringValue("7")
+			}
+			if tc.block != nil {
+				config.GenerateCSR = testGenerateCSRBlock(t, *tc.block)
+			}
+
+			diags := validateGenerateCSR(context.Background(), config)
+			if len(diags) != len(tc.summary) {
+				t.Fatalf("got %d diagnostics, want %d: %v", len(diags), len(tc.summary), diags)
+			}
+			for i, d := range diags {
+				if d.Summary() != tc.summary[i] {
+					t.Errorf("diagnostic %d = %q, want %q", i, d.Summary(), tc.summary[i])
+				}
+			}
+		})
+	}
+}
+
+func TestGeneratedECDSAKeyOutputs(t *testing.T) {
+	h := newTestHierarchy(t)
+	plan := certificateCreateModel{GenerateCSR: testGenerateCSRBlock(t, generateCSRModel{
+		KeyAlgorithm: types.StringValue("ECDSA"),
+		ECDSACurve:   types.StringValue("P384"),
+		CommonName:   types.StringValue("app.example.com"),
+	})}
+	if diags := plan.generateCSR(context.Background()); diags.HasError() {
+		t.Fatalf("unexpected diagnostics: %v", diags)
+	}
+	request, err := parseCertificateRequest(plan.CSR.ValueString())
+	if err != nil {
+		t.Fatalf("could not parse request: %v", err)
+	}
+
+	// Issue the leaf for the generated key the way the CA would.
+	template := *h.leaf
+	der, err := x509.CreateCertificate(rand.Reader, &template, h.issuing, request.PublicKey, h.issuingKey)
+	if err != nil {
+		t.Fatalf("could not issue certificate: %v", err)
+	}
+	h.leaf, _ = x509.ParseCertificate(der)
+
+	if diags := plan.setCertificateOutputs(context.Background(), h.testCertificates(t)); diags.HasError() {
+		t.Fatalf("unexpected diagnostics: %v", diags)
+	}
+	if !strings.Contains(plan.CombinedPEM.ValueString(), "PRIVATE KEY") {
+		t.Error("combined_pem does not hold the generated key")
+	}
+	attributes := plan.AzureKeyVaultCertificate.Attributes()
+	if attributes["key_type"].String() != `"EC"` || attributes["curve"].String() != `"P-384"` || attributes["key_size"].String() != "384" {
+		t.Errorf("azure_key_vault_certificate key properties = %v %v %v", attributes["key_type"], attributes["curve"], attributes["key_size"])
+	}
+}
+
+func TestValidateRequestKeyEd25519(t *testing.T) {
+	_, key, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatalf("could not generate key: %v", err)
+	}
+	der, err := x509.CreateCertificateRequest(rand.Reader, &x509.CertificateRequest{Subject: pkix.Name{CommonName: "web.example.com"}}, key)
+	if err != nil {
+		t.Fatalf("could not create request: %v", err)
+	}
+	request := string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE REQUEST", Bytes: der}))
+
+	diags := validateRequestKey(types.StringValue(request))
+	if len(diags) != 1 || diags[0].Summary() != "Unsupported Key Algorithm" {
+		t.Errorf("validateRequestKey() = %v", diags)
+	}
+	rsaRequest, err := base64.StdEncoding.DecodeString(csr)
+	if err != nil {
+		t.Fatalf("could not decode request: %v", err)
+	}
+	if diags := validateRequestKey(types.StringValue(string(rsaRequest))); diags.HasError() {
+		t.Errorf("validateRequestKey() of an RSA request = %v", diags)
+	}
+
+	keyDER, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		t.Fatalf("could not marshal key: %v", err)
+	}
+	diags = validatePrivateKey(certificateCreateModel{
+		CSR:           types.StringValue(request),
+		PrivateKeyPEM: types.StringValue(string(pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: keyDER}))),
+	})
+	if len(diags) != 1 || diags[0].Summary() != "Unsupported Key Algorithm" {
+		t.Errorf("validatePrivateKey() = %v", diags)
+	}
+}
+
+func TestGeneratedDNSNamesUnknownBlock(t *testing.T) {
+	names, diags := generatedDNSNames(context.Background(), types.ObjectUnknown(generateCSRAttrTypes()))
+	if diags.HasError() || names != nil {
+		t.Errorf("generatedDNSNames() = %v, %v", names, diags)
+	}
+}
+
+func TestAccCertificateResourceGenerateCSR(t *testing.T) {
+	srv := newAccCertsrv(t)
+
+	resource.Test(t, resource.TestCase{
+		ProtoV6ProviderFactories: testAccProtoV6ProviderFactories,
+		Steps: []resource.TestStep{
+			{
+				Config: srv.providerConfig() + `
+resource "microsoftadcs_certificate" "test" {
+  template = "WebServer"
+
+  generate_csr {
+    key_algorithm = "ECDSA"
+    common_name   = "web.example.com"
+    dns_names     = ["web.example.com"]
+  }
+}
+`,
+				Check: resource.ComposeAggregateTestCheckFunc(
+					resource.TestCheckResourceAttrSet("microsoftadcs_certificate.test", "certificate_signing_request"),
+					resource.TestCheckResourceAttrSet("microsoftadcs_certificate.test", "combined_pem"),
+					func(s *terraform.State) error {
+						attributes := s.RootModule().Resources["microsoftadcs_certificate.test"].Primary.Attributes
+						key, err := parsePrivateKey(attributes["generated_private_key_pem"])
+						if err != nil {
+							return fmt.Errorf("generated_private_key_pem: %v", err)
+						}
+						cert, err := parseCertificate(attributes["certificate_pem"])
+						if err != nil {
+							return fmt.Errorf("certificate_pem: %v", err)
+						}
+						if !keyMatches(key, cert.PublicKey) {
+							return fmt.Errorf("the certificate was not issued for the generated key")
+						}
+						return nil
+					},
+				),
+			},
+		},
+	})
+}
+
+func TestGenerateCSRInternationalizedNames(t *testing.T) {
+	block := generateCSRModel{
+		KeyAlgorithm:   types.StringValue("ECDSA"),
+		CommonName:     types.StringValue("Bücher.example"),
+		DNSNames:       testStringList("bücher.example", "*.Bücher.example"),
+		IPAddresses:    types.ListNull(types.StringType),
+		EmailAddresses: types.ListNull(types.StringType),
+	}
+	csrPEM, _, diags := block.generate(context.Background())
+	if diags.HasError() {
+		t.Fatalf("unexpected diagnostics: %v", diags)
+	}
+	request, err := parseCertificateRequest(csrPEM)
+	if err != nil {
+		t.Fatalf("could not parse generated request: %v", err)
+	}
+	if request.Subject.CommonName != "xn--bcher-kva.example" {
+		t.Errorf("common name = %q, want the punycode form", request.Subject.CommonName)
+	}
+	if want := []string{"xn--bcher-kva.example", "*.xn--bcher-kva.example"}; !reflect.DeepEqual(request.DNSNames, want) {
+		t.Errorf("DNSNames = %v, want %v", request.DNSNames, want)
+	}
+}
@@ -0,0 +1,38 @@
+package provider
+
+import (
+	"context"
+	"net"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// krb5ServerRegex matches the krb5.conf realm entries that name a server to connect to.
+var krb5ServerRegex = regexp.MustCompile(`(?m)^(\s*(?:kdc|master_kdc|admin_server|kpasswd_server)\s*=\s*)([^\s:]+)`)
+
+// aliasDialContext returns a dial function that connects to the address configured in host_aliases
+// instead of resolving the host. Hosts without an alias are dialed as usual.
+func aliasDialContext(aliases map[string]string) func(ctx context.Context, network, addr string) (net.Conn, error) {
+	dialer := &net.Dialer{Timeout: 30 * time.Second, KeepAlive: 30 * time.Second}
+	return func(ctx context.Context, network, addr string) (net.Conn, error) {
+		if host, port, err := net.SplitHostPort(addr); err == nil {
+			if alias, ok := aliases[strings.ToLower(host)]; ok {
+				addr = net.JoinHostPort(alias, port)
+			}
+		}
+		return dialer.DialContext(ctx, network, addr)
+	}
+}
+
+// aliasKDCs rewrites the servers of the krb5.conf realms that have an alias, so the Kerberos
+// client reaches the KDC without resolving its name. Ports are kept.
+func aliasKDCs(krb5conf string, aliases map[string]string) string {
+	return krb5ServerRegex.ReplaceAllStringFunc(krb5conf, func(line string) string {
+		match := krb5ServerRegex.FindStringSubmatch(line)
+		if alias, ok := aliases[strings.ToLower(match[2])]; ok {
+			return match[1] + alias
+		}
+		return line
+	})
+}
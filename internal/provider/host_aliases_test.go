@@ -0,0 +1,54 @@
+package provider
+
+import (
+	"context"
+	"net"
+	"testing"
+)
+
+func TestAliasKDCs(t *testing.T) {
+	krb5conf := `[libdefaults]
+  default_realm = COMPANY.LOCAL
+
+[realms]
+  COMPANY.LOCAL = {
+    kdc = DC01.company.local:88
+    kdc = dc02.company.local
+    admin_server = dc01.company.local
+  }
+`
+	want := `[libdefaults]
+  default_realm = COMPANY.LOCAL
+
+[realms]
+  COMPANY.LOCAL = {
+    kdc = 10.1.2.3:88
+    kdc = dc02.company.local
+    admin_server = 10.1.2.3
+  }
+`
+	if got := aliasKDCs(krb5conf, map[string]string{"dc01.company.local": "10.1.2.3"}); got != want {
+		t.Errorf("aliasKDCs() =\n%s\nwant\n%s", got, want)
+	}
+}
+
+func TestAliasDialContext(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("could not listen: %v", err)
+	}
+	defer listener.Close()
+	go func() {
+		if conn, err := listener.Accept(); err == nil {
+			conn.Close()
+		}
+	}()
+
+	_, port, _ := net.SplitHostPort(listener.Addr().String())
+	dial := aliasDialContext(map[string]string{"ca.internal": "127.0.0.1"})
+	conn, err := dial(context.Background(), "tcp", net.JoinHostPort("ca.internal", port))
+	if err != nil {
+		t.Fatalf("dialing the aliased host failed: %v", err)
+	}
+	conn.Close()
+}
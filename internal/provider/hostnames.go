@@ -0,0 +1,113 @@
+package provider
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/hashicorp/terraform-plugin-framework/types/basetypes"
+	"golang.org/x/net/idna"
+)
+
+// dnsNameProfile converts internationalized domain names to their punycode form. STD3 rules are
+// relaxed because Active Directory host names may hold underscores, dnsNameCharacters checks the
+// result instead.
+var dnsNameProfile = idna.New(idna.MapForLookup(), idna.BidiRule(), idna.VerifyDNSLength(true), idna.StrictDomainName(false))
+
+// dnsNameCharacters are the characters left in a DNS name after the punycode conversion.
+const dnsNameCharacters = "abcdefghijklmnopqrstuvwxyz0123456789-_."
+
+// normalizeDNSName validates a DNS name for a certificate and returns it the way it has to be
+// requested: lower case, with internationalized labels in punycode, as certificates carry DNS
+// names as IA5String. A wildcard is only accepted as the whole left-most label and has to be
+// followed by at least two labels.
+func normalizeDNSName(name string) (string, error) {
+	host := strings.TrimPrefix(name, "*.")
+	wildcard := host != name
+	if strings.Contains(host, "*") {
+		return "", fmt.Errorf("%q has a wildcard outside the left-most label, only names such as *.example.com can be requested", name)
+	}
+	if strings.HasSuffix(host, ".") {
+		return "", fmt.Errorf("%q ends with a dot, certificates hold names without the trailing dot", name)
+	}
+
+	ascii, err := dnsNameProfile.ToASCII(host)
+	if err != nil {
+		return "", fmt.Errorf("%q is not a valid DNS name: %v", name, err)
+	}
+	if i := strings.IndexFunc(ascii, func(r rune) bool { return !strings.ContainsRune(dnsNameCharacters, r) }); i >= 0 {
+		return "", fmt.Errorf("%q is not a valid DNS name: %q is not allowed in host names", name, ascii[i])
+	}
+	if wildcard {
+		if !strings.Contains(ascii, ".") {
+			return "", fmt.Errorf("%q covers a whole top-level domain, a wildcard has to be followed by at least two labels", name)
+		}
+		ascii = "*." + ascii
+	}
+	return ascii, nil
+}
+
+// normalizeCommonName converts a common name holding an internationalized host name to punycode and
+// validates wildcards. Common names that are not host names, such as the name of a person, are
+// returned unchanged.
+func normalizeCommonName(cn string) (string, error) {
+	if strings.Contains(cn, "*") {
+		return normalizeDNSName(cn)
+	}
+	if isASCII(cn) || !strings.Contains(cn, ".") || strings.ContainsAny(cn, " @") {
+		return cn, nil
+	}
+	if ascii, err := normalizeDNSName(cn); err == nil {
+		return ascii, nil
+	}
+	return cn, nil
+}
+
+// wildcardNames returns the names that are wildcards.
+func wildcardNames(names []string) []string {
+	var wildcards []string
+	for _, name := range names {
+		if strings.HasPrefix(name, "*") {
+			wildcards = append(wildcards, name)
+		}
+	}
+	return wildcards
+}
+
+// requestedWildcards returns the wildcard names a certificate is requested for, from the common name
+// and subject alternative names of the certificate signing request or generate_csr block and from
+// the san request attribute. Names that cannot be read are left to the other validations.
+func requestedWildcards(ctx context.Context, config certificateCreateModel) []string {
+	attributes, diags := config.submittedAttributes(ctx)
+	generated, generatedDiags := generatedDNSNames(ctx, config.GenerateCSR)
+	diags.Append(generatedDiags...)
+	if diags.HasError() {
+		return nil
+	}
+	names, err := requestedSubjectAlternativeNames(config.CSR.ValueString(), attributes)
+	if err != nil {
+		return nil
+	}
+	names.DNSNames = append(names.DNSNames, generated...)
+
+	if request, err := parseCertificateRequest(config.CSR.ValueString()); err == nil {
+		names.DNSNames = append(names.DNSNames, request.Subject.CommonName)
+	}
+	if !config.GenerateCSR.IsNull() {
+		var block generateCSRModel
+		if diags := config.GenerateCSR.As(ctx, &block, basetypes.ObjectAsOptions{}); !diags.HasError() {
+			names.DNSNames = append(names.DNSNames, block.CommonName.ValueString())
+		}
+	}
+	return wildcardNames(names.DNSNames)
+}
+
+// isASCII reports whether s holds only ASCII characters.
+func isASCII(s string) bool {
+	for i := 0; i < len(s); i++ {
+		if s[i] >= 0x80 {
+			return false
+		}
+	}
+	return true
+}
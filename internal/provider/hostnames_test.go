@@ -0,0 +1,64 @@
+package provider
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestNormalizeDNSName(t *testing.T) {
+	cases := map[string]string{
+		"www.example.com":          "www.example.com",
+		"WWW.Example.COM":          "www.example.com",
+		"bücher.example":           "xn--bcher-kva.example",
+		"xn--bcher-kva.example":    "xn--bcher-kva.example",
+		"*.münchen.de":             "*.xn--mnchen-3ya.de",
+		"host_01.corp.example.com": "host_01.corp.example.com",
+		"*.com":                    "",
+		"*":                        "",
+		"a.*.example.com":          "",
+		"w*.example.com":           "",
+		"www.example.com.":         "",
+		"a..example.com":           "",
+		"bad name.example.com":     "",
+		"odd&name=.example.com":    "",
+	}
+
+	for name, want := range cases {
+		got, err := normalizeDNSName(name)
+		if want == "" {
+			if err == nil {
+				t.Errorf("normalizeDNSName(%q) = %q, want an error", name, got)
+			}
+			continue
+		}
+		if err != nil || got != want {
+			t.Errorf("normalizeDNSName(%q) = %q, %v, want %q", name, got, err, want)
+		}
+	}
+}
+
+func TestNormalizeCommonName(t *testing.T) {
+	cases := map[string]string{
+		"Web.Example.com":     "Web.Example.com",
+		"José Pérez":          "José Pérez",
+		"Bücher.example":      "xn--bcher-kva.example",
+		"josé@bücher.example": "josé@bücher.example",
+		"*.Example.com":       "*.example.com",
+	}
+	for cn, want := range cases {
+		if got, err := normalizeCommonName(cn); err != nil || got != want {
+			t.Errorf("normalizeCommonName(%q) = %q, %v, want %q", cn, got, err, want)
+		}
+	}
+
+	if _, err := normalizeCommonName("web*.example.com"); err == nil {
+		t.Errorf("expected an error for a wildcard inside a label")
+	}
+}
+
+func TestWildcardNames(t *testing.T) {
+	got := wildcardNames([]string{"www.example.com", "*.example.com", "", "*.xn--bcher-kva.example"})
+	if want := []string{"*.example.com", "*.xn--bcher-kva.example"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("wildcardNames() = %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,156 @@
+package provider
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/hex"
+	"fmt"
+	"math/big"
+	"strconv"
+	"strings"
+
+	"github.com/hashicorp/terraform-plugin-log/tflog"
+)
+
+const (
+	// maxImportSearch limits how many request IDs a serial number or thumbprint import probes,
+	// every ID costs a round trip.
+	maxImportSearch = 10000
+	// importSearchMisses is how many request IDs in a row without a certificate end a search once a
+	// certificate has been seen, the end of the CA database. Gaps before the first certificate are
+	// requests removed from the database and do not count.
+	importSearchMisses = 50
+)
+
+// importID is a parsed import ID: the request ID to import, or the serial number or thumbprint to
+// find it by.
+type importID struct {
+	requestID  string
+	serial     *big.Int
+	thumbprint []byte
+	// searchFrom is the request ID a serial number or thumbprint search starts at.
+	searchFrom int64
+}
+
+// search reports whether the request ID has to be found by searching the CA database.
+func (id importID) search() bool {
+	return id.serial != nil || id.thumbprint != nil
+}
+
+// String describes what a search looks for.
+func (id importID) String() string {
+	if id.thumbprint != nil {
+		return fmt.Sprintf("thumbprint %x", id.thumbprint)
+	}
+	return fmt.Sprintf("serial number %x", id.serial)
+}
+
+// matches reports whether cert is the certificate the import ID searches for.
+func (id importID) matches(cert *x509.Certificate) bool {
+	if id.thumbprint != nil {
+		sha1Sum, sha256Sum := sha1.Sum(cert.Raw), sha256.Sum256(cert.Raw)
+		return bytes.Equal(id.thumbprint, sha1Sum[:]) || bytes.Equal(id.thumbprint, sha256Sum[:])
+	}
+	return cert.SerialNumber.Cmp(id.serial) == 0
+}
+
+// parseImportID parses the import ID of a certificate. It is a request ID, either plain or as
+// "request:<id>", "serial:<hex>[@<request id>]" to find the request by the serial number of its
+// certificate or "thumbprint:<hex>[@<request id>]" to find it by the SHA-1 or SHA-256 thumbprint,
+// starting the search at the given request ID.
+func parseImportID(id string) (importID, error) {
+	kind, value, found := strings.Cut(strings.TrimSpace(id), ":")
+	if !found {
+		kind, value = "request", kind
+	}
+	kind = strings.ToLower(kind)
+
+	if kind == "request" {
+		if n, err := strconv.ParseInt(value, 10, 64); err != nil || n < 1 {
+			return importID{}, fmt.Errorf("%q is not a request ID, request IDs are positive numbers", value)
+		}
+		return importID{requestID: value}, nil
+	}
+	if kind != "serial" && kind != "thumbprint" {
+		return importID{}, fmt.Errorf("unknown import ID format %q, use a request ID, request:<id>, serial:<hex> or thumbprint:<hex>", kind)
+	}
+
+	result := importID{searchFrom: 1}
+	value, from, hasFrom := strings.Cut(value, "@")
+	if hasFrom {
+		n, err := strconv.ParseInt(from, 10, 64)
+		if err != nil || n < 1 {
+			return importID{}, fmt.Errorf("%q is not a request ID to start the search at, request IDs are positive numbers", from)
+		}
+		result.searchFrom = n
+	}
+	if kind == "thumbprint" {
+		thumbprint, err := hex.DecodeString(hexSeparators.Replace(value))
+		if err != nil || (len(thumbprint) != sha1.Size && len(thumbprint) != sha256.Size) {
+			return importID{}, fmt.Errorf("%q is not a hex encoded SHA-1 or SHA-256 thumbprint", value)
+		}
+		result.thumbprint = thumbprint
+		return result, nil
+	}
+	var ok bool
+	if result.serial, ok = parseSerialNumber(value); !ok {
+		return importID{}, fmt.Errorf("%q is not a hex encoded serial number", value)
+	}
+	return result, nil
+}
+
+// hexSeparators removes the spaces and colons certutil and the certificate dialog of Windows show
+// between the bytes of serial numbers and thumbprints.
+var hexSeparators = strings.NewReplacer(" ", "", ":", "")
+
+// parseSerialNumber parses a hex encoded serial number, ignoring case and separators.
+func parseSerialNumber(serial string) (*big.Int, bool) {
+	serial = hexSeparators.Replace(serial)
+	serial = strings.TrimPrefix(strings.TrimPrefix(serial, "0x"), "0X")
+	if serial == "" {
+		return nil, false
+	}
+	return new(big.Int).SetString(serial, 16)
+}
+
+// findRequest returns the request ID of the certificate the import ID searches for. The certsrv
+// web enrollment pages cannot search the CA database, so request IDs are retrieved one by one from
+// id.searchFrom until the certificate turns up. Only request IDs the CA answered for without a
+// certificate count towards the end of the database, the search stops at any other failure.
+func (r *certificateResource) findRequest(ctx context.Context, id importID) (string, error) {
+	seen, misses := false, 0
+	for reqID := id.searchFrom; reqID < id.searchFrom+maxImportSearch; reqID++ {
+		reqIDString := strconv.FormatInt(reqID, 10)
+		certificates, err := retrieveCertificate(ctx, r.client, r.provider.parser, reqIDString)
+		if err != nil {
+			if !hasNoCertificate(err) {
+				return "", fmt.Errorf("could not retrieve request ID %d: %w", reqID, err)
+			}
+			tflog.Debug(ctx, "Skipping request ID without an issued certificate", map[string]interface{}{
+				"request_id": reqIDString,
+				"error":      err.Error(),
+			})
+			if misses++; seen && misses >= importSearchMisses {
+				return "", fmt.Errorf("no certificate with %s up to request ID %d, where the CA database ends", id, reqID)
+			}
+			continue
+		}
+		seen, misses = true, 0
+		cert, err := parseCertificate(certificates.CertificateB64)
+		if err != nil {
+			tflog.Debug(ctx, "Skipping request ID with a certificate that does not parse", map[string]interface{}{
+				"request_id": reqIDString,
+				"error":      err.Error(),
+			})
+			continue
+		}
+		if id.matches(cert) {
+			return reqIDString, nil
+		}
+	}
+	return "", fmt.Errorf("no certificate with %s in request IDs %d to %d, append @<request id> to the import ID to search further",
+		id, id.searchFrom, id.searchFrom+maxImportSearch-1)
+}
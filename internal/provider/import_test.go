@@ -0,0 +1,169 @@
+package provider
+
+import (
+	"context"
+	"crypto/sha1"
+	"crypto/sha256"
+	"fmt"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-framework/diag"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+	"github.com/hashicorp/terraform-plugin-testing/helper/resource"
+	"github.com/hashicorp/terraform-plugin-testing/terraform"
+)
+
+func TestParseImportID(t *testing.T) {
+	cases := map[string]struct {
+		requestID  string
+		serial     string
+		thumbprint string
+		from       int64
+		err        string
+	}{
+		"5123":                   {requestID: "5123"},
+		"request:5123":           {requestID: "5123"},
+		"Request:5123":           {requestID: "5123"},
+		"serial:61000000057Ba3":  {serial: "61000000057ba3", from: 1},
+		"serial:61 00 00 00 05":  {serial: "6100000005", from: 1},
+		"serial:0x61:00:05@4000": {serial: "610005", from: 4000},
+		"abc":                    {err: "not a request ID"},
+		"request:0":              {err: "not a request ID"},
+		"serial:xyz":             {err: "not a hex encoded serial number"},
+		"serial:":                {err: "not a hex encoded serial number"},
+		"serial:61@start":        {err: "to start the search at"},
+		"thumbprint:" + strings.Repeat("AB", 20) + "@7": {thumbprint: strings.Repeat("ab", 20), from: 7},
+		"thumbprint:" + strings.Repeat("ab ", 32):       {thumbprint: strings.Repeat("ab", 32), from: 1},
+		"thumbprint:0a1b2c3d4e5f60":                     {err: "not a hex encoded SHA-1 or SHA-256 thumbprint"},
+		"fingerprint:0a1b2c3d":                          {err: "unknown import ID format"},
+	}
+
+	for input, tc := range cases {
+		t.Run(input, func(t *testing.T) {
+			id, err := parseImportID(input)
+			if tc.err != "" {
+				if err == nil || !strings.Contains(err.Error(), tc.err) {
+					t.Fatalf("parseImportID() error = %v, want %q", err, tc.err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("parseImportID() error = %v", err)
+			}
+			if id.requestID != tc.requestID || id.searchFrom != tc.from {
+				t.Errorf("parseImportID() = %+v", id)
+			}
+			if got := fmt.Sprintf("%x", id.serial); (id.serial != nil || tc.serial != "") && got != tc.serial {
+				t.Errorf("serial = %s, want %s", got, tc.serial)
+			}
+			if got := fmt.Sprintf("%x", id.thumbprint); got != tc.thumbprint {
+				t.Errorf("thumbprint = %s, want %s", got, tc.thumbprint)
+			}
+		})
+	}
+}
+
+func TestFindRequest(t *testing.T) {
+	srv := newAccCertsrv(t)
+	t.Setenv("ADCS_PASSWORD", "secret")
+	data, err := debugEnrollConfigure(context.Background(), "test", map[string]interface{}{
+		"host":     srv.host(),
+		"username": "svc-terraform",
+		"use_ntlm": true,
+	}, io.Discard)
+	if err != nil {
+		t.Fatal(err)
+	}
+	r := &certificateResource{client: data.client, provider: data}
+
+	var reqIDs []string
+	for i := 0; i < 3; i++ {
+		csr, err := debugEnrollRequest(context.Background(), "", fmt.Sprintf("app%d.example.com", i))
+		if err != nil {
+			t.Fatal(err)
+		}
+		plan := &certificateCreateModel{CSR: types.StringValue(csr), Template: types.StringValue("WebServer")}
+		var diags diag.Diagnostics
+		certificates := r.requestCertificate(context.Background(), plan, csr, "", &diags)
+		if diags.HasError() {
+			t.Fatalf("unexpected diagnostics: %v", diags)
+		}
+		reqIDs = append(reqIDs, certificates.ID)
+	}
+
+	// The acceptance certsrv numbers requests from 101, the gap before is not the end of the database.
+	want := srv.request(reqIDs[1]).cert.SerialNumber
+	got, err := r.findRequest(context.Background(), importID{serial: want, searchFrom: 1})
+	if err != nil || got != reqIDs[1] {
+		t.Fatalf("findRequest() = %q, %v, want %s", got, err, reqIDs[1])
+	}
+
+	cert := srv.request(reqIDs[2]).cert
+	sha1Sum, sha256Sum := sha1.Sum(cert.Raw), sha256.Sum256(cert.Raw)
+	for _, thumbprint := range [][]byte{sha1Sum[:], sha256Sum[:]} {
+		got, err := r.findRequest(context.Background(), importID{thumbprint: thumbprint, searchFrom: 100})
+		if err != nil || got != reqIDs[2] {
+			t.Errorf("findRequest(thumbprint %x) = %q, %v, want %s", thumbprint, got, err, reqIDs[2])
+		}
+	}
+
+	if _, err := r.findRequest(context.Background(), importID{serial: want, searchFrom: 103}); err == nil || !strings.Contains(err.Error(), "where the CA database ends") {
+		t.Errorf("error = %v, want the search to stop at the end of the database", err)
+	}
+
+	// A CA that cannot be reached is not the end of the database.
+	srv.server.Close()
+	_, err = r.findRequest(withRetryPolicy(context.Background(), noRetry), importID{serial: want, searchFrom: 1})
+	if errorClass(classifyError(err)) != errorClassTransient || !strings.Contains(err.Error(), "could not retrieve request ID 1") {
+		t.Errorf("error = %v, want the search to stop at the unreachable CA", err)
+	}
+}
+
+func TestAccCertificateResourceImportBySearch(t *testing.T) {
+	srv := newAccCertsrv(t)
+	resource.Test(t, resource.TestCase{
+		ProtoV6ProviderFactories: testAccProtoV6ProviderFactories,
+		Steps: []resource.TestStep{
+			{
+				Config: srv.providerConfig() + `
+resource "microsoftadcs_certificate" "test" {
+  template = "WebServer"
+
+  generate_csr {
+    common_name = "app.example.com"
+  }
+}
+`,
+			},
+			{
+				ResourceName: "microsoftadcs_certificate.test",
+				ImportState:  true,
+				ImportStateIdFunc: func(s *terraform.State) (string, error) {
+					reqID := s.RootModule().Resources["microsoftadcs_certificate.test"].Primary.ID
+					return fmt.Sprintf("serial:%X@100", srv.request(reqID).cert.SerialNumber), nil
+				},
+				ImportStateCheck: func(states []*terraform.InstanceState) error {
+					if len(states) != 1 || states[0].ID != "101" {
+						return fmt.Errorf("imported %d states, want request ID 101", len(states))
+					}
+					return nil
+				},
+			},
+			{
+				ResourceName: "microsoftadcs_certificate.test",
+				ImportState:  true,
+				ImportStateIdFunc: func(s *terraform.State) (string, error) {
+					return "thumbprint:" + s.RootModule().Resources["microsoftadcs_certificate.test"].Primary.Attributes["thumbprint_sha1"], nil
+				},
+				ImportStateCheck: func(states []*terraform.InstanceState) error {
+					if len(states) != 1 || states[0].ID != "101" {
+						return fmt.Errorf("imported %d states, want request ID 101", len(states))
+					}
+					return nil
+				},
+			},
+		},
+	})
+}
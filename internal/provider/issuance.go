@@ -0,0 +1,240 @@
+package provider
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/flipyap/microsoft-adcs-client/client"
+)
+
+const (
+	// defaultIssuanceTimeout is how long wait_for_issuance waits for a CA manager by default.
+	defaultIssuanceTimeout = time.Hour
+	// issuancePollInterval is the time between two polling rounds over the pending requests.
+	issuancePollInterval = 30 * time.Second
+	// maxIssuancePollInterval caps the interval when the CA keeps failing and polling backs off.
+	maxIssuancePollInterval = 10 * time.Minute
+	// maxPendingCheckInterval caps the time between two checks of a request that stays pending.
+	maxPendingCheckInterval = 5 * time.Minute
+	// issuancePollBudget is the most requests checked in a single round. With more requests pending,
+	// the rounds take turns so every request is still checked regularly.
+	issuancePollBudget = 10
+)
+
+// isStillPending reports whether a failed download means the request has not been decided yet.
+// certsrv answers downloads of pending requests with the "Taken Under Submission" disposition.
+func isStillPending(err error) bool {
+	message := strings.ToLower(err.Error())
+	return strings.Contains(message, "under submission") || strings.Contains(message, "pending")
+}
+
+// isTransient reports whether a failed download says nothing about the request because the CA
+// could not be reached or failed to answer.
+func isTransient(err error) bool {
+	message := err.Error()
+	return strings.Contains(message, "error making request") || strings.Contains(message, "status error: 5")
+}
+
+// pendingBackoff spaces out the checks of a request that stays pending, approvals can take hours.
+type pendingBackoff struct {
+	due   time.Time
+	delay time.Duration
+}
+
+// issuanceResult is handed to every resource waiting for a request once it has been decided.
+type issuanceResult struct {
+	certificates *client.Certificates
+	err          error
+}
+
+// pendingPoller checks all requests that resources wait on from a single goroutine, so a large
+// apply with many requests pending approval does not poll the CA once per resource and interval.
+// Each round checks at most budget requests one after another, and the interval doubles while the
+// CA is unreachable. A request that stays pending is checked less and less often, up to
+// maxPendingCheckInterval apart.
+type pendingPoller struct {
+	interval time.Duration
+	budget   int
+	check    func(ctx context.Context, reqID string) (*client.Certificates, error)
+
+	mu      sync.Mutex
+	waiters map[string][]chan issuanceResult
+	// queue holds the pending request IDs in the order they are checked next.
+	queue   []string
+	backoff map[string]pendingBackoff
+	running bool
+}
+
+// newPendingPoller creates the poller shared by all resources of a provider instance.
+func newPendingPoller(c *client.ADCSClient, parser *certsrvParser) *pendingPoller {
+	return &pendingPoller{
+		interval: issuancePollInterval,
+		budget:   issuancePollBudget,
+		check: func(ctx context.Context, reqID string) (*client.Certificates, error) {
+			// The poller backs off from transient errors on its own.
+			return retrieveCertificates(withRetryPolicy(ctx, noRetry), c, parser, reqID)
+		},
+		waiters: map[string][]chan issuanceResult{},
+		backoff: map[string]pendingBackoff{},
+	}
+}
+
+// wait blocks until a CA manager issued the pending request. Any other outcome, such as a denial,
+// ends the wait with an error.
+func (p *pendingPoller) wait(ctx context.Context, reqID string, timeout time.Duration) (*client.Certificates, error) {
+	waitCtx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	p.mu.Lock()
+	result := p.add(reqID)
+	if !p.running {
+		p.running = true
+		go p.run()
+	}
+	p.mu.Unlock()
+
+	select {
+	case r := <-result:
+		return r.certificates, r.err
+	case <-waitCtx.Done():
+		p.mu.Lock()
+		p.remove(reqID, result)
+		p.mu.Unlock()
+		// The operation itself may end first, such as when the create timeout is shorter.
+		if err := ctx.Err(); err != nil {
+			return nil, fmt.Errorf("request ID %s was still pending approval when the wait was cut short: %v", reqID, err)
+		}
+		return nil, fmt.Errorf("request ID %s was still pending approval after %s", reqID, timeout)
+	}
+}
+
+// add registers a waiter for the request, p.mu must be held.
+func (p *pendingPoller) add(reqID string) chan issuanceResult {
+	result := make(chan issuanceResult, 1)
+	if _, ok := p.waiters[reqID]; !ok {
+		p.queue = append(p.queue, reqID)
+	}
+	p.waiters[reqID] = append(p.waiters[reqID], result)
+	return result
+}
+
+// remove unregisters a waiter that gave up and drops the request once nobody waits for it,
+// p.mu must be held.
+func (p *pendingPoller) remove(reqID string, result chan issuanceResult) {
+	waiters := p.waiters[reqID]
+	for i, waiter := range waiters {
+		if waiter == result {
+			waiters = append(waiters[:i], waiters[i+1:]...)
+			break
+		}
+	}
+	if len(waiters) > 0 {
+		p.waiters[reqID] = waiters
+		return
+	}
+	delete(p.waiters, reqID)
+	p.dequeue(reqID)
+}
+
+// dequeue drops the request from the polling queue, p.mu must be held.
+func (p *pendingPoller) dequeue(reqID string) {
+	delete(p.backoff, reqID)
+	for i, queued := range p.queue {
+		if queued == reqID {
+			p.queue = append(p.queue[:i], p.queue[i+1:]...)
+			return
+		}
+	}
+}
+
+// deliver hands the outcome of a request to everyone waiting for it.
+func (p *pendingPoller) deliver(reqID string, result issuanceResult) {
+	p.mu.Lock()
+	waiters := p.waiters[reqID]
+	delete(p.waiters, reqID)
+	p.dequeue(reqID)
+	p.mu.Unlock()
+
+	for _, waiter := range waiters {
+		waiter <- result
+	}
+}
+
+// run polls until no request is pending anymore.
+func (p *pendingPoller) run() {
+	interval := p.interval
+	for {
+		time.Sleep(interval)
+
+		p.mu.Lock()
+		if len(p.queue) == 0 {
+			p.running = false
+			p.mu.Unlock()
+			return
+		}
+		p.mu.Unlock()
+
+		if p.poll(context.Background()) {
+			interval *= 2
+			if interval > maxIssuancePollInterval {
+				interval = maxIssuancePollInterval
+			}
+		} else {
+			interval = p.interval
+		}
+	}
+}
+
+// postpone schedules the next check of a request that is still pending, doubling the delay since
+// the previous check.
+func (p *pendingPoller) postpone(reqID string, now time.Time) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if _, ok := p.waiters[reqID]; !ok {
+		return
+	}
+	delay := p.backoff[reqID].delay * 2
+	if delay < p.interval {
+		delay = p.interval
+	}
+	if delay > maxPendingCheckInterval {
+		delay = maxPendingCheckInterval
+	}
+	p.backoff[reqID] = pendingBackoff{due: now.Add(delay), delay: delay}
+}
+
+// poll runs a single round over the next requests in the queue that are due. It reports whether
+// the round was cut short because the CA could not be reached.
+func (p *pendingPoller) poll(ctx context.Context) bool {
+	now := time.Now()
+	p.mu.Lock()
+	var round, rest []string
+	for _, reqID := range p.queue {
+		if len(round) < p.budget && !now.Before(p.backoff[reqID].due) {
+			round = append(round, reqID)
+		} else {
+			rest = append(rest, reqID)
+		}
+	}
+	// Requests checked in this round move to the back, so the next round starts with the others.
+	p.queue = append(rest, round...)
+	p.mu.Unlock()
+
+	for _, reqID := range round {
+		certificates, err := p.check(ctx, reqID)
+		switch {
+		case err == nil:
+			p.deliver(reqID, issuanceResult{certificates: certificates})
+		case isTransient(err):
+			return true
+		case !isStillPending(err):
+			p.deliver(reqID, issuanceResult{err: fmt.Errorf("request ID %s was not issued: %v", reqID, err)})
+		default:
+			p.postpone(reqID, now)
+		}
+	}
+	return false
+}
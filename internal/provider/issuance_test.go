@@ -0,0 +1,145 @@
+package provider
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/flipyap/microsoft-adcs-client/client"
+)
+
+func TestPendingPollerFansOutResults(t *testing.T) {
+	var mu sync.Mutex
+	checks := map[string]int{}
+	poller := &pendingPoller{
+		interval: time.Millisecond,
+		budget:   issuancePollBudget,
+		check: func(_ context.Context, reqID string) (*client.Certificates, error) {
+			mu.Lock()
+			defer mu.Unlock()
+			checks[reqID]++
+			if checks[reqID] < 3 {
+				return nil, errors.New("certificate pending for request id " + reqID)
+			}
+			if reqID == "2" {
+				return nil, errors.New("Denied by Policy Module")
+			}
+			return &client.Certificates{ID: reqID}, nil
+		},
+		waiters: map[string][]chan issuanceResult{},
+		backoff: map[string]pendingBackoff{},
+	}
+
+	type outcome struct {
+		reqID string
+		err   error
+	}
+	outcomes := make(chan outcome, 3)
+	for _, reqID := range []string{"1", "1", "2"} {
+		go func(reqID string) {
+			certificates, err := poller.wait(context.Background(), reqID, time.Minute)
+			if err == nil && certificates.ID != reqID {
+				t.Errorf("waiter for %s got certificate %s", reqID, certificates.ID)
+			}
+			outcomes <- outcome{reqID, err}
+		}(reqID)
+	}
+
+	for i := 0; i < 3; i++ {
+		o := <-outcomes
+		if (o.reqID == "2") != (o.err != nil) {
+			t.Errorf("request ID %s: unexpected error %v", o.reqID, o.err)
+		}
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	// Both waiters of request ID 1 share the same checks. A late waiter may add a round at most.
+	if checks["1"] > 4 {
+		t.Errorf("request ID 1 was checked %d times, want it shared between both waiters", checks["1"])
+	}
+}
+
+func TestPendingPollerBudget(t *testing.T) {
+	var checked []string
+	poller := &pendingPoller{
+		budget: 2,
+		check: func(_ context.Context, reqID string) (*client.Certificates, error) {
+			checked = append(checked, reqID)
+			return nil, errors.New("certificate pending for request id " + reqID)
+		},
+		waiters: map[string][]chan issuanceResult{},
+		backoff: map[string]pendingBackoff{},
+	}
+	for _, reqID := range []string{"1", "2", "3"} {
+		poller.add(reqID)
+	}
+
+	poller.poll(context.Background())
+	poller.poll(context.Background())
+	if want := []string{"1", "2", "3", "1"}; !reflect.DeepEqual(checked, want) {
+		t.Errorf("checked %v, want %v", checked, want)
+	}
+}
+
+func TestPendingPollerBacksOffOnTransientErrors(t *testing.T) {
+	var checked []string
+	poller := &pendingPoller{
+		budget: 2,
+		check: func(_ context.Context, reqID string) (*client.Certificates, error) {
+			checked = append(checked, reqID)
+			return nil, errors.New("failed to download certificate: status error: 503")
+		},
+		waiters: map[string][]chan issuanceResult{},
+		backoff: map[string]pendingBackoff{},
+	}
+	result := poller.add("1")
+	poller.add("2")
+
+	if !poller.poll(context.Background()) {
+		t.Error("poll did not report the unreachable CA")
+	}
+	if want := []string{"1"}; !reflect.DeepEqual(checked, want) {
+		t.Errorf("checked %v, want the round to stop after the first failure", checked)
+	}
+	select {
+	case r := <-result:
+		t.Errorf("transient error was delivered to the waiter: %v", r.err)
+	default:
+	}
+}
+
+func TestPendingPollerBacksOffWhilePending(t *testing.T) {
+	var checked []string
+	poller := &pendingPoller{
+		interval: time.Minute,
+		budget:   issuancePollBudget,
+		check: func(_ context.Context, reqID string) (*client.Certificates, error) {
+			checked = append(checked, reqID)
+			return nil, errors.New("Taken Under Submission")
+		},
+		waiters: map[string][]chan issuanceResult{},
+		backoff: map[string]pendingBackoff{},
+	}
+	poller.add("1")
+
+	poller.poll(context.Background())
+	poller.poll(context.Background())
+	if want := []string{"1"}; !reflect.DeepEqual(checked, want) {
+		t.Errorf("checked %v, want the request to wait until it is due again", checked)
+	}
+
+	var delays []time.Duration
+	for i := 0; i < 5; i++ {
+		poller.backoff["1"] = pendingBackoff{delay: poller.backoff["1"].delay}
+		poller.poll(context.Background())
+		delays = append(delays, poller.backoff["1"].delay)
+	}
+	want := []time.Duration{2 * time.Minute, 4 * time.Minute, maxPendingCheckInterval, maxPendingCheckInterval, maxPendingCheckInterval}
+	if !reflect.DeepEqual(delays, want) {
+		t.Errorf("delays = %v, want %v", delays, want)
+	}
+}
@@ -0,0 +1,209 @@
+package provider
+
+import (
+	"context"
+	"crypto/x509"
+	"fmt"
+	"net"
+	"net/url"
+	"sort"
+	"strings"
+
+	"github.com/flipyap/microsoft-adcs-client/client"
+	"github.com/hashicorp/terraform-plugin-framework/diag"
+	"github.com/hashicorp/terraform-plugin-framework/path"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+	"github.com/hashicorp/terraform-plugin-framework/types/basetypes"
+)
+
+// Values of verify_issued_sans, what happens when the issued certificate does not carry the
+// subject alternative names that were requested.
+const (
+	verifyIssuedSANsError = "error"
+	verifyIssuedSANsWarn  = "warn"
+)
+
+// verifyIssuedSANsModes lists the values of verify_issued_sans.
+var verifyIssuedSANsModes = []string{verifyIssuedSANsError, verifyIssuedSANsWarn}
+
+// sanSet holds subject alternative names in the "type:value" form, such as "dns:www.example.com",
+// with values in the form certificates carry them.
+type sanSet map[string]bool
+
+// add adds a name of the given type, canonicalizing its value so that names compare equal however
+// they were written.
+func (s sanSet) add(kind, value string) {
+	switch kind {
+	case "dns":
+		if normalized, err := normalizeDNSName(value); err == nil {
+			value = normalized
+		}
+		value = strings.ToLower(value)
+	case "ip":
+		if ip := net.ParseIP(value); ip != nil {
+			value = ip.String()
+		}
+	case "email", "upn":
+		value = strings.ToLower(value)
+	}
+	s[kind+":"+value] = true
+}
+
+// addCertificateNames adds the subject alternative names of a certificate or request.
+func (s sanSet) addCertificateNames(dnsNames []string, ips []net.IP, emails []string, uris []string, upns []string) {
+	for _, name := range dnsNames {
+		s.add("dns", name)
+	}
+	for _, ip := range ips {
+		s.add("ip", ip.String())
+	}
+	for _, email := range emails {
+		s.add("email", email)
+	}
+	for _, uri := range uris {
+		s.add("uri", uri)
+	}
+	for _, upn := range upns {
+		s.add("upn", upn)
+	}
+}
+
+// missingFrom returns the names of s that other lacks, sorted.
+func (s sanSet) missingFrom(other sanSet) []string {
+	var missing []string
+	for name := range s {
+		if !other[name] {
+			missing = append(missing, name)
+		}
+	}
+	sort.Strings(missing)
+	return missing
+}
+
+// issuedSANs returns the subject alternative names of an issued certificate.
+func issuedSANs(cert *x509.Certificate) (sanSet, error) {
+	upns, err := userPrincipalNames(cert.Extensions)
+	if err != nil {
+		return nil, err
+	}
+	names := sanSet{}
+	names.addCertificateNames(cert.DNSNames, cert.IPAddresses, cert.EmailAddresses, uriStrings(cert.URIs), upns)
+	return names, nil
+}
+
+// requestedSANs returns the subject alternative names of the certificate signing request, which
+// holds those of generate_csr, together with those of subject_alternative_names or the typed name
+// lists.
+func (m *certificateCreateModel) requestedSANs(ctx context.Context) (sanSet, diag.Diagnostics) {
+	var diags diag.Diagnostics
+	names := sanSet{}
+
+	if csr := m.CSR.ValueString(); csr != "" {
+		request, err := parseCertificateRequest(csr)
+		if err != nil {
+			diags.AddError("Error Parsing Certificate Signing Request", "Could not read the requested subject alternative names: "+err.Error())
+			return nil, diags
+		}
+		upns, err := userPrincipalNames(request.Extensions)
+		if err != nil {
+			diags.AddError("Error Parsing Certificate Signing Request", "Could not read the requested subject alternative names: "+err.Error())
+			return nil, diags
+		}
+		names.addCertificateNames(request.DNSNames, request.IPAddresses, request.EmailAddresses, uriStrings(request.URIs), upns)
+	}
+
+	block := m.subjectAlternativeNames()
+	if block.IsNull() || block.IsUnknown() {
+		return names, diags
+	}
+	var entries subjectAlternativeNamesModel
+	diags.Append(block.As(ctx, &entries, basetypes.ObjectAsOptions{})...)
+	for kind, list := range map[string]types.List{"dns": entries.DNS, "ip": entries.IP, "email": entries.Email, "upn": entries.UPN, "uri": entries.URI} {
+		var values []string
+		diags.Append(list.ElementsAs(ctx, &values, false)...)
+		for _, value := range values {
+			names.add(kind, value)
+		}
+	}
+	return names, diags
+}
+
+// uriStrings returns the URIs as strings.
+func uriStrings(uris []*url.URL) []string {
+	values := make([]string, 0, len(uris))
+	for _, uri := range uris {
+		values = append(values, uri.String())
+	}
+	return values
+}
+
+// checkIssuedSANs compares the subject alternative names of the issued certificate with the
+// requested ones when verify_issued_sans is set. Policy modules drop names the template does not
+// take from the request, or replace them with names from Active Directory, without failing the
+// request.
+func (m *certificateCreateModel) checkIssuedSANs(ctx context.Context, certificates *client.Certificates) diag.Diagnostics {
+	var diags diag.Diagnostics
+	if m.VerifyIssuedSANs.IsNull() || m.VerifyIssuedSANs.IsUnknown() {
+		return diags
+	}
+
+	cert, err := parseCertificate(certificates.CertificateB64)
+	if err != nil {
+		diags.AddError(
+			classifiedSummary("Error Parsing Certificate", &ParseError{Err: err}),
+			fmt.Sprintf("Could not parse the certificate issued for request ID %s to verify its subject alternative names: %s", certificates.ID, err.Error()),
+		)
+		return diags
+	}
+	issued, err := issuedSANs(cert)
+	if err != nil {
+		diags.AddError(
+			classifiedSummary("Error Parsing Certificate", &ParseError{Err: err}),
+			fmt.Sprintf("Could not read the subject alternative names of the certificate issued for request ID %s: %s", certificates.ID, err.Error()),
+		)
+		return diags
+	}
+	requested, requestedDiags := m.requestedSANs(ctx)
+	diags.Append(requestedDiags...)
+	if diags.HasError() {
+		return diags
+	}
+
+	missing, added := requested.missingFrom(issued), issued.missingFrom(requested)
+	if len(missing) == 0 && len(added) == 0 {
+		return diags
+	}
+	var differences []string
+	if len(missing) > 0 {
+		differences = append(differences, "missing "+strings.Join(missing, ", "))
+	}
+	if len(added) > 0 {
+		differences = append(differences, "not requested "+strings.Join(added, ", "))
+	}
+	summary := "Issued Subject Alternative Names Differ From Request"
+	detail := fmt.Sprintf("Request ID %s was issued with other subject alternative names than requested: %s. The policy module of the CA "+
+		"drops the names of the request when the template builds the subject from Active Directory, and ignores the san request attribute "+
+		"without the EDITF_ATTRIBUTESUBJECTALTNAME2 flag.", certificates.ID, strings.Join(differences, "; "))
+	if m.VerifyIssuedSANs.ValueString() == verifyIssuedSANsWarn {
+		diags.AddAttributeWarning(path.Root("verify_issued_sans"), summary, detail)
+		return diags
+	}
+	diags.AddAttributeError(path.Root("verify_issued_sans"), summary, detail+" The certificate has been issued by the CA and may need to be revoked.")
+	return diags
+}
+
+// validateVerifyIssuedSANs checks verify_issued_sans.
+func validateVerifyIssuedSANs(config certificateCreateModel) diag.Diagnostics {
+	var diags diag.Diagnostics
+	if config.VerifyIssuedSANs.IsNull() || config.VerifyIssuedSANs.IsUnknown() {
+		return diags
+	}
+	if mode := config.VerifyIssuedSANs.ValueString(); !containsString(verifyIssuedSANsModes, mode) {
+		diags.AddAttributeError(
+			path.Root("verify_issued_sans"),
+			"Invalid Verify Issued SANs",
+			fmt.Sprintf("verify_issued_sans %q is not one of: %s.", mode, strings.Join(verifyIssuedSANsModes, ", ")),
+		)
+	}
+	return diags
+}
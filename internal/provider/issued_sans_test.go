@@ -0,0 +1,97 @@
+package provider
+
+import (
+	"context"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-framework/diag"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+)
+
+func TestValidateVerifyIssuedSANs(t *testing.T) {
+	for name, tc := range map[string]struct {
+		value types.String
+		want  bool
+	}{
+		"unset":   {value: types.StringNull()},
+		"unknown": {value: types.StringUnknown()},
+		"error":   {value: types.StringValue("error")},
+		"warn":    {value: types.StringValue("warn")},
+		"invalid": {value: types.StringValue("fail"), want: true},
+	} {
+		diags := validateVerifyIssuedSANs(certificateCreateModel{VerifyIssuedSANs: tc.value})
+		if diags.HasError() != tc.want {
+			t.Errorf("%s: validateVerifyIssuedSANs() = %v, want error %t", name, diags, tc.want)
+		}
+	}
+}
+
+func TestSANSetCanonicalizes(t *testing.T) {
+	requested, issued := sanSet{}, sanSet{}
+	requested.add("dns", "App.Example.com")
+	requested.add("ip", "2001:db8:0:0::1")
+	requested.add("email", "JDoe@example.com")
+	issued.add("dns", "app.example.com")
+	issued.add("ip", "2001:db8::1")
+	issued.add("email", "jdoe@example.com")
+	if missing := requested.missingFrom(issued); len(missing) > 0 {
+		t.Errorf("missingFrom() = %v, want none", missing)
+	}
+}
+
+func TestCheckIssuedSANs(t *testing.T) {
+	srv := newAccCertsrv(t)
+	t.Setenv("ADCS_PASSWORD", "secret")
+	ctx := context.Background()
+	data, err := debugEnrollConfigure(ctx, "test", map[string]interface{}{
+		"host":     srv.host(),
+		"username": "svc-terraform",
+		"use_ntlm": true,
+	}, io.Discard)
+	if err != nil {
+		t.Fatal(err)
+	}
+	r := &certificateResource{client: data.client, provider: data}
+
+	// debug-enroll requests carry no subject alternative names, the mock CA takes the DNS names
+	// of the san request attribute and ignores its IP addresses.
+	csr, err := debugEnrollRequest(ctx, "", "app.example.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+	issue := func(mode string, dnsNames []string, ipAddresses []string) diag.Diagnostics {
+		t.Helper()
+		plan := &certificateCreateModel{
+			CSR:              types.StringValue(csr),
+			Template:         types.StringValue("WebServer"),
+			VerifyIssuedSANs: types.StringValue(mode),
+		}
+		plan.DNSNames, _ = types.ListValueFrom(ctx, types.StringType, dnsNames)
+		plan.IPAddresses, _ = types.ListValueFrom(ctx, types.StringType, ipAddresses)
+		attr, diags := plan.submittedAttributes(ctx)
+		if diags.HasError() {
+			t.Fatalf("unexpected diagnostics: %v", diags)
+		}
+		certificates := r.requestCertificate(withRetryPolicy(ctx, noRetry), plan, csr, attr, &diags)
+		if diags.HasError() {
+			t.Fatalf("unexpected diagnostics: %v", diags)
+		}
+		return plan.checkIssuedSANs(ctx, certificates)
+	}
+
+	if diags := issue(verifyIssuedSANsError, []string{"App.Example.com"}, nil); len(diags) > 0 {
+		t.Errorf("checkIssuedSANs() = %v, want no diagnostics for matching names", diags)
+	}
+
+	diags := issue(verifyIssuedSANsError, []string{"app.example.com"}, []string{"10.0.0.9"})
+	if !diags.HasError() || !strings.Contains(diags.Errors()[0].Detail(), "missing ip:10.0.0.9") {
+		t.Errorf("checkIssuedSANs() = %v, want the dropped IP address reported", diags)
+	}
+
+	diags = issue(verifyIssuedSANsWarn, []string{"app.example.com"}, []string{"10.0.0.9"})
+	if diags.HasError() || diags.WarningsCount() != 1 {
+		t.Errorf("checkIssuedSANs() = %v, want a warning", diags)
+	}
+}
@@ -0,0 +1,228 @@
+package provider
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jcmturner/gokrb5/v8/crypto"
+	"github.com/jcmturner/gokrb5/v8/iana/errorcode"
+	"github.com/jcmturner/gokrb5/v8/iana/etypeID"
+	"github.com/jcmturner/gokrb5/v8/iana/keyusage"
+	"github.com/jcmturner/gokrb5/v8/iana/msgtype"
+	"github.com/jcmturner/gokrb5/v8/iana/patype"
+	"github.com/jcmturner/gokrb5/v8/keytab"
+	"github.com/jcmturner/gokrb5/v8/messages"
+	"github.com/jcmturner/gokrb5/v8/types"
+)
+
+const (
+	// accRealm is the Kerberos realm of the acceptance KDC.
+	accRealm = "ACC.TEST"
+	// accKerberosUser and accKerberosPassword are the only account the acceptance KDC knows.
+	accKerberosUser     = "svc-terraform"
+	accKerberosPassword = "secret"
+	// accKerberosEType is the one encryption type the acceptance KDC issues tickets with.
+	accKerberosEType = etypeID.AES256_CTS_HMAC_SHA1_96
+)
+
+// accKDC is a KDC stand-in for acceptance tests. It answers AS and TGS exchanges over TCP for
+// accKerberosUser, without pre-authentication, and issues service tickets for every SPN with keys
+// from the keytab the acceptance certsrv verifies them with. Clients configured with another
+// password fail to decrypt the AS reply, like they would against a domain controller.
+type accKDC struct {
+	listener net.Listener
+	// krbtgt holds the key of the ticket granting service, services the keys of the SPNs.
+	krbtgt   *keytab.Keytab
+	services *keytab.Keytab
+}
+
+// newAccKDC starts a KDC that issues tickets for the SPNs of the given host names.
+func newAccKDC(t *testing.T, hostNames ...string) *accKDC {
+	t.Helper()
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("could not start the KDC: %v", err)
+	}
+	kdc := &accKDC{listener: listener, krbtgt: keytab.New(), services: keytab.New()}
+	now := time.Now()
+	if err := kdc.krbtgt.AddEntry("krbtgt/"+accRealm, accRealm, "krbtgt-secret", now, 1, accKerberosEType); err != nil {
+		t.Fatalf("could not create the krbtgt key: %v", err)
+	}
+	for _, name := range hostNames {
+		if err := kdc.services.AddEntry("HTTP/"+name, accRealm, "service-secret", now, 1, accKerberosEType); err != nil {
+			t.Fatalf("could not create the key of HTTP/%s: %v", name, err)
+		}
+	}
+	t.Cleanup(func() { listener.Close() })
+	go kdc.serve()
+	return kdc
+}
+
+// krb5conf is the Kerberos configuration that reaches the KDC. UDP is disabled, the KDC only
+// listens on TCP.
+func (k *accKDC) krb5conf() string {
+	return fmt.Sprintf(`[libdefaults]
+  default_realm = %[1]s
+  udp_preference_limit = 1
+  dns_lookup_kdc = false
+  default_tkt_enctypes = aes256-cts-hmac-sha1-96
+  default_tgs_enctypes = aes256-cts-hmac-sha1-96
+  permitted_enctypes = aes256-cts-hmac-sha1-96
+
+[realms]
+  %[1]s = {
+    kdc = %[2]s
+  }
+`, accRealm, k.listener.Addr().String())
+}
+
+func (k *accKDC) serve() {
+	for {
+		conn, err := k.listener.Accept()
+		if err != nil {
+			return
+		}
+		go k.handle(conn)
+	}
+}
+
+// handle answers the one message of a KDC exchange over TCP, which frames messages with their
+// length.
+func (k *accKDC) handle(conn net.Conn) {
+	defer conn.Close()
+	var length uint32
+	if err := binary.Read(conn, binary.BigEndian, &length); err != nil {
+		return
+	}
+	request := make([]byte, length)
+	if _, err := io.ReadFull(conn, request); err != nil {
+		return
+	}
+
+	reply, err := k.reply(request)
+	if err != nil {
+		krbErr := messages.NewKRBError(types.PrincipalName{}, accRealm, errorcode.KRB_ERR_GENERIC, err.Error())
+		var known messages.KRBError
+		if errors.As(err, &known) {
+			krbErr = known
+		}
+		if reply, err = krbErr.Marshal(); err != nil {
+			return
+		}
+	}
+	binary.Write(conn, binary.BigEndian, uint32(len(reply)))
+	conn.Write(reply)
+}
+
+// reply answers an AS or a TGS request.
+func (k *accKDC) reply(request []byte) ([]byte, error) {
+	var asReq messages.ASReq
+	if err := asReq.Unmarshal(request); err == nil {
+		return k.asReply(asReq)
+	}
+	var tgsReq messages.TGSReq
+	if err := tgsReq.Unmarshal(request); err == nil {
+		return k.tgsReply(tgsReq)
+	}
+	return nil, errors.New("the KDC only answers AS and TGS requests")
+}
+
+// asReply issues a ticket granting ticket encrypted with the key of accKerberosPassword.
+func (k *accKDC) asReply(req messages.ASReq) ([]byte, error) {
+	cname := req.ReqBody.CName
+	if cname.PrincipalNameString() != accKerberosUser {
+		return nil, messages.NewKRBError(req.ReqBody.SName, accRealm, errorcode.KDC_ERR_C_PRINCIPAL_UNKNOWN, "unknown client "+cname.PrincipalNameString())
+	}
+	userKey, _, err := crypto.GetKeyFromPassword(accKerberosPassword, cname, accRealm, accKerberosEType, nil)
+	if err != nil {
+		return nil, err
+	}
+	ticket, encPart, err := k.issue(cname, req.ReqBody.SName, req.ReqBody.Nonce, k.krbtgt)
+	if err != nil {
+		return nil, err
+	}
+	encrypted, err := crypto.GetEncryptedData(encPart, userKey, keyusage.AS_REP_ENCPART, 1)
+	if err != nil {
+		return nil, err
+	}
+	rep := messages.ASRep{KDCRepFields: messages.KDCRepFields{
+		PVNO: 5, MsgType: msgtype.KRB_AS_REP, CRealm: accRealm, CName: cname, Ticket: ticket, EncPart: encrypted,
+	}}
+	return rep.Marshal()
+}
+
+// tgsReply issues a service ticket for the SPN of the request, encrypted with the session key of
+// the ticket granting ticket the request carries.
+func (k *accKDC) tgsReply(req messages.TGSReq) ([]byte, error) {
+	var apReq messages.APReq
+	for _, pa := range req.PAData {
+		if pa.PADataType == patype.PA_TGS_REQ {
+			if err := apReq.Unmarshal(pa.PADataValue); err != nil {
+				return nil, err
+			}
+		}
+	}
+	if err := apReq.Ticket.DecryptEncPart(k.krbtgt, nil); err != nil {
+		return nil, err
+	}
+	tgt := apReq.Ticket.DecryptedEncPart
+
+	spn := req.ReqBody.SName
+	if _, _, err := k.services.GetEncryptionKey(spn, accRealm, 1, accKerberosEType); err != nil {
+		return nil, messages.NewKRBError(spn, accRealm, errorcode.KDC_ERR_S_PRINCIPAL_UNKNOWN, "unknown service "+spn.PrincipalNameString())
+	}
+	ticket, encPart, err := k.issue(tgt.CName, spn, req.ReqBody.Nonce, k.services)
+	if err != nil {
+		return nil, err
+	}
+	encrypted, err := crypto.GetEncryptedData(encPart, tgt.Key, keyusage.TGS_REP_ENCPART_SESSION_KEY, 0)
+	if err != nil {
+		return nil, err
+	}
+	rep := messages.TGSRep{KDCRepFields: messages.KDCRepFields{
+		PVNO: 5, MsgType: msgtype.KRB_TGS_REP, CRealm: accRealm, CName: tgt.CName, Ticket: ticket, EncPart: encrypted,
+	}}
+	return rep.Marshal()
+}
+
+// issue creates a ticket for sname with a key from the keytab, and the encoded part of the reply
+// telling the client its session key.
+func (k *accKDC) issue(cname, sname types.PrincipalName, nonce int, kt *keytab.Keytab) (messages.Ticket, []byte, error) {
+	now := time.Now().UTC().Truncate(time.Second)
+	end, renewTill := now.Add(10*time.Hour), now.Add(24*time.Hour)
+	flags := types.NewKrbFlags()
+	ticket, sessionKey, err := messages.NewTicket(cname, accRealm, sname, accRealm, flags, kt, accKerberosEType, 1, now, now, end, renewTill)
+	if err != nil {
+		return ticket, nil, err
+	}
+	part := messages.EncKDCRepPart{
+		Key:       sessionKey,
+		LastReqs:  []messages.LastReq{{LRValue: now}},
+		Nonce:     nonce,
+		Flags:     flags,
+		AuthTime:  now,
+		StartTime: now,
+		EndTime:   end,
+		RenewTill: renewTill,
+		SRealm:    accRealm,
+		SName:     sname,
+	}
+	b, err := part.Marshal()
+	return ticket, b, err
+}
+
+// accServiceHost is the host name in the SPN a Kerberos client requests for host, its canonical
+// name like the SPNEGO client looks it up.
+func accServiceHost(host string) string {
+	name := strings.TrimSuffix(host, ".")
+	if canonical, err := net.LookupCNAME(name); err == nil && canonical != "" {
+		name = strings.ToLower(strings.TrimSuffix(canonical, "."))
+	}
+	return name
+}
@@ -0,0 +1,136 @@
+package provider
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"unicode/utf16"
+	"unicode/utf8"
+
+	"github.com/jcmturner/gokrb5/v8/config"
+)
+
+// realmFromHost guesses the Kerberos realm from the domain of the ADCS host, which is the realm
+// in single domain forests: ca.company.local belongs to COMPANY.LOCAL.
+func realmFromHost(host string) string {
+	host = strings.Split(host, ":")[0]
+	_, domain, ok := strings.Cut(host, ".")
+	if !ok {
+		return ""
+	}
+	return strings.ToUpper(domain)
+}
+
+// kerberosConfig builds a krb5.conf that pins the KDCs of the realm, so Kerberos works without DNS
+// SRV discovery and without a hand written configuration.
+func kerberosConfig(realm string, kdcs []string) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "[libdefaults]\n  default_realm = %s\n  dns_lookup_kdc = false\n  dns_lookup_realm = false\n\n", realm)
+	fmt.Fprintf(&b, "[realms]\n  %s = {\n", realm)
+	for _, kdc := range kdcs {
+		fmt.Fprintf(&b, "    kdc = %s\n", kdc)
+	}
+	b.WriteString("  }\n\n")
+	domain := strings.ToLower(realm)
+	fmt.Fprintf(&b, "[domain_realm]\n  .%s = %s\n  %s = %s\n", domain, realm, domain, realm)
+	return b.String()
+}
+
+// loadKerberosConfig returns the Kerberos configuration given through krb5conf, which holds either
+// the configuration itself or the path of a krb5.conf or krb5.ini file. The configuration is
+// normalized and checked for a default realm, the client needs one to log in.
+func loadKerberosConfig(value string) (string, error) {
+	content := []byte(value)
+	if path := strings.TrimSpace(value); isKerberosConfigPath(path) {
+		var err error
+		if content, err = readKerberosConfigFile(path); err != nil {
+			return "", err
+		}
+	}
+
+	krb5conf, err := normalizeKerberosConfig(content)
+	if err != nil {
+		return "", err
+	}
+	conf, err := config.NewFromString(krb5conf)
+	if err != nil {
+		return "", fmt.Errorf("could not parse the Kerberos configuration: %v", err)
+	}
+	if conf.LibDefaults.DefaultRealm == "" {
+		return "", fmt.Errorf("the Kerberos configuration sets no default_realm in its [libdefaults] section")
+	}
+	return krb5conf, nil
+}
+
+// isKerberosConfigPath reports whether krb5conf holds a path rather than a configuration, which
+// always spans several lines and has at least one [section].
+func isKerberosConfigPath(value string) bool {
+	return value != "" && !strings.ContainsAny(value, "\r\n[=")
+}
+
+// isWindowsPath reports whether the path is a drive or UNC path such as C:\krb5.ini.
+func isWindowsPath(path string) bool {
+	if strings.HasPrefix(path, `\\`) {
+		return true
+	}
+	return len(path) >= 3 && path[1] == ':' && (path[2] == '\\' || path[2] == '/') &&
+		(path[0] >= 'A' && path[0] <= 'Z' || path[0] >= 'a' && path[0] <= 'z')
+}
+
+// readKerberosConfigFile reads the krb5conf file, explaining the failures runners of another
+// platform than the configuration was written for run into.
+func readKerberosConfigFile(path string) ([]byte, error) {
+	if runtime.GOOS != "windows" && isWindowsPath(path) {
+		return nil, fmt.Errorf("krb5conf %q is a Windows path but the provider runs on %s, use a path on this runner or "+
+			"pass the configuration itself, for example with file()", path, runtime.GOOS)
+	}
+	if path == "~" || strings.HasPrefix(path, "~/") || strings.HasPrefix(path, `~\`) {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return nil, fmt.Errorf("could not expand ~ in krb5conf %q: %v", path, err)
+		}
+		path = home + path[1:]
+	}
+
+	content, err := os.ReadFile(filepath.Clean(path))
+	switch {
+	case os.IsNotExist(err):
+		return nil, fmt.Errorf("krb5conf %q is neither a Kerberos configuration nor an existing file", path)
+	case err != nil:
+		return nil, fmt.Errorf("could not read krb5conf file %q: %v", path, err)
+	}
+	return content, nil
+}
+
+// normalizeKerberosConfig decodes the configuration to UTF-8 with LF line endings. Editors on
+// Windows save krb5.ini with a byte order mark, as UTF-16 or with CRLF line endings; gokrb5 then
+// silently skips the first section, or every line after a lone CR.
+func normalizeKerberosConfig(content []byte) (string, error) {
+	switch {
+	case bytes.HasPrefix(content, []byte{0xff, 0xfe}), bytes.HasPrefix(content, []byte{0xfe, 0xff}):
+		if len(content)%2 != 0 {
+			return "", fmt.Errorf("the Kerberos configuration starts with a UTF-16 byte order mark but has an odd number of bytes")
+		}
+		littleEndian := content[0] == 0xff
+		units := make([]uint16, 0, len(content)/2-1)
+		for i := 2; i < len(content); i += 2 {
+			if littleEndian {
+				units = append(units, uint16(content[i])|uint16(content[i+1])<<8)
+			} else {
+				units = append(units, uint16(content[i])<<8|uint16(content[i+1]))
+			}
+		}
+		content = []byte(string(utf16.Decode(units)))
+	case bytes.HasPrefix(content, []byte{0xef, 0xbb, 0xbf}):
+		content = content[3:]
+	}
+	if !utf8.Valid(content) {
+		return "", fmt.Errorf("the Kerberos configuration is neither UTF-8 nor UTF-16 encoded")
+	}
+
+	krb5conf := strings.ReplaceAll(string(content), "\r\n", "\n")
+	return strings.ReplaceAll(krb5conf, "\r", "\n"), nil
+}
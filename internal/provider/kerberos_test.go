@@ -0,0 +1,138 @@
+package provider
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+	"unicode/utf16"
+
+	"github.com/jcmturner/gokrb5/v8/config"
+)
+
+func TestRealmFromHost(t *testing.T) {
+	tests := map[string]string{
+		"ca.company.local":      "COMPANY.LOCAL",
+		"ca.eu.company.local:8": "EU.COMPANY.LOCAL",
+		"ca":                    "",
+	}
+	for host, want := range tests {
+		if got := realmFromHost(host); got != want {
+			t.Errorf("realmFromHost(%q) = %q, want %q", host, got, want)
+		}
+	}
+}
+
+func TestKerberosConfig(t *testing.T) {
+	conf, err := config.NewFromString(kerberosConfig("COMPANY.LOCAL", []string{"dc01.company.local", "10.1.2.3:88"}))
+	if err != nil {
+		t.Fatalf("generated configuration does not parse: %v", err)
+	}
+	if conf.LibDefaults.DefaultRealm != "COMPANY.LOCAL" {
+		t.Errorf("default_realm = %q, want COMPANY.LOCAL", conf.LibDefaults.DefaultRealm)
+	}
+
+	count, kdcs, err := conf.GetKDCs("COMPANY.LOCAL", true)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	found := map[string]bool{}
+	for _, kdc := range kdcs {
+		found[kdc] = true
+	}
+	if count != 2 || !found["dc01.company.local:88"] || !found["10.1.2.3:88"] {
+		t.Errorf("got KDCs %v, want dc01.company.local:88 and 10.1.2.3:88", kdcs)
+	}
+	if conf.ResolveRealm("ca.company.local") != "COMPANY.LOCAL" {
+		t.Errorf("ca.company.local does not map to COMPANY.LOCAL")
+	}
+}
+
+const testKerberosConfig = "[libdefaults]\n  default_realm = COMPANY.LOCAL\n\n[realms]\n  COMPANY.LOCAL = {\n    kdc = dc01.company.local\n  }\n"
+
+// utf16LE encodes s the way Windows PowerShell 5 writes files by default.
+func utf16LE(s string) []byte {
+	b := []byte{0xff, 0xfe}
+	for _, unit := range utf16.Encode([]rune(s)) {
+		b = append(b, byte(unit), byte(unit>>8))
+	}
+	return b
+}
+
+func TestNormalizeKerberosConfig(t *testing.T) {
+	crlf := strings.ReplaceAll(testKerberosConfig, "\n", "\r\n")
+	tests := map[string][]byte{
+		"lf":        []byte(testKerberosConfig),
+		"crlf":      []byte(crlf),
+		"cr":        []byte(strings.ReplaceAll(testKerberosConfig, "\n", "\r")),
+		"utf-8 bom": append([]byte{0xef, 0xbb, 0xbf}, crlf...),
+		"utf-16le":  utf16LE(crlf),
+	}
+	for name, content := range tests {
+		t.Run(name, func(t *testing.T) {
+			got, err := normalizeKerberosConfig(content)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got != testKerberosConfig {
+				t.Errorf("normalizeKerberosConfig() = %q, want %q", got, testKerberosConfig)
+			}
+		})
+	}
+
+	if _, err := normalizeKerberosConfig([]byte{0xff, 0xfe, 0x5b}); err == nil {
+		t.Error("expected an error for truncated UTF-16")
+	}
+	if _, err := normalizeKerberosConfig([]byte{0x5b, 0xff, 0x5d}); err == nil {
+		t.Error("expected an error for invalid UTF-8")
+	}
+}
+
+func TestLoadKerberosConfig(t *testing.T) {
+	dir := t.TempDir()
+	file := filepath.Join(dir, "krb5.ini")
+	content := append([]byte{0xef, 0xbb, 0xbf}, strings.ReplaceAll(testKerberosConfig, "\n", "\r\n")...)
+	if err := os.WriteFile(file, content, 0o600); err != nil {
+		t.Fatalf("could not write configuration: %v", err)
+	}
+
+	for name, value := range map[string]string{
+		"contents": string(content),
+		"path":     file,
+		"padded":   "  " + file + "\n",
+	} {
+		t.Run(name, func(t *testing.T) {
+			got, err := loadKerberosConfig(value)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			conf, err := config.NewFromString(got)
+			if err != nil || conf.LibDefaults.DefaultRealm != "COMPANY.LOCAL" {
+				t.Errorf("loaded configuration does not parse to COMPANY.LOCAL: %v", err)
+			}
+		})
+	}
+
+	failures := map[string]struct {
+		value string
+		want  string
+	}{
+		"missing file":     {value: filepath.Join(dir, "missing.conf"), want: "neither a Kerberos configuration nor an existing file"},
+		"no default realm": {value: "[realms]\n  COMPANY.LOCAL = {\n    kdc = dc01\n  }\n", want: "no default_realm"},
+	}
+	if runtime.GOOS != "windows" {
+		failures["windows path"] = struct {
+			value string
+			want  string
+		}{value: `C:\ProgramData\MIT\Kerberos5\krb5.ini`, want: "is a Windows path but the provider runs on " + runtime.GOOS}
+	}
+	for name, tc := range failures {
+		t.Run(name, func(t *testing.T) {
+			_, err := loadKerberosConfig(tc.value)
+			if err == nil || !strings.Contains(err.Error(), tc.want) {
+				t.Errorf("loadKerberosConfig() error = %v, want it to contain %q", err, tc.want)
+			}
+		})
+	}
+}
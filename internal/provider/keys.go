@@ -0,0 +1,134 @@
+package provider
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"fmt"
+
+	"github.com/hashicorp/terraform-plugin-framework/diag"
+	"github.com/hashicorp/terraform-plugin-framework/path"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+)
+
+// parsePrivateKey decodes a PEM encoded PKCS#1, PKCS#8 or SEC 1 EC private key.
+func parsePrivateKey(keyPEM string) (crypto.Signer, error) {
+	block, _ := pem.Decode([]byte(keyPEM))
+	if block == nil {
+		return nil, fmt.Errorf("no PEM block found")
+	}
+
+	var key interface{}
+	var err error
+	switch block.Type {
+	case "RSA PRIVATE KEY":
+		key, err = x509.ParsePKCS1PrivateKey(block.Bytes)
+	case "EC PRIVATE KEY":
+		key, err = x509.ParseECPrivateKey(block.Bytes)
+	case "PRIVATE KEY":
+		key, err = x509.ParsePKCS8PrivateKey(block.Bytes)
+	default:
+		return nil, fmt.Errorf("unsupported PEM block type %q", block.Type)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	signer, ok := key.(crypto.Signer)
+	if !ok {
+		return nil, fmt.Errorf("unsupported private key type %T", key)
+	}
+	return signer, nil
+}
+
+// keyMatches reports whether the private key belongs to the given public key.
+func keyMatches(key crypto.Signer, pub crypto.PublicKey) bool {
+	return publicKeysEqual(key.Public(), pub)
+}
+
+// publicKeysEqual reports whether both public keys are the same key.
+func publicKeysEqual(a, b crypto.PublicKey) bool {
+	comparable, ok := a.(interface{ Equal(crypto.PublicKey) bool })
+	return ok && comparable.Equal(b)
+}
+
+// checkKeyAlgorithm returns an error for public keys ADCS does not issue certificates for. ADCS
+// supports RSA and ECDSA keys only, an Ed25519 request is rejected by the CA with a generic error.
+func checkKeyAlgorithm(pub crypto.PublicKey) error {
+	switch pub.(type) {
+	case *rsa.PublicKey, *ecdsa.PublicKey:
+		return nil
+	case ed25519.PublicKey:
+		return fmt.Errorf("ADCS does not issue certificates for Ed25519 keys, use an RSA or ECDSA key")
+	}
+	return fmt.Errorf("unsupported public key type %T, ADCS issues certificates for RSA and ECDSA keys", pub)
+}
+
+// validateRequestKey checks at plan time that the certificate signing request is for a key ADCS
+// can issue a certificate for.
+func validateRequestKey(csr types.String) diag.Diagnostics {
+	var diags diag.Diagnostics
+
+	if csr.IsNull() || csr.IsUnknown() {
+		return diags
+	}
+	request, err := parseCertificateRequest(csr.ValueString())
+	if err != nil {
+		// The request itself is validated by the CA on submission.
+		return diags
+	}
+	if err := checkKeyAlgorithm(request.PublicKey); err != nil {
+		diags.AddAttributeError(
+			path.Root("certificate_signing_request"),
+			"Unsupported Key Algorithm",
+			err.Error(),
+		)
+	}
+	return diags
+}
+
+// validatePrivateKey checks at plan time that private_key_pem belongs to the certificate signing
+// request, a mismatch would otherwise only surface after the certificate has been issued.
+func validatePrivateKey(config certificateCreateModel) diag.Diagnostics {
+	var diags diag.Diagnostics
+
+	if config.PrivateKeyPEM.IsNull() || config.PrivateKeyPEM.IsUnknown() || config.CSR.IsUnknown() {
+		return diags
+	}
+
+	key, err := parsePrivateKey(config.PrivateKeyPEM.ValueString())
+	if err != nil {
+		diags.AddAttributeError(
+			path.Root("private_key_pem"),
+			"Invalid Private Key",
+			"Could not parse private_key_pem: "+err.Error(),
+		)
+		return diags
+	}
+	if err := checkKeyAlgorithm(key.Public()); err != nil {
+		diags.AddAttributeError(
+			path.Root("private_key_pem"),
+			"Unsupported Key Algorithm",
+			err.Error(),
+		)
+		return diags
+	}
+
+	request, err := parseCertificateRequest(config.CSR.ValueString())
+	if err != nil {
+		// The request itself is validated by the CA on submission.
+		return diags
+	}
+	if !keyMatches(key, request.PublicKey) {
+		diags.AddAttributeError(
+			path.Root("private_key_pem"),
+			"Private Key Does Not Match Certificate Signing Request",
+			"private_key_pem is not the key the certificate signing request was created with.",
+		)
+	}
+
+	return diags
+}
@@ -0,0 +1,226 @@
+package provider
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha1"
+	"crypto/x509"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+
+	"github.com/flipyap/microsoft-adcs-client/client"
+	"github.com/hashicorp/terraform-plugin-framework/datasource"
+	"github.com/hashicorp/terraform-plugin-framework/datasource/schema"
+	"github.com/hashicorp/terraform-plugin-framework/diag"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+)
+
+// defaultNDESPath is where the NDES role service installs the SCEP endpoint.
+const defaultNDESPath = "/certsrv/mscep/mscep.dll"
+
+// Ensure the implementation satisfies the expected interfaces.
+var (
+	_ datasource.DataSource              = &ndesDataSource{}
+	_ datasource.DataSourceWithConfigure = &ndesDataSource{}
+)
+
+// NewNDESDataSource is a helper function to simplify the provider implementation.
+func NewNDESDataSource() datasource.DataSource {
+	return &ndesDataSource{}
+}
+
+// ndesDataSource is the data source implementation.
+type ndesDataSource struct {
+	client   *client.ADCSClient
+	provider *providerData
+}
+
+// ndesModel maps the NDES service configuration.
+type ndesModel struct {
+	ID                   types.String `tfsdk:"id"`
+	Path                 types.String `tfsdk:"path"`
+	Capabilities         types.List   `tfsdk:"capabilities"`
+	CACertificatePEM     types.String `tfsdk:"ca_certificate_pem"`
+	CAThumbprint         types.String `tfsdk:"ca_thumbprint"`
+	RACertificatesPEM    types.List   `tfsdk:"ra_certificates_pem"`
+	RACertificatesThumbs types.List   `tfsdk:"ra_thumbprints"`
+}
+
+// Configure adds the provider configured client to the data source.
+func (d *ndesDataSource) Configure(_ context.Context, req datasource.ConfigureRequest, resp *datasource.ConfigureResponse) {
+	if req.ProviderData == nil {
+		return
+	}
+
+	data, ok := req.ProviderData.(*providerData)
+	if !ok {
+		resp.Diagnostics.AddError(
+			"Unexpected Data Source Configure Type",
+			fmt.Sprintf("Expected *providerData, got: %T. Please report this issue to the provider developers.", req.ProviderData),
+		)
+
+		return
+	}
+
+	d.client = data.client
+	d.provider = data
+}
+
+// Metadata returns the data source type name.
+func (d *ndesDataSource) Metadata(_ context.Context, req datasource.MetadataRequest, resp *datasource.MetadataResponse) {
+	resp.TypeName = req.ProviderTypeName + "_ndes"
+}
+
+// Schema defines the schema for the data source.
+func (d *ndesDataSource) Schema(_ context.Context, _ datasource.SchemaRequest, resp *datasource.SchemaResponse) {
+	resp.Schema = schema.Schema{
+		Description: `Reads the SCEP configuration published by the Network Device Enrollment Service (NDES) on the ADCS host.
+The templates NDES issues from only live in the registry of the NDES server and are not exposed over SCEP.`,
+		Attributes: map[string]schema.Attribute{
+			"id": schema.StringAttribute{
+				Computed:    true,
+				Description: "URL of the SCEP endpoint that was queried.",
+			},
+			"path": schema.StringAttribute{
+				Optional:    true,
+				Description: "Path of the SCEP endpoint on the ADCS host. Defaults to " + defaultNDESPath + ".",
+			},
+			"capabilities": schema.ListAttribute{
+				ElementType: types.StringType,
+				Computed:    true,
+				Description: "Capabilities advertised by GetCACaps, such as POSTPKIOperation or SHA-256.",
+			},
+			"ca_certificate_pem": schema.StringAttribute{
+				Computed:    true,
+				Description: "PEM encoded CA certificate returned by GetCACert.",
+			},
+			"ca_thumbprint": schema.StringAttribute{
+				Computed:    true,
+				Description: "Uppercase hex SHA-1 thumbprint of the CA certificate, as SCEP profiles expect it.",
+			},
+			"ra_certificates_pem": schema.ListAttribute{
+				ElementType: types.StringType,
+				Computed:    true,
+				Description: "PEM encoded registration authority certificates NDES returned alongside the CA certificate.",
+			},
+			"ra_thumbprints": schema.ListAttribute{
+				ElementType: types.StringType,
+				Computed:    true,
+				Description: "Uppercase hex SHA-1 thumbprints of the registration authority certificates.",
+			},
+		},
+	}
+}
+
+// Read refreshes the Terraform state with the latest data.
+func (d *ndesDataSource) Read(ctx context.Context, req datasource.ReadRequest, resp *datasource.ReadResponse) {
+	if d.provider.caOffline(ctx) {
+		resp.Diagnostics.Append(applyOnlyError("read the NDES service"))
+		return
+	}
+	var data ndesModel
+	resp.Diagnostics.Append(req.Config.Get(ctx, &data)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	endpoint := "http://" + d.client.HostURL + defaultNDESPath
+	if !data.Path.IsNull() {
+		endpoint = "http://" + d.client.HostURL + "/" + strings.TrimPrefix(data.Path.ValueString(), "/")
+	}
+
+	caps, _, err := d.scepOperation(ctx, endpoint, "GetCACaps")
+	if err != nil {
+		resp.Diagnostics.AddError(classifiedSummary("Unable to Read NDES Capabilities", err), err.Error())
+		return
+	}
+
+	body, contentType, err := d.scepOperation(ctx, endpoint, "GetCACert")
+	if err != nil {
+		resp.Diagnostics.AddError(classifiedSummary("Unable to Read NDES CA Certificate", err), err.Error())
+		return
+	}
+
+	// A single CA certificate comes back as DER, together with RA certificates it is PKCS#7.
+	var certs []*x509.Certificate
+	if strings.HasPrefix(contentType, "application/x-x509-ca-ra-cert") {
+		certs, err = parsePKCS7Certificates(body)
+	} else {
+		var cert *x509.Certificate
+		cert, err = x509.ParseCertificate(body)
+		certs = []*x509.Certificate{cert}
+	}
+	if err != nil {
+		resp.Diagnostics.AddError(classifiedSummary("Unable to Parse NDES CA Certificate", &ParseError{Err: err}), err.Error())
+		return
+	}
+
+	var capabilities []string
+	for _, line := range strings.Split(string(caps), "\n") {
+		if capability := strings.TrimSpace(line); capability != "" {
+			capabilities = append(capabilities, capability)
+		}
+	}
+
+	// The RA certificates are ordered by thumbprint, NDES does not keep them in a fixed order.
+	var ra []*x509.Certificate
+	for _, cert := range certs {
+		if cert.IsCA {
+			data.CACertificatePEM = types.StringValue(encodePEM(cert))
+			data.CAThumbprint = types.StringValue(fmt.Sprintf("%X", sha1.Sum(cert.Raw)))
+			continue
+		}
+		ra = append(ra, cert)
+	}
+	sort.Slice(ra, func(i, j int) bool {
+		a, b := sha1.Sum(ra[i].Raw), sha1.Sum(ra[j].Raw)
+		return bytes.Compare(a[:], b[:]) < 0
+	})
+	var raPEMs, raThumbprints []string
+	for _, cert := range ra {
+		raPEMs = append(raPEMs, encodePEM(cert))
+		raThumbprints = append(raThumbprints, fmt.Sprintf("%X", sha1.Sum(cert.Raw)))
+	}
+
+	data.ID = types.StringValue(endpoint)
+	var diags diag.Diagnostics
+	data.Capabilities, diags = types.ListValueFrom(ctx, types.StringType, capabilities)
+	resp.Diagnostics.Append(diags...)
+	data.RACertificatesPEM, diags = types.ListValueFrom(ctx, types.StringType, raPEMs)
+	resp.Diagnostics.Append(diags...)
+	data.RACertificatesThumbs, diags = types.ListValueFrom(ctx, types.StringType, raThumbprints)
+	resp.Diagnostics.Append(diags...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	resp.Diagnostics.Append(resp.State.Set(ctx, &data)...)
+}
+
+// scepOperation performs a SCEP GET operation and returns the body with its content type.
+func (d *ndesDataSource) scepOperation(ctx context.Context, endpoint string, operation string) ([]byte, string, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint, nil)
+	if err != nil {
+		return nil, "", fmt.Errorf("could not create %s request: %v", operation, err)
+	}
+	query := url.Values{}
+	query.Add("operation", operation)
+	query.Add("message", "CA")
+	req.URL.RawQuery = query.Encode()
+
+	resp, err := d.client.DoRequest(req)
+	if err != nil {
+		return nil, "", fmt.Errorf("%s request failed: %v", operation, err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, "", fmt.Errorf("error reading %s response body: %v", operation, err)
+	}
+	return body, resp.Header.Get("Content-Type"), nil
+}
@@ -0,0 +1,25 @@
+package provider
+
+import (
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-testing/helper/resource"
+)
+
+func TestAccNDESDataSource(t *testing.T) {
+	resource.Test(t, resource.TestCase{
+		PreCheck:                 func() { testAccPreCheck(t) },
+		ProtoV6ProviderFactories: testAccProtoV6ProviderFactories,
+		Steps: []resource.TestStep{
+			// Read testing
+			{
+				Config: providerConfig + `data "microsoftadcs_ndes" "test" {}`,
+				Check: resource.ComposeAggregateTestCheckFunc(
+					resource.TestCheckResourceAttrSet("data.microsoftadcs_ndes.test", "ca_certificate_pem"),
+					resource.TestCheckResourceAttrSet("data.microsoftadcs_ndes.test", "ca_thumbprint"),
+					resource.TestCheckResourceAttrSet("data.microsoftadcs_ndes.test", "capabilities.#"),
+				),
+			},
+		},
+	})
+}
@@ -0,0 +1,330 @@
+package provider
+
+import (
+	"bytes"
+	"context"
+	"crypto/x509"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/flipyap/microsoft-adcs-client/client"
+	"github.com/hashicorp/terraform-plugin-framework/datasource"
+	"github.com/hashicorp/terraform-plugin-framework/datasource/schema"
+	"github.com/hashicorp/terraform-plugin-framework/path"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+	"github.com/hashicorp/terraform-plugin-log/tflog"
+	"golang.org/x/crypto/ocsp"
+)
+
+// maxOCSPResponseSize limits how much of an OCSP response is read.
+const maxOCSPResponseSize = 1 << 20
+
+// ocspStatuses are the status values by OCSP certificate status.
+var ocspStatuses = map[int]string{
+	ocsp.Good:    "good",
+	ocsp.Revoked: "revoked",
+	ocsp.Unknown: "unknown",
+}
+
+// ocspRevocationReasons names the CRLReason codes OCSP responders return beyond those
+// revocation_reason accepts.
+var ocspRevocationReasons = map[int]string{
+	ocsp.RemoveFromCRL:      "remove_from_crl",
+	ocsp.PrivilegeWithdrawn: "privilege_withdrawn",
+	ocsp.AACompromise:       "aa_compromise",
+}
+
+// revocationReasonName returns the revocation_reason name of a CRLReason code.
+func revocationReasonName(code int) string {
+	for name, reason := range revocationReasons {
+		if reason == code {
+			return name
+		}
+	}
+	if name, ok := ocspRevocationReasons[code]; ok {
+		return name
+	}
+	return fmt.Sprintf("reason_%d", code)
+}
+
+// Ensure the implementation satisfies the expected interfaces.
+var (
+	_ datasource.DataSource                   = &ocspStatusDataSource{}
+	_ datasource.DataSourceWithConfigure      = &ocspStatusDataSource{}
+	_ datasource.DataSourceWithValidateConfig = &ocspStatusDataSource{}
+)
+
+// NewOCSPStatusDataSource is a helper function to simplify the provider implementation.
+func NewOCSPStatusDataSource() datasource.DataSource {
+	return &ocspStatusDataSource{}
+}
+
+// ocspStatusDataSource asks the OCSP responder of the CA whether a certificate is revoked.
+type ocspStatusDataSource struct {
+	client   *client.ADCSClient
+	parser   *certsrvParser
+	provider *providerData
+}
+
+// ocspStatusModel maps the certificate to check and the answer of the responder.
+type ocspStatusModel struct {
+	ID               types.String `tfsdk:"id"`
+	CertificatePEM   types.String `tfsdk:"certificate_pem"`
+	SerialNumber     types.String `tfsdk:"serial_number"`
+	IssuerPEM        types.String `tfsdk:"issuer_pem"`
+	OCSPURL          types.String `tfsdk:"ocsp_url"`
+	Status           types.String `tfsdk:"status"`
+	RevokedAt        types.String `tfsdk:"revoked_at"`
+	RevocationReason types.String `tfsdk:"revocation_reason"`
+	ThisUpdate       types.String `tfsdk:"this_update"`
+	NextUpdate       types.String `tfsdk:"next_update"`
+}
+
+// Configure adds the provider configured client to the data source.
+func (d *ocspStatusDataSource) Configure(_ context.Context, req datasource.ConfigureRequest, resp *datasource.ConfigureResponse) {
+	if req.ProviderData == nil {
+		return
+	}
+
+	data, ok := req.ProviderData.(*providerData)
+	if !ok {
+		resp.Diagnostics.AddError(
+			"Unexpected Data Source Configure Type",
+			fmt.Sprintf("Expected *providerData, got: %T. Please report this issue to the provider developers.", req.ProviderData),
+		)
+
+		return
+	}
+
+	d.client = data.client
+	d.provider = data
+	d.parser = data.parser
+}
+
+// Metadata returns the data source type name.
+func (d *ocspStatusDataSource) Metadata(_ context.Context, req datasource.MetadataRequest, resp *datasource.MetadataResponse) {
+	resp.TypeName = req.ProviderTypeName + "_ocsp_status"
+}
+
+// Schema defines the schema for the data source.
+func (d *ocspStatusDataSource) Schema(_ context.Context, _ datasource.SchemaRequest, resp *datasource.SchemaResponse) {
+	resp.Schema = schema.Schema{
+		Description: `Asks the OCSP responder of the CA whether a certificate is revoked, for preconditions such as only
+deploying a certificate that is currently valid. The certificate is given either as certificate_pem or by
+serial_number, and the response has to be signed for its issuer.`,
+		Attributes: map[string]schema.Attribute{
+			"id": schema.StringAttribute{
+				Computed:    true,
+				Description: "Hex encoded serial number of the certificate.",
+			},
+			"certificate_pem": schema.StringAttribute{
+				Optional:    true,
+				Description: "PEM encoded certificate to check. Conflicts with serial_number.",
+			},
+			"serial_number": schema.StringAttribute{
+				Optional: true,
+				Description: `Hex encoded serial number of the certificate to check, for certificates that are not at hand.
+Requires ocsp_url. Conflicts with certificate_pem.`,
+			},
+			"issuer_pem": schema.StringAttribute{
+				Optional: true,
+				Description: `PEM encoded certificate of the CA that issued the certificate. Defaults to the CA certificate
+that signed certificate_pem, or the current CA certificate with serial_number, from the certsrv web enrollment pages.`,
+			},
+			"ocsp_url": schema.StringAttribute{
+				Optional:    true,
+				Computed:    true,
+				Description: "OCSP responder to ask. Defaults to the first OCSP responder in the authority information access of certificate_pem.",
+			},
+			"status": schema.StringAttribute{
+				Computed:    true,
+				Description: `Status the responder gives the certificate: "good", "revoked" or "unknown".`,
+			},
+			"revoked_at": schema.StringAttribute{
+				Computed:    true,
+				Description: "When the certificate was revoked in RFC 3339 format, null unless it is revoked.",
+			},
+			"revocation_reason": schema.StringAttribute{
+				Computed: true,
+				Description: `Reason the certificate was revoked for, named like revocation_reason of microsoftadcs_certificate,
+for example key_compromise or certificate_hold. Null unless it is revoked.`,
+			},
+			"this_update": schema.StringAttribute{
+				Computed:    true,
+				Description: "When the responder last knew the status to be correct, in RFC 3339 format.",
+			},
+			"next_update": schema.StringAttribute{
+				Computed:    true,
+				Description: "When the responder publishes newer status, in RFC 3339 format. Null when it does not say.",
+			},
+		},
+	}
+}
+
+// ValidateConfig checks that the certificate is given exactly one way.
+func (d *ocspStatusDataSource) ValidateConfig(ctx context.Context, req datasource.ValidateConfigRequest, resp *datasource.ValidateConfigResponse) {
+	var config ocspStatusModel
+	resp.Diagnostics.Append(req.Config.Get(ctx, &config)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	switch {
+	case config.CertificatePEM.IsNull() && config.SerialNumber.IsNull():
+		resp.Diagnostics.AddError("Missing Certificate", "Set certificate_pem, or serial_number together with ocsp_url.")
+	case !config.CertificatePEM.IsNull() && !config.SerialNumber.IsNull():
+		resp.Diagnostics.AddAttributeError(path.Root("serial_number"), "Conflicting Certificate", "Set either certificate_pem or serial_number, not both.")
+	case !config.SerialNumber.IsNull() && config.OCSPURL.IsNull():
+		resp.Diagnostics.AddAttributeError(
+			path.Root("ocsp_url"),
+			"Missing OCSP Responder",
+			"Without the certificate the responder cannot be taken from its authority information access, set ocsp_url.",
+		)
+	}
+
+	if !config.SerialNumber.IsNull() && !config.SerialNumber.IsUnknown() {
+		if _, ok := parseSerialNumber(config.SerialNumber.ValueString()); !ok {
+			resp.Diagnostics.AddAttributeError(path.Root("serial_number"), "Invalid Serial Number", fmt.Sprintf("%q is not a hex encoded serial number.", config.SerialNumber.ValueString()))
+		}
+	}
+}
+
+// Read asks the responder for the status of the certificate.
+func (d *ocspStatusDataSource) Read(ctx context.Context, req datasource.ReadRequest, resp *datasource.ReadResponse) {
+	if d.provider.caOffline(ctx) {
+		resp.Diagnostics.Append(applyOnlyError("check the revocation status of a certificate"))
+		return
+	}
+	var data ocspStatusModel
+	resp.Diagnostics.Append(req.Config.Get(ctx, &data)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	// The request only carries the serial number, so a certificate with just that is enough.
+	cert := &x509.Certificate{}
+	if !data.CertificatePEM.IsNull() {
+		var err error
+		if cert, err = parseCertificate(data.CertificatePEM.ValueString()); err != nil {
+			resp.Diagnostics.AddAttributeError(path.Root("certificate_pem"), "Invalid Certificate", "Could not parse certificate_pem: "+err.Error()+".")
+			return
+		}
+	} else {
+		cert.SerialNumber, _ = parseSerialNumber(data.SerialNumber.ValueString())
+	}
+
+	responder := data.OCSPURL.ValueString()
+	if responder == "" {
+		if len(cert.OCSPServer) == 0 {
+			resp.Diagnostics.AddAttributeError(
+				path.Root("ocsp_url"),
+				"Missing OCSP Responder",
+				fmt.Sprintf("Certificate %x names no OCSP responder in its authority information access, set ocsp_url.", cert.SerialNumber),
+			)
+			return
+		}
+		responder = cert.OCSPServer[0]
+	}
+
+	issuer, err := d.issuer(ctx, data, cert)
+	if err != nil {
+		resp.Diagnostics.AddAttributeError(path.Root("issuer_pem"), "Unable to Find Issuer", err.Error())
+		return
+	}
+
+	status, err := queryOCSP(ctx, responder, cert, issuer)
+	if err != nil {
+		resp.Diagnostics.AddError(classifiedSummary("Unable to Query OCSP Responder", err), fmt.Sprintf("Could not get the status of certificate %x from %s: %s", cert.SerialNumber, responder, err.Error()))
+		return
+	}
+	tflog.Debug(ctx, "OCSP responder answered", map[string]interface{}{
+		"serial_number": fmt.Sprintf("%x", cert.SerialNumber),
+		"status":        ocspStatuses[status.Status],
+	})
+
+	data.ID = types.StringValue(fmt.Sprintf("%x", cert.SerialNumber))
+	data.OCSPURL = types.StringValue(responder)
+	data.Status = types.StringValue(ocspStatuses[status.Status])
+	data.RevokedAt = types.StringNull()
+	data.RevocationReason = types.StringNull()
+	if status.Status == ocsp.Revoked {
+		data.RevokedAt = types.StringValue(status.RevokedAt.UTC().Format(time.RFC3339))
+		data.RevocationReason = types.StringValue(revocationReasonName(status.RevocationReason))
+	}
+	data.ThisUpdate = types.StringValue(status.ThisUpdate.UTC().Format(time.RFC3339))
+	data.NextUpdate = types.StringNull()
+	if !status.NextUpdate.IsZero() {
+		data.NextUpdate = types.StringValue(status.NextUpdate.UTC().Format(time.RFC3339))
+	}
+
+	resp.Diagnostics.Append(resp.State.Set(ctx, &data)...)
+}
+
+// issuer returns issuer_pem, or the CA certificate that signed cert from the CA chain certsrv
+// publishes. Without the certificate, only its serial number, it is the current CA certificate.
+func (d *ocspStatusDataSource) issuer(ctx context.Context, data ocspStatusModel, cert *x509.Certificate) (*x509.Certificate, error) {
+	if !data.IssuerPEM.IsNull() {
+		issuer, err := parseCertificate(data.IssuerPEM.ValueString())
+		if err != nil {
+			return nil, fmt.Errorf("could not parse issuer_pem: %v", err)
+		}
+		return issuer, nil
+	}
+
+	chainB64, _, err := retrieveCAChain(ctx, d.client, d.parser, -1)
+	if err != nil {
+		return nil, fmt.Errorf("could not retrieve the CA certificate chain to find the issuer, set issuer_pem: %v", err)
+	}
+	certs, err := parseCertificateChain(chainB64)
+	if err != nil {
+		return nil, fmt.Errorf("could not parse the CA certificate chain: %v", err)
+	}
+	for _, ca := range certs {
+		if cert.Raw != nil && bytes.Equal(cert.RawIssuer, ca.RawSubject) && cert.CheckSignatureFrom(ca) == nil {
+			return ca, nil
+		}
+		if cert.Raw == nil && !isIssuerOfAny(ca, certs) {
+			return ca, nil
+		}
+	}
+	return nil, fmt.Errorf("certificate %x was not issued by the CA behind certsrv, set issuer_pem", cert.SerialNumber)
+}
+
+// queryOCSP posts an OCSP request for cert to the responder and returns the response, which has to
+// be signed by the issuer or a responder it delegated to.
+func queryOCSP(ctx context.Context, responder string, cert *x509.Certificate, issuer *x509.Certificate) (*ocsp.Response, error) {
+	request, err := ocsp.CreateRequest(cert, issuer, nil)
+	if err != nil {
+		return nil, fmt.Errorf("could not create the OCSP request: %v", err)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, responder, bytes.NewReader(request))
+	if err != nil {
+		return nil, fmt.Errorf("could not create the OCSP request: %v", err)
+	}
+	req.Header.Set("Content-Type", "application/ocsp-request")
+	req.Header.Set("Accept", "application/ocsp-response")
+
+	resp, err := webhookClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("OCSP request failed: %v", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("OCSP responder returned status %d", resp.StatusCode)
+	}
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxOCSPResponseSize))
+	if err != nil {
+		return nil, fmt.Errorf("could not read the OCSP response: %v", err)
+	}
+
+	status, err := ocsp.ParseResponseForCert(body, cert, issuer)
+	if err != nil {
+		return nil, fmt.Errorf("invalid OCSP response: %v", err)
+	}
+	if status.SerialNumber == nil || status.SerialNumber.Cmp(cert.SerialNumber) != 0 {
+		return nil, fmt.Errorf("the OCSP response is for serial number %x", status.SerialNumber)
+	}
+	return status, nil
+}
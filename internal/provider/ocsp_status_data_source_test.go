@@ -0,0 +1,166 @@
+package provider
+
+import (
+	"context"
+	"crypto"
+	"crypto/x509"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/terraform-plugin-framework/types"
+	"github.com/hashicorp/terraform-plugin-testing/helper/resource"
+	"golang.org/x/crypto/ocsp"
+)
+
+// newTestOCSPResponder answers OCSP requests for certificates of the issuing CA of h with status,
+// signed by the issuing CA itself.
+func newTestOCSPResponder(t *testing.T, h *testHierarchy, status ocsp.Response) *httptest.Server {
+	t.Helper()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		request, err := ocsp.ParseRequest(body)
+		if err != nil || r.Header.Get("Content-Type") != "application/ocsp-request" {
+			http.Error(w, "malformed request", http.StatusBadRequest)
+			return
+		}
+		template := status
+		template.SerialNumber = request.SerialNumber
+		response, err := ocsp.CreateResponse(h.issuing, h.issuing, template, crypto.Signer(h.issuingKey))
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "application/ocsp-response")
+		_, _ = w.Write(response)
+	}))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func TestQueryOCSP(t *testing.T) {
+	h := newTestHierarchy(t)
+	thisUpdate := time.Now().Add(-time.Hour).Truncate(time.Second)
+	revokedAt := thisUpdate.Add(-24 * time.Hour)
+	responder := newTestOCSPResponder(t, h, ocsp.Response{
+		Status:           ocsp.Revoked,
+		RevokedAt:        revokedAt,
+		RevocationReason: ocsp.KeyCompromise,
+		ThisUpdate:       thisUpdate,
+		NextUpdate:       thisUpdate.Add(24 * time.Hour),
+	})
+
+	status, err := queryOCSP(context.Background(), responder.URL, h.leaf, h.issuing)
+	if err != nil {
+		t.Fatalf("queryOCSP() = %v", err)
+	}
+	if status.Status != ocsp.Revoked || !status.RevokedAt.Equal(revokedAt) || status.RevocationReason != ocsp.KeyCompromise {
+		t.Errorf("queryOCSP() = %+v, want revoked for key compromise", status)
+	}
+
+	// Only the serial number is known.
+	if _, err := queryOCSP(context.Background(), responder.URL, &x509.Certificate{SerialNumber: h.leaf.SerialNumber}, h.issuing); err != nil {
+		t.Errorf("queryOCSP() by serial number = %v", err)
+	}
+
+	// A response signed by another CA is rejected.
+	if _, err := queryOCSP(context.Background(), responder.URL, h.issuing, h.root); err == nil || !strings.Contains(err.Error(), "invalid OCSP response") {
+		t.Errorf("queryOCSP() for the wrong issuer = %v, want an invalid response", err)
+	}
+}
+
+func TestRevocationReasonName(t *testing.T) {
+	for code, want := range map[int]string{
+		ocsp.Unspecified:        "unspecified",
+		ocsp.KeyCompromise:      "key_compromise",
+		ocsp.CertificateHold:    "certificate_hold",
+		ocsp.RemoveFromCRL:      "remove_from_crl",
+		ocsp.PrivilegeWithdrawn: "privilege_withdrawn",
+		7:                       "reason_7",
+	} {
+		if got := revocationReasonName(code); got != want {
+			t.Errorf("revocationReasonName(%d) = %q, want %q", code, got, want)
+		}
+	}
+}
+
+func TestOCSPStatusIssuer(t *testing.T) {
+	srv := newAccCertsrv(t)
+	t.Setenv("ADCS_PASSWORD", "secret")
+	data, err := debugEnrollConfigure(context.Background(), "test", map[string]interface{}{
+		"host":     srv.host(),
+		"username": "svc-terraform",
+		"use_ntlm": true,
+	}, io.Discard)
+	if err != nil {
+		t.Fatal(err)
+	}
+	d := &ocspStatusDataSource{client: data.client, parser: data.parser}
+	h := srv.hierarchy
+
+	config := ocspStatusModel{IssuerPEM: types.StringNull()}
+	for name, tc := range map[string]struct {
+		cert *x509.Certificate
+		want *x509.Certificate
+	}{
+		"certificate":   {cert: h.leaf, want: h.issuing},
+		"ca":            {cert: h.issuing, want: h.root},
+		"serial number": {cert: &x509.Certificate{SerialNumber: h.leaf.SerialNumber}, want: h.issuing},
+	} {
+		issuer, err := d.issuer(context.Background(), config, tc.cert)
+		if err != nil || !issuer.Equal(tc.want) {
+			t.Errorf("%s: issuer() = %v, want %s", name, err, tc.want.Subject)
+		}
+	}
+
+	other := newTestHierarchy(t)
+	if _, err := d.issuer(context.Background(), config, other.leaf); err == nil || !strings.Contains(err.Error(), "set issuer_pem") {
+		t.Errorf("issuer() of a foreign certificate = %v, want an error", err)
+	}
+	config.IssuerPEM = types.StringValue(encodePEM(other.issuing))
+	if issuer, err := d.issuer(context.Background(), config, other.leaf); err != nil || !issuer.Equal(other.issuing) {
+		t.Errorf("issuer() with issuer_pem = %v", err)
+	}
+}
+
+func TestAccOCSPStatusDataSource(t *testing.T) {
+	srv := newAccCertsrv(t)
+	thisUpdate := time.Now().Add(-time.Hour).Truncate(time.Second)
+	responder := newTestOCSPResponder(t, srv.hierarchy, ocsp.Response{
+		Status:           ocsp.Revoked,
+		RevokedAt:        thisUpdate,
+		RevocationReason: ocsp.Superseded,
+		ThisUpdate:       thisUpdate,
+	})
+
+	resource.Test(t, resource.TestCase{
+		ProtoV6ProviderFactories: testAccProtoV6ProviderFactories,
+		Steps: []resource.TestStep{
+			{
+				Config: srv.providerConfig() + fmt.Sprintf(`
+data "microsoftadcs_ocsp_status" "pem" {
+  certificate_pem = %q
+  ocsp_url        = %q
+}
+
+data "microsoftadcs_ocsp_status" "serial" {
+  serial_number = %q
+  ocsp_url      = %q
+}
+`, encodePEM(srv.hierarchy.leaf), responder.URL, fmt.Sprintf("%X", srv.hierarchy.leaf.SerialNumber), responder.URL),
+				Check: resource.ComposeAggregateTestCheckFunc(
+					resource.TestCheckResourceAttr("data.microsoftadcs_ocsp_status.pem", "id", fmt.Sprintf("%x", srv.hierarchy.leaf.SerialNumber)),
+					resource.TestCheckResourceAttr("data.microsoftadcs_ocsp_status.pem", "status", "revoked"),
+					resource.TestCheckResourceAttr("data.microsoftadcs_ocsp_status.pem", "revocation_reason", "superseded"),
+					resource.TestCheckResourceAttr("data.microsoftadcs_ocsp_status.pem", "revoked_at", thisUpdate.UTC().Format(time.RFC3339)),
+					resource.TestCheckNoResourceAttr("data.microsoftadcs_ocsp_status.pem", "next_update"),
+					resource.TestCheckResourceAttr("data.microsoftadcs_ocsp_status.serial", "status", "revoked"),
+				),
+			},
+		},
+	})
+}
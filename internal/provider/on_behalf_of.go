@@ -0,0 +1,163 @@
+package provider
+
+import (
+	"crypto"
+	"crypto/x509"
+	"encoding/asn1"
+	"encoding/binary"
+	"encoding/pem"
+	"fmt"
+	"time"
+	"unicode/utf16"
+
+	"github.com/hashicorp/terraform-plugin-framework/diag"
+	"github.com/hashicorp/terraform-plugin-framework/path"
+)
+
+var (
+	// oidEnrollmentNameValuePair is szOID_ENROLLMENT_NAME_VALUE_PAIR, the authenticated attribute
+	// an enrollment agent names the requester with.
+	oidEnrollmentNameValuePair = asn1.ObjectIdentifier{1, 3, 6, 1, 4, 1, 311, 13, 2, 1}
+	// oidCertificateRequestAgent is the Certificate Request Agent extended key usage the CA
+	// requires of the certificate signing an enroll on behalf of request.
+	oidCertificateRequestAgent = asn1.ObjectIdentifier{1, 3, 6, 1, 4, 1, 311, 20, 2, 1}
+)
+
+// enrollmentNameValuePair is the EnrollmentNameValuePair structure of MS-WCCE 2.2.2.7.10, both
+// strings BMPString encoded.
+type enrollmentNameValuePair struct {
+	Name  asn1.RawValue
+	Value asn1.RawValue
+}
+
+// asn1BMPString encodes s as an ASN.1 BMPString, which encoding/asn1 does not marshal.
+func asn1BMPString(s string) asn1.RawValue {
+	units := utf16.Encode([]rune(s))
+	b := make([]byte, 2*len(units))
+	for i, unit := range units {
+		binary.BigEndian.PutUint16(b[2*i:], unit)
+	}
+	return asn1.RawValue{Class: asn1.ClassUniversal, Tag: asn1.TagBMPString, Bytes: b}
+}
+
+// decodeBMPString decodes an ASN.1 BMPString, false when value is not one.
+func decodeBMPString(value asn1.RawValue) (string, bool) {
+	if value.Class != asn1.ClassUniversal || value.Tag != asn1.TagBMPString || len(value.Bytes)%2 != 0 {
+		return "", false
+	}
+	units := make([]uint16, len(value.Bytes)/2)
+	for i := range units {
+		units[i] = binary.BigEndian.Uint16(value.Bytes[2*i:])
+	}
+	return string(utf16.Decode(units)), true
+}
+
+// parseEnrollmentAgent parses the enrollment agent certificate and its key, and checks that the CA
+// would accept a request signed with them.
+func parseEnrollmentAgent(certPEM, keyPEM string, now time.Time) (*x509.Certificate, crypto.Signer, error) {
+	cert, err := parseCertificate(certPEM)
+	if err != nil {
+		return nil, nil, fmt.Errorf("could not parse the enrollment agent certificate: %v", err)
+	}
+	agent := false
+	for _, eku := range cert.UnknownExtKeyUsage {
+		agent = agent || eku.Equal(oidCertificateRequestAgent)
+	}
+	if !agent {
+		return nil, nil, fmt.Errorf("the enrollment agent certificate %q lacks the Certificate Request Agent extended key usage (%s)", cert.Subject.CommonName, oidCertificateRequestAgent)
+	}
+	if !now.Before(cert.NotAfter) {
+		return nil, nil, fmt.Errorf("the enrollment agent certificate %q expired on %s", cert.Subject.CommonName, cert.NotAfter.UTC().Format(time.RFC3339))
+	}
+	key, err := parsePrivateKey(keyPEM)
+	if err != nil {
+		return nil, nil, fmt.Errorf("could not parse the enrollment agent private key: %v", err)
+	}
+	if !keyMatches(key, cert.PublicKey) {
+		return nil, nil, fmt.Errorf("the enrollment agent private key is not the key of its certificate")
+	}
+	return cert, key, nil
+}
+
+// newOnBehalfOfRequest wraps the certificate signing request in a PKCS#7 signed by an enrollment
+// agent, naming the account the certificate is for in the RequesterName pair, the way
+// certreq -policy with -cert <agent thumbprint> does. The CA issues the certificate for that
+// account when the agent may enroll on its behalf.
+func newOnBehalfOfRequest(csr, requester, agentCertPEM, agentKeyPEM string) (string, error) {
+	request, err := parseCertificateRequest(csr)
+	if err != nil {
+		return "", fmt.Errorf("could not parse the certificate signing request: %v", err)
+	}
+	cert, key, err := parseEnrollmentAgent(agentCertPEM, agentKeyPEM, time.Now())
+	if err != nil {
+		return "", err
+	}
+	pair, err := asn1.Marshal(enrollmentNameValuePair{Name: asn1BMPString(requesterNameAttribute), Value: asn1BMPString(requester)})
+	if err != nil {
+		return "", err
+	}
+
+	der, err := signPKCS7(request.Raw, cert, key, pkcs7Attribute{Type: oidEnrollmentNameValuePair, Value: pair})
+	if err != nil {
+		return "", err
+	}
+	return string(pem.EncodeToMemory(&pem.Block{Type: "PKCS7", Bytes: der})), nil
+}
+
+// onBehalfOfRequester returns the account an enroll on behalf of request names, false for other
+// signed requests.
+func onBehalfOfRequester(signed *signedRequest) (string, bool) {
+	value, ok := signed.attribute(oidEnrollmentNameValuePair)
+	if !ok {
+		return "", false
+	}
+	var pair enrollmentNameValuePair
+	if _, err := asn1.Unmarshal(value, &pair); err != nil {
+		return "", false
+	}
+	return decodeBMPString(pair.Value)
+}
+
+// validateOnBehalfOf checks on_behalf_of and the enrollment agent at plan time.
+func validateOnBehalfOf(config certificateCreateModel) diag.Diagnostics {
+	var diags diag.Diagnostics
+
+	agentSet := !config.EnrollmentAgentCertificatePEM.IsNull() || !config.EnrollmentAgentPrivateKeyPEM.IsNull()
+	if config.OnBehalfOf.IsNull() {
+		if agentSet {
+			diags.AddAttributeError(path.Root("on_behalf_of"), "Missing On Behalf Of",
+				"The enrollment agent only signs requests on behalf of another account, set on_behalf_of to that account.")
+		}
+		return diags
+	}
+
+	if name := config.OnBehalfOf.ValueString(); !config.OnBehalfOf.IsUnknown() && !isAccountName(name) {
+		diags.AddAttributeError(path.Root("on_behalf_of"), "Invalid On Behalf Of",
+			fmt.Sprintf("on_behalf_of %q is not an account name in the DOMAIN\\user form, such as \"EXAMPLE\\\\jdoe\".", name))
+	}
+	if config.EnrollmentAgentCertificatePEM.IsNull() || config.EnrollmentAgentPrivateKeyPEM.IsNull() {
+		diags.AddAttributeError(path.Root("enrollment_agent_certificate_pem"), "Missing Enrollment Agent",
+			"on_behalf_of requests are signed by an enrollment agent, set enrollment_agent_certificate_pem and enrollment_agent_private_key_pem.")
+	}
+	if !config.RequesterName.IsNull() {
+		diags.AddAttributeError(path.Root("requester_name"), "Conflicting Requester Name",
+			"on_behalf_of names the requester in the signed request, remove requester_name.")
+	}
+	if config.RenewExisting.ValueBool() {
+		diags.AddAttributeError(path.Root("on_behalf_of"), "Conflicting On Behalf Of",
+			"renew_existing signs the request with the key of the current certificate, remove on_behalf_of or renew_existing.")
+	}
+	if config.RequestFormat.ValueString() == requestFormatPKCS7 {
+		diags.AddAttributeError(path.Root("on_behalf_of"), "Conflicting On Behalf Of",
+			`request_format = "pkcs7" submits a request signed elsewhere, remove on_behalf_of or request_format.`)
+	}
+
+	cert, key := config.EnrollmentAgentCertificatePEM, config.EnrollmentAgentPrivateKeyPEM
+	if cert.IsNull() || cert.IsUnknown() || key.IsNull() || key.IsUnknown() {
+		return diags
+	}
+	if _, _, err := parseEnrollmentAgent(cert.ValueString(), key.ValueString(), time.Now()); err != nil {
+		diags.AddAttributeError(path.Root("enrollment_agent_certificate_pem"), "Invalid Enrollment Agent", "The CA would reject the request: "+err.Error()+".")
+	}
+	return diags
+}
@@ -0,0 +1,195 @@
+package provider
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"encoding/pem"
+	"fmt"
+	"io"
+	"math/big"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/terraform-plugin-framework/diag"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+	"github.com/hashicorp/terraform-plugin-testing/helper/resource"
+	"github.com/hashicorp/terraform-plugin-testing/terraform"
+)
+
+// testEnrollmentAgent issues an enrollment agent certificate from the issuing CA of h and returns
+// it and its key as PEM.
+func testEnrollmentAgent(t *testing.T, h *testHierarchy) (string, string) {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.Certificate{
+		SerialNumber:       big.NewInt(7),
+		Subject:            pkix.Name{CommonName: "svc-enrollment-agent"},
+		NotBefore:          time.Now().Add(-time.Hour),
+		NotAfter:           time.Now().Add(24 * time.Hour),
+		UnknownExtKeyUsage: []asn1.ObjectIdentifier{oidCertificateRequestAgent},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, h.issuing, &key.PublicKey, h.issuingKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyDER, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})),
+		string(pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: keyDER}))
+}
+
+func TestParseEnrollmentAgent(t *testing.T) {
+	h := newTestHierarchy(t)
+	agentPEM, agentKeyPEM := testEnrollmentAgent(t, h)
+	leafKeyDER, err := x509.MarshalPKCS8PrivateKey(h.leafKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	leafKeyPEM := string(pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: leafKeyDER}))
+
+	for name, tc := range map[string]struct {
+		cert, key string
+		now       time.Time
+		want      string
+	}{
+		"agent":        {cert: agentPEM, key: agentKeyPEM, now: time.Now()},
+		"not an agent": {cert: encodePEM(h.leaf), key: leafKeyPEM, now: time.Now(), want: "lacks the Certificate Request Agent extended key usage"},
+		"wrong key":    {cert: agentPEM, key: leafKeyPEM, now: time.Now(), want: "not the key of its certificate"},
+		"expired":      {cert: agentPEM, key: agentKeyPEM, now: time.Now().Add(48 * time.Hour), want: "expired on"},
+	} {
+		_, _, err := parseEnrollmentAgent(tc.cert, tc.key, tc.now)
+		switch {
+		case tc.want == "" && err != nil:
+			t.Errorf("%s: parseEnrollmentAgent() = %v", name, err)
+		case tc.want != "" && (err == nil || !strings.Contains(err.Error(), tc.want)):
+			t.Errorf("%s: parseEnrollmentAgent() = %v, want %q", name, err, tc.want)
+		}
+	}
+}
+
+func TestOnBehalfOfSubmission(t *testing.T) {
+	srv := newAccCertsrv(t)
+	t.Setenv("ADCS_PASSWORD", "secret")
+	ctx := context.Background()
+	data, err := debugEnrollConfigure(ctx, "test", map[string]interface{}{
+		"host":     srv.host(),
+		"username": "svc-terraform",
+		"use_ntlm": true,
+	}, io.Discard)
+	if err != nil {
+		t.Fatal(err)
+	}
+	r := &certificateResource{client: data.client, provider: data}
+
+	csr, err := debugEnrollRequest(ctx, "", "jdoe")
+	if err != nil {
+		t.Fatal(err)
+	}
+	agentPEM, agentKeyPEM := testEnrollmentAgent(t, srv.hierarchy)
+	request, err := newOnBehalfOfRequest(csr, `EXAMPLE\jdoe`, agentPEM, agentKeyPEM)
+	if err != nil {
+		t.Fatal(err)
+	}
+	plan := &certificateCreateModel{CSR: types.StringValue(csr), Template: types.StringValue("User")}
+	var diags diag.Diagnostics
+	certificates := r.requestCertificate(withRetryPolicy(ctx, noRetry), plan, request, "", &diags)
+	if diags.HasError() {
+		t.Fatalf("unexpected diagnostics: %v", diags)
+	}
+
+	req := srv.request(certificates.ID)
+	if req == nil || req.onBehalfOf != `EXAMPLE\jdoe` || req.request.Subject.CommonName != "jdoe" {
+		t.Fatalf("the CA did not receive a request on behalf of EXAMPLE\\jdoe: %+v", req)
+	}
+}
+
+func TestValidateOnBehalfOf(t *testing.T) {
+	agentPEM, agentKeyPEM := testEnrollmentAgent(t, newTestHierarchy(t))
+	agent := func(m certificateCreateModel) certificateCreateModel {
+		m.EnrollmentAgentCertificatePEM = types.StringValue(agentPEM)
+		m.EnrollmentAgentPrivateKeyPEM = types.StringValue(agentKeyPEM)
+		return m
+	}
+	for name, tc := range map[string]struct {
+		config certificateCreateModel
+		want   string
+	}{
+		"unset":            {},
+		"on behalf of":     {config: agent(certificateCreateModel{OnBehalfOf: types.StringValue(`EXAMPLE\jdoe`)})},
+		"not an account":   {config: agent(certificateCreateModel{OnBehalfOf: types.StringValue("jdoe")}), want: "Invalid On Behalf Of"},
+		"without agent":    {config: certificateCreateModel{OnBehalfOf: types.StringValue(`EXAMPLE\jdoe`)}, want: "Missing Enrollment Agent"},
+		"agent only":       {config: agent(certificateCreateModel{}), want: "Missing On Behalf Of"},
+		"requester name":   {config: agent(certificateCreateModel{OnBehalfOf: types.StringValue(`EXAMPLE\jdoe`), RequesterName: types.StringValue(`EXAMPLE\jdoe`)}), want: "Conflicting Requester Name"},
+		"renew existing":   {config: agent(certificateCreateModel{OnBehalfOf: types.StringValue(`EXAMPLE\jdoe`), RenewExisting: types.BoolValue(true)}), want: "Conflicting On Behalf Of"},
+		"pkcs7 request":    {config: agent(certificateCreateModel{OnBehalfOf: types.StringValue(`EXAMPLE\jdoe`), RequestFormat: types.StringValue(requestFormatPKCS7)}), want: "Conflicting On Behalf Of"},
+		"agent is invalid": {config: certificateCreateModel{OnBehalfOf: types.StringValue(`EXAMPLE\jdoe`), EnrollmentAgentCertificatePEM: types.StringValue(agentPEM), EnrollmentAgentPrivateKeyPEM: types.StringValue("key")}, want: "Invalid Enrollment Agent"},
+	} {
+		diags := validateOnBehalfOf(tc.config)
+		switch {
+		case tc.want == "" && diags.HasError():
+			t.Errorf("%s: validateOnBehalfOf() = %v", name, diags)
+		case tc.want != "" && (!diags.HasError() || !strings.Contains(diags.Errors()[0].Summary(), tc.want)):
+			t.Errorf("%s: validateOnBehalfOf() = %v, want %q", name, diags, tc.want)
+		}
+	}
+}
+
+func TestAccCertificateResourceOnBehalfOf(t *testing.T) {
+	srv := newAccCertsrv(t)
+	agentPEM, agentKeyPEM := testEnrollmentAgent(t, srv.hierarchy)
+
+	resource.Test(t, resource.TestCase{
+		ProtoV6ProviderFactories: testAccProtoV6ProviderFactories,
+		Steps: []resource.TestStep{
+			{
+				Config: srv.providerConfig() + fmt.Sprintf(`
+resource "microsoftadcs_certificate" "test" {
+  template     = "User"
+  on_behalf_of = "EXAMPLE\\jdoe"
+
+  enrollment_agent_certificate_pem = <<EOT
+%sEOT
+  enrollment_agent_private_key_pem = <<EOT
+%sEOT
+
+  generate_csr {
+    common_name = "jdoe"
+  }
+}
+`, agentPEM, agentKeyPEM),
+				Check: func(s *terraform.State) error {
+					reqID := s.RootModule().Resources["microsoftadcs_certificate.test"].Primary.ID
+					if req := srv.request(reqID); req == nil || req.onBehalfOf != `EXAMPLE\jdoe` {
+						return fmt.Errorf("request ID %s was not submitted on behalf of EXAMPLE\\jdoe", reqID)
+					}
+					return nil
+				},
+			},
+			{
+				Config: srv.providerConfig() + `
+resource "microsoftadcs_certificate" "test" {
+  template     = "User"
+  on_behalf_of = "jdoe"
+
+  generate_csr {
+    common_name = "jdoe"
+  }
+}
+`,
+				ExpectError: regexp.MustCompile(`Invalid On Behalf Of`),
+			},
+		},
+	})
+}
@@ -0,0 +1,84 @@
+package provider
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/hashicorp/terraform-plugin-framework/diag"
+	"github.com/hashicorp/terraform-plugin-framework/path"
+	"github.com/hashicorp/terraform-plugin-framework/resource"
+)
+
+// Values of on_missing, what a refresh does when the CA database no longer holds the request of the
+// certificate in state.
+const (
+	onMissingIgnore   = "ignore"
+	onMissingError    = "error"
+	onMissingRecreate = "recreate"
+)
+
+// onMissingBehaviors lists the values of on_missing.
+var onMissingBehaviors = []string{onMissingIgnore, onMissingError, onMissingRecreate}
+
+// missingRequestRegex matches the disposition messages of requests that are not in the CA database,
+// such as rows removed with certutil -deleterow. 0x80092004 is CRYPT_E_NOT_FOUND.
+var missingRequestRegex = regexp.MustCompile(`(?i)\bnot found\b|0x80092004`)
+
+// onMissing returns on_missing, ignore by default.
+func (m *certificateCreateModel) onMissing() string {
+	if m.OnMissing.IsNull() || m.OnMissing.IsUnknown() {
+		return onMissingIgnore
+	}
+	return m.OnMissing.ValueString()
+}
+
+// isRequestMissing reports whether a download failed because the CA database does not hold the
+// request anymore.
+func isRequestMissing(err error) bool {
+	disposition, ok := requestDisposition(err)
+	return ok && disposition == dispositionErrored && missingRequestRegex.MatchString(dispositionMessage(err))
+}
+
+// handleMissingRequest applies on_missing to a refresh that found the request gone from the CA
+// database. It returns false for ignore, which leaves the certificate in state and records the
+// answer of the CA in request_disposition like for any other request that is no longer issued.
+func handleMissingRequest(ctx context.Context, state certificateCreateModel, err error, resp *resource.ReadResponse) bool {
+	reqID := state.ID.ValueString()
+	switch state.onMissing() {
+	case onMissingError:
+		resp.Diagnostics.AddError(
+			"Certificate Request Not Found",
+			fmt.Sprintf("The CA database no longer holds request ID %s: %s\n\n"+
+				"CA database maintenance removes old requests. Set on_missing = \"ignore\" to keep the certificate in state, "+
+				"or \"recreate\" to request a new one.", reqID, dispositionMessage(err)),
+		)
+		return true
+	case onMissingRecreate:
+		resp.Diagnostics.AddWarning(
+			"Certificate Request Not Found",
+			fmt.Sprintf("The CA database no longer holds request ID %s, the certificate is removed from state and requested again on the next apply: %s",
+				reqID, dispositionMessage(err)),
+		)
+		resp.State.RemoveResource(ctx)
+		return true
+	}
+	return false
+}
+
+// validateOnMissing checks on_missing.
+func validateOnMissing(config certificateCreateModel) diag.Diagnostics {
+	var diags diag.Diagnostics
+	if config.OnMissing.IsNull() || config.OnMissing.IsUnknown() {
+		return diags
+	}
+	if behavior := config.OnMissing.ValueString(); !containsString(onMissingBehaviors, behavior) {
+		diags.AddAttributeError(
+			path.Root("on_missing"),
+			"Invalid On Missing",
+			fmt.Sprintf("on_missing %q is not one of: %s.", behavior, strings.Join(onMissingBehaviors, ", ")),
+		)
+	}
+	return diags
+}
@@ -0,0 +1,111 @@
+package provider
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-framework/types"
+	"github.com/hashicorp/terraform-plugin-testing/helper/resource"
+	"github.com/hashicorp/terraform-plugin-testing/terraform"
+)
+
+func TestIsRequestMissing(t *testing.T) {
+	for name, tc := range map[string]struct {
+		err  error
+		want bool
+	}{
+		"not found":   {err: fmt.Errorf("failed to download certificate: %w", &dispositionError{message: "Request 42 was not found"}), want: true},
+		"crypt error": {err: &dispositionError{message: "Cannot find object or property. 0x80092004 (-2146885628 CRYPT_E_NOT_FOUND)"}, want: true},
+		"denied":      {err: &dispositionError{message: "Denied by Policy Module  0x80094800"}},
+		"pending":     {err: errors.New("certificate pending for request id 42")},
+		"unreachable": {err: errors.New("dial tcp 10.0.0.2:80: connect: connection refused: not found")},
+	} {
+		if got := isRequestMissing(tc.err); got != tc.want {
+			t.Errorf("%s: isRequestMissing() = %t, want %t", name, got, tc.want)
+		}
+	}
+}
+
+func TestValidateOnMissing(t *testing.T) {
+	for _, behavior := range onMissingBehaviors {
+		if diags := validateOnMissing(certificateCreateModel{OnMissing: types.StringValue(behavior)}); diags.HasError() {
+			t.Errorf("validateOnMissing(%q) = %v", behavior, diags)
+		}
+	}
+	if diags := validateOnMissing(certificateCreateModel{OnMissing: types.StringValue("delete")}); !diags.HasError() {
+		t.Error("validateOnMissing(\"delete\") succeeded, want an error")
+	}
+}
+
+func TestAccCertificateResourceOnMissing(t *testing.T) {
+	for _, tc := range []struct {
+		onMissing string
+		// step runs after the request was purged from the CA database.
+		step resource.TestStep
+	}{
+		{
+			onMissing: onMissingError,
+			step:      resource.TestStep{RefreshState: true, ExpectError: regexp.MustCompile(`Certificate Request Not Found`)},
+		},
+		{
+			onMissing: onMissingIgnore,
+			step: resource.TestStep{RefreshState: true, Check: resource.ComposeAggregateTestCheckFunc(
+				resource.TestCheckResourceAttr("microsoftadcs_certificate.test", "request_disposition", dispositionErrored),
+				resource.TestCheckResourceAttrSet("microsoftadcs_certificate.test", "certificate_pem"),
+			)},
+		},
+	} {
+		t.Run(tc.onMissing, func(t *testing.T) {
+			srv := newAccCertsrv(t)
+			tc.step.PreConfig = srv.purge
+			resource.Test(t, resource.TestCase{
+				ProtoV6ProviderFactories: testAccProtoV6ProviderFactories,
+				Steps:                    []resource.TestStep{{Config: onMissingConfig(srv, tc.onMissing)}, tc.step},
+			})
+		})
+	}
+}
+
+func TestAccCertificateResourceOnMissingRecreate(t *testing.T) {
+	srv := newAccCertsrv(t)
+	var firstID string
+
+	resource.Test(t, resource.TestCase{
+		ProtoV6ProviderFactories: testAccProtoV6ProviderFactories,
+		Steps: []resource.TestStep{
+			{
+				Config: onMissingConfig(srv, onMissingRecreate),
+				Check: func(s *terraform.State) error {
+					firstID = s.RootModule().Resources["microsoftadcs_certificate.test"].Primary.ID
+					return nil
+				},
+			},
+			// The refresh drops the purged certificate and the apply requests a new one.
+			{
+				PreConfig: srv.purge,
+				Config:    onMissingConfig(srv, onMissingRecreate),
+				Check: func(s *terraform.State) error {
+					if id := s.RootModule().Resources["microsoftadcs_certificate.test"].Primary.ID; id == firstID {
+						return fmt.Errorf("request ID is still %s, want a new request", id)
+					}
+					return nil
+				},
+			},
+		},
+	})
+}
+
+func onMissingConfig(srv *accCertsrv, onMissing string) string {
+	return srv.providerConfig() + `
+resource "microsoftadcs_certificate" "test" {
+  template   = "WebServer"
+  on_missing = "` + onMissing + `"
+
+  generate_csr {
+    common_name = "web.example.com"
+  }
+}
+`
+}
@@ -0,0 +1,132 @@
+package provider
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strings"
+	"sync"
+
+	"github.com/hashicorp/terraform-plugin-framework/diag"
+)
+
+// operationTracePrefix starts the trailer line error diagnostics get, see annotate.
+const operationTracePrefix = "ADCS request:"
+
+// statusCodeRegex finds the HRESULT in a disposition message, which the CA console lists as the
+// request status code.
+var statusCodeRegex = regexp.MustCompile(`0x[0-9a-fA-F]{8}`)
+
+// pageRequestIDRegex finds the request ID certsrv mentions on the pages of requests it did not issue,
+// such as "Your Request Id is 42. The disposition message is ...".
+var pageRequestIDRegex = regexp.MustCompile(`(?i)Request\s+Id\s+is\s+(\d+)`)
+
+// operationTraceKey is the context key of the operationTrace of a resource operation.
+type operationTraceKey struct{}
+
+// operationTrace collects what a PKI admin needs to find the request of a failed operation in the
+// CA console: the request ID, the CA that holds it and its disposition. The certsrv requests made
+// with the context of the operation fill it in, the last request wins.
+type operationTrace struct {
+	mu          sync.Mutex
+	requestID   string
+	caName      string
+	disposition string
+}
+
+// withOperationTrace starts a trace for the operation run with ctx.
+func withOperationTrace(ctx context.Context) (context.Context, *operationTrace) {
+	trace := &operationTrace{}
+	return context.WithValue(ctx, operationTraceKey{}, trace), trace
+}
+
+// operationTraceFrom returns the trace of ctx, nil when the operation is not traced.
+func operationTraceFrom(ctx context.Context) *operationTrace {
+	trace, _ := ctx.Value(operationTraceKey{}).(*operationTrace)
+	return trace
+}
+
+// record notes a certsrv request for reqID, made along route, and how it ended. Values that are not
+// known, such as the request ID of a submission certsrv rejected, keep what was recorded before.
+func (t *operationTrace) record(route *caRoute, reqID string, err error) {
+	if t == nil {
+		return
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if err != nil {
+		if pending, ok := pendingRequestID(err); ok {
+			reqID = pending
+		}
+		t.disposition = dispositionMessage(err)
+	} else {
+		t.disposition = ""
+	}
+	if reqID != "" {
+		t.requestID = reqID
+	}
+	if route != nil && route.caName != "" {
+		t.caName = route.caName
+	}
+}
+
+// recordSubmission notes the answer certsrv gave to a submission, which names the request also when
+// the CA did not issue it.
+func (t *operationTrace) recordSubmission(route *caRoute, page string, reqID string, err error) {
+	if match := pageRequestIDRegex.FindStringSubmatch(page); reqID == "" && match != nil {
+		reqID = match[1]
+	}
+	t.record(route, reqID, err)
+}
+
+// trailer returns the machine-parsable line naming the request, empty when nothing is known.
+func (t *operationTrace) trailer() string {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	var fields []string
+	if t.requestID != "" {
+		fields = append(fields, "request_id="+t.requestID)
+	}
+	if t.caName != "" {
+		fields = append(fields, fmt.Sprintf("ca_name=%q", t.caName))
+	}
+	if t.disposition != "" {
+		fields = append(fields, fmt.Sprintf("disposition=%q", t.disposition))
+		if code := statusCodeRegex.FindString(t.disposition); code != "" {
+			fields = append(fields, "status_code="+strings.ToLower(code))
+		}
+	}
+	if len(fields) == 0 {
+		return ""
+	}
+	return operationTracePrefix + " " + strings.Join(fields, " ")
+}
+
+// annotate appends the trailer to every error diagnostic. It is deferred by the resource operations,
+// so it sees the diagnostics of the whole operation.
+func (t *operationTrace) annotate(diags *diag.Diagnostics) {
+	if t == nil || !diags.HasError() {
+		return
+	}
+	trailer := t.trailer()
+	if trailer == "" {
+		return
+	}
+
+	annotated := make(diag.Diagnostics, 0, len(*diags))
+	for _, d := range *diags {
+		if d.Severity() != diag.SeverityError || strings.Contains(d.Detail(), operationTracePrefix) {
+			annotated = append(annotated, d)
+			continue
+		}
+		detail := strings.TrimRight(d.Detail(), "\n") + "\n\n" + trailer
+		if withPath, ok := d.(diag.DiagnosticWithPath); ok {
+			annotated = append(annotated, diag.NewAttributeErrorDiagnostic(withPath.Path(), d.Summary(), detail))
+		} else {
+			annotated = append(annotated, diag.NewErrorDiagnostic(d.Summary(), detail))
+		}
+	}
+	*diags = annotated
+}
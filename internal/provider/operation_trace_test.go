@@ -0,0 +1,106 @@
+package provider
+
+import (
+	"context"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-framework/diag"
+	"github.com/hashicorp/terraform-plugin-framework/path"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+)
+
+func TestOperationTraceTrailer(t *testing.T) {
+	route := &caRoute{caName: accCAName}
+	for name, tc := range map[string]struct {
+		record func(trace *operationTrace)
+		want   string
+	}{
+		"nothing known": {record: func(*operationTrace) {}},
+		"denied": {
+			record: func(trace *operationTrace) {
+				trace.recordSubmission(route, `Your Request Id is 42. The disposition message is "Denied by Policy Module  0x80094800".`,
+					"", &dispositionError{message: "Denied by Policy Module  0x80094800"})
+			},
+			want: `ADCS request: request_id=42 ca_name="TEST-ISSUING-CA" disposition="Denied by Policy Module  0x80094800" status_code=0x80094800`,
+		},
+		"pending": {
+			record: func(trace *operationTrace) {
+				trace.record(route, "", errors.New("certificate pending for request id 7"))
+			},
+			want: `ADCS request: request_id=7 ca_name="TEST-ISSUING-CA" disposition="Taken Under Submission"`,
+		},
+		"unreachable after submission": {
+			record: func(trace *operationTrace) {
+				trace.record(nil, "42", nil)
+				trace.record(nil, "", errors.New("dial tcp 10.0.0.2:80: connect: connection refused"))
+			},
+			want: `ADCS request: request_id=42`,
+		},
+	} {
+		_, trace := withOperationTrace(context.Background())
+		tc.record(trace)
+		if got := trace.trailer(); got != tc.want {
+			t.Errorf("%s: trailer() = %q, want %q", name, got, tc.want)
+		}
+	}
+}
+
+func TestOperationTraceAnnotate(t *testing.T) {
+	_, trace := withOperationTrace(context.Background())
+	trace.record(nil, "42", nil)
+
+	var diags diag.Diagnostics
+	diags.AddWarning("Warning", "Left alone.")
+	diags.AddError("Error", "Failed.")
+	diags.AddAttributeError(path.Root("template"), "Attribute Error", "Failed.")
+	trace.annotate(&diags)
+	trace.annotate(&diags)
+
+	if diags[0].Detail() != "Left alone." {
+		t.Errorf("annotate() changed warning to %q", diags[0].Detail())
+	}
+	for _, d := range diags.Errors() {
+		if d.Detail() != "Failed.\n\nADCS request: request_id=42" {
+			t.Errorf("annotate() = %q, want the trailer appended once", d.Detail())
+		}
+	}
+	if withPath, ok := diags[2].(diag.DiagnosticWithPath); !ok || !withPath.Path().Equal(path.Root("template")) {
+		t.Errorf("annotate() lost the attribute path of %v", diags[2])
+	}
+
+	// Operations without a trace leave the diagnostics as they are.
+	operationTraceFrom(context.Background()).annotate(&diags)
+}
+
+func TestOperationTraceDeniedRequest(t *testing.T) {
+	srv := newAccCertsrv(t)
+	t.Setenv("ADCS_PASSWORD", "secret")
+	ctx := withRetryPolicy(context.Background(), noRetry)
+	data, err := debugEnrollConfigure(ctx, "test", map[string]interface{}{
+		"host":     srv.host(),
+		"username": "svc-terraform",
+		"use_ntlm": true,
+	}, io.Discard)
+	if err != nil {
+		t.Fatal(err)
+	}
+	r := &certificateResource{client: data.client, provider: data}
+	csr, err := debugEnrollRequest(ctx, "", "app.example.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, trace := withOperationTrace(ctx)
+	plan := &certificateCreateModel{CSR: types.StringValue(csr), Template: types.StringValue(accDeniedTemplate)}
+	var diags diag.Diagnostics
+	if certificates := r.requestCertificate(ctx, plan, csr, "", &diags); certificates != nil || !diags.HasError() {
+		t.Fatalf("requestCertificate() = %v, %v, want the denial", certificates, diags)
+	}
+	trace.annotate(&diags)
+	if detail := diags.Errors()[0].Detail(); !strings.Contains(detail, "\nADCS request: request_id=101 ") || !strings.Contains(detail, "status_code=0x80094800") {
+		t.Errorf("annotate() = %q, want the request ID and status code of the denial", detail)
+	}
+}
@@ -0,0 +1,168 @@
+package provider
+
+import (
+	"errors"
+	"fmt"
+	"mime"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+const (
+	parserProfileStrict  = "strict"
+	parserProfileLenient = "lenient"
+	parserProfileCustom  = "custom"
+)
+
+// certsrvParser reads request IDs and errors out of certsrv pages. Portals wrapping certsrv tend to
+// change the markup around the parts that matter, profiles other than strict make room for that.
+type certsrvParser struct {
+	profile string
+	// issued, pending and disposition each capture a single value: the request ID of an issued
+	// certificate, the request ID of a pending request and the disposition message of a failure.
+	issued      *regexp.Regexp
+	pending     *regexp.Regexp
+	disposition *regexp.Regexp
+	// lenientDownloads accepts downloads whose content type differs from the expected one as long as
+	// the body holds PEM.
+	lenientDownloads bool
+}
+
+// strictParser matches the pages of a stock certsrv installation, exactly like the ADCS client does.
+var strictParser = &certsrvParser{
+	profile:     parserProfileStrict,
+	issued:      regexp.MustCompile(`certnew.*\?ReqID=(\d+)&`),
+	pending:     regexp.MustCompile(`(?s)Certificate Pending.*Your Request Id is (\d+)\.`),
+	disposition: regexp.MustCompile(`The disposition message is "([^"]+)`),
+}
+
+// lenientParser ignores case, whitespace and HTML entity differences.
+var lenientParser = &certsrvParser{
+	profile:          parserProfileLenient,
+	issued:           regexp.MustCompile(`(?i)certnew\.(?:cer|p7b)\?(?:[^"'\s>]*?&(?:amp;)?)?ReqID=(\d+)`),
+	pending:          regexp.MustCompile(`(?is)pending.*Request\s+Id\s+is\s*:?\s*(\d+)`),
+	disposition:      regexp.MustCompile(`(?i)disposition\s+message\s+is\s*:?\s*(?:"|&quot;)([^"&]+)`),
+	lenientDownloads: true,
+}
+
+// parserOverrideKeys are the patterns that can be replaced with the custom profile.
+var parserOverrideKeys = []string{"disposition_message", "issued_request_id", "pending_request_id"}
+
+// newCertsrvParser returns the parser for a profile. Overrides are only allowed with the custom
+// profile, which starts from the lenient one, and every override needs exactly one capture group.
+func newCertsrvParser(profile string, overrides map[string]string) (*certsrvParser, error) {
+	switch profile {
+	case "", parserProfileStrict:
+		if len(overrides) > 0 {
+			return nil, fmt.Errorf("parser_overrides requires parser_profile %q", parserProfileCustom)
+		}
+		return strictParser, nil
+	case parserProfileLenient:
+		if len(overrides) > 0 {
+			return nil, fmt.Errorf("parser_overrides requires parser_profile %q", parserProfileCustom)
+		}
+		return lenientParser, nil
+	case parserProfileCustom:
+	default:
+		return nil, fmt.Errorf("unknown parser profile %q, expected one of %s, %s or %s", profile, parserProfileStrict, parserProfileLenient, parserProfileCustom)
+	}
+
+	p := *lenientParser
+	p.profile = parserProfileCustom
+
+	keys := make([]string, 0, len(overrides))
+	for key := range overrides {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		re, err := regexp.Compile(overrides[key])
+		if err != nil {
+			return nil, fmt.Errorf("%s: %v", key, err)
+		}
+		if re.NumSubexp() != 1 {
+			return nil, fmt.Errorf("%s: expected exactly one capture group, got %d", key, re.NumSubexp())
+		}
+		switch key {
+		case "issued_request_id":
+			p.issued = re
+		case "pending_request_id":
+			p.pending = re
+		case "disposition_message":
+			p.disposition = re
+		default:
+			return nil, fmt.Errorf("unknown override %q, expected one of %s", key, strings.Join(parserOverrideKeys, ", "))
+		}
+	}
+	return &p, nil
+}
+
+// Disposition messages of the CA database for requests certsrv does not answer with a message.
+const (
+	dispositionMessageIssued  = "Issued"
+	dispositionMessagePending = "Taken Under Submission"
+)
+
+// dispositionError is a failure certsrv answered with the disposition message of the request.
+type dispositionError struct {
+	// prefix keeps the wording of the client's errors in front of the message.
+	prefix  string
+	message string
+}
+
+func (e *dispositionError) Error() string {
+	return e.prefix + e.message
+}
+
+// dispositionMessage returns the disposition message of a request from the outcome of its
+// submission or download: "Issued", "Taken Under Submission" for requests pending approval or the
+// message certsrv answered with, such as "Denied by Policy Module  0x80094800". It is empty for
+// failures that never reached the CA.
+func dispositionMessage(err error) string {
+	var disposition *dispositionError
+	switch {
+	case err == nil:
+		return dispositionMessageIssued
+	case errors.As(err, &disposition):
+		return strings.TrimSpace(disposition.message)
+	case isStillPending(err):
+		return dispositionMessagePending
+	}
+	return ""
+}
+
+// requestID reads the request ID from the certfnsh.asp response page. Errors follow the client's
+// wording so pendingRequestID keeps working.
+func (p *certsrvParser) requestID(page string) (string, error) {
+	if match := p.issued.FindStringSubmatch(page); match != nil {
+		return match[1], nil
+	}
+	if match := p.pending.FindStringSubmatch(page); match != nil {
+		return "", fmt.Errorf("certificate pending for request id %s", match[1])
+	}
+	if match := p.disposition.FindStringSubmatch(page); match != nil {
+		return "", &dispositionError{prefix: "failed to get request ID: ", message: match[1]}
+	}
+	return "", &ParseError{Err: fmt.Errorf("failed to get request ID: an unknown error occurred")}
+}
+
+// checkDownload returns an error when a download is an error page rather than the expected content.
+func (p *certsrvParser) checkDownload(contentType string, wantContentType string, body string) error {
+	if contentType == wantContentType {
+		return nil
+	}
+	if p.lenientDownloads {
+		if mediaType, _, err := mime.ParseMediaType(contentType); err == nil && strings.EqualFold(mediaType, wantContentType) {
+			return nil
+		}
+		if strings.Contains(body, "-----BEGIN ") {
+			return nil
+		}
+	}
+	if match := p.disposition.FindStringSubmatch(body); match != nil {
+		return &dispositionError{message: match[1]}
+	}
+	return &ParseError{Err: fmt.Errorf("unexpected content type %q", contentType)}
+}
@@ -0,0 +1,114 @@
+package provider
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestCertsrvParserRequestID(t *testing.T) {
+	custom, err := newCertsrvParser(parserProfileCustom, map[string]string{
+		"issued_request_id": `data-request="(\d+)"`,
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	tests := []struct {
+		name    string
+		parser  *certsrvParser
+		page    string
+		want    string
+		wantErr string
+	}{
+		{"strict issued", strictParser, `<a href="certnew.cer?ReqID=1337&amp;Enc=b64">`, "1337", ""},
+		{"strict pending", strictParser, `<h3>Certificate Pending</h3> Your Request Id is 99.`, "", "certificate pending for request id 99"},
+		{"strict denied", strictParser, `The disposition message is "Denied by Policy Module"`, "", "Denied by Policy Module"},
+		{"strict unknown", strictParser, `<html></html>`, "", "unknown error"},
+		{"strict rejects portal markup", strictParser, `<A HREF='/certsrv/CERTNEW.CER?Enc=b64&ReqID=1337'>`, "", "unknown error"},
+		{"lenient portal markup", lenientParser, `<A HREF='/certsrv/CERTNEW.CER?Enc=b64&ReqID=1337'>`, "1337", ""},
+		{"lenient pending", lenientParser, `<p>Status: PENDING</p><p>Request ID is: 99</p>`, "", "certificate pending for request id 99"},
+		{"lenient denied", lenientParser, `Disposition message is: &quot;Denied by Policy Module&quot;`, "", "Denied by Policy Module"},
+		{"custom override", custom, `<div data-request="512"></div>`, "512", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.parser.requestID(tt.page)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("error = %v, want it to contain %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("request ID = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNewCertsrvParserErrors(t *testing.T) {
+	tests := []struct {
+		profile   string
+		overrides map[string]string
+	}{
+		{"fuzzy", nil},
+		{parserProfileStrict, map[string]string{"issued_request_id": `(\d+)`}},
+		{parserProfileCustom, map[string]string{"issued_request_id": `\d+`}},
+		{parserProfileCustom, map[string]string{"issued_request_id": `(`}},
+		{parserProfileCustom, map[string]string{"request": `(\d+)`}},
+	}
+	for _, tt := range tests {
+		if _, err := newCertsrvParser(tt.profile, tt.overrides); err == nil {
+			t.Errorf("newCertsrvParser(%q, %v) succeeded, want an error", tt.profile, tt.overrides)
+		}
+	}
+}
+
+func TestCertsrvParserCheckDownload(t *testing.T) {
+	pemBody := "-----BEGIN CERTIFICATE-----\nMIIB\n-----END CERTIFICATE-----\n"
+
+	if err := strictParser.checkDownload("application/pkix-cert", "application/pkix-cert", pemBody); err != nil {
+		t.Errorf("strict rejected the expected content type: %v", err)
+	}
+	if err := strictParser.checkDownload("text/html", "application/pkix-cert", pemBody); err == nil {
+		t.Errorf("strict accepted text/html")
+	}
+	if err := lenientParser.checkDownload("text/html", "application/pkix-cert", pemBody); err != nil {
+		t.Errorf("lenient rejected a PEM body: %v", err)
+	}
+	if err := lenientParser.checkDownload("Application/PKIX-Cert; charset=utf-8", "application/pkix-cert", "MIIB"); err != nil {
+		t.Errorf("lenient rejected a content type with parameters: %v", err)
+	}
+	err := lenientParser.checkDownload("text/html", "application/pkix-cert", `The disposition message is "Denied"`)
+	if err == nil || err.Error() != "Denied" {
+		t.Errorf("error = %v, want the disposition message", err)
+	}
+}
+
+func TestDispositionMessage(t *testing.T) {
+	_, denied := strictParser.requestID(`The disposition message is "Denied by Policy Module  0x80094800"`)
+	download := strictParser.checkDownload("text/html", "application/pkix-cert", `The disposition message is "Revoked"`)
+	for _, tc := range []struct {
+		err  error
+		want string
+	}{
+		{err: nil, want: "Issued"},
+		{err: errors.New("certificate pending for request id 7"), want: "Taken Under Submission"},
+		{err: denied, want: "Denied by Policy Module  0x80094800"},
+		{err: fmt.Errorf("failed to download certificate: %w", download), want: "Revoked"},
+		{err: errors.New("error making request: status error: 503"), want: ""},
+	} {
+		if got := dispositionMessage(tc.err); got != tc.want {
+			t.Errorf("dispositionMessage(%v) = %q, want %q", tc.err, got, tc.want)
+		}
+	}
+	// The errors keep the wording of the client's.
+	if denied.Error() != "failed to get request ID: Denied by Policy Module  0x80094800" {
+		t.Errorf("error = %q", denied.Error())
+	}
+}
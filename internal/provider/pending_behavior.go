@@ -0,0 +1,246 @@
+package provider
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/hashicorp/terraform-plugin-framework/diag"
+	"github.com/hashicorp/terraform-plugin-framework/path"
+	"github.com/hashicorp/terraform-plugin-framework/resource"
+	"github.com/hashicorp/terraform-plugin-framework/tfsdk"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+	"github.com/hashicorp/terraform-plugin-log/tflog"
+)
+
+// Values of pending_behavior, what the certificate resource does when the CA takes a request under
+// submission.
+const (
+	pendingBehaviorError = "error"
+	pendingBehaviorWait  = "wait"
+	pendingBehaviorSave  = "save"
+)
+
+// pendingBehaviors lists the values of pending_behavior.
+var pendingBehaviors = []string{pendingBehaviorError, pendingBehaviorWait, pendingBehaviorSave}
+
+// pendingBehavior returns pending_behavior, by default wait with wait_for_issuance and error otherwise.
+func (m *certificateCreateModel) pendingBehavior() string {
+	if !m.PendingBehavior.IsNull() && !m.PendingBehavior.IsUnknown() {
+		return m.PendingBehavior.ValueString()
+	}
+	if m.WaitForIssuance.ValueBool() {
+		return pendingBehaviorWait
+	}
+	return pendingBehaviorError
+}
+
+// setPending records a request the CA took under submission with pending_behavior = "save". The
+// certificate outputs stay null until a later apply finds the request issued.
+func (m *certificateCreateModel) setPending(reqID string) {
+	m.ID = types.StringValue(reqID)
+	m.RequestDisposition = types.StringValue(dispositionPending)
+	m.DispositionMessage = types.StringValue(dispositionMessagePending)
+	m.setRevocationStatus(nil)
+	m.ReadyForRenewal = types.BoolValue(false)
+	m.LastUpdated = types.StringValue(time.Now().Format(time.RFC850))
+	for _, value := range []*types.String{
+		&m.CertificateB64, &m.CertificateChainB64, &m.CertificateChainP7B, &m.CertificatePEM, &m.CertificateDER, &m.NotAfter,
+		&m.CertificateChainPEM, &m.IssuingCAPEM, &m.RootCAPEM, &m.ThumbprintSHA1, &m.ThumbprintSHA256, &m.SignatureAlgorithm, &m.CombinedPEM, &m.PKCS12B64,
+	} {
+		*value = types.StringNull()
+	}
+	m.CertificateChain = types.ListNull(types.StringType)
+	m.CertificateChainDER = types.ListNull(types.StringType)
+	m.CertificateChains = types.ListNull(certificateChainsType.ElemType)
+	m.KubernetesTLSSecret = types.MapNull(types.StringType)
+	m.AzureKeyVaultCertificate = types.ObjectNull(azureKeyVaultCertificateAttrTypes)
+}
+
+// savedPending reports whether the state holds a request saved with pending_behavior = "save" that
+// has not been resolved yet.
+func (m *certificateCreateModel) savedPending() bool {
+	return m.CertificateB64.IsNull() && !m.ID.IsNull()
+}
+
+// denyOnDestroy applies revoke_on_destroy to a saved request the CA has not issued a certificate for,
+// which leaves nothing to revoke. A request that is still pending is denied through the denial
+// webhook when one is configured, so a CA manager cannot issue it after the resource is gone.
+func (r *certificateResource) denyOnDestroy(ctx context.Context, state certificateCreateModel, diags *diag.Diagnostics) {
+	reqID := state.ID.ValueString()
+	if r.provider.denialWebhookURL == "" {
+		tflog.Info(ctx, "No certificate was issued for the request, nothing to revoke", map[string]interface{}{
+			"request_id": reqID,
+		})
+		return
+	}
+
+	disposition, err := r.provider.currentDisposition(ctx, state.Host.ValueString(), state.CAName.ValueString(), reqID)
+	if err != nil {
+		diags.AddError(
+			classifiedSummary("Unable to Deny Certificate Request", err),
+			fmt.Sprintf("Could not check whether request ID %s is still pending, it is kept in state: %s", reqID, err.Error()),
+		)
+		return
+	}
+	switch disposition {
+	case dispositionPending:
+	case dispositionIssued:
+		diags.AddWarning(
+			"Certificate Not Revoked",
+			fmt.Sprintf("Request ID %s was issued after the last refresh, its certificate is not in state and was not revoked.", reqID),
+		)
+		return
+	default:
+		tflog.Info(ctx, "Request is no longer pending, nothing to deny", map[string]interface{}{
+			"request_id":  reqID,
+			"disposition": disposition,
+		})
+		return
+	}
+
+	host := r.host(state)
+	denial := denialRequest{RequestID: reqID, Host: host, CAName: state.CAName.ValueString(), Reason: "revoke_on_destroy"}
+	if err := r.provider.denyRequest(ctx, denial); err != nil {
+		diags.AddError(
+			classifiedSummary("Unable to Deny Certificate Request", err),
+			fmt.Sprintf("Could not deny pending request ID %s, it is kept in state: %s", reqID, err.Error()),
+		)
+		return
+	}
+	tflog.Info(ctx, "Denied pending certificate request", map[string]interface{}{
+		"request_id": reqID,
+	})
+	event := newLifecycleEvent(eventDenied, host, reqID, state.Template.ValueString(), "")
+	event.CAName = state.CAName.ValueString()
+	if err := r.provider.emitEvent(ctx, event); err != nil {
+		diags.AddWarning(
+			"Unable to Publish Certificate Event",
+			fmt.Sprintf("Request ID %s was denied but the %q event could not be published: %s", reqID, eventDenied, err.Error()),
+		)
+	}
+}
+
+// planPendingResolution marks the outputs of a saved request as changing once a refresh found it
+// decided, so the apply takes over the certificate or reports the denial.
+func planPendingResolution(ctx context.Context, plan *tfsdk.Plan) diag.Diagnostics {
+	var diags diag.Diagnostics
+
+	for _, name := range []string{
+		"certificate_b64", "certificate_chain_b64", "certificate_chain_p7b", "certificate_pem", "certificate_der", "not_after", "last_updated",
+		"certificate_chain_pem", "issuing_ca_pem", "root_ca_pem", "thumbprint_sha1", "thumbprint_sha256", "signature_algorithm", "combined_pem", "pkcs12_b64",
+		"disposition_message", "request_disposition", "revocation_status", "revoked_reason", "revocation_date",
+	} {
+		diags.Append(plan.SetAttribute(ctx, path.Root(name), types.StringUnknown())...)
+	}
+	diags.Append(plan.SetAttribute(ctx, path.Root("certificate_chain"), types.ListUnknown(types.StringType))...)
+	diags.Append(plan.SetAttribute(ctx, path.Root("certificate_chain_der"), types.ListUnknown(types.StringType))...)
+	diags.Append(plan.SetAttribute(ctx, path.Root("certificate_chains"), types.ListUnknown(certificateChainsType.ElemType))...)
+	diags.Append(plan.SetAttribute(ctx, path.Root("kubernetes_tls_secret"), types.MapUnknown(types.StringType))...)
+	diags.Append(plan.SetAttribute(ctx, path.Root("azure_key_vault_certificate"), types.ObjectUnknown(azureKeyVaultCertificateAttrTypes))...)
+	return diags
+}
+
+// readPending refreshes a saved request. Only its disposition is updated, the apply after the
+// refresh takes over the certificate so it goes through the same checks as a new one.
+func (r *certificateResource) readPending(ctx context.Context, state *certificateCreateModel, diags *diag.Diagnostics) {
+	reqID := state.ID.ValueString()
+	_, err := retrieveCertificate(ctx, r.client, r.provider.parser, reqID)
+	if err == nil {
+		tflog.Info(ctx, "Saved certificate request was issued, the next apply takes over the certificate", map[string]interface{}{
+			"request_id": reqID,
+		})
+		state.RequestDisposition = types.StringValue(dispositionIssued)
+		state.DispositionMessage = types.StringValue(dispositionMessageIssued)
+		return
+	}
+	disposition, ok := requestDisposition(err)
+	if !ok {
+		diags.AddError(
+			classifiedSummary("Error Reading Certificate", err),
+			fmt.Sprintf("Could not check the disposition of pending request ID %s: %s", reqID, err.Error()),
+		)
+		return
+	}
+	state.RequestDisposition = types.StringValue(disposition)
+	state.DispositionMessage = types.StringValue(dispositionMessage(err))
+}
+
+// resolvePending takes over the certificate of a saved request that a refresh found decided.
+func (r *certificateResource) resolvePending(ctx context.Context, resp *resource.UpdateResponse, plan certificateCreateModel) {
+	reqID := plan.ID.ValueString()
+	route, diags := r.newCARoute(plan, plan.CAName.ValueString())
+	resp.Diagnostics.Append(diags...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+	routed := withCARoute(ctx, route)
+	certificates, err := retrieveCertificates(routed, r.client, r.provider.parser, reqID)
+	if err != nil {
+		if isStillPending(err) {
+			plan.setPending(reqID)
+			resp.Diagnostics.AddWarning(
+				"Certificate Request Still Pending",
+				fmt.Sprintf("Request ID %s is still pending approval by a CA manager, the next apply checks it again.", reqID),
+			)
+			resp.Diagnostics.Append(resp.State.Set(ctx, plan)...)
+			return
+		}
+		message := dispositionMessage(err)
+		if message == "" {
+			message = err.Error()
+		}
+		resp.Diagnostics.AddError(
+			classifiedSummary("Certificate Was Not Issued", err),
+			fmt.Sprintf("The CA did not issue pending request ID %s: %s\n\nReplace the resource to submit a new request.", reqID, message),
+		)
+		return
+	}
+
+	if plan.GeneratedPrivateKeyPEM.IsUnknown() {
+		plan.GeneratedPrivateKeyPEM = types.StringNull()
+	}
+	resp.Diagnostics.Append(plan.setIssuedCertificate(ctx, certificates)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+	resp.Diagnostics.Append(resp.State.Set(ctx, plan)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+	r.recordIssuance(ctx, resp.Private, plan, certificates, eventIssued, &resp.Diagnostics)
+}
+
+// validatePendingBehavior checks pending_behavior and that wait_for_issuance does not contradict it.
+func validatePendingBehavior(config certificateCreateModel) diag.Diagnostics {
+	var diags diag.Diagnostics
+	if config.PendingBehavior.IsNull() || config.PendingBehavior.IsUnknown() {
+		return diags
+	}
+
+	behavior := config.PendingBehavior.ValueString()
+	if !containsString(pendingBehaviors, behavior) {
+		diags.AddAttributeError(
+			path.Root("pending_behavior"),
+			"Invalid Pending Behavior",
+			fmt.Sprintf("pending_behavior %q is not one of: %s.", behavior, strings.Join(pendingBehaviors, ", ")),
+		)
+		return diags
+	}
+	if config.WaitForIssuance.ValueBool() && behavior != pendingBehaviorWait {
+		diags.AddAttributeError(
+			path.Root("wait_for_issuance"),
+			"Conflicting Pending Behavior",
+			fmt.Sprintf("wait_for_issuance = true waits for pending requests but pending_behavior is %q, remove wait_for_issuance.", behavior),
+		)
+	}
+	if behavior == pendingBehaviorSave && config.RenewExisting.ValueBool() {
+		diags.AddAttributeWarning(
+			path.Root("pending_behavior"),
+			"Renewals Are Not Saved Pending",
+			"renew_existing renews the certificate in place, a renewal the CA takes under submission fails the apply and keeps the current certificate in state.",
+		)
+	}
+	return diags
+}
@@ -0,0 +1,191 @@
+package provider
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/flipyap/microsoft-adcs-client/client"
+	"github.com/hashicorp/terraform-plugin-framework/diag"
+	fwresource "github.com/hashicorp/terraform-plugin-framework/resource"
+	"github.com/hashicorp/terraform-plugin-framework/tfsdk"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+	"github.com/hashicorp/terraform-plugin-go/tftypes"
+	"github.com/hashicorp/terraform-plugin-testing/helper/resource"
+)
+
+func TestPendingBehavior(t *testing.T) {
+	for name, tc := range map[string]struct {
+		model certificateCreateModel
+		want  string
+	}{
+		"default":           {model: certificateCreateModel{}, want: pendingBehaviorError},
+		"wait_for_issuance": {model: certificateCreateModel{WaitForIssuance: types.BoolValue(true)}, want: pendingBehaviorWait},
+		"save":              {model: certificateCreateModel{PendingBehavior: types.StringValue(pendingBehaviorSave)}, want: pendingBehaviorSave},
+	} {
+		if got := tc.model.pendingBehavior(); got != tc.want {
+			t.Errorf("%s: pendingBehavior() = %q, want %q", name, got, tc.want)
+		}
+	}
+}
+
+func TestValidatePendingBehavior(t *testing.T) {
+	for name, tc := range map[string]struct {
+		config certificateCreateModel
+		want   string
+	}{
+		"unset": {config: certificateCreateModel{}},
+		"wait":  {config: certificateCreateModel{PendingBehavior: types.StringValue(pendingBehaviorWait), WaitForIssuance: types.BoolValue(true)}},
+		"unknown value": {
+			config: certificateCreateModel{PendingBehavior: types.StringValue("queue")},
+			want:   "Invalid Pending Behavior",
+		},
+		"conflict": {
+			config: certificateCreateModel{PendingBehavior: types.StringValue(pendingBehaviorSave), WaitForIssuance: types.BoolValue(true)},
+			want:   "Conflicting Pending Behavior",
+		},
+	} {
+		diags := validatePendingBehavior(tc.config)
+		switch {
+		case tc.want == "" && diags.HasError():
+			t.Errorf("%s: validatePendingBehavior() = %v", name, diags)
+		case tc.want != "" && (!diags.HasError() || diags.Errors()[0].Summary() != tc.want):
+			t.Errorf("%s: validatePendingBehavior() = %v, want %q", name, diags, tc.want)
+		}
+	}
+}
+
+func TestSavePendingRequest(t *testing.T) {
+	srv := newAccCertsrv(t)
+	t.Setenv("ADCS_PASSWORD", "secret")
+	ctx := withRetryPolicy(context.Background(), noRetry)
+	data, err := debugEnrollConfigure(ctx, "test", map[string]interface{}{
+		"host":     srv.host(),
+		"username": "svc-terraform",
+		"use_ntlm": true,
+	}, io.Discard)
+	if err != nil {
+		t.Fatal(err)
+	}
+	r := &certificateResource{client: data.client, provider: data}
+	csr, err := debugEnrollRequest(ctx, "", "app.example.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	plan := &certificateCreateModel{
+		CSR:             types.StringValue(csr),
+		Template:        types.StringValue(accApprovalTemplate),
+		PendingBehavior: types.StringValue(pendingBehaviorSave),
+		CertificateB64:  types.StringUnknown(),
+		CertificatePEM:  types.StringUnknown(),
+	}
+	var diags diag.Diagnostics
+	if certificates := r.requestCertificate(ctx, plan, csr, "", &diags); certificates != nil || diags.HasError() {
+		t.Fatalf("requestCertificate() = %v, %v, want the request saved pending", certificates, diags)
+	}
+	if !plan.savedPending() || plan.RequestDisposition.ValueString() != dispositionPending || !plan.CertificatePEM.IsNull() {
+		t.Errorf("requestCertificate() saved request ID %s as %s, want pending with null outputs", plan.ID, plan.RequestDisposition)
+	}
+
+	// The acceptance certsrv approves requests when they are first polled for.
+	state := *plan
+	r.readPending(ctx, &state, &diags)
+	if diags.HasError() || state.RequestDisposition.ValueString() != dispositionIssued || !state.savedPending() {
+		t.Errorf("readPending() = %v, request_disposition %s, want issued and left to the apply", diags, state.RequestDisposition)
+	}
+}
+
+func TestDestroyPendingRequest(t *testing.T) {
+	certsrv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `The disposition message is "Taken Under Submission"`)
+	}))
+	defer certsrv.Close()
+	revocations := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		t.Error("the revocation webhook was called for a request without a certificate")
+	}))
+	defer revocations.Close()
+	var denials []denialRequest
+	webhook := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var denial denialRequest
+		if err := json.NewDecoder(r.Body).Decode(&denial); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		denials = append(denials, denial)
+	}))
+	defer webhook.Close()
+
+	data := &providerData{
+		client:               &client.ADCSClient{HostURL: strings.TrimPrefix(certsrv.URL, "http://"), NtlmClient: certsrv.Client(), UseNtlm: true},
+		parser:               strictParser,
+		revocationWebhookURL: revocations.URL,
+	}
+	r := &certificateResource{client: data.client, provider: data}
+	schema, state := testCertificateConfig(t, map[string]tftypes.Value{
+		"id":                  tftypes.NewValue(tftypes.String, "7"),
+		"template":            tftypes.NewValue(tftypes.String, accApprovalTemplate),
+		"pending_behavior":    tftypes.NewValue(tftypes.String, pendingBehaviorSave),
+		"request_disposition": tftypes.NewValue(tftypes.String, dispositionPending),
+		"revoke_on_destroy":   tftypes.NewValue(tftypes.Bool, true),
+	})
+	destroy := func() diag.Diagnostics {
+		resp := &fwresource.DeleteResponse{State: tfsdk.State{Schema: schema, Raw: state}}
+		r.Delete(context.Background(), fwresource.DeleteRequest{State: tfsdk.State{Schema: schema, Raw: state}}, resp)
+		return resp.Diagnostics
+	}
+
+	// Without a denial webhook there is nothing to do.
+	if diags := destroy(); diags.HasError() || len(denials) != 0 {
+		t.Errorf("Delete() = %v, denials %+v, want the request left alone", diags, denials)
+	}
+
+	r.provider.denialWebhookURL = webhook.URL
+	if diags := destroy(); diags.HasError() {
+		t.Fatalf("unexpected diagnostics: %v", diags)
+	}
+	if len(denials) != 1 || denials[0].RequestID != "7" {
+		t.Errorf("denials = %+v, want pending request 7 denied", denials)
+	}
+}
+
+func TestAccCertificateResourcePendingBehaviorSave(t *testing.T) {
+	srv := newAccCertsrv(t)
+	config := srv.providerConfig() + `
+resource "microsoftadcs_certificate" "test" {
+  template         = "` + accApprovalTemplate + `"
+  pending_behavior = "save"
+
+  generate_csr {
+    common_name = "app.example.com"
+  }
+}
+`
+
+	resource.Test(t, resource.TestCase{
+		ProtoV6ProviderFactories: testAccProtoV6ProviderFactories,
+		Steps: []resource.TestStep{
+			// The refresh after the apply finds the request approved, the next apply takes it over.
+			{
+				Config: config,
+				Check: resource.ComposeAggregateTestCheckFunc(
+					resource.TestCheckResourceAttr("microsoftadcs_certificate.test", "request_disposition", dispositionPending),
+					resource.TestCheckNoResourceAttr("microsoftadcs_certificate.test", "certificate_pem"),
+				),
+				ExpectNonEmptyPlan: true,
+			},
+			{
+				Config: config,
+				Check: resource.ComposeAggregateTestCheckFunc(
+					resource.TestCheckResourceAttr("microsoftadcs_certificate.test", "request_disposition", dispositionIssued),
+					resource.TestCheckResourceAttrSet("microsoftadcs_certificate.test", "certificate_pem"),
+				),
+			},
+		},
+	})
+}
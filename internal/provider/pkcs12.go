@@ -0,0 +1,411 @@
+package provider
+
+import (
+	"context"
+	"crypto"
+	"crypto/cipher"
+	"crypto/des"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha1"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"math/big"
+	"unicode/utf16"
+
+	"github.com/hashicorp/terraform-plugin-framework/diag"
+	"github.com/hashicorp/terraform-plugin-framework/path"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+	"github.com/hashicorp/terraform-plugin-framework/types/basetypes"
+)
+
+// pkcs12Iterations is the iteration count of the key derivations, the count Windows and OpenSSL use.
+const pkcs12Iterations = 2048
+
+var (
+	oidDataContentType            = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 7, 1}
+	oidEncryptedDataContentType   = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 7, 6}
+	oidPBEWithSHAAnd3KeyTripleDES = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 12, 1, 3}
+	oidPKCS8ShroudedKeyBag        = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 12, 10, 1, 2}
+	oidCertBag                    = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 12, 10, 1, 3}
+	oidX509CertificateBag         = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 9, 22, 1}
+	oidLocalKeyID                 = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 9, 21}
+	oidSHA1                       = asn1.ObjectIdentifier{1, 3, 14, 3, 2, 26}
+)
+
+// pkcs12Model maps the pkcs12 block.
+type pkcs12Model struct {
+	Password      types.String `tfsdk:"password"`
+	PrivateKeyPEM types.String `tfsdk:"private_key_pem"`
+}
+
+// setPKCS12 sets pkcs12_b64 to the leaf, the selected chain and the private key as a PFX file
+// protected with the password of the pkcs12 block, null without the block. The key is taken from
+// the block, else from private_key_pem or generate_csr; without one the file holds certificates only.
+func (m *certificateCreateModel) setPKCS12(ctx context.Context, material *certificateMaterial) diag.Diagnostics {
+	var diags diag.Diagnostics
+	m.PKCS12B64 = types.StringNull()
+	if m.PKCS12.IsNull() || m.PKCS12.IsUnknown() {
+		return diags
+	}
+
+	var block pkcs12Model
+	diags.Append(m.PKCS12.As(ctx, &block, basetypes.ObjectAsOptions{})...)
+	if diags.HasError() {
+		return diags
+	}
+
+	keyPEM, keyPath := material.keyPEM, path.Root("private_key_pem")
+	if !block.PrivateKeyPEM.IsNull() {
+		keyPEM, keyPath = block.PrivateKeyPEM.ValueString(), path.Root("pkcs12").AtName("private_key_pem")
+	}
+	var key crypto.Signer
+	if keyPEM != "" {
+		var err error
+		if key, err = parsePrivateKey(keyPEM); err != nil {
+			diags.AddAttributeError(keyPath, "Invalid Private Key", "Could not parse the private key: "+err.Error())
+			return diags
+		}
+		if !keyMatches(key, material.leaf.PublicKey) {
+			diags.AddAttributeError(keyPath, "Private Key Does Not Match Certificate",
+				"The private key for the PKCS#12 bundle is not the key of the issued certificate.")
+			return diags
+		}
+	}
+
+	pfx, err := encodePKCS12(material.leaf, material.chain, key, block.Password.ValueString())
+	if err != nil {
+		diags.AddAttributeError(path.Root("pkcs12"), "Unable to Create PKCS#12 Bundle", err.Error())
+		return diags
+	}
+	m.PKCS12B64 = types.StringValue(base64.StdEncoding.EncodeToString(pfx))
+	return diags
+}
+
+// validatePKCS12 checks that the pkcs12 block has a password, PFX files without one are not
+// protected and several consumers refuse them.
+func validatePKCS12(ctx context.Context, config certificateCreateModel) diag.Diagnostics {
+	var diags diag.Diagnostics
+	if config.PKCS12.IsNull() || config.PKCS12.IsUnknown() {
+		return diags
+	}
+
+	var block pkcs12Model
+	diags.Append(config.PKCS12.As(ctx, &block, basetypes.ObjectAsOptions{})...)
+	if diags.HasError() || block.Password.IsUnknown() {
+		return diags
+	}
+	if block.Password.ValueString() == "" {
+		diags.AddAttributeError(path.Root("pkcs12").AtName("password"), "Missing PKCS#12 Password",
+			"Set a password for the PKCS#12 bundle.")
+		return diags
+	}
+	if _, err := bmpString(block.Password.ValueString()); err != nil {
+		diags.AddAttributeError(path.Root("pkcs12").AtName("password"), "Invalid PKCS#12 Password", err.Error())
+	}
+	return diags
+}
+
+type pkcs12ContentInfo struct {
+	ContentType asn1.ObjectIdentifier
+	Content     asn1.RawValue `asn1:"tag:0,explicit,optional"`
+}
+
+type pkcs12EncryptedData struct {
+	Version              int
+	EncryptedContentInfo pkcs12EncryptedContentInfo
+}
+
+type pkcs12EncryptedContentInfo struct {
+	ContentType                asn1.ObjectIdentifier
+	ContentEncryptionAlgorithm pkix.AlgorithmIdentifier
+	EncryptedContent           []byte `asn1:"tag:0,optional"`
+}
+
+type pkcs12SafeBag struct {
+	ID         asn1.ObjectIdentifier
+	Value      asn1.RawValue     `asn1:"tag:0,explicit"`
+	Attributes []pkcs12Attribute `asn1:"set,optional"`
+}
+
+type pkcs12Attribute struct {
+	ID    asn1.ObjectIdentifier
+	Value asn1.RawValue `asn1:"set"`
+}
+
+type pkcs12CertBag struct {
+	ID   asn1.ObjectIdentifier
+	Data []byte `asn1:"tag:0,explicit"`
+}
+
+type pkcs12EncryptedPrivateKeyInfo struct {
+	AlgorithmIdentifier pkix.AlgorithmIdentifier
+	EncryptedData       []byte
+}
+
+type pkcs12PBEParams struct {
+	Salt       []byte
+	Iterations int
+}
+
+type pkcs12MacData struct {
+	Mac        pkcs12DigestInfo
+	MacSalt    []byte
+	Iterations int `asn1:"optional,default:1"`
+}
+
+type pkcs12DigestInfo struct {
+	Algorithm pkix.AlgorithmIdentifier
+	Digest    []byte
+}
+
+type pkcs12PFX struct {
+	Version  int
+	AuthSafe pkcs12ContentInfo
+	MacData  pkcs12MacData `asn1:"optional"`
+}
+
+// encodePKCS12 bundles the leaf, its chain and, when given, its private key into a password
+// protected PFX file. Keys and certificates are encrypted with pbeWithSHAAnd3-KeyTripleDES-CBC
+// and the file is authenticated with an HMAC-SHA1, the combination every Windows release and
+// Java version imports.
+func encodePKCS12(leaf *x509.Certificate, chain []*x509.Certificate, key crypto.Signer, password string) ([]byte, error) {
+	bmpPassword, err := bmpString(password)
+	if err != nil {
+		return nil, err
+	}
+
+	// The local key ID pairs the leaf with its key on import.
+	localKeyID := sha1.Sum(leaf.Raw)
+	keyIDAttribute, err := pkcs12LocalKeyID(localKeyID[:])
+	if err != nil {
+		return nil, err
+	}
+
+	var certBags []pkcs12SafeBag
+	for i, cert := range append([]*x509.Certificate{leaf}, chain...) {
+		bag, err := asn1.Marshal(pkcs12CertBag{ID: oidX509CertificateBag, Data: cert.Raw})
+		if err != nil {
+			return nil, fmt.Errorf("could not encode certificate bag: %v", err)
+		}
+		safeBag := pkcs12SafeBag{ID: oidCertBag, Value: asn1.RawValue{FullBytes: explicitTag0(bag)}}
+		if i == 0 && key != nil {
+			safeBag.Attributes = []pkcs12Attribute{keyIDAttribute}
+		}
+		certBags = append(certBags, safeBag)
+	}
+	certContents, err := asn1.Marshal(certBags)
+	if err != nil {
+		return nil, fmt.Errorf("could not encode certificates: %v", err)
+	}
+	algorithm, encrypted, err := pkcs12Encrypt(bmpPassword, certContents)
+	if err != nil {
+		return nil, err
+	}
+	encryptedData, err := asn1.Marshal(pkcs12EncryptedData{
+		EncryptedContentInfo: pkcs12EncryptedContentInfo{
+			ContentType:                oidDataContentType,
+			ContentEncryptionAlgorithm: algorithm,
+			EncryptedContent:           encrypted,
+		},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("could not encode encrypted certificates: %v", err)
+	}
+	authenticatedSafe := []pkcs12ContentInfo{{
+		ContentType: oidEncryptedDataContentType,
+		Content:     asn1.RawValue{FullBytes: explicitTag0(encryptedData)},
+	}}
+
+	if key != nil {
+		der, err := x509.MarshalPKCS8PrivateKey(key)
+		if err != nil {
+			return nil, fmt.Errorf("could not encode private key: %v", err)
+		}
+		algorithm, encrypted, err := pkcs12Encrypt(bmpPassword, der)
+		if err != nil {
+			return nil, err
+		}
+		shrouded, err := asn1.Marshal(pkcs12EncryptedPrivateKeyInfo{AlgorithmIdentifier: algorithm, EncryptedData: encrypted})
+		if err != nil {
+			return nil, fmt.Errorf("could not encode encrypted private key: %v", err)
+		}
+		keyContents, err := asn1.Marshal([]pkcs12SafeBag{{
+			ID:         oidPKCS8ShroudedKeyBag,
+			Value:      asn1.RawValue{FullBytes: explicitTag0(shrouded)},
+			Attributes: []pkcs12Attribute{keyIDAttribute},
+		}})
+		if err != nil {
+			return nil, fmt.Errorf("could not encode private key bag: %v", err)
+		}
+		data, err := asn1.Marshal(keyContents)
+		if err != nil {
+			return nil, fmt.Errorf("could not encode private key bag: %v", err)
+		}
+		authenticatedSafe = append(authenticatedSafe, pkcs12ContentInfo{
+			ContentType: oidDataContentType,
+			Content:     asn1.RawValue{FullBytes: explicitTag0(data)},
+		})
+	}
+
+	authSafe, err := asn1.Marshal(authenticatedSafe)
+	if err != nil {
+		return nil, fmt.Errorf("could not encode authenticated safe: %v", err)
+	}
+	authSafeData, err := asn1.Marshal(authSafe)
+	if err != nil {
+		return nil, fmt.Errorf("could not encode authenticated safe: %v", err)
+	}
+
+	macSalt, err := randomBytes(8)
+	if err != nil {
+		return nil, err
+	}
+	mac := hmac.New(sha1.New, pkcs12KeyDerivation(bmpPassword, macSalt, pkcs12Iterations, 3, sha1.Size))
+	mac.Write(authSafe)
+
+	pfx, err := asn1.Marshal(pkcs12PFX{
+		Version: 3,
+		AuthSafe: pkcs12ContentInfo{
+			ContentType: oidDataContentType,
+			Content:     asn1.RawValue{FullBytes: explicitTag0(authSafeData)},
+		},
+		MacData: pkcs12MacData{
+			Mac: pkcs12DigestInfo{
+				Algorithm: pkix.AlgorithmIdentifier{Algorithm: oidSHA1, Parameters: asn1.NullRawValue},
+				Digest:    mac.Sum(nil),
+			},
+			MacSalt:    macSalt,
+			Iterations: pkcs12Iterations,
+		},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("could not encode PFX: %v", err)
+	}
+	return pfx, nil
+}
+
+// pkcs12LocalKeyID builds the localKeyId attribute.
+func pkcs12LocalKeyID(id []byte) (pkcs12Attribute, error) {
+	value, err := asn1.Marshal(id)
+	if err != nil {
+		return pkcs12Attribute{}, fmt.Errorf("could not encode local key ID: %v", err)
+	}
+	return pkcs12Attribute{ID: oidLocalKeyID, Value: asn1.RawValue{Class: asn1.ClassUniversal, Tag: asn1.TagSet, IsCompound: true, Bytes: value}}, nil
+}
+
+// explicitTag0 wraps DER in the [0] EXPLICIT tag of content and bag values.
+func explicitTag0(der []byte) []byte {
+	wrapped, _ := asn1.Marshal(asn1.RawValue{Class: asn1.ClassContextSpecific, Tag: 0, IsCompound: true, Bytes: der})
+	return wrapped
+}
+
+// pkcs12Encrypt encrypts data with pbeWithSHAAnd3-KeyTripleDES-CBC under a fresh salt.
+func pkcs12Encrypt(password []byte, data []byte) (pkix.AlgorithmIdentifier, []byte, error) {
+	salt, err := randomBytes(8)
+	if err != nil {
+		return pkix.AlgorithmIdentifier{}, nil, err
+	}
+	params, err := asn1.Marshal(pkcs12PBEParams{Salt: salt, Iterations: pkcs12Iterations})
+	if err != nil {
+		return pkix.AlgorithmIdentifier{}, nil, fmt.Errorf("could not encode encryption parameters: %v", err)
+	}
+
+	block, err := des.NewTripleDESCipher(pkcs12KeyDerivation(password, salt, pkcs12Iterations, 1, 24))
+	if err != nil {
+		return pkix.AlgorithmIdentifier{}, nil, fmt.Errorf("could not create cipher: %v", err)
+	}
+	iv := pkcs12KeyDerivation(password, salt, pkcs12Iterations, 2, block.BlockSize())
+
+	padding := block.BlockSize() - len(data)%block.BlockSize()
+	encrypted := make([]byte, len(data)+padding)
+	copy(encrypted, data)
+	for i := len(data); i < len(encrypted); i++ {
+		encrypted[i] = byte(padding)
+	}
+	cipher.NewCBCEncrypter(block, iv).CryptBlocks(encrypted, encrypted)
+
+	return pkix.AlgorithmIdentifier{Algorithm: oidPBEWithSHAAnd3KeyTripleDES, Parameters: asn1.RawValue{FullBytes: params}}, encrypted, nil
+}
+
+// pkcs12KeyDerivation derives key material from the password as RFC 7292, appendix B.2 describes
+// it for SHA-1. id selects what the material is for: 1 for keys, 2 for IVs and 3 for MAC keys.
+func pkcs12KeyDerivation(password []byte, salt []byte, iterations int, id byte, size int) []byte {
+	const u, v = sha1.Size, 64
+
+	d := make([]byte, v)
+	for i := range d {
+		d[i] = id
+	}
+	fill := func(b []byte) []byte {
+		if len(b) == 0 {
+			return nil
+		}
+		filled := make([]byte, v*((len(b)+v-1)/v))
+		for i := range filled {
+			filled[i] = b[i%len(b)]
+		}
+		return filled
+	}
+	input := append(fill(salt), fill(password)...)
+
+	one := big.NewInt(1)
+	var derived []byte
+	for len(derived) < size {
+		hash := sha1.New()
+		hash.Write(d)
+		hash.Write(input)
+		a := hash.Sum(nil)
+		for i := 1; i < iterations; i++ {
+			sum := sha1.Sum(a)
+			a = sum[:]
+		}
+		derived = append(derived, a...)
+
+		// Add B + 1 to every v byte block of the input for the next round.
+		b := new(big.Int).SetBytes(fill(a[:u])[:v])
+		b.Add(b, one)
+		for j := 0; j < len(input); j += v {
+			block := new(big.Int).SetBytes(input[j : j+v])
+			block.Add(block, b)
+			sum := block.Bytes()
+			if len(sum) > v {
+				sum = sum[len(sum)-v:]
+			}
+			chunk := input[j : j+v]
+			for k := range chunk {
+				chunk[k] = 0
+			}
+			copy(chunk[v-len(sum):], sum)
+		}
+	}
+	return derived[:size]
+}
+
+// bmpString encodes the password as the NUL terminated UTF-16 big endian string PKCS#12 derives
+// keys from.
+func bmpString(s string) ([]byte, error) {
+	encoded := make([]byte, 0, 2*len(s)+2)
+	for _, r := range s {
+		if r > 0xffff {
+			return nil, fmt.Errorf("the PKCS#12 password cannot hold characters outside the Basic Multilingual Plane such as %q", r)
+		}
+		for _, unit := range utf16.Encode([]rune{r}) {
+			encoded = append(encoded, byte(unit>>8), byte(unit))
+		}
+	}
+	return append(encoded, 0, 0), nil
+}
+
+// randomBytes returns n random bytes for salts.
+func randomBytes(n int) ([]byte, error) {
+	b := make([]byte, n)
+	if _, err := io.ReadFull(rand.Reader, b); err != nil {
+		return nil, fmt.Errorf("could not generate salt: %v", err)
+	}
+	return b, nil
+}
@@ -0,0 +1,229 @@
+package provider
+
+import (
+	"context"
+	"crypto/x509"
+	"encoding/asn1"
+	"encoding/base64"
+	"encoding/pem"
+	"fmt"
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-framework/attr"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+	"github.com/hashicorp/terraform-plugin-testing/helper/resource"
+	"github.com/hashicorp/terraform-plugin-testing/terraform"
+	"golang.org/x/crypto/pkcs12"
+)
+
+var pkcs12AttrTypes = map[string]attr.Type{
+	"password":        types.StringType,
+	"private_key_pem": types.StringType,
+}
+
+func testPKCS12Block(password string) types.Object {
+	return types.ObjectValueMust(pkcs12AttrTypes, map[string]attr.Value{
+		"password":        types.StringValue(password),
+		"private_key_pem": types.StringNull(),
+	})
+}
+
+// decodePKCS12 decodes pkcs12_b64 and returns the PEM block types it holds, by common name for
+// certificates.
+func decodePKCS12(t *testing.T, b64 string, password string) (certificates []string, keys int) {
+	t.Helper()
+
+	pfx, err := base64.StdEncoding.DecodeString(b64)
+	if err != nil {
+		t.Fatalf("pkcs12_b64 is not base64: %v", err)
+	}
+	blocks, err := pkcs12.ToPEM(pfx, password)
+	if err != nil {
+		t.Fatalf("could not decode PFX: %v", err)
+	}
+	for _, block := range blocks {
+		switch block.Type {
+		case "CERTIFICATE":
+			cert, err := x509.ParseCertificate(block.Bytes)
+			if err != nil {
+				t.Fatalf("could not parse certificate: %v", err)
+			}
+			certificates = append(certificates, cert.Subject.CommonName)
+		case "PRIVATE KEY":
+			keys++
+		}
+	}
+	return certificates, keys
+}
+
+func TestPKCS12WithKey(t *testing.T) {
+	h := newTestHierarchy(t)
+	der, err := x509.MarshalPKCS8PrivateKey(h.leafKey)
+	if err != nil {
+		t.Fatalf("could not marshal key: %v", err)
+	}
+
+	model := certificateCreateModel{
+		PrivateKeyPEM: types.StringValue(string(pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der}))),
+		PKCS12:        testPKCS12Block("s3cret"),
+		PKCS12B64:     types.StringUnknown(),
+	}
+	if diags := model.setCertificateOutputs(context.Background(), h.testCertificates(t)); diags.HasError() {
+		t.Fatalf("unexpected diagnostics: %v", diags)
+	}
+
+	certificates, keys := decodePKCS12(t, model.PKCS12B64.ValueString(), "s3cret")
+	if want := []string{"app.example.com", "Test Issuing CA", "Test Root CA"}; len(certificates) != len(want) {
+		t.Errorf("certificates = %v, want %v", certificates, want)
+	} else {
+		for i := range want {
+			if certificates[i] != want[i] {
+				t.Errorf("certificates = %v, want %v", certificates, want)
+				break
+			}
+		}
+	}
+	if keys != 1 {
+		t.Errorf("got %d private keys, want 1", keys)
+	}
+
+	pfx, _ := base64.StdEncoding.DecodeString(model.PKCS12B64.ValueString())
+	if _, err := pkcs12.ToPEM(pfx, "wrong"); err == nil {
+		t.Errorf("expected the PFX not to open with a wrong password")
+	}
+}
+
+func TestPKCS12WithoutKey(t *testing.T) {
+	h := newTestHierarchy(t)
+
+	model := certificateCreateModel{PKCS12: testPKCS12Block("s3cret"), PKCS12B64: types.StringUnknown()}
+	if diags := model.setCertificateOutputs(context.Background(), h.testCertificates(t)); diags.HasError() {
+		t.Fatalf("unexpected diagnostics: %v", diags)
+	}
+
+	// golang.org/x/crypto/pkcs12 only reads files with a key, check that the authenticated safe
+	// holds the encrypted certificates alone.
+	der, err := base64.StdEncoding.DecodeString(model.PKCS12B64.ValueString())
+	if err != nil {
+		t.Fatalf("pkcs12_b64 is not base64: %v", err)
+	}
+	var pfx pkcs12PFX
+	if _, err := asn1.Unmarshal(der, &pfx); err != nil {
+		t.Fatalf("could not decode PFX: %v", err)
+	}
+	var authSafe []byte
+	if _, err := asn1.Unmarshal(pfx.AuthSafe.Content.Bytes, &authSafe); err != nil {
+		t.Fatalf("could not decode authenticated safe: %v", err)
+	}
+	var contents []pkcs12ContentInfo
+	if _, err := asn1.Unmarshal(authSafe, &contents); err != nil {
+		t.Fatalf("could not decode authenticated safe: %v", err)
+	}
+	if len(contents) != 1 || !contents[0].ContentType.Equal(oidEncryptedDataContentType) {
+		t.Errorf("got %d content infos, want only the encrypted certificates", len(contents))
+	}
+}
+
+func TestPKCS12KeyMismatch(t *testing.T) {
+	h := newTestHierarchy(t)
+	der, err := x509.MarshalPKCS8PrivateKey(h.rootKey)
+	if err != nil {
+		t.Fatalf("could not marshal key: %v", err)
+	}
+
+	model := certificateCreateModel{
+		PKCS12: types.ObjectValueMust(pkcs12AttrTypes, map[string]attr.Value{
+			"password":        types.StringValue("s3cret"),
+			"private_key_pem": types.StringValue(string(pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der}))),
+		}),
+		PKCS12B64: types.StringUnknown(),
+	}
+	if diags := model.setCertificateOutputs(context.Background(), h.testCertificates(t)); !diags.HasError() {
+		t.Errorf("expected an error for a key that does not match the certificate")
+	}
+}
+
+func TestPKCS12KeptWhenKnown(t *testing.T) {
+	h := newTestHierarchy(t)
+
+	model := certificateCreateModel{PKCS12: testPKCS12Block("s3cret"), PKCS12B64: types.StringValue("previous")}
+	if diags := model.setCertificateOutputs(context.Background(), h.testCertificates(t)); diags.HasError() {
+		t.Fatalf("unexpected diagnostics: %v", diags)
+	}
+	if model.PKCS12B64.ValueString() != "previous" {
+		t.Errorf("pkcs12_b64 was rebuilt on refresh")
+	}
+}
+
+func TestValidatePKCS12(t *testing.T) {
+	for password, wantError := range map[string]bool{"s3cret": false, "": true, "key\U0001F511": true} {
+		diags := validatePKCS12(context.Background(), certificateCreateModel{PKCS12: testPKCS12Block(password)})
+		if diags.HasError() != wantError {
+			t.Errorf("password %q: got diagnostics %v, want error %v", password, diags, wantError)
+		}
+	}
+}
+
+// testCheckPKCS12Password checks that pkcs12_b64 opens with the password and holds the key.
+func testCheckPKCS12Password(name string, password string) resource.TestCheckFunc {
+	return func(s *terraform.State) error {
+		pfx, err := base64.StdEncoding.DecodeString(s.RootModule().Resources[name].Primary.Attributes["pkcs12_b64"])
+		if err != nil {
+			return fmt.Errorf("pkcs12_b64 is not base64: %v", err)
+		}
+		if _, _, err := pkcs12.Decode(pfx, password); err != nil {
+			return fmt.Errorf("could not decode pkcs12_b64 with password %q: %v", password, err)
+		}
+		return nil
+	}
+}
+
+func TestAccCertificateResourcePKCS12(t *testing.T) {
+	srv := newAccCertsrv(t)
+
+	config := func(password string) string {
+		return srv.providerConfig() + fmt.Sprintf(`
+resource "microsoftadcs_certificate" "test" {
+  template = "WebServer"
+
+  generate_csr {
+    common_name = "web.example.com"
+  }
+
+  pkcs12 {
+    password = %q
+  }
+}
+`, password)
+	}
+
+	var id string
+	resource.Test(t, resource.TestCase{
+		ProtoV6ProviderFactories: testAccProtoV6ProviderFactories,
+		Steps: []resource.TestStep{
+			{
+				Config: config("first"),
+				Check: resource.ComposeAggregateTestCheckFunc(
+					testCheckPKCS12Password("microsoftadcs_certificate.test", "first"),
+					func(s *terraform.State) error {
+						id = s.RootModule().Resources["microsoftadcs_certificate.test"].Primary.ID
+						return nil
+					},
+				),
+			},
+			{
+				// A new password rebuilds the bundle without a new certificate.
+				Config: config("second"),
+				Check: resource.ComposeAggregateTestCheckFunc(
+					testCheckPKCS12Password("microsoftadcs_certificate.test", "second"),
+					func(s *terraform.State) error {
+						if got := s.RootModule().Resources["microsoftadcs_certificate.test"].Primary.ID; got != id {
+							return fmt.Errorf("the certificate was replaced: id %s, was %s", got, id)
+						}
+						return nil
+					},
+				),
+			},
+		},
+	})
+}
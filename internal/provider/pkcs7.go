@@ -0,0 +1,290 @@
+package provider
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"fmt"
+	"math/big"
+	"sort"
+)
+
+var (
+	oidSignedData      = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 7, 2}
+	oidData            = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 7, 1}
+	oidContentType     = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 9, 3}
+	oidMessageDigest   = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 9, 4}
+	oidSHA256          = asn1.ObjectIdentifier{2, 16, 840, 1, 101, 3, 4, 2, 1}
+	oidRSAEncryption   = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 1, 1}
+	oidECDSAWithSHA256 = asn1.ObjectIdentifier{1, 2, 840, 10045, 4, 3, 2}
+)
+
+// pkcs7Attribute is an authenticated attribute of a PKCS#7 signer with a single value.
+type pkcs7Attribute struct {
+	Type  asn1.ObjectIdentifier
+	Value []byte
+}
+
+type pkcs7SignerInfo struct {
+	Version                   int
+	IssuerAndSerialNumber     pkcs7IssuerAndSerialNumber
+	DigestAlgorithm           pkix.AlgorithmIdentifier
+	AuthenticatedAttributes   asn1.RawValue
+	DigestEncryptionAlgorithm pkix.AlgorithmIdentifier
+	EncryptedDigest           []byte
+}
+
+type pkcs7IssuerAndSerialNumber struct {
+	Issuer       asn1.RawValue
+	SerialNumber *big.Int
+}
+
+// signPKCS7 wraps content in a PKCS#7 SignedData structure signed with key, carrying cert as the
+// signer certificate. The signature covers the content type and digest of the content along with
+// the extra authenticated attributes.
+func signPKCS7(content []byte, cert *x509.Certificate, key crypto.Signer, attributes ...pkcs7Attribute) ([]byte, error) {
+	var signatureAlgorithm pkix.AlgorithmIdentifier
+	switch key.Public().(type) {
+	case *rsa.PublicKey:
+		signatureAlgorithm = pkix.AlgorithmIdentifier{Algorithm: oidRSAEncryption, Parameters: asn1.NullRawValue}
+	case *ecdsa.PublicKey:
+		signatureAlgorithm = pkix.AlgorithmIdentifier{Algorithm: oidECDSAWithSHA256}
+	default:
+		return nil, fmt.Errorf("unsupported signing key type %T", key.Public())
+	}
+
+	contentType, err := asn1.Marshal(oidData)
+	if err != nil {
+		return nil, err
+	}
+	digest := sha256.Sum256(content)
+	messageDigest, err := asn1.Marshal(digest[:])
+	if err != nil {
+		return nil, err
+	}
+	attributes = append([]pkcs7Attribute{{oidContentType, contentType}, {oidMessageDigest, messageDigest}}, attributes...)
+
+	// The authenticated attributes are a DER SET OF, sorted by their encoding. They are signed with
+	// the SET tag and sent with the [0] IMPLICIT tag of SignerInfo.
+	encoded := make([][]byte, 0, len(attributes))
+	for _, attribute := range attributes {
+		der, err := asn1.Marshal(struct {
+			Type   asn1.ObjectIdentifier
+			Values asn1.RawValue
+		}{attribute.Type, asn1.RawValue{Class: asn1.ClassUniversal, Tag: asn1.TagSet, IsCompound: true, Bytes: attribute.Value}})
+		if err != nil {
+			return nil, fmt.Errorf("could not marshal authenticated attribute %s: %v", attribute.Type, err)
+		}
+		encoded = append(encoded, der)
+	}
+	sort.Slice(encoded, func(i, j int) bool { return bytes.Compare(encoded[i], encoded[j]) < 0 })
+	authenticated := bytes.Join(encoded, nil)
+
+	signed, err := asn1.Marshal(asn1.RawValue{Class: asn1.ClassUniversal, Tag: asn1.TagSet, IsCompound: true, Bytes: authenticated})
+	if err != nil {
+		return nil, err
+	}
+	signedDigest := sha256.Sum256(signed)
+	signature, err := key.Sign(rand.Reader, signedDigest[:], crypto.SHA256)
+	if err != nil {
+		return nil, fmt.Errorf("could not sign: %v", err)
+	}
+
+	encapsulated, err := asn1.Marshal(content)
+	if err != nil {
+		return nil, err
+	}
+	digestAlgorithm := pkix.AlgorithmIdentifier{Algorithm: oidSHA256, Parameters: asn1.NullRawValue}
+	signedData, err := asn1.Marshal(struct {
+		Version          int
+		DigestAlgorithms []pkix.AlgorithmIdentifier `asn1:"set"`
+		ContentInfo      struct {
+			ContentType asn1.ObjectIdentifier
+			Content     asn1.RawValue
+		}
+		Certificates asn1.RawValue
+		SignerInfos  []pkcs7SignerInfo `asn1:"set"`
+	}{
+		Version:          1,
+		DigestAlgorithms: []pkix.AlgorithmIdentifier{digestAlgorithm},
+		ContentInfo: struct {
+			ContentType asn1.ObjectIdentifier
+			Content     asn1.RawValue
+		}{oidData, asn1.RawValue{Class: asn1.ClassContextSpecific, Tag: 0, IsCompound: true, Bytes: encapsulated}},
+		Certificates: asn1.RawValue{Class: asn1.ClassContextSpecific, Tag: 0, IsCompound: true, Bytes: cert.Raw},
+		SignerInfos: []pkcs7SignerInfo{{
+			Version:                   1,
+			IssuerAndSerialNumber:     pkcs7IssuerAndSerialNumber{Issuer: asn1.RawValue{FullBytes: cert.RawIssuer}, SerialNumber: cert.SerialNumber},
+			DigestAlgorithm:           digestAlgorithm,
+			AuthenticatedAttributes:   asn1.RawValue{Class: asn1.ClassContextSpecific, Tag: 0, IsCompound: true, Bytes: authenticated},
+			DigestEncryptionAlgorithm: signatureAlgorithm,
+			EncryptedDigest:           signature,
+		}},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("could not marshal PKCS#7 signed data: %v", err)
+	}
+
+	return asn1.Marshal(struct {
+		ContentType asn1.ObjectIdentifier
+		Content     asn1.RawValue
+	}{oidSignedData, asn1.RawValue{Class: asn1.ClassContextSpecific, Tag: 0, IsCompound: true, Bytes: signedData}})
+}
+
+// isPKCS7 reports whether der is a PKCS#7 content info of signed data rather than, for example, a
+// certificate signing request.
+func isPKCS7(der []byte) bool {
+	var contentInfo struct {
+		ContentType asn1.ObjectIdentifier
+		Content     asn1.RawValue
+	}
+	_, err := asn1.Unmarshal(der, &contentInfo)
+	return err == nil && contentInfo.ContentType.Equal(oidSignedData)
+}
+
+// parsePKCS7Certificates returns the certificates carried in a degenerate PKCS#7 SignedData
+// structure, which is the format certsrv uses for certificate chains (certnew.p7b).
+func parsePKCS7Certificates(der []byte) ([]*x509.Certificate, error) {
+	var contentInfo struct {
+		ContentType asn1.ObjectIdentifier
+		Content     asn1.RawValue
+	}
+	if _, err := asn1.Unmarshal(der, &contentInfo); err != nil {
+		return nil, fmt.Errorf("could not parse PKCS#7 content info: %v", err)
+	}
+	if !contentInfo.ContentType.Equal(oidSignedData) {
+		return nil, fmt.Errorf("expected PKCS#7 signed data, got content type %s", contentInfo.ContentType)
+	}
+
+	// The content is wrapped in an explicit [0] tag.
+	var signedData asn1.RawValue
+	if _, err := asn1.Unmarshal(contentInfo.Content.Bytes, &signedData); err != nil {
+		return nil, fmt.Errorf("could not parse PKCS#7 signed data: %v", err)
+	}
+
+	// certificates is the only [0] IMPLICIT field of SignedData, walk the fields to find it rather
+	// than modelling the optional fields around it.
+	rest := signedData.Bytes
+	for len(rest) > 0 {
+		var field asn1.RawValue
+		var err error
+		rest, err = asn1.Unmarshal(rest, &field)
+		if err != nil {
+			return nil, fmt.Errorf("could not parse PKCS#7 signed data: %v", err)
+		}
+		if field.Class == asn1.ClassContextSpecific && field.Tag == 0 {
+			return x509.ParseCertificates(field.Bytes)
+		}
+	}
+
+	return nil, nil
+}
+
+// signedRequest is a certificate signing request wrapped in a PKCS#7 signed by another certificate,
+// such as a renewal or enroll on behalf of request.
+type signedRequest struct {
+	request *x509.CertificateRequest
+	signer  *x509.Certificate
+	// attributes are the authenticated attributes of the signer besides the content type and
+	// message digest.
+	attributes []pkcs7Attribute
+}
+
+// attribute returns the value of the authenticated attribute of type oid.
+func (r *signedRequest) attribute(oid asn1.ObjectIdentifier) ([]byte, bool) {
+	for _, attribute := range r.attributes {
+		if attribute.Type.Equal(oid) {
+			return attribute.Value, true
+		}
+	}
+	return nil, false
+}
+
+// parseSignedRequest unwraps a PKCS#7 request like the CA does: the signature has to verify against
+// the signer certificate and the message digest has to match the certificate signing request.
+func parseSignedRequest(der []byte) (*signedRequest, error) {
+	var contentInfo struct {
+		ContentType asn1.ObjectIdentifier
+		Content     asn1.RawValue
+	}
+	if _, err := asn1.Unmarshal(der, &contentInfo); err != nil || !contentInfo.ContentType.Equal(oidSignedData) {
+		return nil, fmt.Errorf("not a PKCS#7 signed data structure: %v", err)
+	}
+	var signedData struct {
+		Version          int
+		DigestAlgorithms asn1.RawValue
+		ContentInfo      struct {
+			ContentType asn1.ObjectIdentifier
+			Content     asn1.RawValue
+		}
+		Certificates asn1.RawValue
+		SignerInfos  []pkcs7SignerInfo `asn1:"set"`
+	}
+	if _, err := asn1.Unmarshal(contentInfo.Content.Bytes, &signedData); err != nil {
+		return nil, fmt.Errorf("could not parse signed data: %v", err)
+	}
+	if len(signedData.SignerInfos) != 1 {
+		return nil, fmt.Errorf("got %d signers, want 1", len(signedData.SignerInfos))
+	}
+	signer := signedData.SignerInfos[0]
+	cert, err := x509.ParseCertificate(signedData.Certificates.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("could not parse signer certificate: %v", err)
+	}
+	if !bytes.Equal(signer.IssuerAndSerialNumber.Issuer.FullBytes, cert.RawIssuer) || signer.IssuerAndSerialNumber.SerialNumber.Cmp(cert.SerialNumber) != 0 {
+		return nil, fmt.Errorf("signer info does not name the signer certificate")
+	}
+	var content []byte
+	if _, err := asn1.Unmarshal(signedData.ContentInfo.Content.Bytes, &content); err != nil {
+		return nil, fmt.Errorf("could not parse content: %v", err)
+	}
+
+	signed, err := asn1.Marshal(asn1.RawValue{Class: asn1.ClassUniversal, Tag: asn1.TagSet, IsCompound: true, Bytes: signer.AuthenticatedAttributes.Bytes})
+	if err != nil {
+		return nil, err
+	}
+	algorithm := x509.ECDSAWithSHA256
+	if cert.PublicKeyAlgorithm == x509.RSA {
+		algorithm = x509.SHA256WithRSA
+	}
+	if err := cert.CheckSignature(algorithm, signed, signer.EncryptedDigest); err != nil {
+		return nil, fmt.Errorf("signature does not verify: %v", err)
+	}
+
+	digest := sha256.Sum256(content)
+	result := &signedRequest{signer: cert}
+	digestMatches := false
+	for rest := signer.AuthenticatedAttributes.Bytes; len(rest) > 0; {
+		var attribute struct {
+			Type   asn1.ObjectIdentifier
+			Values asn1.RawValue
+		}
+		if rest, err = asn1.Unmarshal(rest, &attribute); err != nil {
+			return nil, fmt.Errorf("could not parse authenticated attribute: %v", err)
+		}
+		switch {
+		case attribute.Type.Equal(oidMessageDigest):
+			var messageDigest []byte
+			if _, err := asn1.Unmarshal(attribute.Values.Bytes, &messageDigest); err != nil || !bytes.Equal(messageDigest, digest[:]) {
+				return nil, fmt.Errorf("message digest does not match the content")
+			}
+			digestMatches = true
+		case !attribute.Type.Equal(oidContentType):
+			result.attributes = append(result.attributes, pkcs7Attribute{Type: attribute.Type, Value: attribute.Values.Bytes})
+		}
+	}
+	if !digestMatches {
+		return nil, fmt.Errorf("message digest attribute is missing")
+	}
+
+	if result.request, err = x509.ParseCertificateRequest(content); err != nil {
+		return nil, fmt.Errorf("could not parse the certificate signing request: %v", err)
+	}
+	return result, nil
+}
@@ -0,0 +1,378 @@
+package provider
+
+import (
+	"context"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/hashicorp/terraform-plugin-framework/attr"
+	"github.com/hashicorp/terraform-plugin-framework/diag"
+	"github.com/hashicorp/terraform-plugin-framework/path"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+	"github.com/hashicorp/terraform-plugin-framework/types/basetypes"
+)
+
+// Names of the checks of the post_issuance_checks block, as warn_only lists them.
+const (
+	checkExpectedEKUs     = "expected_ekus"
+	checkMaxValidityHours = "max_validity_hours"
+	checkRequiredDNSNames = "required_dns_names"
+	checkIssuerDN         = "issuer_dn"
+)
+
+// postIssuanceCheckNames are the checks warn_only accepts.
+var postIssuanceCheckNames = []string{checkExpectedEKUs, checkIssuerDN, checkMaxValidityHours, checkRequiredDNSNames}
+
+// extendedKeyUsages are the extended key usages expected_ekus accepts by name, with their OIDs.
+var extendedKeyUsages = map[string]struct {
+	oid   string
+	usage x509.ExtKeyUsage
+}{
+	"any":                {oid: "2.5.29.37.0", usage: x509.ExtKeyUsageAny},
+	"server_auth":        {oid: "1.3.6.1.5.5.7.3.1", usage: x509.ExtKeyUsageServerAuth},
+	"client_auth":        {oid: "1.3.6.1.5.5.7.3.2", usage: x509.ExtKeyUsageClientAuth},
+	"code_signing":       {oid: "1.3.6.1.5.5.7.3.3", usage: x509.ExtKeyUsageCodeSigning},
+	"email_protection":   {oid: "1.3.6.1.5.5.7.3.4", usage: x509.ExtKeyUsageEmailProtection},
+	"time_stamping":      {oid: "1.3.6.1.5.5.7.3.8", usage: x509.ExtKeyUsageTimeStamping},
+	"ocsp_signing":       {oid: "1.3.6.1.5.5.7.3.9", usage: x509.ExtKeyUsageOCSPSigning},
+	"smart_card_logon":   {oid: "1.3.6.1.4.1.311.20.2.2", usage: -1},
+	"kdc_authentication": {oid: "1.3.6.1.5.2.3.5", usage: -1},
+}
+
+// distinguishedNameAttributes are the short names of the attribute types of issuer_dn, as Windows
+// shows them. Other types are compared by their OID.
+var distinguishedNameAttributes = map[string]string{
+	"2.5.4.3":                    "CN",
+	"2.5.4.5":                    "SERIALNUMBER",
+	"2.5.4.6":                    "C",
+	"2.5.4.7":                    "L",
+	"2.5.4.8":                    "S",
+	"2.5.4.9":                    "STREET",
+	"2.5.4.10":                   "O",
+	"2.5.4.11":                   "OU",
+	"0.9.2342.19200300.100.1.25": "DC",
+	"1.2.840.113549.1.9.1":       "E",
+}
+
+// distinguishedNameAliases maps other spellings of attribute types to those of distinguishedNameAttributes.
+var distinguishedNameAliases = map[string]string{
+	"ST":           "S",
+	"EMAIL":        "E",
+	"EMAILADDRESS": "E",
+}
+
+// postIssuanceChecksAttrTypes are the attribute types of the post_issuance_checks block.
+var postIssuanceChecksAttrTypes = map[string]attr.Type{
+	checkExpectedEKUs:     types.ListType{ElemType: types.StringType},
+	checkMaxValidityHours: types.Int64Type,
+	checkRequiredDNSNames: types.ListType{ElemType: types.StringType},
+	checkIssuerDN:         types.StringType,
+	"warn_only":           types.ListType{ElemType: types.StringType},
+}
+
+// postIssuanceChecksModel maps the post_issuance_checks block.
+type postIssuanceChecksModel struct {
+	ExpectedEKUs     types.List   `tfsdk:"expected_ekus"`
+	MaxValidityHours types.Int64  `tfsdk:"max_validity_hours"`
+	RequiredDNSNames types.List   `tfsdk:"required_dns_names"`
+	IssuerDN         types.String `tfsdk:"issuer_dn"`
+	WarnOnly         types.List   `tfsdk:"warn_only"`
+}
+
+// postIssuanceChecksBlock is the post_issuance_checks block of the certificate resource.
+func postIssuanceChecksBlock() schema.Block {
+	return schema.SingleNestedBlock{
+		Description: `Organizational certificate policy the issued certificate has to meet, checked after it was issued or
+adopted, on every refresh and when the block changes. A violation is an error unless the check is listed in warn_only. The
+certificate exists on the CA once it was issued, the error names its request ID so it can be revoked.`,
+		Attributes: map[string]schema.Attribute{
+			checkExpectedEKUs: schema.ListAttribute{
+				Optional:    true,
+				ElementType: types.StringType,
+				Description: fmt.Sprintf(`Extended key usages the certificate has to carry, by name (%s) or OID.`,
+					strings.Join(sortedKeys(extendedKeyUsages), ", ")),
+			},
+			checkMaxValidityHours: schema.Int64Attribute{
+				Optional:    true,
+				Description: "Longest lifetime the certificate may have, from not before to not after, in hours.",
+			},
+			checkRequiredDNSNames: schema.ListAttribute{
+				Optional:    true,
+				ElementType: types.StringType,
+				Description: "DNS names the subject alternative names of the certificate have to include.",
+			},
+			checkIssuerDN: schema.StringAttribute{
+				Optional: true,
+				Description: `Distinguished name of the CA that has to issue the certificate, such as "CN=Issuing CA, DC=example,
+DC=com". Attribute types and values are compared case-insensitively, in either order.`,
+			},
+			"warn_only": schema.ListAttribute{
+				Optional:    true,
+				ElementType: types.StringType,
+				Description: "Checks whose violation is a warning instead of an error, by the name of their attribute.",
+			},
+		},
+	}
+}
+
+// checkPostIssuance checks a certificate against the post_issuance_checks block.
+func checkPostIssuance(ctx context.Context, block types.Object, reqID string, certB64 string) diag.Diagnostics {
+	var diags diag.Diagnostics
+	if block.IsNull() || block.IsUnknown() {
+		return diags
+	}
+	var checks postIssuanceChecksModel
+	diags.Append(block.As(ctx, &checks, basetypes.ObjectAsOptions{})...)
+	if diags.HasError() {
+		return diags
+	}
+	cert, err := parseCertificate(certB64)
+	if err != nil {
+		diags.AddError(
+			classifiedSummary("Error parsing issued certificate", &ParseError{Err: err}),
+			fmt.Sprintf("Could not parse the certificate issued for request ID %s: %s", reqID, err.Error()),
+		)
+		return diags
+	}
+
+	var warnOnly, expectedEKUs, requiredDNSNames []string
+	diags.Append(checks.WarnOnly.ElementsAs(ctx, &warnOnly, false)...)
+	diags.Append(checks.ExpectedEKUs.ElementsAs(ctx, &expectedEKUs, false)...)
+	diags.Append(checks.RequiredDNSNames.ElementsAs(ctx, &requiredDNSNames, false)...)
+	if diags.HasError() {
+		return diags
+	}
+	violation := func(check string, detail string) {
+		detail = fmt.Sprintf("The certificate of request ID %s violates post_issuance_checks.%s: %s", reqID, check, detail)
+		if containsString(warnOnly, check) {
+			diags.AddAttributeWarning(path.Root("post_issuance_checks").AtName(check), "Post-Issuance Check Failed", detail)
+			return
+		}
+		diags.AddAttributeError(path.Root("post_issuance_checks").AtName(check), "Post-Issuance Check Failed",
+			detail+" The certificate has been issued by the CA and may need to be revoked.")
+	}
+
+	if missing := missingExtKeyUsages(cert, expectedEKUs); len(missing) > 0 {
+		violation(checkExpectedEKUs, fmt.Sprintf("it does not carry the extended key usages %s.", strings.Join(missing, ", ")))
+	}
+	if !checks.MaxValidityHours.IsNull() {
+		maxHours := checks.MaxValidityHours.ValueInt64()
+		if validity := cert.NotAfter.Sub(cert.NotBefore); validity > time.Duration(maxHours)*time.Hour {
+			violation(checkMaxValidityHours, fmt.Sprintf("its lifetime of %.0f hours is more than the %d hours allowed.", validity.Hours(), maxHours))
+		}
+	}
+	var missingNames []string
+	for _, name := range requiredDNSNames {
+		if !containsFold(cert.DNSNames, strings.TrimSuffix(name, ".")) {
+			missingNames = append(missingNames, name)
+		}
+	}
+	if len(missingNames) > 0 {
+		violation(checkRequiredDNSNames, fmt.Sprintf("its subject alternative names do not include %s.", strings.Join(missingNames, ", ")))
+	}
+	if !checks.IssuerDN.IsNull() {
+		if want, _ := parseDistinguishedName(checks.IssuerDN.ValueString()); !sameDistinguishedName(distinguishedName(cert.Issuer), want) {
+			violation(checkIssuerDN, fmt.Sprintf("it was issued by %q.", formatDistinguishedName(distinguishedName(cert.Issuer))))
+		}
+	}
+	return diags
+}
+
+// validatePostIssuanceChecks checks the values of the post_issuance_checks block.
+func validatePostIssuanceChecks(ctx context.Context, block types.Object) diag.Diagnostics {
+	var diags diag.Diagnostics
+	if block.IsNull() || block.IsUnknown() {
+		return diags
+	}
+	var checks postIssuanceChecksModel
+	diags.Append(block.As(ctx, &checks, basetypes.ObjectAsOptions{})...)
+	if diags.HasError() {
+		return diags
+	}
+	blockPath := path.Root("post_issuance_checks")
+
+	if !checks.ExpectedEKUs.IsUnknown() {
+		for i, element := range checks.ExpectedEKUs.Elements() {
+			if eku, ok := element.(types.String); ok && !eku.IsUnknown() && extKeyUsageOID(eku.ValueString()) == "" {
+				diags.AddAttributeError(blockPath.AtName(checkExpectedEKUs).AtListIndex(i), "Invalid Extended Key Usage",
+					fmt.Sprintf("%q is neither an OID nor one of %s.", eku.ValueString(), strings.Join(sortedKeys(extendedKeyUsages), ", ")))
+			}
+		}
+	}
+	if !checks.MaxValidityHours.IsNull() && !checks.MaxValidityHours.IsUnknown() && checks.MaxValidityHours.ValueInt64() < 1 {
+		diags.AddAttributeError(blockPath.AtName(checkMaxValidityHours), "Invalid Maximum Validity",
+			fmt.Sprintf("max_validity_hours must be at least 1, got %d.", checks.MaxValidityHours.ValueInt64()))
+	}
+	if !checks.IssuerDN.IsNull() && !checks.IssuerDN.IsUnknown() {
+		if _, err := parseDistinguishedName(checks.IssuerDN.ValueString()); err != nil {
+			diags.AddAttributeError(blockPath.AtName(checkIssuerDN), "Invalid Issuer DN", err.Error()+".")
+		}
+	}
+	if !checks.WarnOnly.IsUnknown() {
+		for i, element := range checks.WarnOnly.Elements() {
+			if check, ok := element.(types.String); ok && !check.IsUnknown() && !containsString(postIssuanceCheckNames, check.ValueString()) {
+				diags.AddAttributeError(blockPath.AtName("warn_only").AtListIndex(i), "Invalid Check Name",
+					fmt.Sprintf("warn_only entries must be one of %s, got %q.", strings.Join(postIssuanceCheckNames, ", "), check.ValueString()))
+			}
+		}
+	}
+	return diags
+}
+
+// extKeyUsageOID returns the OID of an extended key usage given by name or OID, empty when it is neither.
+func extKeyUsageOID(eku string) string {
+	if known, ok := extendedKeyUsages[strings.ToLower(eku)]; ok {
+		return known.oid
+	}
+	for _, arc := range strings.Split(eku, ".") {
+		if arc == "" || strings.Trim(arc, "0123456789") != "" {
+			return ""
+		}
+	}
+	if !strings.Contains(eku, ".") {
+		return ""
+	}
+	return eku
+}
+
+// missingExtKeyUsages returns the expected extended key usages the certificate does not carry.
+func missingExtKeyUsages(cert *x509.Certificate, expected []string) []string {
+	carried := map[string]bool{}
+	for _, oid := range cert.UnknownExtKeyUsage {
+		carried[oid.String()] = true
+	}
+	for _, usage := range cert.ExtKeyUsage {
+		for _, known := range extendedKeyUsages {
+			if known.usage == usage {
+				carried[known.oid] = true
+			}
+		}
+	}
+	var missing []string
+	for _, eku := range expected {
+		if !carried[extKeyUsageOID(eku)] {
+			missing = append(missing, eku)
+		}
+	}
+	return missing
+}
+
+// distinguishedName returns the attributes of a name as TYPE=value pairs in the order they are encoded.
+func distinguishedName(name pkix.Name) []string {
+	var rdns []string
+	for _, atv := range name.Names {
+		rdns = append(rdns, distinguishedNameType(atv.Type)+"="+fmt.Sprint(atv.Value))
+	}
+	return rdns
+}
+
+// distinguishedNameType returns the short name of an attribute type, or its OID.
+func distinguishedNameType(oid asn1.ObjectIdentifier) string {
+	if name, ok := distinguishedNameAttributes[oid.String()]; ok {
+		return name
+	}
+	return oid.String()
+}
+
+// parseDistinguishedName splits a distinguished name such as "CN=Issuing CA, DC=example, DC=com"
+// into its TYPE=value pairs. Commas escaped with a backslash are part of the value.
+func parseDistinguishedName(dn string) ([]string, error) {
+	var rdns []string
+	var current strings.Builder
+	escaped := false
+	flush := func() error {
+		rdn := strings.TrimSpace(current.String())
+		current.Reset()
+		attrType, value, ok := strings.Cut(rdn, "=")
+		if !ok || strings.TrimSpace(attrType) == "" || strings.TrimSpace(value) == "" {
+			return fmt.Errorf("%q is not a distinguished name such as \"CN=Issuing CA, DC=example, DC=com\"", dn)
+		}
+		attrType = strings.ToUpper(strings.TrimSpace(attrType))
+		if alias, ok := distinguishedNameAliases[attrType]; ok {
+			attrType = alias
+		}
+		if strings.HasPrefix(attrType, "OID.") {
+			attrType = strings.TrimPrefix(attrType, "OID.")
+		}
+		rdns = append(rdns, attrType+"="+strings.TrimSpace(value))
+		return nil
+	}
+	for _, r := range dn {
+		switch {
+		case escaped:
+			current.WriteRune(r)
+			escaped = false
+		case r == '\\':
+			escaped = true
+		case r == ',' || r == ';':
+			if err := flush(); err != nil {
+				return nil, err
+			}
+		default:
+			current.WriteRune(r)
+		}
+	}
+	if err := flush(); err != nil {
+		return nil, err
+	}
+	return rdns, nil
+}
+
+// sameDistinguishedName reports whether two distinguished names hold the same attributes, ignoring
+// case and whether they are written root first, as encoded, or leaf first, as Windows shows them.
+func sameDistinguishedName(got []string, want []string) bool {
+	if len(got) == 0 || len(got) != len(want) {
+		return false
+	}
+	forward, reverse := true, true
+	for i := range got {
+		forward = forward && strings.EqualFold(got[i], want[i])
+		reverse = reverse && strings.EqualFold(got[i], want[len(want)-1-i])
+	}
+	return forward || reverse
+}
+
+// formatDistinguishedName writes a distinguished name leaf first, the way Windows shows it.
+func formatDistinguishedName(rdns []string) string {
+	reversed := make([]string, 0, len(rdns))
+	for i := len(rdns) - 1; i >= 0; i-- {
+		reversed = append(reversed, rdns[i])
+	}
+	return strings.Join(reversed, ", ")
+}
+
+// containsString reports whether values holds value.
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+// containsFold reports whether values holds value, ignoring case.
+func containsFold(values []string, value string) bool {
+	for _, v := range values {
+		if strings.EqualFold(v, value) {
+			return true
+		}
+	}
+	return false
+}
+
+// sortedKeys returns the keys of m in order.
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,215 @@
+package provider
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"encoding/pem"
+	"math/big"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/terraform-plugin-framework/attr"
+	"github.com/hashicorp/terraform-plugin-framework/diag"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+	"github.com/hashicorp/terraform-plugin-testing/helper/resource"
+)
+
+// testPostIssuanceChecks returns a post_issuance_checks block, unset checks are null.
+func testPostIssuanceChecks(t *testing.T, checks map[string]attr.Value) types.Object {
+	t.Helper()
+	values := map[string]attr.Value{
+		checkExpectedEKUs:     types.ListNull(types.StringType),
+		checkMaxValidityHours: types.Int64Null(),
+		checkRequiredDNSNames: types.ListNull(types.StringType),
+		checkIssuerDN:         types.StringNull(),
+		"warn_only":           types.ListNull(types.StringType),
+	}
+	for name, value := range checks {
+		values[name] = value
+	}
+	block, diags := types.ObjectValue(postIssuanceChecksAttrTypes, values)
+	if diags.HasError() {
+		t.Fatal(diags)
+	}
+	return block
+}
+
+// testDomainCA returns a self-signed CA certificate named like an enterprise CA, with domain components.
+func testDomainCA(t *testing.T) (*x509.Certificate, *ecdsa.PrivateKey) {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	dc := asn1.ObjectIdentifier{0, 9, 2342, 19200300, 100, 1, 25}
+	name := pkix.Name{ExtraNames: []pkix.AttributeTypeAndValue{
+		{Type: dc, Value: "com"},
+		{Type: dc, Value: "example"},
+		{Type: asn1.ObjectIdentifier{2, 5, 4, 3}, Value: "Example Issuing CA"},
+	}}
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               name,
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(24 * time.Hour),
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+		KeyUsage:              x509.KeyUsageCertSign,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return cert, key
+}
+
+func TestCheckPostIssuance(t *testing.T) {
+	ca, caKey := testDomainCA(t)
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	der, err := x509.CreateCertificate(rand.Reader, &x509.Certificate{
+		SerialNumber:       big.NewInt(2),
+		Subject:            pkix.Name{CommonName: "web.example.com"},
+		NotBefore:          time.Now().Add(-time.Hour),
+		NotAfter:           time.Now().Add(99 * time.Hour),
+		DNSNames:           []string{"web.example.com", "www.example.com"},
+		ExtKeyUsage:        []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+		UnknownExtKeyUsage: []asn1.ObjectIdentifier{{1, 3, 6, 1, 4, 1, 311, 20, 2, 2}},
+	}, ca, &key.PublicKey, caKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	certB64 := string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}))
+
+	for name, tc := range map[string]struct {
+		checks   map[string]attr.Value
+		errors   []string
+		warnings []string
+	}{
+		"passing": {
+			checks: map[string]attr.Value{
+				checkExpectedEKUs:     testStringList("server_auth", "Smart_Card_Logon", "1.3.6.1.5.5.7.3.1"),
+				checkMaxValidityHours: types.Int64Value(100),
+				checkRequiredDNSNames: testStringList("WWW.example.com."),
+				checkIssuerDN:         types.StringValue("cn=Example Issuing CA, DC=example, DC=com"),
+			},
+		},
+		"issuer dn root first": {
+			checks: map[string]attr.Value{checkIssuerDN: types.StringValue("DC=com,DC=example,CN=Example Issuing CA")},
+		},
+		"violations": {
+			checks: map[string]attr.Value{
+				checkExpectedEKUs:     testStringList("server_auth", "client_auth"),
+				checkMaxValidityHours: types.Int64Value(24),
+				checkRequiredDNSNames: testStringList("api.example.com"),
+				checkIssuerDN:         types.StringValue("CN=Other CA, DC=example, DC=com"),
+			},
+			errors: []string{checkExpectedEKUs, checkMaxValidityHours, checkRequiredDNSNames, checkIssuerDN},
+		},
+		"warn only": {
+			checks: map[string]attr.Value{
+				checkExpectedEKUs:     testStringList("client_auth"),
+				checkMaxValidityHours: types.Int64Value(24),
+				"warn_only":           testStringList(checkMaxValidityHours),
+			},
+			errors:   []string{checkExpectedEKUs},
+			warnings: []string{checkMaxValidityHours},
+		},
+	} {
+		diags := checkPostIssuance(context.Background(), testPostIssuanceChecks(t, tc.checks), "42", certB64)
+		var errors, warnings []string
+		for _, d := range diags {
+			if !strings.Contains(d.Detail(), "request ID 42") {
+				t.Errorf("%s: %q does not name the request ID", name, d.Detail())
+			}
+			check := strings.TrimPrefix(d.(diag.DiagnosticWithPath).Path().String(), "post_issuance_checks.")
+			if d.Severity() == diag.SeverityError {
+				errors = append(errors, check)
+			} else {
+				warnings = append(warnings, check)
+			}
+		}
+		if strings.Join(errors, ",") != strings.Join(tc.errors, ",") || strings.Join(warnings, ",") != strings.Join(tc.warnings, ",") {
+			t.Errorf("%s: checkPostIssuance() failed %v and warned about %v, want %v and %v", name, errors, warnings, tc.errors, tc.warnings)
+		}
+	}
+
+	if diags := checkPostIssuance(context.Background(), types.ObjectNull(postIssuanceChecksAttrTypes), "42", "not a certificate"); diags.HasError() {
+		t.Errorf("checkPostIssuance() without the block = %v", diags)
+	}
+}
+
+func TestValidatePostIssuanceChecks(t *testing.T) {
+	for name, tc := range map[string]struct {
+		checks map[string]attr.Value
+		want   string
+	}{
+		"valid": {checks: map[string]attr.Value{
+			checkExpectedEKUs: testStringList("server_auth", "1.3.6.1.4.1.311.10.3.4"),
+			checkIssuerDN:     types.StringValue(`CN=Issuing CA\, Europe, O=Example`),
+			"warn_only":       testStringList(checkIssuerDN),
+		}},
+		"unknown eku":        {checks: map[string]attr.Value{checkExpectedEKUs: testStringList("web_server")}, want: "Invalid Extended Key Usage"},
+		"zero validity":      {checks: map[string]attr.Value{checkMaxValidityHours: types.Int64Value(0)}, want: "Invalid Maximum Validity"},
+		"malformed issuer":   {checks: map[string]attr.Value{checkIssuerDN: types.StringValue("Issuing CA")}, want: "Invalid Issuer DN"},
+		"unknown check name": {checks: map[string]attr.Value{"warn_only": testStringList("issuer")}, want: "Invalid Check Name"},
+	} {
+		diags := validatePostIssuanceChecks(context.Background(), testPostIssuanceChecks(t, tc.checks))
+		switch {
+		case tc.want == "" && diags.HasError():
+			t.Errorf("%s: validatePostIssuanceChecks() = %v", name, diags)
+		case tc.want != "" && (!diags.HasError() || diags.Errors()[0].Summary() != tc.want):
+			t.Errorf("%s: validatePostIssuanceChecks() = %v, want %q", name, diags, tc.want)
+		}
+	}
+}
+
+func TestAccCertificateResourcePostIssuanceChecks(t *testing.T) {
+	srv := newAccCertsrv(t)
+	config := func(eku string) string {
+		return srv.providerConfig() + `
+resource "microsoftadcs_certificate" "test" {
+  template = "WebServer"
+
+  generate_csr {
+    common_name = "web.example.com"
+    dns_names   = ["web.example.com"]
+  }
+
+  post_issuance_checks {
+    expected_ekus      = ["` + eku + `"]
+    required_dns_names = ["web.example.com"]
+    issuer_dn          = "CN=Test Issuing CA"
+  }
+}
+`
+	}
+
+	resource.Test(t, resource.TestCase{
+		ProtoV6ProviderFactories: testAccProtoV6ProviderFactories,
+		Steps: []resource.TestStep{
+			{
+				Config: config("server_auth"),
+				Check:  resource.TestCheckResourceAttrSet("microsoftadcs_certificate.test", "certificate_pem"),
+			},
+			// Tightening the checks checks the certificate in state again.
+			{
+				Config:      config("client_auth"),
+				ExpectError: regexp.MustCompile(`Post-Issuance Check Failed`),
+			},
+		},
+	})
+}
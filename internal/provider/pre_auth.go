@@ -0,0 +1,178 @@
+package provider
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/flipyap/microsoft-adcs-client/client"
+)
+
+// preAuthSource supplies the headers and cookies that let requests through a reverse proxy in front
+// of certsrv, such as one enforcing SSO or MFA that would answer a CI runner with its login page.
+// Proxies usually grant service accounts an exception token for non-interactive use.
+type preAuthSource interface {
+	// preAuthentication returns the headers and cookies to send with every certsrv request.
+	preAuthentication(ctx context.Context) (*preAuthentication, error)
+}
+
+// preAuthSourceFactory builds a pre-authentication source from pre_auth_source_options.
+type preAuthSourceFactory func(options map[string]string) (preAuthSource, error)
+
+// preAuthSources holds the sources pre_auth_source can select. Sources compiled into custom builds
+// add themselves with registerPreAuthSource from an init function.
+var preAuthSources = map[string]preAuthSourceFactory{
+	"exec": newExecPreAuthSource,
+	"file": newFilePreAuthSource,
+}
+
+// registerPreAuthSource makes a pre-authentication source selectable by name.
+func registerPreAuthSource(name string, factory preAuthSourceFactory) {
+	preAuthSources[name] = factory
+}
+
+// newPreAuthSource looks up the named source and configures it.
+func newPreAuthSource(name string, options map[string]string) (preAuthSource, error) {
+	factory, ok := preAuthSources[name]
+	if !ok {
+		names := make([]string, 0, len(preAuthSources))
+		for name := range preAuthSources {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		return nil, fmt.Errorf("unknown pre-authentication source %q, expected one of: %s", name, strings.Join(names, ", "))
+	}
+	return factory(options)
+}
+
+// preAuthentication holds the headers and cookies every certsrv request carries.
+type preAuthentication struct {
+	headers http.Header
+	cookies []*http.Cookie
+}
+
+// preAuthDocument is the JSON the exec and file sources read.
+type preAuthDocument struct {
+	Headers map[string]string `json:"headers"`
+	Cookies map[string]string `json:"cookies"`
+}
+
+// parsePreAuthDocument decodes the headers and cookies and requires at least one of them. The
+// Authorization header is refused, certsrv itself authenticates requests with it.
+func parsePreAuthDocument(b []byte) (*preAuthentication, error) {
+	var doc preAuthDocument
+	if err := json.Unmarshal(b, &doc); err != nil {
+		return nil, fmt.Errorf("could not decode the pre-authentication headers and cookies: %v", err)
+	}
+	if len(doc.Headers) == 0 && len(doc.Cookies) == 0 {
+		return nil, fmt.Errorf("the pre-authentication document contains neither headers nor cookies")
+	}
+
+	auth := &preAuthentication{headers: http.Header{}}
+	for name, value := range doc.Headers {
+		if strings.EqualFold(name, "Authorization") {
+			return nil, fmt.Errorf("the Authorization header carries the Kerberos or NTLM authentication of certsrv and cannot be set, pass the token in another header or a cookie")
+		}
+		auth.headers.Set(name, value)
+	}
+	names := make([]string, 0, len(doc.Cookies))
+	for name := range doc.Cookies {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		cookie := &http.Cookie{Name: name, Value: doc.Cookies[name]}
+		if err := cookie.Valid(); err != nil {
+			return nil, fmt.Errorf("invalid cookie %q: %v", name, err)
+		}
+		auth.cookies = append(auth.cookies, cookie)
+	}
+	return auth, nil
+}
+
+// wrap makes the client send the headers and cookies with every request, redirects and the
+// requests of the authentication handshakes included. The NTLM handshake starts with a request of
+// its own, so the connection pool adds them to each of its exchanges.
+func (a *preAuthentication) wrap(c *client.ADCSClient) {
+	if a == nil {
+		return
+	}
+	if c.UseNtlm {
+		if pool, ok := c.NtlmClient.Transport.(*ntlmConnectionPool); ok {
+			pool.preAuth = a
+			return
+		}
+		c.NtlmClient.Transport = preAuthTransport{next: c.NtlmClient.Transport, auth: a}
+		return
+	}
+	if c.SpnegoClient != nil && c.SpnegoClient.Client != nil {
+		c.SpnegoClient.Client.Transport = preAuthTransport{next: c.SpnegoClient.Client.Transport, auth: a}
+	}
+}
+
+// preAuthTransport adds the headers and cookies of a pre-authentication source to requests.
+type preAuthTransport struct {
+	next http.RoundTripper
+	auth *preAuthentication
+}
+
+func (t preAuthTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	req = req.Clone(req.Context())
+	for name, values := range t.auth.headers {
+		req.Header[name] = values
+	}
+	for _, cookie := range t.auth.cookies {
+		req.AddCookie(cookie)
+	}
+	next := t.next
+	if next == nil {
+		next = http.DefaultTransport
+	}
+	return next.RoundTrip(req)
+}
+
+// execPreAuthSource runs a helper that prints the headers and cookies as JSON on stdout, for example
+// a client fetching the exception token of the proxy from a vault.
+type execPreAuthSource struct {
+	execCommand
+}
+
+func newExecPreAuthSource(options map[string]string) (preAuthSource, error) {
+	command, err := newExecCommand("pre-authentication", options)
+	if err != nil {
+		return nil, err
+	}
+	return &execPreAuthSource{execCommand: command}, nil
+}
+
+func (s *execPreAuthSource) preAuthentication(ctx context.Context) (*preAuthentication, error) {
+	stdout, err := s.run(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return parsePreAuthDocument(stdout)
+}
+
+// filePreAuthSource reads headers and cookies a sidecar keeps up to date on disk.
+type filePreAuthSource struct {
+	path string
+}
+
+func newFilePreAuthSource(options map[string]string) (preAuthSource, error) {
+	if options["path"] == "" {
+		return nil, fmt.Errorf("the file pre-authentication source requires the path option")
+	}
+	return &filePreAuthSource{path: options["path"]}, nil
+}
+
+func (s *filePreAuthSource) preAuthentication(_ context.Context) (*preAuthentication, error) {
+	b, err := os.ReadFile(s.path)
+	if err != nil {
+		return nil, fmt.Errorf("could not read the pre-authentication headers and cookies: %v", err)
+	}
+	return parsePreAuthDocument(b)
+}
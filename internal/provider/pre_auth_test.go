@@ -0,0 +1,107 @@
+package provider
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/http/httputil"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestParsePreAuthDocument(t *testing.T) {
+	for name, tc := range map[string]struct {
+		document string
+		want     string
+	}{
+		"header":        {document: `{"headers": {"X-Proxy-Token": "abc"}}`},
+		"cookie":        {document: `{"cookies": {"duo_session": "xyz"}}`},
+		"both":          {document: `{"headers": {"X-Proxy-Token": "abc"}, "cookies": {"duo_session": "xyz"}}`},
+		"empty":         {document: `{}`, want: "neither headers nor cookies"},
+		"authorization": {document: `{"headers": {"authorization": "Bearer abc"}}`, want: "Authorization header"},
+		"invalid":       {document: `{"cookies": {"duo session": "xyz"}}`, want: "invalid cookie"},
+		"not json":      {document: `X-Proxy-Token: abc`, want: "could not decode"},
+	} {
+		_, err := parsePreAuthDocument([]byte(tc.document))
+		switch {
+		case tc.want == "" && err != nil:
+			t.Errorf("%s: parsePreAuthDocument() = %v", name, err)
+		case tc.want != "" && (err == nil || !strings.Contains(err.Error(), tc.want)):
+			t.Errorf("%s: parsePreAuthDocument() = %v, want %q", name, err, tc.want)
+		}
+	}
+}
+
+func TestFileAndExecPreAuthSources(t *testing.T) {
+	document := filepath.Join(t.TempDir(), "pre-auth.json")
+	if err := os.WriteFile(document, []byte(`{"headers": {"X-Proxy-Token": "abc"}, "cookies": {"duo_session": "xyz"}}`), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	for name, options := range map[string]map[string]string{
+		"file": {"path": document},
+		"exec": {"command": "cat", "args": document},
+	} {
+		source, err := newPreAuthSource(name, options)
+		if err != nil {
+			t.Fatalf("%s: unexpected error: %v", name, err)
+		}
+		auth, err := source.preAuthentication(context.Background())
+		if err != nil {
+			t.Fatalf("%s: unexpected error: %v", name, err)
+		}
+		if auth.headers.Get("X-Proxy-Token") != "abc" || len(auth.cookies) != 1 || auth.cookies[0].String() != "duo_session=xyz" {
+			t.Errorf("%s: preAuthentication() = %v, %v", name, auth.headers, auth.cookies)
+		}
+	}
+
+	if _, err := newPreAuthSource("exec", nil); err == nil || !strings.Contains(err.Error(), "pre-authentication source requires the command option") {
+		t.Errorf("expected an error without a command, got %v", err)
+	}
+	if _, err := newPreAuthSource("duo", nil); err == nil || !strings.Contains(err.Error(), "exec, file") {
+		t.Errorf("expected an error listing the known sources, got %v", err)
+	}
+}
+
+func TestPreAuthTransport(t *testing.T) {
+	srv := newAccCertsrv(t)
+	target, _ := url.Parse(srv.server.URL)
+	backend := httputil.NewSingleHostReverseProxy(target)
+	// The proxy sends everyone without the exception token to its login page.
+	proxy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		cookie, err := r.Cookie("duo_session")
+		if r.Header.Get("X-Proxy-Token") != "abc" || err != nil || cookie.Value != "xyz" {
+			http.Redirect(w, r, "/login", http.StatusFound)
+			return
+		}
+		backend.ServeHTTP(w, r)
+	}))
+	t.Cleanup(proxy.Close)
+
+	t.Setenv("ADCS_PASSWORD", "secret")
+	ctx := context.Background()
+	data, err := debugEnrollConfigure(ctx, "test", map[string]interface{}{
+		"host":     strings.TrimPrefix(proxy.URL, "http://"),
+		"username": "svc-terraform",
+		"use_ntlm": true,
+	}, io.Discard)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := retrieveCertificate(withRetryPolicy(ctx, noRetry), data.client, data.parser, "101"); err == nil {
+		t.Fatal("the proxy should turn requests without the token away")
+	}
+
+	auth, err := parsePreAuthDocument([]byte(`{"headers": {"X-Proxy-Token": "abc"}, "cookies": {"duo_session": "xyz"}}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	auth.wrap(data.client)
+	if page, err := downloadCertsrv(withRetryPolicy(ctx, noRetry), data.client, data.parser, "certrqxt.asp", url.Values{}, ""); err != nil || !strings.Contains(page, "WebServer") {
+		t.Errorf("downloadCertsrv() through the proxy = %v", err)
+	}
+}
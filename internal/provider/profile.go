@@ -0,0 +1,221 @@
+package provider
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/hashicorp/terraform-plugin-framework/diag"
+	"github.com/hashicorp/terraform-plugin-framework/path"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+	"github.com/hashicorp/terraform-plugin-framework/types/basetypes"
+)
+
+// certificateProfile is a preset of the settings a common kind of certificate needs. Each setting
+// only applies when the configuration leaves it unset.
+type certificateProfile struct {
+	// keyAlgorithm, rsaBits and ecdsaCurve are the key generate_csr creates.
+	keyAlgorithm string
+	rsaBits      int64
+	ecdsaCurve   string
+	// expectedEKUs are the extended key usages of post_issuance_checks.
+	expectedEKUs []string
+	// requireSAN requires the request to carry a DNS name or IP address.
+	requireSAN bool
+	// earlyRenewalHours is the early_renewal_hours of the profile.
+	earlyRenewalHours int64
+}
+
+// certificateProfiles are the values of profile.
+var certificateProfiles = map[string]certificateProfile{
+	"tls_server": {
+		keyAlgorithm:      keyAlgorithmECDSA,
+		ecdsaCurve:        "P256",
+		expectedEKUs:      []string{"server_auth"},
+		requireSAN:        true,
+		earlyRenewalHours: 30 * 24,
+	},
+	"mtls_client": {
+		keyAlgorithm:      keyAlgorithmECDSA,
+		ecdsaCurve:        "P256",
+		expectedEKUs:      []string{"client_auth"},
+		earlyRenewalHours: 14 * 24,
+	},
+	"code_signing": {
+		keyAlgorithm:      keyAlgorithmRSA,
+		rsaBits:           3072,
+		expectedEKUs:      []string{"code_signing"},
+		earlyRenewalHours: 60 * 24,
+	},
+	// domain_controller has the extended key usages of the Kerberos Authentication template. Windows
+	// Hello for Business key trust and smart card logon need the KDC Authentication one.
+	domainControllerProfile: {
+		keyAlgorithm:      keyAlgorithmRSA,
+		rsaBits:           2048,
+		expectedEKUs:      []string{"client_auth", "server_auth", "smart_card_logon", "kdc_authentication"},
+		requireSAN:        true,
+		earlyRenewalHours: 30 * 24,
+	},
+}
+
+// profileNames lists the values of profile.
+func profileNames() []string {
+	names := make([]string, 0, len(certificateProfiles))
+	for name := range certificateProfiles {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// profileName returns profile, or domain_controller when it is not set and the domain_controller
+// block is.
+func (m *certificateCreateModel) profileName() types.String {
+	if m.Profile.IsNull() && !m.DomainController.IsNull() {
+		return types.StringValue(domainControllerProfile)
+	}
+	return m.Profile
+}
+
+// profile returns the profile the certificate uses, false when none is set.
+func (m *certificateCreateModel) profile() (certificateProfile, bool) {
+	name := m.profileName()
+	if name.IsNull() || name.IsUnknown() {
+		return certificateProfile{}, false
+	}
+	profile, ok := certificateProfiles[name.ValueString()]
+	return profile, ok
+}
+
+// applyProfile sets the key of the generate_csr block to the one of the profile, unless the block
+// sets any of key_algorithm, rsa_bits or ecdsa_curve itself.
+func (m *generateCSRModel) applyProfile(profile certificateProfile) {
+	if !m.KeyAlgorithm.IsNull() || !m.RSABits.IsNull() || !m.ECDSACurve.IsNull() {
+		return
+	}
+	m.KeyAlgorithm = types.StringValue(profile.keyAlgorithm)
+	if profile.rsaBits != 0 {
+		m.RSABits = types.Int64Value(profile.rsaBits)
+	}
+	if profile.ecdsaCurve != "" {
+		m.ECDSACurve = types.StringValue(profile.ecdsaCurve)
+	}
+}
+
+// earlyRenewalHours returns early_renewal_hours, or that of the profile when it is not set.
+func (m *certificateCreateModel) earlyRenewalHours() types.Int64 {
+	if profile, ok := m.profile(); ok && m.EarlyRenewalHours.IsNull() {
+		return types.Int64Value(profile.earlyRenewalHours)
+	}
+	return m.EarlyRenewalHours
+}
+
+// postIssuanceChecks returns the post_issuance_checks block, with the extended key usages of the
+// profile as expected_ekus when the block does not list any.
+func (m *certificateCreateModel) postIssuanceChecks(ctx context.Context) (types.Object, diag.Diagnostics) {
+	var diags diag.Diagnostics
+	profile, ok := m.profile()
+	if !ok || m.PostIssuanceChecks.IsUnknown() {
+		return m.PostIssuanceChecks, diags
+	}
+
+	checks := postIssuanceChecksModel{
+		ExpectedEKUs:     types.ListNull(types.StringType),
+		MaxValidityHours: types.Int64Null(),
+		RequiredDNSNames: types.ListNull(types.StringType),
+		IssuerDN:         types.StringNull(),
+		WarnOnly:         types.ListNull(types.StringType),
+	}
+	if !m.PostIssuanceChecks.IsNull() {
+		diags.Append(m.PostIssuanceChecks.As(ctx, &checks, basetypes.ObjectAsOptions{})...)
+		if diags.HasError() || !checks.ExpectedEKUs.IsNull() {
+			return m.PostIssuanceChecks, diags
+		}
+	}
+	expected, listDiags := types.ListValueFrom(ctx, types.StringType, profile.expectedEKUs)
+	diags.Append(listDiags...)
+	checks.ExpectedEKUs = expected
+	block, objectDiags := types.ObjectValueFrom(ctx, postIssuanceChecksAttrTypes, checks)
+	diags.Append(objectDiags...)
+	return block, diags
+}
+
+// checkPostIssuance checks a certificate against post_issuance_checks and the extended key usages
+// of the profile.
+func (m *certificateCreateModel) checkPostIssuance(ctx context.Context, reqID string, certB64 string) diag.Diagnostics {
+	checks, diags := m.postIssuanceChecks(ctx)
+	if diags.HasError() {
+		return diags
+	}
+	diags.Append(checkPostIssuance(ctx, checks, reqID, certB64)...)
+	return diags
+}
+
+// validateProfile checks profile at plan time, and that a request for a profile that requires
+// subject alternative names carries a DNS name or IP address.
+func validateProfile(ctx context.Context, config certificateCreateModel) diag.Diagnostics {
+	var diags diag.Diagnostics
+	name := config.profileName()
+	if name.IsNull() || name.IsUnknown() {
+		return diags
+	}
+	profile, ok := config.profile()
+	if !ok {
+		diags.AddAttributeError(
+			path.Root("profile"),
+			"Invalid Profile",
+			fmt.Sprintf("profile %q is not one of: %s.", config.Profile.ValueString(), strings.Join(profileNames(), ", ")),
+		)
+		return diags
+	}
+	if !profile.requireSAN {
+		return diags
+	}
+
+	hasNames := func(lists ...types.List) (bool, bool) {
+		for _, list := range lists {
+			if list.IsUnknown() {
+				return false, false
+			}
+			if len(list.Elements()) > 0 {
+				return true, true
+			}
+		}
+		return false, true
+	}
+	if block := config.subjectAlternativeNames(); !block.IsNull() {
+		if block.IsUnknown() {
+			return diags
+		}
+		var names subjectAlternativeNamesModel
+		diags.Append(block.As(ctx, &names, basetypes.ObjectAsOptions{})...)
+		if found, known := hasNames(names.DNS, names.IP); diags.HasError() || found || !known {
+			return diags
+		}
+	}
+	switch {
+	case config.GenerateCSR.IsUnknown():
+		return diags
+	case !config.GenerateCSR.IsNull():
+		var block generateCSRModel
+		diags.Append(config.GenerateCSR.As(ctx, &block, basetypes.ObjectAsOptions{})...)
+		if found, known := hasNames(block.DNSNames, block.IPAddresses); diags.HasError() || found || !known {
+			return diags
+		}
+	case config.CSR.IsNull() || config.CSR.IsUnknown():
+		return diags
+	default:
+		request, err := parseCertificateRequest(config.CSR.ValueString())
+		if err != nil || len(request.DNSNames) > 0 || len(request.IPAddresses) > 0 {
+			return diags
+		}
+	}
+	diags.AddAttributeError(
+		path.Root("profile"),
+		"Missing Subject Alternative Name",
+		fmt.Sprintf("profile %q requires a DNS name or IP address in the subject alternative names, which clients check instead of the "+
+			"common name. Add dns_names to generate_csr or the resource, a subject_alternative_names block, or names to the certificate signing request.", name.ValueString()),
+	)
+	return diags
+}
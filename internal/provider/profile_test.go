@@ -0,0 +1,128 @@
+package provider
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-framework/attr"
+	"github.com/hashicorp/terraform-plugin-framework/path"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+)
+
+func TestCertificateProfiles(t *testing.T) {
+	for name, profile := range certificateProfiles {
+		block := generateCSRModel{}
+		block.applyProfile(profile)
+		if diags := block.validateKey(path.Root("generate_csr").AtName); diags.HasError() {
+			t.Errorf("%s: the key of the profile is invalid: %v", name, diags)
+		}
+		for _, eku := range profile.expectedEKUs {
+			if extKeyUsageOID(eku) == "" {
+				t.Errorf("%s: unknown extended key usage %q", name, eku)
+			}
+		}
+	}
+}
+
+func TestApplyProfile(t *testing.T) {
+	profile := certificateProfiles["tls_server"]
+
+	block := generateCSRModel{}
+	block.applyProfile(profile)
+	if block.keyAlgorithm() != keyAlgorithmECDSA || block.ecdsaCurve() != "P256" {
+		t.Errorf("applyProfile() = %s %s, want ECDSA P256", block.keyAlgorithm(), block.ecdsaCurve())
+	}
+
+	// Any key setting of the block overrides the profile.
+	block = generateCSRModel{RSABits: types.Int64Value(4096)}
+	block.applyProfile(profile)
+	if block.keyAlgorithm() != keyAlgorithmRSA || block.rsaBits() != 4096 {
+		t.Errorf("applyProfile() = %s %d, want RSA 4096", block.keyAlgorithm(), block.rsaBits())
+	}
+}
+
+func TestEarlyRenewalHours(t *testing.T) {
+	for name, tc := range map[string]struct {
+		model certificateCreateModel
+		want  types.Int64
+	}{
+		"unset":    {want: types.Int64Null()},
+		"profile":  {model: certificateCreateModel{Profile: types.StringValue("mtls_client")}, want: types.Int64Value(336)},
+		"override": {model: certificateCreateModel{Profile: types.StringValue("mtls_client"), EarlyRenewalHours: types.Int64Value(48)}, want: types.Int64Value(48)},
+	} {
+		if got := tc.model.earlyRenewalHours(); !got.Equal(tc.want) {
+			t.Errorf("%s: earlyRenewalHours() = %s, want %s", name, got, tc.want)
+		}
+	}
+}
+
+func TestProfilePostIssuanceChecks(t *testing.T) {
+	ctx := context.Background()
+	// The leaf of the test hierarchy carries no extended key usages.
+	certificates := newTestHierarchy(t).testCertificates(t)
+
+	m := certificateCreateModel{Profile: types.StringValue("tls_server"), PostIssuanceChecks: types.ObjectNull(postIssuanceChecksAttrTypes)}
+	diags := m.checkPostIssuance(ctx, certificates.ID, certificates.CertificateB64)
+	if !diags.HasError() || !strings.Contains(diags.Errors()[0].Detail(), "server_auth") {
+		t.Errorf("checkPostIssuance() = %v, want server_auth expected", diags)
+	}
+
+	// expected_ekus of post_issuance_checks overrides the profile.
+	m.PostIssuanceChecks = types.ObjectValueMust(postIssuanceChecksAttrTypes, map[string]attr.Value{
+		checkExpectedEKUs:     types.ListValueMust(types.StringType, nil),
+		checkMaxValidityHours: types.Int64Null(),
+		checkRequiredDNSNames: types.ListNull(types.StringType),
+		checkIssuerDN:         types.StringNull(),
+		"warn_only":           types.ListNull(types.StringType),
+	})
+	if diags := m.checkPostIssuance(ctx, certificates.ID, certificates.CertificateB64); diags.HasError() {
+		t.Errorf("checkPostIssuance() = %v", diags)
+	}
+}
+
+func TestValidateProfile(t *testing.T) {
+	ctx := context.Background()
+	// debug-enroll requests carry no subject alternative names.
+	withoutNames, err := debugEnrollRequest(ctx, "", "app.example.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+	generate := func(dnsNames ...string) types.Object {
+		block := generateCSRModel{
+			CommonName:     types.StringValue("app.example.com"),
+			DNSNames:       types.ListNull(types.StringType),
+			IPAddresses:    types.ListNull(types.StringType),
+			EmailAddresses: types.ListNull(types.StringType),
+		}
+		if len(dnsNames) > 0 {
+			block.DNSNames, _ = types.ListValueFrom(ctx, types.StringType, dnsNames)
+		}
+		object, diags := types.ObjectValueFrom(ctx, generateCSRAttrTypes(), block)
+		if diags.HasError() {
+			t.Fatal(diags)
+		}
+		return object
+	}
+
+	for name, tc := range map[string]struct {
+		config certificateCreateModel
+		want   string
+	}{
+		"unset":           {},
+		"unknown profile": {config: certificateCreateModel{Profile: types.StringValue("web")}, want: "Invalid Profile"},
+		"client":          {config: certificateCreateModel{Profile: types.StringValue("mtls_client"), GenerateCSR: generate()}},
+		"server":          {config: certificateCreateModel{Profile: types.StringValue("tls_server"), GenerateCSR: generate("app.example.com")}},
+		"server unnamed":  {config: certificateCreateModel{Profile: types.StringValue("tls_server"), GenerateCSR: generate()}, want: "Missing Subject Alternative Name"},
+		"server request":  {config: certificateCreateModel{Profile: types.StringValue("tls_server"), CSR: types.StringValue(withoutNames), GenerateCSR: types.ObjectNull(generateCSRAttrTypes())}, want: "Missing Subject Alternative Name"},
+		"unknown request": {config: certificateCreateModel{Profile: types.StringValue("tls_server"), CSR: types.StringUnknown(), GenerateCSR: types.ObjectNull(generateCSRAttrTypes())}},
+	} {
+		diags := validateProfile(ctx, tc.config)
+		switch {
+		case tc.want == "" && diags.HasError():
+			t.Errorf("%s: validateProfile() = %v", name, diags)
+		case tc.want != "" && (!diags.HasError() || !strings.Contains(diags.Errors()[0].Summary(), tc.want)):
+			t.Errorf("%s: validateProfile() = %v, want %q", name, diags, tc.want)
+		}
+	}
+}
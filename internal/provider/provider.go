@@ -5,10 +5,14 @@ package provider
 
 import (
 	"context"
+	"fmt"
 	"os"
+	"strings"
+	"sync"
 
 	"github.com/flipyap/microsoft-adcs-client/client"
 	"github.com/hashicorp/terraform-plugin-framework/datasource"
+	"github.com/hashicorp/terraform-plugin-framework/diag"
 	"github.com/hashicorp/terraform-plugin-framework/path"
 	"github.com/hashicorp/terraform-plugin-framework/provider"
 	"github.com/hashicorp/terraform-plugin-framework/provider/schema"
@@ -35,6 +39,105 @@ type MicrosoftADCSProviderModel struct {
 	Password types.String `tfsdk:"password"`
 	Krb5Conf types.String `tfsdk:"krb5conf"`
 	Ntlm     types.Bool   `tfsdk:"use_ntlm"`
+
+	ApprovalWebhookURL       types.String `tfsdk:"approval_webhook_url"`
+	ApprovalWebhookHeaders   types.Map    `tfsdk:"approval_webhook_headers"`
+	RevocationWebhookURL     types.String `tfsdk:"revocation_webhook_url"`
+	RevocationWebhookHeaders types.Map    `tfsdk:"revocation_webhook_headers"`
+	DenialWebhookURL         types.String `tfsdk:"denial_webhook_url"`
+	DenialWebhookHeaders     types.Map    `tfsdk:"denial_webhook_headers"`
+	EventURL                 types.String `tfsdk:"event_url"`
+	EventFile                types.String `tfsdk:"event_file"`
+	ParserProfile            types.String `tfsdk:"parser_profile"`
+	ParserOverrides          types.Map    `tfsdk:"parser_overrides"`
+	ReadOnly                 types.Bool   `tfsdk:"read_only"`
+	RequireNetwork           types.String `tfsdk:"require_network"`
+	LDAPURL                  types.String `tfsdk:"ldap_url"`
+	LDAPBaseDN               types.String `tfsdk:"ldap_base_dn"`
+	HostAliases              types.Map    `tfsdk:"host_aliases"`
+	CAHosts                  types.Map    `tfsdk:"ca_hosts"`
+	KDCAddresses             types.List   `tfsdk:"kdc_addresses"`
+	KerberosRealm            types.String `tfsdk:"kerberos_realm"`
+	CredentialSource         types.String `tfsdk:"credential_source"`
+	CredentialOptions        types.Map    `tfsdk:"credential_source_options"`
+	PreAuthSource            types.String `tfsdk:"pre_auth_source"`
+	PreAuthOptions           types.Map    `tfsdk:"pre_auth_source_options"`
+	SubmissionEncoding       types.String `tfsdk:"submission_transfer_encoding"`
+	FetchAIAIssuers          types.Bool   `tfsdk:"fetch_aia_issuers"`
+	Workspace                types.String `tfsdk:"workspace"`
+}
+
+// providerData is handed to resources and data sources during their Configure methods. It carries
+// the ADCS client alongside the provider level settings that influence how they behave.
+type providerData struct {
+	client *client.ADCSClient
+	parser *certsrvParser
+
+	// version is the provider version, reported by the provider_info data source.
+	version string
+
+	// readOnly makes every operation that would change the CA or drop a certificate from state fail.
+	readOnly bool
+
+	// applyOnly keeps refreshes and plans that cannot reach the CA from contacting it and the
+	// directory, see require_network and caOffline.
+	applyOnly bool
+	probeOnce sync.Once
+	offline   bool
+
+	// chunkedSubmissions sends certificate requests with chunked transfer encoding.
+	chunkedSubmissions bool
+
+	// aia completes certificate chains the CA returned without some issuers, nil when
+	// fetch_aia_issuers is false.
+	aia *aiaFetcher
+
+	// workspace is the Terraform workspace certificate requests are described with, see
+	// certificate_description.
+	workspace string
+
+	// poller checks the requests that resources wait on while they are pending approval.
+	poller *pendingPoller
+
+	// caHosts maps lower-cased CA names to the host of their certsrv server, see ca_hosts.
+	caHosts map[string]string
+	// pollers holds a poller per CA server in caHosts, see pollerFor.
+	pollersMu sync.Mutex
+	pollers   map[pollerKey]*pendingPoller
+
+	// clientConfig and hostAliases build the clients of resources that bring their own credentials,
+	// see clientFor.
+	clientConfig client.ClientConfig
+	hostAliases  map[string]string
+	preAuth      *preAuthentication
+	clientsMu    sync.Mutex
+	clients      map[clientCredentials]*client.ADCSClient
+
+	// templates is only set when ldap_url is configured.
+	templates *templateDirectory
+
+	approvalWebhookURL     string
+	approvalWebhookHeaders map[string]string
+
+	revocationWebhookURL     string
+	revocationWebhookHeaders map[string]string
+
+	denialWebhookURL     string
+	denialWebhookHeaders map[string]string
+
+	eventURL    string
+	eventFile   string
+	eventFileMu sync.Mutex
+
+	// detectedVersion is filled in by caVersion the first time a failed operation needs it.
+	caVersionOnce   sync.Once
+	detectedVersion adcsVersion
+
+	// enrollable lists the templates the CA offers, read by enrollableTemplates at the first plan
+	// of a new certificate.
+	templatesOnce sync.Once
+	enrollable    []string
+	enrollableErr error
 }
 
 func (p *MicrosoftADCSProvider) Metadata(ctx context.Context, req provider.MetadataRequest, resp *provider.MetadataResponse) {
@@ -44,6 +147,7 @@ func (p *MicrosoftADCSProvider) Metadata(ctx context.Context, req provider.Metad
 
 func (p *MicrosoftADCSProvider) Schema(ctx context.Context, req provider.SchemaRequest, resp *provider.SchemaResponse) {
 	resp.Schema = schema.Schema{
+		MarkdownDescription: "Creates certificates through the web enrollment pages (certsrv) of Microsoft Active Directory Certificate Services.",
 		Attributes: map[string]schema.Attribute{
 			"host": schema.StringAttribute{
 				MarkdownDescription: "Hostname of the Server hosting the Active Directory Certificate Services",
@@ -59,13 +163,127 @@ func (p *MicrosoftADCSProvider) Schema(ctx context.Context, req provider.SchemaR
 				Sensitive:           true,
 			},
 			"krb5conf": schema.StringAttribute{
-				MarkdownDescription: "Kerberos Config to use for authentication",
+				MarkdownDescription: "Kerberos configuration to use for authentication, either its contents or the path of a `krb5.conf` or `krb5.ini` file. Byte order marks, UTF-16 and CRLF line endings, as written by Windows editors, are accepted",
 				Optional:            true,
 			},
 			"use_ntlm": schema.BoolAttribute{
 				MarkdownDescription: "Use NTLM authentication",
 				Optional:            true,
 			},
+			"approval_webhook_url": schema.StringAttribute{
+				MarkdownDescription: "URL that receives a JSON `POST` with the request ID, subject and template whenever a certificate request is taken under submission and waits for CA manager approval",
+				Optional:            true,
+			},
+			"approval_webhook_headers": schema.MapAttribute{
+				MarkdownDescription: "Extra HTTP headers, such as `Authorization`, sent with every approval webhook call",
+				ElementType:         types.StringType,
+				Optional:            true,
+				Sensitive:           true,
+			},
+			"revocation_webhook_url": schema.StringAttribute{
+				MarkdownDescription: "URL that receives a JSON `POST` with the serial number and revocation reason of every certificate destroyed with `revoke_on_destroy`. The web enrollment pages cannot revoke certificates, so the webhook performs the revocation on the CA, for example with `certutil -revoke`",
+				Optional:            true,
+			},
+			"revocation_webhook_headers": schema.MapAttribute{
+				MarkdownDescription: "Extra HTTP headers, such as `Authorization`, sent with every revocation webhook call",
+				ElementType:         types.StringType,
+				Optional:            true,
+				Sensitive:           true,
+			},
+			"denial_webhook_url": schema.StringAttribute{
+				MarkdownDescription: "URL that receives a JSON `POST` with the request ID of every pending request `microsoftadcs_request_cleanup` or `microsoftadcs_request_denial` denies. The web enrollment pages cannot deny requests, so the webhook denies them on the CA, for example with `certutil -deny`",
+				Optional:            true,
+			},
+			"denial_webhook_headers": schema.MapAttribute{
+				MarkdownDescription: "Extra HTTP headers, such as `Authorization`, sent with every denial webhook call",
+				ElementType:         types.StringType,
+				Optional:            true,
+				Sensitive:           true,
+			},
+			"event_url": schema.StringAttribute{
+				MarkdownDescription: "URL that receives a JSON `POST` for every certificate lifecycle event (`issued`, `adopted`, `renewed`, `revoked`, `pending`, `denied`) the provider performs",
+				Optional:            true,
+			},
+			"event_file": schema.StringAttribute{
+				MarkdownDescription: "Path of a file that certificate lifecycle events are appended to as newline delimited JSON",
+				Optional:            true,
+			},
+			"read_only": schema.BoolAttribute{
+				MarkdownDescription: "Fail every create and destroy of a resource while still allowing refreshes and data sources, so audits and drift detection can run with production CA credentials",
+				Optional:            true,
+			},
+			"require_network": schema.StringAttribute{
+				MarkdownDescription: "When the provider may contact the CA: `always` (default), or `apply_only` for pipelines that plan in a network segment that cannot reach the CA. With `apply_only`, the provider checks once whether the CA can be reached. When it cannot, refreshes keep the state as it was applied, plans mark the disposition and revocation status as unknown for the apply to read, the template checks against the CA and the directory are skipped, and data sources that read the CA fail. Applies and imports that reach the CA work as with `always`",
+				Optional:            true,
+			},
+			"ldap_url": schema.StringAttribute{
+				MarkdownDescription: "LDAP URL of a domain controller, such as `ldaps://dc.company.local`, used to read certificate template settings for plan time checks and to manage `microsoftadcs_certificate_template` resources. The provider username and password are used to bind",
+				Optional:            true,
+			},
+			"ldap_base_dn": schema.StringAttribute{
+				MarkdownDescription: "Distinguished name of the Active Directory configuration partition, such as `CN=Configuration,DC=company,DC=local`. Read from the RootDSE when not set",
+				Optional:            true,
+			},
+			"credential_source": schema.StringAttribute{
+				MarkdownDescription: "Where to fetch the password, and optionally the username, from at configure time instead of the `password` attribute: `exec` runs a helper command, `file` reads a file kept up to date by a sidecar. Both expect a JSON object with `username` and `password`",
+				Optional:            true,
+			},
+			"credential_source_options": schema.MapAttribute{
+				ElementType:         types.StringType,
+				MarkdownDescription: "Settings of the `credential_source`: `command`, `args` and `timeout` for `exec`, `path` for `file`",
+				Optional:            true,
+				Sensitive:           true,
+			},
+			"pre_auth_source": schema.StringAttribute{
+				MarkdownDescription: "Where to fetch headers and cookies from at configure time that every certsrv request carries, to pass a reverse proxy enforcing SSO or MFA in front of certsrv with a service exception token: `exec` runs a helper command, `file` reads a file kept up to date by a sidecar. Both expect a JSON object with `headers` and `cookies`, each mapping names to values",
+				Optional:            true,
+			},
+			"pre_auth_source_options": schema.MapAttribute{
+				ElementType:         types.StringType,
+				MarkdownDescription: "Settings of the `pre_auth_source`: `command`, `args` and `timeout` for `exec`, `path` for `file`",
+				Optional:            true,
+				Sensitive:           true,
+			},
+			"kdc_addresses": schema.ListAttribute{
+				ElementType:         types.StringType,
+				MarkdownDescription: "KDCs to authenticate against, as `host` or `host:port`, for runners that cannot discover them through DNS SRV records. The provider builds the Kerberos configuration from them, so this cannot be combined with `krb5conf`",
+				Optional:            true,
+			},
+			"kerberos_realm": schema.StringAttribute{
+				MarkdownDescription: "Kerberos realm the `kdc_addresses` serve, such as `COMPANY.LOCAL`. Defaults to the upper-cased domain of `host`",
+				Optional:            true,
+			},
+			"host_aliases": schema.MapAttribute{
+				ElementType:         types.StringType,
+				MarkdownDescription: "Addresses to connect to instead of resolving a host name, such as `{ \"ca.internal\" = \"10.1.2.3\" }`. Applies to the connections to the ADCS host and to the KDCs named in the Kerberos configuration, which keep using the host names for authentication",
+				Optional:            true,
+			},
+			"ca_hosts": schema.MapAttribute{
+				ElementType:         types.StringType,
+				MarkdownDescription: "Hosts of the certsrv servers of the CAs in a cluster behind `host`, by CA name, such as `{ \"Corp Issuing CA 1\" = \"ca1.example.com\" }`. Certificates record the CA that took their request in `ca_name`, and downloads and polls for that request go to the server of that CA instead of whichever CA `host` reaches",
+				Optional:            true,
+			},
+			"parser_profile": schema.StringAttribute{
+				MarkdownDescription: "How certsrv pages are parsed: `strict` (default) for stock certsrv, `lenient` for portals that change the markup around certsrv, or `custom` to replace patterns with `parser_overrides`",
+				Optional:            true,
+			},
+			"submission_transfer_encoding": schema.StringAttribute{
+				MarkdownDescription: "How certificate requests are sent to certsrv: `content-length` (default) or `chunked`, for proxies in front of IIS that refuse or buffer large bodies with a `Content-Length`. IIS applies its request limits either way",
+				Optional:            true,
+			},
+			"fetch_aia_issuers": schema.BoolAttribute{
+				MarkdownDescription: "Follow the http AIA URLs of issued certificates to fetch the intermediates and root a CA leaves out of its chain, and ask their OCSP responders whether they were revoked. Defaults to `true`, set it to `false` for air-gapped runs that must not reach out to the network",
+				Optional:            true,
+			},
+			"workspace": schema.StringAttribute{
+				MarkdownDescription: "Terraform workspace named in the default `CertificateDescription` request attribute of certificates, such as `terraform.workspace`. Defaults to the `TF_WORKSPACE` environment variable, then `default`, as providers are not told the workspace they run in",
+				Optional:            true,
+			},
+			"parser_overrides": schema.MapAttribute{
+				MarkdownDescription: "Regular expressions replacing the `issued_request_id`, `pending_request_id` and `disposition_message` patterns of the `custom` parser profile, each with exactly one capture group",
+				ElementType:         types.StringType,
+				Optional:            true,
+			},
 		},
 	}
 }
@@ -139,6 +357,34 @@ func (p *MicrosoftADCSProvider) Configure(ctx context.Context, req provider.Conf
 		krb5conf = config.Krb5Conf.ValueString()
 	}
 
+	if !config.CredentialSource.IsNull() && config.CredentialSource.ValueString() != "" {
+		var options map[string]string
+		if !config.CredentialOptions.IsNull() {
+			resp.Diagnostics.Append(config.CredentialOptions.ElementsAs(ctx, &options, false)...)
+			if resp.Diagnostics.HasError() {
+				return
+			}
+		}
+		source, err := newCredentialSource(config.CredentialSource.ValueString(), options)
+		if err != nil {
+			resp.Diagnostics.AddAttributeError(path.Root("credential_source"), "Invalid Credential Source", err.Error())
+			return
+		}
+		sourceUsername, sourcePassword, err := source.credentials(ctx)
+		if err != nil {
+			resp.Diagnostics.AddAttributeError(
+				path.Root("credential_source"),
+				"Unable to Fetch Credentials",
+				fmt.Sprintf("The %s credential source failed: %s", config.CredentialSource.ValueString(), err.Error()),
+			)
+			return
+		}
+		if sourceUsername != "" {
+			username = sourceUsername
+		}
+		password = sourcePassword
+	}
+
 	// If any of the expected configurations are missing, return
 	// errors with provider-specific guidance.
 
@@ -182,6 +428,116 @@ func (p *MicrosoftADCSProvider) Configure(ctx context.Context, req provider.Conf
 
 	tflog.Debug(ctx, "Creating Active Directory Certificate Services client")
 
+	if krb5conf != "" && !useNtlm {
+		loaded, err := loadKerberosConfig(krb5conf)
+		if err != nil {
+			resp.Diagnostics.AddAttributeError(
+				path.Root("krb5conf"),
+				"Invalid Kerberos Configuration",
+				"The provider cannot use the Kerberos configuration set in krb5conf or the ADCS_KRB5CONF environment variable: "+
+					err.Error(),
+			)
+			return
+		}
+		krb5conf = loaded
+	}
+
+	var kdcAddresses []string
+	if !config.KDCAddresses.IsNull() {
+		resp.Diagnostics.Append(config.KDCAddresses.ElementsAs(ctx, &kdcAddresses, false)...)
+		if resp.Diagnostics.HasError() {
+			return
+		}
+	}
+	if len(kdcAddresses) > 0 && !useNtlm {
+		if krb5conf != "" {
+			resp.Diagnostics.AddAttributeError(
+				path.Root("kdc_addresses"),
+				"Conflicting Kerberos Configuration",
+				"kdc_addresses cannot be combined with krb5conf or the ADCS_KRB5CONF environment variable. "+
+					"List the KDCs in the Kerberos configuration instead, or remove it.",
+			)
+			return
+		}
+		realm := config.KerberosRealm.ValueString()
+		if realm == "" {
+			realm = realmFromHost(host)
+		}
+		if realm == "" {
+			resp.Diagnostics.AddAttributeError(
+				path.Root("kerberos_realm"),
+				"Missing Kerberos Realm",
+				fmt.Sprintf("The Kerberos realm cannot be derived from the host %q, set kerberos_realm.", host),
+			)
+			return
+		}
+		krb5conf = kerberosConfig(realm, kdcAddresses)
+	}
+
+	var hostAliases map[string]string
+	if !config.HostAliases.IsNull() {
+		resp.Diagnostics.Append(config.HostAliases.ElementsAs(ctx, &hostAliases, false)...)
+		if resp.Diagnostics.HasError() {
+			return
+		}
+	}
+	if len(hostAliases) > 0 {
+		normalized := make(map[string]string, len(hostAliases))
+		for name, address := range hostAliases {
+			if address == "" {
+				resp.Diagnostics.AddAttributeError(
+					path.Root("host_aliases").AtMapKey(name),
+					"Invalid Host Alias",
+					fmt.Sprintf("The alias of %s is empty, set it to the address the host should be reached at.", name),
+				)
+				continue
+			}
+			normalized[strings.ToLower(name)] = address
+		}
+		if resp.Diagnostics.HasError() {
+			return
+		}
+		hostAliases = normalized
+
+		if !useNtlm {
+			// The client reads /etc/krb5.conf on its own when no configuration is given, read it
+			// here instead so its KDCs can be aliased too.
+			if krb5conf == "" {
+				if b, err := os.ReadFile("/etc/krb5.conf"); err == nil {
+					krb5conf = string(b)
+					if normalized, err := normalizeKerberosConfig(b); err == nil {
+						krb5conf = normalized
+					}
+				}
+			}
+			krb5conf = aliasKDCs(krb5conf, hostAliases)
+		}
+	}
+
+	var preAuth *preAuthentication
+	if !config.PreAuthSource.IsNull() && config.PreAuthSource.ValueString() != "" {
+		var options map[string]string
+		if !config.PreAuthOptions.IsNull() {
+			resp.Diagnostics.Append(config.PreAuthOptions.ElementsAs(ctx, &options, false)...)
+			if resp.Diagnostics.HasError() {
+				return
+			}
+		}
+		source, err := newPreAuthSource(config.PreAuthSource.ValueString(), options)
+		if err != nil {
+			resp.Diagnostics.AddAttributeError(path.Root("pre_auth_source"), "Invalid Pre-Authentication Source", err.Error())
+			return
+		}
+		if preAuth, err = source.preAuthentication(ctx); err != nil {
+			resp.Diagnostics.AddAttributeError(
+				path.Root("pre_auth_source"),
+				"Unable to Fetch Pre-Authentication",
+				fmt.Sprintf("The %s pre-authentication source failed: %s", config.PreAuthSource.ValueString(), err.Error()),
+			)
+			return
+		}
+	}
+
 	// Create a new ADCS client using the configuration values.
 	clientConfig := client.ClientConfig{
 		Host:     host,
@@ -193,7 +549,7 @@ func (p *MicrosoftADCSProvider) Configure(ctx context.Context, req provider.Conf
 	client, err := client.NewClient(&clientConfig)
 	if err != nil {
 		resp.Diagnostics.AddError(
-			"Unable to Create Active Directory Certificate Services API Client",
+			classifiedSummary("Unable to Create Active Directory Certificate Services API Client", err),
 			"An unexpected error occurred when creating the Active Directory Certificate Services API client. "+
 				"If the error is not clear, please contact the provider developers.\n\n"+
 				"ADCS Client Error: "+err.Error(),
@@ -201,23 +557,162 @@ func (p *MicrosoftADCSProvider) Configure(ctx context.Context, req provider.Conf
 		return
 	}
 
-	// Make the adcs client available during DataSource and Resource
-	// type Configure methods.
-	resp.DataSourceData = client
-	resp.ResourceData = client
+	configureTransport(client, username, password, hostAliases)
+	preAuth.wrap(client)
+
+	data := &providerData{
+		client:               client,
+		clientConfig:         clientConfig,
+		hostAliases:          hostAliases,
+		preAuth:              preAuth,
+		version:              p.version,
+		approvalWebhookURL:   config.ApprovalWebhookURL.ValueString(),
+		revocationWebhookURL: config.RevocationWebhookURL.ValueString(),
+		denialWebhookURL:     config.DenialWebhookURL.ValueString(),
+		eventURL:             config.EventURL.ValueString(),
+		eventFile:            config.EventFile.ValueString(),
+		readOnly:             config.ReadOnly.ValueBool(),
+		workspace:            providerWorkspace(config),
+	}
+	if config.FetchAIAIssuers.IsNull() || config.FetchAIAIssuers.ValueBool() {
+		data.aia = newAIAFetcher()
+	}
+
+	if !config.LDAPURL.IsNull() && config.LDAPURL.ValueString() != "" {
+		data.templates = &templateDirectory{
+			url:      config.LDAPURL.ValueString(),
+			baseDN:   config.LDAPBaseDN.ValueString(),
+			username: username,
+			password: password,
+		}
+	}
+
+	var parserOverrides map[string]string
+	if !config.ParserOverrides.IsNull() {
+		resp.Diagnostics.Append(config.ParserOverrides.ElementsAs(ctx, &parserOverrides, false)...)
+		if resp.Diagnostics.HasError() {
+			return
+		}
+	}
+	data.parser, err = newCertsrvParser(config.ParserProfile.ValueString(), parserOverrides)
+	if err != nil {
+		resp.Diagnostics.AddAttributeError(
+			path.Root("parser_profile"),
+			"Invalid certsrv Parser Configuration",
+			err.Error(),
+		)
+		return
+	}
+	data.poller = newPendingPoller(client, data.parser)
+
+	if !config.CAHosts.IsNull() {
+		var caHosts map[string]string
+		resp.Diagnostics.Append(config.CAHosts.ElementsAs(ctx, &caHosts, false)...)
+		if resp.Diagnostics.HasError() {
+			return
+		}
+		data.caHosts = make(map[string]string, len(caHosts))
+		for name, caHost := range caHosts {
+			if caHost == "" {
+				resp.Diagnostics.AddAttributeError(
+					path.Root("ca_hosts").AtMapKey(name),
+					"Invalid CA Host",
+					fmt.Sprintf("The host of CA %s is empty, set it to the host of the certsrv server of the CA.", name),
+				)
+				continue
+			}
+			data.caHosts[strings.ToLower(name)] = caHost
+		}
+		if resp.Diagnostics.HasError() {
+			return
+		}
+	}
+
+	switch requireNetwork := config.RequireNetwork.ValueString(); requireNetwork {
+	case "", requireNetworkAlways:
+	case requireNetworkApplyOnly:
+		data.applyOnly = true
+	default:
+		resp.Diagnostics.AddAttributeError(
+			path.Root("require_network"),
+			"Invalid Require Network",
+			fmt.Sprintf("require_network must be %q or %q, got %q.", requireNetworkAlways, requireNetworkApplyOnly, requireNetwork),
+		)
+		return
+	}
+
+	switch encoding := config.SubmissionEncoding.ValueString(); encoding {
+	case "", transferEncodingContentLength:
+	case transferEncodingChunked:
+		data.chunkedSubmissions = true
+	default:
+		resp.Diagnostics.AddAttributeError(
+			path.Root("submission_transfer_encoding"),
+			"Invalid Submission Transfer Encoding",
+			fmt.Sprintf("submission_transfer_encoding must be %q or %q, got %q.", transferEncodingContentLength, transferEncodingChunked, encoding),
+		)
+		return
+	}
+
+	if !config.ApprovalWebhookHeaders.IsNull() {
+		resp.Diagnostics.Append(config.ApprovalWebhookHeaders.ElementsAs(ctx, &data.approvalWebhookHeaders, false)...)
+		if resp.Diagnostics.HasError() {
+			return
+		}
+	}
+
+	if !config.RevocationWebhookHeaders.IsNull() {
+		resp.Diagnostics.Append(config.RevocationWebhookHeaders.ElementsAs(ctx, &data.revocationWebhookHeaders, false)...)
+		if resp.Diagnostics.HasError() {
+			return
+		}
+	}
+
+	if !config.DenialWebhookHeaders.IsNull() {
+		resp.Diagnostics.Append(config.DenialWebhookHeaders.ElementsAs(ctx, &data.denialWebhookHeaders, false)...)
+		if resp.Diagnostics.HasError() {
+			return
+		}
+	}
+
+	// Make the adcs client and provider settings available during DataSource
+	// and Resource type Configure methods.
+	resp.DataSourceData = data
+	resp.ResourceData = data
 
 	tflog.Info(ctx, "Configured Active Directory Certificate Services client", map[string]any{"success": true})
 }
 
+// readOnlyError is the diagnostic for operations refused because of read_only.
+func readOnlyError(operation string) diag.Diagnostic {
+	return diag.NewErrorDiagnostic(
+		"Provider Is Read-Only",
+		"The provider is configured with read_only = true and will not "+operation+". "+
+			"Refreshes and data sources keep working; unset read_only to make changes.",
+	)
+}
+
 func (p *MicrosoftADCSProvider) Resources(ctx context.Context) []func() resource.Resource {
 	return []func() resource.Resource{
 		NewCertificateResource,
+		NewCertificateRequestResource,
+		NewCertificateBatchResource,
+		NewRequestCleanupResource,
+		NewRequestDenialResource,
+		NewCertificateTemplateResource,
 	}
 }
 
 func (p *MicrosoftADCSProvider) DataSources(ctx context.Context) []func() datasource.DataSource {
 	return []func() datasource.DataSource{
 		NewCertificateDataSource,
+		NewNDESDataSource,
+		NewCAChainDataSource,
+		NewCertificatesDataSource,
+		NewExpiringCertificatesDataSource,
+		NewProviderInfoDataSource,
+		NewOCSPStatusDataSource,
+		NewAbandonedRequestsDataSource,
 	}
 }
 
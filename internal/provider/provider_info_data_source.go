@@ -0,0 +1,157 @@
+package provider
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/flipyap/microsoft-adcs-client/client"
+	"github.com/hashicorp/terraform-plugin-framework/datasource"
+	"github.com/hashicorp/terraform-plugin-framework/datasource/schema"
+	"github.com/hashicorp/terraform-plugin-framework/diag"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+)
+
+// Ensure the implementation satisfies the expected interfaces.
+var (
+	_ datasource.DataSource              = &providerInfoDataSource{}
+	_ datasource.DataSourceWithConfigure = &providerInfoDataSource{}
+)
+
+// NewProviderInfoDataSource is a helper function to simplify the provider implementation.
+func NewProviderInfoDataSource() datasource.DataSource {
+	return &providerInfoDataSource{}
+}
+
+// providerInfoDataSource is the data source implementation.
+type providerInfoDataSource struct {
+	client   *client.ADCSClient
+	provider *providerData
+}
+
+// providerInfoModel maps the provider build and the capabilities of the configured CA.
+type providerInfoModel struct {
+	ID                   types.String `tfsdk:"id"`
+	Version              types.String `tfsdk:"version"`
+	Backends             types.List   `tfsdk:"backends"`
+	Authentication       types.String `tfsdk:"authentication"`
+	CAName               types.String `tfsdk:"ca_name"`
+	WebEnrollmentServer  types.String `tfsdk:"web_enrollment_server"`
+	WebEnrollmentVersion types.String `tfsdk:"web_enrollment_version"`
+	ADCSVersion          types.String `tfsdk:"adcs_version"`
+	WSTEPAvailable       types.Bool   `tfsdk:"wstep_available"`
+}
+
+// Configure adds the provider configured client to the data source.
+func (d *providerInfoDataSource) Configure(_ context.Context, req datasource.ConfigureRequest, resp *datasource.ConfigureResponse) {
+	if req.ProviderData == nil {
+		return
+	}
+
+	data, ok := req.ProviderData.(*providerData)
+	if !ok {
+		resp.Diagnostics.AddError(
+			"Unexpected Data Source Configure Type",
+			fmt.Sprintf("Expected *providerData, got: %T. Please report this issue to the provider developers.", req.ProviderData),
+		)
+
+		return
+	}
+
+	d.client = data.client
+	d.provider = data
+}
+
+// Metadata returns the data source type name.
+func (d *providerInfoDataSource) Metadata(_ context.Context, req datasource.MetadataRequest, resp *datasource.MetadataResponse) {
+	resp.TypeName = req.ProviderTypeName + "_provider_info"
+}
+
+// Schema defines the schema for the data source.
+func (d *providerInfoDataSource) Schema(_ context.Context, _ datasource.SchemaRequest, resp *datasource.SchemaResponse) {
+	resp.Schema = schema.Schema{
+		Description: `Describes the provider build and what it detects about the configured CA, so modules shared across CA estates
+can branch on the capabilities of each CA. Capabilities that can only be read with CA administration rights, such as whether the
+CA honors san request attributes (EDITF_ATTRIBUTESUBJECTALTNAME2), are not detected.`,
+		Attributes: map[string]schema.Attribute{
+			"id": schema.StringAttribute{
+				Computed:    true,
+				Description: "Host of the CA that was queried.",
+			},
+			"version": schema.StringAttribute{
+				Computed:    true,
+				Description: `Version of the provider, "dev" for local builds.`,
+			},
+			"backends": schema.ListAttribute{
+				ElementType: types.StringType,
+				Computed:    true,
+				Description: `Protocols the provider talks to the CA with: "certsrv" for web enrollment and "ldap" when ldap_url is configured.`,
+			},
+			"authentication": schema.StringAttribute{
+				Computed:    true,
+				Description: `How the provider authenticates to the CA, "kerberos" or "ntlm".`,
+			},
+			"ca_name": schema.StringAttribute{
+				Computed:    true,
+				Description: "Name of the CA from the banner of the web enrollment pages, empty when the pages are customized.",
+			},
+			"web_enrollment_server": schema.StringAttribute{
+				Computed:    true,
+				Description: "Server header of the web enrollment pages, such as Microsoft-IIS/10.0.",
+			},
+			"web_enrollment_version": schema.StringAttribute{
+				Computed:    true,
+				Description: "IIS version serving the web enrollment pages, such as 10.0. Empty when a proxy hides it.",
+			},
+			"adcs_version": schema.StringAttribute{
+				Computed: true,
+				Description: `Windows Server release of the CA host the IIS version maps to, from "2003" to "2016-2022" (these share IIS
+10.0), or "unknown". Operations older releases do not support fail with a diagnostic naming the release.`,
+			},
+			"wstep_available": schema.BoolAttribute{
+				Computed:    true,
+				Description: "Whether the Certificate Enrollment Web Service (WSTEP) of the CA is installed on the host.",
+			},
+		},
+	}
+}
+
+// Read refreshes the Terraform state with the latest data.
+func (d *providerInfoDataSource) Read(ctx context.Context, req datasource.ReadRequest, resp *datasource.ReadResponse) {
+	if d.provider.caOffline(ctx) {
+		resp.Diagnostics.Append(applyOnlyError("detect the capabilities of the CA"))
+		return
+	}
+	capabilities, err := detectCACapabilities(ctx, d.client)
+	if err != nil {
+		resp.Diagnostics.AddError(classifiedSummary("Unable to Detect CA Capabilities", err), err.Error())
+		return
+	}
+
+	backends := []string{"certsrv"}
+	if d.provider.templates != nil {
+		backends = append(backends, "ldap")
+	}
+	authentication := "kerberos"
+	if d.client.UseNtlm {
+		authentication = "ntlm"
+	}
+
+	data := providerInfoModel{
+		ID:                   types.StringValue(d.client.HostURL),
+		Version:              types.StringValue(d.provider.version),
+		Authentication:       types.StringValue(authentication),
+		CAName:               types.StringValue(capabilities.CAName),
+		WebEnrollmentServer:  types.StringValue(capabilities.Server),
+		WebEnrollmentVersion: types.StringValue(capabilities.IISVersion),
+		ADCSVersion:          types.StringValue(capabilities.Version.String()),
+		WSTEPAvailable:       types.BoolValue(capabilities.WSTEP),
+	}
+	var diags diag.Diagnostics
+	data.Backends, diags = types.ListValueFrom(ctx, types.StringType, backends)
+	resp.Diagnostics.Append(diags...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	resp.Diagnostics.Append(resp.State.Set(ctx, &data)...)
+}
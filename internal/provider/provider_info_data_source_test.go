@@ -0,0 +1,29 @@
+package provider
+
+import (
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-testing/helper/resource"
+)
+
+func TestAccProviderInfoDataSource(t *testing.T) {
+	srv := newAccCertsrv(t)
+
+	resource.Test(t, resource.TestCase{
+		ProtoV6ProviderFactories: testAccProtoV6ProviderFactories,
+		Steps: []resource.TestStep{
+			{
+				Config: srv.providerConfig() + `data "microsoftadcs_provider_info" "test" {}`,
+				Check: resource.ComposeAggregateTestCheckFunc(
+					resource.TestCheckResourceAttr("data.microsoftadcs_provider_info.test", "version", "test"),
+					resource.TestCheckResourceAttr("data.microsoftadcs_provider_info.test", "backends.#", "1"),
+					resource.TestCheckResourceAttr("data.microsoftadcs_provider_info.test", "authentication", "ntlm"),
+					resource.TestCheckResourceAttr("data.microsoftadcs_provider_info.test", "ca_name", accCAName),
+					resource.TestCheckResourceAttr("data.microsoftadcs_provider_info.test", "web_enrollment_version", "10.0"),
+					resource.TestCheckResourceAttr("data.microsoftadcs_provider_info.test", "adcs_version", "2016-2022"),
+					resource.TestCheckResourceAttr("data.microsoftadcs_provider_info.test", "wstep_available", "true"),
+				),
+			},
+		},
+	})
+}
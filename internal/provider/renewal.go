@@ -0,0 +1,319 @@
+package provider
+
+import (
+	"context"
+	"crypto/x509"
+	"encoding/asn1"
+	"encoding/pem"
+	"fmt"
+	"time"
+
+	"github.com/hashicorp/terraform-plugin-framework/diag"
+	"github.com/hashicorp/terraform-plugin-framework/path"
+	"github.com/hashicorp/terraform-plugin-framework/resource"
+	"github.com/hashicorp/terraform-plugin-framework/tfsdk"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+	"github.com/hashicorp/terraform-plugin-log/tflog"
+)
+
+// renewalThreshold is the share of the certificate lifetime after which a scheduled renewal may
+// happen, leaving the last third of the lifetime to roll the new certificate out.
+const renewalThreshold = 2.0 / 3.0
+
+// renewalTime returns when the certificate should be renewed according to the schedule: the first
+// scheduled window once two thirds of the lifetime have passed. If the schedule has no window left
+// before the certificate expires, the threshold itself is used.
+func renewalTime(cert *x509.Certificate, schedule *cronSchedule) time.Time {
+	lifetime := cert.NotAfter.Sub(cert.NotBefore)
+	threshold := cert.NotBefore.Add(time.Duration(float64(lifetime) * renewalThreshold))
+
+	window := schedule.next(threshold)
+	if window.IsZero() || !window.Before(cert.NotAfter) {
+		return threshold
+	}
+	return window
+}
+
+// expired reports whether the certificate in state expired before now. A certificate that cannot be
+// parsed is left to the renewal settings to report.
+func (m *certificateCreateModel) expired(now time.Time) bool {
+	cert, err := parseCertificate(m.CertificateB64.ValueString())
+	return err == nil && !now.Before(cert.NotAfter)
+}
+
+// setReadyForRenewal evaluates the renewal settings against the certificate in state. A certificate
+// that is ready for renewal is replaced by the next plan, see ModifyPlan. One that has expired
+// always is, whatever the settings.
+func (m *certificateCreateModel) setReadyForRenewal(ctx context.Context, now time.Time) diag.Diagnostics {
+	var diags diag.Diagnostics
+
+	m.ReadyForRenewal = types.BoolValue(false)
+	if m.expired(now) {
+		tflog.Info(ctx, "Certificate has expired", map[string]interface{}{
+			"request_id": m.ID.ValueString(),
+			"not_after":  m.NotAfter.ValueString(),
+		})
+		m.ReadyForRenewal = types.BoolValue(true)
+		return diags
+	}
+	hasSchedule := !m.RenewalSchedule.IsNull() && m.RenewalSchedule.ValueString() != ""
+	earlyRenewalHours := m.earlyRenewalHours()
+	hasEarlyRenewal := !earlyRenewalHours.IsNull()
+	hasRotation := !m.RotationPeriod.IsNull() && m.RotationPeriod.ValueString() != ""
+	if !hasSchedule && !hasEarlyRenewal && !hasRotation {
+		return diags
+	}
+
+	cert, err := parseCertificate(m.CertificateB64.ValueString())
+	if err != nil {
+		diags.AddError(
+			classifiedSummary("Error Parsing Certificate", &ParseError{Err: err}),
+			fmt.Sprintf("Could not parse the certificate of request ID %s to evaluate its renewal: %s", m.ID.ValueString(), err.Error()),
+		)
+		return diags
+	}
+
+	var renewAt time.Time
+	if hasSchedule {
+		schedule, err := parseCronSchedule(m.RenewalSchedule.ValueString())
+		if err != nil {
+			diags.AddAttributeError(path.Root("renewal_schedule"), "Invalid Renewal Schedule", err.Error())
+			return diags
+		}
+		renewAt = renewalTime(cert, schedule)
+	}
+	if hasEarlyRenewal {
+		early := cert.NotAfter.Add(-time.Duration(earlyRenewalHours.ValueInt64()) * time.Hour)
+		if renewAt.IsZero() || early.Before(renewAt) {
+			renewAt = early
+		}
+	}
+	if hasRotation {
+		period, err := time.ParseDuration(m.RotationPeriod.ValueString())
+		if err != nil || period <= 0 {
+			diags.AddAttributeError(path.Root("rotation_period"), "Invalid Rotation Period", fmt.Sprintf("rotation_period %q is not a positive duration.", m.RotationPeriod.ValueString()))
+			return diags
+		}
+		if rotate := cert.NotBefore.Add(period); renewAt.IsZero() || rotate.Before(renewAt) {
+			renewAt = rotate
+		}
+	}
+
+	if !now.Before(renewAt) {
+		tflog.Info(ctx, "Certificate is ready for renewal", map[string]interface{}{
+			"request_id": m.ID.ValueString(),
+			"renew_at":   renewAt.Format(time.RFC3339),
+		})
+		m.ReadyForRenewal = types.BoolValue(true)
+	}
+	return diags
+}
+
+// oidRenewalCertificate is szOID_RENEWAL_CERTIFICATE, the authenticated attribute naming the
+// certificate a request renews.
+var oidRenewalCertificate = asn1.ObjectIdentifier{1, 3, 6, 1, 4, 1, 311, 13, 1}
+
+// newRenewalRequest wraps the certificate signing request in a PKCS#7 signed with the key of the
+// certificate it renews, the way certreq -enroll -cert <thumbprint> renew does. The CA checks the signature against that
+// certificate and issues the renewal from its template and subject instead of treating the
+// request as a new enrollment.
+func newRenewalRequest(csr string, cert *x509.Certificate, keyPEM string) (string, error) {
+	request, err := parseCertificateRequest(csr)
+	if err != nil {
+		return "", fmt.Errorf("could not parse the certificate signing request: %v", err)
+	}
+	key, err := parsePrivateKey(keyPEM)
+	if err != nil {
+		return "", fmt.Errorf("could not parse the private key: %v", err)
+	}
+	if !keyMatches(key, cert.PublicKey) {
+		return "", fmt.Errorf("the private key is not the key of the certificate being renewed")
+	}
+
+	der, err := signPKCS7(request.Raw, cert, key, pkcs7Attribute{Type: oidRenewalCertificate, Value: cert.Raw})
+	if err != nil {
+		return "", err
+	}
+	return string(pem.EncodeToMemory(&pem.Block{Type: "PKCS7", Bytes: der})), nil
+}
+
+// parseRenewalRequest unwraps a PKCS#7 renewal request like the CA does: the signature has to
+// verify against the signer certificate, which the renewal certificate attribute has to name. It
+// returns the certificate signing request inside and the certificate it renews.
+func parseRenewalRequest(der []byte) (*x509.CertificateRequest, *x509.Certificate, error) {
+	signed, err := parseSignedRequest(der)
+	if err != nil {
+		return nil, nil, err
+	}
+	var renews *x509.Certificate
+	if value, ok := signed.attribute(oidRenewalCertificate); ok {
+		if renews, err = x509.ParseCertificate(value); err != nil {
+			return nil, nil, fmt.Errorf("could not parse renewal certificate: %v", err)
+		}
+	}
+	if renews == nil || !renews.Equal(signed.signer) {
+		return nil, nil, fmt.Errorf("the renewal certificate is not the signer certificate")
+	}
+	return signed.request, renews, nil
+}
+
+const (
+	requestFormatPKCS10 = "pkcs10"
+	requestFormatPKCS7  = "pkcs7"
+)
+
+// validateRequestFormat checks that certificate_signing_request holds what request_format says. A
+// pkcs7 renewal request has to verify like the CA would check it, and cannot be combined with the
+// settings that build the request in the provider.
+func validateRequestFormat(config certificateCreateModel) diag.Diagnostics {
+	var diags diag.Diagnostics
+	if config.RequestFormat.IsUnknown() {
+		return diags
+	}
+
+	format := requestFormatPKCS10
+	if !config.RequestFormat.IsNull() {
+		format = config.RequestFormat.ValueString()
+	}
+	if format != requestFormatPKCS10 && format != requestFormatPKCS7 {
+		diags.AddAttributeError(
+			path.Root("request_format"),
+			"Invalid Request Format",
+			fmt.Sprintf("request_format %q is not one of: %s, %s.", format, requestFormatPKCS10, requestFormatPKCS7),
+		)
+		return diags
+	}
+	if format == requestFormatPKCS7 {
+		if !config.GenerateCSR.IsNull() {
+			diags.AddAttributeError(path.Root("request_format"), "Conflicting Request Format", "generate_csr creates a pkcs10 certificate signing request, remove request_format or the generate_csr block.")
+		}
+		if config.RenewExisting.ValueBool() {
+			diags.AddAttributeError(path.Root("request_format"), "Conflicting Request Format", "renew_existing builds the renewal request itself from a pkcs10 certificate signing request, remove request_format or renew_existing.")
+		}
+	}
+
+	if config.CSR.IsNull() || config.CSR.IsUnknown() {
+		return diags
+	}
+	der, err := decodePEMOrBase64(config.CSR.ValueString())
+	if err != nil {
+		// The request itself is validated by the CA on submission.
+		return diags
+	}
+	switch {
+	case format == requestFormatPKCS10 && isPKCS7(der):
+		diags.AddAttributeError(
+			path.Root("certificate_signing_request"),
+			"Unexpected Renewal Request",
+			`certificate_signing_request holds a PKCS#7 renewal request, set request_format = "pkcs7" to submit it.`,
+		)
+	case format == requestFormatPKCS7 && !isPKCS7(der):
+		diags.AddAttributeError(
+			path.Root("certificate_signing_request"),
+			"Invalid Renewal Request",
+			`request_format is "pkcs7" but certificate_signing_request does not hold a PKCS#7 renewal request.`,
+		)
+	case format == requestFormatPKCS7:
+		if _, _, err := parseRenewalRequest(der); err != nil {
+			diags.AddAttributeError(
+				path.Root("certificate_signing_request"),
+				"Invalid Renewal Request",
+				"The CA would reject the renewal request in certificate_signing_request: "+err.Error()+".",
+			)
+		}
+	}
+	return diags
+}
+
+// planRenewal plans the in-place renewal of renew_existing. Everything derived from the certificate
+// becomes unknown, which Update takes as the signal to renew. A generated request_nonce is
+// replaced so the renewal is not mistaken for the original submission in the CA database.
+func planRenewal(ctx context.Context, config tfsdk.Config, plan *tfsdk.Plan) diag.Diagnostics {
+	var diags diag.Diagnostics
+
+	for _, name := range []string{
+		"id", "certificate_b64", "certificate_chain_b64", "certificate_chain_p7b", "certificate_pem", "certificate_der", "not_after", "ca_name",
+		"certificate_chain_pem", "issuing_ca_pem", "root_ca_pem", "thumbprint_sha1", "thumbprint_sha256", "signature_algorithm", "combined_pem", "pkcs12_b64",
+		"request_disposition", "revocation_status", "revoked_reason", "revocation_date",
+	} {
+		diags.Append(plan.SetAttribute(ctx, path.Root(name), types.StringUnknown())...)
+	}
+	diags.Append(plan.SetAttribute(ctx, path.Root("certificate_chain"), types.ListUnknown(types.StringType))...)
+	diags.Append(plan.SetAttribute(ctx, path.Root("certificate_chain_der"), types.ListUnknown(types.StringType))...)
+	diags.Append(plan.SetAttribute(ctx, path.Root("certificate_chains"), types.ListUnknown(certificateChainsType.ElemType))...)
+	diags.Append(plan.SetAttribute(ctx, path.Root("kubernetes_tls_secret"), types.MapUnknown(types.StringType))...)
+	diags.Append(plan.SetAttribute(ctx, path.Root("azure_key_vault_certificate"), types.ObjectUnknown(azureKeyVaultCertificateAttrTypes))...)
+
+	var nonce types.String
+	diags.Append(config.GetAttribute(ctx, path.Root("request_nonce"), &nonce)...)
+	if nonce.IsNull() {
+		diags.Append(plan.SetAttribute(ctx, path.Root("request_nonce"), types.StringUnknown())...)
+	}
+	return diags
+}
+
+// renewCertificate renews the certificate in state in place, see renew_existing. The request is the
+// one in state, so the renewed certificate keeps the key.
+func (r *certificateResource) renewCertificate(ctx context.Context, req resource.UpdateRequest, resp *resource.UpdateResponse, plan certificateCreateModel) {
+	if r.provider.readOnly {
+		resp.Diagnostics.Append(readOnlyError("renew a certificate"))
+		return
+	}
+
+	var state certificateCreateModel
+	resp.Diagnostics.Append(req.State.Get(ctx, &state)...)
+	attr, diags := plan.submittedAttributes(ctx)
+	resp.Diagnostics.Append(diags...)
+	attr = plan.describedAttributes(attr, r.provider.workspace)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	cert, err := parseCertificate(state.CertificateB64.ValueString())
+	if err != nil {
+		resp.Diagnostics.AddError(
+			classifiedSummary("Error Renewing Certificate", &ParseError{Err: err}),
+			fmt.Sprintf("Could not parse the certificate of request ID %s: %s", state.ID.ValueString(), err.Error()),
+		)
+		return
+	}
+	request, err := newRenewalRequest(plan.CSR.ValueString(), cert, plan.privateKeyPEM().ValueString())
+	if err != nil {
+		resp.Diagnostics.AddAttributeError(
+			path.Root("renew_existing"),
+			"Error Renewing Certificate",
+			fmt.Sprintf("Could not build the renewal request for request ID %s: %s", state.ID.ValueString(), err.Error()),
+		)
+		return
+	}
+
+	tflog.Info(ctx, "Renewing certificate", map[string]interface{}{
+		"request_id":    state.ID.ValueString(),
+		"serial_number": fmt.Sprintf("%x", cert.SerialNumber),
+	})
+	if plan.GeneratedPrivateKeyPEM.IsUnknown() {
+		plan.GeneratedPrivateKeyPEM = types.StringNull()
+	}
+	certificates := r.requestCertificate(ctx, &plan, request, attr, &resp.Diagnostics)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+	if certificates == nil {
+		resp.Diagnostics.AddError(
+			errorClassPending+": Certificate Renewal Pending Approval",
+			fmt.Sprintf("The CA took the renewal of request ID %s under submission as request ID %s. Renewals are not saved pending, "+
+				"the current certificate is kept in state. Once a CA manager issued it, set adopt_request_id = %q.", state.ID.ValueString(), plan.ID.ValueString(), plan.ID.ValueString()),
+		)
+		return
+	}
+	resp.Diagnostics.Append(plan.setIssuedCertificate(ctx, certificates)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	resp.Diagnostics.Append(resp.State.Set(ctx, plan)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+	r.recordIssuance(ctx, resp.Private, plan, certificates, eventRenewed, &resp.Diagnostics)
+}
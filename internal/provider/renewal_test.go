@@ -0,0 +1,274 @@
+package provider
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/base64"
+	"encoding/pem"
+	"fmt"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-framework/diag"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+	"github.com/hashicorp/terraform-plugin-testing/helper/resource"
+	"github.com/hashicorp/terraform-plugin-testing/terraform"
+)
+
+// testRenewalCSR returns a certificate signing request and the PEM private key for key.
+func testRenewalCSR(t *testing.T, key interface{}) (string, string) {
+	t.Helper()
+	der, err := x509.CreateCertificateRequest(rand.Reader, &x509.CertificateRequest{Subject: pkix.Name{CommonName: "app.example.com"}}, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyDER, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE REQUEST", Bytes: der})),
+		string(pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: keyDER}))
+}
+
+func TestNewRenewalRequest(t *testing.T) {
+	h := newTestHierarchy(t)
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rsaLeaf := testCertificateWithKey(t, h, rsaKey)
+
+	for name, tc := range map[string]struct {
+		cert *x509.Certificate
+		key  interface{}
+	}{
+		"ecdsa": {h.leaf, h.leafKey},
+		"rsa":   {rsaLeaf, rsaKey},
+	} {
+		t.Run(name, func(t *testing.T) {
+			csrPEM, keyPEM := testRenewalCSR(t, tc.key)
+			request, err := newRenewalRequest(csrPEM, tc.cert, keyPEM)
+			if err != nil {
+				t.Fatalf("newRenewalRequest() = %v", err)
+			}
+			block, _ := pem.Decode([]byte(request))
+			if block == nil || block.Type != "PKCS7" {
+				t.Fatalf("renewal request is not a PKCS7 PEM block: %q", request)
+			}
+			csr, renews, err := parseRenewalRequest(block.Bytes)
+			if err != nil {
+				t.Fatalf("renewal request does not verify: %v", err)
+			}
+			if !renews.Equal(tc.cert) {
+				t.Errorf("renewal request names the wrong certificate")
+			}
+			if wantCSR, _ := parseCertificateRequest(csrPEM); !bytes.Equal(csr.Raw, wantCSR.Raw) {
+				t.Errorf("renewal request does not carry the certificate signing request")
+			}
+		})
+	}
+
+	csrPEM, _ := testRenewalCSR(t, h.leafKey)
+	_, otherKey := testRenewalCSR(t, h.issuingKey)
+	if _, err := newRenewalRequest(csrPEM, h.leaf, otherKey); err == nil || !strings.Contains(err.Error(), "not the key") {
+		t.Errorf("error = %v, want a key mismatch", err)
+	}
+}
+
+// testCertificateWithKey issues a leaf for key from the test issuing CA.
+func testCertificateWithKey(t *testing.T, h *testHierarchy, key *rsa.PrivateKey) *x509.Certificate {
+	t.Helper()
+	template, _ := x509.ParseCertificate(h.leaf.Raw)
+	der, err := x509.CreateCertificate(rand.Reader, template, h.issuing, &key.PublicKey, h.issuingKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return cert
+}
+
+func TestRenewCertificateSubmission(t *testing.T) {
+	srv := newAccCertsrv(t)
+	t.Setenv("ADCS_PASSWORD", "secret")
+	data, err := debugEnrollConfigure(context.Background(), "test", map[string]interface{}{
+		"host":     srv.host(),
+		"username": "svc-terraform",
+		"use_ntlm": true,
+	}, io.Discard)
+	if err != nil {
+		t.Fatal(err)
+	}
+	r := &certificateResource{client: data.client, provider: data}
+
+	csrPEM, keyPEM := testRenewalCSR(t, srv.hierarchy.leafKey)
+	request, err := newRenewalRequest(csrPEM, srv.hierarchy.leaf, keyPEM)
+	if err != nil {
+		t.Fatal(err)
+	}
+	plan := &certificateCreateModel{
+		CSR:      types.StringValue(csrPEM),
+		Template: types.StringValue("WebServer"),
+	}
+	var diags diag.Diagnostics
+	certificates := r.requestCertificate(context.Background(), plan, request, "", &diags)
+	if diags.HasError() {
+		t.Fatalf("unexpected diagnostics: %v", diags)
+	}
+
+	req := srv.request(certificates.ID)
+	if req == nil || req.renews == nil || !req.renews.Equal(srv.hierarchy.leaf) {
+		t.Fatalf("the CA did not receive a renewal of the leaf")
+	}
+	if strings.Join(req.attributes, "\n") != requestNonceAttribute+":"+plan.RequestNonce.ValueString() {
+		t.Errorf("attributes = %q, want only the request nonce", req.attributes)
+	}
+}
+
+func TestAccCertificateResourceRenewExisting(t *testing.T) {
+	srv := newAccCertsrv(t)
+	config := func(extra string) string {
+		return srv.providerConfig() + fmt.Sprintf(`
+resource "microsoftadcs_certificate" "test" {
+  template       = "WebServer"
+  renew_existing = true
+  %s
+
+  generate_csr {
+    common_name = "app.example.com"
+  }
+}
+`, extra)
+	}
+
+	var firstID string
+	resource.Test(t, resource.TestCase{
+		ProtoV6ProviderFactories: testAccProtoV6ProviderFactories,
+		Steps: []resource.TestStep{
+			{
+				Config: config(""),
+				Check: func(s *terraform.State) error {
+					firstID = s.RootModule().Resources["microsoftadcs_certificate.test"].Primary.ID
+					return nil
+				},
+			},
+			// An early renewal longer than the lifetime makes the certificate due right away, and
+			// again after every renewal.
+			{
+				Config:             config("early_renewal_hours = 100000"),
+				ExpectNonEmptyPlan: true,
+				Check: func(s *terraform.State) error {
+					renewed := s.RootModule().Resources["microsoftadcs_certificate.test"].Primary
+					if renewed.ID == firstID {
+						return fmt.Errorf("request ID %s was not renewed", firstID)
+					}
+					first := srv.request(firstID)
+					req := srv.request(renewed.ID)
+					if req == nil || req.renews == nil || !req.renews.Equal(first.cert) {
+						return fmt.Errorf("request ID %s is not a renewal of request ID %s", renewed.ID, firstID)
+					}
+					return nil
+				},
+			},
+		},
+	})
+}
+
+func TestValidateRequestFormat(t *testing.T) {
+	h := newTestHierarchy(t)
+	csrPEM, keyPEM := testRenewalCSR(t, h.leafKey)
+	renewal, err := newRenewalRequest(csrPEM, h.leaf, keyPEM)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// A renewal request for a certificate the signing key does not belong to.
+	block, _ := pem.Decode([]byte(renewal))
+	forged, err := signPKCS7(block.Bytes, h.leaf, h.issuingKey, pkcs7Attribute{Type: oidRenewalCertificate, Value: h.leaf.Raw})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cases := map[string]struct {
+		format string
+		csr    string
+		errors []string
+	}{
+		"pkcs10":                         {csr: csrPEM},
+		"pkcs7":                          {format: "pkcs7", csr: renewal},
+		"pkcs7 as base64":                {format: "pkcs7", csr: base64.StdEncoding.EncodeToString(block.Bytes)},
+		"unknown format":                 {format: "cmc", csr: csrPEM, errors: []string{"Invalid Request Format"}},
+		"renewal without request_format": {csr: renewal, errors: []string{"Unexpected Renewal Request"}},
+		"pkcs10 as pkcs7":                {format: "pkcs7", csr: csrPEM, errors: []string{"Invalid Renewal Request"}},
+		"forged signature": {
+			format: "pkcs7",
+			csr:    string(pem.EncodeToMemory(&pem.Block{Type: "PKCS7", Bytes: forged})),
+			errors: []string{"Invalid Renewal Request"},
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			config := certificateCreateModel{
+				CSR:           types.StringValue(tc.csr),
+				RequestFormat: types.StringNull(),
+				GenerateCSR:   types.ObjectNull(generateCSRAttrTypes()),
+			}
+			if tc.format != "" {
+				config.RequestFormat = types.StringValue(tc.format)
+			}
+			diags := validateRequestFormat(config)
+			var got []string
+			for _, d := range diags.Errors() {
+				got = append(got, d.Summary())
+			}
+			if strings.Join(got, ",") != strings.Join(tc.errors, ",") {
+				t.Errorf("errors = %v, want %v", got, tc.errors)
+			}
+		})
+	}
+
+	// The request inside is what the rest of the resource sees.
+	request, err := parseCertificateRequest(renewal)
+	if err != nil || request.Subject.CommonName != "app.example.com" {
+		t.Errorf("parseCertificateRequest() = %v, %v, want the certificate signing request inside", request, err)
+	}
+}
+
+func TestAccCertificateResourcePKCS7RenewalRequest(t *testing.T) {
+	srv := newAccCertsrv(t)
+	csrPEM, keyPEM := testRenewalCSR(t, srv.hierarchy.leafKey)
+	renewal, err := newRenewalRequest(csrPEM, srv.hierarchy.leaf, keyPEM)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	resource.Test(t, resource.TestCase{
+		ProtoV6ProviderFactories: testAccProtoV6ProviderFactories,
+		Steps: []resource.TestStep{
+			{
+				Config: srv.providerConfig() + fmt.Sprintf(`
+resource "microsoftadcs_certificate" "test" {
+  template                    = "WebServer"
+  request_format              = "pkcs7"
+  certificate_signing_request = <<-EOT
+%sEOT
+}
+`, renewal),
+				Check: func(s *terraform.State) error {
+					req := srv.request(s.RootModule().Resources["microsoftadcs_certificate.test"].Primary.ID)
+					if req == nil || req.renews == nil || !req.renews.Equal(srv.hierarchy.leaf) {
+						return fmt.Errorf("the CA did not receive a renewal of the leaf")
+					}
+					return nil
+				},
+			},
+		},
+	})
+}
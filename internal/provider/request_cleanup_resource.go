@@ -0,0 +1,219 @@
+package provider
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/hashicorp/terraform-plugin-framework/diag"
+	"github.com/hashicorp/terraform-plugin-framework/resource"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/planmodifier"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/stringplanmodifier"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+	"github.com/hashicorp/terraform-plugin-log/tflog"
+)
+
+// Ensure the implementation satisfies the expected interfaces.
+var (
+	_ resource.Resource              = &requestCleanupResource{}
+	_ resource.ResourceWithConfigure = &requestCleanupResource{}
+)
+
+// NewRequestCleanupResource is a helper function to simplify the provider implementation.
+func NewRequestCleanupResource() resource.Resource {
+	return &requestCleanupResource{}
+}
+
+// requestCleanupResource denies pending requests through the denial webhook, typically those
+// microsoftadcs_abandoned_requests found.
+type requestCleanupResource struct {
+	provider *providerData
+}
+
+type requestCleanupModel struct {
+	ID               types.String `tfsdk:"id"`
+	RequestIDs       types.Set    `tfsdk:"request_ids"`
+	Host             types.String `tfsdk:"host"`
+	Reason           types.String `tfsdk:"reason"`
+	DeniedRequestIDs types.Set    `tfsdk:"denied_request_ids"`
+}
+
+// Metadata returns the resource type name.
+func (r *requestCleanupResource) Metadata(_ context.Context, req resource.MetadataRequest, resp *resource.MetadataResponse) {
+	resp.TypeName = req.ProviderTypeName + "_request_cleanup"
+}
+
+// Schema defines the schema for the resource.
+func (r *requestCleanupResource) Schema(_ context.Context, _ resource.SchemaRequest, resp *resource.SchemaResponse) {
+	resp.Schema = schema.Schema{
+		Description: `Denies pending certificate requests through the denial_webhook_url of the provider, to keep the queue of
+the CA clean of requests nobody is going to approve, such as those microsoftadcs_abandoned_requests finds. Every apply
+that changes request_ids denies the requests that are still pending; requests the CA issued or denied in the meantime
+are left alone. Destroying the resource does not undo the denials.`,
+		Attributes: map[string]schema.Attribute{
+			"id": schema.StringAttribute{
+				Computed:    true,
+				Description: "When the resource was created, in RFC 3339 format.",
+				PlanModifiers: []planmodifier.String{
+					stringplanmodifier.UseStateForUnknown(),
+				},
+			},
+			"request_ids": schema.SetAttribute{
+				Required:    true,
+				ElementType: types.StringType,
+				Description: "Request IDs to deny, for example request_ids of microsoftadcs_abandoned_requests.",
+			},
+			"host": schema.StringAttribute{
+				Optional: true,
+				Description: `The certsrv server the requests were submitted to, which is asked whether they are still pending.
+Defaults to the host of the provider.`,
+			},
+			"reason": schema.StringAttribute{
+				Optional:    true,
+				Description: "Reason passed on to the denial webhook, for its audit log.",
+			},
+			"denied_request_ids": schema.SetAttribute{
+				Computed:    true,
+				ElementType: types.StringType,
+				Description: "The request IDs of request_ids this resource denied.",
+			},
+		},
+	}
+}
+
+// Configure adds the provider settings to the resource.
+func (r *requestCleanupResource) Configure(_ context.Context, req resource.ConfigureRequest, resp *resource.ConfigureResponse) {
+	if req.ProviderData == nil {
+		return
+	}
+
+	data, ok := req.ProviderData.(*providerData)
+	if !ok {
+		resp.Diagnostics.AddError(
+			"Unexpected Resource Configure Type",
+			fmt.Sprintf("Expected *providerData, got: %T. Please report this issue to the provider developers.", req.ProviderData),
+		)
+
+		return
+	}
+
+	r.provider = data
+}
+
+// deny denies the requests of plan that are still pending and not denied before, and records
+// them in denied_request_ids.
+func (r *requestCleanupResource) deny(ctx context.Context, plan *requestCleanupModel, denied []string) diag.Diagnostics {
+	var diags diag.Diagnostics
+	if r.provider.readOnly {
+		diags.Append(readOnlyError("deny certificate requests"))
+		return diags
+	}
+
+	var requestIDs []string
+	diags.Append(plan.RequestIDs.ElementsAs(ctx, &requestIDs, false)...)
+	if diags.HasError() {
+		return diags
+	}
+	sort.Strings(requestIDs)
+
+	previously := map[string]bool{}
+	for _, reqID := range denied {
+		previously[reqID] = true
+	}
+	// Events name the host like those of the certificates, the configured one when host is not set.
+	host, eventHost := plan.Host.ValueString(), plan.Host.ValueString()
+	if eventHost == "" {
+		eventHost = r.provider.client.HostURL
+	}
+	result := []string{}
+	for _, reqID := range requestIDs {
+		if previously[reqID] {
+			result = append(result, reqID)
+			continue
+		}
+		pending, err := r.provider.requestPending(ctx, host, "", reqID)
+		if err != nil {
+			diags.AddError(
+				classifiedSummary("Error Reading Certificate Request", err),
+				fmt.Sprintf("Could not check whether request ID %s is still pending: %s", reqID, err.Error()),
+			)
+			return diags
+		}
+		if !pending {
+			tflog.Info(ctx, "Request is no longer pending, not denying it", map[string]interface{}{
+				"request_id": reqID,
+			})
+			continue
+		}
+
+		if err := r.provider.denyRequest(ctx, denialRequest{RequestID: reqID, Host: eventHost, Reason: plan.Reason.ValueString()}); err != nil {
+			diags.AddError(
+				"Unable to Deny Certificate Request",
+				fmt.Sprintf("Request ID %s is still pending but could not be denied: %s", reqID, err.Error()),
+			)
+			return diags
+		}
+		tflog.Info(ctx, "Denied pending certificate request", map[string]interface{}{
+			"request_id": reqID,
+		})
+		if err := r.provider.emitEvent(ctx, newLifecycleEvent(eventDenied, eventHost, reqID, "", "")); err != nil {
+			diags.AddWarning(
+				"Unable to Publish Certificate Event",
+				fmt.Sprintf("Request ID %s was denied but the %q event could not be published: %s", reqID, eventDenied, err.Error()),
+			)
+		}
+		result = append(result, reqID)
+	}
+
+	deniedValue, setDiags := types.SetValueFrom(ctx, types.StringType, result)
+	diags.Append(setDiags...)
+	plan.DeniedRequestIDs = deniedValue
+	return diags
+}
+
+// Create denies the pending requests.
+func (r *requestCleanupResource) Create(ctx context.Context, req resource.CreateRequest, resp *resource.CreateResponse) {
+	var plan requestCleanupModel
+	resp.Diagnostics.Append(req.Plan.Get(ctx, &plan)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	resp.Diagnostics.Append(r.deny(ctx, &plan, nil)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+	plan.ID = types.StringValue(time.Now().UTC().Format(time.RFC3339))
+	resp.Diagnostics.Append(resp.State.Set(ctx, plan)...)
+}
+
+// Read keeps the state, denials cannot be undone and are not read back.
+func (r *requestCleanupResource) Read(ctx context.Context, req resource.ReadRequest, resp *resource.ReadResponse) {
+}
+
+// Update denies the requests added to request_ids.
+func (r *requestCleanupResource) Update(ctx context.Context, req resource.UpdateRequest, resp *resource.UpdateResponse) {
+	var plan, state requestCleanupModel
+	resp.Diagnostics.Append(req.Plan.Get(ctx, &plan)...)
+	resp.Diagnostics.Append(req.State.Get(ctx, &state)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	var denied []string
+	resp.Diagnostics.Append(state.DeniedRequestIDs.ElementsAs(ctx, &denied, false)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+	resp.Diagnostics.Append(r.deny(ctx, &plan, denied)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+	resp.Diagnostics.Append(resp.State.Set(ctx, plan)...)
+}
+
+// Delete removes the resource from state, the requests stay denied.
+func (r *requestCleanupResource) Delete(ctx context.Context, req resource.DeleteRequest, resp *resource.DeleteResponse) {
+}
@@ -0,0 +1,69 @@
+package provider
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/flipyap/microsoft-adcs-client/client"
+	"github.com/hashicorp/terraform-plugin-framework/attr"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+)
+
+func TestRequestCleanupDeny(t *testing.T) {
+	// Request 7 is pending, 8 was denied by a CA manager in the meantime.
+	certsrv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Query().Get("ReqID") {
+		case "7":
+			fmt.Fprint(w, `The disposition message is "Taken Under Submission"`)
+		default:
+			fmt.Fprint(w, `The disposition message is "Denied by Officer"`)
+		}
+	}))
+	defer certsrv.Close()
+
+	var denials []denialRequest
+	webhook := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var denial denialRequest
+		if err := json.NewDecoder(r.Body).Decode(&denial); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		denials = append(denials, denial)
+	}))
+	defer webhook.Close()
+
+	r := &requestCleanupResource{provider: &providerData{
+		client:           &client.ADCSClient{HostURL: strings.TrimPrefix(certsrv.URL, "http://"), NtlmClient: certsrv.Client(), UseNtlm: true},
+		parser:           strictParser,
+		denialWebhookURL: webhook.URL,
+	}}
+	plan := requestCleanupModel{
+		RequestIDs: types.SetValueMust(types.StringType, []attr.Value{types.StringValue("7"), types.StringValue("8")}),
+		Reason:     types.StringValue("abandoned"),
+	}
+	if diags := r.deny(context.Background(), &plan, nil); diags.HasError() {
+		t.Fatalf("unexpected diagnostics: %v", diags)
+	}
+	if len(denials) != 1 || denials[0].RequestID != "7" || denials[0].Event != "deny" || denials[0].Reason != "abandoned" {
+		t.Errorf("denials = %+v, want only request 7", denials)
+	}
+	if want := types.SetValueMust(types.StringType, []attr.Value{types.StringValue("7")}); !plan.DeniedRequestIDs.Equal(want) {
+		t.Errorf("denied_request_ids = %v, want %v", plan.DeniedRequestIDs, want)
+	}
+
+	// Requests denied before are not denied again.
+	denials = nil
+	if diags := r.deny(context.Background(), &plan, []string{"7"}); diags.HasError() || len(denials) != 0 {
+		t.Errorf("denials = %+v, diagnostics %v, want no denials", denials, diags)
+	}
+
+	r.provider.denialWebhookURL = ""
+	if diags := r.deny(context.Background(), &plan, nil); !diags.HasError() {
+		t.Error("denying without denial_webhook_url succeeded, want an error")
+	}
+}
@@ -0,0 +1,213 @@
+package provider
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/hashicorp/terraform-plugin-framework/diag"
+	"github.com/hashicorp/terraform-plugin-framework/path"
+	"github.com/hashicorp/terraform-plugin-framework/resource"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/planmodifier"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/stringplanmodifier"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+	"github.com/hashicorp/terraform-plugin-log/tflog"
+)
+
+// Ensure the implementation satisfies the expected interfaces.
+var (
+	_ resource.Resource              = &requestDenialResource{}
+	_ resource.ResourceWithConfigure = &requestDenialResource{}
+)
+
+// NewRequestDenialResource is a helper function to simplify the provider implementation.
+func NewRequestDenialResource() resource.Resource {
+	return &requestDenialResource{}
+}
+
+// requestDenialResource denies a single pending request through the denial webhook.
+type requestDenialResource struct {
+	provider *providerData
+}
+
+type requestDenialModel struct {
+	ID          types.String `tfsdk:"id"`
+	RequestID   types.String `tfsdk:"request_id"`
+	Host        types.String `tfsdk:"host"`
+	CAName      types.String `tfsdk:"ca_name"`
+	Reason      types.String `tfsdk:"reason"`
+	Disposition types.String `tfsdk:"disposition"`
+}
+
+// Metadata returns the resource type name.
+func (r *requestDenialResource) Metadata(_ context.Context, req resource.MetadataRequest, resp *resource.MetadataResponse) {
+	resp.TypeName = req.ProviderTypeName + "_request_denial"
+}
+
+// Schema defines the schema for the resource.
+func (r *requestDenialResource) Schema(_ context.Context, _ resource.SchemaRequest, resp *resource.SchemaResponse) {
+	resp.Schema = schema.Schema{
+		Description: `Denies a pending certificate request by its request ID through the denial_webhook_url of the provider, so
+stuck or unwanted requests can be cleaned up from Terraform instead of the Certification Authority console. Creation fails
+when the CA already issued the certificate, and succeeds without calling the webhook when the request is already denied.
+Destroying the resource does not undo the denial.`,
+		Attributes: map[string]schema.Attribute{
+			"id": schema.StringAttribute{
+				Computed:    true,
+				Description: "The request ID of the denied request.",
+				PlanModifiers: []planmodifier.String{
+					stringplanmodifier.UseStateForUnknown(),
+				},
+			},
+			"request_id": schema.StringAttribute{
+				Required:    true,
+				Description: "Request ID to deny, for example id of microsoftadcs_certificate_request.",
+				PlanModifiers: []planmodifier.String{
+					stringplanmodifier.RequiresReplace(),
+				},
+			},
+			"host": schema.StringAttribute{
+				Optional: true,
+				Description: `The certsrv server the request was submitted to, which is asked whether it is still pending. Defaults
+to the host of the provider.`,
+				PlanModifiers: []planmodifier.String{
+					stringplanmodifier.RequiresReplace(),
+				},
+			},
+			"ca_name": schema.StringAttribute{
+				Optional: true,
+				Description: `Name of the CA that took the request, for example ca_name of microsoftadcs_certificate_request. With
+ca_hosts on the provider, the certsrv server of this CA is asked, and the webhook is told the CA to deny the request on.`,
+				PlanModifiers: []planmodifier.String{
+					stringplanmodifier.RequiresReplace(),
+				},
+			},
+			"reason": schema.StringAttribute{
+				Optional:    true,
+				Description: "Reason passed on to the denial webhook, for its audit log. Changing it does not deny the request again.",
+			},
+			"disposition": schema.StringAttribute{
+				Computed:    true,
+				Description: `Disposition of the request once the resource was created, "denied".`,
+				PlanModifiers: []planmodifier.String{
+					stringplanmodifier.UseStateForUnknown(),
+				},
+			},
+		},
+	}
+}
+
+// Configure adds the provider settings to the resource.
+func (r *requestDenialResource) Configure(_ context.Context, req resource.ConfigureRequest, resp *resource.ConfigureResponse) {
+	if req.ProviderData == nil {
+		return
+	}
+
+	data, ok := req.ProviderData.(*providerData)
+	if !ok {
+		resp.Diagnostics.AddError(
+			"Unexpected Resource Configure Type",
+			fmt.Sprintf("Expected *providerData, got: %T. Please report this issue to the provider developers.", req.ProviderData),
+		)
+
+		return
+	}
+
+	r.provider = data
+}
+
+// deny denies the request of plan unless the CA denied it already. Requests the CA issued or that
+// failed cannot be denied.
+func (r *requestDenialResource) deny(ctx context.Context, plan *requestDenialModel) diag.Diagnostics {
+	var diags diag.Diagnostics
+	if r.provider.readOnly {
+		diags.Append(readOnlyError("deny a certificate request"))
+		return diags
+	}
+
+	reqID := plan.RequestID.ValueString()
+	disposition, err := r.provider.currentDisposition(ctx, plan.Host.ValueString(), plan.CAName.ValueString(), reqID)
+	if err != nil {
+		diags.AddError(
+			classifiedSummary("Error Reading Certificate Request", err),
+			fmt.Sprintf("Could not check whether request ID %s is still pending: %s", reqID, err.Error()),
+		)
+		return diags
+	}
+
+	switch disposition {
+	case dispositionDenied:
+		tflog.Info(ctx, "Request is already denied", map[string]interface{}{
+			"request_id": reqID,
+		})
+	case dispositionPending:
+		// Events name the host like those of the certificates, the configured one when host is not set.
+		host := plan.Host.ValueString()
+		if host == "" {
+			host = r.provider.client.HostURL
+		}
+		denial := denialRequest{RequestID: reqID, Host: host, CAName: plan.CAName.ValueString(), Reason: plan.Reason.ValueString()}
+		if err := r.provider.denyRequest(ctx, denial); err != nil {
+			diags.AddError(
+				"Unable to Deny Certificate Request",
+				fmt.Sprintf("Request ID %s is still pending but could not be denied: %s", reqID, err.Error()),
+			)
+			return diags
+		}
+		tflog.Info(ctx, "Denied pending certificate request", map[string]interface{}{
+			"request_id": reqID,
+		})
+		event := newLifecycleEvent(eventDenied, host, reqID, "", "")
+		event.CAName = plan.CAName.ValueString()
+		if err := r.provider.emitEvent(ctx, event); err != nil {
+			diags.AddWarning(
+				"Unable to Publish Certificate Event",
+				fmt.Sprintf("Request ID %s was denied but the %q event could not be published: %s", reqID, eventDenied, err.Error()),
+			)
+		}
+	default:
+		diags.AddAttributeError(
+			path.Root("request_id"),
+			"Request Not Pending",
+			fmt.Sprintf("Request ID %s is %s, only pending requests can be denied.", reqID, disposition),
+		)
+		return diags
+	}
+
+	plan.ID = types.StringValue(reqID)
+	plan.Disposition = types.StringValue(dispositionDenied)
+	return diags
+}
+
+// Create denies the request.
+func (r *requestDenialResource) Create(ctx context.Context, req resource.CreateRequest, resp *resource.CreateResponse) {
+	var plan requestDenialModel
+	resp.Diagnostics.Append(req.Plan.Get(ctx, &plan)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	resp.Diagnostics.Append(r.deny(ctx, &plan)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+	resp.Diagnostics.Append(resp.State.Set(ctx, plan)...)
+}
+
+// Read keeps the state, a denial is final.
+func (r *requestDenialResource) Read(ctx context.Context, req resource.ReadRequest, resp *resource.ReadResponse) {
+}
+
+// Update stores the new reason, the request stays denied.
+func (r *requestDenialResource) Update(ctx context.Context, req resource.UpdateRequest, resp *resource.UpdateResponse) {
+	var plan requestDenialModel
+	resp.Diagnostics.Append(req.Plan.Get(ctx, &plan)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+	resp.Diagnostics.Append(resp.State.Set(ctx, plan)...)
+}
+
+// Delete removes the resource from state, the request stays denied.
+func (r *requestDenialResource) Delete(ctx context.Context, req resource.DeleteRequest, resp *resource.DeleteResponse) {
+}
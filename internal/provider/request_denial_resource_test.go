@@ -0,0 +1,68 @@
+package provider
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/flipyap/microsoft-adcs-client/client"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+)
+
+func TestRequestDenialDeny(t *testing.T) {
+	// Request 7 is pending, 8 was denied by a CA manager and 9 failed.
+	certsrv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Query().Get("ReqID") {
+		case "7":
+			fmt.Fprint(w, `The disposition message is "Taken Under Submission"`)
+		case "8":
+			fmt.Fprint(w, `The disposition message is "Denied by Officer"`)
+		default:
+			fmt.Fprint(w, `The disposition message is "Error Constructing or Publishing Certificate"`)
+		}
+	}))
+	defer certsrv.Close()
+
+	var denials []denialRequest
+	webhook := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var denial denialRequest
+		if err := json.NewDecoder(r.Body).Decode(&denial); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		denials = append(denials, denial)
+	}))
+	defer webhook.Close()
+
+	r := &requestDenialResource{provider: &providerData{
+		client:           &client.ADCSClient{HostURL: strings.TrimPrefix(certsrv.URL, "http://"), NtlmClient: certsrv.Client(), UseNtlm: true},
+		parser:           strictParser,
+		denialWebhookURL: webhook.URL,
+	}}
+	plan := requestDenialModel{RequestID: types.StringValue("7"), CAName: types.StringValue("Example Issuing CA"), Reason: types.StringValue("unwanted")}
+	if diags := r.deny(context.Background(), &plan); diags.HasError() {
+		t.Fatalf("unexpected diagnostics: %v", diags)
+	}
+	if len(denials) != 1 || denials[0].RequestID != "7" || denials[0].CAName != "Example Issuing CA" || denials[0].Reason != "unwanted" {
+		t.Errorf("denials = %+v, want request 7 of Example Issuing CA", denials)
+	}
+	if plan.ID.ValueString() != "7" || plan.Disposition.ValueString() != dispositionDenied {
+		t.Errorf("deny() = %s, %s, want 7, denied", plan.ID, plan.Disposition)
+	}
+
+	// A request denied in the meantime is not denied again.
+	denials = nil
+	plan = requestDenialModel{RequestID: types.StringValue("8")}
+	if diags := r.deny(context.Background(), &plan); diags.HasError() || len(denials) != 0 || plan.Disposition.ValueString() != dispositionDenied {
+		t.Errorf("deny() = %v, denials %+v, want denied without calling the webhook", diags, denials)
+	}
+
+	plan = requestDenialModel{RequestID: types.StringValue("9")}
+	if diags := r.deny(context.Background(), &plan); !diags.HasError() || len(denials) != 0 {
+		t.Errorf("deny() of a failed request = %v, denials %+v, want an error", diags, denials)
+	}
+}
@@ -0,0 +1,92 @@
+package provider
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/hashicorp/terraform-plugin-framework/types"
+	"github.com/hashicorp/terraform-plugin-log/tflog"
+	"golang.org/x/crypto/ocsp"
+)
+
+// Further values of request_disposition on the certificate resource, next to the dispositions of
+// the certificate_request resource.
+const (
+	dispositionRevoked = "revoked"
+	dispositionErrored = "error"
+)
+
+// requestDisposition returns the request_disposition of a request whose certificate could not be
+// downloaded, and false when the CA did not answer for the request at all.
+func requestDisposition(err error) (string, bool) {
+	var disposition *dispositionError
+	switch {
+	case isStillPending(err):
+		return dispositionPending, true
+	case !errors.As(err, &disposition) || isTransientDisposition(err):
+		return "", false
+	}
+	message := strings.ToLower(disposition.message)
+	switch {
+	case strings.Contains(message, "revoked"):
+		return dispositionRevoked, true
+	case strings.Contains(message, "denied"):
+		return dispositionDenied, true
+	}
+	return dispositionErrored, true
+}
+
+// hasNoCertificate reports whether retrieving the certificate of a request failed because of the
+// request itself: it is pending, was denied or failed, or the CA database does not hold it. Refused
+// credentials, an unreachable CA or a page that could not be read say nothing about the request.
+func hasNoCertificate(err error) bool {
+	switch errorClass(classifyError(err)) {
+	case errorClassAuth, errorClassTransient, errorClassParse:
+		return false
+	}
+	_, decided := requestDisposition(err)
+	return decided
+}
+
+// revocationStatus asks the OCSP responder named in the certificate whether it was revoked. It
+// returns nil when the certificate has no responder, its issuer is not known or the responder
+// cannot be reached, a refresh should not fail because of it.
+func revocationStatus(ctx context.Context, certificatePEM string, issuerPEM types.String) *ocsp.Response {
+	cert, err := parseCertificate(certificatePEM)
+	if err != nil || len(cert.OCSPServer) == 0 || issuerPEM.IsNull() {
+		return nil
+	}
+	issuer, err := parseCertificate(issuerPEM.ValueString())
+	if err != nil {
+		return nil
+	}
+	status, err := queryOCSP(ctx, cert.OCSPServer[0], cert, issuer)
+	if err != nil {
+		tflog.Debug(ctx, "Could not check the revocation status of the certificate", map[string]interface{}{
+			"ocsp_url": cert.OCSPServer[0],
+			"error":    err.Error(),
+		})
+		return nil
+	}
+	return status
+}
+
+// setRevocationStatus sets revocation_status, revoked_reason and revocation_date to the answer of
+// the OCSP responder, null when there is none, and request_disposition to revoked for a revoked
+// certificate.
+func (m *certificateCreateModel) setRevocationStatus(status *ocsp.Response) {
+	m.RevocationStatus = types.StringNull()
+	m.RevokedReason = types.StringNull()
+	m.RevocationDate = types.StringNull()
+	if status == nil {
+		return
+	}
+	m.RevocationStatus = types.StringValue(ocspStatuses[status.Status])
+	if status.Status == ocsp.Revoked {
+		m.RequestDisposition = types.StringValue(dispositionRevoked)
+		m.RevokedReason = types.StringValue(revocationReasonName(status.RevocationReason))
+		m.RevocationDate = types.StringValue(status.RevokedAt.UTC().Format(time.RFC3339))
+	}
+}
@@ -0,0 +1,149 @@
+package provider
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"errors"
+	"fmt"
+	"math/big"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/terraform-plugin-framework/types"
+	"github.com/hashicorp/terraform-plugin-testing/helper/resource"
+	"golang.org/x/crypto/ocsp"
+)
+
+func TestRequestDisposition(t *testing.T) {
+	for name, tc := range map[string]struct {
+		err  error
+		want string
+	}{
+		"pending":     {err: errors.New("certificate pending for request id 42"), want: dispositionPending},
+		"denied":      {err: fmt.Errorf("failed to download certificate: %w", &dispositionError{message: "Denied by Policy Module  0x80094800"}), want: dispositionDenied},
+		"revoked":     {err: &dispositionError{message: "Revoked by CA manager"}, want: dispositionRevoked},
+		"not found":   {err: &dispositionError{message: "Request 42 was not found"}, want: dispositionErrored},
+		"unavailable": {err: &dispositionError{message: "The RPC server is unavailable. 0x800706ba"}},
+		"unreachable": {err: errors.New("error making request: dial tcp 10.0.0.2:80: connect: connection refused")},
+	} {
+		got, ok := requestDisposition(tc.err)
+		if got != tc.want || ok != (tc.want != "") {
+			t.Errorf("%s: requestDisposition() = %q, %t, want %q", name, got, ok, tc.want)
+		}
+	}
+}
+
+func TestHasNoCertificate(t *testing.T) {
+	for name, tc := range map[string]struct {
+		err  error
+		want bool
+	}{
+		"pending":      {err: errors.New("certificate pending for request id 42"), want: true},
+		"denied":       {err: fmt.Errorf("failed to download certificate: %w", &dispositionError{message: "Denied by Policy Module  0x80094800"}), want: true},
+		"not found":    {err: fmt.Errorf("failed to download certificate: %w", &dispositionError{message: "Request 42 was not found"}), want: true},
+		"unauthorized": {err: errors.New("failed to download certificate: status error: 401")},
+		"unavailable":  {err: &dispositionError{message: "The RPC server is unavailable. 0x800706ba"}},
+		"unreachable":  {err: errors.New("error making request: dial tcp 10.0.0.2:80: connect: connection refused")},
+		"unreadable":   {err: &ParseError{Err: errors.New(`unexpected content type "text/html"`)}},
+	} {
+		if got := hasNoCertificate(tc.err); got != tc.want {
+			t.Errorf("%s: hasNoCertificate() = %t, want %t", name, got, tc.want)
+		}
+	}
+}
+
+func TestRevocationDisposition(t *testing.T) {
+	h := newTestHierarchy(t)
+	revokedAt := time.Now().Add(-time.Hour).Truncate(time.Second)
+	responder := newTestOCSPResponder(t, h, ocsp.Response{
+		Status:           ocsp.Revoked,
+		RevokedAt:        revokedAt,
+		RevocationReason: ocsp.KeyCompromise,
+		ThisUpdate:       time.Now().Add(-time.Minute),
+	})
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	der, err := x509.CreateCertificate(rand.Reader, &x509.Certificate{
+		SerialNumber: big.NewInt(42),
+		Subject:      pkix.Name{CommonName: "app.example.com"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		OCSPServer:   []string{responder.URL},
+	}, h.issuing, &key.PublicKey, h.issuingKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+
+	var model certificateCreateModel
+	model.setRevocationStatus(revocationStatus(ctx, encodePEM(cert), types.StringValue(encodePEM(h.issuing))))
+	if model.RequestDisposition.ValueString() != dispositionRevoked || model.RevocationStatus.ValueString() != "revoked" {
+		t.Errorf("setRevocationStatus() = %s, %s, want revoked", model.RequestDisposition, model.RevocationStatus)
+	}
+	if model.RevokedReason.ValueString() != "key_compromise" || model.RevocationDate.ValueString() != revokedAt.UTC().Format(time.RFC3339) {
+		t.Errorf("setRevocationStatus() = %s at %s, want key_compromise at %s", model.RevokedReason, model.RevocationDate, revokedAt)
+	}
+	if status := revocationStatus(ctx, encodePEM(cert), types.StringNull()); status != nil {
+		t.Errorf("revocationStatus() without the issuer = %v, want nil", status)
+	}
+	if status := revocationStatus(ctx, encodePEM(h.leaf), types.StringValue(encodePEM(h.issuing))); status != nil {
+		t.Errorf("revocationStatus() without an OCSP responder = %v, want nil", status)
+	}
+	// A response signed by another CA does not make the certificate revoked.
+	if status := revocationStatus(ctx, encodePEM(cert), types.StringValue(encodePEM(h.root))); status != nil {
+		t.Errorf("revocationStatus() with an invalid response = %v, want nil", status)
+	}
+	model.setRevocationStatus(nil)
+	if !model.RevocationStatus.IsNull() || !model.RevokedReason.IsNull() || !model.RevocationDate.IsNull() {
+		t.Errorf("setRevocationStatus(nil) = %s, %s, %s, want null", model.RevocationStatus, model.RevokedReason, model.RevocationDate)
+	}
+}
+
+func TestAccCertificateResourceRequestDisposition(t *testing.T) {
+	srv := newAccCertsrv(t)
+	config := srv.providerConfig() + `
+resource "microsoftadcs_certificate" "test" {
+  template = "WebServer"
+
+  generate_csr {
+    common_name = "web.example.com"
+  }
+}
+`
+
+	resource.Test(t, resource.TestCase{
+		ProtoV6ProviderFactories: testAccProtoV6ProviderFactories,
+		Steps: []resource.TestStep{
+			{
+				Config: config,
+				Check:  resource.TestCheckResourceAttr("microsoftadcs_certificate.test", "request_disposition", dispositionIssued),
+			},
+			// The CA losing the request is reported instead of failing the refresh.
+			{
+				PreConfig: func() {
+					srv.mu.Lock()
+					defer srv.mu.Unlock()
+					for reqID := range srv.requests {
+						delete(srv.requests, reqID)
+					}
+				},
+				RefreshState: true,
+				Check: resource.ComposeAggregateTestCheckFunc(
+					resource.TestCheckResourceAttr("microsoftadcs_certificate.test", "request_disposition", dispositionErrored),
+					resource.TestMatchResourceAttr("microsoftadcs_certificate.test", "disposition_message", regexp.MustCompile(`was not found`)),
+				),
+			},
+		},
+	})
+}
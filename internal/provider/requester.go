@@ -0,0 +1,54 @@
+package provider
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/hashicorp/terraform-plugin-framework/diag"
+	"github.com/hashicorp/terraform-plugin-framework/path"
+)
+
+// requesterNameAttribute is the request attribute recording a request in the CA database under
+// another account than the one submitting it. The CA only accepts it from submitters it grants
+// the right to request on behalf of others, and denies the request otherwise.
+const requesterNameAttribute = "RequesterName"
+
+// requesterNameRequestAttribute returns the RequesterName request attribute for requester_name,
+// empty when it is not set.
+func (m *certificateCreateModel) requesterNameRequestAttribute() string {
+	if m.RequesterName.IsNull() || m.RequesterName.IsUnknown() {
+		return ""
+	}
+	return requesterNameAttribute + ":" + m.RequesterName.ValueString()
+}
+
+// validateRequesterName checks that requester_name is a DOMAIN\user account name, the form the CA
+// database records requesters in, and that no request attribute sets the requester as well.
+func validateRequesterName(config certificateCreateModel) diag.Diagnostics {
+	var diags diag.Diagnostics
+	if config.RequesterName.IsNull() || config.RequesterName.IsUnknown() {
+		return diags
+	}
+
+	name := config.RequesterName.ValueString()
+	if !isAccountName(name) {
+		diags.AddAttributeError(path.Root("requester_name"), "Invalid Requester Name",
+			fmt.Sprintf("requester_name %q is not an account name in the DOMAIN\\user form, such as \"EXAMPLE\\\\jdoe\".", name))
+	}
+
+	if attributes := config.requestAttributes(); !attributes.IsUnknown() {
+		for _, attribute := range splitAttributes(attributes.ValueString()) {
+			if attributeName(attribute) == strings.ToLower(requesterNameAttribute) {
+				diags.AddAttributeError(path.Root("requester_name"), "Conflicting Requester Name",
+					"Set the requester either in the RequesterName entry of request_attributes or in requester_name.")
+			}
+		}
+	}
+	return diags
+}
+
+// isAccountName reports whether name is an account name in the DOMAIN\user form.
+func isAccountName(name string) bool {
+	domain, user, ok := strings.Cut(name, `\`)
+	return ok && domain != "" && user != "" && !strings.ContainsAny(user, "\\\r\n") && !strings.ContainsAny(domain, "\r\n")
+}
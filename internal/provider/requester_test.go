@@ -0,0 +1,58 @@
+package provider
+
+import (
+	"context"
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-framework/types"
+)
+
+func TestRequesterNameRequestAttribute(t *testing.T) {
+	model := certificateCreateModel{
+		RequestAttributes: types.StringValue("Owner:platform"),
+		RequesterName:     types.StringValue(`EXAMPLE\jdoe`),
+	}
+	attributes, diags := model.submittedAttributes(context.Background())
+	if diags.HasError() {
+		t.Fatalf("unexpected diagnostics: %v", diags)
+	}
+	if want := "Owner:platform\nRequesterName:EXAMPLE\\jdoe"; attributes != want {
+		t.Errorf("submittedAttributes() = %q, want %q", attributes, want)
+	}
+
+	if got := (&certificateCreateModel{}).requesterNameRequestAttribute(); got != "" {
+		t.Errorf("requesterNameRequestAttribute() = %q without requester_name", got)
+	}
+}
+
+func TestValidateRequesterName(t *testing.T) {
+	cases := map[string]struct {
+		name       string
+		attributes string
+		errors     int
+	}{
+		"none":                {},
+		"valid":               {name: `EXAMPLE\jdoe`},
+		"user principal name": {name: "jdoe@example.com", errors: 1},
+		"no domain":           {name: `\jdoe`, errors: 1},
+		"no user":             {name: `EXAMPLE\`, errors: 1},
+		"nested":              {name: `EXAMPLE\ops\jdoe`, errors: 1},
+		"in request_attributes": {
+			name:       `EXAMPLE\jdoe`,
+			attributes: "Owner:platform\nrequestername:EXAMPLE\\svc-terraform",
+			errors:     1,
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			config := certificateCreateModel{RequestAttributes: types.StringValue(tc.attributes)}
+			if tc.name != "" {
+				config.RequesterName = types.StringValue(tc.name)
+			}
+			if diags := validateRequesterName(config); diags.ErrorsCount() != tc.errors {
+				t.Errorf("got %d errors, want %d: %v", diags.ErrorsCount(), tc.errors, diags)
+			}
+		})
+	}
+}
@@ -0,0 +1,126 @@
+package provider
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"time"
+
+	"github.com/hashicorp/terraform-plugin-framework/diag"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+	"github.com/hashicorp/terraform-plugin-log/tflog"
+)
+
+// Values of require_network.
+const (
+	requireNetworkAlways    = "always"
+	requireNetworkApplyOnly = "apply_only"
+)
+
+// offlineRefreshPrivateStateKey is the private state key marking a certificate whose last refresh
+// could not reach the CA.
+const offlineRefreshPrivateStateKey = "offline_refresh"
+
+// caProbeTimeout bounds the dial that decides whether the CA can be reached.
+const caProbeTimeout = 5 * time.Second
+
+// caOffline reports whether refreshes and plans have to do without the CA and the directory:
+// require_network is apply_only and the CA host cannot be reached from where Terraform runs. The
+// framework does not tell plans from applies, so the host is dialed once per provider process.
+// Plans in a network segment without the CA stay offline, while applies and imports next to it
+// read as usual.
+func (p *providerData) caOffline(ctx context.Context) bool {
+	if p == nil || !p.applyOnly {
+		return false
+	}
+	p.probeOnce.Do(func() {
+		p.offline = !caReachable(ctx, p.client.HostURL, p.hostAliases)
+		if p.offline {
+			tflog.Info(ctx, "require_network is apply_only and the CA cannot be reached, the CA is not contacted", map[string]interface{}{
+				"host": p.client.HostURL,
+			})
+		}
+	})
+	return p.offline
+}
+
+// caReachable dials the certsrv host, through its host_aliases entry if it has one. Hosts without
+// a port are served over http, as the certsrv requests are.
+func caReachable(ctx context.Context, host string, aliases map[string]string) bool {
+	if _, _, err := net.SplitHostPort(host); err != nil {
+		host = net.JoinHostPort(host, "80")
+	}
+	ctx, cancel := context.WithTimeout(ctx, caProbeTimeout)
+	defer cancel()
+	conn, err := aliasDialContext(aliases)(ctx, "tcp", host)
+	if err != nil {
+		return false
+	}
+	conn.Close()
+	return true
+}
+
+// applyOnlyError is the diagnostic for data sources refused because of require_network.
+func applyOnlyError(operation string) diag.Diagnostic {
+	return diag.NewErrorDiagnostic(
+		"CA Not Reachable",
+		"The provider is configured with require_network = \"apply_only\" and cannot reach the CA to "+operation+". "+
+			"Terraform reads data sources while planning unless they depend on a resource with planned changes. Add depends_on "+
+			"on such a resource to read it during the apply, or read the value from a resource instead.",
+	)
+}
+
+// refreshedOffline reports whether the last refresh of the certificate could not reach the CA.
+func refreshedOffline(ctx context.Context, private privateState) (bool, diag.Diagnostics) {
+	raw, diags := private.GetKey(ctx, offlineRefreshPrivateStateKey)
+	return string(raw) == "true", diags
+}
+
+// setRefreshedOffline records whether the last refresh of the certificate could reach the CA.
+func setRefreshedOffline(ctx context.Context, private privateStateSetter, offline bool) diag.Diagnostics {
+	return private.SetKey(ctx, offlineRefreshPrivateStateKey, []byte(fmt.Sprint(offline)))
+}
+
+// refreshDisposition asks the CA for the disposition and revocation status a refresh that could
+// not reach it left unknown in the plan, the way Read sets them.
+func (r *certificateResource) refreshDisposition(ctx context.Context, plan *certificateCreateModel, state certificateCreateModel, diags *diag.Diagnostics) {
+	reqID := plan.ID.ValueString()
+	plan.RequestDisposition = state.RequestDisposition
+	plan.DispositionMessage = state.DispositionMessage
+	plan.RevocationStatus = state.RevocationStatus
+	plan.RevokedReason = state.RevokedReason
+	plan.RevocationDate = state.RevocationDate
+
+	route, routeDiags := r.newCARoute(*plan, plan.CAName.ValueString())
+	diags.Append(routeDiags...)
+	if diags.HasError() {
+		return
+	}
+	certificates, err := retrieveCertificate(withCARoute(ctx, route), r.client, r.provider.parser, reqID)
+	if err != nil {
+		if disposition, ok := requestDisposition(err); ok {
+			plan.RequestDisposition = types.StringValue(disposition)
+			plan.DispositionMessage = types.StringValue(dispositionMessage(err))
+			if disposition == dispositionRevoked {
+				plan.RevocationStatus = types.StringValue(dispositionRevoked)
+			}
+			diags.AddWarning(
+				"Certificate No Longer Issued",
+				fmt.Sprintf("ADCS did not return the certificate of request ID %s, request_disposition is now %q: %s", reqID, disposition, err.Error()),
+			)
+			return
+		}
+		diags.AddError(
+			classifiedSummary("Error Reading Certificate", err),
+			fmt.Sprintf("Could not read the disposition of request ID %s: %s", reqID, err.Error()),
+		)
+		return
+	}
+
+	plan.RequestDisposition = types.StringValue(dispositionIssued)
+	plan.DispositionMessage = types.StringValue(dispositionMessageIssued)
+	plan.setRevocationStatus(nil)
+	if r.provider.aia != nil {
+		plan.setRevocationStatus(revocationStatus(ctx, certificates.CertificateB64, plan.IssuingCAPEM))
+	}
+}
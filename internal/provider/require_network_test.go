@@ -0,0 +1,196 @@
+package provider
+
+import (
+	"context"
+	"io"
+	"net"
+	"regexp"
+	"testing"
+
+	"github.com/flipyap/microsoft-adcs-client/client"
+	"github.com/hashicorp/terraform-plugin-framework/datasource"
+	"github.com/hashicorp/terraform-plugin-framework/diag"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+	"github.com/hashicorp/terraform-plugin-testing/helper/resource"
+)
+
+func TestRequireNetwork(t *testing.T) {
+	srv := newAccCertsrv(t)
+	t.Setenv("ADCS_PASSWORD", "secret")
+	ctx := context.Background()
+	for name, tc := range map[string]struct {
+		host    string
+		value   interface{}
+		offline bool
+		invalid bool
+	}{
+		"unset":                   {host: srv.host()},
+		"always":                  {host: srv.host(), value: requireNetworkAlways},
+		"always, unreachable":     {host: unreachableHost(t), value: requireNetworkAlways},
+		"apply only":              {host: srv.host(), value: requireNetworkApplyOnly},
+		"apply only, unreachable": {host: unreachableHost(t), value: requireNetworkApplyOnly, offline: true},
+		"invalid":                 {host: srv.host(), value: "never", invalid: true},
+	} {
+		data, err := debugEnrollConfigure(ctx, "test", map[string]interface{}{
+			"host":            tc.host,
+			"username":        "svc-terraform",
+			"use_ntlm":        true,
+			"require_network": tc.value,
+		}, io.Discard)
+		switch {
+		case tc.invalid && err == nil:
+			t.Errorf("%s: expected the provider configuration to fail", name)
+		case !tc.invalid && err != nil:
+			t.Errorf("%s: unexpected error: %v", name, err)
+		case !tc.invalid && data.caOffline(ctx) != tc.offline:
+			t.Errorf("%s: caOffline() = %t, want %t", name, data.caOffline(ctx), tc.offline)
+		}
+	}
+
+	var unconfigured *providerData
+	if unconfigured.caOffline(ctx) {
+		t.Error("caOffline() of an unconfigured provider should be false")
+	}
+}
+
+func TestRequireNetworkDataSources(t *testing.T) {
+	data := &providerData{client: &client.ADCSClient{HostURL: unreachableHost(t)}, applyOnly: true}
+	for name, d := range map[string]datasource.DataSourceWithConfigure{
+		"ca_chain":              &caChainDataSource{provider: data},
+		"certificate":           &certificateDataSource{provider: data},
+		"certificates":          &certificatesDataSource{provider: data},
+		"expiring_certificates": &expiringCertificatesDataSource{provider: data},
+		"ndes":                  &ndesDataSource{provider: data},
+		"ocsp_status":           &ocspStatusDataSource{provider: data},
+		"provider_info":         &providerInfoDataSource{provider: data},
+	} {
+		var resp datasource.ReadResponse
+		d.Read(context.Background(), datasource.ReadRequest{}, &resp)
+		if !resp.Diagnostics.HasError() || resp.Diagnostics.Errors()[0].Summary() != "CA Not Reachable" {
+			t.Errorf("%s: Read() = %v, want the require_network error", name, resp.Diagnostics)
+		}
+	}
+}
+
+func TestRefreshDisposition(t *testing.T) {
+	srv := newAccCertsrv(t)
+	t.Setenv("ADCS_PASSWORD", "secret")
+	ctx := withRetryPolicy(context.Background(), noRetry)
+	data, err := debugEnrollConfigure(ctx, "test", map[string]interface{}{
+		"host":            srv.host(),
+		"username":        "svc-terraform",
+		"use_ntlm":        true,
+		"require_network": requireNetworkApplyOnly,
+	}, io.Discard)
+	if err != nil {
+		t.Fatal(err)
+	}
+	r := &certificateResource{client: data.client, provider: data}
+	csr, err := debugEnrollRequest(ctx, "", "app.example.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var diags diag.Diagnostics
+	issued := r.requestCertificate(ctx, &certificateCreateModel{CSR: types.StringValue(csr), Template: types.StringValue("WebServer")}, csr, "", &diags)
+	if diags.HasError() {
+		t.Fatalf("unexpected diagnostics: %v", diags)
+	}
+
+	// The refresh of the plan could not reach the CA, the apply asks it.
+	state := certificateCreateModel{
+		ID:                 types.StringValue(issued.ID),
+		RequestDisposition: types.StringValue(dispositionPending),
+		DispositionMessage: types.StringValue(dispositionMessagePending),
+	}
+	plan := state
+	plan.RequestDisposition = types.StringUnknown()
+	plan.DispositionMessage = types.StringUnknown()
+	plan.RevocationStatus = types.StringUnknown()
+	plan.RevokedReason = types.StringUnknown()
+	plan.RevocationDate = types.StringUnknown()
+	r.refreshDisposition(ctx, &plan, state, &diags)
+	if diags.HasError() {
+		t.Fatalf("unexpected diagnostics: %v", diags)
+	}
+	if plan.RequestDisposition.ValueString() != dispositionIssued || plan.DispositionMessage.ValueString() != dispositionMessageIssued ||
+		plan.RevocationStatus.IsUnknown() || plan.RevokedReason.IsUnknown() || plan.RevocationDate.IsUnknown() {
+		t.Errorf("refreshDisposition() planned %v, %v, %v, want the issued request with known revocation outputs",
+			plan.RequestDisposition, plan.DispositionMessage, plan.RevocationStatus)
+	}
+
+	// An apply that cannot reach the CA either fails instead of keeping the state.
+	srv.server.Close()
+	plan.RequestDisposition = types.StringUnknown()
+	r.refreshDisposition(ctx, &plan, state, &diags)
+	if !diags.HasError() {
+		t.Error("refreshDisposition() without the CA should fail")
+	}
+}
+
+func TestAccCertificateResourceRequireNetworkApplyOnly(t *testing.T) {
+	srv := newAccCertsrv(t)
+	config := func(host string) string {
+		return `
+provider "microsoftadcs" {
+  host            = "` + host + `"
+  username        = "svc-terraform"
+  password        = "secret"
+  use_ntlm        = true
+  require_network = "apply_only"
+}
+
+resource "microsoftadcs_certificate" "test" {
+  template = "WebServer"
+
+  generate_csr {
+    common_name = "app.example.com"
+  }
+}
+`
+	}
+	offline := config(unreachableHost(t))
+
+	resource.Test(t, resource.TestCase{
+		ProtoV6ProviderFactories: testAccProtoV6ProviderFactories,
+		Steps: []resource.TestStep{
+			// Applies that reach the CA read it as usual.
+			{
+				Config: config(srv.host()),
+				Check: resource.ComposeAggregateTestCheckFunc(
+					resource.TestCheckResourceAttrSet("microsoftadcs_certificate.test", "certificate_pem"),
+					resource.TestCheckResourceAttr("microsoftadcs_certificate.test", "request_disposition", dispositionIssued),
+				),
+			},
+			// Plans that cannot reach it keep the state, and plan what the CA reports as unknown.
+			{
+				Config:             offline,
+				PlanOnly:           true,
+				ExpectNonEmptyPlan: true,
+			},
+			{
+				Config: offline + `
+data "microsoftadcs_ca_chain" "test" {}
+`,
+				ExpectError: regexp.MustCompile(`CA Not Reachable`),
+			},
+			// Data sources read where the CA can be reached.
+			{
+				Config: config(srv.host()) + `
+data "microsoftadcs_ca_chain" "test" {}
+`,
+				Check: resource.TestCheckResourceAttrSet("data.microsoftadcs_ca_chain.test", "certificate_chain_b64"),
+			},
+		},
+	})
+}
+
+// unreachableHost returns an address nothing listens on.
+func unreachableHost(t *testing.T) string {
+	t.Helper()
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer listener.Close()
+	return listener.Addr().String()
+}
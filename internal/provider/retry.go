@@ -0,0 +1,248 @@
+package provider
+
+import (
+	"context"
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"math/big"
+	"net/http"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/hashicorp/terraform-plugin-framework/attr"
+	"github.com/hashicorp/terraform-plugin-framework/diag"
+	"github.com/hashicorp/terraform-plugin-framework/path"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+	"github.com/hashicorp/terraform-plugin-framework/types/basetypes"
+	"github.com/hashicorp/terraform-plugin-log/tflog"
+)
+
+// retryPolicy says how often and how far apart requests to certsrv are retried after transient
+// errors, see the retry block.
+type retryPolicy struct {
+	attempts   int64
+	minBackoff time.Duration
+	maxBackoff time.Duration
+}
+
+// defaultRetryPolicy applies to requests made without a retry block, such as those of data sources.
+var defaultRetryPolicy = retryPolicy{attempts: 3, minBackoff: time.Second, maxBackoff: 30 * time.Second}
+
+// noRetry makes a single attempt, for callers that handle transient errors themselves.
+var noRetry = retryPolicy{attempts: 1}
+
+// transientDispositions are certsrv disposition messages of a CA service that cannot be reached for
+// a moment, such as while it restarts. certsrv answers them before the CA saw the request.
+var transientDispositions = []string{
+	"0x800706ba", // RPC_S_SERVER_UNAVAILABLE
+	"0x800706be", // RPC_S_CALL_FAILED
+	"rpc server is unavailable",
+	"rpc_s_server_unavailable",
+}
+
+// retryPolicyKey is the context key of the retry policy of certsrv requests.
+type retryPolicyKey struct{}
+
+// withRetryPolicy makes certsrv requests made with ctx retry along policy.
+func withRetryPolicy(ctx context.Context, policy retryPolicy) context.Context {
+	return context.WithValue(ctx, retryPolicyKey{}, policy)
+}
+
+// retryPolicyFrom returns the retry policy of ctx, the default one when none is set.
+func retryPolicyFrom(ctx context.Context) retryPolicy {
+	if policy, ok := ctx.Value(retryPolicyKey{}).(retryPolicy); ok {
+		return policy
+	}
+	return defaultRetryPolicy
+}
+
+// isTransientDisposition reports whether certsrv answered that the CA service is unavailable.
+func isTransientDisposition(err error) bool {
+	message := strings.ToLower(err.Error())
+	for _, disposition := range transientDispositions {
+		if strings.Contains(message, disposition) {
+			return true
+		}
+	}
+	return false
+}
+
+// isRetryable reports whether a download failed for a reason that may be gone on the next attempt:
+// an NTLM or Kerberos handshake the server restarted, an overloaded or restarting IIS, an
+// unavailable CA service or a dropped connection, also one only noticed because the answer never
+// came.
+func isRetryable(err error) bool {
+	// The transport's own timeout reports itself as an exceeded deadline as well, but only the
+	// request timed out and not the operation.
+	if strings.Contains(err.Error(), "timeout awaiting response headers") {
+		return true
+	}
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return false
+	}
+	switch responseStatus(err) {
+	case http.StatusUnauthorized, http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return true
+	}
+	if isTransientDisposition(err) || errors.Is(err, syscall.ECONNRESET) || errors.Is(err, syscall.ECONNREFUSED) {
+		return true
+	}
+	message := err.Error()
+	return strings.Contains(message, "connection reset") || strings.Contains(message, "connection refused") || strings.HasSuffix(message, "EOF")
+}
+
+// isRetryableSubmission reports whether a submission failed before the CA could have received the
+// request, so submitting it again cannot issue a second certificate. A connection that drops
+// while the request is under way may have delivered it, so that is not retried.
+func isRetryableSubmission(err error) bool {
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return false
+	}
+	switch responseStatus(err) {
+	case http.StatusUnauthorized, http.StatusServiceUnavailable:
+		return true
+	}
+	return isTransientDisposition(err) || errors.Is(err, syscall.ECONNREFUSED) || strings.Contains(err.Error(), "connection refused")
+}
+
+// retryTransient calls fn until it succeeds, fails with an error retryable does not accept or the
+// attempts of the retry policy of ctx are used up. The delay between attempts doubles from the
+// minimum backoff up to the maximum, each drawn at random from its upper half so that parallel
+// resources do not retry in lockstep.
+func retryTransient(ctx context.Context, operation string, retryable func(error) bool, fn func() error) error {
+	policy := retryPolicyFrom(ctx)
+	backoff := policy.minBackoff
+	for attempt := int64(1); ; attempt++ {
+		err := fn()
+		if err == nil || attempt >= policy.attempts || !retryable(err) {
+			return err
+		}
+
+		delay := jitter(backoff)
+		tflog.Warn(ctx, "Retrying after a transient error", map[string]interface{}{
+			"operation": operation,
+			"attempt":   attempt,
+			"delay":     delay.String(),
+			"error":     err.Error(),
+		})
+		select {
+		case <-ctx.Done():
+			return err
+		case <-time.After(delay):
+		}
+		if backoff *= 2; backoff > policy.maxBackoff {
+			backoff = policy.maxBackoff
+		}
+	}
+}
+
+// jitter returns a random delay between half of backoff and backoff.
+func jitter(backoff time.Duration) time.Duration {
+	if backoff <= 1 {
+		return backoff
+	}
+	n, err := rand.Int(rand.Reader, big.NewInt(int64(backoff/2)))
+	if err != nil {
+		return backoff
+	}
+	return backoff - time.Duration(n.Int64())
+}
+
+// retryAttrTypes are the attribute types of the retry block.
+var retryAttrTypes = map[string]attr.Type{
+	"max_attempts": types.Int64Type,
+	"min_backoff":  types.StringType,
+	"max_backoff":  types.StringType,
+}
+
+// retryModel maps the retry block.
+type retryModel struct {
+	MaxAttempts types.Int64  `tfsdk:"max_attempts"`
+	MinBackoff  types.String `tfsdk:"min_backoff"`
+	MaxBackoff  types.String `tfsdk:"max_backoff"`
+}
+
+// retryBlock is the retry block of the certificate resource.
+func retryBlock() schema.Block {
+	return schema.SingleNestedBlock{
+		Description: fmt.Sprintf(`How requests to certsrv are retried after transient errors: a renegotiated NTLM or Kerberos handshake,
+HTTP 502, 503 or 504, an RPC server unavailable answer of a CA that restarts and dropped connections. Submissions are only
+retried when the CA cannot have received the request. Without the block requests are tried %d times, %s to %s apart.`,
+			defaultRetryPolicy.attempts, defaultRetryPolicy.minBackoff, defaultRetryPolicy.maxBackoff),
+		Attributes: map[string]schema.Attribute{
+			"max_attempts": schema.Int64Attribute{
+				Optional:    true,
+				Description: fmt.Sprintf("How often a request is tried in total, 1 disables retries. Defaults to %d.", defaultRetryPolicy.attempts),
+			},
+			"min_backoff": schema.StringAttribute{
+				Optional:    true,
+				Description: fmt.Sprintf("Delay before the first retry, doubled for each further one. Defaults to %q.", defaultRetryPolicy.minBackoff.String()),
+			},
+			"max_backoff": schema.StringAttribute{
+				Optional:    true,
+				Description: fmt.Sprintf("Longest delay between two attempts. Defaults to %q.", defaultRetryPolicy.maxBackoff.String()),
+			},
+		},
+	}
+}
+
+// retryPolicyOf returns the policy of a retry block, ValidateConfig checks its values.
+func retryPolicyOf(ctx context.Context, block types.Object) retryPolicy {
+	policy := defaultRetryPolicy
+	if block.IsNull() || block.IsUnknown() {
+		return policy
+	}
+	var retry retryModel
+	if diags := block.As(ctx, &retry, basetypes.ObjectAsOptions{}); diags.HasError() {
+		return policy
+	}
+	if !retry.MaxAttempts.IsNull() && retry.MaxAttempts.ValueInt64() > 0 {
+		policy.attempts = retry.MaxAttempts.ValueInt64()
+	}
+	if d, err := time.ParseDuration(retry.MinBackoff.ValueString()); err == nil && d > 0 {
+		policy.minBackoff = d
+	}
+	if d, err := time.ParseDuration(retry.MaxBackoff.ValueString()); err == nil && d > 0 {
+		policy.maxBackoff = d
+	}
+	if policy.maxBackoff < policy.minBackoff {
+		policy.maxBackoff = policy.minBackoff
+	}
+	return policy
+}
+
+// validateRetry checks the values of the retry block.
+func validateRetry(ctx context.Context, block types.Object) diag.Diagnostics {
+	var diags diag.Diagnostics
+	if block.IsNull() || block.IsUnknown() {
+		return diags
+	}
+	var retry retryModel
+	diags.Append(block.As(ctx, &retry, basetypes.ObjectAsOptions{})...)
+	if diags.HasError() {
+		return diags
+	}
+	if !retry.MaxAttempts.IsNull() && !retry.MaxAttempts.IsUnknown() && retry.MaxAttempts.ValueInt64() < 1 {
+		diags.AddAttributeError(
+			path.Root("retry").AtName("max_attempts"),
+			"Invalid Retry Attempts",
+			fmt.Sprintf("retry.max_attempts must be at least 1, got %d.", retry.MaxAttempts.ValueInt64()),
+		)
+	}
+	for name, value := range map[string]types.String{"min_backoff": retry.MinBackoff, "max_backoff": retry.MaxBackoff} {
+		if value.IsNull() || value.IsUnknown() {
+			continue
+		}
+		if d, err := time.ParseDuration(value.ValueString()); err != nil || d <= 0 {
+			diags.AddAttributeError(
+				path.Root("retry").AtName(name),
+				"Invalid Retry Backoff",
+				fmt.Sprintf("retry.%s %q is not a positive duration such as \"2s\" or \"1m\".", name, value.ValueString()),
+			)
+		}
+	}
+	return diags
+}
@@ -0,0 +1,180 @@
+package provider
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/terraform-plugin-framework/attr"
+	"github.com/hashicorp/terraform-plugin-framework/diag"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+)
+
+func TestIsRetryable(t *testing.T) {
+	for _, tc := range []struct {
+		err        error
+		retryable  bool
+		submission bool
+	}{
+		{err: errors.New("error making request: status error: 401"), retryable: true, submission: true},
+		{err: errors.New("status error: 503"), retryable: true, submission: true},
+		{err: errors.New("status error: 502"), retryable: true},
+		{err: errors.New("status error: 500")},
+		{err: errors.New("status error: 404")},
+		{err: errors.New("failed to get request ID: The RPC server is unavailable. 0x800706ba (WIN32: 1722 RPC_S_SERVER_UNAVAILABLE)"), retryable: true, submission: true},
+		{err: fmt.Errorf("error making request: %w", syscall.ECONNRESET), retryable: true},
+		{err: errors.New("error making request: read tcp 10.0.0.1:51234->10.0.0.2:80: read: connection reset by peer"), retryable: true},
+		{err: errors.New("error making request: dial tcp 10.0.0.2:80: connect: connection refused"), retryable: true, submission: true},
+		{err: errors.New(`error making request: Post "http://ca/certsrv/certfnsh.asp": EOF`), retryable: true},
+		{err: errors.New(`error making request: Get "http://ca/certsrv/certnew.cer": net/http: timeout awaiting response headers`), retryable: true},
+		{err: errors.New("certificate pending for request id 7")},
+		{err: errors.New("failed to get request ID: Denied by Policy Module  0x80094800")},
+		{err: fmt.Errorf("error making request: %w", context.DeadlineExceeded)},
+	} {
+		if got := isRetryable(tc.err); got != tc.retryable {
+			t.Errorf("isRetryable(%q) = %t, want %t", tc.err, got, tc.retryable)
+		}
+		if got := isRetryableSubmission(tc.err); got != tc.submission {
+			t.Errorf("isRetryableSubmission(%q) = %t, want %t", tc.err, got, tc.submission)
+		}
+	}
+}
+
+func TestRetryTransient(t *testing.T) {
+	ctx := withRetryPolicy(context.Background(), retryPolicy{attempts: 3, minBackoff: time.Millisecond, maxBackoff: 2 * time.Millisecond})
+	transient := errors.New("status error: 503")
+
+	calls := 0
+	err := retryTransient(ctx, "test", isRetryable, func() error {
+		if calls++; calls < 3 {
+			return transient
+		}
+		return nil
+	})
+	if err != nil || calls != 3 {
+		t.Errorf("retryTransient() = %v after %d calls, want success on the third", err, calls)
+	}
+
+	calls = 0
+	err = retryTransient(ctx, "test", isRetryable, func() error { calls++; return transient })
+	if err != transient || calls != 3 {
+		t.Errorf("retryTransient() = %v after %d calls, want the error after 3 attempts", err, calls)
+	}
+
+	calls = 0
+	denied := errors.New("Denied by Policy Module")
+	err = retryTransient(ctx, "test", isRetryable, func() error { calls++; return denied })
+	if err != denied || calls != 1 {
+		t.Errorf("retryTransient() = %v after %d calls, want no retry of permanent errors", err, calls)
+	}
+
+	cancelled, cancel := context.WithCancel(withRetryPolicy(context.Background(), retryPolicy{attempts: 5, minBackoff: time.Hour, maxBackoff: time.Hour}))
+	calls = 0
+	go func() {
+		time.Sleep(10 * time.Millisecond)
+		cancel()
+	}()
+	if err := retryTransient(cancelled, "test", isRetryable, func() error { calls++; return transient }); err != transient || calls != 1 {
+		t.Errorf("retryTransient() = %v after %d calls, want the wait cut short by the context", err, calls)
+	}
+
+	for i := 0; i < 100; i++ {
+		if d := jitter(time.Second); d < 500*time.Millisecond || d > time.Second {
+			t.Fatalf("jitter(1s) = %s", d)
+		}
+	}
+}
+
+func TestRetryPolicyOf(t *testing.T) {
+	block := func(attempts attr.Value, min string, max string) types.Object {
+		value := func(s string) attr.Value {
+			if s == "" {
+				return types.StringNull()
+			}
+			return types.StringValue(s)
+		}
+		return types.ObjectValueMust(retryAttrTypes, map[string]attr.Value{"max_attempts": attempts, "min_backoff": value(min), "max_backoff": value(max)})
+	}
+
+	if got := retryPolicyOf(context.Background(), types.ObjectNull(retryAttrTypes)); got != defaultRetryPolicy {
+		t.Errorf("retryPolicyOf(null) = %+v, want the default", got)
+	}
+	got := retryPolicyOf(context.Background(), block(types.Int64Value(5), "10s", "5s"))
+	if want := (retryPolicy{attempts: 5, minBackoff: 10 * time.Second, maxBackoff: 10 * time.Second}); got != want {
+		t.Errorf("retryPolicyOf() = %+v, want %+v", got, want)
+	}
+
+	if diags := validateRetry(context.Background(), block(types.Int64Value(1), "500ms", "1m")); diags.HasError() {
+		t.Errorf("unexpected diagnostics: %v", diags)
+	}
+	if diags := validateRetry(context.Background(), block(types.Int64Value(0), "soon", "")); len(diags) != 2 {
+		t.Errorf("diagnostics = %v, want max_attempts and min_backoff rejected", diags)
+	}
+}
+
+func TestSubmissionRetry(t *testing.T) {
+	srv := newAccCertsrv(t)
+	// The first submission meets an IIS that is restarting, the second a CA service that is not up yet.
+	var mu sync.Mutex
+	failures := map[string][]func(http.ResponseWriter){
+		"/certsrv/certfnsh.asp": {
+			func(w http.ResponseWriter) { http.Error(w, "Service Unavailable", http.StatusServiceUnavailable) },
+			func(w http.ResponseWriter) {
+				fmt.Fprint(w, `The disposition message is "The RPC server is unavailable. 0x800706ba (WIN32: 1722 RPC_S_SERVER_UNAVAILABLE)"`)
+			},
+		},
+		"/certsrv/certnew.cer": {
+			func(w http.ResponseWriter) { http.Error(w, "Bad Gateway", http.StatusBadGateway) },
+		},
+	}
+	srv.server.Config.Handler = func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if strings.HasPrefix(r.Header.Get("Authorization"), "NTLM ") {
+				mu.Lock()
+				pending := failures[r.URL.Path]
+				if len(pending) > 0 {
+					failures[r.URL.Path] = pending[1:]
+				}
+				mu.Unlock()
+				if len(pending) > 0 {
+					pending[0](w)
+					return
+				}
+			}
+			next.ServeHTTP(w, r)
+		})
+	}(srv.server.Config.Handler)
+
+	t.Setenv("ADCS_PASSWORD", "secret")
+	data, err := debugEnrollConfigure(context.Background(), "test", map[string]interface{}{
+		"host":     srv.host(),
+		"username": "svc-terraform",
+		"use_ntlm": true,
+	}, io.Discard)
+	if err != nil {
+		t.Fatal(err)
+	}
+	r := &certificateResource{client: data.client, provider: data}
+	csr, err := debugEnrollRequest(context.Background(), "", "app.example.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx := withRetryPolicy(context.Background(), retryPolicy{attempts: 3, minBackoff: time.Millisecond, maxBackoff: time.Millisecond})
+	plan := &certificateCreateModel{CSR: types.StringValue(csr), Template: types.StringValue("WebServer")}
+	var diags diag.Diagnostics
+	certificates := r.requestCertificate(ctx, plan, csr, "", &diags)
+	if diags.HasError() {
+		t.Fatalf("unexpected diagnostics: %v", diags)
+	}
+	if len(srv.requests) != 1 || srv.request(certificates.ID).cert == nil {
+		t.Errorf("the CA holds %d requests, want the retried submission issued once", len(srv.requests))
+	}
+}
@@ -0,0 +1,80 @@
+package provider
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// defaultRevocationReason is used when revoke_on_destroy is set without a revocation_reason.
+const defaultRevocationReason = "unspecified"
+
+// revocationReasons maps revocation_reason to the CRLReason code of RFC 5280, which is also the
+// reason argument of certutil -revoke and ICertAdmin::RevokeCertificate.
+var revocationReasons = map[string]int{
+	"unspecified":            0,
+	"key_compromise":         1,
+	"ca_compromise":          2,
+	"affiliation_changed":    3,
+	"superseded":             4,
+	"cessation_of_operation": 5,
+	"certificate_hold":       6,
+}
+
+// revocationReasonNames lists the accepted revocation_reason values for error messages.
+func revocationReasonNames() string {
+	names := make([]string, 0, len(revocationReasons))
+	for name := range revocationReasons {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return strings.Join(names, ", ")
+}
+
+// revocationRequest is the JSON body posted to the revocation webhook. The web enrollment pages
+// cannot revoke certificates, so the webhook has to do it through the CA administration interface,
+// for example by running certutil -revoke <serial_number> <reason_code> on the CA.
+type revocationRequest struct {
+	Event        string `json:"event"`
+	RequestID    string `json:"request_id"`
+	SerialNumber string `json:"serial_number"`
+	Subject      string `json:"subject"`
+	Template     string `json:"template"`
+	Host         string `json:"host"`
+	Reason       string `json:"reason"`
+	ReasonCode   int    `json:"reason_code"`
+}
+
+// newRevocationRequest builds the revocation of the certificate in state with the given reason.
+func newRevocationRequest(host string, reqID string, template string, certB64 string, reason string) (revocationRequest, error) {
+	if reason == "" {
+		reason = defaultRevocationReason
+	}
+	code, ok := revocationReasons[reason]
+	if !ok {
+		return revocationRequest{}, fmt.Errorf("unknown revocation reason %q, expected one of: %s", reason, revocationReasonNames())
+	}
+	cert, err := parseCertificate(certB64)
+	if err != nil {
+		return revocationRequest{}, fmt.Errorf("could not read the serial number of the certificate: %v", err)
+	}
+	return revocationRequest{
+		Event:        "revoke",
+		RequestID:    reqID,
+		SerialNumber: fmt.Sprintf("%x", cert.SerialNumber),
+		Subject:      cert.Subject.String(),
+		Template:     template,
+		Host:         host,
+		Reason:       reason,
+		ReasonCode:   code,
+	}, nil
+}
+
+// revokeCertificate asks the configured revocation webhook to revoke the certificate on the CA.
+func (p *providerData) revokeCertificate(ctx context.Context, payload revocationRequest) error {
+	if p.revocationWebhookURL == "" {
+		return fmt.Errorf("revoke_on_destroy requires revocation_webhook_url to be configured on the provider")
+	}
+	return postWebhook(ctx, p.revocationWebhookURL, p.revocationWebhookHeaders, payload)
+}
@@ -0,0 +1,73 @@
+package provider
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestRevokeCertificate(t *testing.T) {
+	h := newTestHierarchy(t)
+	certificates := h.testCertificates(t)
+
+	var received revocationRequest
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer token" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		if err := json.NewDecoder(r.Body).Decode(&received); err != nil {
+			t.Errorf("could not decode revocation request: %v", err)
+		}
+	}))
+	defer server.Close()
+
+	payload, err := newRevocationRequest("ca.example.com", certificates.ID, "WebServer", certificates.CertificateB64, "superseded")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	p := &providerData{revocationWebhookURL: server.URL, revocationWebhookHeaders: map[string]string{"Authorization": "Bearer token"}}
+	if err := p.revokeCertificate(context.Background(), payload); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if received.SerialNumber != fmt.Sprintf("%x", h.leaf.SerialNumber) {
+		t.Errorf("serial_number = %q, want %x", received.SerialNumber, h.leaf.SerialNumber)
+	}
+	if received.Reason != "superseded" || received.ReasonCode != 4 {
+		t.Errorf("reason = %q (%d), want superseded (4)", received.Reason, received.ReasonCode)
+	}
+	if received.RequestID != "42" || received.Subject != "CN=app.example.com" {
+		t.Errorf("request_id = %q, subject = %q", received.RequestID, received.Subject)
+	}
+
+	p.revocationWebhookHeaders = nil
+	if err := p.revokeCertificate(context.Background(), payload); err == nil {
+		t.Error("expected an error when the webhook rejects the revocation")
+	}
+	if err := (&providerData{}).revokeCertificate(context.Background(), payload); err == nil {
+		t.Error("expected an error without revocation_webhook_url")
+	}
+}
+
+func TestNewRevocationRequest(t *testing.T) {
+	certificates := newTestHierarchy(t).testCertificates(t)
+
+	payload, err := newRevocationRequest("ca.example.com", certificates.ID, "WebServer", certificates.CertificateB64, "")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if payload.Reason != defaultRevocationReason || payload.ReasonCode != 0 {
+		t.Errorf("reason = %q (%d), want the default", payload.Reason, payload.ReasonCode)
+	}
+
+	if _, err := newRevocationRequest("ca.example.com", certificates.ID, "WebServer", certificates.CertificateB64, "lost"); err == nil {
+		t.Error("expected an error for an unknown reason")
+	}
+	if _, err := newRevocationRequest("ca.example.com", certificates.ID, "WebServer", "not a certificate", "superseded"); err == nil {
+		t.Error("expected an error for a certificate that cannot be parsed")
+	}
+}
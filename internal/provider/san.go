@@ -0,0 +1,550 @@
+package provider
+
+import (
+	"context"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"fmt"
+	"net"
+	"net/url"
+	"regexp"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/hashicorp/terraform-plugin-framework/attr"
+	"github.com/hashicorp/terraform-plugin-framework/diag"
+	"github.com/hashicorp/terraform-plugin-framework/path"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+	"github.com/hashicorp/terraform-plugin-framework/types/basetypes"
+)
+
+var (
+	oidExtensionSubjectAltName = asn1.ObjectIdentifier{2, 5, 29, 17}
+	oidUserPrincipalName       = asn1.ObjectIdentifier{1, 3, 6, 1, 4, 1, 311, 20, 2, 3}
+)
+
+// maxSANAttributeLength is the longest san request attribute value the CA keeps. Request attribute
+// values are stored in a 4096 character column of the CA database; a longer value is cut off and
+// the names past the cut are silently left out of the certificate.
+const maxSANAttributeLength = 4096
+
+// subjectAlternativeNames holds the names a request asks ADCS to put in the certificate.
+type subjectAlternativeNames struct {
+	DNSNames []string
+	UPNs     []string
+}
+
+// requestedSubjectAlternativeNames collects the DNS and UPN names requested either through the
+// CSR's subjectAltName extension or through a "san:" request attribute.
+func requestedSubjectAlternativeNames(csr string, attributes string) (subjectAlternativeNames, error) {
+	var names subjectAlternativeNames
+
+	if csr != "" {
+		request, err := parseCertificateRequest(csr)
+		if err != nil {
+			return names, fmt.Errorf("could not parse certificate signing request: %v", err)
+		}
+		upns, err := userPrincipalNames(request.Extensions)
+		if err != nil {
+			return names, fmt.Errorf("could not parse subject alternative names of certificate signing request: %v", err)
+		}
+		names.DNSNames = append(names.DNSNames, request.DNSNames...)
+		names.UPNs = append(names.UPNs, upns...)
+	}
+
+	attrNames, err := attributeSubjectAlternativeNames(attributes)
+	if err != nil {
+		return names, err
+	}
+	names.DNSNames = append(names.DNSNames, attrNames.DNSNames...)
+	names.UPNs = append(names.UPNs, attrNames.UPNs...)
+
+	return names, nil
+}
+
+// attributeSubjectAlternativeNames parses request attributes of the form
+// "san:dns=a.example.com&dns=b.example.com&upn=user@example.com", one attribute per line.
+func attributeSubjectAlternativeNames(attributes string) (subjectAlternativeNames, error) {
+	var names subjectAlternativeNames
+
+	for _, line := range strings.Split(strings.ReplaceAll(attributes, "\r\n", "\n"), "\n") {
+		name, value, found := strings.Cut(strings.TrimSpace(line), ":")
+		if !found || !strings.EqualFold(strings.TrimSpace(name), "san") {
+			continue
+		}
+		entries, err := url.ParseQuery(strings.TrimSpace(value))
+		if err != nil {
+			return names, fmt.Errorf("could not parse san request attribute %q: %v", value, err)
+		}
+		for key, values := range entries {
+			switch strings.ToLower(key) {
+			case "dns":
+				names.DNSNames = append(names.DNSNames, values...)
+			case "upn":
+				names.UPNs = append(names.UPNs, values...)
+			}
+		}
+	}
+
+	return names, nil
+}
+
+// subjectAlternativeNamesModel maps the subject_alternative_names block.
+type subjectAlternativeNamesModel struct {
+	DNS   types.List `tfsdk:"dns"`
+	IP    types.List `tfsdk:"ip"`
+	Email types.List `tfsdk:"email"`
+	UPN   types.List `tfsdk:"upn"`
+	URI   types.List `tfsdk:"uri"`
+}
+
+var subjectAlternativeNamesAttrTypes = map[string]attr.Type{
+	"dns":   types.ListType{ElemType: types.StringType},
+	"ip":    types.ListType{ElemType: types.StringType},
+	"email": types.ListType{ElemType: types.StringType},
+	"upn":   types.ListType{ElemType: types.StringType},
+	"uri":   types.ListType{ElemType: types.StringType},
+}
+
+// typedSubjectAlternativeNames maps the top-level name lists to the entries of the
+// subject_alternative_names block they stand for.
+var typedSubjectAlternativeNames = map[string]string{
+	"dns":   "dns_names",
+	"ip":    "ip_addresses",
+	"email": "emails",
+	"upn":   "upns",
+	"uri":   "uris",
+}
+
+// typedNames returns the top-level name lists keyed by the entry of the subject_alternative_names
+// block they stand for.
+func (m *certificateCreateModel) typedNames() map[string]types.List {
+	return map[string]types.List{
+		"dns":   m.DNSNames,
+		"ip":    m.IPAddresses,
+		"email": m.Emails,
+		"upn":   m.UPNs,
+		"uri":   m.URIs,
+	}
+}
+
+// hasTypedNames reports whether any of dns_names, ip_addresses, emails, upns or uris is set.
+func (m *certificateCreateModel) hasTypedNames() bool {
+	for _, list := range m.typedNames() {
+		if !list.IsNull() {
+			return true
+		}
+	}
+	return false
+}
+
+// subjectAlternativeNames returns the subject_alternative_names block, or a block built from
+// dns_names, ip_addresses, emails, upns and uris when those are set instead, with the names of the
+// domain_controller block added. The block is unknown while any of the lists is.
+func (m *certificateCreateModel) subjectAlternativeNames() types.Object {
+	return m.withDomainControllerNames(m.configuredSubjectAlternativeNames())
+}
+
+// configuredSubjectAlternativeNames returns the subject_alternative_names block or the block built
+// from the top-level name lists.
+func (m *certificateCreateModel) configuredSubjectAlternativeNames() types.Object {
+	if !m.SubjectAlternativeNames.IsNull() || !m.hasTypedNames() {
+		return m.SubjectAlternativeNames
+	}
+	values := map[string]attr.Value{}
+	for entry, list := range m.typedNames() {
+		if list.IsUnknown() {
+			return types.ObjectUnknown(subjectAlternativeNamesAttrTypes)
+		}
+		if list.IsNull() {
+			list = types.ListNull(types.StringType)
+		}
+		values[entry] = list
+	}
+	return types.ObjectValueMust(subjectAlternativeNamesAttrTypes, values)
+}
+
+// sanEntryPath is the path of an entry of the subject alternative names, in the block or in the
+// top-level list standing for it.
+func (m *certificateCreateModel) sanEntryPath(entry string) path.Path {
+	if m.SubjectAlternativeNames.IsNull() {
+		return path.Root(typedSubjectAlternativeNames[entry])
+	}
+	return path.Root("subject_alternative_names").AtName(entry)
+}
+
+// sanValueEscaper percent-encodes the characters that delimit entries of the san request attribute,
+// leaving the "@" and ":" of UPNs, email and IPv6 addresses readable in the CA database.
+var sanValueEscaper = strings.NewReplacer("%", "%25", "&", "%26", "=", "%3D")
+
+// sanRequestAttribute serializes the subject_alternative_names block into a san request attribute
+// such as "san:dns=a.example.com&ipaddress=10.0.0.1&url=spiffe://example.com/web". DNS names are sent in punycode and values are
+// escaped so an "&" or "=" in a name cannot start another entry. It returns an empty string when
+// the block is not set.
+func sanRequestAttribute(ctx context.Context, block types.Object) (string, diag.Diagnostics) {
+	var diags diag.Diagnostics
+	if block.IsNull() || block.IsUnknown() {
+		return "", diags
+	}
+
+	var names subjectAlternativeNamesModel
+	diags.Append(block.As(ctx, &names, basetypes.ObjectAsOptions{})...)
+	if diags.HasError() {
+		return "", diags
+	}
+
+	// ADCS calls IP address entries "ipaddress".
+	entries := []struct {
+		key  string
+		list types.List
+	}{
+		{key: "dns", list: names.DNS},
+		{key: "ipaddress", list: names.IP},
+		{key: "email", list: names.Email},
+		{key: "upn", list: names.UPN},
+		{key: "url", list: names.URI},
+	}
+	var pairs []string
+	for _, entry := range entries {
+		var values []string
+		diags.Append(entry.list.ElementsAs(ctx, &values, false)...)
+		for _, value := range values {
+			// validateSubjectAlternativeNames reports names that cannot be converted.
+			if normalized, err := normalizeDNSName(value); entry.key == "dns" && err == nil {
+				value = normalized
+			}
+			pairs = append(pairs, entry.key+"="+sanValueEscaper.Replace(value))
+		}
+	}
+	if diags.HasError() || len(pairs) == 0 {
+		return "", diags
+	}
+	return "san:" + strings.Join(pairs, "&"), diags
+}
+
+// sanAttributeLength returns how many names a "san:" request attribute holds and how many
+// characters its value is long, which has to stay within maxSANAttributeLength.
+func sanAttributeLength(attribute string) (int, int) {
+	_, value, _ := strings.Cut(attribute, ":")
+	value = strings.TrimSpace(value)
+	return strings.Count(value, "&") + 1, utf8.RuneCountInString(value)
+}
+
+// sanTooLongDetail describes a san request attribute longer than maxSANAttributeLength.
+func sanTooLongDetail(attribute string) string {
+	names, length := sanAttributeLength(attribute)
+	return fmt.Sprintf("The san request attribute holds %d names in %d characters, %d more than the %d characters the CA "+
+		"keeps of a request attribute value. The CA would cut the value off and leave the names past the limit out of "+
+		"the certificate.", names, length, length-maxSANAttributeLength, maxSANAttributeLength)
+}
+
+// sanInRequest reports whether the subject alternative names go into the certificate signing
+// request of generate_csr instead of the san request attribute san, which happens when the
+// attribute is too long for the CA. A generated request cannot hold UPNs or URIs, so names listing
+// any keep the attribute.
+func (m *certificateCreateModel) sanInRequest(ctx context.Context, san string) bool {
+	if m.GenerateCSR.IsNull() || m.GenerateCSR.IsUnknown() || san == "" {
+		return false
+	}
+	if _, length := sanAttributeLength(san); length <= maxSANAttributeLength {
+		return false
+	}
+	var names subjectAlternativeNamesModel
+	if diags := m.subjectAlternativeNames().As(ctx, &names, basetypes.ObjectAsOptions{}); diags.HasError() {
+		return false
+	}
+	return len(names.UPN.Elements()) == 0 && len(names.URI.Elements()) == 0
+}
+
+// validateSANAttributeLength checks every san request attribute against maxSANAttributeLength,
+// whether it comes from request_attributes, attributes_map or the subject_alternative_names block.
+func validateSANAttributeLength(ctx context.Context, config certificateCreateModel) diag.Diagnostics {
+	var diags diag.Diagnostics
+	const advice = " Split the names across several certificates, or put them in the certificate signing request, " +
+		"where their number is not limited."
+	tooLong := func(attribute string) bool {
+		_, length := sanAttributeLength(attribute)
+		return length > maxSANAttributeLength
+	}
+
+	if attributes := config.requestAttributes(); !attributes.IsUnknown() {
+		for _, attribute := range splitAttributes(attributes.ValueString()) {
+			if attributeName(attribute) == "san" && tooLong(attribute) {
+				diags.AddAttributeError(path.Root("request_attributes"), "Subject Alternative Names Too Long", sanTooLongDetail(attribute)+advice)
+			}
+		}
+	}
+	if !config.AttributesMap.IsNull() && !config.AttributesMap.IsUnknown() {
+		for name, element := range config.AttributesMap.Elements() {
+			value, ok := element.(types.String)
+			if !ok || value.IsUnknown() || strings.ToLower(strings.TrimSpace(name)) != "san" {
+				continue
+			}
+			if attribute := name + ":" + value.ValueString(); tooLong(attribute) {
+				diags.AddAttributeError(path.Root("attributes_map").AtMapKey(name), "Subject Alternative Names Too Long", sanTooLongDetail(attribute)+advice)
+			}
+		}
+	}
+
+	san, sanDiags := sanRequestAttribute(ctx, config.subjectAlternativeNames())
+	if sanDiags.HasError() || san == "" || !tooLong(san) {
+		return diags
+	}
+	// Point at the first of the typed name lists when the block is not used.
+	sanPath := path.Root("subject_alternative_names")
+	for _, entry := range []string{"uri", "upn", "email", "ip", "dns"} {
+		if config.SubjectAlternativeNames.IsNull() && !config.typedNames()[entry].IsNull() {
+			sanPath = config.sanEntryPath(entry)
+		}
+	}
+	if config.sanInRequest(ctx, san) {
+		diags.AddAttributeWarning(
+			sanPath,
+			"Subject Alternative Names Moved Into Request",
+			sanTooLongDetail(san)+" The names are put in the certificate signing request generate_csr creates instead, "+
+				"which the CA only uses when the template builds the subject from the request.",
+		)
+		return diags
+	}
+	diags.AddAttributeError(sanPath, "Subject Alternative Names Too Long", sanTooLongDetail(san)+advice)
+	return diags
+}
+
+// validateSubjectAlternativeNames checks the entries of the subject_alternative_names block or of
+// the top-level name lists, and that the names are not also requested through a san entry in
+// request_attributes, which ADCS would resolve by ignoring one of them.
+func validateSubjectAlternativeNames(ctx context.Context, config certificateCreateModel) diag.Diagnostics {
+	var diags diag.Diagnostics
+	if !config.SubjectAlternativeNames.IsNull() && config.hasTypedNames() {
+		diags.AddAttributeError(
+			path.Root("subject_alternative_names"),
+			"Conflicting Subject Alternative Names",
+			"Set subject alternative names either in the subject_alternative_names block or in dns_names, ip_addresses, emails, upns and uris.",
+		)
+		return diags
+	}
+	block := config.subjectAlternativeNames()
+	if block.IsNull() || block.IsUnknown() {
+		return diags
+	}
+
+	var names subjectAlternativeNamesModel
+	diags.Append(block.As(ctx, &names, basetypes.ObjectAsOptions{})...)
+	if diags.HasError() {
+		return diags
+	}
+
+	checks := []struct {
+		entry string
+		list  types.List
+		valid func(string) bool
+		want  string
+	}{
+		{entry: "ip", list: names.IP, valid: func(v string) bool { return net.ParseIP(v) != nil }, want: "an IPv4 or IPv6 address"},
+		{entry: "email", list: names.Email, valid: func(v string) bool { return strings.Contains(v, "@") }, want: "an email address"},
+		{entry: "upn", list: names.UPN, valid: func(v string) bool { return strings.Contains(v, "@") }, want: "a user principal name such as user@example.com"},
+		{entry: "uri", list: names.URI, valid: isAbsoluteURI, want: "an absolute URI such as spiffe://example.com/web"},
+	}
+	for _, check := range checks {
+		for i, element := range check.list.Elements() {
+			value, ok := element.(types.String)
+			if !ok || value.IsNull() || value.IsUnknown() || check.valid(value.ValueString()) {
+				continue
+			}
+			diags.AddAttributeError(
+				config.sanEntryPath(check.entry).AtListIndex(i),
+				"Invalid Subject Alternative Name",
+				fmt.Sprintf("%q is not %s.", value.ValueString(), check.want),
+			)
+		}
+	}
+
+	for i, element := range names.DNS.Elements() {
+		value, ok := element.(types.String)
+		if !ok || value.IsNull() || value.IsUnknown() {
+			continue
+		}
+		if _, err := normalizeDNSName(value.ValueString()); err != nil {
+			diags.AddAttributeError(
+				config.sanEntryPath("dns").AtListIndex(i),
+				"Invalid Subject Alternative Name",
+				err.Error()+".",
+			)
+		}
+	}
+
+	attributes := config.requestAttributes()
+	if attributes.IsUnknown() {
+		return diags
+	}
+	for _, attribute := range splitAttributes(attributes.ValueString()) {
+		if name, _, _ := strings.Cut(attribute, ":"); strings.EqualFold(strings.TrimSpace(name), "san") {
+			conflict := path.Root("subject_alternative_names")
+			if config.SubjectAlternativeNames.IsNull() {
+				conflict = path.Root("request_attributes")
+			}
+			diags.AddAttributeError(
+				conflict,
+				"Conflicting Subject Alternative Names",
+				"request_attributes already carries a san entry, move its names into the subject alternative names of the resource.",
+			)
+			break
+		}
+	}
+	return diags
+}
+
+// isAbsoluteURI reports whether value is a URI with a scheme, the form URI subject alternative names
+// have to take.
+func isAbsoluteURI(value string) bool {
+	uri, err := url.Parse(value)
+	return err == nil && uri.Scheme != "" && (uri.Host != "" || uri.Opaque != "" || uri.Path != "")
+}
+
+// userPrincipalNames extracts the Microsoft UPN otherName entries from a subjectAltName
+// extension. crypto/x509 skips otherName entries so they have to be decoded by hand.
+func userPrincipalNames(extensions []pkix.Extension) ([]string, error) {
+	var upns []string
+
+	for _, ext := range extensions {
+		if !ext.Id.Equal(oidExtensionSubjectAltName) {
+			continue
+		}
+
+		var generalNames asn1.RawValue
+		if _, err := asn1.Unmarshal(ext.Value, &generalNames); err != nil {
+			return nil, err
+		}
+
+		rest := generalNames.Bytes
+		for len(rest) > 0 {
+			var generalName asn1.RawValue
+			var err error
+			rest, err = asn1.Unmarshal(rest, &generalName)
+			if err != nil {
+				return nil, err
+			}
+			// otherName is [0] in the GeneralName CHOICE.
+			if generalName.Class != asn1.ClassContextSpecific || generalName.Tag != 0 {
+				continue
+			}
+
+			var otherName struct {
+				TypeID asn1.ObjectIdentifier
+				Value  asn1.RawValue
+			}
+			if _, err := asn1.UnmarshalWithParams(generalName.FullBytes, &otherName, "tag:0"); err != nil {
+				return nil, err
+			}
+			if !otherName.TypeID.Equal(oidUserPrincipalName) {
+				continue
+			}
+
+			// The value is wrapped in an explicit [0] tag.
+			var upn string
+			if _, err := asn1.Unmarshal(otherName.Value.Bytes, &upn); err != nil {
+				return nil, err
+			}
+			upns = append(upns, upn)
+		}
+	}
+
+	return upns, nil
+}
+
+// disallowedNames returns every name that does not fully match at least one of the patterns.
+func disallowedNames(names []string, patterns []*regexp.Regexp) []string {
+	var denied []string
+
+	for _, name := range names {
+		allowed := false
+		for _, pattern := range patterns {
+			if pattern.MatchString(name) {
+				allowed = true
+				break
+			}
+		}
+		if !allowed {
+			denied = append(denied, name)
+		}
+	}
+
+	return denied
+}
+
+// compileSANPattern anchors the pattern so it has to match the whole name, a partial match
+// on something like "example\.com" would otherwise let "example.com.attacker.net" through.
+func compileSANPattern(pattern string) (*regexp.Regexp, error) {
+	return regexp.Compile("^(?:" + pattern + ")$")
+}
+
+// validateAllowedSANPatterns fails when the certificate request asks for a DNS or UPN name that does
+// not match any of allowed_san_patterns.
+func validateAllowedSANPatterns(ctx context.Context, config certificateCreateModel) diag.Diagnostics {
+	var diags diag.Diagnostics
+
+	if config.AllowedSANPatterns.IsNull() || config.AllowedSANPatterns.IsUnknown() {
+		return diags
+	}
+
+	var rawPatterns []types.String
+	diags.Append(config.AllowedSANPatterns.ElementsAs(ctx, &rawPatterns, false)...)
+	if diags.HasError() {
+		return diags
+	}
+
+	var patterns []*regexp.Regexp
+	for i, rawPattern := range rawPatterns {
+		if rawPattern.IsUnknown() {
+			return diags
+		}
+		pattern, err := compileSANPattern(rawPattern.ValueString())
+		if err != nil {
+			diags.AddAttributeError(
+				path.Root("allowed_san_patterns").AtListIndex(i),
+				"Invalid Subject Alternative Name Pattern",
+				fmt.Sprintf("Could not compile %q as a regular expression: %s", rawPattern.ValueString(), err.Error()),
+			)
+			continue
+		}
+		patterns = append(patterns, pattern)
+	}
+	if diags.HasError() {
+		return diags
+	}
+
+	// The names can only be checked once everything they are derived from is known.
+	if config.CSR.IsUnknown() || config.Attributes.IsUnknown() || config.RequestAttributes.IsUnknown() ||
+		config.AttributesMap.IsUnknown() || config.subjectAlternativeNames().IsUnknown() || config.GenerateCSR.IsUnknown() {
+		return diags
+	}
+	attributes, sanDiags := config.submittedAttributes(ctx)
+	diags.Append(sanDiags...)
+	generated, generatedDiags := generatedDNSNames(ctx, config.GenerateCSR)
+	diags.Append(generatedDiags...)
+	if diags.HasError() {
+		return diags
+	}
+
+	names, err := requestedSubjectAlternativeNames(config.CSR.ValueString(), attributes)
+	if err != nil {
+		diags.AddAttributeError(
+			path.Root("certificate_signing_request"),
+			"Unable to Read Subject Alternative Names",
+			err.Error(),
+		)
+		return diags
+	}
+
+	names.DNSNames = append(names.DNSNames, generated...)
+	denied := disallowedNames(append(names.DNSNames, names.UPNs...), patterns)
+	if len(denied) > 0 {
+		diags.AddAttributeError(
+			path.Root("certificate_signing_request"),
+			"Subject Alternative Name Not Allowed",
+			fmt.Sprintf("The following requested names do not match any of allowed_san_patterns: %s", strings.Join(denied, ", ")),
+		)
+	}
+
+	return diags
+}
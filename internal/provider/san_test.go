@@ -0,0 +1,445 @@
+package provider
+
+import (
+	"context"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"reflect"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-framework/attr"
+	"github.com/hashicorp/terraform-plugin-framework/diag"
+	"github.com/hashicorp/terraform-plugin-framework/path"
+	"github.com/hashicorp/terraform-plugin-framework/resource"
+	fwschema "github.com/hashicorp/terraform-plugin-framework/resource/schema"
+	"github.com/hashicorp/terraform-plugin-framework/tfsdk"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+	"github.com/hashicorp/terraform-plugin-go/tftypes"
+)
+
+func TestRequestedSubjectAlternativeNames(t *testing.T) {
+	decodedCSR, _ := base64.StdEncoding.DecodeString(csr)
+
+	names, err := requestedSubjectAlternativeNames(string(decodedCSR), "CertificateTemplate:User\r\nsan:dns=extra.domain.com&upn=svc@domain.com")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	wantDNS := []string{"exmaple.domain.com", "localhost", "localhost4", "extra.domain.com"}
+	if !reflect.DeepEqual(names.DNSNames, wantDNS) {
+		t.Errorf("DNSNames = %v, want %v", names.DNSNames, wantDNS)
+	}
+	if !reflect.DeepEqual(names.UPNs, []string{"svc@domain.com"}) {
+		t.Errorf("UPNs = %v, want [svc@domain.com]", names.UPNs)
+	}
+}
+
+func TestUserPrincipalNames(t *testing.T) {
+	upn, _ := asn1.Marshal("user@domain.com")
+	otherName, _ := asn1.Marshal(struct {
+		TypeID asn1.ObjectIdentifier
+		Value  asn1.RawValue
+	}{oidUserPrincipalName, asn1.RawValue{Class: asn1.ClassContextSpecific, Tag: 0, IsCompound: true, Bytes: upn}})
+	// Re-tag the otherName SEQUENCE as [0] IMPLICIT.
+	otherName[0] = 0xa0
+	value, _ := asn1.Marshal(asn1.RawValue{Tag: asn1.TagSequence, Class: asn1.ClassUniversal, IsCompound: true, Bytes: otherName})
+
+	upns, err := userPrincipalNames([]pkix.Extension{{Id: oidExtensionSubjectAltName, Value: value}})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !reflect.DeepEqual(upns, []string{"user@domain.com"}) {
+		t.Errorf("upns = %v, want [user@domain.com]", upns)
+	}
+}
+
+func TestDisallowedNames(t *testing.T) {
+	pattern, err := compileSANPattern(`[a-z0-9-]+\.apps\.example\.com`)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	denied := disallowedNames([]string{"web.apps.example.com", "web.apps.example.com.evil.net", "example.com"}, []*regexp.Regexp{pattern})
+	want := []string{"web.apps.example.com.evil.net", "example.com"}
+	if !reflect.DeepEqual(denied, want) {
+		t.Errorf("denied = %v, want %v", denied, want)
+	}
+}
+
+// testSANBlock builds a subject_alternative_names block, nil entries are left unset.
+func testSANBlock(t *testing.T, entries map[string][]string) types.Object {
+	t.Helper()
+	values := map[string]attr.Value{}
+	for name := range subjectAlternativeNamesAttrTypes {
+		values[name] = types.ListNull(types.StringType)
+		if entries[name] != nil {
+			list, diags := types.ListValueFrom(context.Background(), types.StringType, entries[name])
+			if diags.HasError() {
+				t.Fatalf("could not build %s: %v", name, diags)
+			}
+			values[name] = list
+		}
+	}
+	return types.ObjectValueMust(subjectAlternativeNamesAttrTypes, values)
+}
+
+func TestSANRequestAttribute(t *testing.T) {
+	block := testSANBlock(t, map[string][]string{
+		"dns":   {"www.example.com", "odd&name=.example.com"},
+		"ip":    {"10.0.0.1", "2001:db8::1"},
+		"email": {"ops@example.com"},
+		"upn":   {"svc-app@example.com"},
+		"uri":   {"spiffe://example.com/web?tier=1&zone=a"},
+	})
+
+	got, diags := sanRequestAttribute(context.Background(), block)
+	if diags.HasError() {
+		t.Fatalf("unexpected diagnostics: %v", diags)
+	}
+	want := "san:dns=www.example.com&dns=odd%26name%3D.example.com&ipaddress=10.0.0.1&ipaddress=2001:db8::1&email=ops@example.com&upn=svc-app@example.com" +
+		"&url=spiffe://example.com/web?tier%3D1%26zone%3Da"
+	if got != want {
+		t.Errorf("sanRequestAttribute() = %q, want %q", got, want)
+	}
+
+	// The names survive the round trip through the parser allowed_san_patterns uses.
+	names, err := attributeSubjectAlternativeNames(got)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !reflect.DeepEqual(names.DNSNames, []string{"www.example.com", "odd&name=.example.com"}) {
+		t.Errorf("DNSNames = %v", names.DNSNames)
+	}
+
+	idn := testSANBlock(t, map[string][]string{"dns": {"*.Bücher.example"}})
+	if got, _ := sanRequestAttribute(context.Background(), idn); got != "san:dns=*.xn--bcher-kva.example" {
+		t.Errorf("sanRequestAttribute() = %q, want the punycode form", got)
+	}
+
+	for name, block := range map[string]types.Object{
+		"no block":    types.ObjectNull(block.AttributeTypes(context.Background())),
+		"empty block": testSANBlock(t, nil),
+	} {
+		if got, _ := sanRequestAttribute(context.Background(), block); got != "" {
+			t.Errorf("%s: sanRequestAttribute() = %q, want no attribute", name, got)
+		}
+	}
+}
+
+func TestValidateSubjectAlternativeNames(t *testing.T) {
+	cases := map[string]struct {
+		entries    map[string][]string
+		attributes string
+		errors     int
+	}{
+		"valid": {
+			entries:    map[string][]string{"dns": {"www.example.com"}, "ip": {"10.0.0.1"}, "upn": {"svc@example.com"}},
+			attributes: "ClientRequestNonce:1",
+		},
+		"invalid entries": {
+			entries: map[string][]string{"ip": {"10.0.0.256", "::1"}, "email": {"ops"}, "upn": {"svc"}, "uri": {"spiffe://example.com/web", "/web"}},
+			errors:  4,
+		},
+		"internationalized and wildcard dns": {
+			entries: map[string][]string{"dns": {"*.example.com", "bücher.example", "host_01.corp.example.com"}},
+		},
+		"invalid dns": {
+			entries: map[string][]string{"dns": {"a.*.example.com", "*.com", "bad name.example.com", "host.example.com.", "-a.example.com"}},
+			errors:  5,
+		},
+		"san in request_attributes": {
+			entries:    map[string][]string{"dns": {"www.example.com"}},
+			attributes: "SAN:dns=other.example.com",
+			errors:     1,
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			config := certificateCreateModel{
+				Attributes:              types.StringNull(),
+				RequestAttributes:       types.StringValue(tc.attributes),
+				SubjectAlternativeNames: testSANBlock(t, tc.entries),
+			}
+			if diags := validateSubjectAlternativeNames(context.Background(), config); diags.ErrorsCount() != tc.errors {
+				t.Errorf("got %d errors, want %d: %v", diags.ErrorsCount(), tc.errors, diags)
+			}
+		})
+	}
+}
+
+func TestSubmittedAttributes(t *testing.T) {
+	plan := certificateCreateModel{
+		Attributes:              types.StringNull(),
+		RequestAttributes:       types.StringValue("ClientRequestNonce:1\n"),
+		SubjectAlternativeNames: testSANBlock(t, map[string][]string{"dns": {"www.example.com"}}),
+	}
+	got, diags := plan.submittedAttributes(context.Background())
+	if diags.HasError() {
+		t.Fatalf("unexpected diagnostics: %v", diags)
+	}
+	if want := "ClientRequestNonce:1\nsan:dns=www.example.com"; got != want {
+		t.Errorf("submittedAttributes() = %q, want %q", got, want)
+	}
+}
+
+func TestTypedSubjectAlternativeNames(t *testing.T) {
+	ctx := context.Background()
+	list := func(values ...string) types.List {
+		l, diags := types.ListValueFrom(ctx, types.StringType, values)
+		if diags.HasError() {
+			t.Fatal(diags)
+		}
+		return l
+	}
+	typed := func(m certificateCreateModel) certificateCreateModel {
+		m.Attributes, m.RequestAttributes = types.StringNull(), types.StringNull()
+		m.SubjectAlternativeNames = types.ObjectNull(subjectAlternativeNamesAttrTypes)
+		for _, l := range []*types.List{&m.DNSNames, &m.IPAddresses, &m.Emails, &m.UPNs, &m.URIs} {
+			if l.IsNull() {
+				*l = types.ListNull(types.StringType)
+			}
+		}
+		return m
+	}
+
+	plan := typed(certificateCreateModel{DNSNames: list("www.example.com"), URIs: list("spiffe://example.com/web")})
+	got, diags := plan.submittedAttributes(ctx)
+	if diags.HasError() {
+		t.Fatalf("unexpected diagnostics: %v", diags)
+	}
+	if want := "san:dns=www.example.com&url=spiffe://example.com/web"; got != want {
+		t.Errorf("submittedAttributes() = %q, want %q", got, want)
+	}
+
+	unknown := typed(certificateCreateModel{DNSNames: types.ListUnknown(types.StringType)})
+	if block := unknown.subjectAlternativeNames(); !block.IsUnknown() {
+		t.Errorf("subjectAlternativeNames() = %s, want unknown while a list is", block)
+	}
+
+	for name, tc := range map[string]struct {
+		config certificateCreateModel
+		want   path.Path
+	}{
+		"valid":         {config: typed(certificateCreateModel{DNSNames: list("www.example.com"), IPAddresses: list("10.0.0.1"), UPNs: list("svc@example.com")})},
+		"invalid ip":    {config: typed(certificateCreateModel{IPAddresses: list("10.0.0.1", "10.0.0.256")}), want: path.Root("ip_addresses").AtListIndex(1)},
+		"invalid dns":   {config: typed(certificateCreateModel{DNSNames: list("bad name.example.com")}), want: path.Root("dns_names").AtListIndex(0)},
+		"invalid upn":   {config: typed(certificateCreateModel{UPNs: list("svc")}), want: path.Root("upns").AtListIndex(0)},
+		"invalid email": {config: typed(certificateCreateModel{Emails: list("ops")}), want: path.Root("emails").AtListIndex(0)},
+		"invalid uri":   {config: typed(certificateCreateModel{URIs: list("example.com/web")}), want: path.Root("uris").AtListIndex(0)},
+		"with block": {
+			config: func() certificateCreateModel {
+				m := typed(certificateCreateModel{DNSNames: list("www.example.com")})
+				m.SubjectAlternativeNames = testSANBlock(t, map[string][]string{"dns": {"www.example.com"}})
+				return m
+			}(),
+			want: path.Root("subject_alternative_names"),
+		},
+	} {
+		diags := validateSubjectAlternativeNames(ctx, tc.config)
+		switch wantError := len(tc.want.Steps()) > 0; {
+		case !wantError && diags.HasError():
+			t.Errorf("%s: validateSubjectAlternativeNames() = %v", name, diags)
+		case wantError && (diags.ErrorsCount() != 1 || !diags.Errors()[0].(diag.DiagnosticWithPath).Path().Equal(tc.want)):
+			t.Errorf("%s: validateSubjectAlternativeNames() = %v, want an error at %s", name, diags, tc.want)
+		}
+	}
+}
+
+// testManyDNSNames returns count DNS names that take 25 characters each in a san attribute.
+func testManyDNSNames(count int) []string {
+	names := make([]string, count)
+	for i := range names {
+		names[i] = fmt.Sprintf("host%04d.example.com", i)
+	}
+	return names
+}
+
+func TestValidateSANAttributeLength(t *testing.T) {
+	long := "san:dns=" + strings.Join(testManyDNSNames(200), "&dns=")
+	generate := testGenerateCSRBlock(t, generateCSRModel{CommonName: types.StringValue("app.example.com")})
+
+	cases := map[string]struct {
+		config   certificateCreateModel
+		errors   int
+		warnings int
+		detail   string
+	}{
+		"short": {
+			config: certificateCreateModel{
+				RequestAttributes:       types.StringValue("san:dns=www.example.com"),
+				SubjectAlternativeNames: testSANBlock(t, map[string][]string{"dns": testManyDNSNames(10)}),
+			},
+		},
+		"request_attributes": {
+			config: certificateCreateModel{RequestAttributes: types.StringValue(long)},
+			errors: 1,
+			detail: "200 names in 4999 characters, 903 more than the 4096",
+		},
+		"attributes_map": {
+			config: certificateCreateModel{AttributesMap: types.MapValueMust(types.StringType, map[string]attr.Value{
+				"SAN": types.StringValue(strings.TrimPrefix(long, "san:")),
+			})},
+			errors: 1,
+		},
+		"block with certificate_signing_request": {
+			config: certificateCreateModel{SubjectAlternativeNames: testSANBlock(t, map[string][]string{"dns": testManyDNSNames(200)})},
+			errors: 1,
+		},
+		"block with generate_csr": {
+			config: certificateCreateModel{
+				GenerateCSR:             generate,
+				SubjectAlternativeNames: testSANBlock(t, map[string][]string{"dns": testManyDNSNames(200)}),
+			},
+			warnings: 1,
+		},
+		"block with upn and generate_csr": {
+			config: certificateCreateModel{
+				GenerateCSR:             generate,
+				SubjectAlternativeNames: testSANBlock(t, map[string][]string{"dns": testManyDNSNames(200), "upn": {"svc@example.com"}}),
+			},
+			errors: 1,
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			diags := validateSANAttributeLength(context.Background(), tc.config)
+			if diags.ErrorsCount() != tc.errors || diags.WarningsCount() != tc.warnings {
+				t.Fatalf("got %d errors and %d warnings, want %d and %d: %v", diags.ErrorsCount(), diags.WarningsCount(), tc.errors, tc.warnings, diags)
+			}
+			if tc.detail != "" && !strings.Contains(diags.Errors()[0].Detail(), tc.detail) {
+				t.Errorf("detail = %q, want it to contain %q", diags.Errors()[0].Detail(), tc.detail)
+			}
+		})
+	}
+}
+
+func TestSANInGeneratedRequest(t *testing.T) {
+	names := testManyDNSNames(200)
+	plan := certificateCreateModel{
+		Attributes:        types.StringNull(),
+		RequestAttributes: types.StringValue("ClientRequestNonce:1"),
+		AttributesMap:     types.MapNull(types.StringType),
+		GenerateCSR: testGenerateCSRBlock(t, generateCSRModel{
+			CommonName: types.StringValue("app.example.com"),
+			DNSNames:   types.ListValueMust(types.StringType, []attr.Value{types.StringValue("app.example.com")}),
+		}),
+		SubjectAlternativeNames: testSANBlock(t, map[string][]string{"dns": names, "ip": {"10.0.0.1"}}),
+	}
+
+	attributes, diags := plan.submittedAttributes(context.Background())
+	if diags.HasError() {
+		t.Fatalf("unexpected diagnostics: %v", diags)
+	}
+	if attributes != "ClientRequestNonce:1" {
+		t.Errorf("submittedAttributes() = %q, want no san attribute", attributes)
+	}
+
+	if diags := plan.generateCSR(context.Background()); diags.HasError() {
+		t.Fatalf("unexpected diagnostics: %v", diags)
+	}
+	request, err := parseCertificateRequest(plan.CSR.ValueString())
+	if err != nil {
+		t.Fatalf("could not parse request: %v", err)
+	}
+	if want := append([]string{"app.example.com"}, names...); !reflect.DeepEqual(request.DNSNames, want) {
+		t.Errorf("request holds %d DNS names, want %d", len(request.DNSNames), len(want))
+	}
+	if len(request.IPAddresses) != 1 || request.IPAddresses[0].String() != "10.0.0.1" {
+		t.Errorf("request IP addresses = %v", request.IPAddresses)
+	}
+}
+
+// testCertificateConfig returns a config, plan or state of the certificate resource with the
+// attributes of values set and every other attribute null.
+func testCertificateConfig(t *testing.T, values map[string]tftypes.Value) (fwschema.Schema, tftypes.Value) {
+	t.Helper()
+	var schemaResp resource.SchemaResponse
+	(&certificateResource{}).Schema(context.Background(), resource.SchemaRequest{}, &schemaResp)
+	objectType := schemaResp.Schema.Type().TerraformType(context.Background()).(tftypes.Object)
+	attributes := make(map[string]tftypes.Value, len(objectType.AttributeTypes))
+	for name, attributeType := range objectType.AttributeTypes {
+		attributes[name] = tftypes.NewValue(attributeType, nil)
+	}
+	for name, value := range values {
+		if _, ok := attributes[name]; !ok {
+			t.Fatalf("the certificate resource has no attribute %s", name)
+		}
+		attributes[name] = value
+	}
+	return schemaResp.Schema, tftypes.NewValue(objectType, attributes)
+}
+
+func TestAllowedSANPatternsUnknownCSR(t *testing.T) {
+	srv := newAccCertsrv(t)
+	t.Setenv("ADCS_PASSWORD", "secret")
+	ctx := withRetryPolicy(context.Background(), noRetry)
+	data, err := debugEnrollConfigure(ctx, "test", map[string]interface{}{
+		"host":     srv.host(),
+		"username": "svc-terraform",
+		"use_ntlm": true,
+	}, io.Discard)
+	if err != nil {
+		t.Fatal(err)
+	}
+	r := &certificateResource{client: data.client, provider: data}
+	block := generateCSRModel{
+		KeyAlgorithm:   types.StringValue(keyAlgorithmECDSA),
+		CommonName:     types.StringValue("app.example.com"),
+		DNSNames:       testStringList("app.example.com", "app.other.example.com"),
+		IPAddresses:    types.ListNull(types.StringType),
+		EmailAddresses: types.ListNull(types.StringType),
+	}
+	csr, _, diags := block.generate(ctx)
+	if diags.HasError() {
+		t.Fatalf("unexpected diagnostics: %v", diags)
+	}
+
+	// The CSR of a tls_cert_request created in the same apply is unknown until the apply plans again.
+	values := map[string]tftypes.Value{
+		"template":                    tftypes.NewValue(tftypes.String, "WebServer"),
+		"allowed_san_patterns":        tftypes.NewValue(tftypes.List{ElementType: tftypes.String}, []tftypes.Value{tftypes.NewValue(tftypes.String, `[a-z0-9-]+\.example\.com`)}),
+		"certificate_signing_request": tftypes.NewValue(tftypes.String, tftypes.UnknownValue),
+	}
+	schema, unknown := testCertificateConfig(t, values)
+	values["certificate_signing_request"] = tftypes.NewValue(tftypes.String, csr)
+	_, known := testCertificateConfig(t, values)
+	null := tftypes.NewValue(schema.Type().TerraformType(ctx), nil)
+
+	validateResp := &resource.ValidateConfigResponse{}
+	r.ValidateConfig(ctx, resource.ValidateConfigRequest{Config: tfsdk.Config{Schema: schema, Raw: unknown}}, validateResp)
+	if validateResp.Diagnostics.HasError() {
+		t.Fatalf("ValidateConfig() with an unknown CSR = %v", validateResp.Diagnostics)
+	}
+
+	for name, raw := range map[string]tftypes.Value{"plan": unknown, "apply": known} {
+		planResp := &resource.ModifyPlanResponse{Plan: tfsdk.Plan{Schema: schema, Raw: raw}}
+		r.ModifyPlan(ctx, resource.ModifyPlanRequest{
+			Config: tfsdk.Config{Schema: schema, Raw: raw},
+			Plan:   tfsdk.Plan{Schema: schema, Raw: raw},
+			State:  tfsdk.State{Schema: schema, Raw: null},
+		}, planResp)
+		denied := planResp.Diagnostics.HasError() && planResp.Diagnostics.Errors()[0].Summary() == "Subject Alternative Name Not Allowed"
+		if denied != (name == "apply") {
+			t.Errorf("%s: ModifyPlan() = %v", name, planResp.Diagnostics)
+		}
+	}
+
+	createResp := &resource.CreateResponse{State: tfsdk.State{Schema: schema, Raw: null}}
+	r.Create(ctx, resource.CreateRequest{
+		Config: tfsdk.Config{Schema: schema, Raw: known},
+		Plan:   tfsdk.Plan{Schema: schema, Raw: known},
+	}, createResp)
+	if !createResp.Diagnostics.HasError() || createResp.Diagnostics.Errors()[0].Summary() != "Subject Alternative Name Not Allowed" {
+		t.Errorf("Create() = %v, want the name refused", createResp.Diagnostics)
+	}
+	if srv.request("101") != nil {
+		t.Error("the request was submitted to the CA")
+	}
+}
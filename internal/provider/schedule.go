@@ -0,0 +1,189 @@
+package provider
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// cronSchedule is a parsed five field cron expression: minute, hour, day of month, month and day
+// of week. Besides lists, ranges and steps, the day of week accepts "d#n" for the nth weekday of
+// the month, for example "0#1" for the first Sunday. Schedules are evaluated in UTC.
+type cronSchedule struct {
+	minute, hour, dom, month, dow uint64
+	// nthDow holds, per weekday, the occurrences in the month that match ("0#1" sets bit 1 of nthDow[0]).
+	nthDow [7]uint8
+	// domAny and dowAny are set when the field is "*", cron matches either day field when both are restricted.
+	domAny, dowAny bool
+}
+
+var cronDescriptors = map[string]string{
+	"@yearly":   "0 0 1 1 *",
+	"@annually": "0 0 1 1 *",
+	"@monthly":  "0 0 1 * *",
+	"@weekly":   "0 0 * * 0",
+	"@daily":    "0 0 * * *",
+	"@midnight": "0 0 * * *",
+	"@hourly":   "0 * * * *",
+}
+
+// parseCronSchedule parses a cron expression or one of the @yearly, @monthly, @weekly, @daily and
+// @hourly descriptors.
+func parseCronSchedule(expr string) (*cronSchedule, error) {
+	expr = strings.TrimSpace(expr)
+	if descriptor, ok := cronDescriptors[expr]; ok {
+		expr = descriptor
+	}
+
+	fields := strings.Fields(expr)
+	if len(fields) != 5 {
+		return nil, fmt.Errorf("expected 5 fields (minute hour day-of-month month day-of-week), got %d", len(fields))
+	}
+
+	s := &cronSchedule{
+		domAny: fields[2] == "*",
+		dowAny: fields[4] == "*",
+	}
+	var err error
+	if s.minute, err = parseCronField(fields[0], 0, 59); err != nil {
+		return nil, fmt.Errorf("minute: %v", err)
+	}
+	if s.hour, err = parseCronField(fields[1], 0, 23); err != nil {
+		return nil, fmt.Errorf("hour: %v", err)
+	}
+	if s.dom, err = parseCronField(fields[2], 1, 31); err != nil {
+		return nil, fmt.Errorf("day of month: %v", err)
+	}
+	if s.month, err = parseCronField(fields[3], 1, 12); err != nil {
+		return nil, fmt.Errorf("month: %v", err)
+	}
+	if err = s.parseDow(fields[4]); err != nil {
+		return nil, fmt.Errorf("day of week: %v", err)
+	}
+	return s, nil
+}
+
+// parseDow parses the day of week field, where 7 is an alias for Sunday.
+func (s *cronSchedule) parseDow(field string) error {
+	var plain []string
+	for _, part := range strings.Split(field, ",") {
+		day, nth, found := strings.Cut(part, "#")
+		if !found {
+			plain = append(plain, part)
+			continue
+		}
+		d, err := strconv.Atoi(day)
+		if err != nil || d < 0 || d > 7 {
+			return fmt.Errorf("invalid weekday %q", day)
+		}
+		n, err := strconv.Atoi(nth)
+		if err != nil || n < 1 || n > 5 {
+			return fmt.Errorf("invalid occurrence %q, expected 1 to 5", nth)
+		}
+		s.nthDow[d%7] |= 1 << n
+	}
+	if len(plain) == 0 {
+		return nil
+	}
+
+	bits, err := parseCronField(strings.Join(plain, ","), 0, 7)
+	if err != nil {
+		return err
+	}
+	if bits&(1<<7) != 0 {
+		bits |= 1
+	}
+	s.dow = bits &^ (1 << 7)
+	return nil
+}
+
+// parseCronField returns the allowed values of a field as a bit set.
+func parseCronField(field string, min, max int) (uint64, error) {
+	var bits uint64
+	for _, part := range strings.Split(field, ",") {
+		rangePart, stepPart, hasStep := strings.Cut(part, "/")
+		step := 1
+		if hasStep {
+			var err error
+			if step, err = strconv.Atoi(stepPart); err != nil || step < 1 {
+				return 0, fmt.Errorf("invalid step %q", stepPart)
+			}
+		}
+
+		lo, hi := min, max
+		if rangePart != "*" {
+			from, to, isRange := strings.Cut(rangePart, "-")
+			var err error
+			if lo, err = strconv.Atoi(from); err != nil {
+				return 0, fmt.Errorf("invalid value %q", from)
+			}
+			hi = lo
+			if isRange {
+				if hi, err = strconv.Atoi(to); err != nil {
+					return 0, fmt.Errorf("invalid value %q", to)
+				}
+			} else if hasStep {
+				hi = max
+			}
+		}
+		if lo < min || hi > max || lo > hi {
+			return 0, fmt.Errorf("%q is outside of %d-%d", part, min, max)
+		}
+
+		for v := lo; v <= hi; v += step {
+			bits |= 1 << uint(v)
+		}
+	}
+	return bits, nil
+}
+
+// dayMatches reports whether the date matches the day of month and day of week fields.
+func (s *cronSchedule) dayMatches(t time.Time) bool {
+	weekday := int(t.Weekday())
+	dowMatch := s.dow&(1<<uint(weekday)) != 0 || s.nthDow[weekday]&(1<<uint((t.Day()-1)/7+1)) != 0
+	domMatch := s.dom&(1<<uint(t.Day())) != 0
+
+	switch {
+	case s.domAny && s.dowAny:
+		return true
+	case s.domAny:
+		return dowMatch
+	case s.dowAny:
+		return domMatch
+	default:
+		return domMatch || dowMatch
+	}
+}
+
+// next returns the first time at or after t that matches the schedule, in UTC and rounded up to
+// the minute. The zero time is returned when nothing matches within five years, for example for
+// the 31st of February.
+func (s *cronSchedule) next(t time.Time) time.Time {
+	t = t.UTC()
+	if rounded := t.Truncate(time.Minute); rounded.Before(t) {
+		t = rounded.Add(time.Minute)
+	}
+	limit := t.AddDate(5, 0, 0)
+
+	for t.Before(limit) {
+		if s.month&(1<<uint(t.Month())) == 0 {
+			t = time.Date(t.Year(), t.Month()+1, 1, 0, 0, 0, 0, time.UTC)
+			continue
+		}
+		if !s.dayMatches(t) {
+			t = time.Date(t.Year(), t.Month(), t.Day()+1, 0, 0, 0, 0, time.UTC)
+			continue
+		}
+		if s.hour&(1<<uint(t.Hour())) == 0 {
+			t = time.Date(t.Year(), t.Month(), t.Day(), t.Hour()+1, 0, 0, 0, time.UTC)
+			continue
+		}
+		if s.minute&(1<<uint(t.Minute())) == 0 {
+			t = t.Add(time.Minute)
+			continue
+		}
+		return t
+	}
+	return time.Time{}
+}
@@ -0,0 +1,133 @@
+package provider
+
+import (
+	"context"
+	"crypto/x509"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/terraform-plugin-framework/types"
+)
+
+func TestCronScheduleNext(t *testing.T) {
+	from := time.Date(2026, 10, 16, 9, 30, 15, 0, time.UTC)
+
+	tests := []struct {
+		expr string
+		want time.Time
+	}{
+		{"0 2 * * 0#1", time.Date(2026, 11, 1, 2, 0, 0, 0, time.UTC)},
+		{"*/15 * * * *", time.Date(2026, 10, 16, 9, 45, 0, 0, time.UTC)},
+		{"30 22 * * 1-5", time.Date(2026, 10, 16, 22, 30, 0, 0, time.UTC)},
+		{"0 4 1,15 * *", time.Date(2026, 11, 1, 4, 0, 0, 0, time.UTC)},
+		{"0 0 * * 7", time.Date(2026, 10, 18, 0, 0, 0, 0, time.UTC)},
+		{"@monthly", time.Date(2026, 11, 1, 0, 0, 0, 0, time.UTC)},
+		{"0 0 31 2 *", time.Time{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.expr, func(t *testing.T) {
+			schedule, err := parseCronSchedule(tt.expr)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got := schedule.next(from); !got.Equal(tt.want) {
+				t.Errorf("next() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseCronScheduleErrors(t *testing.T) {
+	for _, expr := range []string{"", "* * * *", "60 * * * *", "* * * * 0#6", "5-1 * * * *", "*/0 * * * *", "FREQ=MONTHLY"} {
+		if _, err := parseCronSchedule(expr); err == nil {
+			t.Errorf("parseCronSchedule(%q) succeeded, want an error", expr)
+		}
+	}
+}
+
+func TestRenewalTime(t *testing.T) {
+	notBefore := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	cert := &x509.Certificate{NotBefore: notBefore, NotAfter: notBefore.Add(90 * 24 * time.Hour)}
+
+	// Two thirds of 90 days is Monday the 2nd of March, the next Sunday window is the 8th.
+	schedule, _ := parseCronSchedule("0 2 * * 0")
+	if got, want := renewalTime(cert, schedule), time.Date(2026, 3, 8, 2, 0, 0, 0, time.UTC); !got.Equal(want) {
+		t.Errorf("renewalTime() = %s, want %s", got, want)
+	}
+
+	// The first Sunday of April would only come after expiry, so the threshold is used.
+	schedule, _ = parseCronSchedule("0 2 * * 0#1")
+	if got, want := renewalTime(cert, schedule), time.Date(2026, 3, 2, 0, 0, 0, 0, time.UTC); !got.Equal(want) {
+		t.Errorf("renewalTime() = %s, want %s", got, want)
+	}
+}
+
+func TestEarlyRenewal(t *testing.T) {
+	h := newTestHierarchy(t)
+	model := certificateCreateModel{
+		ID:                types.StringValue("42"),
+		CertificateB64:    types.StringValue(h.testCertificates(t).CertificateB64),
+		EarlyRenewalHours: types.Int64Value(24),
+	}
+
+	if diags := model.setReadyForRenewal(context.Background(), h.leaf.NotAfter.Add(-25*time.Hour)); diags.HasError() || model.ReadyForRenewal.ValueBool() {
+		t.Errorf("certificate expiring in 25 hours should not be ready for renewal: %v", diags)
+	}
+	if diags := model.setReadyForRenewal(context.Background(), h.leaf.NotAfter.Add(-23*time.Hour)); diags.HasError() || !model.ReadyForRenewal.ValueBool() {
+		t.Errorf("certificate expiring in 23 hours should be ready for renewal: %v", diags)
+	}
+
+	// The earlier of both settings wins, a schedule cannot push a renewal past early_renewal_hours.
+	model.RenewalSchedule = types.StringValue("0 2 1 1 *")
+	if diags := model.setReadyForRenewal(context.Background(), h.leaf.NotAfter.Add(-23*time.Hour)); diags.HasError() || !model.ReadyForRenewal.ValueBool() {
+		t.Errorf("early_renewal_hours should apply alongside renewal_schedule: %v", diags)
+	}
+}
+
+func TestRotationPeriod(t *testing.T) {
+	h := newTestHierarchy(t)
+	model := certificateCreateModel{
+		ID:             types.StringValue("42"),
+		CertificateB64: types.StringValue(h.testCertificates(t).CertificateB64),
+		RotationPeriod: types.StringValue("720h"),
+	}
+
+	if diags := model.setReadyForRenewal(context.Background(), h.leaf.NotBefore.Add(719*time.Hour)); diags.HasError() || model.ReadyForRenewal.ValueBool() {
+		t.Errorf("certificate younger than the rotation period should not be ready for renewal: %v", diags)
+	}
+	if diags := model.setReadyForRenewal(context.Background(), h.leaf.NotBefore.Add(721*time.Hour)); diags.HasError() || !model.ReadyForRenewal.ValueBool() {
+		t.Errorf("certificate older than the rotation period should be ready for renewal: %v", diags)
+	}
+
+	model.RotationPeriod = types.StringValue("monthly")
+	if diags := model.setReadyForRenewal(context.Background(), h.leaf.NotBefore); !diags.HasError() {
+		t.Error("an invalid rotation_period should be reported")
+	}
+}
+
+func TestExpiredCertificateReadyForRenewal(t *testing.T) {
+	h := newTestHierarchy(t)
+	model := certificateCreateModel{
+		ID:             types.StringValue("42"),
+		CertificateB64: types.StringValue(h.testCertificates(t).CertificateB64),
+	}
+
+	// Without renewal settings only expiry counts.
+	if diags := model.setReadyForRenewal(context.Background(), h.leaf.NotAfter.Add(-time.Minute)); diags.HasError() || model.ReadyForRenewal.ValueBool() {
+		t.Errorf("certificate that has not expired should not be ready for renewal: %v", diags)
+	}
+	if diags := model.setReadyForRenewal(context.Background(), h.leaf.NotAfter.Add(time.Minute)); diags.HasError() || !model.ReadyForRenewal.ValueBool() {
+		t.Errorf("expired certificate should be ready for renewal: %v", diags)
+	}
+
+	// An expired certificate is replaced even when the schedule has no window left.
+	model.RenewalSchedule = types.StringValue("0 2 1 1 *")
+	if diags := model.setReadyForRenewal(context.Background(), h.leaf.NotAfter.Add(time.Minute)); diags.HasError() || !model.ReadyForRenewal.ValueBool() {
+		t.Errorf("expired certificate should be ready for renewal regardless of renewal_schedule: %v", diags)
+	}
+
+	model.CertificateB64 = types.StringValue("")
+	if model.expired(time.Now()) {
+		t.Error("a missing certificate should not count as expired")
+	}
+}
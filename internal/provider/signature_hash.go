@@ -0,0 +1,84 @@
+package provider
+
+import (
+	"crypto/x509"
+	"fmt"
+	"strings"
+
+	"github.com/flipyap/microsoft-adcs-client/client"
+	"github.com/hashicorp/terraform-plugin-framework/diag"
+	"github.com/hashicorp/terraform-plugin-framework/path"
+)
+
+// signatureHashAlgorithms lists the values of signature_hash_algorithm.
+var signatureHashAlgorithms = []string{"SHA256", "SHA384", "SHA512"}
+
+// signatureHash returns the hash of a certificate signature algorithm, and whether it is one of the
+// RSASSA-PSS algorithms of PKCS#1 v2.1 a CA with AlternateSignatureAlgorithm set signs with.
+func signatureHash(algorithm x509.SignatureAlgorithm) (string, bool) {
+	switch algorithm {
+	case x509.SHA1WithRSA, x509.ECDSAWithSHA1:
+		return "SHA1", false
+	case x509.SHA256WithRSA, x509.ECDSAWithSHA256:
+		return "SHA256", false
+	case x509.SHA384WithRSA, x509.ECDSAWithSHA384:
+		return "SHA384", false
+	case x509.SHA512WithRSA, x509.ECDSAWithSHA512:
+		return "SHA512", false
+	case x509.SHA256WithRSAPSS:
+		return "SHA256", true
+	case x509.SHA384WithRSAPSS:
+		return "SHA384", true
+	case x509.SHA512WithRSAPSS:
+		return "SHA512", true
+	}
+	return "", false
+}
+
+// checkSignatureHash fails when the CA signed the certificate with another hash than
+// signature_hash_algorithm. ADCS signs with the hash configured on the CA, not one chosen by the
+// request, so the check is what makes the setting hold. As with max_accepted_validity_hours, the
+// certificate already exists on the CA, so the request ID is included.
+func (m *certificateCreateModel) checkSignatureHash(certificates *client.Certificates) diag.Diagnostics {
+	var diags diag.Diagnostics
+	if m.SignatureHashAlgorithm.IsNull() || m.SignatureHashAlgorithm.IsUnknown() {
+		return diags
+	}
+
+	cert, err := parseCertificate(certificates.CertificateB64)
+	if err != nil {
+		diags.AddError(
+			classifiedSummary("Error Parsing Certificate", &ParseError{Err: err}),
+			fmt.Sprintf("Could not parse the certificate issued for request ID %s to verify its signature: %s", certificates.ID, err.Error()),
+		)
+		return diags
+	}
+
+	want := m.SignatureHashAlgorithm.ValueString()
+	if hash, _ := signatureHash(cert.SignatureAlgorithm); hash != want {
+		diags.AddAttributeError(
+			path.Root("signature_hash_algorithm"),
+			"Issued Certificate Signature Hash Mismatch",
+			fmt.Sprintf("The CA signed request ID %s with %s instead of %s. ADCS signs with the hash configured on the CA, see "+
+				"certutil -getreg ca\\csp\\CNGHashAlgorithm, and with RSASSA-PSS only when ca\\csp\\AlternateSignatureAlgorithm is 1. "+
+				"The certificate has been issued by the CA and may need to be revoked.", certificates.ID, cert.SignatureAlgorithm, want),
+		)
+	}
+	return diags
+}
+
+// validateSignatureHashAlgorithm checks signature_hash_algorithm at plan time.
+func validateSignatureHashAlgorithm(config certificateCreateModel) diag.Diagnostics {
+	var diags diag.Diagnostics
+	if config.SignatureHashAlgorithm.IsNull() || config.SignatureHashAlgorithm.IsUnknown() {
+		return diags
+	}
+	if hash := config.SignatureHashAlgorithm.ValueString(); !containsString(signatureHashAlgorithms, hash) {
+		diags.AddAttributeError(
+			path.Root("signature_hash_algorithm"),
+			"Invalid Signature Hash Algorithm",
+			fmt.Sprintf("signature_hash_algorithm %q is not one of: %s.", hash, strings.Join(signatureHashAlgorithms, ", ")),
+		)
+	}
+	return diags
+}
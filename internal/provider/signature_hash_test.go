@@ -0,0 +1,59 @@
+package provider
+
+import (
+	"crypto/x509"
+	"strings"
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-framework/types"
+)
+
+func TestSignatureHash(t *testing.T) {
+	for algorithm, want := range map[x509.SignatureAlgorithm]struct {
+		hash      string
+		alternate bool
+	}{
+		x509.SHA256WithRSA:    {hash: "SHA256"},
+		x509.SHA384WithRSAPSS: {hash: "SHA384", alternate: true},
+		x509.ECDSAWithSHA512:  {hash: "SHA512"},
+		x509.PureEd25519:      {},
+	} {
+		if hash, alternate := signatureHash(algorithm); hash != want.hash || alternate != want.alternate {
+			t.Errorf("signatureHash(%s) = %q, %t, want %q, %t", algorithm, hash, alternate, want.hash, want.alternate)
+		}
+	}
+}
+
+func TestCheckSignatureHash(t *testing.T) {
+	// The test hierarchy signs with ECDSA-SHA256.
+	certificates := newTestHierarchy(t).testCertificates(t)
+
+	for name, tc := range map[string]struct {
+		hash types.String
+		want string
+	}{
+		"unset":    {hash: types.StringNull()},
+		"matches":  {hash: types.StringValue("SHA256")},
+		"mismatch": {hash: types.StringValue("SHA384"), want: "with ECDSA-SHA256 instead of SHA384"},
+	} {
+		m := certificateCreateModel{SignatureHashAlgorithm: tc.hash}
+		diags := m.checkSignatureHash(certificates)
+		switch {
+		case tc.want == "" && diags.HasError():
+			t.Errorf("%s: checkSignatureHash() = %v", name, diags)
+		case tc.want != "" && (!diags.HasError() || !strings.Contains(diags.Errors()[0].Detail(), tc.want)):
+			t.Errorf("%s: checkSignatureHash() = %v, want %q", name, diags, tc.want)
+		}
+	}
+}
+
+func TestValidateSignatureHashAlgorithm(t *testing.T) {
+	for _, hash := range signatureHashAlgorithms {
+		if diags := validateSignatureHashAlgorithm(certificateCreateModel{SignatureHashAlgorithm: types.StringValue(hash)}); diags.HasError() {
+			t.Errorf("validateSignatureHashAlgorithm(%q) = %v", hash, diags)
+		}
+	}
+	if diags := validateSignatureHashAlgorithm(certificateCreateModel{SignatureHashAlgorithm: types.StringValue("SHA1")}); !diags.HasError() {
+		t.Error("validateSignatureHashAlgorithm(\"SHA1\") succeeded, want an error")
+	}
+}
@@ -0,0 +1,266 @@
+package provider
+
+import (
+	"context"
+	"crypto"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/flipyap/microsoft-adcs-client/client"
+	"github.com/hashicorp/terraform-plugin-framework/diag"
+	"github.com/hashicorp/terraform-plugin-framework/path"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+	"github.com/hashicorp/terraform-plugin-log/tflog"
+)
+
+const (
+	// sealedBlockType is the PEM block type of values encrypted with state_encryption_public_key.
+	sealedBlockType = "MICROSOFTADCS SEALED DATA"
+
+	sealedKeyEncryption     = "RSA-OAEP-SHA256"
+	sealedContentEncryption = "AES-256-GCM"
+
+	// minSealingKeyBits is the smallest RSA key state_encryption_public_key accepts.
+	minSealingKeyBits = 2048
+)
+
+// parseSealingKey parses state_encryption_public_key, an RSA public key given as a PUBLIC KEY or
+// RSA PUBLIC KEY PEM block, or as the certificate holding it.
+func parseSealingKey(value string) (*rsa.PublicKey, error) {
+	block, _ := pem.Decode([]byte(value))
+	if block == nil {
+		return nil, fmt.Errorf("no PEM block found")
+	}
+
+	var key interface{}
+	var err error
+	switch block.Type {
+	case "PUBLIC KEY":
+		key, err = x509.ParsePKIXPublicKey(block.Bytes)
+	case "RSA PUBLIC KEY":
+		key, err = x509.ParsePKCS1PublicKey(block.Bytes)
+	case "CERTIFICATE":
+		var cert *x509.Certificate
+		if cert, err = x509.ParseCertificate(block.Bytes); err == nil {
+			key = cert.PublicKey
+		}
+	default:
+		return nil, fmt.Errorf("unsupported PEM block type %q", block.Type)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	rsaKey, ok := key.(*rsa.PublicKey)
+	if !ok {
+		return nil, fmt.Errorf("state is encrypted with RSA keys, got %T", key)
+	}
+	if rsaKey.N.BitLen() < minSealingKeyBits {
+		return nil, fmt.Errorf("the key has %d bits, at least %d are required", rsaKey.N.BitLen(), minSealingKeyBits)
+	}
+	return rsaKey, nil
+}
+
+// seal encrypts plaintext for the holder of the private key of pub: the content is encrypted with
+// a random AES-256-GCM key, which is encrypted with RSA-OAEP. The result is a PEM block whose
+// headers carry the encrypted key and nonce.
+func seal(plaintext []byte, pub *rsa.PublicKey) (string, error) {
+	contentKey := make([]byte, 32)
+	if _, err := rand.Read(contentKey); err != nil {
+		return "", fmt.Errorf("could not generate the content key: %v", err)
+	}
+	gcm, err := newSealingCipher(contentKey)
+	if err != nil {
+		return "", err
+	}
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", fmt.Errorf("could not generate the nonce: %v", err)
+	}
+	encryptedKey, err := rsa.EncryptOAEP(sha256.New(), rand.Reader, pub, contentKey, nil)
+	if err != nil {
+		return "", fmt.Errorf("could not encrypt the content key: %v", err)
+	}
+
+	return string(pem.EncodeToMemory(&pem.Block{
+		Type: sealedBlockType,
+		Headers: map[string]string{
+			"Key-Encryption":     sealedKeyEncryption,
+			"Content-Encryption": sealedContentEncryption,
+			"Encrypted-Key":      base64.StdEncoding.EncodeToString(encryptedKey),
+			"Nonce":              base64.StdEncoding.EncodeToString(nonce),
+		},
+		Bytes: gcm.Seal(nil, nonce, plaintext, nil),
+	})), nil
+}
+
+// unseal decrypts a value sealed with the public key of key.
+func unseal(sealed string, key crypto.Signer) ([]byte, error) {
+	block, _ := pem.Decode([]byte(sealed))
+	if block == nil || block.Type != sealedBlockType {
+		return nil, fmt.Errorf("no %s PEM block found", sealedBlockType)
+	}
+	if block.Headers["Key-Encryption"] != sealedKeyEncryption || block.Headers["Content-Encryption"] != sealedContentEncryption {
+		return nil, fmt.Errorf("unsupported encryption %s with %s", block.Headers["Content-Encryption"], block.Headers["Key-Encryption"])
+	}
+	rsaKey, ok := key.(*rsa.PrivateKey)
+	if !ok {
+		return nil, fmt.Errorf("sealed values are decrypted with an RSA key, got %T", key)
+	}
+
+	encryptedKey, err := base64.StdEncoding.DecodeString(block.Headers["Encrypted-Key"])
+	if err != nil {
+		return nil, fmt.Errorf("could not decode the encrypted key: %v", err)
+	}
+	nonce, err := base64.StdEncoding.DecodeString(block.Headers["Nonce"])
+	if err != nil {
+		return nil, fmt.Errorf("could not decode the nonce: %v", err)
+	}
+	contentKey, err := rsa.DecryptOAEP(sha256.New(), nil, rsaKey, encryptedKey, nil)
+	if err != nil {
+		return nil, fmt.Errorf("could not decrypt the content key, the value was sealed for another key: %v", err)
+	}
+	gcm, err := newSealingCipher(contentKey)
+	if err != nil {
+		return nil, err
+	}
+	if len(nonce) != gcm.NonceSize() {
+		return nil, fmt.Errorf("the nonce has %d bytes, want %d", len(nonce), gcm.NonceSize())
+	}
+	plaintext, err := gcm.Open(nil, nonce, block.Bytes, nil)
+	if err != nil {
+		return nil, fmt.Errorf("could not decrypt the value: %v", err)
+	}
+	return plaintext, nil
+}
+
+// newSealingCipher returns the AES-256-GCM cipher for a content key.
+func newSealingCipher(contentKey []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(contentKey)
+	if err != nil {
+		return nil, fmt.Errorf("could not create the content cipher: %v", err)
+	}
+	return cipher.NewGCM(block)
+}
+
+// isSealed reports whether a state value was encrypted with state_encryption_public_key.
+func isSealed(value string) bool {
+	return strings.HasPrefix(strings.TrimSpace(value), "-----BEGIN "+sealedBlockType+"-----")
+}
+
+// sealState encrypts generated_private_key_pem and pkcs12_b64 with state_encryption_public_key
+// before they are written to state. The bundled outputs are set again without the generated key,
+// which the provider cannot read back once it is sealed.
+func (m *certificateCreateModel) sealState(ctx context.Context, certificates *client.Certificates) diag.Diagnostics {
+	var diags diag.Diagnostics
+	if m.StateEncryptionPublicKey.IsNull() {
+		return diags
+	}
+	pub, err := parseSealingKey(m.StateEncryptionPublicKey.ValueString())
+	if err != nil {
+		diags.AddAttributeError(path.Root("state_encryption_public_key"), "Invalid State Encryption Key", "Could not parse state_encryption_public_key: "+err.Error()+".")
+		return diags
+	}
+
+	for _, value := range []*types.String{&m.GeneratedPrivateKeyPEM, &m.PKCS12B64} {
+		if value.IsNull() || value.IsUnknown() || isSealed(value.ValueString()) {
+			continue
+		}
+		sealed, err := seal([]byte(value.ValueString()), pub)
+		if err != nil {
+			diags.AddError("Error Encrypting State", fmt.Sprintf("Could not encrypt a value of request ID %s: %s.", certificates.ID, err))
+			return diags
+		}
+		*value = types.StringValue(sealed)
+	}
+	tflog.Debug(ctx, "Encrypted the private key and PKCS#12 bundle for state", map[string]interface{}{
+		"request_id": certificates.ID,
+	})
+	return m.setCertificateOutputs(ctx, certificates)
+}
+
+// validateStateEncryption checks state_encryption_public_key at plan time. renew_existing needs a
+// generated key the provider can read, so it cannot rely on one that is sealed.
+func validateStateEncryption(config certificateCreateModel) diag.Diagnostics {
+	var diags diag.Diagnostics
+	if config.StateEncryptionPublicKey.IsNull() || config.StateEncryptionPublicKey.IsUnknown() {
+		return diags
+	}
+	if _, err := parseSealingKey(config.StateEncryptionPublicKey.ValueString()); err != nil {
+		diags.AddAttributeError(path.Root("state_encryption_public_key"), "Invalid State Encryption Key", "Could not parse state_encryption_public_key: "+err.Error()+".")
+	}
+	if config.RenewExisting.ValueBool() && config.PrivateKeyPEM.IsNull() && !config.GenerateCSR.IsNull() {
+		diags.AddAttributeError(
+			path.Root("state_encryption_public_key"),
+			"Conflicting State Encryption",
+			"renew_existing signs the renewal with the generated key, which is encrypted in state. Set private_key_pem or remove state_encryption_public_key.",
+		)
+	}
+	return diags
+}
+
+// decryptUsage introduces the flags of decrypt.
+const decryptUsage = `Usage: terraform-provider-microsoft-adcs decrypt -key FILE [-in FILE]
+
+Decrypts a value the microsoftadcs_certificate resource encrypted with state_encryption_public_key, such as
+generated_private_key_pem or pkcs12_b64, with the matching RSA private key. The value is read from -in or
+stdin and the plaintext is written to stdout.
+
+Flags:
+`
+
+// Decrypt runs the decrypt mode of the provider binary, which opens values sealed with
+// state_encryption_public_key outside of Terraform.
+func Decrypt(args []string, stdin io.Reader, stdout io.Writer, stderr io.Writer) error {
+	flags := flag.NewFlagSet("decrypt", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	flags.Usage = func() {
+		fmt.Fprint(stderr, decryptUsage)
+		flags.PrintDefaults()
+	}
+	keyPath := flags.String("key", "", "PEM file holding the RSA private key of state_encryption_public_key")
+	inPath := flags.String("in", "", "file holding the encrypted value, defaults to stdin")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if *keyPath == "" {
+		flags.Usage()
+		return fmt.Errorf("-key is required")
+	}
+
+	keyPEM, err := os.ReadFile(*keyPath)
+	if err != nil {
+		return fmt.Errorf("could not read the private key: %v", err)
+	}
+	key, err := parsePrivateKey(string(keyPEM))
+	if err != nil {
+		return fmt.Errorf("could not parse the private key: %v", err)
+	}
+
+	var sealed []byte
+	if *inPath != "" {
+		sealed, err = os.ReadFile(*inPath)
+	} else {
+		sealed, err = io.ReadAll(stdin)
+	}
+	if err != nil {
+		return fmt.Errorf("could not read the encrypted value: %v", err)
+	}
+	plaintext, err := unseal(string(sealed), key)
+	if err != nil {
+		return err
+	}
+	_, err = stdout.Write(plaintext)
+	return err
+}
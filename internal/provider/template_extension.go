@@ -0,0 +1,118 @@
+package provider
+
+import (
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/hashicorp/terraform-plugin-framework/diag"
+	"github.com/hashicorp/terraform-plugin-framework/path"
+)
+
+// oidCertificateTemplate is the Certificate Template Information extension of version 2 and later
+// templates, szOID_CERTIFICATE_TEMPLATE.
+var oidCertificateTemplate = asn1.ObjectIdentifier{1, 3, 6, 1, 4, 1, 311, 21, 7}
+
+// certificateTemplateID is the CertificateTemplateOID structure of MS-WCCE 2.2.2.7.7.2. Both
+// versions are optional, and the minor version is only encoded after a major version.
+type certificateTemplateID struct {
+	TemplateID asn1.ObjectIdentifier
+}
+
+type certificateTemplateIDMajor struct {
+	TemplateID   asn1.ObjectIdentifier
+	MajorVersion int64
+}
+
+type certificateTemplateIDVersion struct {
+	TemplateID   asn1.ObjectIdentifier
+	MajorVersion int64
+	MinorVersion int64
+}
+
+// templateExtension returns the Certificate Template Information extension naming template_oid and
+// its versions, nil when template_oid is not set. Certreq embeds it in requests for version 2
+// templates, and some policy module configurations require it rather than the template request
+// attribute.
+func (m *generateCSRModel) templateExtension() (*pkix.Extension, error) {
+	if m.TemplateOID.IsNull() {
+		return nil, nil
+	}
+	oid, err := parseObjectIdentifier(m.TemplateOID.ValueString())
+	if err != nil {
+		return nil, err
+	}
+
+	var value interface{} = certificateTemplateID{TemplateID: oid}
+	switch {
+	case !m.TemplateMinorVersion.IsNull():
+		value = certificateTemplateIDVersion{TemplateID: oid, MajorVersion: m.TemplateMajorVersion.ValueInt64(), MinorVersion: m.TemplateMinorVersion.ValueInt64()}
+	case !m.TemplateMajorVersion.IsNull():
+		value = certificateTemplateIDMajor{TemplateID: oid, MajorVersion: m.TemplateMajorVersion.ValueInt64()}
+	}
+	der, err := asn1.Marshal(value)
+	if err != nil {
+		return nil, fmt.Errorf("could not encode the certificate template extension: %v", err)
+	}
+	return &pkix.Extension{Id: oidCertificateTemplate, Value: der}, nil
+}
+
+// parseObjectIdentifier parses a dotted object identifier such as "1.3.6.1.4.1.311.21.8.1.2".
+func parseObjectIdentifier(value string) (asn1.ObjectIdentifier, error) {
+	parts := strings.Split(value, ".")
+	if len(parts) < 2 {
+		return nil, fmt.Errorf("%q is not a dotted object identifier such as 1.3.6.1.4.1.311.21.8.1.2", value)
+	}
+	oid := make(asn1.ObjectIdentifier, len(parts))
+	for i, part := range parts {
+		arc, err := strconv.Atoi(part)
+		if err != nil || arc < 0 {
+			return nil, fmt.Errorf("%q is not a dotted object identifier such as 1.3.6.1.4.1.311.21.8.1.2", value)
+		}
+		oid[i] = arc
+	}
+	if oid[0] > 2 || (oid[0] < 2 && oid[1] > 39) {
+		return nil, fmt.Errorf("%q is not a valid object identifier", value)
+	}
+	return oid, nil
+}
+
+// validateTemplateExtension checks template_oid and the template versions of the generate_csr
+// block. at returns the path of an attribute by name.
+func (m *generateCSRModel) validateTemplateExtension(at func(string) path.Path) diag.Diagnostics {
+	var diags diag.Diagnostics
+
+	if !m.TemplateOID.IsNull() && !m.TemplateOID.IsUnknown() {
+		if _, err := parseObjectIdentifier(m.TemplateOID.ValueString()); err != nil {
+			diags.AddAttributeError(at("template_oid"), "Invalid Template OID", err.Error()+".")
+		}
+	}
+	for _, version := range []struct {
+		attribute string
+		value     int64
+		set       bool
+	}{
+		{attribute: "template_major_version", value: m.TemplateMajorVersion.ValueInt64(), set: !m.TemplateMajorVersion.IsNull()},
+		{attribute: "template_minor_version", value: m.TemplateMinorVersion.ValueInt64(), set: !m.TemplateMinorVersion.IsNull()},
+	} {
+		if !version.set {
+			continue
+		}
+		if m.TemplateOID.IsNull() {
+			diags.AddAttributeError(at(version.attribute), "Missing Template OID",
+				fmt.Sprintf("%s is part of the certificate template extension, set template_oid as well.", version.attribute))
+		}
+		if version.value < 0 || version.value > math.MaxUint32 {
+			diags.AddAttributeError(at(version.attribute), "Invalid Template Version",
+				fmt.Sprintf("%s must be between 0 and %d, got %d.", version.attribute, uint32(math.MaxUint32), version.value))
+		}
+	}
+	if !m.TemplateMinorVersion.IsNull() && m.TemplateMajorVersion.IsNull() {
+		diags.AddAttributeError(at("template_minor_version"), "Missing Template Major Version",
+			"The certificate template extension only carries a minor version after the major version, set template_major_version as well.")
+	}
+	return diags
+}
@@ -0,0 +1,95 @@
+package provider
+
+import (
+	"bytes"
+	"context"
+	"encoding/asn1"
+	"strings"
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-framework/path"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+)
+
+func TestGenerateTemplateExtension(t *testing.T) {
+	oid := asn1.ObjectIdentifier{1, 3, 6, 1, 4, 1, 311, 21, 8, 1, 2}
+	for name, tc := range map[string]struct {
+		block generateCSRModel
+		want  interface{}
+	}{
+		"oid only": {
+			block: generateCSRModel{TemplateOID: types.StringValue("1.3.6.1.4.1.311.21.8.1.2")},
+			want:  certificateTemplateID{TemplateID: oid},
+		},
+		// A minor version of 0 is encoded, unlike an omitted one.
+		"versions": {
+			block: generateCSRModel{
+				TemplateOID:          types.StringValue("1.3.6.1.4.1.311.21.8.1.2"),
+				TemplateMajorVersion: types.Int64Value(100),
+				TemplateMinorVersion: types.Int64Value(0),
+			},
+			want: certificateTemplateIDVersion{TemplateID: oid, MajorVersion: 100},
+		},
+	} {
+		tc.block.CommonName = types.StringValue("web.example.com")
+		tc.block.DNSNames = types.ListNull(types.StringType)
+		tc.block.IPAddresses = types.ListNull(types.StringType)
+		tc.block.EmailAddresses = types.ListNull(types.StringType)
+		csrPEM, _, diags := tc.block.generate(context.Background())
+		if diags.HasError() {
+			t.Fatalf("%s: generate() = %v", name, diags)
+		}
+		request, err := parseCertificateRequest(csrPEM)
+		if err != nil {
+			t.Fatal(err)
+		}
+		want, err := asn1.Marshal(tc.want)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		var got []byte
+		for _, extension := range request.Extensions {
+			if extension.Id.Equal(oidCertificateTemplate) {
+				got = extension.Value
+			}
+		}
+		if !bytes.Equal(got, want) {
+			t.Errorf("%s: template extension = %x, want %x", name, got, want)
+		}
+	}
+}
+
+func TestValidateTemplateExtension(t *testing.T) {
+	for name, tc := range map[string]struct {
+		block generateCSRModel
+		want  string
+	}{
+		"unset": {},
+		"oid":   {block: generateCSRModel{TemplateOID: types.StringValue("1.3.6.1.4.1.311.21.8.1.2"), TemplateMajorVersion: types.Int64Value(100)}},
+		"template name": {
+			block: generateCSRModel{TemplateOID: types.StringValue("WebServer")},
+			want:  "Invalid Template OID",
+		},
+		"version without oid": {
+			block: generateCSRModel{TemplateMajorVersion: types.Int64Value(100)},
+			want:  "Missing Template OID",
+		},
+		"minor without major": {
+			block: generateCSRModel{TemplateOID: types.StringValue("1.3.6.1.4.1.311.21.8.1.2"), TemplateMinorVersion: types.Int64Value(4)},
+			want:  "Missing Template Major Version",
+		},
+		"negative": {
+			block: generateCSRModel{TemplateOID: types.StringValue("1.3.6.1.4.1.311.21.8.1.2"), TemplateMajorVersion: types.Int64Value(-1)},
+			want:  "Invalid Template Version",
+		},
+	} {
+		diags := tc.block.validateTemplateExtension(path.Root("generate_csr").AtName)
+		switch {
+		case tc.want == "" && diags.HasError():
+			t.Errorf("%s: validateTemplateExtension() = %v", name, diags)
+		case tc.want != "" && (!diags.HasError() || !strings.Contains(diags.Errors()[0].Summary(), tc.want)):
+			t.Errorf("%s: validateTemplateExtension() = %v, want %q", name, diags, tc.want)
+		}
+	}
+}
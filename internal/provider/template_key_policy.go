@@ -0,0 +1,163 @@
+package provider
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/rsa"
+	"crypto/x509"
+	"fmt"
+	"strings"
+
+	"github.com/hashicorp/terraform-plugin-framework/diag"
+	"github.com/hashicorp/terraform-plugin-framework/path"
+	"github.com/hashicorp/terraform-plugin-framework/types/basetypes"
+	"github.com/hashicorp/terraform-plugin-log/tflog"
+)
+
+// requestKey describes the key and signature of a certificate signing request, in the terms
+// certificate templates use.
+type requestKey struct {
+	// algorithm is keyAlgorithmRSA or keyAlgorithmECDSA.
+	algorithm string
+	bits      int
+	// curve is the ECDSA curve, such as "P256".
+	curve string
+	// hash signs the request, such as "SHA256".
+	hash string
+}
+
+func (k requestKey) String() string {
+	if k.algorithm == keyAlgorithmECDSA {
+		return keyAlgorithmECDSA + " " + k.curve
+	}
+	return fmt.Sprintf("%s %d", k.algorithm, k.bits)
+}
+
+// keyOfRequest describes the key of a certificate signing request, false for keys ADCS does not
+// issue certificates for.
+func keyOfRequest(request *x509.CertificateRequest) (requestKey, bool) {
+	var key requestKey
+	switch pub := request.PublicKey.(type) {
+	case *rsa.PublicKey:
+		key = requestKey{algorithm: keyAlgorithmRSA, bits: pub.N.BitLen()}
+	case *ecdsa.PublicKey:
+		bits := pub.Curve.Params().BitSize
+		key = requestKey{algorithm: keyAlgorithmECDSA, bits: bits, curve: fmt.Sprintf("P%d", bits)}
+	default:
+		return key, false
+	}
+	switch request.SignatureAlgorithm {
+	case x509.SHA1WithRSA, x509.ECDSAWithSHA1:
+		key.hash = "SHA1"
+	case x509.SHA256WithRSA, x509.SHA256WithRSAPSS, x509.ECDSAWithSHA256:
+		key.hash = "SHA256"
+	case x509.SHA384WithRSA, x509.SHA384WithRSAPSS, x509.ECDSAWithSHA384:
+		key.hash = "SHA384"
+	case x509.SHA512WithRSA, x509.SHA512WithRSAPSS, x509.ECDSAWithSHA512:
+		key.hash = "SHA512"
+	}
+	return key, true
+}
+
+// keyOfGenerateCSR describes the key generate_csr creates and the hash signatureAlgorithm signs
+// its request with, false while the block is not known yet.
+func keyOfGenerateCSR(m generateCSRModel) (requestKey, bool) {
+	if m.KeyAlgorithm.IsUnknown() || m.RSABits.IsUnknown() || m.ECDSACurve.IsUnknown() {
+		return requestKey{}, false
+	}
+	switch m.keyAlgorithm() {
+	case keyAlgorithmRSA:
+		return requestKey{algorithm: keyAlgorithmRSA, bits: int(m.rsaBits()), hash: "SHA256"}, true
+	case keyAlgorithmECDSA:
+		curve, ok := ecdsaCurves[m.ecdsaCurve()]
+		if !ok {
+			return requestKey{}, false
+		}
+		key := requestKey{algorithm: keyAlgorithmECDSA, bits: curve.Params().BitSize, curve: m.ecdsaCurve(), hash: "SHA256"}
+		switch key.bits {
+		case 384:
+			key.hash = "SHA384"
+		case 521:
+			key.hash = "SHA512"
+		}
+		return key, true
+	}
+	return requestKey{}, false
+}
+
+// checkKey returns why the CA would reject a request for key from the template: a key algorithm
+// other than the one of a version 3 template, or a key smaller than its minimal key size.
+func (t *templateInfo) checkKey(key requestKey) error {
+	if t.KeyAlgorithm != "" {
+		// Version 3 templates name ECDSA keys by curve, such as ECDSA_P256, and accept keys of
+		// ECDH templates for the same curve.
+		want := strings.ToUpper(t.KeyAlgorithm)
+		matches := want == key.algorithm
+		if key.algorithm == keyAlgorithmECDSA {
+			matches = want == "ECDSA_"+key.curve || want == "ECDH_"+key.curve
+		}
+		if !matches {
+			return fmt.Errorf("template %q requires %s keys, the request has %s", t.Name, strings.Replace(t.KeyAlgorithm, "_", " ", 1), key)
+		}
+	}
+	// The cryptographic providers of older templates only create RSA keys, their minimal key size
+	// does not apply to ECDSA keys.
+	if key.bits < t.MinimalKeySize && (key.algorithm == keyAlgorithmRSA || t.KeyAlgorithm != "") {
+		required := requestKey{algorithm: key.algorithm, bits: t.MinimalKeySize, curve: fmt.Sprintf("P%d", t.MinimalKeySize)}
+		return fmt.Errorf("template %q requires %s or larger, the request has %s", t.Name, required, key)
+	}
+	return nil
+}
+
+// checkTemplateKeyPolicy checks at plan time that the certificate signing request, or the one
+// generate_csr is going to create, is for a key the template accepts, so the CA does not reject the
+// submission at apply. Templates are only checked when ldap_url is configured.
+func (r *certificateResource) checkTemplateKeyPolicy(ctx context.Context, plan certificateCreateModel, diags *diag.Diagnostics) {
+	if r.provider == nil || r.provider.templates == nil || plan.Template.IsUnknown() || !plan.AdoptRequestID.IsNull() {
+		return
+	}
+
+	var key requestKey
+	var ok bool
+	attribute := path.Root("certificate_signing_request")
+	switch {
+	case !plan.GenerateCSR.IsNull() && !plan.GenerateCSR.IsUnknown():
+		var block generateCSRModel
+		if diags.Append(plan.GenerateCSR.As(ctx, &block, basetypes.ObjectAsOptions{})...); diags.HasError() {
+			return
+		}
+		if profile, ok := plan.profile(); ok {
+			block.applyProfile(profile)
+		}
+		key, ok = keyOfGenerateCSR(block)
+		attribute = path.Root("generate_csr")
+	case !plan.CSR.IsNull() && !plan.CSR.IsUnknown():
+		if request, err := parseCertificateRequest(plan.CSR.ValueString()); err == nil {
+			key, ok = keyOfRequest(request)
+		}
+	}
+	if !ok {
+		return
+	}
+
+	template, err := r.provider.templates.lookup(ctx, plan.Template.ValueString())
+	if err != nil {
+		tflog.Warn(ctx, "Could not check the key of the request against the certificate template", map[string]interface{}{
+			"template": plan.Template.ValueString(),
+			"error":    err.Error(),
+		})
+		return
+	}
+	if err := template.checkKey(key); err != nil {
+		diags.AddAttributeError(attribute, "Key Not Accepted by Certificate Template", err.Error()+".")
+		return
+	}
+	if template.HashAlgorithm != "" && key.hash != "" && !strings.EqualFold(template.HashAlgorithm, key.hash) {
+		diags.AddAttributeWarning(
+			attribute,
+			"Request Hash Differs From Certificate Template",
+			fmt.Sprintf("Template %q asks for requests signed with %s, the request is signed with %s. "+
+				"Sign the request with %s if a policy module on the CA enforces the template settings.", template.Name, template.HashAlgorithm, key.hash, template.HashAlgorithm),
+		)
+	}
+}
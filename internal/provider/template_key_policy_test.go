@@ -0,0 +1,93 @@
+package provider
+
+import (
+	"context"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-framework/diag"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+)
+
+func TestCryptographyPolicies(t *testing.T) {
+	got := cryptographyPolicies([]string{
+		"msPKI-Asymmetric-Algorithm`PZPWSTR`ECDSA_P384`msPKI-Key-Usage`DWORD`16777215`msPKI-Symmetric-Algorithm`PZPWSTR`3DES`msPKI-Symmetric-Key-Length`DWORD`168`msPKI-Hash-Algorithm`PZPWSTR`SHA384`",
+		// Issuance requirements of older templates.
+		"1.3.6.1.4.1.311.20.2.1",
+	})
+	if got["msPKI-Asymmetric-Algorithm"] != "ECDSA_P384" || got["msPKI-Hash-Algorithm"] != "SHA384" || len(got) != 5 {
+		t.Errorf("cryptographyPolicies() = %v", got)
+	}
+}
+
+func TestTemplateCheckKey(t *testing.T) {
+	rsa2048 := requestKey{algorithm: keyAlgorithmRSA, bits: 2048, hash: "SHA256"}
+	p256 := requestKey{algorithm: keyAlgorithmECDSA, bits: 256, curve: "P256", hash: "SHA256"}
+	for name, tc := range map[string]struct {
+		template templateInfo
+		key      requestKey
+		want     string
+	}{
+		"version 2":       {template: templateInfo{Name: "WebServer", MinimalKeySize: 2048}, key: rsa2048},
+		"too small":       {template: templateInfo{Name: "WebServer", MinimalKeySize: 4096}, key: rsa2048, want: `template "WebServer" requires RSA 4096 or larger, the request has RSA 2048`},
+		"ecdsa too small": {template: templateInfo{Name: "WebServerV3", KeyAlgorithm: "ECDH_P256", MinimalKeySize: 384}, key: p256, want: `requires ECDSA P384 or larger, the request has ECDSA P256`},
+		"ecdsa version 2": {template: templateInfo{Name: "WebServer", MinimalKeySize: 2048}, key: p256},
+		"wrong algorithm": {template: templateInfo{Name: "WebServerV3", KeyAlgorithm: "ECDSA_P256"}, key: rsa2048, want: `requires ECDSA P256 keys, the request has RSA 2048`},
+		"wrong curve":     {template: templateInfo{Name: "WebServerV3", KeyAlgorithm: "ECDSA_P384"}, key: p256, want: `requires ECDSA P384 keys`},
+		"curve":           {template: templateInfo{Name: "WebServerV3", KeyAlgorithm: "ECDSA_P256", MinimalKeySize: 256}, key: p256},
+		"ecdh curve":      {template: templateInfo{Name: "WebServerV3", KeyAlgorithm: "ECDH_P256"}, key: p256},
+	} {
+		err := tc.template.checkKey(tc.key)
+		switch {
+		case tc.want == "" && err != nil:
+			t.Errorf("%s: checkKey() = %v", name, err)
+		case tc.want != "" && (err == nil || !strings.Contains(err.Error(), tc.want)):
+			t.Errorf("%s: checkKey() = %v, want %q", name, err, tc.want)
+		}
+	}
+}
+
+func TestKeyOfGenerateCSR(t *testing.T) {
+	for name, tc := range map[string]struct {
+		block generateCSRModel
+		want  requestKey
+	}{
+		"default": {want: requestKey{algorithm: keyAlgorithmRSA, bits: 2048, hash: "SHA256"}},
+		"p384": {
+			block: generateCSRModel{KeyAlgorithm: types.StringValue("ECDSA"), ECDSACurve: types.StringValue("P384")},
+			want:  requestKey{algorithm: keyAlgorithmECDSA, bits: 384, curve: "P384", hash: "SHA384"},
+		},
+	} {
+		if got, ok := keyOfGenerateCSR(tc.block); !ok || !reflect.DeepEqual(got, tc.want) {
+			t.Errorf("%s: keyOfGenerateCSR() = %v, %t, want %v", name, got, ok, tc.want)
+		}
+	}
+}
+
+func TestCheckTemplateKeyPolicy(t *testing.T) {
+	ctx := context.Background()
+	// debug-enroll requests are for ECDSA P-256 keys.
+	csr, err := debugEnrollRequest(ctx, "", "app.example.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+	r := &certificateResource{provider: &providerData{templates: &templateDirectory{cache: map[string]*templateInfo{
+		"WebServerP384": {Name: "WebServerP384", KeyAlgorithm: "ECDSA_P384"},
+		"WebServerV3":   {Name: "WebServerV3", KeyAlgorithm: "ECDSA_P256", HashAlgorithm: "SHA384"},
+	}}}}
+
+	var diags diag.Diagnostics
+	plan := certificateCreateModel{CSR: types.StringValue(csr), Template: types.StringValue("WebServerP384"), GenerateCSR: types.ObjectNull(nil)}
+	r.checkTemplateKeyPolicy(ctx, plan, &diags)
+	if !diags.HasError() || !strings.Contains(diags.Errors()[0].Detail(), "requires ECDSA P384 keys, the request has ECDSA P256") {
+		t.Errorf("checkTemplateKeyPolicy() = %v, want the key rejected", diags)
+	}
+
+	diags = nil
+	plan.Template = types.StringValue("WebServerV3")
+	r.checkTemplateKeyPolicy(ctx, plan, &diags)
+	if diags.HasError() || diags.WarningsCount() != 1 {
+		t.Errorf("checkTemplateKeyPolicy() = %v, want a warning about the hash", diags)
+	}
+}
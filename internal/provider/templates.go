@@ -0,0 +1,153 @@
+package provider
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/go-ldap/ldap/v3"
+	"github.com/hashicorp/terraform-plugin-log/tflog"
+)
+
+// Flags of the msPKI-Enrollment-Flag template attribute.
+const (
+	// ctFlagPendAllRequests makes the CA hold every request for CA manager approval.
+	ctFlagPendAllRequests = 0x2
+)
+
+// Flags of the msPKI-Certificate-Name-Flag template attribute.
+const (
+	// ctFlagEnrolleeSuppliesSubject takes the subject from the request.
+	ctFlagEnrolleeSuppliesSubject = 0x1
+	// ctFlagEnrolleeSuppliesSubjectAltName takes the subject alternative names from the request.
+	ctFlagEnrolleeSuppliesSubjectAltName = 0x10000
+)
+
+// templateInfo holds the enrollment settings of a certificate template as published in Active Directory.
+type templateInfo struct {
+	Name        string
+	DisplayName string
+	// OID is the object identifier of the template, which the Certificate Template Information
+	// extension of issued certificates names.
+	OID string
+	// RequiresApproval is set when requests are held for CA manager approval, either because the
+	// template says so or because it requires additional authorized signatures.
+	RequiresApproval bool
+	// SuppliesSubject is set when the subject or the subject alternative names are taken from the
+	// request. Otherwise the CA builds them from the Active Directory object of the requester and
+	// ignores the names asked for.
+	SuppliesSubject bool
+	// MinimalKeySize is the smallest key in bits the CA accepts requests for, 0 when not set.
+	MinimalKeySize int
+	// KeyAlgorithm and HashAlgorithm are the key algorithm, such as "RSA" or "ECDSA_P384", and the
+	// hash requests are signed with, such as "SHA256", of version 3 templates. They are empty for
+	// older templates, which leave both to the cryptographic provider.
+	KeyAlgorithm  string
+	HashAlgorithm string
+}
+
+// templateDirectory looks up certificate templates in the configuration partition of Active
+// Directory. Lookups are cached for the lifetime of the provider, templates rarely change during a run.
+type templateDirectory struct {
+	url      string
+	baseDN   string
+	username string
+	password string
+
+	mu    sync.Mutex
+	cache map[string]*templateInfo
+}
+
+// lookup returns the settings of the template with the given common name, the name certsrv
+// requests refer to.
+func (d *templateDirectory) lookup(ctx context.Context, name string) (*templateInfo, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if info, ok := d.cache[name]; ok {
+		return info, nil
+	}
+
+	conn, baseDN, err := d.connect()
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+
+	tflog.Debug(ctx, "Looking up certificate template in Active Directory", map[string]interface{}{
+		"template": name,
+		"base_dn":  baseDN,
+	})
+	result, err := conn.Search(ldap.NewSearchRequest(
+		templatesDN(baseDN),
+		ldap.ScopeSingleLevel, ldap.NeverDerefAliases, 1, 0, false,
+		fmt.Sprintf("(&(objectClass=pKICertificateTemplate)(cn=%s))", ldap.EscapeFilter(name)),
+		[]string{"cn", "displayName", "msPKI-Enrollment-Flag", "msPKI-RA-Signature", "msPKI-Certificate-Name-Flag", "msPKI-Minimal-Key-Size", "msPKI-RA-Application-Policies", "msPKI-Cert-Template-OID"},
+		nil,
+	))
+	if err != nil {
+		return nil, fmt.Errorf("could not search for template %q: %v", name, err)
+	}
+	if len(result.Entries) == 0 {
+		return nil, fmt.Errorf("template %q was not found in Active Directory", name)
+	}
+
+	entry := result.Entries[0]
+	enrollmentFlag, _ := strconv.ParseInt(entry.GetAttributeValue("msPKI-Enrollment-Flag"), 10, 64)
+	raSignatures, _ := strconv.ParseInt(entry.GetAttributeValue("msPKI-RA-Signature"), 10, 64)
+	// The flag is stored as a signed 32 bit integer.
+	nameFlag, _ := strconv.ParseInt(entry.GetAttributeValue("msPKI-Certificate-Name-Flag"), 10, 32)
+	minimalKeySize, _ := strconv.Atoi(entry.GetAttributeValue("msPKI-Minimal-Key-Size"))
+	policies := cryptographyPolicies(entry.GetAttributeValues("msPKI-RA-Application-Policies"))
+	info := &templateInfo{
+		Name:             entry.GetAttributeValue("cn"),
+		DisplayName:      entry.GetAttributeValue("displayName"),
+		OID:              entry.GetAttributeValue("msPKI-Cert-Template-OID"),
+		RequiresApproval: enrollmentFlag&ctFlagPendAllRequests != 0 || raSignatures > 0,
+		SuppliesSubject:  nameFlag&(ctFlagEnrolleeSuppliesSubject|ctFlagEnrolleeSuppliesSubjectAltName) != 0,
+		MinimalKeySize:   minimalKeySize,
+		KeyAlgorithm:     policies["msPKI-Asymmetric-Algorithm"],
+		HashAlgorithm:    policies["msPKI-Hash-Algorithm"],
+	}
+
+	if d.cache == nil {
+		d.cache = map[string]*templateInfo{}
+	}
+	d.cache[name] = info
+	return info, nil
+}
+
+// cryptographyPolicies reads the cryptography settings version 3 templates keep in
+// msPKI-RA-Application-Policies as name`type`value triples, such as
+// "msPKI-Asymmetric-Algorithm`PZPWSTR`RSA`msPKI-Hash-Algorithm`PZPWSTR`SHA256`". Older templates
+// keep the application policy OIDs of issuance requirements there instead, which are skipped.
+func cryptographyPolicies(values []string) map[string]string {
+	policies := map[string]string{}
+	for _, value := range values {
+		fields := strings.Split(strings.TrimSuffix(value, "`"), "`")
+		if len(fields) < 3 {
+			continue
+		}
+		for i := 0; i+2 < len(fields); i += 3 {
+			policies[fields[i]] = fields[i+2]
+		}
+	}
+	return policies
+}
+
+// configurationNamingContext reads the configuration partition from the RootDSE.
+func configurationNamingContext(conn *ldap.Conn) (string, error) {
+	result, err := conn.Search(ldap.NewSearchRequest(
+		"", ldap.ScopeBaseObject, ldap.NeverDerefAliases, 1, 0, false,
+		"(objectClass=*)", []string{"configurationNamingContext"}, nil,
+	))
+	if err != nil {
+		return "", fmt.Errorf("could not read the RootDSE: %v", err)
+	}
+	if len(result.Entries) == 0 || result.Entries[0].GetAttributeValue("configurationNamingContext") == "" {
+		return "", fmt.Errorf("the RootDSE does not advertise a configurationNamingContext, set ldap_base_dn")
+	}
+	return result.Entries[0].GetAttributeValue("configurationNamingContext"), nil
+}
@@ -0,0 +1,119 @@
+package provider
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/hashicorp/terraform-plugin-framework/attr"
+	"github.com/hashicorp/terraform-plugin-framework/diag"
+	"github.com/hashicorp/terraform-plugin-framework/path"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+	"github.com/hashicorp/terraform-plugin-framework/types/basetypes"
+)
+
+// timeoutsAttrTypes are the attribute types of the timeouts block.
+var timeoutsAttrTypes = map[string]attr.Type{
+	"create": types.StringType,
+	"read":   types.StringType,
+}
+
+// timeoutsModel maps the timeouts block.
+type timeoutsModel struct {
+	Create types.String `tfsdk:"create"`
+	Read   types.String `tfsdk:"read"`
+}
+
+// timeoutsBlock is the timeouts block of the certificate resource, shaped like the one of the
+// terraform-plugin-framework-timeouts module so configurations read the same as for other providers.
+func timeoutsBlock() schema.Block {
+	return schema.SingleNestedBlock{
+		Description: `How long creating and refreshing the certificate may take, as durations such as "30m" or "2h". Without a
+timeout an operation only ends when Terraform is interrupted or, for requests pending approval, at issuance_timeout.`,
+		Attributes: map[string]schema.Attribute{
+			"create": schema.StringAttribute{
+				Optional: true,
+				Description: `Time allowed for creating the certificate, including generating the key, submitting the request and,
+with wait_for_issuance, waiting for a CA manager to approve it.`,
+			},
+			"read": schema.StringAttribute{
+				Optional:    true,
+				Description: "Time allowed for refreshing the certificate from the CA.",
+			},
+		},
+	}
+}
+
+// operationTimeout returns the duration of an operation from the timeouts block, zero when none is set.
+// ValidateConfig checks the format.
+func operationTimeout(ctx context.Context, timeouts types.Object, operation string) time.Duration {
+	if timeouts.IsNull() || timeouts.IsUnknown() {
+		return 0
+	}
+	var block timeoutsModel
+	if diags := timeouts.As(ctx, &block, basetypes.ObjectAsOptions{}); diags.HasError() {
+		return 0
+	}
+	value := block.Create
+	if operation == "read" {
+		value = block.Read
+	}
+	d, err := time.ParseDuration(value.ValueString())
+	if err != nil {
+		return 0
+	}
+	return d
+}
+
+// withTimeout bounds ctx by the timeout of an operation, if one is set.
+func withTimeout(ctx context.Context, timeouts types.Object, operation string) (context.Context, context.CancelFunc, time.Duration) {
+	d := operationTimeout(ctx, timeouts, operation)
+	if d <= 0 {
+		ctx, cancel := context.WithCancel(ctx)
+		return ctx, cancel, 0
+	}
+	ctx, cancel := context.WithTimeout(ctx, d)
+	return ctx, cancel, d
+}
+
+// timeoutExceeded reports an operation that ran out of its timeout, after the error of the call that
+// was cut short.
+func timeoutExceeded(ctx context.Context, operation string, d time.Duration) diag.Diagnostics {
+	var diags diag.Diagnostics
+	if d > 0 && errors.Is(ctx.Err(), context.DeadlineExceeded) {
+		diags.AddAttributeError(
+			path.Root("timeouts").AtName(operation),
+			"Timeout Exceeded",
+			fmt.Sprintf("The %s timeout of %s ran out. Raise timeouts.%s for slow CAs or templates that need approval.", operation, d, operation),
+		)
+	}
+	return diags
+}
+
+// validateTimeouts checks the durations of the timeouts block.
+func validateTimeouts(ctx context.Context, timeouts types.Object) diag.Diagnostics {
+	var diags diag.Diagnostics
+	if timeouts.IsNull() || timeouts.IsUnknown() {
+		return diags
+	}
+	var block timeoutsModel
+	diags.Append(timeouts.As(ctx, &block, basetypes.ObjectAsOptions{})...)
+	if diags.HasError() {
+		return diags
+	}
+	for operation, value := range map[string]types.String{"create": block.Create, "read": block.Read} {
+		if value.IsNull() || value.IsUnknown() {
+			continue
+		}
+		if d, err := time.ParseDuration(value.ValueString()); err != nil || d <= 0 {
+			diags.AddAttributeError(
+				path.Root("timeouts").AtName(operation),
+				"Invalid Timeout",
+				fmt.Sprintf("timeouts.%s %q is not a positive duration such as \"30m\" or \"2h\".", operation, value.ValueString()),
+			)
+		}
+	}
+	return diags
+}
@@ -0,0 +1,141 @@
+package provider
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"regexp"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/flipyap/microsoft-adcs-client/client"
+	"github.com/hashicorp/terraform-plugin-framework/attr"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+	"github.com/hashicorp/terraform-plugin-testing/helper/resource"
+	"github.com/hashicorp/terraform-plugin-testing/terraform"
+)
+
+func testTimeouts(create string, read string) types.Object {
+	value := func(s string) attr.Value {
+		if s == "" {
+			return types.StringNull()
+		}
+		return types.StringValue(s)
+	}
+	return types.ObjectValueMust(timeoutsAttrTypes, map[string]attr.Value{"create": value(create), "read": value(read)})
+}
+
+func TestValidateTimeouts(t *testing.T) {
+	for name, tc := range map[string]struct {
+		timeouts types.Object
+		err      string
+	}{
+		"unset":    {timeouts: types.ObjectNull(timeoutsAttrTypes)},
+		"valid":    {timeouts: testTimeouts("2h", "5m")},
+		"partial":  {timeouts: testTimeouts("", "30s")},
+		"invalid":  {timeouts: testTimeouts("two hours", ""), err: "timeouts.create"},
+		"negative": {timeouts: testTimeouts("", "-5m"), err: "timeouts.read"},
+	} {
+		t.Run(name, func(t *testing.T) {
+			diags := validateTimeouts(context.Background(), tc.timeouts)
+			if tc.err == "" && diags.HasError() {
+				t.Fatalf("unexpected diagnostics: %v", diags)
+			}
+			if tc.err != "" && (!diags.HasError() || !strings.Contains(diags[0].Detail(), tc.err)) {
+				t.Fatalf("diagnostics = %v, want %q", diags, tc.err)
+			}
+		})
+	}
+}
+
+func TestWithTimeout(t *testing.T) {
+	ctx, cancel, d := withTimeout(context.Background(), testTimeouts("", "50ms"), "read")
+	defer cancel()
+	if d != 50*time.Millisecond {
+		t.Fatalf("read timeout = %s, want 50ms", d)
+	}
+	<-ctx.Done()
+	if diags := timeoutExceeded(ctx, "read", d); !diags.HasError() {
+		t.Error("an expired timeout should be reported")
+	}
+
+	ctx, cancel, d = withTimeout(context.Background(), testTimeouts("", "50ms"), "create")
+	defer cancel()
+	if _, ok := ctx.Deadline(); ok || d != 0 {
+		t.Errorf("create without a timeout got deadline %s", d)
+	}
+	cancel()
+	if diags := timeoutExceeded(ctx, "create", d); diags.HasError() {
+		t.Errorf("a cancelled operation without a timeout is not a timeout: %v", diags)
+	}
+}
+
+func TestPendingPollerWaitCutShort(t *testing.T) {
+	poller := &pendingPoller{
+		interval: time.Millisecond,
+		budget:   issuancePollBudget,
+		check: func(_ context.Context, reqID string) (*client.Certificates, error) {
+			return nil, errors.New("certificate pending for request id " + reqID)
+		},
+		waiters: map[string][]chan issuanceResult{},
+		backoff: map[string]pendingBackoff{},
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	_, err := poller.wait(ctx, "7", time.Hour)
+	if err == nil || !strings.Contains(err.Error(), "cut short") || !isStillPending(err) {
+		t.Errorf("wait() = %v, want the wait to end with the operation", err)
+	}
+}
+
+func TestAccCertificateResourceReadTimeout(t *testing.T) {
+	srv := newAccCertsrv(t)
+	// Refreshes hang once the certificate exists, the read timeout has to end them.
+	var slow atomic.Bool
+	srv.server.Config.Handler = func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if slow.Load() && strings.HasSuffix(r.URL.Path, "/certnew.p7b") {
+				select {
+				case <-r.Context().Done():
+				case <-time.After(10 * time.Second):
+				}
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}(srv.server.Config.Handler)
+
+	config := srv.providerConfig() + `
+resource "microsoftadcs_certificate" "test" {
+  template = "WebServer"
+
+  generate_csr {
+    common_name = "app.example.com"
+  }
+
+  timeouts {
+    create = "5m"
+    read   = "1s"
+  }
+}
+`
+	resource.Test(t, resource.TestCase{
+		ProtoV6ProviderFactories: testAccProtoV6ProviderFactories,
+		Steps: []resource.TestStep{
+			{
+				Config: config,
+				Check: func(_ *terraform.State) error {
+					slow.Store(true)
+					return nil
+				},
+			},
+			{
+				Config:      config,
+				ExpectError: regexp.MustCompile("Timeout Exceeded"),
+			},
+		},
+	})
+}
@@ -0,0 +1,141 @@
+package provider
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"time"
+
+	"github.com/flipyap/microsoft-adcs-client/client"
+	httpntlm "github.com/vadimi/go-http-ntlm/v2"
+)
+
+// ntlmConnections is how many NTLM authenticated connections the provider keeps to the CA, matching
+// the default parallelism of Terraform.
+const ntlmConnections = 10
+
+// maxRedirects is how many redirects a single certsrv request follows.
+const maxRedirects = 10
+
+// responseHeaderTimeout bounds the wait for the answer to a request. Firewalls drop idle
+// keep-alive connections without telling either end, a request written to such a connection would
+// otherwise wait for an answer until the operation times out. certsrv answers within seconds, even
+// a submission the policy module takes its time over.
+const responseHeaderTimeout = 5 * time.Minute
+
+// ntlmConnectionPool authenticates requests with NTLM over connections it hands out exclusively.
+// NTLM authenticates a connection rather than a request: the challenge and the authenticate message
+// have to travel over the same connection. The client's NTLM transport shares one connection pool
+// between all requests, so under parallel operations the authenticate message of one handshake
+// can leave over a connection another handshake got its challenge on, and IIS rejects it.
+type ntlmConnectionPool struct {
+	user     string
+	password string
+	// transports each hold at most one connection. A request checks one out for its whole handshake.
+	transports chan *http.Transport
+	// trace logs every exchange of the handshakes, see debug-enroll.
+	trace bool
+	// preAuth adds headers and cookies to every exchange of the handshakes, see pre_auth_source.
+	preAuth *preAuthentication
+}
+
+func newNTLMConnectionPool(user string, password string, size int, newTransport func() *http.Transport) *ntlmConnectionPool {
+	p := &ntlmConnectionPool{
+		user:       user,
+		password:   password,
+		transports: make(chan *http.Transport, size),
+	}
+	for i := 0; i < size; i++ {
+		transport := newTransport()
+		transport.MaxConnsPerHost = 1
+		p.transports <- transport
+	}
+	return p
+}
+
+// RoundTrip performs the NTLM handshake and the request over a connection nobody else uses meanwhile.
+func (p *ntlmConnectionPool) RoundTrip(req *http.Request) (*http.Response, error) {
+	var transport *http.Transport
+	select {
+	case transport = <-p.transports:
+	case <-req.Context().Done():
+		return nil, req.Context().Err()
+	}
+	defer func() { p.transports <- transport }()
+
+	var next http.RoundTripper = transport
+	if p.trace {
+		next = tracingTransport{next: transport}
+	}
+	if p.preAuth != nil {
+		next = preAuthTransport{next: next, auth: p.preAuth}
+	}
+	ntlm := httpntlm.NtlmTransport{User: p.user, Password: p.password, RoundTripper: next}
+	resp, err := ntlm.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+
+	// The client does not close the body of responses other than 200, which would hold on to the
+	// only connection of the transport. certsrv pages are small, read them before handing the
+	// connection to the next request.
+	body, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		return nil, fmt.Errorf("error reading response body: %v", err)
+	}
+	resp.Body = io.NopCloser(bytes.NewReader(body))
+	return resp, nil
+}
+
+// newCertsrvTransport returns the transport requests to the CA are sent with, dialing the hosts
+// of host_aliases directly. Idle connections are closed before common firewall idle timeouts and
+// TCP keep-alives hold on to the firewall state of the others.
+func newCertsrvTransport(aliases map[string]string) *http.Transport {
+	dialer := &net.Dialer{Timeout: 30 * time.Second, KeepAlive: 30 * time.Second}
+	transport := &http.Transport{
+		Proxy:                 http.ProxyFromEnvironment,
+		DialContext:           dialer.DialContext,
+		MaxIdleConns:          100,
+		IdleConnTimeout:       90 * time.Second,
+		TLSHandshakeTimeout:   10 * time.Second,
+		ExpectContinueTimeout: 1 * time.Second,
+		ResponseHeaderTimeout: responseHeaderTimeout,
+	}
+	if len(aliases) > 0 {
+		transport.DialContext = aliasDialContext(aliases)
+	}
+	return transport
+}
+
+// followSameHostRedirects replaces the redirect handling of the Kerberos client, which records
+// redirects in a list shared by every request of the client. That list is appended to without a
+// lock and never emptied, so parallel redirects race and after ten redirects over the lifetime of
+// the provider every further one fails. Redirects to other hosts are not followed, the Kerberos
+// ticket is only valid for the configured host.
+func followSameHostRedirects(req *http.Request, via []*http.Request) error {
+	if len(via) >= maxRedirects {
+		return fmt.Errorf("stopped after %d redirects", maxRedirects)
+	}
+	if req.URL.Host != via[0].URL.Host {
+		return http.ErrUseLastResponse
+	}
+	return nil
+}
+
+// configureTransport makes the ADCS client safe for the parallel operations of a Terraform run and
+// for long polls over connections a firewall may drop, and applies host_aliases.
+func configureTransport(c *client.ADCSClient, username string, password string, aliases map[string]string) {
+	if c.UseNtlm {
+		c.NtlmClient.Transport = newNTLMConnectionPool(username, password, ntlmConnections, func() *http.Transport {
+			return newCertsrvTransport(aliases)
+		})
+		return
+	}
+	if c.SpnegoClient != nil && c.SpnegoClient.Client != nil {
+		c.SpnegoClient.Client.Transport = newCertsrvTransport(aliases)
+		c.SpnegoClient.Client.CheckRedirect = followSameHostRedirects
+	}
+}
@@ -0,0 +1,193 @@
+package provider
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/flipyap/microsoft-adcs-client/client"
+	httpntlm "github.com/vadimi/go-http-ntlm/v2"
+	"github.com/vadimi/go-ntlm/ntlm"
+)
+
+// connectionKey carries the NTLM state of a connection of newNTLMConnectionServer.
+type connectionKey struct{}
+
+// newNTLMConnectionServer authenticates connections the way IIS does: an authenticate message is
+// only accepted on the connection its challenge was sent over.
+func newNTLMConnectionServer(t *testing.T, next http.Handler) *httptest.Server {
+	t.Helper()
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		challenged, ok := r.Context().Value(connectionKey{}).(*bool)
+		if !ok {
+			http.Error(w, "no connection state", http.StatusInternalServerError)
+			return
+		}
+		message, _ := base64.StdEncoding.DecodeString(strings.TrimPrefix(r.Header.Get("Authorization"), "NTLM "))
+		switch {
+		case len(message) > 8 && message[8] == 1:
+			session, err := ntlm.CreateServerSession(ntlm.Version2, ntlm.ConnectionlessMode)
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusInternalServerError)
+				return
+			}
+			challenge, err := session.GenerateChallengeMessage()
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusInternalServerError)
+				return
+			}
+			*challenged = true
+			w.Header().Add("WWW-Authenticate", "NTLM "+base64.StdEncoding.EncodeToString(challenge.Bytes()))
+			w.WriteHeader(http.StatusUnauthorized)
+		case len(message) > 8 && message[8] == 3 && *challenged:
+			*challenged = false
+			next.ServeHTTP(w, r)
+		default:
+			w.Header().Add("WWW-Authenticate", "NTLM")
+			w.WriteHeader(http.StatusUnauthorized)
+		}
+	}))
+	server.Config.ConnContext = func(ctx context.Context, _ net.Conn) context.Context {
+		return context.WithValue(ctx, connectionKey{}, new(bool))
+	}
+	server.Start()
+	t.Cleanup(server.Close)
+	return server
+}
+
+// newTestNTLMClient returns an NTLM client for the server as the provider configures it.
+func newTestNTLMClient(server *httptest.Server) *client.ADCSClient {
+	c := &client.ADCSClient{
+		HostURL:    strings.TrimPrefix(server.URL, "http://"),
+		NtlmClient: &http.Client{Transport: &httpntlm.NtlmTransport{User: "svc-terraform", Password: "secret"}},
+		UseNtlm:    true,
+	}
+	configureTransport(c, "svc-terraform", "secret", nil)
+	return c
+}
+
+func TestNTLMConnectionPoolConcurrentRequests(t *testing.T) {
+	server := newNTLMConnectionServer(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// Hold on to the connection for a moment so handshakes overlap.
+		time.Sleep(time.Millisecond)
+		fmt.Fprint(w, r.URL.Query().Get("ReqID"))
+	}))
+	c := newTestNTLMClient(server)
+
+	var wg sync.WaitGroup
+	errs := make(chan error, 100)
+	for i := 0; i < 100; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			_, body, err := getCertsrvPage(context.Background(), c, fmt.Sprintf("/certsrv/certnew.cer?ReqID=%d", i))
+			if err != nil {
+				errs <- err
+				return
+			}
+			if body != fmt.Sprint(i) {
+				errs <- fmt.Errorf("request %d got the response of request %s", i, body)
+			}
+		}(i)
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		t.Error(err)
+	}
+}
+
+func TestNTLMConnectionPoolReleasesFailedResponses(t *testing.T) {
+	server := newNTLMConnectionServer(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/missing" {
+			http.NotFound(w, r)
+			return
+		}
+		fmt.Fprint(w, "ok")
+	}))
+	c := newTestNTLMClient(server)
+
+	// The client never closes the body of a failed response.
+	for i := 0; i < 2*ntlmConnections; i++ {
+		if _, _, err := getCertsrvPage(context.Background(), c, "/missing"); responseStatus(err) != http.StatusNotFound {
+			t.Fatalf("expected a 404, got %v", err)
+		}
+	}
+
+	done := make(chan error, 1)
+	go func() {
+		_, _, err := getCertsrvPage(context.Background(), c, "/certsrv/")
+		done <- err
+	}()
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Errorf("unexpected error: %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("the request did not get a connection after earlier requests failed")
+	}
+}
+
+func TestFollowSameHostRedirects(t *testing.T) {
+	via := []*http.Request{{URL: &url.URL{Scheme: "http", Host: "ca.company.local", Path: "/certsrv"}}}
+	if err := followSameHostRedirects(&http.Request{URL: &url.URL{Scheme: "http", Host: "ca.company.local", Path: "/certsrv/"}}, via); err != nil {
+		t.Errorf("a redirect on the same host was not followed: %v", err)
+	}
+	if err := followSameHostRedirects(&http.Request{URL: &url.URL{Scheme: "http", Host: "login.example.com"}}, via); err != http.ErrUseLastResponse {
+		t.Errorf("a redirect to another host was followed: %v", err)
+	}
+	for len(via) < maxRedirects {
+		via = append(via, via[0])
+	}
+	if err := followSameHostRedirects(&http.Request{URL: via[0].URL}, via); err == nil {
+		t.Error("redirects were followed past the limit")
+	}
+}
+
+func TestResponseHeaderTimeout(t *testing.T) {
+	// A connection a firewall dropped takes requests but never answers them.
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer listener.Close()
+	var mu sync.Mutex
+	var conns []net.Conn
+	t.Cleanup(func() {
+		mu.Lock()
+		defer mu.Unlock()
+		for _, conn := range conns {
+			conn.Close()
+		}
+	})
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			mu.Lock()
+			conns = append(conns, conn)
+			mu.Unlock()
+		}
+	}()
+
+	transport := newCertsrvTransport(nil)
+	if transport.ResponseHeaderTimeout != responseHeaderTimeout {
+		t.Errorf("ResponseHeaderTimeout = %s, want %s", transport.ResponseHeaderTimeout, responseHeaderTimeout)
+	}
+	transport.ResponseHeaderTimeout = 50 * time.Millisecond
+	_, err = (&http.Client{Transport: transport}).Get("http://" + listener.Addr().String() + "/certsrv/certnew.cer")
+	if err == nil || !isRetryable(err) {
+		t.Errorf("request over a dropped connection = %v, want a retryable timeout", err)
+	}
+}
@@ -0,0 +1,210 @@
+package provider
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/flipyap/microsoft-adcs-client/client"
+	"github.com/hashicorp/terraform-plugin-framework/diag"
+	"github.com/hashicorp/terraform-plugin-framework/path"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+)
+
+// expirationDateAttribute is the request attribute asking for a specific end date. The CA honors
+// it only with the EDITF_ATTRIBUTEENDDATE policy flag, and never beyond the lifetime of its own
+// certificate.
+const expirationDateAttribute = "ExpirationDate"
+
+// Request attributes asking for a validity period counted from issuance, such as 2 Years. Like
+// ExpirationDate, the CA honors them only with the EDITF_ATTRIBUTEENDDATE policy flag.
+const (
+	validityPeriodAttribute      = "ValidityPeriod"
+	validityPeriodUnitsAttribute = "ValidityPeriodUnits"
+)
+
+// validityPeriods lists the values of validity_period, the units the CA accepts in ValidityPeriod.
+var validityPeriods = []string{"Seconds", "Minutes", "Hours", "Days", "Weeks", "Months", "Years"}
+
+// validityTolerance is how far the issued validity may be from the requested dates before a
+// warning is shown, covering the rounding of the CA and the clock skew allowance it backdates by.
+const validityTolerance = 15 * time.Minute
+
+// parseRequestedTime parses requested_not_before or requested_not_after, null when not set.
+func parseRequestedTime(value types.String) (time.Time, error) {
+	if value.IsNull() || value.IsUnknown() {
+		return time.Time{}, nil
+	}
+	return time.Parse(time.RFC3339, value.ValueString())
+}
+
+// expirationDateRequestAttribute returns the ExpirationDate request attribute for
+// requested_not_after, empty when it is not set. The CA expects the HTTP date format in GMT.
+func (m *certificateCreateModel) expirationDateRequestAttribute() string {
+	notAfter, err := parseRequestedTime(m.RequestedNotAfter)
+	if err != nil || notAfter.IsZero() {
+		return ""
+	}
+	return expirationDateAttribute + ":" + notAfter.UTC().Format(http.TimeFormat)
+}
+
+// validityPeriodRequestAttributes returns the ValidityPeriod and ValidityPeriodUnits request
+// attributes for validity_period and validity_period_units, nil when they are not set.
+func (m *certificateCreateModel) validityPeriodRequestAttributes() []string {
+	if m.ValidityPeriod.IsNull() || m.ValidityPeriod.IsUnknown() || m.ValidityPeriodUnits.IsNull() || m.ValidityPeriodUnits.IsUnknown() {
+		return nil
+	}
+	return []string{
+		validityPeriodAttribute + ":" + m.ValidityPeriod.ValueString(),
+		validityPeriodUnitsAttribute + ":" + strconv.FormatInt(m.ValidityPeriodUnits.ValueInt64(), 10),
+	}
+}
+
+// requestedNotAfter returns when a certificate valid from notBefore expires with the validity period
+// of validity_period and validity_period_units, zero when they are not set.
+func (m *certificateCreateModel) requestedNotAfter(notBefore time.Time) time.Time {
+	if m.validityPeriodRequestAttributes() == nil {
+		return time.Time{}
+	}
+	units := m.ValidityPeriodUnits.ValueInt64()
+	switch m.ValidityPeriod.ValueString() {
+	case "Seconds":
+		return notBefore.Add(time.Duration(units) * time.Second)
+	case "Minutes":
+		return notBefore.Add(time.Duration(units) * time.Minute)
+	case "Hours":
+		return notBefore.Add(time.Duration(units) * time.Hour)
+	case "Days":
+		return notBefore.AddDate(0, 0, int(units))
+	case "Weeks":
+		return notBefore.AddDate(0, 0, 7*int(units))
+	case "Months":
+		return notBefore.AddDate(0, int(units), 0)
+	case "Years":
+		return notBefore.AddDate(int(units), 0, 0)
+	}
+	return time.Time{}
+}
+
+// validateValidityPeriod checks that validity_period and validity_period_units are set together
+// with a known unit and a positive count, and that the validity is not requested elsewhere too.
+func validateValidityPeriod(config certificateCreateModel) diag.Diagnostics {
+	var diags diag.Diagnostics
+	if config.ValidityPeriod.IsNull() && config.ValidityPeriodUnits.IsNull() {
+		return diags
+	}
+
+	if config.ValidityPeriod.IsNull() != config.ValidityPeriodUnits.IsNull() {
+		diags.AddAttributeError(path.Root("validity_period"), "Incomplete Validity Period",
+			"validity_period and validity_period_units have to be set together, such as \"Years\" and 2.")
+		return diags
+	}
+	if period := config.ValidityPeriod; !period.IsUnknown() && !containsString(validityPeriods, period.ValueString()) {
+		diags.AddAttributeError(path.Root("validity_period"), "Invalid Validity Period",
+			fmt.Sprintf("validity_period %q is not one of: %s.", period.ValueString(), strings.Join(validityPeriods, ", ")))
+	}
+	if units := config.ValidityPeriodUnits; !units.IsUnknown() && units.ValueInt64() < 1 {
+		diags.AddAttributeError(path.Root("validity_period_units"), "Invalid Validity Period",
+			fmt.Sprintf("validity_period_units must be at least 1, got %d.", units.ValueInt64()))
+	}
+	if !config.RequestedNotAfter.IsNull() {
+		diags.AddAttributeError(path.Root("validity_period"), "Conflicting Requested Validity",
+			"Request the validity either as an expiry with requested_not_after or as a period with validity_period.")
+	}
+	if attributes := config.requestAttributes(); !attributes.IsUnknown() {
+		for _, attribute := range splitAttributes(attributes.ValueString()) {
+			if name := attributeName(attribute); name == strings.ToLower(validityPeriodAttribute) || name == strings.ToLower(validityPeriodUnitsAttribute) {
+				diags.AddAttributeError(path.Root("validity_period"), "Conflicting Requested Validity",
+					"Set the validity period either in the ValidityPeriod and ValidityPeriodUnits entries of request_attributes or in validity_period and validity_period_units.")
+				return diags
+			}
+		}
+	}
+	return diags
+}
+
+// validateRequestedValidity checks that requested_not_before and requested_not_after are RFC 3339
+// timestamps in the right order.
+func validateRequestedValidity(config certificateCreateModel) diag.Diagnostics {
+	var diags diag.Diagnostics
+
+	notBefore, err := parseRequestedTime(config.RequestedNotBefore)
+	if err != nil {
+		diags.AddAttributeError(path.Root("requested_not_before"), "Invalid Requested Validity",
+			fmt.Sprintf("requested_not_before %q is not an RFC 3339 timestamp such as \"2030-01-01T00:00:00Z\".", config.RequestedNotBefore.ValueString()))
+	}
+	notAfter, err := parseRequestedTime(config.RequestedNotAfter)
+	if err != nil {
+		diags.AddAttributeError(path.Root("requested_not_after"), "Invalid Requested Validity",
+			fmt.Sprintf("requested_not_after %q is not an RFC 3339 timestamp such as \"2030-01-01T00:00:00Z\".", config.RequestedNotAfter.ValueString()))
+	}
+	if diags.HasError() || notBefore.IsZero() || notAfter.IsZero() {
+		return diags
+	}
+
+	if !notBefore.Before(notAfter) {
+		diags.AddAttributeError(path.Root("requested_not_after"), "Invalid Requested Validity",
+			fmt.Sprintf("requested_not_after %s is not after requested_not_before %s.", config.RequestedNotAfter.ValueString(), config.RequestedNotBefore.ValueString()))
+	}
+	return diags
+}
+
+// checkRequestedValidity warns when the issued certificate does not cover the requested dates.
+// The certificate is already issued, so the differences are reported rather than failing: the CA
+// starts the validity at issuance, ignores ExpirationDate without EDITF_ATTRIBUTEENDDATE and cuts
+// it to the lifetime left on its own certificate.
+func (m *certificateCreateModel) checkRequestedValidity(certificates *client.Certificates) diag.Diagnostics {
+	var diags diag.Diagnostics
+
+	notBefore, _ := parseRequestedTime(m.RequestedNotBefore)
+	notAfter, _ := parseRequestedTime(m.RequestedNotAfter)
+	if notBefore.IsZero() && notAfter.IsZero() && m.validityPeriodRequestAttributes() == nil {
+		return diags
+	}
+
+	cert, err := parseCertificate(certificates.CertificateB64)
+	if err != nil {
+		diags.AddError(
+			classifiedSummary("Error Parsing Certificate", &ParseError{Err: err}),
+			fmt.Sprintf("Could not parse the certificate issued for request ID %s to verify its validity: %s", certificates.ID, err.Error()),
+		)
+		return diags
+	}
+
+	if !notBefore.IsZero() && cert.NotBefore.After(notBefore.Add(validityTolerance)) {
+		diags.AddAttributeWarning(
+			path.Root("requested_not_before"),
+			"Issued Validity Differs From Request",
+			fmt.Sprintf("Request ID %s is valid from %s, later than the requested %s. ADCS starts the validity when it issues "+
+				"the certificate and cannot backdate it.", certificates.ID, cert.NotBefore.UTC().Format(time.RFC3339), notBefore.UTC().Format(time.RFC3339)),
+		)
+	}
+	if !notAfter.IsZero() {
+		if difference := cert.NotAfter.Sub(notAfter); difference > validityTolerance || difference < -validityTolerance {
+			diags.AddAttributeWarning(
+				path.Root("requested_not_after"),
+				"Issued Validity Differs From Request",
+				fmt.Sprintf("Request ID %s is valid until %s instead of the requested %s. The CA only honors the %s request attribute "+
+					"with the EDITF_ATTRIBUTEENDDATE policy flag, and never beyond the expiry of its own certificate.",
+					certificates.ID, cert.NotAfter.UTC().Format(time.RFC3339), notAfter.UTC().Format(time.RFC3339), expirationDateAttribute),
+			)
+		}
+	}
+	// The CA backdates the start of the validity by its clock skew allowance, which the tolerance covers.
+	if periodEnd := m.requestedNotAfter(cert.NotBefore); !periodEnd.IsZero() {
+		if difference := cert.NotAfter.Sub(periodEnd); difference > validityTolerance || difference < -validityTolerance {
+			diags.AddAttributeWarning(
+				path.Root("validity_period"),
+				"Issued Validity Differs From Request",
+				fmt.Sprintf("Request ID %s is valid from %s until %s instead of the requested %d %s. The CA only honors the %s and %s "+
+					"request attributes with the EDITF_ATTRIBUTEENDDATE policy flag, never beyond the validity period of the template on an "+
+					"enterprise CA, and never beyond the expiry of its own certificate.",
+					certificates.ID, cert.NotBefore.UTC().Format(time.RFC3339), cert.NotAfter.UTC().Format(time.RFC3339), m.ValidityPeriodUnits.ValueInt64(),
+					m.ValidityPeriod.ValueString(), validityPeriodAttribute, validityPeriodUnitsAttribute),
+			)
+		}
+	}
+	return diags
+}
@@ -0,0 +1,170 @@
+package provider
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/terraform-plugin-framework/types"
+)
+
+func TestExpirationDateRequestAttribute(t *testing.T) {
+	model := certificateCreateModel{RequestedNotAfter: types.StringValue("2030-06-01T12:30:00+02:00")}
+	if got, want := model.expirationDateRequestAttribute(), "ExpirationDate:Sat, 01 Jun 2030 10:30:00 GMT"; got != want {
+		t.Errorf("expirationDateRequestAttribute() = %q, want %q", got, want)
+	}
+
+	model.RequestAttributes = types.StringValue("Owner:platform")
+	attributes, diags := model.submittedAttributes(context.Background())
+	if diags.HasError() {
+		t.Fatalf("unexpected diagnostics: %v", diags)
+	}
+	if attributes != "Owner:platform\nExpirationDate:Sat, 01 Jun 2030 10:30:00 GMT" {
+		t.Errorf("submittedAttributes() = %q", attributes)
+	}
+
+	if got := (&certificateCreateModel{}).expirationDateRequestAttribute(); got != "" {
+		t.Errorf("expirationDateRequestAttribute() = %q without requested_not_after", got)
+	}
+}
+
+func TestValidityPeriodRequestAttributes(t *testing.T) {
+	model := certificateCreateModel{ValidityPeriod: types.StringValue("Years"), ValidityPeriodUnits: types.Int64Value(2), RequestAttributes: types.StringValue("Owner:platform")}
+	attributes, diags := model.submittedAttributes(context.Background())
+	if diags.HasError() {
+		t.Fatalf("unexpected diagnostics: %v", diags)
+	}
+	if attributes != "Owner:platform\nValidityPeriod:Years\nValidityPeriodUnits:2" {
+		t.Errorf("submittedAttributes() = %q", attributes)
+	}
+
+	notBefore := time.Date(2030, 1, 31, 0, 0, 0, 0, time.UTC)
+	for period, want := range map[string]time.Time{
+		"Hours":  notBefore.Add(2 * time.Hour),
+		"Weeks":  notBefore.AddDate(0, 0, 14),
+		"Months": time.Date(2030, 3, 31, 0, 0, 0, 0, time.UTC),
+		"Years":  time.Date(2032, 1, 31, 0, 0, 0, 0, time.UTC),
+	} {
+		model.ValidityPeriod = types.StringValue(period)
+		if got := model.requestedNotAfter(notBefore); !got.Equal(want) {
+			t.Errorf("requestedNotAfter() for 2 %s = %s, want %s", period, got, want)
+		}
+	}
+
+	if got := (&certificateCreateModel{}).validityPeriodRequestAttributes(); got != nil {
+		t.Errorf("validityPeriodRequestAttributes() = %q without validity_period", got)
+	}
+}
+
+func TestValidateValidityPeriod(t *testing.T) {
+	for name, tc := range map[string]struct {
+		config certificateCreateModel
+		want   string
+	}{
+		"unset":          {},
+		"valid":          {config: certificateCreateModel{ValidityPeriod: types.StringValue("Years"), ValidityPeriodUnits: types.Int64Value(2)}},
+		"unknown units":  {config: certificateCreateModel{ValidityPeriod: types.StringValue("Years"), ValidityPeriodUnits: types.Int64Unknown()}},
+		"without units":  {config: certificateCreateModel{ValidityPeriod: types.StringValue("Years"), ValidityPeriodUnits: types.Int64Null()}, want: "Incomplete Validity Period"},
+		"without period": {config: certificateCreateModel{ValidityPeriod: types.StringNull(), ValidityPeriodUnits: types.Int64Value(2)}, want: "Incomplete Validity Period"},
+		"unknown period": {config: certificateCreateModel{ValidityPeriod: types.StringValue("Decades"), ValidityPeriodUnits: types.Int64Value(2)}, want: "Invalid Validity Period"},
+		"no units":       {config: certificateCreateModel{ValidityPeriod: types.StringValue("Days"), ValidityPeriodUnits: types.Int64Value(0)}, want: "Invalid Validity Period"},
+		"with expiry": {
+			config: certificateCreateModel{ValidityPeriod: types.StringValue("Days"), ValidityPeriodUnits: types.Int64Value(30), RequestedNotAfter: types.StringValue("2030-01-01T00:00:00Z")},
+			want:   "Conflicting Requested Validity",
+		},
+		"with request attribute": {
+			config: certificateCreateModel{ValidityPeriod: types.StringValue("Days"), ValidityPeriodUnits: types.Int64Value(30), RequestAttributes: types.StringValue("ValidityPeriodUnits:60")},
+			want:   "Conflicting Requested Validity",
+		},
+	} {
+		diags := validateValidityPeriod(tc.config)
+		switch {
+		case tc.want == "" && diags.HasError():
+			t.Errorf("%s: validateValidityPeriod() = %v", name, diags)
+		case tc.want != "" && (!diags.HasError() || !strings.Contains(diags.Errors()[0].Summary(), tc.want)):
+			t.Errorf("%s: validateValidityPeriod() = %v, want %q", name, diags, tc.want)
+		}
+	}
+}
+
+func TestValidateRequestedValidity(t *testing.T) {
+	cases := map[string]struct {
+		notBefore, notAfter string
+		errors              int
+	}{
+		"none":         {},
+		"valid":        {notBefore: "2030-01-01T00:00:00Z", notAfter: "2031-01-01T00:00:00Z"},
+		"only end":     {notAfter: "2031-01-01T00:00:00Z"},
+		"not rfc 3339": {notBefore: "2030-01-01", notAfter: "next year", errors: 2},
+		"reversed":     {notBefore: "2031-01-01T00:00:00Z", notAfter: "2030-01-01T00:00:00Z", errors: 1},
+		"empty period": {notBefore: "2030-01-01T00:00:00Z", notAfter: "2030-01-01T00:00:00Z", errors: 1},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			var config certificateCreateModel
+			if tc.notBefore != "" {
+				config.RequestedNotBefore = types.StringValue(tc.notBefore)
+			}
+			if tc.notAfter != "" {
+				config.RequestedNotAfter = types.StringValue(tc.notAfter)
+			}
+			if diags := validateRequestedValidity(config); diags.ErrorsCount() != tc.errors {
+				t.Errorf("got %d errors, want %d: %v", diags.ErrorsCount(), tc.errors, diags)
+			}
+		})
+	}
+}
+
+func TestCheckRequestedValidity(t *testing.T) {
+	h := newTestHierarchy(t)
+	format := func(at time.Time) types.String { return types.StringValue(at.UTC().Format(time.RFC3339)) }
+
+	cases := map[string]struct {
+		model    certificateCreateModel
+		warnings []string
+	}{
+		"not requested": {},
+		"as requested": {
+			model: certificateCreateModel{RequestedNotBefore: format(h.leaf.NotBefore), RequestedNotAfter: format(h.leaf.NotAfter)},
+		},
+		"starts later": {
+			model:    certificateCreateModel{RequestedNotBefore: format(h.leaf.NotBefore.Add(-24 * time.Hour))},
+			warnings: []string{"cannot backdate"},
+		},
+		"expires earlier": {
+			model:    certificateCreateModel{RequestedNotAfter: format(h.leaf.NotAfter.Add(30 * 24 * time.Hour))},
+			warnings: []string{"EDITF_ATTRIBUTEENDDATE"},
+		},
+		"expires later": {
+			model:    certificateCreateModel{RequestedNotAfter: format(h.leaf.NotAfter.Add(-time.Hour))},
+			warnings: []string{"EDITF_ATTRIBUTEENDDATE"},
+		},
+		// The leaf is valid for 90 days and an hour.
+		"period as requested": {
+			model: certificateCreateModel{ValidityPeriod: types.StringValue("Hours"), ValidityPeriodUnits: types.Int64Value(90*24 + 1)},
+		},
+		"other period": {
+			model:    certificateCreateModel{ValidityPeriod: types.StringValue("Days"), ValidityPeriodUnits: types.Int64Value(90)},
+			warnings: []string{"requested 90 Days"},
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			diags := tc.model.checkRequestedValidity(h.testCertificates(t))
+			if diags.HasError() {
+				t.Fatalf("unexpected errors: %v", diags)
+			}
+			if diags.WarningsCount() != len(tc.warnings) {
+				t.Fatalf("got %d warnings, want %d: %v", diags.WarningsCount(), len(tc.warnings), diags)
+			}
+			for i, warning := range diags.Warnings() {
+				if !strings.Contains(warning.Detail(), tc.warnings[i]) {
+					t.Errorf("warning %q does not mention %q", warning.Detail(), tc.warnings[i])
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,104 @@
+package provider
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/hashicorp/terraform-plugin-log/tflog"
+)
+
+// adcsVersion is the Windows Server release of the CA host as far as it can be told from the
+// outside. It is read from the IIS version serving the web enrollment pages, so the releases that
+// ship the same IIS version cannot be told apart.
+type adcsVersion int
+
+const (
+	adcsVersionUnknown adcsVersion = iota
+	adcsVersion2003
+	adcsVersion2008
+	adcsVersion2008R2
+	adcsVersion2012
+	adcsVersion2012R2
+	adcsVersion2016
+)
+
+// adcsVersionsByIIS maps the IIS version in the Server header to the release it shipped with.
+var adcsVersionsByIIS = map[string]adcsVersion{
+	"6.0":  adcsVersion2003,
+	"7.0":  adcsVersion2008,
+	"7.5":  adcsVersion2008R2,
+	"8.0":  adcsVersion2012,
+	"8.5":  adcsVersion2012R2,
+	"10.0": adcsVersion2016,
+}
+
+func (v adcsVersion) String() string {
+	switch v {
+	case adcsVersion2003:
+		return "2003"
+	case adcsVersion2008:
+		return "2008"
+	case adcsVersion2008R2:
+		return "2008 R2"
+	case adcsVersion2012:
+		return "2012"
+	case adcsVersion2012R2:
+		return "2012 R2"
+	case adcsVersion2016:
+		// Windows Server 2016, 2019 and 2022 all ship IIS 10.0.
+		return "2016-2022"
+	}
+	return "unknown"
+}
+
+// adcsFeature is an operation that older releases of the web enrollment pages do not support.
+type adcsFeature struct {
+	name    string
+	minimum adcsVersion
+}
+
+var (
+	// featureRenewalIndex selects CA certificates by renewal index on certcarc.asp and certnew.p7b.
+	featureRenewalIndex = adcsFeature{name: "retrieving CA certificates by renewal index", minimum: adcsVersion2008R2}
+	// featurePendingDisposition reads the disposition of a pending request from certnew.cer.
+	featurePendingDisposition = adcsFeature{name: "checking the disposition of pending requests", minimum: adcsVersion2008R2}
+	// featureCES is the Certificate Enrollment Web Service, which was introduced with 2008 R2.
+	featureCES = adcsFeature{name: "the Certificate Enrollment Web Service", minimum: adcsVersion2008R2}
+)
+
+// supports reports whether the release supports the feature. Unknown releases are assumed to.
+func (v adcsVersion) supports(feature adcsFeature) bool {
+	return v == adcsVersionUnknown || v >= feature.minimum
+}
+
+// caVersion detects the release of the CA once per provider configuration. Detection failures
+// leave the release unknown rather than failing the operation that asked.
+func (p *providerData) caVersion(ctx context.Context) adcsVersion {
+	p.caVersionOnce.Do(func() {
+		header, _, err := getCertsrvPage(ctx, p.client, "/certsrv/")
+		if err != nil {
+			tflog.Debug(ctx, "Could not detect the ADCS version", map[string]interface{}{"error": err.Error()})
+			return
+		}
+		p.detectedVersion = versionFromServerHeader(header.Get("Server"))
+	})
+	return p.detectedVersion
+}
+
+// versionFromServerHeader maps a Server header such as "Microsoft-IIS/7.5" to the release.
+func versionFromServerHeader(server string) adcsVersion {
+	if match := iisVersionRegex.FindStringSubmatch(server); match != nil {
+		return adcsVersionsByIIS[match[1]]
+	}
+	return adcsVersionUnknown
+}
+
+// unsupportedFeature returns an error when the CA is too old to support the feature. It is called
+// after an operation failed: the pages of older releases look different, so their failures would
+// otherwise surface as confusing parse errors. Detection stays off the happy path that way.
+func (p *providerData) unsupportedFeature(ctx context.Context, feature adcsFeature) error {
+	if version := p.caVersion(ctx); !version.supports(feature) {
+		return fmt.Errorf("%s is not supported on ADCS version %s, it requires %s or later", feature.name, version, feature.minimum)
+	}
+	return nil
+}
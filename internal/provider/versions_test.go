@@ -0,0 +1,92 @@
+package provider
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	"github.com/flipyap/microsoft-adcs-client/client"
+)
+
+func TestVersionFromServerHeader(t *testing.T) {
+	cases := map[string]adcsVersion{
+		"Microsoft-IIS/6.0":  adcsVersion2003,
+		"Microsoft-IIS/7.5":  adcsVersion2008R2,
+		"Microsoft-IIS/8.5":  adcsVersion2012R2,
+		"Microsoft-IIS/10.0": adcsVersion2016,
+		"Microsoft-IIS/11.0": adcsVersionUnknown,
+		"nginx":              adcsVersionUnknown,
+	}
+	for server, want := range cases {
+		if got := versionFromServerHeader(server); got != want {
+			t.Errorf("versionFromServerHeader(%q) = %s, want %s", server, got, want)
+		}
+	}
+}
+
+func TestUnsupportedFeature(t *testing.T) {
+	cases := map[string]struct {
+		server      string
+		unsupported bool
+	}{
+		"2003":    {server: "Microsoft-IIS/6.0", unsupported: true},
+		"2008":    {server: "Microsoft-IIS/7.0", unsupported: true},
+		"2008 R2": {server: "Microsoft-IIS/7.5"},
+		"2022":    {server: "Microsoft-IIS/10.0"},
+		"unknown": {server: "nginx"},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			requests := 0
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				requests++
+				w.Header().Set("Server", tc.server)
+			}))
+			defer server.Close()
+
+			p := &providerData{client: &client.ADCSClient{HostURL: strings.TrimPrefix(server.URL, "http://"), NtlmClient: server.Client(), UseNtlm: true}}
+			for _, feature := range []adcsFeature{featureRenewalIndex, featurePendingDisposition} {
+				err := p.unsupportedFeature(context.Background(), feature)
+				if (err != nil) != tc.unsupported {
+					t.Errorf("%s: unexpected result %v", feature.name, err)
+				}
+				if err != nil && !strings.Contains(err.Error(), "not supported on ADCS version "+name) {
+					t.Errorf("%s: error does not name the version: %v", feature.name, err)
+				}
+			}
+			if requests != 1 {
+				t.Errorf("the version was detected with %d requests, want 1", requests)
+			}
+		})
+	}
+}
+
+func TestCAVersionConcurrentDetection(t *testing.T) {
+	var requests int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&requests, 1)
+		w.Header().Set("Server", "Microsoft-IIS/7.0")
+	}))
+	defer server.Close()
+
+	p := &providerData{client: &client.ADCSClient{HostURL: strings.TrimPrefix(server.URL, "http://"), NtlmClient: server.Client(), UseNtlm: true}}
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if p.caVersion(context.Background()) != adcsVersion2008 {
+				t.Error("a concurrent caller did not see the detected version")
+			}
+		}()
+	}
+	wg.Wait()
+	if requests != 1 {
+		t.Errorf("the version was detected with %d requests, want 1", requests)
+	}
+}
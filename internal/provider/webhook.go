@@ -0,0 +1,68 @@
+package provider
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"regexp"
+	"time"
+)
+
+// webhookClient is used for calls to external systems, these should never hang an apply.
+var webhookClient = &http.Client{Timeout: 30 * time.Second}
+
+// The client reports requests taken under submission as an error carrying the request ID.
+var pendingRequestRegex = regexp.MustCompile(`certificate pending for request id (\d+)`)
+
+// approvalRequest is the JSON body posted to the approval webhook.
+type approvalRequest struct {
+	Event     string `json:"event"`
+	RequestID string `json:"request_id"`
+	Subject   string `json:"subject"`
+	Template  string `json:"template"`
+	Host      string `json:"host"`
+}
+
+// pendingRequestID returns the request ID if err says ADCS is holding the request for approval.
+func pendingRequestID(err error) (string, bool) {
+	match := pendingRequestRegex.FindStringSubmatch(err.Error())
+	if match == nil {
+		return "", false
+	}
+	return match[1], true
+}
+
+// notifyApprovalWebhook posts the pending request to the configured approval webhook.
+func (p *providerData) notifyApprovalWebhook(ctx context.Context, payload approvalRequest) error {
+	return postWebhook(ctx, p.approvalWebhookURL, p.approvalWebhookHeaders, payload)
+}
+
+// postWebhook posts the payload as JSON with the extra headers and requires a 2xx response.
+func postWebhook(ctx context.Context, url string, headers map[string]string, payload interface{}) error {
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return fmt.Errorf("could not encode webhook payload: %v", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("could not create webhook request: %v", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for name, value := range headers {
+		req.Header.Set(name, value)
+	}
+
+	resp, err := webhookClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("webhook request failed: %v", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("webhook returned status %d", resp.StatusCode)
+	}
+	return nil
+}
@@ -6,7 +6,9 @@ package main
 import (
 	"context"
 	"flag"
+	"fmt"
 	"log"
+	"os"
 
 	"terraform-provider-microsoft-adcs/internal/provider"
 
@@ -33,6 +35,24 @@ var (
 )
 
 func main() {
+	// debug-enroll requests one certificate outside of Terraform to troubleshoot the CA and authentication.
+	if len(os.Args) > 1 && os.Args[1] == "debug-enroll" {
+		if err := provider.DebugEnroll(context.Background(), version, os.Args[2:], os.Stdout, os.Stderr); err != nil {
+			fmt.Fprintln(os.Stderr, "Error:", err)
+			os.Exit(1)
+		}
+		return
+	}
+
+	// decrypt opens values encrypted with state_encryption_public_key.
+	if len(os.Args) > 1 && os.Args[1] == "decrypt" {
+		if err := provider.Decrypt(os.Args[2:], os.Stdin, os.Stdout, os.Stderr); err != nil {
+			fmt.Fprintln(os.Stderr, "Error:", err)
+			os.Exit(1)
+		}
+		return
+	}
+
 	var debug bool
 
 	flag.BoolVar(&debug, "debug", false, "set to true to run the provider with support for debuggers like delve")